@@ -0,0 +1,38 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+const DBCompactPath = "/trader/db/compact"
+
+// DBCompactRequest removes the completed limiter jobs whose last order is
+// older than the retention window. Jobs are exported to the server's export
+// directory before they are removed unless NoExport is set.
+type DBCompactRequest struct {
+	Retention time.Duration
+
+	NoExport bool
+
+	// DryRun when true, only reports the jobs that would be removed.
+	DryRun bool
+}
+
+type DBCompactResponse struct {
+	// Removed holds the uids of the jobs that are removed (or would be removed
+	// in a dry run).
+	Removed []string
+
+	// Exported holds the export file paths for the removed jobs.
+	Exported []string
+}
+
+func (r *DBCompactRequest) Check() error {
+	if r.Retention <= 0 {
+		return fmt.Errorf("retention must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+const ExchangeCancelStalePath = "/exchange/cancel-stale"
+
+// ExchangeCancelStaleRequest cancels the open orders of a product that are
+// created more than OlderThan duration ago.
+type ExchangeCancelStaleRequest struct {
+	ExchangeName string
+	ProductID    string
+
+	OlderThan time.Duration
+
+	// DryRun when true, only reports the orders that would be canceled.
+	DryRun bool
+}
+
+type ExchangeCancelStaleOrder struct {
+	OrderID    string
+	Side       string
+	CreateTime time.Time
+
+	// Error is non-empty when the order could not be canceled.
+	Error string
+}
+
+type ExchangeCancelStaleResponse struct {
+	// NumOpen is the number of open orders of the product.
+	NumOpen int
+
+	// NumLive is the number of stale orders that are skipped because they are
+	// live orders of the running jobs.
+	NumLive int
+
+	// Orders holds the stale orders that are (or would be, with dry-run)
+	// canceled.
+	Orders []*ExchangeCancelStaleOrder
+}
+
+func (req *ExchangeCancelStaleRequest) Check() error {
+	if len(req.ExchangeName) == 0 {
+		return fmt.Errorf("exchange name cannot be empty")
+	}
+	if len(req.ProductID) == 0 {
+		return fmt.Errorf("product id cannot be empty")
+	}
+	if req.OlderThan <= 0 {
+		return fmt.Errorf("older-than duration must be positive")
+	}
+	return nil
+}
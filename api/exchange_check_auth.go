@@ -0,0 +1,23 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import "github.com/shopspring/decimal"
+
+const ExchangeCheckAuthPath = "/exchange/check-auth"
+
+type ExchangeCheckAuthRequest struct {
+	ExchangeName string
+}
+
+type ExchangeCheckAuthResponse struct {
+	Error string
+
+	AccountID   string
+	AccountType string
+	Permissions []string
+
+	PricingTier string
+	MakerFeePct decimal.Decimal
+	TakerFeePct decimal.Decimal
+}
@@ -0,0 +1,20 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import "github.com/shopspring/decimal"
+
+const ExchangeFeeTierPath = "/exchange/fee-tier"
+
+type ExchangeFeeTierRequest struct {
+	ExchangeName string
+}
+
+type ExchangeFeeTierResponse struct {
+	Error string
+
+	// MakerFeePct and TakerFeePct are fee percentages for the account's current
+	// fee tier.
+	MakerFeePct decimal.Decimal
+	TakerFeePct decimal.Decimal
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bvk/tradebot/gobs"
+)
+
+const ExchangeGetOrdersPath = "/exchange/get-orders"
+
+// MaxGetOrdersConcurrency is the upper limit for the number of orders fetched
+// from the exchange in parallel by a get-orders request.
+const MaxGetOrdersConcurrency = 32
+
+type ExchangeGetOrdersRequest struct {
+	Name string
+
+	OrderIDs []string
+
+	// Concurrency is the number of orders fetched in parallel. A default value
+	// is used when it is zero.
+	Concurrency int
+}
+
+func (r *ExchangeGetOrdersRequest) Check() error {
+	if len(r.OrderIDs) == 0 {
+		return fmt.Errorf("order ids cannot be empty: %w", os.ErrInvalid)
+	}
+	if r.Concurrency < 0 || r.Concurrency > MaxGetOrdersConcurrency {
+		return fmt.Errorf("concurrency must be in between 0-%d: %w", MaxGetOrdersConcurrency, os.ErrInvalid)
+	}
+	return nil
+}
+
+type ExchangeGetOrdersResponse struct {
+	Error string
+
+	// Orders holds the order states in the same order as the request's order
+	// ids.
+	Orders []*ExchangeGetOrdersItem
+}
+
+type ExchangeGetOrdersItem struct {
+	OrderID string
+
+	// Error is non-empty when the order could not be fetched.
+	Error string
+
+	Order *gobs.Order
+}
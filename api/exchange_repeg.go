@@ -0,0 +1,57 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const ExchangeRepegPath = "/exchange/repeg"
+
+type ExchangeRepegRequest struct {
+	ExchangeName string
+	ProductID    string
+
+	// DryRun when true, only reports the orders that would be canceled without
+	// pausing or resuming any jobs.
+	DryRun bool
+}
+
+type ExchangeRepegOrder struct {
+	// JobUID is the uid of the job and LimiterUID is the uid of the limiter
+	// that owns the order.
+	JobUID     string
+	LimiterUID string
+
+	OrderID    string
+	Side       string
+	Size       decimal.Decimal
+	Price      decimal.Decimal
+	CreateTime time.Time
+}
+
+type ExchangeRepegResponse struct {
+	// UIDs holds the jobs that are paused and resumed successfully.
+	UIDs []string
+
+	// Failed holds the error messages for the jobs that could not be paused or
+	// resumed, keyed by the job uid.
+	Failed map[string]string
+
+	// Orders holds the live orders that are (or would be, with dry-run)
+	// canceled by the repeg.
+	Orders []*ExchangeRepegOrder
+}
+
+func (req *ExchangeRepegRequest) Check() error {
+	if len(req.ExchangeName) == 0 {
+		return fmt.Errorf("exchange name cannot be empty")
+	}
+	if len(req.ProductID) == 0 {
+		return fmt.Errorf("product id cannot be empty")
+	}
+	return nil
+}
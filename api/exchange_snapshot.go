@@ -0,0 +1,32 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const ExchangeSnapshotPath = "/exchange/snapshot"
+
+type ExchangeSnapshotRequest struct {
+	// Save when true, persists the snapshot in the database.
+	Save bool
+}
+
+type ExchangeSnapshotResponse struct {
+	Timestamp time.Time
+
+	Prices []*ExchangeSnapshotPrice
+}
+
+type ExchangeSnapshotPrice struct {
+	ExchangeName string
+	ProductID    string
+
+	Price decimal.Decimal
+
+	// Error is non-empty when the price couldn't be fetched.
+	Error string `json:",omitempty"`
+}
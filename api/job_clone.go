@@ -0,0 +1,40 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+const JobClonePath = "/trader/job/clone"
+
+type JobCloneRequest struct {
+	UID string
+
+	JobName string
+
+	// ProductID when non-empty, overrides the product for the cloned job.
+	ProductID string
+
+	// PriceOffset is added to all buy/sell and cancel prices of the cloned job.
+	PriceOffset decimal.Decimal
+
+	// Start when true, starts the cloned job immediately.
+	Start bool
+}
+
+type JobCloneResponse struct {
+	UID string
+}
+
+func (r *JobCloneRequest) Check() error {
+	if len(r.UID) == 0 {
+		return fmt.Errorf("source job uid cannot be empty")
+	}
+	if len(r.JobName) == 0 {
+		return fmt.Errorf("job name cannot be empty")
+	}
+	return nil
+}
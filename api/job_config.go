@@ -0,0 +1,28 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import "fmt"
+
+const JobConfigPath = "/trader/config"
+
+type JobConfigRequest struct {
+	UID string
+}
+
+type JobConfigResponse struct {
+	UID string
+
+	// Running is true if the configuration is taken from the running job.
+	Running bool
+
+	// Options holds the effective values for all job options.
+	Options map[string]string
+}
+
+func (req *JobConfigRequest) Check() error {
+	if len(req.UID) == 0 {
+		return fmt.Errorf("job uid cannot be empty")
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import "fmt"
+
+const JobHoldPath = "/trader/job/hold"
+
+// JobHoldRequest holds or releases a limiter job without pausing it. Held
+// limiter cancels it's active order and creates no new orders until it is
+// released, but the job keeps running and processing the tickers.
+type JobHoldRequest struct {
+	UID string
+
+	// Hold when false, releases a held limiter.
+	Hold bool
+}
+
+type JobHoldResponse struct {
+	Held bool
+}
+
+func (req *JobHoldRequest) Check() error {
+	if len(req.UID) == 0 {
+		return fmt.Errorf("job uid cannot be empty")
+	}
+	return nil
+}
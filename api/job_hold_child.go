@@ -0,0 +1,30 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import "fmt"
+
+const JobHoldChildPath = "/trader/job/hold-child"
+
+type JobHoldChildRequest struct {
+	UID string
+
+	// Child is the name (eg: buy-000003) or the full uid of the child limiter.
+	Child string
+
+	// Hold when false, releases a held child limiter.
+	Hold bool
+}
+
+type JobHoldChildResponse struct {
+}
+
+func (req *JobHoldChildRequest) Check() error {
+	if len(req.UID) == 0 {
+		return fmt.Errorf("job uid cannot be empty")
+	}
+	if len(req.Child) == 0 {
+		return fmt.Errorf("child limiter name cannot be empty")
+	}
+	return nil
+}
@@ -12,8 +12,16 @@ type JobListResponseItem struct {
 	Type  string
 	State string
 	Name  string
+	Note  string
 
 	ManualFlag bool
+
+	// StartPaused is true when the job is not resumed automatically on the
+	// server restarts.
+	StartPaused bool
+
+	// Held is true when the job is a running limiter with the hold option set.
+	Held bool
 }
 
 type JobListResponse struct {
@@ -0,0 +1,27 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import (
+	"fmt"
+)
+
+const JobNotePath = "/trader/job/note"
+
+// JobNoteRequest attaches a note to a job. An empty note removes the existing
+// note.
+type JobNoteRequest struct {
+	UID string
+
+	Note string
+}
+
+type JobNoteResponse struct {
+}
+
+func (r *JobNoteRequest) Check() error {
+	if len(r.UID) == 0 {
+		return fmt.Errorf("job uid cannot be empty")
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+const JobSplitPath = "/trader/job/split"
+
+type JobSplitRequest struct {
+	UID string
+
+	// PivotPrice partitions the buy-sell cycles of the looper. Cycles with buy
+	// price at or above the pivot are moved into the new job.
+	PivotPrice decimal.Decimal
+
+	// NewUID is the uid for the new job.
+	NewUID string
+}
+
+type JobSplitResponse struct {
+	// MovedLimiters holds the new uids of the child limiters moved into the
+	// new job.
+	MovedLimiters []string
+}
+
+func (req *JobSplitRequest) Check() error {
+	if len(req.UID) == 0 {
+		return fmt.Errorf("job uid cannot be empty")
+	}
+	if !req.PivotPrice.IsPositive() {
+		return fmt.Errorf("pivot price must be positive")
+	}
+	if len(req.NewUID) == 0 {
+		return fmt.Errorf("new job uid cannot be empty")
+	}
+	return nil
+}
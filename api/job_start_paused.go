@@ -0,0 +1,26 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import "fmt"
+
+const JobStartPausedPath = "/trader/job/start-paused"
+
+// JobStartPausedRequest sets or clears the start-paused flag on a job. Jobs
+// with the flag are left paused when the server is restarted and must be
+// resumed explicitly.
+type JobStartPausedRequest struct {
+	UID string
+
+	StartPaused bool
+}
+
+type JobStartPausedResponse struct {
+}
+
+func (req *JobStartPausedRequest) Check() error {
+	if len(req.UID) == 0 {
+		return fmt.Errorf("job uid cannot be empty")
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+const JobVerifyPath = "/trader/verify"
+
+// JobVerifyRequest compares the in-memory state of the running jobs with
+// their state saved in the database. All running jobs are verified when UIDs
+// is empty.
+type JobVerifyRequest struct {
+	UIDs []string
+}
+
+type JobVerifyResponseItem struct {
+	UID string
+
+	// Divergences holds the differences between the in-memory and the saved
+	// state of the job. It is empty when the states match.
+	Divergences []string
+}
+
+type JobVerifyResponse struct {
+	Jobs []*JobVerifyResponseItem
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// JobWatchPath is a GET endpoint that streams the job status as Server-Sent
+// Events. Job uid is taken from the "uid" query parameter.
+const JobWatchPath = "/trader/watch"
+
+type JobWatchStatus struct {
+	UID   string
+	State string
+
+	ProductID    string
+	ExchangeName string
+
+	// PendingSize is the total unfilled size of the job's active orders.
+	PendingSize decimal.Decimal
+
+	// ActiveOrders holds the server order ids for the job's active orders.
+	ActiveOrders []string `json:",omitempty"`
+
+	LastPrice      decimal.Decimal
+	LastTickerTime time.Time
+
+	// UnrealizedProfit is the profit if the job's unsold size is sold at the
+	// last price. It is zero for the jobs without a status summary.
+	UnrealizedProfit decimal.Decimal
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import (
+	"fmt"
+)
+
+const KillSwitchPath = "/trader/kill-switch"
+
+// KillSwitchRequest engages the kill-switch, which halts all running jobs and
+// cancels all open orders on the exchanges. Kill-switch stays engaged across
+// restarts until it is cleared with the Clear field.
+type KillSwitchRequest struct {
+	Reason string
+
+	Clear bool
+
+	// Resume when true, resumes the jobs halted by the kill-switch when it is
+	// cleared.
+	Resume bool
+}
+
+type KillSwitchResponse struct {
+	// UIDs holds the jobs halted by the kill-switch, or the jobs resumed when it
+	// is cleared.
+	UIDs []string
+
+	// CanceledOrders holds the open orders that were canceled explicitly after
+	// the jobs are halted, including the orders not owned by any job.
+	CanceledOrders []string
+
+	// Failed holds the error messages for the jobs that could not be halted or
+	// resumed, and for the open orders that could not be canceled.
+	Failed map[string]string
+
+	// FailedProducts holds the exchange/product names whose open orders could
+	// not be listed or canceled.
+	FailedProducts []string
+}
+
+func (r *KillSwitchRequest) Check() error {
+	if r.Resume && !r.Clear {
+		return fmt.Errorf("resume is only valid when clearing the kill-switch")
+	}
+	return nil
+}
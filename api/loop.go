@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/bvk/tradebot/point"
+	"github.com/shopspring/decimal"
 )
 
 const LoopPath = "/trader/loop"
@@ -17,6 +18,9 @@ type LoopRequest struct {
 
 	Buy  *point.Point
 	Sell *point.Point
+
+	// Budget when non-zero, limits the cost of unsold buys of the looper.
+	Budget decimal.Decimal
 }
 
 type LoopResponse struct {
@@ -42,5 +46,11 @@ func (r *LoopRequest) Check() error {
 	if r.Sell.Side() != "SELL" {
 		return fmt.Errorf("invalid sell point side")
 	}
+	if r.Budget.IsNegative() {
+		return fmt.Errorf("budget cannot be negative")
+	}
+	if !r.Budget.IsZero() && r.Budget.LessThan(r.Buy.Value()) {
+		return fmt.Errorf("budget %s is less than the buy point value %s", r.Budget, r.Buy.Value())
+	}
 	return nil
 }
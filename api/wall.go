@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/bvk/tradebot/point"
+	"github.com/shopspring/decimal"
 )
 
 const WallPath = "/trader/wall"
@@ -16,10 +17,21 @@ type WallRequest struct {
 	ProductID string
 
 	Pairs []*point.Pair
+
+	// Validate when true, only validates the request against the product
+	// properties and reports the budget without creating the job.
+	Validate bool
+
+	// FeePct is the fee percentage used to compute the budget.
+	FeePct float64
 }
 
 type WallResponse struct {
 	UID string
+
+	// Budget and NumPairs are the validation report for the request.
+	Budget   decimal.Decimal
+	NumPairs int
 }
 
 func (r *WallRequest) Check() error {
@@ -32,6 +44,9 @@ func (r *WallRequest) Check() error {
 	if len(r.Pairs) == 0 {
 		return fmt.Errorf("buy/sell pairs cannot be empty")
 	}
+	if r.FeePct < 0 || r.FeePct >= 100 {
+		return fmt.Errorf("fee percentage should be in between 0-100")
+	}
 	for i, p := range r.Pairs {
 		if err := p.Check(); err != nil {
 			return fmt.Errorf("invalid buy/sell pair %d: %w", i, err)
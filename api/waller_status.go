@@ -0,0 +1,56 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package api
+
+import (
+	"fmt"
+
+	"github.com/bvk/tradebot/point"
+	"github.com/shopspring/decimal"
+)
+
+const WallerStatusPath = "/trader/waller/status"
+
+type WallerStatusRequest struct {
+	UID string
+}
+
+type WallerLoopStatus struct {
+	UID   string
+	Pair  *point.Pair
+	State string
+
+	NumBuys  int
+	NumSells int
+
+	Profit decimal.Decimal
+}
+
+type WallerStatusResponse struct {
+	UID       string
+	ProductID string
+
+	// InventorySize is the base size held by the waller, i.e., bought but not
+	// sold yet, and InventoryAvgCost is the volume-weighted average buy price
+	// for the held size.
+	InventorySize    decimal.Decimal
+	InventoryAvgCost decimal.Decimal
+
+	// MarketPrice is the last price of the product when it is available, in
+	// which case NumActive is the number of loops with an order resting at the
+	// market price and ActiveBudget and IdleBudget are the budgets of the
+	// active and the remaining (not completed) loops.
+	MarketPrice  decimal.Decimal
+	NumActive    int
+	ActiveBudget decimal.Decimal
+	IdleBudget   decimal.Decimal
+
+	Loops []*WallerLoopStatus
+}
+
+func (req *WallerStatusRequest) Check() error {
+	if len(req.UID) == 0 {
+		return fmt.Errorf("waller uid cannot be empty")
+	}
+	return nil
+}
@@ -2,7 +2,142 @@
 
 package coinbase
 
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+const (
+	// Environment variables to pass the coinbase credentials.
+	KeyEnv    = "COINBASE_KEY"
+	SecretEnv = "COINBASE_SECRET"
+
+	// Service name for the credentials in the OS keyring.
+	KeyringService = "tradebot-coinbase"
+)
+
 type Credentials struct {
 	Key    string
 	Secret string
+
+	// AuthScheme is an optional authentication scheme for the credentials. See
+	// Options.AuthScheme for the valid values.
+	AuthScheme string
+}
+
+func (c *Credentials) isValid() bool {
+	return c != nil && len(c.Key) != 0 && len(c.Secret) != 0
+}
+
+// CredentialsFromEnv returns credentials from the COINBASE_KEY and
+// COINBASE_SECRET environment variables. Returns nil if any of them is empty.
+func CredentialsFromEnv() *Credentials {
+	c := &Credentials{
+		Key:    os.Getenv(KeyEnv),
+		Secret: os.Getenv(SecretEnv),
+	}
+	if !c.isValid() {
+		return nil
+	}
+	return c
+}
+
+// ProfileCredentialsFromEnv returns credentials for a named profile from the
+// COINBASE_<PROFILE>_KEY and COINBASE_<PROFILE>_SECRET environment variables,
+// where profile name is upper-cased and dashes are replaced by underscores.
+// Returns nil if any of them is empty.
+func ProfileCredentialsFromEnv(profile string) *Credentials {
+	prefix := "COINBASE_" + strings.ToUpper(strings.ReplaceAll(profile, "-", "_"))
+	c := &Credentials{
+		Key:    os.Getenv(prefix + "_KEY"),
+		Secret: os.Getenv(prefix + "_SECRET"),
+	}
+	if !c.isValid() {
+		return nil
+	}
+	return c
+}
+
+// CredentialsFromKeyring returns credentials saved in the OS keyring under
+// the KeyringService service name with "key" and "secret" as the account
+// names. Uses the secret-tool command on Linux and the security command on
+// macOS.
+func CredentialsFromKeyring(ctx context.Context) (*Credentials, error) {
+	lookup := func(account string) (string, error) {
+		var cmd *exec.Cmd
+		switch runtime.GOOS {
+		case "linux":
+			cmd = exec.CommandContext(ctx, "secret-tool", "lookup", "service", KeyringService, "account", account)
+		case "darwin":
+			cmd = exec.CommandContext(ctx, "security", "find-generic-password", "-s", KeyringService, "-a", account, "-w")
+		default:
+			return "", fmt.Errorf("keyring is not supported on %s: %w", runtime.GOOS, os.ErrInvalid)
+		}
+		data, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("could not lookup %q in the keyring: %w", account, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	key, err := lookup("key")
+	if err != nil {
+		return nil, err
+	}
+	secret, err := lookup("secret")
+	if err != nil {
+		return nil, err
+	}
+	c := &Credentials{Key: key, Secret: secret}
+	if !c.isValid() {
+		return nil, fmt.Errorf("keyring credentials are empty: %w", os.ErrNotExist)
+	}
+	return c, nil
+}
+
+// ResolveCredentials picks the credentials from one of the supported sources
+// in the following order of precedence:
+//
+//  1. COINBASE_KEY and COINBASE_SECRET environment variables
+//  2. OS keyring, when useKeyring is true
+//  3. Input credentials, which are typically loaded from a secrets file
+//
+// Returns os.ErrNotExist if no credentials are found.
+func ResolveCredentials(ctx context.Context, file *Credentials, useKeyring bool) (*Credentials, error) {
+	if c := CredentialsFromEnv(); c != nil {
+		return c, nil
+	}
+	var keyringErr error
+	if useKeyring {
+		c, err := CredentialsFromKeyring(ctx)
+		if err == nil {
+			return c, nil
+		}
+		keyringErr = err
+	}
+	if file.isValid() {
+		return file, nil
+	}
+	if keyringErr != nil {
+		return nil, fmt.Errorf("could not find coinbase credentials in the keyring: %w", keyringErr)
+	}
+	return nil, fmt.Errorf("could not find coinbase credentials: %w", os.ErrNotExist)
+}
+
+// ResolveProfileCredentials is similar to ResolveCredentials, but picks the
+// credentials for a named profile, from the profile specific environment
+// variables or the input credentials. Default environment variables and the
+// keyring hold the default account's credentials, so they are not used.
+func ResolveProfileCredentials(profile string, file *Credentials) (*Credentials, error) {
+	if c := ProfileCredentialsFromEnv(profile); c != nil {
+		return c, nil
+	}
+	if file.isValid() {
+		return file, nil
+	}
+	return nil, fmt.Errorf("could not find coinbase credentials for profile %q: %w", profile, os.ErrNotExist)
 }
@@ -0,0 +1,70 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package coinbase
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestResolveProfileCredentials(t *testing.T) {
+	t.Setenv(KeyEnv, "default-key")
+	t.Setenv(SecretEnv, "default-secret")
+	t.Setenv("COINBASE_STRATEGY_A_KEY", "a-key")
+	t.Setenv("COINBASE_STRATEGY_A_SECRET", "a-secret")
+
+	c, err := ResolveProfileCredentials("strategy-a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Key != "a-key" || c.Secret != "a-secret" {
+		t.Fatalf("want profile credentials from the environment, got key %q", c.Key)
+	}
+
+	file := &Credentials{Key: "b-key", Secret: "b-secret"}
+	if c, err := ResolveProfileCredentials("strategy-b", file); err != nil || c != file {
+		t.Fatalf("want profile credentials from the file, got %v (%v)", c, err)
+	}
+	if c, err := ResolveProfileCredentials("strategy-a", file); err != nil || c.Key != "a-key" {
+		t.Fatalf("profile environment must take precedence over the file, got %v (%v)", c, err)
+	}
+	if _, err := ResolveProfileCredentials("strategy-c", nil); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("default credentials must not be used for a profile, got %v", err)
+	}
+}
+
+func TestResolveCredentials(t *testing.T) {
+	ctx := context.Background()
+
+	// Environment takes precedence over the secrets file.
+	t.Setenv(KeyEnv, "env-key")
+	t.Setenv(SecretEnv, "env-secret")
+	file := &Credentials{Key: "file-key", Secret: "file-secret"}
+	c, err := ResolveCredentials(ctx, file, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Key != "env-key" || c.Secret != "env-secret" {
+		t.Fatalf("want credentials from the environment, got key %q", c.Key)
+	}
+
+	// Secrets file is the fallback.
+	t.Setenv(KeyEnv, "")
+	if c, err := ResolveCredentials(ctx, file, false); err != nil || c != file {
+		t.Fatalf("want credentials from the file, got %v (%v)", c, err)
+	}
+	if _, err := ResolveCredentials(ctx, nil, false); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("want os.ErrNotExist without any credentials, got %v", err)
+	}
+
+	// Secrets file is also the fallback when the keyring lookup fails.
+	t.Setenv("PATH", "")
+	if c, err := ResolveCredentials(ctx, file, true); err != nil || c != file {
+		t.Fatalf("want credentials from the file after keyring failure, got %v (%v)", c, err)
+	}
+	if _, err := ResolveCredentials(ctx, nil, true); err == nil {
+		t.Fatalf("want error when the keyring lookup fails without a file")
+	}
+}
@@ -418,21 +418,57 @@ func (ds *Datastore) ProductsPriceMap(ctx context.Context) (map[string]decimal.D
 	return pmap, nil
 }
 
-func (ds *Datastore) saveAccounts(ctx context.Context, as []*internal.Account) error {
+// ProductsBaseIncrementMap returns the base increment for every product in
+// the saved products information.
+func (ds *Datastore) ProductsBaseIncrementMap(ctx context.Context) (map[string]decimal.Decimal, error) {
+	imap := make(map[string]decimal.Decimal)
+
+	collector := func(ctx context.Context, r kv.Reader) error {
+		key := path.Join(Keyspace, "products")
+		value, err := kvutil.Get[gobs.CoinbaseProducts](ctx, r, key)
+		if err != nil {
+			return fmt.Errorf("could not load coinbase products information: %w", err)
+		}
+		for _, p := range value.Products {
+			product := new(internal.Product)
+			if err := json.Unmarshal(p.Product, product); err != nil {
+				return fmt.Errorf("could not unmarshal coinbase product %q: %w", p.ProductID, err)
+			}
+			imap[p.ProductID] = product.BaseIncr.Decimal
+		}
+		return nil
+	}
+
+	if err := kv.WithReader(ctx, ds.db, collector); err != nil {
+		return nil, err
+	}
+	return imap, nil
+}
+
+// accountsKey returns the key for the account balances of a credential
+// profile. Default (empty) profile uses the original accounts key.
+func accountsKey(profile string) string {
+	if len(profile) == 0 {
+		return path.Join(Keyspace, "accounts")
+	}
+	return path.Join(Keyspace, "profiles", profile, "accounts")
+}
+
+func (ds *Datastore) saveAccounts(ctx context.Context, profile string, as []*internal.Account) error {
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
 
 	return kv.WithReadWriter(ctx, ds.db, func(ctx context.Context, rw kv.ReadWriter) error {
-		return ds.saveAccountsLocked(ctx, rw, as)
+		return ds.saveAccountsLocked(ctx, rw, profile, as)
 	})
 }
 
-func (ds *Datastore) saveAccountsLocked(ctx context.Context, rw kv.ReadWriter, as []*internal.Account) error {
+func (ds *Datastore) saveAccountsLocked(ctx context.Context, rw kv.ReadWriter, profile string, as []*internal.Account) error {
 	sort.Slice(as, func(i, j int) bool {
 		return as[i].Currency < as[j].Currency
 	})
 
-	key := path.Join(Keyspace, "accounts")
+	key := accountsKey(profile)
 	value := &gobs.CoinbaseAccounts{
 		Timestamp: time.Now(),
 	}
@@ -453,7 +489,13 @@ func (ds *Datastore) saveAccountsLocked(ctx context.Context, rw kv.ReadWriter, a
 }
 
 func (ds *Datastore) LoadAccounts(ctx context.Context) ([]*gobs.Account, error) {
-	key := path.Join(Keyspace, "accounts")
+	return ds.LoadProfileAccounts(ctx, "")
+}
+
+// LoadProfileAccounts returns the account balances saved by the exchange with
+// the credential profile. Empty profile refers to the default credentials.
+func (ds *Datastore) LoadProfileAccounts(ctx context.Context, profile string) ([]*gobs.Account, error) {
+	key := accountsKey(profile)
 	value, err := kvutil.GetDB[gobs.CoinbaseAccounts](ctx, ds.db, key)
 	if err != nil {
 		return nil, fmt.Errorf("could not load coinbase accounts data: %w", err)
@@ -0,0 +1,52 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package coinbase
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/bvk/tradebot/coinbase/internal"
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvkgo/kv/kvmemdb"
+	"github.com/shopspring/decimal"
+)
+
+func TestDatastoreProfileAccounts(t *testing.T) {
+	ctx := context.Background()
+	ds := NewDatastore(kvmemdb.New())
+
+	account := func(currency, avail string) *internal.Account {
+		a := &internal.Account{Currency: currency}
+		a.AvailableBalance.Value = exchange.NullDecimal{Decimal: decimal.RequireFromString(avail)}
+		return a
+	}
+	if err := ds.saveAccounts(ctx, "", []*internal.Account{account("USD", "100")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.saveAccounts(ctx, "sub", []*internal.Account{account("USD", "5"), account("BTC", "1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	main, err := ds.LoadAccounts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(main) != 1 || main[0].CurrencyID != "USD" || !main[0].Available.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("default accounts must not be replaced by the profile accounts, got %v", main)
+	}
+
+	sub, err := ds.LoadProfileAccounts(ctx, "sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sub) != 2 || sub[0].CurrencyID != "BTC" || !sub[1].Available.Equal(decimal.NewFromInt(5)) {
+		t.Fatalf("want the profile accounts, got %v", sub)
+	}
+
+	if _, err := ds.LoadProfileAccounts(ctx, "other"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("want os.ErrNotExist for a profile without accounts, got %v", err)
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"net/url"
 	"os"
 	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/bvk/tradebot/coinbase/internal"
@@ -18,6 +20,7 @@ import (
 	"github.com/bvk/tradebot/gobs"
 	"github.com/bvk/tradebot/syncmap"
 	"github.com/bvkgo/kv"
+	"github.com/shopspring/decimal"
 )
 
 type Exchange struct {
@@ -31,7 +34,10 @@ type Exchange struct {
 	// known orders. TODO: We should cleanup the oldest orders.
 	clientOrderIDMap syncmap.Map[string, *exchange.Order]
 
-	productMap syncmap.Map[string, *Product]
+	// productLock serializes opening and closing of the products, which are
+	// reference counted and shared by all users of the same product-id.
+	productLock sync.Mutex
+	productMap  syncmap.Map[string, *Product]
 
 	datastore *Datastore
 
@@ -47,20 +53,60 @@ type Exchange struct {
 	// operations (eg: CancelOrder), so we use this map to make the callers wait
 	// till the orders becomes ready.
 	pendingMap syncmap.Map[string, chan struct{}]
+
+	// feeMap holds the last known cumulative fee for an order-id, which is used
+	// to compute the last fill fee for the order updates.
+	feeMap syncmap.Map[string, decimal.Decimal]
+
+	// fillsMap holds the fee and liquidity computed from the listed fills of
+	// an order, so that fills are not listed again for every update of the
+	// order. Orders waiting for their fills to be listed are in fillsPending
+	// and fillsCh.
+	fillsMap     syncmap.Map[string, *orderFills]
+	fillsPending syncmap.Map[string, bool]
+	fillsCh      chan *fillsRequest
+
+	// feeTierMu protects the cached fee tier percentages, which are refreshed
+	// from the exchange after FeeTierCacheTTL.
+	feeTierMu                sync.Mutex
+	feeTierTime              time.Time
+	makerFeePct, takerFeePct decimal.Decimal
+
+	// accountsMu protects the cached account balances, which are refreshed
+	// from the exchange after AccountsCacheTTL.
+	accountsMu   sync.Mutex
+	accountsTime time.Time
+	accounts     []*internal.Account
 }
 
-// New creates a client for coinbase exchange.
+// New creates a client for coinbase exchange. Input key and secret are
+// only used when credentials are not found in the environment or the keyring
+// (see ResolveCredentials).
 func New(ctx context.Context, db kv.Database, key, secret string, opts *Options) (_ *Exchange, status error) {
 	if opts == nil {
 		opts = new(Options)
 	}
 	opts.setDefaults()
+	if err := opts.check(); err != nil {
+		return nil, err
+	}
+
+	creds, err := ResolveCredentials(ctx, &Credentials{Key: key, Secret: secret}, opts.UseKeyring)
+	if len(opts.Profile) != 0 {
+		creds, err = ResolveProfileCredentials(opts.Profile, &Credentials{Key: key, Secret: secret})
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, secret = creds.Key, creds.Secret
 
 	copts := &internal.Options{
 		RestHostname:           opts.RestHostname,
 		WebsocketHostname:      opts.WebsocketHostname,
 		HttpClientTimeout:      opts.HttpClientTimeout,
+		AuthScheme:             opts.AuthScheme,
 		WebsocketRetryInterval: opts.WebsocketRetryInterval,
+		HeartbeatTimeout:       opts.HeartbeatTimeout,
 		MaxTimeAdjustment:      opts.MaxTimeAdjustment,
 		MaxFetchTimeLatency:    opts.MaxFetchTimeLatency,
 	}
@@ -89,12 +135,15 @@ func New(ctx context.Context, db kv.Database, key, secret string, opts *Options)
 		opts:      *opts,
 		client:    client,
 		datastore: NewDatastore(db),
+		fillsCh:   make(chan *fillsRequest, fillsQueueSize),
 	}
+	client.Go(exchange.goFetchFills)
 
 	// User channel is subscribed for all supported products in a separate
 	// connection from product specific channels.
 	if !opts.subcmdMode {
 		exchange.websocket = client.GetMessages("heartbeats", pids, exchange.dispatchMessage)
+		exchange.websocket.SetConnectHandler(exchange.handleUserConnect)
 		exchange.websocket.Subscribe("user", pids)
 	}
 
@@ -133,6 +182,9 @@ func (ex *Exchange) Close() error {
 }
 
 func (ex *Exchange) ExchangeName() string {
+	if len(ex.opts.Profile) != 0 {
+		return "coinbase:" + ex.opts.Profile
+	}
 	return "coinbase"
 }
 
@@ -258,7 +310,8 @@ func (ex *Exchange) goRunBackgroundTasks(ctx context.Context) {
 		if accounts, err := ex.listRawAccounts(ctx); err != nil {
 			log.Printf("could not fetch account balances (will retry): %v", err)
 		} else {
-			if err := ex.datastore.saveAccounts(ctx, accounts); err != nil {
+			ex.setCachedAccounts(accounts, time.Now())
+			if err := ex.datastore.saveAccounts(ctx, ex.opts.Profile, accounts); err != nil {
 				log.Printf("could not save account balances (will retry): %v", err)
 			}
 		}
@@ -269,7 +322,7 @@ func (ex *Exchange) goRunBackgroundTasks(ctx context.Context) {
 // appropriate product for side-channel handling.
 func (ex *Exchange) dispatchOrder(productID string, order *exchange.Order) {
 	ready := slices.Contains(readyStatuses, order.Status)
-	done := slices.Contains(doneStatuses, order.Status)
+	done := slices.Contains(doneStatuses, order.Status) || order.Done
 
 	if ready || done {
 		if ch, ok := ex.pendingMap.LoadAndDelete(order.ClientOrderID); ok {
@@ -277,6 +330,15 @@ func (ex *Exchange) dispatchOrder(productID string, order *exchange.Order) {
 		}
 	}
 
+	if needsFills(order) && !ex.applyFills(order) {
+		ex.queueFills(productID, order, 0)
+	} else if order.Done {
+		// Completed orders are not updated anymore, so their fills are dropped
+		// once they are dispatched with the fee and liquidity fields.
+		ex.fillsMap.Delete(string(order.OrderID))
+	}
+	ex.updateFees(order)
+
 	ex.clientOrderIDMap.LoadOrStore(order.ClientOrderID, order)
 
 	// Relay the order to the appropriate product.
@@ -285,6 +347,38 @@ func (ex *Exchange) dispatchOrder(productID string, order *exchange.Order) {
 	}
 }
 
+// updateFees computes the last fill fee of the order from the last known
+// cumulative fee. Orders with an unknown fee are skipped till their fee is
+// known from the fills.
+func (ex *Exchange) updateFees(order *exchange.Order) {
+	if order.FilledSize.IsZero() || order.Fee.IsZero() {
+		return
+	}
+
+	last, _ := ex.feeMap.Load(string(order.OrderID))
+	if order.Fee.GreaterThan(last) {
+		order.LastFillFee = order.Fee.Sub(last)
+		ex.feeMap.Store(string(order.OrderID), order.Fee)
+	}
+	if order.Done {
+		ex.feeMap.Delete(string(order.OrderID))
+	}
+}
+
+// handleUserConnect notifies all products when the user channel websocket is
+// reconnected, so that order updates missed during the gap can be resynced.
+func (ex *Exchange) handleUserConnect(nconnects int) {
+	if nconnects < 2 {
+		return
+	}
+	log.Printf("user channel websocket is reconnected (%d connects)", nconnects)
+	now := time.Now()
+	ex.productMap.Range(func(_ string, p *Product) bool {
+		p.prodReconnectTopic.Send(now)
+		return true
+	})
+}
+
 // dispatchMessage relays the websocket message to appropriate product.
 func (ex *Exchange) dispatchMessage(msg *internal.Message) {
 	if msg.Channel == "user" {
@@ -315,13 +409,16 @@ func (ex *Exchange) dispatchMessage(msg *internal.Message) {
 }
 
 func (ex *Exchange) createReadyOrder(ctx context.Context, req *internal.CreateOrderRequest) (*internal.CreateOrderResponse, error) {
+	// Order updates use the client order id without the tag.
+	clientOrderID, _ := splitClientOrderID(req.ClientOrderID)
+
 	statusReadyCh := make(chan struct{})
-	if v, loaded := ex.pendingMap.LoadOrStore(req.ClientOrderID, statusReadyCh); loaded {
+	if v, loaded := ex.pendingMap.LoadOrStore(clientOrderID, statusReadyCh); loaded {
 		log.Printf("unexpected: client id %s already exists in the pending map (previous request may've failed; ignored)", req.ClientOrderID)
 		statusReadyCh = v
 	}
 
-	resp, err := ex.client.CreateOrder(ctx, req)
+	resp, err := ex.createOrder(ctx, req)
 
 	if err == nil && resp.Success {
 		// Wait for the order-id to be *ready*. We cannot cancel an order-id unless
@@ -343,6 +440,80 @@ func (ex *Exchange) createReadyOrder(ctx context.Context, req *internal.CreateOr
 	return resp, err
 }
 
+// createOrder creates a new order and retries the request on timeouts and
+// server errors with the same client order id, which is used as an
+// idempotency key by the exchange. A duplicate client order id response is
+// treated as a success by finding the order created by an earlier attempt.
+func (ex *Exchange) createOrder(ctx context.Context, req *internal.CreateOrderRequest) (*internal.CreateOrderResponse, error) {
+	var err error
+	var resp *internal.CreateOrderResponse
+	for i := uint(0); i <= ex.opts.RetryCount; i++ {
+		if i > 0 {
+			ctxutil.Sleep(ctx, time.Duration(1<<(i-1))*time.Second)
+		}
+
+		resp, err = ex.client.CreateOrder(ctx, req)
+		if err == nil {
+			if !resp.Success && isDuplicateClientOrderID(resp) {
+				return ex.findCreatedOrder(ctx, req)
+			}
+			return resp, nil
+		}
+		if !internal.IsRetriable(err) || ctx.Err() != nil {
+			return nil, err
+		}
+		log.Printf("warning: create order with client order id %s has failed with a retriable error (attempt %d): %v", req.ClientOrderID, i+1, err)
+	}
+	return nil, err
+}
+
+func isDuplicateClientOrderID(resp *internal.CreateOrderResponse) bool {
+	if strings.Contains(resp.FailureReason, "DUPLICATE") {
+		return true
+	}
+	if v := resp.ErrorResponse; v != nil {
+		return strings.Contains(v.Error, "DUPLICATE") || strings.Contains(v.NewOrderFailureReason, "DUPLICATE")
+	}
+	return false
+}
+
+// findCreatedOrder returns a successful create order response for an order
+// that was already created with the client order id.
+func (ex *Exchange) findCreatedOrder(ctx context.Context, req *internal.CreateOrderRequest) (*internal.CreateOrderResponse, error) {
+	success := func(orderID string) *internal.CreateOrderResponse {
+		return &internal.CreateOrderResponse{
+			Success: true,
+			OrderID: orderID,
+			SuccessResponse: &internal.CreateOrderSuccessResponse{
+				OrderID:       orderID,
+				ProductID:     req.ProductID,
+				Side:          req.Side,
+				ClientOrderID: req.ClientOrderID,
+			},
+		}
+	}
+
+	clientOrderID, _ := splitClientOrderID(req.ClientOrderID)
+	if old, ok := ex.clientOrderIDMap.Load(clientOrderID); ok {
+		return success(string(old.OrderID)), nil
+	}
+
+	from := ex.client.Now().Time.Add(-time.Hour)
+	for _, status := range []string{"OPEN", "FILLED", "CANCELLED"} {
+		orders, err := ex.listRawOrders(ctx, from, status)
+		if err != nil {
+			return nil, fmt.Errorf("could not list %s orders to find client order id %s: %w", status, req.ClientOrderID, err)
+		}
+		for _, order := range orders {
+			if order.ClientOrderID == req.ClientOrderID {
+				log.Printf("create order request with duplicate client order id %s is resolved to server order id %s", req.ClientOrderID, order.OrderID)
+				return success(order.OrderID), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("could not find the order with duplicate client order id %s: %w", req.ClientOrderID, os.ErrNotExist)
+}
+
 func (ex *Exchange) recreateOldOrder(clientOrderID string) (*exchange.Order, bool) {
 	old, ok := ex.clientOrderIDMap.Load(clientOrderID)
 	if !ok {
@@ -352,9 +523,71 @@ func (ex *Exchange) recreateOldOrder(clientOrderID string) (*exchange.Order, boo
 	return old, true
 }
 
+// TimeSkew returns the measured difference between the local time and the
+// coinbase server time, which is positive when local time is ahead.
+func (ex *Exchange) TimeSkew() time.Duration {
+	return ex.client.TimeSkew()
+}
+
+// FeeTier returns the maker and taker fee percentages for the account's
+// current fee tier. Fee tier is cached for FeeTierCacheTTL duration.
+func (ex *Exchange) FeeTier(ctx context.Context) (maker, taker decimal.Decimal, err error) {
+	ex.feeTierMu.Lock()
+	defer ex.feeTierMu.Unlock()
+
+	if !ex.feeTierTime.IsZero() && time.Since(ex.feeTierTime) < ex.opts.FeeTierCacheTTL {
+		return ex.makerFeePct, ex.takerFeePct, nil
+	}
+
+	resp, err := ex.client.GetTransactionSummary(ctx)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("could not fetch transaction summary: %w", err)
+	}
+	d100 := decimal.NewFromInt(100)
+	ex.makerFeePct = resp.FeeTier.MakerFeeRate.Decimal.Mul(d100)
+	ex.takerFeePct = resp.FeeTier.TakerFeeRate.Decimal.Mul(d100)
+	ex.feeTierTime = time.Now()
+	log.Printf("fee tier %q has maker fee %s%% and taker fee %s%%", resp.FeeTier.PricingTier, ex.makerFeePct, ex.takerFeePct)
+	return ex.makerFeePct, ex.takerFeePct, nil
+}
+
+// CheckAuth verifies that the api credentials are accepted by the exchange and
+// returns the account, permissions and the fee tier of the credentials.
+func (ex *Exchange) CheckAuth(ctx context.Context) (*exchange.AuthInfo, error) {
+	perms, err := ex.client.GetKeyPermissions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch api key permissions: %w", err)
+	}
+	summary, err := ex.client.GetTransactionSummary(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch transaction summary: %w", err)
+	}
+	info := &exchange.AuthInfo{
+		AccountID:   perms.PortfolioUUID,
+		AccountType: perms.PortfolioType,
+		PricingTier: summary.FeeTier.PricingTier,
+		MakerFeePct: summary.FeeTier.MakerFeeRate.Decimal.Mul(decimal.NewFromInt(100)),
+		TakerFeePct: summary.FeeTier.TakerFeeRate.Decimal.Mul(decimal.NewFromInt(100)),
+	}
+	if perms.CanView {
+		info.Permissions = append(info.Permissions, "view")
+	}
+	if perms.CanTrade {
+		info.Permissions = append(info.Permissions, "trade")
+	}
+	if perms.CanTransfer {
+		info.Permissions = append(info.Permissions, "transfer")
+	}
+	return info, nil
+}
+
 func (ex *Exchange) GetOrder(ctx context.Context, orderID exchange.OrderID) (*exchange.Order, error) {
 	if v, err := ex.datastore.GetOrder(ctx, string(orderID)); err == nil {
-		return exchangeOrderFromOrder(v), nil
+		order := exchangeOrderFromOrder(v)
+		if needsFills(order) && !ex.applyFills(order) {
+			ex.queueFills(v.ProductID, order, 0)
+		}
+		return order, nil
 	}
 	resp, err := ex.client.GetOrder(ctx, string(orderID))
 	if err != nil {
@@ -415,12 +648,35 @@ func (ex *Exchange) listFillsFrom(ctx context.Context, from time.Time) ([]*inter
 	return result, nil
 }
 
+func (ex *Exchange) listOrderFills(ctx context.Context, orderID string) ([]*internal.Fill, error) {
+	var result []*internal.Fill
+
+	values := make(url.Values)
+	values.Add("order_id", orderID)
+	for i := 0; i == 0 || values != nil; i++ {
+		resp, cont, err := ex.client.ListFills(ctx, values)
+		if err != nil {
+			return nil, err
+		}
+		values = cont
+
+		for _, fill := range resp.Fills {
+			if fill != nil && fill.OrderID == orderID {
+				result = append(result, fill)
+			}
+		}
+	}
+	return result, nil
+}
+
 func (ex *Exchange) listRawOrders(ctx context.Context, from time.Time, status string) ([]*internal.Order, error) {
 	var result []*internal.Order
 
 	values := make(url.Values)
 	values.Add("limit", "100")
-	values.Add("start_date", from.UTC().Format(time.RFC3339))
+	if !from.IsZero() {
+		values.Add("start_date", from.UTC().Format(time.RFC3339))
+	}
 	values.Add("order_status", status)
 	for i := 0; i == 0 || values != nil; i++ {
 		resp, cont, err := ex.client.ListOrders(ctx, values)
@@ -453,6 +709,42 @@ func (ex *Exchange) ListOrders(ctx context.Context, from time.Time, status strin
 	return orders, nil
 }
 
+// ListProductOrders returns the orders of a product in all statuses that are
+// created after the from time. Zero from time lists all orders.
+func (ex *Exchange) ListProductOrders(ctx context.Context, productID string, from time.Time) ([]*exchange.Order, error) {
+	var orders []*exchange.Order
+	for _, status := range readyStatuses {
+		rorders, err := ex.listRawOrders(ctx, from, status)
+		if err != nil {
+			return nil, fmt.Errorf("could not list raw %s orders: %w", status, err)
+		}
+		for _, order := range rorders {
+			if order.ProductID != productID {
+				continue
+			}
+			orders = append(orders, exchangeOrderFromOrder(order))
+		}
+	}
+	return orders, nil
+}
+
+// ListOpenOrders returns all open orders of a product, irrespective of their
+// creation time.
+func (ex *Exchange) ListOpenOrders(ctx context.Context, productID string) ([]*exchange.Order, error) {
+	rorders, err := ex.listRawOrders(ctx, time.Time{}, "OPEN")
+	if err != nil {
+		return nil, fmt.Errorf("could not list raw open orders: %w", err)
+	}
+	var orders []*exchange.Order
+	for _, order := range rorders {
+		if order.ProductID != productID {
+			continue
+		}
+		orders = append(orders, exchangeOrderFromOrder(order))
+	}
+	return orders, nil
+}
+
 func (ex *Exchange) listRawAccounts(ctx context.Context) ([]*internal.Account, error) {
 	var accounts []*internal.Account
 
@@ -469,6 +761,32 @@ func (ex *Exchange) listRawAccounts(ctx context.Context) ([]*internal.Account, e
 	return accounts, nil
 }
 
+// cachedAccounts returns the account balances, which are fetched from the
+// exchange only when the cached balances are older than AccountsCacheTTL.
+func (ex *Exchange) cachedAccounts(ctx context.Context) ([]*internal.Account, error) {
+	ex.accountsMu.Lock()
+	defer ex.accountsMu.Unlock()
+
+	if !ex.accountsTime.IsZero() && time.Since(ex.accountsTime) < ex.opts.AccountsCacheTTL {
+		return ex.accounts, nil
+	}
+	accounts, err := ex.listRawAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ex.accounts, ex.accountsTime = accounts, time.Now()
+	return accounts, nil
+}
+
+func (ex *Exchange) setCachedAccounts(accounts []*internal.Account, at time.Time) {
+	ex.accountsMu.Lock()
+	defer ex.accountsMu.Unlock()
+
+	if at.After(ex.accountsTime) {
+		ex.accounts, ex.accountsTime = accounts, at
+	}
+}
+
 func (ex *Exchange) GetProduct(ctx context.Context, productID string) (*gobs.Product, error) {
 	resp, err := ex.client.GetProduct(ctx, productID)
 	if err != nil {
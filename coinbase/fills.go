@@ -0,0 +1,135 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package coinbase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/bvk/tradebot/coinbase/internal"
+	"github.com/bvk/tradebot/exchange"
+	"github.com/shopspring/decimal"
+)
+
+// fillsQueueSize is the max number of orders waiting for their fills to be
+// listed. Orders are dropped when the queue is full, in which case they are
+// queued again with their next update.
+const fillsQueueSize = 1024
+
+// maxFillsRetries is the max number of times fills of a completed order are
+// listed again when they don't cover the order's filled size yet.
+const maxFillsRetries = 3
+
+// orderFills holds the fee and liquidity computed from an order's fills.
+//
+// Coinbase fills and orders do not report the commission currency; fees are
+// always charged in the quote currency, which is already set on the orders
+// from the product id, so fills do not carry a fee currency.
+type orderFills struct {
+	size      decimal.Decimal
+	fee       decimal.Decimal
+	liquidity string
+}
+
+// fillsRequest is an order waiting for it's fills to be listed.
+type fillsRequest struct {
+	productID string
+	order     *exchange.Order
+	retries   int
+}
+
+// needsFills returns true if the order has fills, but it's fee or the
+// liquidity indicator (for the completed orders) is unknown.
+func needsFills(order *exchange.Order) bool {
+	if order.FilledSize.IsZero() {
+		return false
+	}
+	return order.Fee.IsZero() || (order.Done && order.Liquidity == "")
+}
+
+// applyFills sets the missing fee and liquidity fields of the order from it's
+// listed fills, if they are known. Returns false if the fills are not known or
+// do not cover the order's filled size.
+func (ex *Exchange) applyFills(order *exchange.Order) bool {
+	v, ok := ex.fillsMap.Load(string(order.OrderID))
+	if !ok || !v.size.Equal(order.FilledSize) {
+		return false
+	}
+	if order.Fee.IsZero() {
+		order.Fee = v.fee
+	}
+	if order.Done && order.Liquidity == "" {
+		order.Liquidity = v.liquidity
+	}
+	return true
+}
+
+// queueFills schedules the order's fills to be listed in the background, so
+// that the callers, like the websocket message dispatch, are not blocked on
+// the REST calls. Order is dispatched again when it's fills are known.
+func (ex *Exchange) queueFills(productID string, order *exchange.Order, retries int) {
+	if _, loaded := ex.fillsPending.LoadOrStore(string(order.OrderID), true); loaded {
+		return
+	}
+	dup := *order
+	select {
+	case ex.fillsCh <- &fillsRequest{productID: productID, order: &dup, retries: retries}:
+	default:
+		ex.fillsPending.Delete(string(order.OrderID))
+		log.Printf("warning: fills queue is full (order %s is not queued)", order.OrderID)
+	}
+}
+
+// goFetchFills lists the fills for the queued orders.
+func (ex *Exchange) goFetchFills(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case req := <-ex.fillsCh:
+			fctx, cancel := context.WithTimeout(ctx, ex.opts.HttpClientTimeout)
+			fills, err := ex.listOrderFills(fctx, string(req.order.OrderID))
+			cancel()
+			if err != nil {
+				ex.fillsPending.Delete(string(req.order.OrderID))
+				log.Printf("warning: could not list fills for order %s (will retry with the next update): %v", req.order.OrderID, err)
+				continue
+			}
+			ex.handleFills(req, fills)
+		}
+	}
+}
+
+// handleFills records the order's fills and dispatches the order again with
+// the fee and liquidity fields from the fills.
+func (ex *Exchange) handleFills(req *fillsRequest, fills []*internal.Fill) {
+	id := string(req.order.OrderID)
+	v := &orderFills{
+		fee:       sumFillFees(fills),
+		liquidity: fillsLiquidity(fills),
+	}
+	for _, fill := range fills {
+		v.size = v.size.Add(fill.Size.Decimal)
+	}
+	ex.fillsMap.Store(id, v)
+	ex.fillsPending.Delete(id)
+
+	order := *req.order
+	if !ex.applyFills(&order) {
+		// Fills may not be listed immediately after the final order update, so
+		// completed orders are retried a few times. Partially filled orders are
+		// queued again by their next update.
+		if order.Done && req.retries < maxFillsRetries {
+			ex.client.AfterDurationFunc(time.Second, func(context.Context) {
+				ex.queueFills(req.productID, req.order, req.retries+1)
+			})
+			return
+		}
+		if order.Done {
+			ex.fillsMap.Delete(id)
+		}
+		return
+	}
+	ex.dispatchOrder(req.productID, &order)
+}
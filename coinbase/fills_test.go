@@ -0,0 +1,100 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package coinbase
+
+import (
+	"testing"
+
+	"github.com/bvk/tradebot/coinbase/internal"
+	"github.com/bvk/tradebot/exchange"
+	"github.com/shopspring/decimal"
+)
+
+func TestDispatchOrderQueuesFills(t *testing.T) {
+	d := decimal.RequireFromString
+	ex := &Exchange{fillsCh: make(chan *fillsRequest, 2)}
+
+	update := func(size string, done bool) *exchange.Order {
+		return &exchange.Order{OrderID: "order-1", ClientOrderID: "client-1", FilledSize: d(size), FeeCurrency: "USD", Status: "OPEN", Done: done}
+	}
+
+	// Orders without the fee are dispatched immediately and queued only once
+	// for the fills.
+	first := update("1", false)
+	ex.dispatchOrder("BTC-USD", first)
+	ex.dispatchOrder("BTC-USD", update("1", false))
+	if n := len(ex.fillsCh); n != 1 {
+		t.Fatalf("want one queued fills request, got %d", n)
+	}
+	if !first.Fee.IsZero() || !first.LastFillFee.IsZero() {
+		t.Fatalf("want unknown fee before the fills are listed, got %s", first.Fee)
+	}
+
+	req := <-ex.fillsCh
+	fills := []*internal.Fill{
+		{OrderID: "order-1", ProductID: "BTC-USD", Size: exchange.NullDecimal{Decimal: d("0.4")}, Commission: exchange.NullDecimal{Decimal: d("0.1")}, LiquidityIndicator: "MAKER"},
+		{OrderID: "order-1", ProductID: "BTC-USD", Size: exchange.NullDecimal{Decimal: d("0.6")}, Commission: exchange.NullDecimal{Decimal: d("0.15")}, LiquidityIndicator: "MAKER"},
+	}
+	ex.handleFills(req, fills)
+
+	done := update("1", true)
+	ex.dispatchOrder("BTC-USD", done)
+	if n := len(ex.fillsCh); n != 0 {
+		t.Fatalf("want no fills requests for known fills, got %d", n)
+	}
+	if !done.Fee.Equal(d("0.25")) || done.FeeCurrency != "USD" {
+		t.Fatalf("want fee 0.25 USD from the fills, got %s %s", done.Fee, done.FeeCurrency)
+	}
+	if done.Liquidity != exchange.LiquidityMaker {
+		t.Fatalf("want maker liquidity from the fills, got %q", done.Liquidity)
+	}
+	if _, ok := ex.fillsMap.Load("order-1"); ok {
+		t.Fatalf("want fills of the completed order to be dropped after dispatch")
+	}
+
+	// Fills do not cover a later fill, so the order is queued again.
+	ex.handleFills(&fillsRequest{productID: "BTC-USD", order: update("1", false)}, fills)
+	ex.dispatchOrder("BTC-USD", &exchange.Order{OrderID: "order-1", ClientOrderID: "client-1", FilledSize: d("2"), Status: "OPEN"})
+	if n := len(ex.fillsCh); n != 1 {
+		t.Fatalf("want fills requested again for a new fill, got %d", n)
+	}
+}
+
+func TestHandleFillsDropsStaleFills(t *testing.T) {
+	d := decimal.RequireFromString
+	ex := &Exchange{fillsCh: make(chan *fillsRequest, 1)}
+
+	// Fills that never cover a completed order are dropped after the last
+	// retry instead of being held forever.
+	order := &exchange.Order{OrderID: "order-1", ClientOrderID: "client-1", FilledSize: d("1"), Status: "FILLED", Done: true}
+	fills := []*internal.Fill{
+		{OrderID: "order-1", ProductID: "BTC-USD", Size: exchange.NullDecimal{Decimal: d("0.4")}, Commission: exchange.NullDecimal{Decimal: d("0.1")}, LiquidityIndicator: "MAKER"},
+	}
+	ex.handleFills(&fillsRequest{productID: "BTC-USD", order: order, retries: maxFillsRetries}, fills)
+	if _, ok := ex.fillsMap.Load("order-1"); ok {
+		t.Fatalf("want partial fills of the completed order to be dropped after the last retry")
+	}
+}
+
+func TestOrderFeeCurrency(t *testing.T) {
+	// Coinbase doesn't report the commission currency, so fees are in the
+	// quote currency of the product.
+	order := exchangeOrderFromOrder(&internal.Order{OrderID: "order-1", ProductID: "BTC-EUR", Status: "FILLED"})
+	if order.FeeCurrency != "EUR" {
+		t.Fatalf("want quote currency as the fee currency for orders, got %q", order.FeeCurrency)
+	}
+	event := exchangeOrderFromEvent(&internal.OrderEvent{OrderID: "order-1", ProductID: "BTC-EUR", Status: "FILLED"})
+	if event.FeeCurrency != "EUR" {
+		t.Fatalf("want quote currency as the fee currency for order events, got %q", event.FeeCurrency)
+	}
+
+	// Fills do not change the fee currency of the orders.
+	d := decimal.RequireFromString
+	ex := &Exchange{fillsCh: make(chan *fillsRequest, 1)}
+	req := &fillsRequest{productID: "BTC-EUR", order: &exchange.Order{OrderID: "order-1", FilledSize: d("1"), FeeCurrency: "EUR", Status: "OPEN"}}
+	ex.handleFills(req, []*internal.Fill{{OrderID: "order-1", ProductID: "BTC-EUR", Size: exchange.NullDecimal{Decimal: d("1")}, Commission: exchange.NullDecimal{Decimal: d("0.1")}}})
+	filled := &exchange.Order{OrderID: "order-1", FilledSize: d("1"), FeeCurrency: "EUR", Status: "OPEN"}
+	if !ex.applyFills(filled) || !filled.Fee.Equal(d("0.1")) || filled.FeeCurrency != "EUR" {
+		t.Fatalf("want fee 0.1 EUR from the fills, got %s %s", filled.Fee, filled.FeeCurrency)
+	}
+}
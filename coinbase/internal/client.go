@@ -5,17 +5,21 @@ package internal
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"os"
 	"path"
 	"sync/atomic"
 	"time"
@@ -25,6 +29,20 @@ import (
 	"golang.org/x/time/rate"
 )
 
+// ErrServerError is returned when the server responds with a 5xx status code.
+// It wraps exchange.ErrTransient.
+var ErrServerError = fmt.Errorf("server error: %w", exchange.ErrTransient)
+
+// IsRetriable returns true if the error is a network timeout or a server side
+// error, where the request may or may not have been processed by the server.
+func IsRetriable(err error) bool {
+	if errors.Is(err, ErrServerError) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var nerr net.Error
+	return errors.As(err, &nerr) && nerr.Timeout()
+}
+
 type Client struct {
 	cg ctxutil.CloseGroup
 
@@ -33,14 +51,18 @@ type Client struct {
 	key    string
 	secret []byte
 
+	// ecKey is the private key used to sign the JWTs when JWT auth scheme is
+	// used.
+	ecKey *ecdsa.PrivateKey
+
 	client *http.Client
 
 	limiter *rate.Limiter
 
 	// timeAdjustment is positive when local time is found to be ahead of the
-	// server time, in which case, this value must be subtracted from the local
-	// time before the local time can be used as a timestamp in the signature
-	// calculations.
+	// server time and negative when it is behind. This value must be
+	// subtracted from the local time before the local time can be used as a
+	// timestamp in the signature calculations.
 	timeAdjustment atomic.Int64
 }
 
@@ -51,13 +73,17 @@ func New(ctx context.Context, key, secret string, opts *Options) (*Client, error
 	}
 	opts.setDefaults()
 
+	if opts.AuthScheme != HMACAuth && opts.AuthScheme != JWTAuth {
+		return nil, fmt.Errorf("unsupported auth scheme %q: %w", opts.AuthScheme, os.ErrInvalid)
+	}
+
 	adjustment, err := findTimeAdjustment(ctx, opts.MaxFetchTimeLatency)
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("local time needs to be adjusted by -%s to match the coinbase server time", adjustment)
-	if adjustment > opts.MaxTimeAdjustment {
-		return nil, fmt.Errorf("local time is out-of-sync by large amount with the server time")
+	log.Printf("local time needs to be adjusted by %s to match the coinbase server time", -adjustment)
+	if absDuration(adjustment) > opts.MaxTimeAdjustment {
+		return nil, fmt.Errorf("local time is out-of-sync by %s with the server time (max %s)", adjustment, opts.MaxTimeAdjustment)
 	}
 
 	jar, err := cookiejar.New(nil /* options */)
@@ -76,6 +102,14 @@ func New(ctx context.Context, key, secret string, opts *Options) (*Client, error
 		limiter: rate.NewLimiter(25, 1),
 	}
 
+	if opts.AuthScheme == JWTAuth {
+		key, err := parseECPrivateKey(secret)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse jwt private key: %w", err)
+		}
+		c.ecKey = key
+	}
+
 	c.timeAdjustment.Store(int64(adjustment))
 	c.cg.Go(c.goFindTimeAdjustment)
 	return c, nil
@@ -89,10 +123,19 @@ func (c *Client) Close() error {
 
 func (c *Client) goFindTimeAdjustment(ctx context.Context) {
 	for ctxutil.Sleep(ctx, c.opts.SyncTimeInterval); ctx.Err() == nil; ctxutil.Sleep(ctx, c.opts.SyncTimeInterval) {
-		if diff, err := findTimeAdjustment(ctx, c.opts.MaxFetchTimeLatency); err == nil && diff != 0 {
-			log.Printf("local time needs to be adjusted by -%s to match the coinbase server time", diff)
-			c.timeAdjustment.Store(int64(diff))
+		diff, err := findTimeAdjustment(ctx, c.opts.MaxFetchTimeLatency)
+		if err != nil {
+			log.Printf("could not sync time with the coinbase server (will retry): %v", err)
+			continue
 		}
+		// Server time is used for signing even when the skew is large, so that
+		// requests continue to work, but the clock needs to be fixed.
+		if absDuration(diff) > c.opts.MaxTimeAdjustment {
+			log.Printf("WARNING: local time is out-of-sync by %s (> %s) with the coinbase server time; check the ntp service", diff, c.opts.MaxTimeAdjustment)
+		} else if diff != 0 {
+			log.Printf("local time needs to be adjusted by %s to match the coinbase server time", -diff)
+		}
+		c.timeAdjustment.Store(int64(diff))
 	}
 }
 
@@ -105,14 +148,20 @@ func findTimeAdjustment(ctx context.Context, maxLatency time.Duration) (time.Dur
 		start := time.Now()
 		resp, err := http.Get("https://api.exchange.coinbase.com/time")
 		stop := time.Now()
+		if err != nil {
+			log.Printf("warning: could not get coinbase server time (will retry): %v", err)
+			continue
+		}
 
 		latency := stop.Sub(start)
 		if latency > maxLatency {
+			resp.Body.Close()
 			log.Printf("warning: get coinbase server time took %s > %s (too long; will retry)", latency, maxLatency)
 			continue // retry
 		}
 
 		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
 		if err != nil {
 			return 0, fmt.Errorf("could not ready server time response: %w", err)
 		}
@@ -130,16 +179,25 @@ func findTimeAdjustment(ctx context.Context, maxLatency time.Duration) (time.Dur
 		ltime := start.Add(latency / 2).UTC()
 		adjust := ltime.Sub(stime)
 		// log.Println("localtime", ltime, "servertime", stime, "latency", latency, "diff", adjust)
-
-		if adjust < 0 {
-			return 0, nil
-		}
 		return adjust, nil
 	}
 
 	return 0, context.Cause(ctx)
 }
 
+// TimeSkew returns the measured difference between the local time and the
+// coinbase server time. It is positive when local time is ahead.
+func (c *Client) TimeSkew() time.Duration {
+	return time.Duration(c.timeAdjustment.Load())
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
 func (c *Client) Now() exchange.RemoteTime {
 	return exchange.RemoteTime{Time: time.Now().Add(time.Duration(-c.timeAdjustment.Load()))}
 }
@@ -156,18 +214,13 @@ func (c *Client) sign(message string) string {
 }
 
 func (c *Client) getJSON(ctx context.Context, url *url.URL, result interface{}) error {
-	at := fmt.Sprintf("%d", c.Now().Unix())
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
 	if err != nil {
 		return err
 	}
-	sdata := fmt.Sprintf("%s%s%s%s", at, req.Method, url.Path, "")
-	signature := c.sign(sdata)
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Cache-Control", "no-store")
-	req.Header.Add("CB-ACCESS-KEY", c.key)
-	req.Header.Add("CB-ACCESS-SIGN", signature)
-	req.Header.Add("CB-ACCESS-TIMESTAMP", at)
+	if err := c.authorize(req, nil); err != nil {
+		return err
+	}
 	if err := c.limiter.Wait(ctx); err != nil {
 		return err
 	}
@@ -182,22 +235,19 @@ func (c *Client) getJSON(ctx context.Context, url *url.URL, result interface{})
 			return c.getJSON(ctx, url, result)
 		}
 		slog.Error("http GET is unsuccessful", "status", resp.StatusCode, "url", url.String())
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("http GET returned %d: %w", resp.StatusCode, exchange.ErrNotFound)
+		}
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("http GET returned %d: %w", resp.StatusCode, ErrServerError)
+		}
 		return fmt.Errorf("http GET returned %d", resp.StatusCode)
 	}
-	var body io.Reader = resp.Body
-
-	// data, err := io.ReadAll(resp.Body)
-	// if err != nil {
-	// 	return err
-	// }
-	// log.Printf("response body: %s", data)
-	// body = bytes.NewReader(data)
-
-	if err := json.NewDecoder(body).Decode(result); err != nil {
-		slog.Error("could not decode response to json", "error", err)
-		return err
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("could not read http GET response: %w", err)
 	}
-	return nil
+	return decodeJSON(data, result)
 }
 
 func (c *Client) postJSON(ctx context.Context, url *url.URL, request, resultPtr interface{}) error {
@@ -205,18 +255,13 @@ func (c *Client) postJSON(ctx context.Context, url *url.URL, request, resultPtr
 	if err != nil {
 		return err
 	}
-	at := fmt.Sprintf("%d", c.Now().Unix())
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url.String(), bytes.NewReader(payload))
 	if err != nil {
 		return err
 	}
-	sdata := fmt.Sprintf("%s%s%s%s", at, req.Method, url.Path, payload)
-	signature := c.sign(sdata)
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Cache-Control", "no-store")
-	req.Header.Add("CB-ACCESS-KEY", c.key)
-	req.Header.Add("CB-ACCESS-SIGN", signature)
-	req.Header.Add("CB-ACCESS-TIMESTAMP", at)
+	if err := c.authorize(req, payload); err != nil {
+		return err
+	}
 	if err := c.limiter.Wait(ctx); err != nil {
 		return err
 	}
@@ -235,20 +280,32 @@ func (c *Client) postJSON(ctx context.Context, url *url.URL, request, resultPtr
 			return c.postJSON(ctx, url, request, resultPtr)
 		}
 		slog.Error("http POST is unsuccessful", "status", resp.StatusCode)
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("http POST returned %d: %w", resp.StatusCode, ErrServerError)
+		}
 		return fmt.Errorf("http POST returned %d", resp.StatusCode)
 	}
-	var body io.Reader = resp.Body
-	/////
-	// data, err := ioutil.ReadAll(resp.Body)
-	// if err != nil {
-	// 	return err
-	// }
-	// slog.Info("response body", "data", data)
-	// body = bytes.NewReader(data)
-	/////
-	if err := json.NewDecoder(body).Decode(resultPtr); err != nil {
-		slog.Error("could not decode response to json", "error", err)
-		return err
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("could not read http POST response: %w", err)
+	}
+	return decodeJSON(data, resultPtr)
+}
+
+// decodeJSON decodes the response payload into the result and validates it
+// when the result implements a Check method, so that malformed numeric fields
+// fail the request instead of silently turning into zero values. Raw payload
+// is logged on failures.
+func decodeJSON(data []byte, result any) error {
+	if err := json.Unmarshal(data, result); err != nil {
+		slog.Error("could not decode response to json", "error", err, "payload", string(data))
+		return fmt.Errorf("could not decode response: %w", err)
+	}
+	if v, ok := result.(interface{ Check() error }); ok {
+		if err := v.Check(); err != nil {
+			slog.Error("response has invalid fields", "error", err, "payload", string(data))
+			return fmt.Errorf("invalid response: %w", err)
+		}
 	}
 	return nil
 }
@@ -259,18 +316,13 @@ func (c *Client) Do(ctx context.Context, method string, url *url.URL, payload in
 		return nil, err
 	}
 
-	at := fmt.Sprintf("%d", c.Now().Unix())
 	req, err := http.NewRequestWithContext(ctx, method, url.String(), bytes.NewReader(data))
 	if err != nil {
 		return nil, err
 	}
-	sdata := fmt.Sprintf("%s%s%s%s", at, req.Method, url.Path, data)
-	signature := c.sign(sdata)
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Cache-Control", "no-store")
-	req.Header.Add("CB-ACCESS-KEY", c.key)
-	req.Header.Add("CB-ACCESS-SIGN", signature)
-	req.Header.Add("CB-ACCESS-TIMESTAMP", at)
+	if err := c.authorize(req, data); err != nil {
+		return nil, err
+	}
 
 	if err := c.limiter.Wait(ctx); err != nil {
 		return nil, err
@@ -366,6 +418,32 @@ func (c *Client) ListOrders(ctx context.Context, values url.Values) (_ *ListOrde
 	return resp, nil, nil
 }
 
+func (c *Client) GetTransactionSummary(ctx context.Context) (*GetTransactionSummaryResponse, error) {
+	url := &url.URL{
+		Scheme: "https",
+		Host:   c.opts.RestHostname,
+		Path:   "/api/v3/brokerage/transaction_summary",
+	}
+	resp := new(GetTransactionSummaryResponse)
+	if err := c.getJSON(ctx, url, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) GetKeyPermissions(ctx context.Context) (*GetKeyPermissionsResponse, error) {
+	url := &url.URL{
+		Scheme: "https",
+		Host:   c.opts.RestHostname,
+		Path:   "/api/v3/brokerage/key_permissions",
+	}
+	resp := new(GetKeyPermissionsResponse)
+	if err := c.getJSON(ctx, url, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 func (c *Client) GetProduct(ctx context.Context, productID string) (*GetProductResponse, error) {
 	url := &url.URL{
 		Scheme: "https",
@@ -422,6 +500,19 @@ func (c *Client) CancelOrder(ctx context.Context, request *CancelOrderRequest) (
 	return resp, nil
 }
 
+func (c *Client) EditOrder(ctx context.Context, request *EditOrderRequest) (*EditOrderResponse, error) {
+	url := &url.URL{
+		Scheme: "https",
+		Host:   c.opts.RestHostname,
+		Path:   "/api/v3/brokerage/orders/edit",
+	}
+	resp := new(EditOrderResponse)
+	if err := c.postJSON(ctx, url, request, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 func (c *Client) GetProductCandles(ctx context.Context, productID string, values url.Values) (*GetProductCandlesResponse, error) {
 	url := &url.URL{
 		Scheme:   "https",
@@ -72,3 +72,29 @@ func TestClient(t *testing.T) {
 		t.Logf("%s", js)
 	}
 }
+
+func TestDecodeJSONMalformedOrder(t *testing.T) {
+	testCases := []struct {
+		payload string
+		valid   bool
+	}{
+		{`{"order":{"order_id":"a","status":"FILLED","number_of_fills":"1","filled_size":"0.5","average_filled_price":"100"}}`, true},
+		{`{"order":{"order_id":"b","status":"OPEN","number_of_fills":"0","filled_size":"","average_filled_price":""}}`, true},
+		{`{"order":{"order_id":"c","status":"FILLED","number_of_fills":"1","filled_size":"abc","average_filled_price":"100"}}`, false},
+		{`{"order":{"order_id":"d","status":"FILLED","number_of_fills":"1","filled_size":"","average_filled_price":"100"}}`, false},
+		{`{"order":{"order_id":"e","status":"CANCELLED","number_of_fills":"2","filled_size":"0.5","average_filled_price":null}}`, false},
+		{`{"order":{"order_id":"f","status":"OPEN","number_of_fills":"x"}}`, false},
+		{`{}`, false},
+	}
+
+	for i, tc := range testCases {
+		resp := new(GetOrderResponse)
+		err := decodeJSON([]byte(tc.payload), resp)
+		if tc.valid && err != nil {
+			t.Errorf("%d: want success, got %v", i, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("%d: want failure for payload %s, got success", i, tc.payload)
+		}
+	}
+}
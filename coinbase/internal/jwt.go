@@ -0,0 +1,128 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+)
+
+const (
+	HMACAuth = "hmac"
+	JWTAuth  = "jwt"
+)
+
+// parseECPrivateKey parses an EC private key in the PEM format (SEC 1 or
+// PKCS #8) as given by the Coinbase Developer Platform.
+func parseECPrivateKey(secret string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(secret))
+	if block == nil {
+		return nil, fmt.Errorf("could not decode private key pem: %w", os.ErrInvalid)
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	v, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse private key: %w", err)
+	}
+	key, ok := v.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an ecdsa key: %w", os.ErrInvalid)
+	}
+	return key, nil
+}
+
+// makeJWT returns an ES256 signed JWT for the given request uri. Websocket
+// messages use an empty uri.
+func (c *Client) makeJWT(uri string) (string, error) {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return "", fmt.Errorf("could not generate nonce: %w", err)
+	}
+
+	header := map[string]any{
+		"alg":   "ES256",
+		"typ":   "JWT",
+		"kid":   c.key,
+		"nonce": hex.EncodeToString(nonce[:]),
+	}
+	now := c.Now().Unix()
+	claims := map[string]any{
+		"sub": c.key,
+		"iss": "cdp",
+		"nbf": now,
+		"exp": now + 120,
+	}
+	if len(uri) != 0 {
+		claims["uri"] = uri
+	}
+
+	hdata, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	cdata, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(hdata) + "." + enc.EncodeToString(cdata)
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, c.ecKey, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("could not sign the jwt: %w", err)
+	}
+
+	// ES256 signature is a fixed size concatenation of R and S values.
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// websocketJWT returns a JWT for the websocket messages. Returns an empty
+// string if the JWT could not be created.
+func (c *Client) websocketJWT() string {
+	token, err := c.makeJWT("")
+	if err != nil {
+		log.Printf("could not create jwt for the websocket message (ignored): %v", err)
+		return ""
+	}
+	return token
+}
+
+// authorize adds the authentication headers to the request.
+func (c *Client) authorize(req *http.Request, payload []byte) error {
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Cache-Control", "no-store")
+
+	if c.opts.AuthScheme == JWTAuth {
+		uri := fmt.Sprintf("%s %s%s", req.Method, req.URL.Host, req.URL.Path)
+		token, err := c.makeJWT(uri)
+		if err != nil {
+			return err
+		}
+		req.Header.Add("Authorization", "Bearer "+token)
+		return nil
+	}
+
+	at := fmt.Sprintf("%d", c.Now().Unix())
+	sdata := fmt.Sprintf("%s%s%s%s", at, req.Method, req.URL.Path, payload)
+	signature := c.sign(sdata)
+	req.Header.Add("CB-ACCESS-KEY", c.key)
+	req.Header.Add("CB-ACCESS-SIGN", signature)
+	req.Header.Add("CB-ACCESS-TIMESTAMP", at)
+	return nil
+}
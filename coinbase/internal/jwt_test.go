@@ -0,0 +1,67 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestJWT(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+
+	ecKey, err := parseECPrivateKey(string(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{key: "test-key-name", ecKey: ecKey}
+
+	token, err := c.makeJWT("GET api.coinbase.com/api/v3/brokerage/accounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("want 3 jwt parts, got %d", len(parts))
+	}
+
+	enc := base64.RawURLEncoding
+	cdata, err := enc.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := make(map[string]any)
+	if err := json.Unmarshal(cdata, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["sub"] != "test-key-name" {
+		t.Fatalf("want sub claim test-key-name, got %v", claims["sub"])
+	}
+
+	sig, err := enc.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+	if !ecdsa.Verify(&key.PublicKey, digest[:], r, s) {
+		t.Fatalf("jwt signature verification failed")
+	}
+}
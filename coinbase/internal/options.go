@@ -17,9 +17,18 @@ type Options struct {
 	// Timeout to use for the HTTP requests.
 	HttpClientTimeout time.Duration
 
+	// AuthScheme selects the authentication scheme for the API keys. It must
+	// be "hmac" (default) for the legacy key and secret or "jwt" for the key
+	// name and EC private key (in PEM format) as the secret.
+	AuthScheme string
+
 	// Timeout interval to create a new websocket session after a failure.
 	WebsocketRetryInterval time.Duration
 
+	// Max time to wait for a websocket message (including heartbeats) before
+	// the connection is considered dead and is reconnected.
+	HeartbeatTimeout time.Duration
+
 	// Max limit for time difference between local time and the server times.
 	MaxTimeAdjustment time.Duration
 
@@ -38,12 +47,18 @@ func (v *Options) setDefaults() {
 	if v.WebsocketHostname == "" {
 		v.WebsocketHostname = WebsocketHostname
 	}
+	if v.AuthScheme == "" {
+		v.AuthScheme = HMACAuth
+	}
 	if v.HttpClientTimeout == 0 {
 		v.HttpClientTimeout = 5 * time.Second
 	}
 	if v.WebsocketRetryInterval == 0 {
 		v.WebsocketRetryInterval = time.Second
 	}
+	if v.HeartbeatTimeout == 0 {
+		v.HeartbeatTimeout = 30 * time.Second
+	}
 	if v.MaxTimeAdjustment == 0 {
 		v.MaxTimeAdjustment = time.Minute
 	}
@@ -4,6 +4,8 @@ package internal
 
 import (
 	"encoding/json"
+	"fmt"
+	"strconv"
 
 	"github.com/bvk/tradebot/exchange"
 )
@@ -71,15 +73,49 @@ type Order struct {
 	IsLiquidation bool   `json:"is_liquidation"`
 	PendingCancel bool   `json:"pending_cancel"`
 	CancelMessage string `json:"cancel_message"`
+
+	OrderConfiguration *OrderConfig `json:"order_configuration"`
+}
+
+// Check returns an error if the order has fills, but the filled size or the
+// average filled price is missing, which would otherwise be treated as zero.
+func (v *Order) Check() error {
+	nfills := int64(0)
+	if len(v.NumberOfFills) != 0 {
+		n, err := strconv.ParseInt(v.NumberOfFills, 10, 64)
+		if err != nil {
+			return fmt.Errorf("order %s has invalid number_of_fills %q: %w", v.OrderID, v.NumberOfFills, err)
+		}
+		nfills = n
+	}
+	if nfills == 0 && v.Status != "FILLED" {
+		return nil
+	}
+	if !v.FilledSize.Decimal.IsPositive() {
+		return fmt.Errorf("order %s with status %s has %d fills, but filled_size is %s", v.OrderID, v.Status, nfills, v.FilledSize.Decimal)
+	}
+	if !v.AvgFilledPrice.Decimal.IsPositive() {
+		return fmt.Errorf("order %s with status %s has %d fills, but average_filled_price is %s", v.OrderID, v.Status, nfills, v.AvgFilledPrice.Decimal)
+	}
+	return nil
 }
 
 type GetOrderResponse struct {
 	Order *Order `json:"order"`
 }
 
+func (v *GetOrderResponse) Check() error {
+	if v.Order == nil {
+		return fmt.Errorf("order field is missing")
+	}
+	return v.Order.Check()
+}
+
+// MarketMarketIOC takes either the QuoteSize or the BaseSize, so the unused
+// field must be omitted.
 type MarketMarketIOC struct {
-	QuoteSize exchange.NullDecimal `json:"quote_size"`
-	BaseSize  exchange.NullDecimal `json:"base_size"`
+	QuoteSize *exchange.NullDecimal `json:"quote_size,omitempty"`
+	BaseSize  *exchange.NullDecimal `json:"base_size,omitempty"`
 }
 
 type LimitLimitGTC struct {
@@ -268,6 +304,15 @@ type ListOrdersResponse struct {
 	HasNext  bool     `json:"has_next"`
 }
 
+func (v *ListOrdersResponse) Check() error {
+	for _, order := range v.Orders {
+		if err := order.Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type CancelOrderRequest struct {
 	OrderIDs []string `json:"order_ids"`
 }
@@ -281,3 +326,42 @@ type CancelOrderResultResponse struct {
 	FailureReason string `json:"failure_reason"`
 	OrderID       string `json:"order_id"`
 }
+
+// EditOrderRequest updates the size and price of an open good-till-cancel
+// limit order. Size is the new total size of the order, including the filled
+// size.
+type EditOrderRequest struct {
+	OrderID string               `json:"order_id"`
+	Price   exchange.NullDecimal `json:"price"`
+	Size    exchange.NullDecimal `json:"size"`
+}
+
+type EditOrderResponse struct {
+	Success bool                `json:"success"`
+	Errors  []*EditOrderFailure `json:"errors"`
+}
+
+type EditOrderFailure struct {
+	EditFailureReason    string `json:"edit_failure_reason"`
+	PreviewFailureReason string `json:"preview_failure_reason"`
+}
+
+type FeeTier struct {
+	PricingTier  string               `json:"pricing_tier"`
+	TakerFeeRate exchange.NullDecimal `json:"taker_fee_rate"`
+	MakerFeeRate exchange.NullDecimal `json:"maker_fee_rate"`
+}
+
+type GetKeyPermissionsResponse struct {
+	CanView       bool   `json:"can_view"`
+	CanTrade      bool   `json:"can_trade"`
+	CanTransfer   bool   `json:"can_transfer"`
+	PortfolioUUID string `json:"portfolio_uuid"`
+	PortfolioType string `json:"portfolio_type"`
+}
+
+type GetTransactionSummaryResponse struct {
+	TotalVolume float64 `json:"total_volume"`
+	TotalFees   float64 `json:"total_fees"`
+	FeeTier     FeeTier `json:"fee_tier"`
+}
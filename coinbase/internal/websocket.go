@@ -31,6 +31,7 @@ type Message struct {
 	APIKey     string   `json:"api_key"`
 	Timestamp  string   `json:"timestamp"`
 	Signature  string   `json:"signature"`
+	JWT        string   `json:"jwt,omitempty"`
 
 	Sequence int64 `json:"sequence_num,number"`
 
@@ -63,6 +64,11 @@ type TickerEvent struct {
 	Low52W      exchange.NullDecimal `json:"low_52_w"`
 	High52W     exchange.NullDecimal `json:"high_52_w"`
 	PricePct24H exchange.NullDecimal `json:"price_percent_chg_24_h"`
+	BestBid     exchange.NullDecimal `json:"best_bid"`
+	BestAsk     exchange.NullDecimal `json:"best_ask"`
+
+	BestBidQuantity exchange.NullDecimal `json:"best_bid_quantity"`
+	BestAskQuantity exchange.NullDecimal `json:"best_ask_quantity"`
 }
 
 type OrderEvent struct {
@@ -87,6 +93,29 @@ type Websocket struct {
 
 	dirty           atomic.Bool
 	chanProductsMap map[string][]string
+
+	// connectHandler when non-nil, is called after every successful websocket
+	// connection with the number of connections made so far.
+	connectHandler func(nconnects int)
+}
+
+// SetConnectHandler sets a callback that is invoked after every successful
+// websocket connection. Callers can use it to detect reconnects, during which
+// some messages may have been missed.
+func (w *Websocket) SetConnectHandler(fn func(nconnects int)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.connectHandler = fn
+}
+
+func (w *Websocket) connected(nconnects int) {
+	w.mu.Lock()
+	fn := w.connectHandler
+	w.mu.Unlock()
+
+	if fn != nil {
+		fn(nconnects)
+	}
 }
 
 func (c *Client) newWebsocket() (_ *Websocket) {
@@ -205,7 +234,14 @@ func (w *Websocket) diff(oldMap map[string][]string) (newMap, subMap, unsubMap m
 	return newMap, subMap, unsubMap
 }
 
-func readMessage(ctx context.Context, conn *websocket.Conn) (*Message, error) {
+// readMessage reads the next message from the websocket. Returns an error if
+// no message is received within the timeout, so that callers can treat the
+// connection as dead.
+func readMessage(ctx context.Context, conn *websocket.Conn, timeout time.Duration) (*Message, error) {
+	if timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+	}
+
 	stopc := make(chan struct{})
 	stop := context.AfterFunc(ctx, func() {
 		conn.SetReadDeadline(time.Now())
@@ -249,6 +285,11 @@ func (c *Client) subscribeMsg(channel string, products []string) *Message {
 		Timestamp:  fmt.Sprintf("%d", c.Now().Unix()),
 		Signature:  "",
 	}
+	if c.opts.AuthScheme == JWTAuth {
+		submsg.APIKey = ""
+		submsg.JWT = c.websocketJWT()
+		return submsg
+	}
 	subdata := fmt.Sprintf("%s%s%s", submsg.Timestamp, submsg.Channel, strings.Join(submsg.ProductIDs, ","))
 	signature := c.sign(subdata)
 	submsg.Signature = signature
@@ -264,6 +305,11 @@ func (c *Client) unsubscribeMsg(channel string, products []string) *Message {
 		Timestamp:  fmt.Sprintf("%d", c.Now().Unix()),
 		Signature:  "",
 	}
+	if c.opts.AuthScheme == JWTAuth {
+		unsubmsg.APIKey = ""
+		unsubmsg.JWT = c.websocketJWT()
+		return unsubmsg
+	}
 	unsubdata := fmt.Sprintf("%s%s%s", unsubmsg.Timestamp, unsubmsg.Channel, strings.Join(unsubmsg.ProductIDs, ","))
 	signature := c.sign(unsubdata)
 	unsubmsg.Signature = signature
@@ -283,6 +329,7 @@ func (c *Client) GetMessages(channel string, products []string, handler MessageH
 		return
 	}
 
+	nconnects := 0
 	dispatch := func(ctx context.Context) error {
 		conn, err := w.dial(ctx)
 		if err != nil {
@@ -291,6 +338,9 @@ func (c *Client) GetMessages(channel string, products []string, handler MessageH
 		}
 		defer conn.Close()
 
+		nconnects++
+		w.connected(nconnects)
+
 		channels := []string{}
 		chanProductsMap := make(map[string][]string)
 
@@ -320,7 +370,7 @@ func (c *Client) GetMessages(channel string, products []string, handler MessageH
 				log.Printf("websocket is updated to watch channels %v from previous %v", channels, oldChannels)
 			}
 
-			msg, err := readMessage(ctx, conn)
+			msg, err := readMessage(ctx, conn, w.client.opts.HeartbeatTimeout)
 			if err != nil {
 				if ctx.Err() == nil {
 					log.Printf("closing the websocket connection to channels %v: %v", channels, err)
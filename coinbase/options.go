@@ -2,13 +2,25 @@
 
 package coinbase
 
-import "time"
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bvk/tradebot/exchange"
+)
 
 var (
 	RestHostname      = "api.coinbase.com"
 	WebsocketHostname = "advanced-trade-ws.coinbase.com"
 )
 
+const (
+	TickerModeWebsocket = "websocket"
+	TickerModeRESTPoll  = "rest-poll"
+	TickerModeAuto      = "auto"
+)
+
 type Options struct {
 	// Hostnames for the REST and WebSocket service endpoints.
 	RestHostname      string
@@ -17,12 +29,21 @@ type Options struct {
 	// Timeout to use for the HTTP requests.
 	HttpClientTimeout time.Duration
 
+	// AuthScheme selects the authentication scheme for the API keys. It must
+	// be "hmac" (default) for the legacy key and secret or "jwt" for the key
+	// name and EC private key (in PEM format) as the secret.
+	AuthScheme string
+
 	// RetryCount indicates number of times to retry using exponential backoff.
 	RetryCount uint
 
 	// Timeout interval to create a new websocket session after a failure.
 	WebsocketRetryInterval time.Duration
 
+	// Max time to wait for a websocket message (including heartbeats) before
+	// the connection is considered dead and is reconnected.
+	HeartbeatTimeout time.Duration
+
 	// Timeout interval to retry list-orders polling operation.
 	PollOrdersRetryInterval time.Duration
 
@@ -42,9 +63,49 @@ type Options struct {
 	// Timeout interval to fetch and save products list in the datastore.
 	FetchProductsInterval time.Duration
 
+	// Max age of the last ticker for a product to be considered alive by the
+	// product's Ping.
+	MaxTickerAge time.Duration
+
+	// Time to cache the fee tier fetched from the exchange.
+	FeeTierCacheTTL time.Duration
+
+	// Time to cache the account balances used to check the available balance
+	// before creating new orders.
+	AccountsCacheTTL time.Duration
+
 	// List of product ids to fetch and save data in the data store.
 	WatchProductIDs []string
 
+	// AllowedProducts when non-empty, restricts the products that can be opened
+	// for trading, so that a mistyped product id cannot place orders on a wrong
+	// market. Empty list allows all products.
+	AllowedProducts []string
+
+	// PriceSource selects the price delivered in the product tickers. It must
+	// be "last-trade" (default) or "mid". Coinbase doesn't publish an index
+	// price for the spot products, so "index" is not supported.
+	PriceSource string
+
+	// TickerMode selects how the product tickers are received. It must be
+	// "websocket" (default), "rest-poll" to poll the product endpoint every
+	// TickerPollInterval or "auto" to poll only while the websocket isn't
+	// delivering any tickers.
+	TickerMode string
+
+	// Interval between the ticker polls in the "rest-poll" and "auto" modes.
+	TickerPollInterval time.Duration
+
+	// UseKeyring when true, allows loading the credentials from the OS keyring
+	// when they are not given in the environment variables.
+	UseKeyring bool
+
+	// Profile when non-empty, is the name of the credential profile for a
+	// sub-account. Exchange is named "coinbase:<profile>" and credentials are
+	// loaded from the profile specific environment variables (see
+	// ProfileCredentialsFromEnv) or the input credentials.
+	Profile string
+
 	subcmdMode bool
 }
 
@@ -61,6 +122,9 @@ func (v *Options) setDefaults() {
 	if v.WebsocketRetryInterval == 0 {
 		v.WebsocketRetryInterval = time.Second
 	}
+	if v.HeartbeatTimeout == 0 {
+		v.HeartbeatTimeout = 30 * time.Second
+	}
 	if v.PollOrdersRetryInterval == 0 {
 		v.PollOrdersRetryInterval = time.Minute
 	}
@@ -79,6 +143,24 @@ func (v *Options) setDefaults() {
 	if v.FetchProductsInterval == 0 {
 		v.FetchProductsInterval = time.Minute
 	}
+	if v.MaxTickerAge == 0 {
+		v.MaxTickerAge = 5 * time.Minute
+	}
+	if v.FeeTierCacheTTL == 0 {
+		v.FeeTierCacheTTL = time.Hour
+	}
+	if v.AccountsCacheTTL == 0 {
+		v.AccountsCacheTTL = 10 * time.Second
+	}
+	if v.PriceSource == "" {
+		v.PriceSource = exchange.PriceSourceLastTrade
+	}
+	if v.TickerMode == "" {
+		v.TickerMode = TickerModeWebsocket
+	}
+	if v.TickerPollInterval == 0 {
+		v.TickerPollInterval = 5 * time.Second
+	}
 	if len(v.WatchProductIDs) == 0 {
 		v.WatchProductIDs = []string{
 			"BTC-USD", "BCH-USD", "ETH-USD", "AVAX-USD","DOGE-USD","SHIB-USD",
@@ -86,6 +168,32 @@ func (v *Options) setDefaults() {
 	}
 }
 
+func (v *Options) check() error {
+	switch v.PriceSource {
+	case exchange.PriceSourceLastTrade, exchange.PriceSourceMid:
+	case exchange.PriceSourceIndex:
+		return fmt.Errorf("index price source is not supported for coinbase spot products: %w", os.ErrInvalid)
+	default:
+		return fmt.Errorf("invalid price source %q: %w", v.PriceSource, os.ErrInvalid)
+	}
+
+	switch v.TickerMode {
+	case TickerModeWebsocket:
+	case TickerModeRESTPoll, TickerModeAuto:
+		// Product endpoint doesn't report the book top, so polled tickers cannot
+		// carry a mid price.
+		if v.PriceSource == exchange.PriceSourceMid {
+			return fmt.Errorf("mid price source is not supported with ticker mode %q: %w", v.TickerMode, os.ErrInvalid)
+		}
+		if v.TickerPollInterval < 0 {
+			return fmt.Errorf("ticker poll interval cannot be negative: %w", os.ErrInvalid)
+		}
+	default:
+		return fmt.Errorf("invalid ticker mode %q: %w", v.TickerMode, os.ErrInvalid)
+	}
+	return nil
+}
+
 func SubcommandOptions() *Options {
 	return &Options{subcmdMode: true}
 }
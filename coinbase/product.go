@@ -10,6 +10,8 @@ import (
 	"log/slog"
 	"os"
 	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bvk/tradebot/coinbase/internal"
@@ -23,43 +25,121 @@ type Product struct {
 
 	exchange *Exchange
 
-	lastTicker *exchange.Ticker
+	lastTicker atomic.Pointer[exchange.Ticker]
+
+	// lastWebsocketTime holds the local time (in unix nanoseconds) when a
+	// ticker is last received from the websocket. It is used to decide if
+	// polling is necessary in the auto ticker mode, because the polled tickers
+	// also update the lastTicker.
+	lastWebsocketTime atomic.Int64
 
 	prodTickerTopic *topic.Topic[*exchange.Ticker]
 	prodOrderTopic  *topic.Topic[*exchange.Order]
 
+	prodReconnectTopic *topic.Topic[time.Time]
+
+	tapsMu sync.Mutex
+	taps   []*tickerTap
+
+	orderSubsMu sync.Mutex
+	orderSubs   map[*filteredOrderSub]struct{}
+
 	productData *internal.GetProductResponse
 
+	// priceSource selects the price delivered in the tickers. Empty value is
+	// same as the last trade price.
+	priceSource string
+
 	websocket *internal.Websocket
+
+	// pollDoneCh is closed when the product is closed to stop the ticker
+	// polling in the rest-poll and auto ticker modes.
+	pollDoneCh chan struct{}
+
+	// refs is the number of OpenProduct calls that are not yet closed. It is
+	// protected by the exchange's productLock.
+	refs int
 }
 
+// OpenProduct returns the product for the product-id. Products are shared by
+// all callers, so every OpenProduct call must be paired with a Close call and
+// the ticker feed is stopped only when the last user closes the product.
 func (ex *Exchange) OpenProduct(ctx context.Context, pid string) (_ exchange.Product, status error) {
-	if p, ok := ex.productMap.Load(pid); ok {
-		return p, nil
+	if !ex.isAllowedProduct(pid) {
+		return nil, fmt.Errorf("product %q is not in the allowed products list: %w", pid, os.ErrPermission)
 	}
+	return ex.acquireProduct(pid, func() (*Product, error) {
+		product, err := ex.client.GetProduct(ctx, pid)
+		if err != nil {
+			return nil, fmt.Errorf("could not get product named %q: %w", pid, err)
+		}
 
-	product, err := ex.client.GetProduct(ctx, pid)
-	if err != nil {
-		return nil, fmt.Errorf("could not get product named %q: %w", pid, err)
+		p := &Product{
+			client:             ex.client,
+			exchange:           ex,
+			productData:        product,
+			priceSource:        ex.opts.PriceSource,
+			prodTickerTopic:    topic.New[*exchange.Ticker](),
+			prodOrderTopic:     topic.New[*exchange.Order](),
+			prodReconnectTopic: topic.New[time.Time](),
+		}
+		if ex.opts.TickerMode != TickerModeRESTPoll {
+			p.websocket = ex.client.GetMessages("heartbeats", []string{pid}, ex.dispatchMessage)
+			p.websocket.Subscribe("ticker", []string{pid})
+		}
+		if ex.opts.TickerMode != TickerModeWebsocket {
+			p.pollDoneCh = make(chan struct{})
+			ex.client.Go(p.pollTickers)
+		}
+		return p, nil
+	})
+}
+
+// isAllowedProduct returns true if the product can be opened for trading as
+// per the AllowedProducts option.
+func (ex *Exchange) isAllowedProduct(pid string) bool {
+	if len(ex.opts.AllowedProducts) == 0 {
+		return true
 	}
+	return slices.Contains(ex.opts.AllowedProducts, pid)
+}
 
-	p := &Product{
-		client:          ex.client,
-		exchange:        ex,
-		productData:     product,
-		prodTickerTopic: topic.New[*exchange.Ticker](),
-		prodOrderTopic:  topic.New[*exchange.Order](),
-		websocket:       ex.client.GetMessages("heartbeats", []string{pid}, ex.dispatchMessage),
+// acquireProduct increments the reference count of an already open product
+// or creates a new product with the create function.
+func (ex *Exchange) acquireProduct(pid string, create func() (*Product, error)) (*Product, error) {
+	ex.productLock.Lock()
+	defer ex.productLock.Unlock()
+
+	if p, ok := ex.productMap.Load(pid); ok {
+		p.refs++
+		return p, nil
 	}
-	p.websocket.Subscribe("ticker", []string{pid})
 
+	p, err := create()
+	if err != nil {
+		return nil, err
+	}
+	p.refs = 1
 	ex.productMap.Store(pid, p)
 	return p, nil
 }
 
+// Close releases a reference to the product. Websocket subscription for the
+// product is closed when the last reference is released.
 func (p *Product) Close() error {
+	p.exchange.productLock.Lock()
+	defer p.exchange.productLock.Unlock()
+
+	if p.refs--; p.refs > 0 {
+		return nil
+	}
 	p.exchange.productMap.Delete(p.productData.ProductID)
-	p.websocket.Close()
+	if p.websocket != nil {
+		p.websocket.Close()
+	}
+	if p.pollDoneCh != nil {
+		close(p.pollDoneCh)
+	}
 	return nil
 }
 
@@ -68,13 +148,17 @@ func (p *Product) ProductID() string {
 }
 
 func (p *Product) ExchangeName() string {
-	return "coinbase"
+	return p.exchange.ExchangeName()
 }
 
 func (p *Product) BaseMinSize() decimal.Decimal {
 	return p.productData.BaseMinSize.Decimal
 }
 
+func (p *Product) BaseIncrement() decimal.Decimal {
+	return p.productData.BaseIncrement.Decimal
+}
+
 func (p *Product) TickerCh() (<-chan *exchange.Ticker, func()) {
 	sub, ch, _ := p.prodTickerTopic.Subscribe(1, true /* includeRecent */)
 	return ch, sub.Unsubscribe
@@ -85,11 +169,113 @@ func (p *Product) OrderUpdatesCh() (<-chan *exchange.Order, func()) {
 	return ch, sub.Unsubscribe
 }
 
+type filteredOrderSub struct {
+	match func(*exchange.Order) bool
+
+	topic *topic.Topic[*exchange.Order]
+}
+
+// OrderUpdatesChFiltered is similar to OrderUpdatesCh, but only delivers the
+// order updates selected by the match function.
+func (p *Product) OrderUpdatesChFiltered(match func(*exchange.Order) bool) (<-chan *exchange.Order, func()) {
+	sub := &filteredOrderSub{
+		match: match,
+		topic: topic.New[*exchange.Order](),
+	}
+	_, ch, _ := sub.topic.Subscribe(0, false /* includeRecent */)
+
+	p.orderSubsMu.Lock()
+	if p.orderSubs == nil {
+		p.orderSubs = make(map[*filteredOrderSub]struct{})
+	}
+	p.orderSubs[sub] = struct{}{}
+	p.orderSubsMu.Unlock()
+
+	var once sync.Once
+	stopf := func() {
+		once.Do(func() {
+			p.orderSubsMu.Lock()
+			delete(p.orderSubs, sub)
+			p.orderSubsMu.Unlock()
+			sub.topic.Close()
+		})
+	}
+	return ch, stopf
+}
+
+// sendOrder delivers the order update to all order updates subscribers and
+// to the filtered subscribers that match the order.
+func (p *Product) sendOrder(order *exchange.Order) {
+	p.prodOrderTopic.Send(order)
+
+	p.orderSubsMu.Lock()
+	defer p.orderSubsMu.Unlock()
+
+	for sub := range p.orderSubs {
+		if sub.match(order) {
+			sub.topic.Send(order)
+		}
+	}
+}
+
+func (p *Product) ReconnectCh() (<-chan time.Time, func()) {
+	sub, ch, _ := p.prodReconnectTopic.Subscribe(1, false /* includeRecent */)
+	return ch, sub.Unsubscribe
+}
+
+// LastPrice returns the last ticker price for the product. Current product
+// price is fetched from the exchange when no ticker is received yet.
+func (p *Product) LastPrice(ctx context.Context) (decimal.Decimal, error) {
+	if last := p.lastTicker.Load(); last != nil && last.Price.IsPositive() {
+		return last.Price, nil
+	}
+	resp, err := p.client.GetProduct(ctx, p.productData.ProductID)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if !resp.Price.Decimal.IsPositive() {
+		return decimal.Zero, fmt.Errorf("product %q has no valid price", p.productData.ProductID)
+	}
+	return resp.Price.Decimal, nil
+}
+
+// Ping fetches the product information with an authenticated REST call and
+// verifies that a ticker was received within the MaxTickerAge duration.
+func (p *Product) Ping(ctx context.Context) error {
+	if _, err := p.client.GetProduct(ctx, p.productData.ProductID); err != nil {
+		return fmt.Errorf("could not get product %q: %w", p.productData.ProductID, err)
+	}
+	last := p.lastTicker.Load()
+	if last == nil {
+		return fmt.Errorf("no ticker is received yet for product %q", p.productData.ProductID)
+	}
+	if age := time.Since(last.Timestamp.Time); age > p.exchange.opts.MaxTickerAge {
+		return fmt.Errorf("last ticker for product %q is %s old", p.productData.ProductID, age.Round(time.Second))
+	}
+	return nil
+}
+
+// AvailableBalance returns the sum of available balances for the currency
+// from all accounts. Balances are cached for the AccountsCacheTTL duration.
+func (p *Product) AvailableBalance(ctx context.Context, currency string) (decimal.Decimal, error) {
+	accounts, err := p.exchange.cachedAccounts(ctx)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("could not list accounts: %w", err)
+	}
+	var sum decimal.Decimal
+	for _, a := range accounts {
+		if a.Currency == currency {
+			sum = sum.Add(a.AvailableBalance.Value.Decimal)
+		}
+	}
+	return sum, nil
+}
+
 func (p *Product) Get(ctx context.Context, serverOrderID exchange.OrderID) (*exchange.Order, error) {
 	return p.exchange.GetOrder(ctx, serverOrderID)
 }
 
-func (p *Product) LimitBuy(ctx context.Context, clientOrderID string, size, price decimal.Decimal) (exchange.OrderID, error) {
+func (p *Product) LimitBuy(ctx context.Context, clientOrderID string, size, price decimal.Decimal, goodTill time.Duration, tag string) (exchange.OrderID, error) {
 	if size.LessThan(p.productData.BaseMinSize.Decimal) {
 		return "", fmt.Errorf("min size is %s: %w", p.productData.BaseMinSize.Decimal, os.ErrInvalid)
 	}
@@ -99,22 +285,17 @@ func (p *Product) LimitBuy(ctx context.Context, clientOrderID string, size, pric
 
 	// check if this is a retry request for the clientOrderID.
 	if order, ok := p.exchange.recreateOldOrder(clientOrderID); ok {
-		p.prodOrderTopic.Send(order)
+		p.sendOrder(order)
 		return order.OrderID, nil
 	}
 
 	roundPrice := price.Sub(price.Mod(p.productData.QuoteIncrement.Decimal))
 
 	req := &internal.CreateOrderRequest{
-		ClientOrderID: clientOrderID,
+		ClientOrderID: encodeClientOrderID(clientOrderID, tag),
 		ProductID:     p.productData.ProductID,
 		Side:          "BUY",
-		Order: &internal.OrderConfig{
-			LimitGTC: &internal.LimitLimitGTC{
-				BaseSize:   exchange.NullDecimal{Decimal: size},
-				LimitPrice: exchange.NullDecimal{Decimal: roundPrice},
-			},
-		},
+		Order:         p.limitOrderConfig(size, roundPrice, goodTill),
 	}
 	resp, err := p.exchange.createReadyOrder(ctx, req)
 	if err != nil {
@@ -122,12 +303,12 @@ func (p *Product) LimitBuy(ctx context.Context, clientOrderID string, size, pric
 	}
 	if !resp.Success {
 		slog.ErrorContext(ctx, "create order has failed", "error_response", resp.ErrorResponse)
-		return "", errors.New(resp.FailureReason)
+		return "", createOrderError(resp)
 	}
 	return exchange.OrderID(resp.OrderID), nil
 }
 
-func (p *Product) LimitSell(ctx context.Context, clientOrderID string, size, price decimal.Decimal) (exchange.OrderID, error) {
+func (p *Product) LimitSell(ctx context.Context, clientOrderID string, size, price decimal.Decimal, goodTill time.Duration, tag string) (exchange.OrderID, error) {
 	if size.LessThan(p.productData.BaseMinSize.Decimal) {
 		return "", fmt.Errorf("min size is %s: %w", p.productData.BaseMinSize.Decimal, os.ErrInvalid)
 	}
@@ -137,20 +318,80 @@ func (p *Product) LimitSell(ctx context.Context, clientOrderID string, size, pri
 
 	// check if this is a retry request for the clientOrderID.
 	if order, ok := p.exchange.recreateOldOrder(clientOrderID); ok {
-		p.prodOrderTopic.Send(order)
+		p.sendOrder(order)
 		return order.OrderID, nil
 	}
 
 	roundPrice := price.Sub(price.Mod(p.productData.QuoteIncrement.Decimal))
 
 	req := &internal.CreateOrderRequest{
-		ClientOrderID: clientOrderID,
+		ClientOrderID: encodeClientOrderID(clientOrderID, tag),
 		ProductID:     p.productData.ProductID,
 		Side:          "SELL",
-		Order: &internal.OrderConfig{
+		Order:         p.limitOrderConfig(size, roundPrice, goodTill),
+	}
+	resp, err := p.exchange.createReadyOrder(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if !resp.Success {
+		slog.ErrorContext(ctx, "create order has failed", "error_response", resp.ErrorResponse)
+		return "", createOrderError(resp)
+	}
+
+	return exchange.OrderID(resp.OrderID), nil
+}
+
+// limitOrderConfig returns a good-till-cancel limit order configuration when
+// goodTill is zero and a good-till-date configuration otherwise.
+func (p *Product) limitOrderConfig(size, price decimal.Decimal, goodTill time.Duration) *internal.OrderConfig {
+	if goodTill == 0 {
+		return &internal.OrderConfig{
 			LimitGTC: &internal.LimitLimitGTC{
 				BaseSize:   exchange.NullDecimal{Decimal: size},
-				LimitPrice: exchange.NullDecimal{Decimal: roundPrice},
+				LimitPrice: exchange.NullDecimal{Decimal: price},
+			},
+		}
+	}
+	endTime := p.exchange.client.Now().Time.Add(goodTill)
+	return &internal.OrderConfig{
+		LimitGTD: &internal.LimitLimitGTD{
+			BaseSize:   exchange.NullDecimal{Decimal: size},
+			LimitPrice: exchange.NullDecimal{Decimal: price},
+			EndTime:    endTime.UTC().Format(time.RFC3339),
+		},
+	}
+}
+
+func (p *Product) MarketBuy(ctx context.Context, clientOrderID string, size decimal.Decimal) (exchange.OrderID, error) {
+	return p.createMarketOrder(ctx, "BUY", clientOrderID, size)
+}
+
+func (p *Product) MarketSell(ctx context.Context, clientOrderID string, size decimal.Decimal) (exchange.OrderID, error) {
+	return p.createMarketOrder(ctx, "SELL", clientOrderID, size)
+}
+
+func (p *Product) createMarketOrder(ctx context.Context, side, clientOrderID string, size decimal.Decimal) (exchange.OrderID, error) {
+	if size.LessThan(p.productData.BaseMinSize.Decimal) {
+		return "", fmt.Errorf("min size is %s: %w", p.productData.BaseMinSize.Decimal, os.ErrInvalid)
+	}
+	if size.GreaterThan(p.productData.BaseMaxSize.Decimal) {
+		return "", fmt.Errorf("max size is %s: %w", p.productData.BaseMaxSize.Decimal, os.ErrInvalid)
+	}
+
+	// check if this is a retry request for the clientOrderID.
+	if order, ok := p.exchange.recreateOldOrder(clientOrderID); ok {
+		p.sendOrder(order)
+		return order.OrderID, nil
+	}
+
+	req := &internal.CreateOrderRequest{
+		ClientOrderID: clientOrderID,
+		ProductID:     p.productData.ProductID,
+		Side:          side,
+		Order: &internal.OrderConfig{
+			MarketIOC: &internal.MarketMarketIOC{
+				BaseSize: &exchange.NullDecimal{Decimal: size},
 			},
 		},
 	}
@@ -159,13 +400,93 @@ func (p *Product) LimitSell(ctx context.Context, clientOrderID string, size, pri
 		return "", err
 	}
 	if !resp.Success {
-		slog.ErrorContext(ctx, "create order has failed", "error_response", resp.ErrorResponse)
-		return "", errors.New(resp.FailureReason)
+		slog.ErrorContext(ctx, "create market order has failed", "error_response", resp.ErrorResponse)
+		return "", createOrderError(resp)
+	}
+	return exchange.OrderID(resp.OrderID), nil
+}
+
+func (p *Product) StopLimitBuy(ctx context.Context, clientOrderID string, size, limitPrice, triggerPrice decimal.Decimal, goodTill time.Duration, tag string) (exchange.OrderID, error) {
+	return p.createStopLimitOrder(ctx, "BUY", "STOP_DIRECTION_STOP_UP", clientOrderID, size, limitPrice, triggerPrice, goodTill, tag)
+}
+
+func (p *Product) StopLimitSell(ctx context.Context, clientOrderID string, size, limitPrice, triggerPrice decimal.Decimal, goodTill time.Duration, tag string) (exchange.OrderID, error) {
+	return p.createStopLimitOrder(ctx, "SELL", "STOP_DIRECTION_STOP_DOWN", clientOrderID, size, limitPrice, triggerPrice, goodTill, tag)
+}
+
+func (p *Product) createStopLimitOrder(ctx context.Context, side, direction, clientOrderID string, size, limitPrice, triggerPrice decimal.Decimal, goodTill time.Duration, tag string) (exchange.OrderID, error) {
+	req, err := p.stopLimitOrderRequest(side, direction, clientOrderID, size, limitPrice, triggerPrice, goodTill, tag)
+	if err != nil {
+		return "", err
+	}
+
+	// check if this is a retry request for the clientOrderID.
+	if order, ok := p.exchange.recreateOldOrder(clientOrderID); ok {
+		p.sendOrder(order)
+		return order.OrderID, nil
 	}
 
+	resp, err := p.exchange.createReadyOrder(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if !resp.Success {
+		slog.ErrorContext(ctx, "create stop-limit order has failed", "error_response", resp.ErrorResponse)
+		return "", createOrderError(resp)
+	}
 	return exchange.OrderID(resp.OrderID), nil
 }
 
+// stopLimitOrderRequest returns the create order request for a stop-limit
+// order. Size is rounded down to the base increment and the prices are rounded
+// down to the quote increment.
+func (p *Product) stopLimitOrderRequest(side, direction, clientOrderID string, size, limitPrice, triggerPrice decimal.Decimal, goodTill time.Duration, tag string) (*internal.CreateOrderRequest, error) {
+	if inc := p.productData.BaseIncrement.Decimal; inc.IsPositive() {
+		size = size.Sub(size.Mod(inc))
+	}
+	if size.LessThan(p.productData.BaseMinSize.Decimal) {
+		return nil, fmt.Errorf("min size is %s: %w", p.productData.BaseMinSize.Decimal, os.ErrInvalid)
+	}
+	if size.GreaterThan(p.productData.BaseMaxSize.Decimal) {
+		return nil, fmt.Errorf("max size is %s: %w", p.productData.BaseMaxSize.Decimal, os.ErrInvalid)
+	}
+
+	inc := p.productData.QuoteIncrement.Decimal
+	req := &internal.CreateOrderRequest{
+		ClientOrderID: encodeClientOrderID(clientOrderID, tag),
+		ProductID:     p.productData.ProductID,
+		Side:          side,
+		Order:         p.stopLimitOrderConfig(direction, size, limitPrice.Sub(limitPrice.Mod(inc)), triggerPrice.Sub(triggerPrice.Mod(inc)), goodTill),
+	}
+	return req, nil
+}
+
+// stopLimitOrderConfig returns a good-till-cancel stop-limit order
+// configuration when goodTill is zero and a good-till-date configuration
+// otherwise.
+func (p *Product) stopLimitOrderConfig(direction string, size, limitPrice, stopPrice decimal.Decimal, goodTill time.Duration) *internal.OrderConfig {
+	if goodTill == 0 {
+		return &internal.OrderConfig{
+			StopLimitGTC: &internal.StopLimitStopLimitGTC{
+				BaseSize:      exchange.NullDecimal{Decimal: size},
+				LimitPrice:    exchange.NullDecimal{Decimal: limitPrice},
+				StopPrice:     exchange.NullDecimal{Decimal: stopPrice},
+				StopDirection: direction,
+			},
+		}
+	}
+	endTime := p.exchange.client.Now().Time.Add(goodTill)
+	return &internal.OrderConfig{
+		StopLimitGTD: &internal.StopLimitStopLimitGTD{
+			BaseSize:      exchange.NullDecimal{Decimal: size},
+			LimitPrice:    exchange.NullDecimal{Decimal: limitPrice},
+			StopPrice:     exchange.NullDecimal{Decimal: stopPrice},
+			StopDirection: direction,
+			EndTime:       endTime.UTC().Format(time.RFC3339),
+		},
+	}
+}
+
 func (p *Product) Cancel(ctx context.Context, serverOrderID exchange.OrderID) error {
 	req := &internal.CancelOrderRequest{
 		OrderIDs: []string{string(serverOrderID)},
@@ -178,8 +499,14 @@ func (p *Product) Cancel(ctx context.Context, serverOrderID exchange.OrderID) er
 		return fmt.Errorf("unexpected: cancel order response has %d results", n)
 	}
 	if !resp.Results[0].Success {
-		if resp.Results[0].FailureReason != "DUPLICATE_CANCEL_REQUEST" {
-			return errors.New(resp.Results[0].FailureReason)
+		switch reason := resp.Results[0].FailureReason; reason {
+		case "DUPLICATE_CANCEL_REQUEST":
+		case "UNKNOWN_CANCEL_ORDER":
+			// Order is not open anymore, typically cause it's filled just before
+			// the cancel request.
+			return fmt.Errorf("%s: %w", reason, exchange.ErrOrderDone)
+		default:
+			return errors.New(reason)
 		}
 	}
 	// Schedule a Get for the canceled order so that a notification is generated.
@@ -195,20 +522,130 @@ func (p *Product) Cancel(ctx context.Context, serverOrderID exchange.OrderID) er
 	return nil
 }
 
+// Reduce shrinks an open good-till-cancel limit order to the new size, which
+// keeps it's queue priority at the exchange. Other order types cannot be
+// edited and return errors.ErrUnsupported.
+func (p *Product) Reduce(ctx context.Context, serverOrderID exchange.OrderID, newSize decimal.Decimal) error {
+	resp, err := p.client.GetOrder(ctx, string(serverOrderID))
+	if err != nil {
+		return fmt.Errorf("could not get order %s: %w", serverOrderID, err)
+	}
+	order := resp.Order
+	if order.Status != "OPEN" {
+		return fmt.Errorf("order %s is not open (status %s): %w", serverOrderID, order.Status, os.ErrInvalid)
+	}
+	if order.OrderConfiguration == nil || order.OrderConfiguration.LimitGTC == nil {
+		return fmt.Errorf("order %s is not a good-till-cancel limit order: %w", serverOrderID, errors.ErrUnsupported)
+	}
+	config := order.OrderConfiguration.LimitGTC
+	if !newSize.LessThan(config.BaseSize.Decimal) {
+		return fmt.Errorf("new size %s is not less than the order size %s: %w", newSize, config.BaseSize.Decimal, os.ErrInvalid)
+	}
+	if !newSize.GreaterThan(order.FilledSize.Decimal) {
+		return fmt.Errorf("new size %s is not more than the filled size %s: %w", newSize, order.FilledSize.Decimal, os.ErrInvalid)
+	}
+
+	req := &internal.EditOrderRequest{
+		OrderID: string(serverOrderID),
+		Price:   config.LimitPrice,
+		Size:    exchange.NullDecimal{Decimal: newSize},
+	}
+	eresp, err := p.client.EditOrder(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !eresp.Success {
+		for _, v := range eresp.Errors {
+			if len(v.EditFailureReason) != 0 {
+				return errors.New(v.EditFailureReason)
+			}
+			if len(v.PreviewFailureReason) != 0 {
+				return errors.New(v.PreviewFailureReason)
+			}
+		}
+		return fmt.Errorf("edit order %s was unsuccessful", serverOrderID)
+	}
+	return nil
+}
+
 func (p *Product) handleTickerEvent(timestamp time.Time, event *internal.TickerEvent) {
-	if p.lastTicker != nil && timestamp.Before(p.lastTicker.Timestamp.Time) {
+	if event.Type != "poll" {
+		p.lastWebsocketTime.Store(time.Now().UnixNano())
+	}
+	if last := p.lastTicker.Load(); last != nil && timestamp.Before(last.Timestamp.Time) {
 		return
 	}
-	p.lastTicker = &exchange.Ticker{
+	ticker := &exchange.Ticker{
 		Timestamp: exchange.RemoteTime{Time: timestamp},
 		Price:     event.Price.Decimal,
+		Bid:       event.BestBid.Decimal,
+		Ask:       event.BestAsk.Decimal,
+		BidSize:   event.BestBidQuantity.Decimal,
+		AskSize:   event.BestAskQuantity.Decimal,
+	}
+	if p.priceSource == exchange.PriceSourceMid {
+		// Ticker events without the book top are dropped instead of falling
+		// back to the trade price, so that the price source is not mixed.
+		if !ticker.Bid.IsPositive() || !ticker.Ask.IsPositive() {
+			return
+		}
+		ticker.Price = ticker.Bid.Add(ticker.Ask).Div(decimal.NewFromInt(2))
+	}
+	p.lastTicker.Store(ticker)
+	p.prodTickerTopic.Send(ticker)
+	p.sendTaps(ticker)
+}
+
+// pollTickers feeds the product tickers by polling the product endpoint. In
+// the auto ticker mode, polling is skipped while the websocket tickers are
+// arriving in time.
+func (p *Product) pollTickers(ctx context.Context) {
+	opts := p.exchange.opts
+	ticker := time.NewTicker(opts.TickerPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.pollDoneCh:
+			return
+		case <-ticker.C:
+		}
+
+		if !p.needPoll(time.Now()) {
+			continue
+		}
+
+		resp, err := p.client.GetProduct(ctx, p.productData.ProductID)
+		if err != nil {
+			log.Printf("could not poll ticker for product %q (will retry): %v", p.productData.ProductID, err)
+			continue
+		}
+		p.handleTickerEvent(p.client.Now().Time, &internal.TickerEvent{
+			Type:      "poll",
+			ProductID: resp.ProductID,
+			Price:     resp.Price,
+		})
+	}
+}
+
+// needPoll returns true if the ticker must be polled at the input time. In the
+// auto ticker mode, tickers are polled only when no websocket ticker is
+// received in the heartbeat timeout.
+func (p *Product) needPoll(now time.Time) bool {
+	opts := p.exchange.opts
+	if opts.TickerMode != TickerModeAuto {
+		return true
 	}
-	p.prodTickerTopic.Send(p.lastTicker)
+	last := p.lastWebsocketTime.Load()
+	return last == 0 || now.Sub(time.Unix(0, last)) >= opts.HeartbeatTimeout
 }
 
 func (p *Product) handleOrder(order *exchange.Order) {
-	// We don't want to expose PENDING state outside this package.
-	if slices.Contains(readyStatuses, order.Status) {
-		p.prodOrderTopic.Send(order)
+	// We don't want to expose PENDING state outside this package, but orders
+	// rejected before they are open are still relayed as terminal updates.
+	if order.Done || slices.Contains(readyStatuses, order.Status) {
+		p.sendOrder(order)
 	}
 }
@@ -0,0 +1,303 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package coinbase
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bvk/tradebot/coinbase/internal"
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvkgo/topic"
+	"github.com/shopspring/decimal"
+)
+
+func TestProductRefCount(t *testing.T) {
+	ex := new(Exchange)
+
+	ncreates := 0
+	create := func() (*Product, error) {
+		ncreates++
+		return &Product{
+			exchange:           ex,
+			productData:        &internal.GetProductResponse{ProductID: "BTC-USD"},
+			prodTickerTopic:    topic.New[*exchange.Ticker](),
+			prodOrderTopic:     topic.New[*exchange.Order](),
+			prodReconnectTopic: topic.New[time.Time](),
+		}, nil
+	}
+
+	p1, err := ex.acquireProduct("BTC-USD", create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := ex.acquireProduct("BTC-USD", create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ncreates != 1 || p1 != p2 {
+		t.Fatalf("want one shared product, got %d creates", ncreates)
+	}
+
+	tickerCh, stop := p2.TickerCh()
+	defer stop()
+
+	if err := p1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ex.productMap.Load("BTC-USD"); !ok {
+		t.Fatalf("product is removed while it is still in use")
+	}
+
+	event := &internal.TickerEvent{Price: exchange.NullDecimal{Decimal: decimal.NewFromInt(100)}}
+	p2.handleTickerEvent(time.Now(), event)
+	select {
+	case ticker := <-tickerCh:
+		if !ticker.Price.Equal(decimal.NewFromInt(100)) {
+			t.Fatalf("want ticker price 100, got %s", ticker.Price)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("ticker feed has stopped after the first close")
+	}
+
+	if err := p2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ex.productMap.Load("BTC-USD"); ok {
+		t.Fatalf("product is not removed after the last close")
+	}
+}
+
+func TestClientOrderIDTag(t *testing.T) {
+	id := "6f1e6f2c-1b6d-4bb4-9a9c-2d1a1d0e0f00"
+	if got := encodeClientOrderID(id, ""); got != id {
+		t.Fatalf("want untagged id %q, got %q", id, got)
+	}
+	encoded := encodeClientOrderID(id, "1a2b3c4d/loop-000001/buy-000002")
+	if cid, tag := splitClientOrderID(encoded); cid != id || tag != "1a2b3c4d/loop-000001/buy-000002" {
+		t.Fatalf("want id %q and tag, got %q and %q", id, cid, tag)
+	}
+	if cid, tag := splitClientOrderID(id); cid != id || tag != "" {
+		t.Fatalf("want untagged id %q, got %q with tag %q", id, cid, tag)
+	}
+}
+
+func TestFillsLiquidity(t *testing.T) {
+	maker := &internal.Fill{LiquidityIndicator: "MAKER"}
+	taker := &internal.Fill{LiquidityIndicator: "TAKER"}
+	unknown := &internal.Fill{LiquidityIndicator: "UNKNOWN_LIQUIDITY_INDICATOR"}
+
+	testCases := []struct {
+		fills []*internal.Fill
+		want  string
+	}{
+		{nil, ""},
+		{[]*internal.Fill{unknown}, ""},
+		{[]*internal.Fill{maker, maker}, exchange.LiquidityMaker},
+		{[]*internal.Fill{taker, unknown}, exchange.LiquidityTaker},
+		{[]*internal.Fill{maker, taker}, exchange.LiquidityMixed},
+	}
+	for i, tc := range testCases {
+		if got := fillsLiquidity(tc.fills); got != tc.want {
+			t.Errorf("%d: want %q, got %q", i, tc.want, got)
+		}
+	}
+}
+
+func TestOpenProductAllowList(t *testing.T) {
+	ex := &Exchange{opts: Options{AllowedProducts: []string{"BTC-USD"}}}
+	if _, err := ex.OpenProduct(context.Background(), "BTC-USDC"); !errors.Is(err, os.ErrPermission) {
+		t.Fatalf("want os.ErrPermission for a product not in the allow list, got %v", err)
+	}
+	if !ex.isAllowedProduct("BTC-USD") {
+		t.Fatalf("want BTC-USD to be allowed")
+	}
+
+	ex = new(Exchange)
+	if !ex.isAllowedProduct("BTC-USDC") {
+		t.Fatalf("empty allow list must allow all products")
+	}
+}
+
+func TestTickerMidPriceSource(t *testing.T) {
+	p := &Product{
+		productData:     &internal.GetProductResponse{ProductID: "BTC-USD"},
+		prodTickerTopic: topic.New[*exchange.Ticker](),
+		priceSource:     exchange.PriceSourceMid,
+	}
+
+	now := time.Now()
+	event := &internal.TickerEvent{
+		Price:   exchange.NullDecimal{Decimal: decimal.NewFromInt(100)},
+		BestBid: exchange.NullDecimal{Decimal: decimal.NewFromInt(98)},
+		BestAsk: exchange.NullDecimal{Decimal: decimal.NewFromInt(99)},
+	}
+	p.handleTickerEvent(now, event)
+	if last := p.lastTicker.Load(); last == nil || !last.Price.Equal(decimal.RequireFromString("98.5")) {
+		t.Fatalf("want mid price 98.5, got %v", last)
+	}
+
+	// Tickers without the book top must not fallback to the trade price.
+	p.handleTickerEvent(now.Add(time.Second), &internal.TickerEvent{Price: exchange.NullDecimal{Decimal: decimal.NewFromInt(120)}})
+	if last := p.lastTicker.Load(); !last.Price.Equal(decimal.RequireFromString("98.5")) {
+		t.Fatalf("want mid price 98.5 to be unchanged, got %s", last.Price)
+	}
+}
+
+func TestAvailableBalanceCached(t *testing.T) {
+	ctx := context.Background()
+	d := decimal.RequireFromString
+
+	// Exchange has no client, so balances must come from the cache.
+	ex := &Exchange{opts: Options{AccountsCacheTTL: time.Hour}}
+	account := func(currency, avail string) *internal.Account {
+		a := &internal.Account{Currency: currency}
+		a.AvailableBalance.Value = exchange.NullDecimal{Decimal: d(avail)}
+		return a
+	}
+	ex.setCachedAccounts([]*internal.Account{account("USD", "10"), account("USD", "5"), account("BTC", "1")}, time.Now())
+
+	p := &Product{exchange: ex, productData: &internal.GetProductResponse{ProductID: "BTC-USD"}}
+	if v, err := p.AvailableBalance(ctx, "USD"); err != nil || !v.Equal(d("15")) {
+		t.Fatalf("want cached USD balance 15, got %s (%v)", v, err)
+	}
+
+	// Older balances do not replace the newer cached balances.
+	ex.setCachedAccounts([]*internal.Account{account("BTC", "2")}, time.Now().Add(-time.Minute))
+	if v, err := p.AvailableBalance(ctx, "BTC"); err != nil || !v.Equal(d("1")) {
+		t.Fatalf("want cached BTC balance 1, got %s (%v)", v, err)
+	}
+}
+
+func TestLimitOrderConfig(t *testing.T) {
+	d := decimal.RequireFromString
+	p := &Product{exchange: &Exchange{client: new(internal.Client)}}
+
+	gtc := p.limitOrderConfig(d("1"), d("100"), 0)
+	if gtc.LimitGTC == nil || gtc.LimitGTD != nil {
+		t.Fatalf("want a good-till-cancel limit order without good-till")
+	}
+
+	config := p.limitOrderConfig(d("1"), d("100"), 2*time.Hour)
+	gtd := config.LimitGTD
+	if gtd == nil || config.LimitGTC != nil {
+		t.Fatalf("want a good-till-date limit order with good-till")
+	}
+	if !gtd.BaseSize.Decimal.Equal(d("1")) || !gtd.LimitPrice.Decimal.Equal(d("100")) {
+		t.Fatalf("want size 1 and price 100, got %s and %s", gtd.BaseSize.Decimal, gtd.LimitPrice.Decimal)
+	}
+	// End time must be in UTC with the RFC3339 format without fractional
+	// seconds.
+	end, err := time.Parse(time.RFC3339, gtd.EndTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := end.UTC().Format(time.RFC3339); gtd.EndTime != want || !strings.HasSuffix(gtd.EndTime, "Z") {
+		t.Fatalf("want end time %q in utc, got %q", want, gtd.EndTime)
+	}
+	if v := time.Until(end); v < 2*time.Hour-time.Minute || v > 2*time.Hour {
+		t.Fatalf("want end time two hours from now, got %s", end)
+	}
+}
+
+func TestStopLimitOrder(t *testing.T) {
+	d := decimal.RequireFromString
+
+	ex := &Exchange{client: new(internal.Client)}
+	p := &Product{exchange: ex, productData: &internal.GetProductResponse{
+		ProductID:      "BTC-USD",
+		BaseMinSize:    exchange.NullDecimal{Decimal: d("0.001")},
+		BaseMaxSize:    exchange.NullDecimal{Decimal: d("100")},
+		BaseIncrement:  exchange.NullDecimal{Decimal: d("0.001")},
+		QuoteIncrement: exchange.NullDecimal{Decimal: d("0.01")},
+	}}
+
+	// Sizes are rounded down to the base increment.
+	req, err := p.stopLimitOrderRequest("BUY", "STOP_DIRECTION_STOP_UP", "client-1", d("0.0019"), d("100.005"), d("99.999"), 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gtc := req.Order.StopLimitGTC
+	if gtc == nil || req.Order.StopLimitGTD != nil {
+		t.Fatalf("want a good-till-cancel stop-limit order without good-till")
+	}
+	if !gtc.BaseSize.Decimal.Equal(d("0.001")) {
+		t.Fatalf("want size 0.001 rounded to the base increment, got %s", gtc.BaseSize.Decimal)
+	}
+	if !gtc.LimitPrice.Decimal.Equal(d("100")) || !gtc.StopPrice.Decimal.Equal(d("99.99")) {
+		t.Fatalf("want prices rounded to the quote increment, got %s and %s", gtc.LimitPrice.Decimal, gtc.StopPrice.Decimal)
+	}
+	if req.ClientOrderID != "client-1" {
+		t.Fatalf("want untagged client order id, got %q", req.ClientOrderID)
+	}
+
+	if _, err := p.stopLimitOrderRequest("SELL", "STOP_DIRECTION_STOP_DOWN", "client-2", d("0.0009"), d("100"), d("101"), 0, ""); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("size 0.0009 must be rejected below the min size, got %v", err)
+	}
+
+	// Good-till and tag must be applied.
+	req, err = p.stopLimitOrderRequest("SELL", "STOP_DIRECTION_STOP_DOWN", "client-3", d("1"), d("100"), d("101"), time.Hour, "1a2b3c4d/buy-000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gtd := req.Order.StopLimitGTD
+	if gtd == nil || req.Order.StopLimitGTC != nil {
+		t.Fatalf("want a good-till-date stop-limit order with good-till")
+	}
+	end, err := time.Parse(time.RFC3339, gtd.EndTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := time.Until(end); v < 59*time.Minute || v > time.Hour {
+		t.Fatalf("want end time an hour from now, got %s", end)
+	}
+	if cid, tag := splitClientOrderID(req.ClientOrderID); cid != "client-3" || tag != "1a2b3c4d/buy-000001" {
+		t.Fatalf("want tagged client order id, got %q", req.ClientOrderID)
+	}
+}
+
+func TestTickerModeNeedPoll(t *testing.T) {
+	newProduct := func(mode string) *Product {
+		ex := &Exchange{opts: Options{TickerMode: mode, HeartbeatTimeout: time.Minute}}
+		return &Product{
+			exchange:        ex,
+			productData:     &internal.GetProductResponse{ProductID: "BTC-USD"},
+			prodTickerTopic: topic.New[*exchange.Ticker](),
+		}
+	}
+	price := exchange.NullDecimal{Decimal: decimal.NewFromInt(100)}
+
+	p := newProduct(TickerModeRESTPoll)
+	p.handleTickerEvent(time.Now(), &internal.TickerEvent{Type: "ticker", Price: price})
+	if !p.needPoll(time.Now()) {
+		t.Fatalf("rest-poll mode must always poll")
+	}
+
+	p = newProduct(TickerModeAuto)
+	if !p.needPoll(time.Now()) {
+		t.Fatalf("auto mode must poll before any websocket ticker")
+	}
+
+	// Polled tickers must not stop the polling.
+	p.handleTickerEvent(time.Now(), &internal.TickerEvent{Type: "poll", Price: price})
+	if !p.needPoll(time.Now()) {
+		t.Fatalf("auto mode must keep polling without websocket tickers")
+	}
+
+	// Websocket tickers stop the polling till they stall.
+	p.handleTickerEvent(time.Now(), &internal.TickerEvent{Type: "ticker", Price: price})
+	if p.needPoll(time.Now()) {
+		t.Fatalf("auto mode must not poll while websocket tickers arrive")
+	}
+	p.handleTickerEvent(time.Now(), &internal.TickerEvent{Type: "poll", Price: price})
+	if p.needPoll(time.Now()) {
+		t.Fatalf("polled tickers must not affect the websocket ticker time")
+	}
+	if !p.needPoll(time.Now().Add(2 * time.Minute)) {
+		t.Fatalf("auto mode must poll after the websocket tickers stall")
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package coinbase
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"slices"
+
+	"github.com/bvk/tradebot/exchange"
+)
+
+// tickerTapSize is the max number of tickers buffered for a tap before new
+// tickers are dropped.
+const tickerTapSize = 4096
+
+type tickerTap struct {
+	ch   chan *exchange.Ticker
+	done chan error
+
+	ndropped int
+}
+
+// RecordTickers tees all ticker messages for the product to the writer as
+// JSON lines. Tickers are buffered and written asynchronously, so a slow
+// writer doesn't slow down the live feed; tickers are dropped when the buffer
+// is full. Returned stop function flushes the buffered tickers and returns the
+// first write error, if any.
+func (p *Product) RecordTickers(w io.Writer) (stopf func() error) {
+	tap := &tickerTap{
+		ch:   make(chan *exchange.Ticker, tickerTapSize),
+		done: make(chan error, 1),
+	}
+
+	go func() {
+		var status error
+		encoder := json.NewEncoder(w)
+		for ticker := range tap.ch {
+			if status != nil {
+				continue
+			}
+			if err := encoder.Encode(ticker); err != nil {
+				log.Printf("could not record ticker for product %s (recording is stopped): %v", p.ProductID(), err)
+				status = err
+			}
+		}
+		tap.done <- status
+	}()
+
+	p.tapsMu.Lock()
+	p.taps = append(p.taps, tap)
+	p.tapsMu.Unlock()
+
+	return func() error {
+		p.tapsMu.Lock()
+		p.taps = slices.DeleteFunc(p.taps, func(v *tickerTap) bool { return v == tap })
+		p.tapsMu.Unlock()
+
+		close(tap.ch)
+		err := <-tap.done
+		if tap.ndropped > 0 {
+			log.Printf("dropped %d tickers for product %s cause recording was too slow", tap.ndropped, p.ProductID())
+		}
+		return err
+	}
+}
+
+func (p *Product) sendTaps(ticker *exchange.Ticker) {
+	p.tapsMu.Lock()
+	defer p.tapsMu.Unlock()
+
+	for _, tap := range p.taps {
+		select {
+		case tap.ch <- ticker:
+		default:
+			tap.ndropped++
+		}
+	}
+}
@@ -3,12 +3,15 @@
 package coinbase
 
 import (
+	"errors"
+	"fmt"
 	"slices"
 	"strings"
 
 	"github.com/bvk/tradebot/coinbase/internal"
 	"github.com/bvk/tradebot/exchange"
 	"github.com/bvk/tradebot/gobs"
+	"github.com/shopspring/decimal"
 )
 
 var doneStatuses []string = []string{
@@ -19,62 +22,161 @@ var readyStatuses []string = []string{
 	"OPEN", "FILLED", "CANCELLED", "EXPIRED", "FAILED",
 }
 
+// rejectedDoneReason returns the DoneReason for an order rejected by the
+// exchange after it was accepted (ex: self-trade prevention). Empty string is
+// returned if the order is not rejected.
+func rejectedDoneReason(rejectReason string) string {
+	if rejectReason == "" || rejectReason == "REJECT_REASON_UNSPECIFIED" {
+		return ""
+	}
+	return "REJECTED: " + rejectReason
+}
+
+// clientOrderIDTagSep separates the order tag from the client order id.
+// Coinbase doesn't support a memo field for the orders, so the tag is encoded
+// as a prefix of the client order id.
+const clientOrderIDTagSep = ":"
+
+// MaxTagLength is the maximum length of the order tag encoded in the client
+// order id.
+const MaxTagLength = 40
+
+func encodeClientOrderID(clientOrderID, tag string) string {
+	if len(tag) == 0 {
+		return clientOrderID
+	}
+	if len(tag) > MaxTagLength {
+		tag = tag[:MaxTagLength]
+	}
+	tag = strings.ReplaceAll(tag, clientOrderIDTagSep, "_")
+	return tag + clientOrderIDTagSep + clientOrderID
+}
+
+// splitClientOrderID returns the client order id and the tag from a client
+// order id created by encodeClientOrderID.
+func splitClientOrderID(s string) (clientOrderID, tag string) {
+	if i := strings.LastIndex(s, clientOrderIDTagSep); i >= 0 {
+		return s[i+1:], s[:i]
+	}
+	return s, ""
+}
+
 func gobOrderFromOrder(v *internal.Order) *gobs.Order {
 	order := &gobs.Order{
 		ServerOrderID: v.OrderID,
-		ClientOrderID: v.ClientOrderID,
 		CreateTime:    gobs.RemoteTime{Time: v.CreatedTime.Time},
 		FinishTime:    gobs.RemoteTime{Time: v.LastFillTime.Time},
 		Side:          v.Side,
 		FilledFee:     v.TotalFees.Decimal,
+		FeeCurrency:   exchange.QuoteCurrency(v.ProductID),
 		FilledSize:    v.FilledSize.Decimal,
 		FilledPrice:   v.AvgFilledPrice.Decimal,
 		Status:        v.Status,
 		Done:          slices.Contains(doneStatuses, v.Status),
 	}
+	order.ClientOrderID, order.Tag = splitClientOrderID(v.ClientOrderID)
 	if order.Done && order.Status != "FILLED" {
 		order.DoneReason = order.Status
 	}
+	if reason := rejectedDoneReason(v.RejectReason); reason != "" {
+		order.Done, order.DoneReason = true, reason
+	}
 	return order
 }
 
 func exchangeOrderFromOrder(v *internal.Order) *exchange.Order {
 	order := &exchange.Order{
-		ClientOrderID: v.ClientOrderID,
-		OrderID:       exchange.OrderID(v.OrderID),
-		CreateTime:    exchange.RemoteTime{Time: v.CreatedTime.Time},
-		FinishTime:    exchange.RemoteTime{Time: v.LastFillTime.Time},
-		Side:          v.Side,
-		Fee:           v.TotalFees.Decimal,
-		FilledSize:    v.FilledSize.Decimal,
-		FilledPrice:   v.AvgFilledPrice.Decimal,
-		Status:        v.Status,
-		Done:          slices.Contains(doneStatuses, v.Status),
+		OrderID:     exchange.OrderID(v.OrderID),
+		CreateTime:  exchange.RemoteTime{Time: v.CreatedTime.Time},
+		FinishTime:  exchange.RemoteTime{Time: v.LastFillTime.Time},
+		Side:        v.Side,
+		Fee:         v.TotalFees.Decimal,
+		FeeCurrency: exchange.QuoteCurrency(v.ProductID),
+		FilledSize:  v.FilledSize.Decimal,
+		FilledPrice: v.AvgFilledPrice.Decimal,
+		Status:      v.Status,
+		Done:        slices.Contains(doneStatuses, v.Status),
 	}
+	order.ClientOrderID, order.Tag = splitClientOrderID(v.ClientOrderID)
 	if order.Done && order.Status != "FILLED" {
 		order.DoneReason = order.Status
 	}
+	if reason := rejectedDoneReason(v.RejectReason); reason != "" {
+		order.Done, order.DoneReason = true, reason
+	}
 	return order
 }
 
 func exchangeOrderFromEvent(event *internal.OrderEvent) *exchange.Order {
 	order := &exchange.Order{
-		OrderID:       exchange.OrderID(event.OrderID),
-		ClientOrderID: event.ClientOrderID,
-		CreateTime:    exchange.RemoteTime{Time: event.CreatedTime.Time},
-		Side:          event.OrderSide,
-		Status:        event.Status,
-		Done:          slices.Contains(doneStatuses, event.Status),
-		FilledSize:    event.CumulativeQuantity.Decimal,
-		FilledPrice:   event.AvgPrice.Decimal,
-		Fee:           event.TotalFees.Decimal,
+		OrderID:     exchange.OrderID(event.OrderID),
+		CreateTime:  exchange.RemoteTime{Time: event.CreatedTime.Time},
+		Side:        event.OrderSide,
+		Status:      event.Status,
+		Done:        slices.Contains(doneStatuses, event.Status),
+		FilledSize:  event.CumulativeQuantity.Decimal,
+		FilledPrice: event.AvgPrice.Decimal,
+		Fee:         event.TotalFees.Decimal,
+		FeeCurrency: exchange.QuoteCurrency(event.ProductID),
 	}
+	order.ClientOrderID, order.Tag = splitClientOrderID(event.ClientOrderID)
 	if order.Done && event.Status != "FILLED" {
 		order.DoneReason = event.Status
 	}
+	if reason := rejectedDoneReason(event.RejectReason); reason != "" {
+		order.Done, order.DoneReason = true, reason
+	}
 	return order
 }
 
+// createOrderError returns an error for the unsuccessful create order
+// response. Insufficient funds failures are wrapped with the
+// exchange.ErrInsufficientFunds error and invalid product failures are wrapped
+// with the exchange.ErrProductDelisted error.
+func createOrderError(resp *internal.CreateOrderResponse) error {
+	if v := resp.ErrorResponse; v != nil {
+		if strings.Contains(v.Error, "INSUFFICIENT_FUND") || strings.Contains(v.PreviewFailureReason, "INSUFFICIENT_FUND") {
+			return fmt.Errorf("%s: %w", resp.FailureReason, exchange.ErrInsufficientFunds)
+		}
+		if strings.Contains(v.Error, "INVALID_PRODUCT_ID") || strings.Contains(v.PreviewFailureReason, "INVALID_PRODUCT_ID") {
+			return fmt.Errorf("%s: %w", resp.FailureReason, exchange.ErrProductDelisted)
+		}
+	}
+	return errors.New(resp.FailureReason)
+}
+
+// sumFillFees returns the total commission charged for the fills.
+func sumFillFees(fills []*internal.Fill) decimal.Decimal {
+	var sum decimal.Decimal
+	for _, fill := range fills {
+		sum = sum.Add(fill.Commission.Decimal)
+	}
+	return sum
+}
+
+// fillsLiquidity returns the liquidity indicator for an order from it's fills,
+// which is empty when none of the fills have a known liquidity indicator.
+func fillsLiquidity(fills []*internal.Fill) string {
+	var maker, taker bool
+	for _, fill := range fills {
+		switch fill.LiquidityIndicator {
+		case "MAKER":
+			maker = true
+		case "TAKER":
+			taker = true
+		}
+	}
+	switch {
+	case maker && taker:
+		return exchange.LiquidityMixed
+	case maker:
+		return exchange.LiquidityMaker
+	case taker:
+		return exchange.LiquidityTaker
+	}
+	return ""
+}
+
 func compareFilledSize(a, b *internal.Order) int {
 	return a.FilledSize.Decimal.Cmp(b.FilledSize.Decimal)
 }
@@ -4,12 +4,57 @@ package exchange
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"net"
+	"os"
+	"time"
 
 	"github.com/bvk/tradebot/gobs"
 	"github.com/shopspring/decimal"
 )
 
+// ErrInsufficientFunds is returned when an order cannot be created because
+// the account doesn't have enough funds.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrNotFound is returned when the exchange doesn't know about an order. It
+// wraps os.ErrNotExist.
+var ErrNotFound = fmt.Errorf("not found: %w", os.ErrNotExist)
+
+// ErrOrderDone is returned when an order cannot be canceled because it is
+// already done at the exchange, e.g., when it is filled just before the
+// cancel request.
+var ErrOrderDone = errors.New("order is already done")
+
+// ErrTransient is wrapped by the errors that may go away when the operation is
+// retried later, like the exchange server errors and the rate limits.
+var ErrTransient = errors.New("transient error")
+
+// ErrFatal is wrapped by the errors that cannot go away by retrying the
+// operation, like the operations on an unknown or delisted product.
+var ErrFatal = errors.New("fatal error")
+
+// ErrProductDelisted is returned when the product is not (or no longer)
+// traded on the exchange. It wraps ErrFatal.
+var ErrProductDelisted = fmt.Errorf("product is delisted: %w", ErrFatal)
+
+// IsTransient returns true if the error wraps ErrTransient or is a network
+// timeout.
+func IsTransient(err error) bool {
+	if errors.Is(err, ErrTransient) {
+		return true
+	}
+	var nerr net.Error
+	return errors.As(err, &nerr) && nerr.Timeout()
+}
+
+// IsFatal returns true if the error wraps ErrFatal.
+func IsFatal(err error) bool {
+	return errors.Is(err, ErrFatal)
+}
+
 type OrderID string
 
 type Order struct {
@@ -17,15 +62,33 @@ type Order struct {
 
 	ClientOrderID string
 
+	// Tag is the optional human-readable memo for the order.
+	Tag string
+
 	Side string
 
 	CreateTime RemoteTime
 	FinishTime RemoteTime
 
+	// Fee is the cumulative fee for all fills of the order so far and
+	// LastFillFee is the fee charged for the most recent fill(s) since the
+	// previous update.
 	Fee         decimal.Decimal
+	LastFillFee decimal.Decimal
+
+	// FeeCurrency is the currency for the fee values. An empty value indicates
+	// the quote currency of the product.
+	FeeCurrency string
+
 	FilledSize  decimal.Decimal
 	FilledPrice decimal.Decimal
 
+	// Liquidity indicates if the order's fills have added (maker) or removed
+	// (taker) the liquidity from the order book. It is one of the Liquidity*
+	// constants or empty when it is not known, e.g., for the orders that are
+	// not complete yet and the orders recorded before it was tracked.
+	Liquidity string
+
 	Status string
 
 	// Done is true if order is complete. DoneReason below indicates if order has
@@ -38,9 +101,63 @@ type Order struct {
 	DoneReason string
 }
 
+// Liquidity values for the filled orders.
+const (
+	LiquidityMaker = "MAKER"
+	LiquidityTaker = "TAKER"
+
+	// LiquidityMixed indicates that the order has both maker and taker fills.
+	LiquidityMixed = "MIXED"
+)
+
+// Price sources for the ticker price.
+const (
+	// PriceSourceLastTrade uses the price of the last trade as the ticker price.
+	PriceSourceLastTrade = "last-trade"
+
+	// PriceSourceMid uses the average of the best bid and the best ask prices
+	// as the ticker price.
+	PriceSourceMid = "mid"
+
+	// PriceSourceIndex uses the exchange's index price as the ticker price.
+	PriceSourceIndex = "index"
+)
+
 type Ticker struct {
 	Timestamp RemoteTime
-	Price     decimal.Decimal
+
+	// Price holds the ticker price from the price source selected for the
+	// product, which is the last trade price by default.
+	Price decimal.Decimal
+
+	// Bid and Ask hold the best bid and ask prices when they are known.
+	Bid decimal.Decimal
+	Ask decimal.Decimal
+
+	// BidSize and AskSize hold the sizes available at the best bid and ask
+	// prices when they are known.
+	BidSize decimal.Decimal
+	AskSize decimal.Decimal
+}
+
+// BookImbalance returns the order book top imbalance as (bid-size -
+// ask-size)/(bid-size + ask-size), which is positive when there are more bids
+// than asks. Returns false when the book top sizes are not known.
+func (t *Ticker) BookImbalance() (decimal.Decimal, bool) {
+	if !t.BidSize.IsPositive() || !t.AskSize.IsPositive() {
+		return decimal.Zero, false
+	}
+	total := t.BidSize.Add(t.AskSize)
+	return t.BidSize.Sub(t.AskSize).Div(total), true
+}
+
+// OrderUpdatesFilterer is implemented by the products that can deliver only
+// the order updates selected by a match function, so that the subscribers are
+// not woken up for the updates of the orders owned by other jobs. Match
+// function is called on the product's dispatch path, so it must be cheap and
+// must not block.
+type OrderUpdatesFilterer interface {
+	OrderUpdatesChFiltered(match func(*Order) bool) (ch <-chan *Order, stopf func())
 }
 
 type Product interface {
@@ -49,19 +166,73 @@ type Product interface {
 	ProductID() string
 	ExchangeName() string
 	BaseMinSize() decimal.Decimal
+	BaseIncrement() decimal.Decimal
+
+	// LastPrice returns the most recent trade price for the product.
+	LastPrice(ctx context.Context) (decimal.Decimal, error)
 
 	TickerCh() (ch <-chan *Ticker, stopf func())
 	OrderUpdatesCh() (ch <-chan *Order, stopf func())
 
-	LimitBuy(ctx context.Context, clientOrderID string, size, price decimal.Decimal) (OrderID, error)
-	LimitSell(ctx context.Context, clientOrderID string, size, price decimal.Decimal) (OrderID, error)
+	// AvailableBalance returns the balance available for trading in the given
+	// currency. Products that do not support it return errors.ErrUnsupported.
+	AvailableBalance(ctx context.Context, currency string) (decimal.Decimal, error)
+
+	// ReconnectCh returns a channel that receives the time when the order
+	// updates feed is reconnected, after which some order updates may have
+	// been missed.
+	ReconnectCh() (ch <-chan time.Time, stopf func())
+
+	// LimitBuy and LimitSell create limit orders. Orders are good-till-cancel
+	// when goodTill is zero and expire at the exchange after goodTill duration
+	// otherwise. Optional tag is a human-readable memo that is attached to the
+	// order for external reconciliation.
+	LimitBuy(ctx context.Context, clientOrderID string, size, price decimal.Decimal, goodTill time.Duration, tag string) (OrderID, error)
+	LimitSell(ctx context.Context, clientOrderID string, size, price decimal.Decimal, goodTill time.Duration, tag string) (OrderID, error)
+
+	// MarketBuy and MarketSell create market orders for the base size, which
+	// are filled immediately at the best available prices.
+	MarketBuy(ctx context.Context, clientOrderID string, size decimal.Decimal) (OrderID, error)
+	MarketSell(ctx context.Context, clientOrderID string, size decimal.Decimal) (OrderID, error)
+
+	// StopLimitBuy and StopLimitSell create limit orders that are placed on the
+	// book at the limit price only after the market reaches the trigger price,
+	// i.e., when the price goes up to the trigger for buys and down to the
+	// trigger for sells. goodTill and tag are same as in LimitBuy.
+	StopLimitBuy(ctx context.Context, clientOrderID string, size, limitPrice, triggerPrice decimal.Decimal, goodTill time.Duration, tag string) (OrderID, error)
+	StopLimitSell(ctx context.Context, clientOrderID string, size, limitPrice, triggerPrice decimal.Decimal, goodTill time.Duration, tag string) (OrderID, error)
 
 	Get(ctx context.Context, id OrderID) (*Order, error)
 	Cancel(ctx context.Context, id OrderID) error
 
+	// Reduce shrinks an open order to the new total size (including the filled
+	// size) in place, so that the order keeps it's queue priority. Products
+	// that cannot edit the order return errors.ErrUnsupported, in which case
+	// callers must cancel and recreate the order instead.
+	Reduce(ctx context.Context, id OrderID, newSize decimal.Decimal) error
+
+	// Ping verifies that both the REST api and the ticker feed of the product
+	// are alive. Returns a non-nil error describing the failure otherwise.
+	Ping(ctx context.Context) error
+
 	// Retire(id OrderID)
 }
 
+// AuthInfo describes the account and permissions of the credentials used for
+// an exchange.
+type AuthInfo struct {
+	AccountID   string
+	AccountType string
+
+	// Permissions holds the names of the operations allowed for the
+	// credentials, e.g., "view", "trade", "transfer", etc.
+	Permissions []string
+
+	PricingTier string
+	MakerFeePct decimal.Decimal
+	TakerFeePct decimal.Decimal
+}
+
 type Exchange interface {
 	io.Closer
 
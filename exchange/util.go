@@ -4,6 +4,7 @@ package exchange
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/bvk/tradebot/gobs"
@@ -18,6 +19,7 @@ func Equal(a, b *Order) bool {
 		a.Fee.Equal(b.Fee) &&
 		a.FilledSize.Equal(b.FilledSize) &&
 		a.FilledPrice.Equal(b.FilledPrice) &&
+		a.Liquidity == b.Liquidity &&
 		a.Status == b.Status &&
 		a.Done == b.Done &&
 		a.DoneReason == b.DoneReason
@@ -34,17 +36,21 @@ func Merge(known, update *Order) *Order {
 	if known.ClientOrderID == "" && update.ClientOrderID != "" {
 		tmp.ClientOrderID = update.ClientOrderID
 	}
+	if known.Tag == "" && update.Tag != "" {
+		tmp.Tag = update.Tag
+	}
 	if known.Side == "" && update.Side != "" {
 		tmp.Side = update.Side
 	}
 	if known.CreateTime.IsZero() && !update.CreateTime.IsZero() {
 		tmp.CreateTime = update.CreateTime
 	}
-	if known.Fee.IsZero() && !update.Fee.IsZero() {
-		tmp.Fee = update.Fee
+	if known.FeeCurrency == "" && update.FeeCurrency != "" {
+		tmp.FeeCurrency = update.FeeCurrency
 	}
 	if known.Fee.LessThan(update.Fee) {
 		tmp.Fee = update.Fee
+		tmp.LastFillFee = update.LastFillFee
 	}
 	if known.FilledSize.LessThan(update.FilledSize) {
 		tmp.FilledSize = update.FilledSize
@@ -53,6 +59,9 @@ func Merge(known, update *Order) *Order {
 	if known.FilledPrice.IsZero() && !update.FilledPrice.IsZero() {
 		tmp.FilledPrice = update.FilledPrice
 	}
+	if known.Liquidity == "" && update.Liquidity != "" {
+		tmp.Liquidity = update.Liquidity
+	}
 	if known.Status == "" && update.Status != "" {
 		tmp.Status = update.Status
 	}
@@ -71,6 +80,26 @@ func Merge(known, update *Order) *Order {
 	return tmp
 }
 
+// IsStale returns true if the update for an order is older than the known
+// state of the order. Order updates can arrive out of order, so applying a
+// stale update could regress the filled size or the done status. Finish
+// times are compared only when both orders have them.
+func IsStale(known, update *Order) bool {
+	if known.OrderID != update.OrderID {
+		return false
+	}
+	if update.FilledSize.LessThan(known.FilledSize) {
+		return true
+	}
+	if known.Done && !update.Done {
+		return true
+	}
+	if !known.FinishTime.Time.IsZero() && !update.FinishTime.Time.IsZero() {
+		return update.FinishTime.Time.Before(known.FinishTime.Time)
+	}
+	return false
+}
+
 func (v *Order) String() string {
 	return fmt.Sprintf("{ID: %s ClientID %s Side %s Price %s Size %s Fee %s Status %s CreatedAt %s}",
 		v.OrderID, v.ClientOrderID, v.Side, v.FilledPrice.StringFixed(3), v.FilledSize.StringFixed(3), v.Fee.StringFixed(3), v.Status, v.CreateTime.Time.Format(time.DateTime))
@@ -100,12 +129,57 @@ func FilledFee(vs []*gobs.Order) decimal.Decimal {
 	return sum
 }
 
-func AvgPrice(vs []*gobs.Order) decimal.Decimal {
+// IsQuoteFee returns true if the order fee is in the quote currency.
+func IsQuoteFee(v *gobs.Order, quote string) bool {
+	return v.FeeCurrency == "" || v.FeeCurrency == quote
+}
+
+// FilledQuoteFee returns the total fee for the orders that are charged in the
+// quote currency.
+func FilledQuoteFee(vs []*gobs.Order, quote string) decimal.Decimal {
 	var sum decimal.Decimal
 	for _, v := range vs {
-		sum = sum.Add(v.FilledPrice)
+		if IsQuoteFee(v, quote) {
+			sum = sum.Add(v.FilledFee)
+		}
+	}
+	return sum
+}
+
+// AddOtherFees adds the fees for the orders that are not charged in the quote
+// currency to the currency keyed map.
+func AddOtherFees(feeMap map[string]decimal.Decimal, vs []*gobs.Order, quote string) {
+	for _, v := range vs {
+		if !IsQuoteFee(v, quote) && !v.FilledFee.IsZero() {
+			feeMap[v.FeeCurrency] = feeMap[v.FeeCurrency].Add(v.FilledFee)
+		}
+	}
+}
+
+// QuoteCurrency returns the quote currency from a BASE-QUOTE style product id.
+func QuoteCurrency(productID string) string {
+	if _, quote, ok := strings.Cut(productID, "-"); ok {
+		return quote
+	}
+	return ""
+}
+
+// BaseCurrency returns the base currency from a BASE-QUOTE style product id.
+func BaseCurrency(productID string) string {
+	base, _, _ := strings.Cut(productID, "-")
+	return base
+}
+
+func AvgPrice(vs []*gobs.Order) decimal.Decimal {
+	var size, value decimal.Decimal
+	for _, v := range vs {
+		size = size.Add(v.FilledSize)
+		value = value.Add(v.FilledSize.Mul(v.FilledPrice))
+	}
+	if size.IsZero() {
+		return decimal.Zero
 	}
-	return sum.Div(decimal.NewFromInt(int64(len(vs))))
+	return value.Div(size)
 }
 
 func MaxPrice(vs []*gobs.Order) decimal.Decimal {
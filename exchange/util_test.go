@@ -0,0 +1,78 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/shopspring/decimal"
+)
+
+func TestMergeLiquidity(t *testing.T) {
+	known := &Order{OrderID: "a", FilledSize: decimal.NewFromInt(1), Status: "FILLED", Done: true}
+	update := &Order{OrderID: "a", FilledSize: decimal.NewFromInt(1), Status: "FILLED", Done: true, Liquidity: LiquidityMaker}
+
+	merged := Merge(known, update)
+	if merged.Liquidity != LiquidityMaker {
+		t.Fatalf("want liquidity %q from the update, got %q", LiquidityMaker, merged.Liquidity)
+	}
+	if Equal(known, merged) {
+		t.Fatalf("orders with different liquidity must not be equal")
+	}
+
+	// An update without liquidity must not clear the known value.
+	if v := Merge(merged, known); v.Liquidity != LiquidityMaker {
+		t.Fatalf("want liquidity %q to be kept, got %q", LiquidityMaker, v.Liquidity)
+	}
+}
+
+func TestIsStale(t *testing.T) {
+	d := decimal.RequireFromString
+	now := time.Now()
+
+	known := &Order{OrderID: "a", FilledSize: d("1"), FinishTime: RemoteTime{Time: now}, Done: true}
+	testCases := []struct {
+		name   string
+		update *Order
+		stale  bool
+	}{
+		{"fresh", &Order{OrderID: "a", FilledSize: d("1"), FinishTime: RemoteTime{Time: now.Add(time.Second)}, Done: true}, false},
+		{"same", &Order{OrderID: "a", FilledSize: d("1"), FinishTime: RemoteTime{Time: now}, Done: true}, false},
+		{"older-finish-time", &Order{OrderID: "a", FilledSize: d("1"), FinishTime: RemoteTime{Time: now.Add(-time.Second)}, Done: true}, true},
+		{"zero-finish-time", &Order{OrderID: "a", FilledSize: d("1"), Done: true}, false},
+		{"smaller-filled-size", &Order{OrderID: "a", FilledSize: d("0.5"), Done: true}, true},
+		{"not-done", &Order{OrderID: "a", FilledSize: d("1")}, true},
+		{"other-order", &Order{OrderID: "b"}, false},
+	}
+	for _, tc := range testCases {
+		if v := IsStale(known, tc.update); v != tc.stale {
+			t.Errorf("%s: want stale %t, got %t", tc.name, tc.stale, v)
+		}
+	}
+
+	// Updates for an order with zero finish time are never stale by time.
+	open := &Order{OrderID: "a", FilledSize: d("0.5")}
+	if IsStale(open, &Order{OrderID: "a", FilledSize: d("0.5"), FinishTime: RemoteTime{Time: now}}) {
+		t.Fatalf("want update with finish time to be fresh for an open order")
+	}
+}
+
+func TestAvgPrice(t *testing.T) {
+	d := decimal.RequireFromString
+	if v := AvgPrice(nil); !v.IsZero() {
+		t.Fatalf("want zero average price without orders, got %s", v)
+	}
+
+	// Orders are weighted by their filled sizes and unfilled orders are
+	// ignored.
+	orders := []*gobs.Order{
+		{FilledSize: d("1"), FilledPrice: d("100")},
+		{FilledSize: d("3"), FilledPrice: d("96")},
+		{FilledSize: d("0"), FilledPrice: d("90")},
+	}
+	if v, want := AvgPrice(orders), d("97"); !v.Equal(want) {
+		t.Fatalf("want average price %s, got %s", want, v)
+	}
+}
@@ -16,6 +16,9 @@ type Order struct {
 	ServerOrderID string
 	ClientOrderID string
 
+	// Tag is the optional human-readable memo for the order.
+	Tag string
+
 	CreateTime RemoteTime
 	FinishTime RemoteTime
 
@@ -26,6 +29,14 @@ type Order struct {
 	FilledSize  decimal.Decimal
 	FilledPrice decimal.Decimal
 
+	// FeeCurrency is the currency for the FilledFee. An empty value indicates
+	// the quote currency of the product.
+	FeeCurrency string
+
+	// Liquidity is one of MAKER, TAKER or MIXED for the filled orders. An
+	// empty value indicates that it is not known.
+	Liquidity string
+
 	Done       bool
 	DoneReason string
 }
@@ -81,3 +92,18 @@ type Account struct {
 type Accounts struct {
 	Accounts []*Account
 }
+
+// PriceSnapshot holds the prices of multiple products taken at the same time,
+// so that all jobs can be valued against the same marks.
+type PriceSnapshot struct {
+	Timestamp time.Time
+
+	Prices []*ProductPrice
+}
+
+type ProductPrice struct {
+	ExchangeName string
+	ProductID    string
+
+	Price decimal.Decimal
+}
@@ -2,6 +2,8 @@
 
 package gobs
 
+import "time"
+
 type JobExportData struct {
 	UID      string
 	Name     string
@@ -20,3 +22,9 @@ type JobData struct {
 
 	State string
 }
+
+// JobNote holds a free-form operator note attached to a job.
+type JobNote struct {
+	Note       string
+	UpdateTime time.Time
+}
@@ -2,6 +2,12 @@
 
 package gobs
 
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
 type LimiterState struct {
 	V2 *LimiterStateV2
 }
@@ -14,6 +20,37 @@ type LimiterStateV2 struct {
 	TradePoint       Point
 	ServerIDOrderMap map[string]*Order
 	Options          map[string]string
+
+	// LastActionTime is the time of the last order create or cancel action.
+	LastActionTime time.Time
+
+	// MarketFillAnchor is the creation time of the first limit order, which is
+	// the anchor for the market-fill-after deadline.
+	MarketFillAnchor time.Time
+
+	// OneShotCanceled is true when a one-shot limiter's order was canceled by
+	// the cancel threshold, after which no more orders are created.
+	OneShotCanceled bool
+
+	// ForceCompleted is true when the limiter is marked as complete manually
+	// with ResidualSize left unfilled.
+	ForceCompleted bool
+	ResidualSize   decimal.Decimal
+
+	// ArchivedOrders is the number of completed orders moved out of the
+	// ServerIDOrderMap into a separate keyspace. Archived* fields hold the
+	// cumulative fills of the archived orders.
+	ArchivedOrders      int
+	ArchivedFilledSize  decimal.Decimal
+	ArchivedFilledValue decimal.Decimal
+	ArchivedFees        decimal.Decimal
+	ArchivedStartTime   time.Time
+}
+
+// LimiterOffset holds the client id offset of a limiter, which is saved
+// separately from the LimiterState when only the offset has changed.
+type LimiterOffset struct {
+	ClientIDOffset uint64
 }
 
 func (v *LimiterState) Upgrade() {
@@ -21,3 +58,22 @@ func (v *LimiterState) Upgrade() {
 		v.V2.ExchangeName = "coinbase"
 	}
 }
+
+// LimiterTrailEntry records an order create or cancel decision made by a
+// limiter along with the ticker price that triggered it.
+type LimiterTrailEntry struct {
+	Time   time.Time
+	Action string
+	Reason string
+
+	TickerPrice decimal.Decimal
+
+	ServerOrderID string
+	ClientOrderID string
+
+	// Latency is the time taken by the exchange to complete the action.
+	Latency time.Duration
+
+	// Error is non-empty when the action has failed.
+	Error string
+}
@@ -2,6 +2,12 @@
 
 package gobs
 
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
 type LooperState struct {
 	V2 *LooperStateV2
 }
@@ -11,6 +17,19 @@ type LooperStateV2 struct {
 	ExchangeName string
 	LimiterIDs   []string
 	TradePair    Pair
+	Options      map[string]string
+
+	// State holds the name of the looper's buy-sell cycle state. It is empty
+	// for the loopers saved by older versions.
+	State string
+
+	// Budget holds the max cost of unsold buys allowed for the looper. It is
+	// zero when the looper has no budget limit.
+	Budget decimal.Decimal
+
+	// LastBuyTime holds the time when the most recent child buy limiter was
+	// added. It is zero for the loopers saved by older versions.
+	LastBuyTime time.Time
 }
 
 func (v *LooperState) Upgrade() {
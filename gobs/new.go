@@ -13,6 +13,8 @@ func NewByTypename(typename string) (any, error) {
 		v = new(JobData)
 	case "LimiterState":
 		v = new(LimiterState)
+	case "LimiterTrailEntry":
+		v = new(LimiterTrailEntry)
 	case "LooperState":
 		v = new(LooperState)
 	case "WallerState":
@@ -35,6 +37,8 @@ func NewByTypename(typename string) (any, error) {
 		v = new(CoinbaseAccounts)
 	case "CoinbaseProducts":
 		v = new(CoinbaseProducts)
+	case "PriceSnapshot":
+		v = new(PriceSnapshot)
 	default:
 		return nil, fmt.Errorf("unsupported type name %q", typename)
 	}
@@ -8,6 +8,19 @@ type Point struct {
 	Size   decimal.Decimal
 	Price  decimal.Decimal
 	Cancel decimal.Decimal
+
+	// SizeInQuote when true, indicates that Size is in quote currency units
+	// (i.e., the amount of funds to spend or receive) instead of the base units.
+	SizeInQuote bool
+
+	// CancelPct when non-zero, is the cancel threshold as a percentage offset
+	// from the Price. It is resolved to an absolute Cancel price based on the
+	// side; an absolute Cancel price, when set, takes precedence.
+	CancelPct decimal.Decimal
+
+	// Tag is an optional label, like the name of the strategy that generated
+	// the point. It is metadata only and doesn't affect the trading.
+	Tag string
 }
 
 type Pair struct {
@@ -2,6 +2,8 @@
 
 package gobs
 
+import "time"
+
 type ServerExchangeState struct {
 	EnabledProductIDs []string
 
@@ -11,3 +13,13 @@ type ServerExchangeState struct {
 type ServerState struct {
 	ExchangeMap map[string]*ServerExchangeState
 }
+
+// KillSwitch holds the state of an engaged kill-switch. No jobs are started or
+// resumed while it is engaged.
+type KillSwitch struct {
+	EngageTime time.Time
+	Reason     string
+
+	// JobIDs holds the uids of the jobs that were halted by the kill-switch.
+	JobIDs []string
+}
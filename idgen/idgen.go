@@ -59,6 +59,27 @@ func (v *Generator) RevertID() {
 	}
 }
 
+// IDAt returns the id generated at the given offset, which is the same id
+// returned by NextID when the generator is at that offset.
+func (v *Generator) IDAt(offset uint64) uuid.UUID {
+	return v.prepare(offset, 1)[0]
+}
+
+// LastUsed returns the largest offset below the limit whose id is accepted by
+// the used function. Returns false if no such offset is found.
+func (v *Generator) LastUsed(limit uint64, used func(uuid.UUID) bool) (uint64, bool) {
+	var last uint64
+	found := false
+	for from := uint64(0); from < limit; from += 10 {
+		for i, id := range v.prepare(from, min(10, limit-from)) {
+			if used(id) {
+				last, found = from+uint64(i), true
+			}
+		}
+	}
+	return last, found
+}
+
 func (v *Generator) prepare(from, n uint64) []uuid.UUID {
 	var buf [16 + 8]byte
 	copy(buf[:16], []byte(v.base[:]))
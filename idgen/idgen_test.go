@@ -64,3 +64,37 @@ func TestIDGenRevert(t *testing.T) {
 		t.Fatalf("want %v, got %v", wanted, id)
 	}
 }
+
+func TestLastUsed(t *testing.T) {
+	g := New("unique message id", 0)
+	used := make(map[uuid.UUID]bool)
+	for i := 0; i < 25; i++ {
+		id := g.NextID()
+		if i == 3 || i == 17 {
+			used[id] = true
+		}
+	}
+
+	check := func(id uuid.UUID) bool { return used[id] }
+	if last, ok := New("unique message id", 0).LastUsed(100, check); !ok || last != 17 {
+		t.Fatalf("want last used offset 17, got %d (%t)", last, ok)
+	}
+	if last, ok := New("unique message id", 0).LastUsed(10, check); !ok || last != 3 {
+		t.Fatalf("want last used offset 3 below the limit, got %d (%t)", last, ok)
+	}
+	if _, ok := New("other message id", 0).LastUsed(100, check); ok {
+		t.Fatalf("ids from a different seed must not be found")
+	}
+}
+
+func TestIDAt(t *testing.T) {
+	g := New(t.Name(), 0)
+	for i := uint64(0); i < 25; i++ {
+		if a, b := g.IDAt(i), g.NextID(); a != b {
+			t.Fatalf("offset %d: want %v, got %v", i, b, a)
+		}
+	}
+	if g.Offset() != 25 {
+		t.Fatalf("IDAt must not change the offset")
+	}
+}
@@ -46,6 +46,22 @@ func (j *Job) Wait() {
 	j.wg.Wait()
 }
 
+// WaitContext is like Wait, but returns the context error if the context is
+// done before the job is stopped.
+func (j *Job) WaitContext(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
 func (j *Job) State() State {
 	j.mu.Lock()
 	defer j.mu.Unlock()
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestPauseResume(t *testing.T) {
@@ -41,3 +42,31 @@ func TestPauseResume(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestWaitContext(t *testing.T) {
+	release := make(chan struct{})
+	jobf := func(ctx context.Context) error {
+		<-ctx.Done()
+		<-release
+		return context.Cause(ctx)
+	}
+	j := Run(jobf, context.Background())
+	j.Pause()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := j.WaitContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want deadline exceeded error, got %v", err)
+	}
+	if j.State() != RUNNING {
+		t.Fatalf("job must still be running")
+	}
+
+	close(release)
+	if err := j.WaitContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if j.State() != PAUSED {
+		t.Fatalf("job must be paused")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"log"
 	"os"
 	"path"
+	"sort"
 	"strings"
 	"sync"
 
@@ -100,20 +101,40 @@ func (r *Runner) syncLocked(ctx context.Context, rw kv.ReadWriter) error {
 }
 
 func (r *Runner) StopAll(ctx context.Context, rw kv.ReadWriter) error {
-	var jobs []*Job
+	r.PauseAll(context.Background())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.syncLocked(ctx, rw)
+}
+
+// PauseAll pauses all running jobs and waits for them to stop till the input
+// context is done. Returns the uids of the jobs that are still running when
+// the context is done.
+func (r *Runner) PauseAll(ctx context.Context) []string {
+	jobs := make(map[string]*Job)
 
 	r.mu.Lock()
 	for uid, job := range r.jobMap {
 		job.Pause()
 		delete(r.jobMap, uid)
-		jobs = append(jobs, job)
+		jobs[uid] = job
 	}
 	r.mu.Unlock()
 
-	for _, job := range jobs {
-		job.Wait()
+	var running []string
+	for uid, job := range jobs {
+		if err := job.WaitContext(ctx); err != nil {
+			running = append(running, uid)
+		}
 	}
+	sort.Strings(running)
+	return running
+}
 
+// Sync saves the metadata for all jobs to the database.
+func (r *Runner) Sync(ctx context.Context, rw kv.ReadWriter) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -138,6 +159,21 @@ func (r *Runner) wrapJobFunc(uid string, fn Func) Func {
 	}
 }
 
+// Running returns the uids of all jobs that are in RUNNING state according to
+// the runner.
+func (r *Runner) Running() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var uids []string
+	for uid, job := range r.jobMap {
+		if job.State() == RUNNING {
+			uids = append(uids, uid)
+		}
+	}
+	return uids
+}
+
 // Get returns a job's information.
 func (r *Runner) Get(ctx context.Context, reader kv.Reader, uid string) (*JobData, error) {
 	r.mu.Lock()
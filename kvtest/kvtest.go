@@ -0,0 +1,121 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+// Package kvtest contains helpers to test the persistent state saved in the
+// key-value databases.
+package kvtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/bvkgo/kv"
+	"github.com/bvkgo/kv/kvmemdb"
+)
+
+// Snapshot holds a copy of all key-value pairs in a database.
+type Snapshot map[string][]byte
+
+// New returns a new in-memory database.
+func New() kv.Database {
+	return kvmemdb.New()
+}
+
+// Take returns a copy of all key-value pairs from the database.
+func Take(ctx context.Context, db kv.Database) (Snapshot, error) {
+	snap := make(Snapshot)
+	collect := func(ctx context.Context, r kv.Reader) error {
+		it, err := r.Scan(ctx)
+		if err != nil {
+			return fmt.Errorf("could not create scanning iterator: %w", err)
+		}
+		defer kv.Close(it)
+
+		for k, v, err := it.Fetch(ctx, false); err == nil; k, v, err = it.Fetch(ctx, true) {
+			value, err := io.ReadAll(v)
+			if err != nil {
+				return fmt.Errorf("could not read value at key %q: %w", k, err)
+			}
+			snap[k] = value
+		}
+		if _, _, err := it.Fetch(ctx, false); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("iterator fetch has failed: %w", err)
+		}
+		return nil
+	}
+	if err := kv.WithReader(ctx, db, collect); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Keys returns all keys in the snapshot in sorted order.
+func (s Snapshot) Keys() []string {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Diff returns human readable differences between two snapshots in the
+// sorted key order. Returns nil if both snapshots are the same.
+func Diff(a, b Snapshot) []string {
+	var diffs []string
+	for _, k := range a.Keys() {
+		bv, ok := b[k]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("-%s", k))
+			continue
+		}
+		if !bytes.Equal(a[k], bv) {
+			diffs = append(diffs, fmt.Sprintf("~%s", k))
+		}
+	}
+	for _, k := range b.Keys() {
+		if _, ok := a[k]; !ok {
+			diffs = append(diffs, fmt.Sprintf("+%s", k))
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i][1:] < diffs[j][1:]
+	})
+	return diffs
+}
+
+// CheckGolden compares the snapshot with the snapshot saved in the golden
+// file. Golden file is (re)written with the snapshot when update is true or
+// if the UPDATE_GOLDEN environment variable is set.
+func CheckGolden(t testing.TB, snap Snapshot, file string, update bool) {
+	t.Helper()
+
+	if update || os.Getenv("UPDATE_GOLDEN") != "" {
+		data, err := json.MarshalIndent(snap, "", "  ")
+		if err != nil {
+			t.Fatalf("could not marshal snapshot: %v", err)
+		}
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			t.Fatalf("could not write golden file %q: %v", file, err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("could not read golden file %q: %v", file, err)
+	}
+	golden := make(Snapshot)
+	if err := json.Unmarshal(data, &golden); err != nil {
+		t.Fatalf("could not unmarshal golden file %q: %v", file, err)
+	}
+	if diffs := Diff(golden, snap); len(diffs) != 0 {
+		t.Fatalf("snapshot doesn't match the golden file %q: %v", file, diffs)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package kvtest
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bvkgo/kv"
+)
+
+func TestSnapshotDiffGolden(t *testing.T) {
+	ctx := context.Background()
+
+	db := New()
+	set := func(key, value string) {
+		if err := kv.WithReadWriter(ctx, db, func(ctx context.Context, rw kv.ReadWriter) error {
+			return rw.Set(ctx, key, strings.NewReader(value))
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	set("/a", "1")
+	set("/b", "2")
+	s1, err := Take(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join(t.TempDir(), "golden.json")
+	CheckGolden(t, s1, golden, true /* update */)
+	CheckGolden(t, s1, golden, false)
+
+	set("/b", "3")
+	set("/c", "4")
+	s2, err := Take(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"~/b", "+/c"}
+	if got := Diff(s1, s2); !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	if got := Diff(s2, s2); len(got) != 0 {
+		t.Fatalf("want no diffs, got %v", got)
+	}
+}
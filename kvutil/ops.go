@@ -45,6 +45,41 @@ func Export(ctx context.Context, r kv.Reader, w io.Writer) error {
 	return nil
 }
 
+// ExportPaths is similar to Export, but only exports the keys under the given
+// directory paths. Key-value items are encoded one at a time, so the entire
+// database is never held in memory.
+func ExportPaths(ctx context.Context, r kv.Reader, w io.Writer, dirs ...string) error {
+	encoder := gob.NewEncoder(w)
+	for _, dir := range dirs {
+		begin, end := PathRange(dir)
+		it, err := r.Ascend(ctx, begin, end)
+		if err != nil {
+			return fmt.Errorf("could not create ascending iterator for %q: %w", dir, err)
+		}
+		for k, v, err := it.Fetch(ctx, false); err == nil; k, v, err = it.Fetch(ctx, true) {
+			value, err := io.ReadAll(v)
+			if err != nil {
+				kv.Close(it)
+				return fmt.Errorf("could not read value at key %q: %w", k, err)
+			}
+			item := &gobs.KeyValue{
+				Key:   k,
+				Value: value,
+			}
+			if err := encoder.Encode(item); err != nil {
+				kv.Close(it)
+				return fmt.Errorf("could not encode key/value item: %w", err)
+			}
+		}
+		if _, _, err := it.Fetch(ctx, false); err != nil && !errors.Is(err, io.EOF) {
+			kv.Close(it)
+			return fmt.Errorf("iterator fetch has failed: %w", err)
+		}
+		kv.Close(it)
+	}
+	return nil
+}
+
 func Import(ctx context.Context, r io.Reader, rw kv.ReadWriter) error {
 	decoder := gob.NewDecoder(r)
 
@@ -81,6 +116,19 @@ func DeleteAll(ctx context.Context, rw kv.ReadWriter) error {
 }
 
 func BackupDB(ctx context.Context, db kv.Database, file string) (status error) {
+	return exportFile(ctx, db, file, Export)
+}
+
+// ExportPathsDB is similar to BackupDB, but only saves the keys under the
+// given directory paths.
+func ExportPathsDB(ctx context.Context, db kv.Database, file string, dirs ...string) error {
+	export := func(ctx context.Context, r kv.Reader, w io.Writer) error {
+		return ExportPaths(ctx, r, w, dirs...)
+	}
+	return exportFile(ctx, db, file, export)
+}
+
+func exportFile(ctx context.Context, db kv.Database, file string, export func(context.Context, kv.Reader, io.Writer) error) (status error) {
 	abspath, err := filepath.Abs(file)
 	if err != nil {
 		return fmt.Errorf("could not determine absolute path: %w", err)
@@ -100,7 +148,7 @@ func BackupDB(ctx context.Context, db kv.Database, file string) (status error) {
 	bw := bufio.NewWriter(fp)
 
 	save := func(ctx context.Context, r kv.Reader) error {
-		if err := Export(ctx, r, bw); err != nil {
+		if err := export(ctx, r, bw); err != nil {
 			return fmt.Errorf("could not export db content: %w", err)
 		}
 		return nil
@@ -0,0 +1,86 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package kvutil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bvkgo/kv"
+	"github.com/bvkgo/kv/kvmemdb"
+)
+
+func TestExportPathsImport(t *testing.T) {
+	ctx := context.Background()
+
+	src := kvmemdb.New()
+	want := make(map[string]string)
+	populate := func(ctx context.Context, rw kv.ReadWriter) error {
+		for _, dir := range []string{"/limiters", "/loopers", "/wallers", "/names", "/other"} {
+			for i := 0; i < 100; i++ {
+				key := fmt.Sprintf("%s/%06d", dir, i)
+				value := strings.Repeat(key, i+1)
+				if err := rw.Set(ctx, key, strings.NewReader(value)); err != nil {
+					return err
+				}
+				if dir != "/other" {
+					want[key] = value
+				}
+			}
+		}
+		return nil
+	}
+	if err := kv.WithReadWriter(ctx, src, populate); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	export := func(ctx context.Context, r kv.Reader) error {
+		return ExportPaths(ctx, r, &buf, "/limiters", "/loopers", "/wallers", "/names")
+	}
+	if err := kv.WithReader(ctx, src, export); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := kvmemdb.New()
+	restore := func(ctx context.Context, rw kv.ReadWriter) error {
+		return Import(ctx, &buf, rw)
+	}
+	if err := kv.WithReadWriter(ctx, dst, restore); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	collect := func(ctx context.Context, r kv.Reader) error {
+		it, err := r.Scan(ctx)
+		if err != nil {
+			return err
+		}
+		defer kv.Close(it)
+
+		for k, v, err := it.Fetch(ctx, false); err == nil; k, v, err = it.Fetch(ctx, true) {
+			data, err := io.ReadAll(v)
+			if err != nil {
+				return err
+			}
+			got[k] = string(data)
+		}
+		return nil
+	}
+	if err := kv.WithReader(ctx, dst, collect); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("want %d keys, got %d keys", len(want), len(got))
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("key %q: want %q, got %q", k, v, got[k])
+		}
+	}
+}
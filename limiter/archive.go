@@ -0,0 +1,147 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvkgo/kv"
+	"github.com/shopspring/decimal"
+)
+
+// ArchiveKeyspace holds the completed orders moved out of the limiter order
+// maps by the archive-orders option. Orders are keyed by the limiter uid and
+// the server order id and they are never updated.
+const ArchiveKeyspace = "/limiter-archives/"
+
+// archiveTotals holds the cumulative fills of the archived orders. Totals are
+// persisted in the limiter state, so that the filled and pending sizes do not
+// depend on the archived orders.
+type archiveTotals struct {
+	count int
+
+	size  decimal.Decimal
+	value decimal.Decimal
+	fee   decimal.Decimal
+
+	startTime time.Time
+}
+
+func (v *archiveTotals) add(order *exchange.Order) {
+	v.count++
+	v.size = v.size.Add(order.FilledSize)
+	v.value = v.value.Add(order.FilledSize.Mul(order.FilledPrice))
+	v.fee = v.fee.Add(order.Fee)
+	if v.startTime.IsZero() || order.CreateTime.Time.Before(v.startTime) {
+		v.startTime = order.CreateTime.Time
+	}
+}
+
+func (v *Limiter) setArchiveOrdersOption(value string) error {
+	arg := strings.ToLower(value)
+	if arg == "true" {
+		v.archiveOrdersOpt.Store(true)
+		return nil
+	}
+	if arg == "false" {
+		v.archiveOrdersOpt.Store(false)
+		return nil
+	}
+	return fmt.Errorf(`%v: archive-orders option only takes a "true" or "false" value`, v.uid)
+}
+
+// archiveTotals returns the cumulative fills of the archived orders.
+func (v *Limiter) archiveTotals() archiveTotals {
+	v.archiveMu.Lock()
+	defer v.archiveMu.Unlock()
+	return v.archived
+}
+
+// dupArchivedOrders returns the orders archived by the limiter.
+func (v *Limiter) dupArchivedOrders() []*exchange.Order {
+	v.archiveMu.Lock()
+	defer v.archiveMu.Unlock()
+	return slices.Clone(v.archivedOrders)
+}
+
+// archiveOrderMap moves the completed orders with fills out of the order map
+// when archive-orders option is set. Fills of the moved orders are added to
+// the archive totals.
+func (v *Limiter) archiveOrderMap() {
+	if !v.archiveOrdersOpt.Load() {
+		return
+	}
+
+	v.archiveMu.Lock()
+	defer v.archiveMu.Unlock()
+
+	v.orderMap.Range(func(id exchange.OrderID, order *exchange.Order) bool {
+		if !order.Done || order.FilledSize.IsZero() {
+			return true
+		}
+		v.orderMap.Delete(id)
+		v.archived.add(order)
+		v.archivedOrders = append(v.archivedOrders, order)
+		v.unsavedArchive = append(v.unsavedArchive, order)
+		return true
+	})
+}
+
+// saveArchive writes the archived orders that are not known to be saved yet.
+// Orders are dropped from the unsaved list only after a later save finds them
+// in the database, because the transaction of an earlier save may not have
+// been committed.
+func (v *Limiter) saveArchive(ctx context.Context, rw kv.ReadWriter) error {
+	v.archiveMu.Lock()
+	defer v.archiveMu.Unlock()
+
+	var unsaved []*exchange.Order
+	for _, order := range v.unsavedArchive {
+		key := path.Join(ArchiveKeyspace, v.uid, string(order.OrderID))
+		if _, err := kvutil.Get[gobs.Order](ctx, rw, key); err == nil {
+			continue
+		}
+		if err := kvutil.Set(ctx, rw, key, gobOrder(order)); err != nil {
+			return fmt.Errorf("could not save archived order %s: %w", order.OrderID, err)
+		}
+		unsaved = append(unsaved, order)
+	}
+	v.unsavedArchive = unsaved
+	return nil
+}
+
+// loadArchive loads the archived orders of the limiter, which are used only
+// for the actions because the archive totals are saved in the limiter state.
+func (v *Limiter) loadArchive(ctx context.Context, r kv.Reader) error {
+	dir := path.Join(ArchiveKeyspace, v.uid)
+	var orders []*exchange.Order
+	collect := func(ctx context.Context, r kv.Reader, key string, order *gobs.Order) error {
+		// Skip the orders of the child limiters, if any.
+		if path.Dir(key) == dir {
+			orders = append(orders, exchangeOrder(order))
+		}
+		return nil
+	}
+	begin, end := kvutil.PathRange(dir)
+	if err := kvutil.Ascend(ctx, r, begin, end, collect); err != nil {
+		return fmt.Errorf("could not scan archived orders for limiter %s: %w", v.uid, err)
+	}
+
+	v.archiveMu.Lock()
+	defer v.archiveMu.Unlock()
+
+	if n := len(orders); n != v.archived.count {
+		log.Printf("%s:%s: WARNING: found %d archived orders instead of %d (actions may be incomplete)", v.uid, v.point, n, v.archived.count)
+	}
+	v.archivedOrders = orders
+	return nil
+}
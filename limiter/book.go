@@ -0,0 +1,42 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bvk/tradebot/exchange"
+)
+
+func (v *Limiter) setFavorableBookOption(value string) error {
+	arg := strings.ToLower(value)
+	if arg == "true" {
+		v.favorableBookOpt.Store(true)
+		return nil
+	}
+	if arg == "false" {
+		v.favorableBookOpt.Store(false)
+		return nil
+	}
+	return fmt.Errorf(`%v: require-favorable-book option only takes a "true" or "false" value`, v.uid)
+}
+
+// isBookFavorable returns false if the require-favorable-book option is set
+// and the order book top imbalance in the ticker is against the limiter side,
+// i.e., more asks than bids for a buy and more bids than asks for a sell.
+// Tickers without the book top sizes are not gated, so that the products that
+// do not report the book sizes can still create orders.
+func (v *Limiter) isBookFavorable(ticker *exchange.Ticker) bool {
+	if !v.favorableBookOpt.Load() {
+		return true
+	}
+	imbalance, ok := ticker.BookImbalance()
+	if !ok {
+		return true
+	}
+	if v.IsBuy() {
+		return !imbalance.IsNegative()
+	}
+	return !imbalance.IsPositive()
+}
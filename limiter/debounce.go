@@ -0,0 +1,78 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bvk/tradebot/exchange"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	debounceCreate = "create"
+	debounceCancel = "cancel"
+)
+
+// debounce returns the debounce option value, which is zero (disabled) by
+// default.
+func (v *Limiter) debounce() time.Duration {
+	return time.Duration(v.debounceOpt.Load())
+}
+
+func (v *Limiter) setDebounceOption(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return fmt.Errorf("debounce value cannot be -ve")
+	}
+	v.debounceOpt.Store(int64(d))
+	return nil
+}
+
+// debounceState holds the create or cancel action that is waiting for the
+// ticker price to hold for the debounce duration.
+type debounceState struct {
+	action string
+	since  time.Time
+}
+
+func (s *debounceState) reset() {
+	s.action, s.since = "", time.Time{}
+}
+
+// isDebounceDue returns true if the action has been pending for at least the
+// debounce duration at the input ticker time, so that the action is taken
+// only on a later ticker after all other checks pass again. Pending action is
+// recorded when it is not already pending and is cleared when it is due.
+func (v *Limiter) isDebounceDue(s *debounceState, action string, at time.Time) bool {
+	d := v.debounce()
+	if d <= 0 {
+		return true
+	}
+	if s.action != action {
+		s.action, s.since = action, at
+		return false
+	}
+	if at.Sub(s.since) < d {
+		return false
+	}
+	s.reset()
+	return true
+}
+
+// isDebounceActionable returns true if the debounced create or cancel action
+// can still be taken at the input price, i.e., the price hasn't reverted back
+// across the cancel threshold and the active order state hasn't changed.
+func (v *Limiter) isDebounceActionable(action string, activeOrderID exchange.OrderID, price decimal.Decimal) bool {
+	switch action {
+	case debounceCreate:
+		return activeOrderID == "" && v.shouldCreate(price)
+	case debounceCancel:
+		return activeOrderID != "" && v.shouldCancel(price)
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvkgo/kv"
+)
+
+// Delete removes a saved limiter along with it's client id offset, archived
+// orders and audit trail entries. Limiter must not be running while it is
+// deleted.
+func Delete(ctx context.Context, rw kv.ReadWriter, uid string) error {
+	if err := checkUID(uid); err != nil {
+		return err
+	}
+	if err := rw.Delete(ctx, path.Join(DefaultKeyspace, uid)); err != nil {
+		return fmt.Errorf("could not delete limiter state: %w", err)
+	}
+	if err := rw.Delete(ctx, path.Join(OffsetKeyspace, uid)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not delete limiter client id offset: %w", err)
+	}
+
+	for _, keyspace := range []string{ArchiveKeyspace, TrailKeyspace} {
+		dir := path.Join(keyspace, uid)
+		var keys []string
+		begin, end := kvutil.PathRange(dir)
+		it, err := rw.Ascend(ctx, begin, end)
+		if err != nil {
+			return fmt.Errorf("could not scan keys under %s: %w", dir, err)
+		}
+		for k, _, err := it.Fetch(ctx, false); err == nil; k, _, err = it.Fetch(ctx, true) {
+			// Skip the keys of the child limiters, if any.
+			if path.Dir(k) == dir {
+				keys = append(keys, k)
+			}
+		}
+		_, _, err = it.Fetch(ctx, false)
+		kv.Close(it)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("could not complete scan under %s: %w", dir, err)
+		}
+		for _, key := range keys {
+			if err := rw.Delete(ctx, key); err != nil {
+				return fmt.Errorf("could not delete key %s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
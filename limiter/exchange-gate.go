@@ -0,0 +1,56 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// exchangeGate holds the semaphore shared by all limiters to bound the number
+// of in-flight exchange REST calls, which is nil when it is unlimited. It
+// smooths the bursts of calls, like when many limiters are resumed at once,
+// where the rate limits alone would queue up too many requests.
+var exchangeGate atomic.Pointer[chan struct{}]
+
+// SetExchangeConcurrency sets the max number of in-flight exchange REST calls
+// by all limiters. Zero value removes the limit. Calls that are in-flight are
+// not affected by the change.
+func SetExchangeConcurrency(n int) error {
+	if n < 0 {
+		return fmt.Errorf("exchange concurrency cannot be -ve")
+	}
+	if n == 0 {
+		exchangeGate.Store(nil)
+		return nil
+	}
+	sem := make(chan struct{}, n)
+	exchangeGate.Store(&sem)
+	return nil
+}
+
+// ExchangeConcurrency returns the max number of in-flight exchange REST calls
+// by all limiters, which is zero when it is unlimited.
+func ExchangeConcurrency() int {
+	if sem := exchangeGate.Load(); sem != nil {
+		return cap(*sem)
+	}
+	return 0
+}
+
+// acquireExchange waits for a slot in the exchange gate and returns a function
+// to release it.
+func acquireExchange(ctx context.Context) (release func(), err error) {
+	p := exchangeGate.Load()
+	if p == nil {
+		return func() {}, nil
+	}
+	sem := *p
+	select {
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	}
+}
@@ -5,7 +5,6 @@ package limiter
 import (
 	"context"
 	"errors"
-	"fmt"
 	"log"
 	"strings"
 	"time"
@@ -19,6 +18,11 @@ import (
 
 var activeLimiters syncmap.Map[*Limiter, bool]
 
+// finishTimeAttempted holds the ids of the completed orders that were fetched
+// for the missing finish-time or liquidity, but the exchange didn't report
+// them, so that they are not fetched again.
+var finishTimeAttempted syncmap.Map[exchange.OrderID, bool]
+
 // fixFinishTimes is a background job that updates FinishTime field in order
 // metadata stored by active and completed limiters.
 func fixFinishTimes(ctx context.Context, db kv.Database, ex exchange.Exchange) {
@@ -112,7 +116,10 @@ func updateActiveLimiter(ctx context.Context, ex exchange.Exchange, v *Limiter)
 		if order.Done == false {
 			return true
 		}
-		if !order.FinishTime.Time.IsZero() {
+		if !order.FinishTime.Time.IsZero() && order.Liquidity != "" {
+			return true
+		}
+		if _, ok := finishTimeAttempted.Load(id); ok {
 			return true
 		}
 		v, err := ex.GetOrder(ctx, exchange.OrderID(id))
@@ -121,7 +128,16 @@ func updateActiveLimiter(ctx context.Context, ex exchange.Exchange, v *Limiter)
 			status = err
 			return false
 		}
-		order.FinishTime = v.FinishTime
+		if order.FinishTime.Time.IsZero() {
+			order.FinishTime = v.FinishTime
+		}
+		// Liquidity is left empty (unknown) when the exchange doesn't report it.
+		if order.Liquidity == "" {
+			order.Liquidity = v.Liquidity
+		}
+		if order.FinishTime.Time.IsZero() || order.Liquidity == "" {
+			finishTimeAttempted.Store(id, true)
+		}
 		return true
 	})
 	return status
@@ -155,12 +171,17 @@ func updateFinishTime(ctx context.Context, rw kv.ReadWriter, ex exchange.Exchang
 		if !order.FinishTime.Time.IsZero() {
 			continue
 		}
+		if _, ok := finishTimeAttempted.Load(exchange.OrderID(id)); ok {
+			continue
+		}
 		v, err := ex.GetOrder(ctx, exchange.OrderID(id))
 		if err != nil {
 			return err
 		}
 		if v.FinishTime.Time.IsZero() {
-			return fmt.Errorf("finish time is empty for exchange order %s", id)
+			log.Printf("finish time is empty for exchange order %s (not retried)", id)
+			finishTimeAttempted.Store(exchange.OrderID(id), true)
+			continue
 		}
 		order.FinishTime = gobs.RemoteTime{Time: v.FinishTime.Time}
 		modified = true
@@ -0,0 +1,77 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"time"
+
+	"github.com/bvk/tradebot/exchange"
+)
+
+// FillLatency holds the statistics for the time between the decision to
+// create an order and the final update for the order with a non-zero fill.
+// Short latencies indicate the market moving through the price immediately
+// and long latencies indicate orders waiting in the queue.
+type FillLatency struct {
+	Count int
+
+	Min     time.Duration
+	Max     time.Duration
+	Average time.Duration
+	Last    time.Duration
+
+	total time.Duration
+}
+
+// Add merges the statistics from another value.
+func (v *FillLatency) Add(other *FillLatency) {
+	if other.Count == 0 {
+		return
+	}
+	if v.Count == 0 || other.Min < v.Min {
+		v.Min = other.Min
+	}
+	if other.Max > v.Max {
+		v.Max = other.Max
+	}
+	v.Count += other.Count
+	v.total += other.total
+	v.Average = v.total / time.Duration(v.Count)
+	v.Last = other.Last
+}
+
+func (v *FillLatency) record(d time.Duration) {
+	v.Add(&FillLatency{Count: 1, Min: d, Max: d, Average: d, Last: d, total: d})
+}
+
+// FillLatency returns the fill latency statistics for the orders created by
+// this limiter since it was started. Statistics are not persisted.
+func (v *Limiter) FillLatency() *FillLatency {
+	v.fillLatencyMu.Lock()
+	defer v.fillLatencyMu.Unlock()
+
+	stats := v.fillLatency
+	return &stats
+}
+
+// recordCreate saves the order create decision time for the order.
+func (v *Limiter) recordCreate(id exchange.OrderID, decided time.Time) {
+	v.createTimes.Store(id, decided)
+}
+
+// recordDone updates the fill latency statistics when an order with a
+// non-zero fill is complete.
+func (v *Limiter) recordDone(order *exchange.Order, now time.Time) {
+	if !order.Done {
+		return
+	}
+	decided, ok := v.createTimes.LoadAndDelete(order.OrderID)
+	if !ok || !order.FilledSize.IsPositive() {
+		return
+	}
+
+	v.fillLatencyMu.Lock()
+	defer v.fillLatencyMu.Unlock()
+
+	v.fillLatency.record(now.Sub(decided))
+}
@@ -5,8 +5,14 @@ package limiter
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/gob"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"os"
 	"path"
 	"sort"
 	"strings"
@@ -28,6 +34,10 @@ import (
 
 const DefaultKeyspace = "/limiters/"
 
+// OffsetKeyspace holds the client id offsets of the limiters, which are saved
+// separately from the limiter states when only the offset is changed.
+const OffsetKeyspace = "/limiter-offsets/"
+
 type Limiter struct {
 	runtimeLock sync.Mutex
 
@@ -46,8 +56,11 @@ type Limiter struct {
 
 	optionMap map[string]string
 
-	// holdOpt when true, pauses the buy/sell operations by this job. This flag
-	// can be updated while job is running, so it needs to be an atomic.
+	// holdOpt when true, pauses the buy/sell operations by this job. Active
+	// order is canceled and no new order is created until the option is
+	// cleared, but the Run loop keeps processing the tickers and order updates.
+	// This flag can be updated while job is running, so it needs to be an
+	// atomic.
 	holdOpt atomic.Bool
 
 	// waitForTickerSideOpt when true, makes the job wait for ticker price to be
@@ -60,6 +73,172 @@ type Limiter struct {
 	// orders. It's value is typically less than the total size so that large
 	// orders can be avoided.
 	sizeLimitOpt atomic.Pointer[decimal.Decimal]
+
+	// readinessBandOpt when set and non-zero, contains the distance the ticker
+	// price must move past the cancel threshold before an order is created. It
+	// avoids rapid create/cancel churn around the cancel threshold.
+	readinessBandOpt atomic.Pointer[decimal.Decimal]
+
+	// goodTillOpt when non-zero, contains the duration after which the limit
+	// orders expire at the exchange, so that stale orders do not linger when the
+	// job dies.
+	goodTillOpt atomic.Int64
+
+	// fetchConcurrencyOpt when non-zero, limits the number of parallel requests
+	// used to fetch the live orders from the exchange on startup.
+	fetchConcurrencyOpt atomic.Int32
+
+	// baseIncrement holds the product's base size increment, which is known
+	// only after the job is started. Pending size below one increment cannot be
+	// traded, so it is treated as complete.
+	baseIncrement atomic.Pointer[decimal.Decimal]
+
+	// minActionIntervalOpt when non-zero, contains the minimum duration between
+	// ticker driven order create/cancel actions, to avoid order churn when the
+	// ticker price oscillates around the cancel threshold.
+	minActionIntervalOpt atomic.Int64
+
+	// lastActionTime holds the time of last create or cancel action as unix
+	// nanoseconds. It is persisted, so that restarts do not burst.
+	lastActionTime atomic.Int64
+
+	// marketFillAfterOpt when non-zero, contains the duration after which the
+	// unfilled size is canceled and filled with a market order.
+	marketFillAfterOpt atomic.Int64
+
+	// marketFillAnchor holds the creation time of the first limit order as unix
+	// nanoseconds, which is the anchor for the market-fill-after deadline. It is
+	// persisted, so that restarts do not extend the deadline.
+	marketFillAnchor atomic.Int64
+
+	// flushIntervalOpt when non-zero, contains the interval between saves of
+	// the dirty limiter state to the database.
+	flushIntervalOpt atomic.Int64
+
+	// staggerFlushOpt when true, delays the first flush by a deterministic
+	// offset derived from the uid, so that flushes by many limiters are spread
+	// across the flush interval.
+	staggerFlushOpt atomic.Bool
+
+	// oneShotOpt when true, disables the automatic order recreation, i.e., the
+	// limiter is considered complete after it's order is canceled by the cancel
+	// threshold. This option is meant for standalone limiter jobs.
+	oneShotOpt atomic.Bool
+
+	// oneShotCanceled is true when the order was canceled by the cancel
+	// threshold with the one-shot option. It is persisted.
+	oneShotCanceled atomic.Bool
+
+	// forceCompleted is true when the limiter is marked as complete manually,
+	// with residualSize left unfilled. Both are persisted.
+	forceCompleted atomic.Bool
+	residualSize   atomic.Pointer[decimal.Decimal]
+
+	// failOnMissingOrdersOpt when true, fails the order map fetch when an order
+	// is not found at the exchange, instead of marking the order as done.
+	failOnMissingOrdersOpt atomic.Bool
+
+	// pollIntervalOpt when non-zero, contains the interval between the order
+	// state fetches for the active order when no updates are received.
+	pollIntervalOpt atomic.Int64
+
+	// completionGraceOpt when non-zero, contains the time to wait after the
+	// pending size reaches zero before the completion is verified with the
+	// exchange, so that a last fill update in flight is not missed.
+	completionGraceOpt atomic.Int64
+
+	// maxPriceAgeOpt when non-zero, contains the max age of the first ticker
+	// that is acted upon after the Run starts, so that a stale cached ticker
+	// delivered at startup doesn't create or cancel orders.
+	maxPriceAgeOpt atomic.Int64
+
+	// noTickerTimeoutOpt when non-zero, contains the max time without any
+	// tickers after which the active order is canceled and the limiter stays
+	// dormant, retrying the ticker subscription, until tickers resume.
+	noTickerTimeoutOpt atomic.Int64
+
+	// maxFeePctOpt when set and non-zero, contains the max estimated fee for an
+	// order as a percentage of it's notional value.
+	maxFeePctOpt atomic.Pointer[decimal.Decimal]
+
+	// triggerPriceOpt when set and non-zero, contains the trigger price for
+	// the orders, which are created as stop-limit orders.
+	triggerPriceOpt atomic.Pointer[decimal.Decimal]
+
+	// auditTrailOpt when true, persists every order create and cancel decision
+	// in the TrailKeyspace.
+	auditTrailOpt atomic.Bool
+
+	// archiveOrdersOpt when true, moves the completed orders with fills out of
+	// the order map into the ArchiveKeyspace, so that the saved limiter state
+	// doesn't grow with the number of orders.
+	archiveOrdersOpt atomic.Bool
+
+	// archiveMu protects the archive totals and the archived orders. Archived
+	// orders are kept in memory for the actions and unsavedArchive holds the
+	// archived orders that are not known to be saved yet.
+	archiveMu      sync.Mutex
+	archived       archiveTotals
+	archivedOrders []*exchange.Order
+	unsavedArchive []*exchange.Order
+
+	// savedMu protects the checksums of the last saved state, which are used to
+	// skip the writes when the state is unchanged since the last save.
+	savedMu sync.Mutex
+
+	// savedStateSum is the checksum of the canonical (json) form of the last
+	// saved state and savedDataSum is the checksum of the encoded bytes written
+	// to the database.
+	savedStateSum [sha256.Size]byte
+	savedDataSum  [sha256.Size]byte
+
+	// savedOffset is the last saved client id offset and savedOffsetOnly is
+	// true if it was saved to the separate offset key.
+	savedOffset     uint64
+	savedOffsetOnly bool
+
+	// reduceOpt when true, shrinks the active order in place when the
+	// size-limit option is lowered, instead of canceling and recreating it, so
+	// that the order keeps it's queue priority at the exchange.
+	reduceOpt atomic.Bool
+
+	// completeSubMinOpt when true, marks the limiter as complete with the
+	// pending size left unfilled as residual when it is below the product's
+	// min size, instead of bumping up the order size to the min size.
+	completeSubMinOpt atomic.Bool
+
+	// logThrottleOpt when non-zero, contains the min interval between the
+	// high-frequency log messages of the same kind.
+	logThrottleOpt atomic.Int64
+
+	// debounceOpt when non-zero, contains the duration the ticker price must
+	// stay past the cancel threshold before the order is created or canceled,
+	// to avoid reacting to the momentary price spikes.
+	debounceOpt atomic.Int64
+
+	logThrottle logThrottle
+
+	// favorableBookOpt when true, skips the order creation when the order book
+	// top imbalance is unfavorable for the limiter side, e.g., when there are
+	// more asks than bids for a buy.
+	favorableBookOpt atomic.Bool
+
+	// orderSizes holds the sizes of the limit orders created by this process,
+	// which are needed to reduce the orders. It is not persisted.
+	orderSizes syncmap.Map[exchange.OrderID, decimal.Decimal]
+
+	// createTimes holds the order create decision times for the orders that
+	// are not complete yet, which are used to measure the fill latencies.
+	createTimes syncmap.Map[exchange.OrderID, time.Time]
+
+	// clientIDs holds the client order ids used by this process, which are
+	// recorded before the orders are created so that the filtered order
+	// updates subscription doesn't miss the updates that arrive before the
+	// create call returns. It is not persisted.
+	clientIDs syncmap.Map[string, bool]
+
+	fillLatencyMu sync.Mutex
+	fillLatency   FillLatency
 }
 
 var _ trader.Trader = &Limiter{}
@@ -113,6 +292,11 @@ func (v *Limiter) BudgetAt(feePct float64) decimal.Decimal {
 	return v.point.Value().Add(v.point.FeeAt(feePct))
 }
 
+func (v *Limiter) Point() *point.Point {
+	p := v.point
+	return &p
+}
+
 func (v *Limiter) IsBuy() bool {
 	return v.point.Side() == "BUY"
 }
@@ -131,7 +315,7 @@ func (v *Limiter) dupOrderMap() map[exchange.OrderID]*exchange.Order {
 }
 
 func (v *Limiter) StartTime() time.Time {
-	var min time.Time
+	min := v.archiveTotals().startTime
 	for _, order := range v.dupOrderMap() {
 		if min.IsZero() {
 			min = order.CreateTime.Time
@@ -146,22 +330,12 @@ func (v *Limiter) Actions() []*gobs.Action {
 	var orders []*gobs.Order
 	for _, order := range v.dupOrderMap() {
 		if order.Done && !order.FilledSize.IsZero() {
-			gorder := &gobs.Order{
-				ServerOrderID: string(order.OrderID),
-				ClientOrderID: order.ClientOrderID,
-				CreateTime:    gobs.RemoteTime{Time: order.CreateTime.Time},
-				FinishTime:    gobs.RemoteTime{Time: order.FinishTime.Time},
-				Side:          order.Side,
-				Status:        order.Status,
-				FilledFee:     order.Fee,
-				FilledSize:    order.FilledSize,
-				FilledPrice:   order.FilledPrice,
-				Done:          order.Done,
-				DoneReason:    order.DoneReason,
-			}
-			orders = append(orders, gorder)
+			orders = append(orders, gobOrder(order))
 		}
 	}
+	for _, order := range v.dupArchivedOrders() {
+		orders = append(orders, gobOrder(order))
+	}
 	sort.Slice(orders, func(i, j int) bool {
 		return orders[i].CreateTime.Before(orders[j].CreateTime.Time)
 	})
@@ -172,7 +346,7 @@ func (v *Limiter) Actions() []*gobs.Action {
 }
 
 func (v *Limiter) Fees() decimal.Decimal {
-	var sum decimal.Decimal
+	sum := v.archiveTotals().fee
 	for _, order := range v.dupOrderMap() {
 		sum = sum.Add(order.Fee)
 	}
@@ -201,7 +375,7 @@ func (v *Limiter) UnsoldValue() decimal.Decimal {
 }
 
 func (v *Limiter) FilledSize() decimal.Decimal {
-	var filled decimal.Decimal
+	filled := v.archiveTotals().size
 	for _, order := range v.dupOrderMap() {
 		filled = filled.Add(order.FilledSize)
 	}
@@ -209,25 +383,167 @@ func (v *Limiter) FilledSize() decimal.Decimal {
 }
 
 func (v *Limiter) FilledValue() decimal.Decimal {
-	var value decimal.Decimal
+	value := v.archiveTotals().value
 	for _, order := range v.dupOrderMap() {
 		value = value.Add(order.FilledSize.Mul(order.FilledPrice))
 	}
 	return value
 }
 
+// AvgEntryPrice returns the size-weighted average fill price across all orders
+// of the limiter, which may be different from the point price because orders
+// are canceled and recreated. Returns zero when nothing is filled.
+func (v *Limiter) AvgEntryPrice() decimal.Decimal {
+	size := v.FilledSize()
+	if size.IsZero() {
+		return decimal.Zero
+	}
+	return v.FilledValue().Div(size)
+}
+
 func (v *Limiter) PendingSize() decimal.Decimal {
-	size := v.point.Size.Sub(v.FilledSize())
-	if size.LessThanOrEqual(decimal.Zero) {
+	if v.forceCompleted.Load() {
+		return decimal.Zero
+	}
+	var size decimal.Decimal
+	if v.point.SizeInQuote {
+		funds := v.point.Size.Sub(v.FilledValue())
+		if funds.LessThanOrEqual(decimal.Zero) {
+			return decimal.Zero
+		}
+		size = funds.Div(v.point.Price)
+	} else {
+		size = v.point.Size.Sub(v.FilledSize())
+		if size.LessThanOrEqual(decimal.Zero) {
+			return decimal.Zero
+		}
+	}
+	// Residual dust below one base increment cannot be traded.
+	if inc := v.baseIncrement.Load(); inc != nil && size.LessThan(*inc) {
 		return decimal.Zero
 	}
 	return size
 }
 
+// Complete marks the limiter as complete manually, leaving the pending size
+// (eg: dust below the min size) unfilled as residual. Limiter must not have
+// any live orders and must not be running.
+func (v *Limiter) Complete() error {
+	if v.forceCompleted.Load() {
+		return fmt.Errorf("limiter %s is already force-completed", v.uid)
+	}
+	for id, order := range v.dupOrderMap() {
+		if !order.Done {
+			return fmt.Errorf("limiter %s has a live order %s", v.uid, id)
+		}
+	}
+	residual := v.PendingSize()
+	if residual.IsZero() {
+		return fmt.Errorf("limiter %s is already complete", v.uid)
+	}
+	v.residualSize.Store(&residual)
+	v.forceCompleted.Store(true)
+	return nil
+}
+
+// IsForceCompleted returns true if the limiter was marked as complete
+// manually.
+func (v *Limiter) IsForceCompleted() bool {
+	return v.forceCompleted.Load()
+}
+
+// ResidualSize returns the size left unfilled when the limiter is marked as
+// complete manually.
+func (v *Limiter) ResidualSize() decimal.Decimal {
+	if p := v.residualSize.Load(); p != nil {
+		return *p
+	}
+	return decimal.Zero
+}
+
+// OverfilledSize returns the size filled beyond the point size, which can
+// happen when the exchange reports more filled than requested (eg: rounding
+// or combined fills). Overfilled size is not considered as pending, so
+// PendingSize is zero when OverfilledSize is positive.
+func (v *Limiter) OverfilledSize() decimal.Decimal {
+	if v.point.SizeInQuote {
+		extra := v.FilledValue().Sub(v.point.Size)
+		if !extra.IsPositive() {
+			return decimal.Zero
+		}
+		return extra.Div(v.point.Price)
+	}
+	extra := v.FilledSize().Sub(v.point.Size)
+	if !extra.IsPositive() {
+		return decimal.Zero
+	}
+	return extra
+}
+
 func (v *Limiter) PendingValue() decimal.Decimal {
 	return v.PendingSize().Mul(v.point.Price)
 }
 
+// BudgetFootprint returns the quote funds reserved by the limiter, which is
+// the value of the pending size at the limit price plus the estimated fee for
+// a buy. It is zero for a sell because sells free up the quote funds.
+func (v *Limiter) BudgetFootprint() decimal.Decimal {
+	if v.IsSell() {
+		return decimal.Zero
+	}
+	return buyFunds(v.PendingSize(), v.point.Price)
+}
+
+// buyFunds returns the quote funds required to buy the size at the price,
+// including the fee at EstimatedFeePct.
+func buyFunds(size, price decimal.Decimal) decimal.Decimal {
+	value := size.Mul(price)
+	return value.Add(value.Mul(decimal.NewFromFloat(EstimatedFeePct)).Div(decimal.NewFromInt(100)))
+}
+
+// EstimatedCompletion returns the estimated time to fill the pending size at
+// the average fill rate observed since the first order was created. Returns
+// false when nothing is filled yet. It is only a heuristic because fill rate
+// depends on the market moving through the limit price.
+func (v *Limiter) EstimatedCompletion() (time.Duration, bool) {
+	return v.estimatedCompletionAt(time.Now())
+}
+
+func (v *Limiter) estimatedCompletionAt(now time.Time) (time.Duration, bool) {
+	pending := v.PendingSize()
+	if pending.IsZero() {
+		return 0, true
+	}
+	start := v.StartTime()
+	if start.IsZero() || !now.After(start) {
+		return 0, false
+	}
+	filled := v.FilledSize()
+	if !filled.IsPositive() {
+		return 0, false
+	}
+	elapsed := decimal.NewFromInt(int64(now.Sub(start)))
+	remaining := pending.Mul(elapsed).Div(filled)
+	return time.Duration(remaining.IntPart()), true
+}
+
+// PriceGapPct returns the percentage move from the mark price that is needed
+// for the market to reach the limit price, i.e., a rise for sells and a drop
+// for buys. Returns zero when the mark price is already past the limit price.
+func (v *Limiter) PriceGapPct(mark decimal.Decimal) decimal.Decimal {
+	if !mark.IsPositive() {
+		return decimal.Zero
+	}
+	gap := v.point.Price.Sub(mark)
+	if v.IsBuy() {
+		gap = gap.Neg()
+	}
+	if !gap.IsPositive() {
+		return decimal.Zero
+	}
+	return gap.Mul(decimal.NewFromInt(100)).Div(mark)
+}
+
 func (v *Limiter) compactOrderMap() {
 	v.orderMap.Range(func(id exchange.OrderID, order *exchange.Order) bool {
 		if order.Done && order.FilledSize.IsZero() {
@@ -237,14 +553,54 @@ func (v *Limiter) compactOrderMap() {
 	})
 }
 
+// checkOrderMap returns an error if the client order ids and server order ids
+// of the orders are not mapped one-to-one, i.e., if an order is stored under a
+// different server order id or if two server orders share a client order id.
+func checkOrderMap(orders map[exchange.OrderID]*exchange.Order) error {
+	clientServerMap := make(map[string]exchange.OrderID)
+	for id, order := range orders {
+		if order.OrderID != "" && order.OrderID != id {
+			return fmt.Errorf("order %s is stored under a different server order id %s", order.OrderID, id)
+		}
+		cid := order.ClientOrderID
+		if cid == "" {
+			continue
+		}
+		if sid, ok := clientServerMap[cid]; ok && sid != id {
+			return fmt.Errorf("client order id %s is mapped to different orders %s and %s", cid, sid, id)
+		}
+		clientServerMap[cid] = id
+	}
+	return nil
+}
+
 func (v *Limiter) updateOrderMap(order *exchange.Order) {
-	if _, ok := v.orderMap.Load(order.OrderID); ok {
+	if old, ok := v.orderMap.Load(order.OrderID); ok {
+		if exchange.IsStale(old, order) {
+			log.Printf("%s:%s: ignoring stale update %s for order %s", v.uid, v.point, order, old)
+			return
+		}
 		v.orderMap.Store(order.OrderID, order)
 	}
 }
 
 func (v *Limiter) Save(ctx context.Context, rw kv.ReadWriter) error {
+	_, err := v.SaveIfChanged(ctx, rw)
+	return err
+}
+
+// SaveIfChanged is similar to Save, but skips the database write when the
+// limiter state is identical to the last saved state and the database still
+// holds the last written value. Returns true if the state is written.
+func (v *Limiter) SaveIfChanged(ctx context.Context, rw kv.ReadWriter) (bool, error) {
+	// An inconsistent order map is not saved, so that the corruption doesn't
+	// reach the database.
+	if err := checkOrderMap(v.dupOrderMap()); err != nil {
+		log.Printf("%s:%s: ERROR: order map is inconsistent (not saved): %v", v.uid, v.point, err)
+		return false, fmt.Errorf("limiter %s: %w", v.uid, err)
+	}
 	v.compactOrderMap()
+	v.archiveOrderMap()
 	gv := &gobs.LimiterState{
 		V2: &gobs.LimiterStateV2{
 			ProductID:      v.productID,
@@ -255,38 +611,125 @@ func (v *Limiter) Save(ctx context.Context, rw kv.ReadWriter) error {
 				Size:   v.point.Size,
 				Price:  v.point.Price,
 				Cancel: v.point.Cancel,
+
+				SizeInQuote: v.point.SizeInQuote,
+
+				Tag: v.point.Tag,
 			},
 			ServerIDOrderMap: make(map[string]*gobs.Order),
 			Options:          v.optionMap,
 		},
 	}
+	if t := v.lastActionTime.Load(); t != 0 {
+		gv.V2.LastActionTime = time.Unix(0, t)
+	}
+	if t := v.marketFillAnchor.Load(); t != 0 {
+		gv.V2.MarketFillAnchor = time.Unix(0, t)
+	}
+	gv.V2.OneShotCanceled = v.oneShotCanceled.Load()
+	gv.V2.ForceCompleted = v.forceCompleted.Load()
+	gv.V2.ResidualSize = v.ResidualSize()
 	for k, v := range v.dupOrderMap() {
-		order := &gobs.Order{
-			ServerOrderID: string(v.OrderID),
-			ClientOrderID: v.ClientOrderID,
-			CreateTime:    gobs.RemoteTime{Time: v.CreateTime.Time},
-			FinishTime:    gobs.RemoteTime{Time: v.FinishTime.Time},
-			Side:          v.Side,
-			Status:        v.Status,
-			FilledFee:     v.Fee,
-			FilledSize:    v.FilledSize,
-			FilledPrice:   v.FilledPrice,
-			Done:          v.Done,
-			DoneReason:    v.DoneReason,
+		gv.V2.ServerIDOrderMap[string(k)] = gobOrder(v)
+	}
+	archived := v.archiveTotals()
+	gv.V2.ArchivedOrders = archived.count
+	gv.V2.ArchivedFilledSize = archived.size
+	gv.V2.ArchivedFilledValue = archived.value
+	gv.V2.ArchivedFees = archived.fee
+	gv.V2.ArchivedStartTime = archived.startTime
+	if err := v.saveArchive(ctx, rw); err != nil {
+		return false, err
+	}
+
+	// Gob encoding of maps is not deterministic, so state is compared in the
+	// json form, which orders the map keys. Client id offset is excluded from
+	// the comparison, so that offset only changes are saved to a separate key.
+	offset := gv.V2.ClientIDOffset
+	gv.V2.ClientIDOffset = 0
+	js, err := json.Marshal(gv)
+	if err != nil {
+		return false, fmt.Errorf("could not marshal limiter state: %w", err)
+	}
+	gv.V2.ClientIDOffset = offset
+	stateSum := sha256.Sum256(js)
+	key := path.Join(DefaultKeyspace, v.uid)
+
+	v.savedMu.Lock()
+	defer v.savedMu.Unlock()
+
+	// Previous write may not have been committed (ex: a failed transaction), so
+	// the stored values are also verified before skipping the write.
+	if stateSum == v.savedStateSum && v.isSavedStateLocked(ctx, rw, key) {
+		if offset == v.savedOffset && v.isSavedOffsetLocked(ctx, rw) {
+			return false, nil
 		}
-		gv.V2.ServerIDOrderMap[string(k)] = order
+		if err := v.saveOffsetLocked(ctx, rw, offset); err != nil {
+			return false, err
+		}
+		return true, nil
 	}
+
 	var buf bytes.Buffer
 	if err := gob.NewEncoder(&buf).Encode(gv); err != nil {
-		return fmt.Errorf("could not encode limiter state: %w", err)
+		return false, fmt.Errorf("could not encode limiter state: %w", err)
 	}
-	key := path.Join(DefaultKeyspace, v.uid)
+	dataSum := sha256.Sum256(buf.Bytes())
 	if err := rw.Set(ctx, key, &buf); err != nil {
-		return fmt.Errorf("could not save limiter state: %w", err)
+		return false, fmt.Errorf("could not save limiter state: %w", err)
 	}
+	v.savedStateSum, v.savedDataSum = stateSum, dataSum
+	v.savedOffset, v.savedOffsetOnly = offset, false
+	return true, nil
+}
+
+// isSavedStateLocked returns true if the database holds the last written
+// limiter state.
+func (v *Limiter) isSavedStateLocked(ctx context.Context, r kv.Reader, key string) bool {
+	rd, err := r.Get(ctx, key)
+	if err != nil {
+		return false
+	}
+	data, err := io.ReadAll(rd)
+	return err == nil && sha256.Sum256(data) == v.savedDataSum
+}
+
+// isSavedOffsetLocked returns true if the database holds the last saved
+// client id offset.
+func (v *Limiter) isSavedOffsetLocked(ctx context.Context, r kv.Reader) bool {
+	if !v.savedOffsetOnly {
+		return true
+	}
+	key := path.Join(OffsetKeyspace, v.uid)
+	gv, err := kvutil.Get[gobs.LimiterOffset](ctx, r, key)
+	return err == nil && gv.ClientIDOffset == v.savedOffset
+}
+
+// saveOffsetLocked updates just the client id offset of the limiter, which
+// avoids rewriting the full limiter state with the order map.
+func (v *Limiter) saveOffsetLocked(ctx context.Context, rw kv.ReadWriter, offset uint64) error {
+	key := path.Join(OffsetKeyspace, v.uid)
+	if err := kvutil.Set(ctx, rw, key, &gobs.LimiterOffset{ClientIDOffset: offset}); err != nil {
+		return fmt.Errorf("could not save limiter client id offset: %w", err)
+	}
+	v.savedOffset, v.savedOffsetOnly = offset, true
 	return nil
 }
 
+// loadOffset returns the client id offset saved separately from the limiter
+// state, if any.
+func loadOffset(ctx context.Context, r kv.Reader, uid string) (uint64, error) {
+	key := path.Join(OffsetKeyspace, uid)
+	gv, err := kvutil.Get[gobs.LimiterOffset](ctx, r, key)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("could not load limiter client id offset: %w", err)
+	}
+	return gv.ClientIDOffset, nil
+}
+
 func checkUID(uid string) error {
 	fs := strings.Split(uid, "/")
 	if len(fs) == 0 {
@@ -319,6 +762,15 @@ func Load(ctx context.Context, uid string, r kv.Reader) (*Limiter, error) {
 	if len(gv.V2.ClientIDSeed) > 0 {
 		seed = gv.V2.ClientIDSeed
 	}
+	// Offset saved separately is newer than the offset in the limiter state
+	// when it is larger.
+	offset, err := loadOffset(ctx, r, uid)
+	if err != nil {
+		return nil, err
+	}
+	if offset > gv.V2.ClientIDOffset {
+		gv.V2.ClientIDOffset = offset
+	}
 	v := &Limiter{
 		uid:          uid,
 		productID:    gv.V2.ProductID,
@@ -330,23 +782,38 @@ func Load(ctx context.Context, uid string, r kv.Reader) (*Limiter, error) {
 			Size:   gv.V2.TradePoint.Size,
 			Price:  gv.V2.TradePoint.Price,
 			Cancel: gv.V2.TradePoint.Cancel,
+
+			SizeInQuote: gv.V2.TradePoint.SizeInQuote,
+
+			Tag: gv.V2.TradePoint.Tag,
 		},
 	}
 	for kk, vv := range gv.V2.ServerIDOrderMap {
-		order := &exchange.Order{
-			OrderID:       exchange.OrderID(vv.ServerOrderID),
-			ClientOrderID: vv.ClientOrderID,
-			CreateTime:    exchange.RemoteTime{Time: vv.CreateTime.Time},
-			FinishTime:    exchange.RemoteTime{Time: vv.FinishTime.Time},
-			Side:          vv.Side,
-			Status:        vv.Status,
-			Fee:           vv.FilledFee,
-			FilledSize:    vv.FilledSize,
-			FilledPrice:   vv.FilledPrice,
-			Done:          vv.Done,
-			DoneReason:    vv.DoneReason,
+		v.orderMap.Store(exchange.OrderID(kk), exchangeOrder(vv))
+	}
+	v.archived = archiveTotals{
+		count:     gv.V2.ArchivedOrders,
+		size:      gv.V2.ArchivedFilledSize,
+		value:     gv.V2.ArchivedFilledValue,
+		fee:       gv.V2.ArchivedFees,
+		startTime: gv.V2.ArchivedStartTime,
+	}
+	if v.archived.count > 0 {
+		if err := v.loadArchive(ctx, r); err != nil {
+			return nil, err
 		}
-		v.orderMap.Store(exchange.OrderID(kk), order)
+	}
+	if !gv.V2.LastActionTime.IsZero() {
+		v.lastActionTime.Store(gv.V2.LastActionTime.UnixNano())
+	}
+	if !gv.V2.MarketFillAnchor.IsZero() {
+		v.marketFillAnchor.Store(gv.V2.MarketFillAnchor.UnixNano())
+	}
+	v.oneShotCanceled.Store(gv.V2.OneShotCanceled)
+	if gv.V2.ForceCompleted {
+		residual := gv.V2.ResidualSize
+		v.residualSize.Store(&residual)
+		v.forceCompleted.Store(true)
 	}
 	if err := v.check(); err != nil {
 		return nil, err
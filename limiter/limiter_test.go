@@ -0,0 +1,2247 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/kvtest"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/paper"
+	"github.com/bvk/tradebot/point"
+	"github.com/bvk/tradebot/trader"
+	"github.com/bvkgo/kv"
+	"github.com/bvkgo/kv/kvmemdb"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func newTestOrder(id string, size, price string, done bool) *exchange.Order {
+	order := &exchange.Order{
+		OrderID:       exchange.OrderID(id),
+		ClientOrderID: uuid.NewString(),
+		Side:          "BUY",
+		CreateTime:    exchange.RemoteTime{Time: time.Now().Add(-time.Hour).Truncate(time.Second)},
+		FilledSize:    decimal.RequireFromString(size),
+		FilledPrice:   decimal.RequireFromString(price),
+		Fee:           decimal.RequireFromString("0.01"),
+		Status:        "OPEN",
+		Done:          done,
+	}
+	if done {
+		order.Status = "FILLED"
+		order.FinishTime = exchange.RemoteTime{Time: time.Now().Truncate(time.Second)}
+	}
+	return order
+}
+
+func TestLimiterSaveLoad(t *testing.T) {
+	ctx := context.Background()
+	db := kvtest.New()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("10"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+		Tag:    "grid-a",
+	}
+	uid := uuid.NewString()
+	l1, err := New(uid, "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		l1.idgen.NextID()
+	}
+	done := newTestOrder("done", "4", "100", true)
+	done.Liquidity = exchange.LiquidityMaker
+	l1.orderMap.Store("done", done)
+	l1.orderMap.Store("partial", newTestOrder("partial", "2", "99", false))
+	if err := l1.SetOption("size-limit", "5"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := kv.WithReadWriter(ctx, db, l1.Save); err != nil {
+		t.Fatal(err)
+	}
+
+	var l2 *Limiter
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		l2, err = Load(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+
+	if !l1.point.Equal(&l2.point) {
+		t.Fatalf("point: want %s, got %s", l1.point, l2.point)
+	}
+	if l2.point.Tag != "grid-a" {
+		t.Fatalf("point tag: want grid-a, got %q", l2.point.Tag)
+	}
+	if a, b := l1.idgen.Offset(), l2.idgen.Offset(); a != b {
+		t.Fatalf("idgen offset: want %d, got %d", a, b)
+	}
+	if a, b := l1.idgen.Seed(), l2.idgen.Seed(); a != b {
+		t.Fatalf("idgen seed: want %s, got %s", a, b)
+	}
+	if a, b := l1.PendingSize(), l2.PendingSize(); !a.Equal(b) {
+		t.Fatalf("pending size: want %s, got %s", a, b)
+	}
+	if a, b := l1.FilledValue(), l2.FilledValue(); !a.Equal(b) {
+		t.Fatalf("filled value: want %s, got %s", a, b)
+	}
+	if a, b := l1.Fees(), l2.Fees(); !a.Equal(b) {
+		t.Fatalf("fees: want %s, got %s", a, b)
+	}
+	if a, b := l1.sizeLimit(), l2.sizeLimit(); !a.Equal(b) {
+		t.Fatalf("size limit: want %s, got %s", a, b)
+	}
+	m1, m2 := l1.dupOrderMap(), l2.dupOrderMap()
+	if len(m1) != len(m2) {
+		t.Fatalf("order map: want %d orders, got %d", len(m1), len(m2))
+	}
+	for id, o1 := range m1 {
+		o2, ok := m2[id]
+		if !ok {
+			t.Fatalf("order %s is not found after load", id)
+		}
+		if !exchange.Equal(o1, o2) {
+			t.Fatalf("order %s: want %s, got %s", id, o1, o2)
+		}
+	}
+
+	// Loaded limiter must save the same keys and values.
+	snap1, err := kvtest.Take(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db2 := kvtest.New()
+	if err := kv.WithReadWriter(ctx, db2, l2.Save); err != nil {
+		t.Fatal(err)
+	}
+	snap2, err := kvtest.Take(ctx, db2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, diff := range kvtest.Diff(snap1, snap2) {
+		if !strings.HasPrefix(diff, "~") {
+			t.Fatalf("want same keys after a round-trip, got %v", kvtest.Diff(snap1, snap2))
+		}
+		// Gob encoding of the order map is not deterministic, so the values are
+		// compared in the json form.
+		key := diff[1:]
+		js := func(data []byte) string {
+			var gv gobs.LimiterState
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&gv); err != nil {
+				t.Fatalf("could not decode value at key %q: %v", key, err)
+			}
+			v, err := json.Marshal(&gv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(v)
+		}
+		if a, b := js(snap1[key]), js(snap2[key]); a != b {
+			t.Fatalf("key %s: want %s, got %s", key, a, b)
+		}
+	}
+}
+
+// TestLimiterGolden pins the saved limiter state format. Golden file must be
+// updated with the UPDATE_GOLDEN environment variable only when the gob
+// contract is changed intentionally.
+func TestLimiterGolden(t *testing.T) {
+	ctx := context.Background()
+	db := kvtest.New()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("2"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+		Tag:    "grid-a",
+	}
+	l, err := New("00000000-0000-0000-0000-000000000001", "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.idgen.NextID()
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.orderMap.Store("done", &exchange.Order{
+		OrderID:       "done",
+		ClientOrderID: l.idgen.NextID().String(),
+		Side:          "BUY",
+		CreateTime:    exchange.RemoteTime{Time: at},
+		FinishTime:    exchange.RemoteTime{Time: at.Add(time.Minute)},
+		FilledSize:    decimal.RequireFromString("1"),
+		FilledPrice:   decimal.RequireFromString("100"),
+		Fee:           decimal.RequireFromString("0.25"),
+		Liquidity:     exchange.LiquidityMaker,
+		Status:        "FILLED",
+		Done:          true,
+	})
+	if err := kv.WithReadWriter(ctx, db, l.Save); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := kvtest.Take(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kvtest.CheckGolden(t, snap, "testdata/limiter-state.golden.json", false /* update */)
+}
+
+func TestLimiterLoadUpgrade(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	// Older limiter states do not have the exchange name.
+	uid := uuid.NewString()
+	gv := &gobs.LimiterState{
+		V2: &gobs.LimiterStateV2{
+			ProductID:      "BTC-USD",
+			ClientIDOffset: 3,
+			TradePoint: gobs.Point{
+				Size:   decimal.RequireFromString("1"),
+				Price:  decimal.RequireFromString("100"),
+				Cancel: decimal.RequireFromString("90"),
+			},
+		},
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(gv); err != nil {
+		t.Fatal(err)
+	}
+	save := func(ctx context.Context, rw kv.ReadWriter) error {
+		return rw.Set(ctx, path.Join(DefaultKeyspace, uid), &buf)
+	}
+	if err := kv.WithReadWriter(ctx, db, save); err != nil {
+		t.Fatal(err)
+	}
+
+	var l *Limiter
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		l, err = Load(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+	if l.ExchangeName() != "coinbase" {
+		t.Fatalf("exchange name: want coinbase, got %q", l.ExchangeName())
+	}
+	if l.idgen.Seed() != uid {
+		t.Fatalf("idgen seed: want %s, got %s", uid, l.idgen.Seed())
+	}
+	if l.idgen.Offset() != 3 {
+		t.Fatalf("idgen offset: want 3, got %d", l.idgen.Offset())
+	}
+	if !l.IsSell() {
+		t.Fatalf("limiter must be a sell")
+	}
+}
+
+func TestLimiterDust(t *testing.T) {
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.orderMap.Store("done", newTestOrder("done", "0.99995", "100", true))
+
+	if v := l.PendingSize(); !v.Equal(decimal.RequireFromString("0.00005")) {
+		t.Fatalf("pending size without base increment: want 0.00005, got %s", v)
+	}
+
+	inc := decimal.RequireFromString("0.0001")
+	l.baseIncrement.Store(&inc)
+	if v := l.PendingSize(); !v.IsZero() {
+		t.Fatalf("pending size below base increment: want 0, got %s", v)
+	}
+
+	inc = decimal.RequireFromString("0.00001")
+	l.baseIncrement.Store(&inc)
+	if v := l.PendingSize(); !v.Equal(decimal.RequireFromString("0.00005")) {
+		t.Fatalf("pending size above base increment: want 0.00005, got %s", v)
+	}
+}
+
+func TestRoundDown(t *testing.T) {
+	tests := []struct {
+		size, increment, want string
+	}{
+		{"0.123456789", "0.00001", "0.12345"},
+		{"0.12345", "0.00001", "0.12345"},
+		{"0.00000999", "0.00001", "0"},
+		{"12.7", "1", "12"},
+		{"0.123456789", "0", "0.123456789"},
+	}
+	for _, test := range tests {
+		size := decimal.RequireFromString(test.size)
+		inc := decimal.RequireFromString(test.increment)
+		if got := roundDown(size, inc); !got.Equal(decimal.RequireFromString(test.want)) {
+			t.Errorf("roundDown(%s, %s): want %s, got %s", test.size, test.increment, test.want, got)
+		}
+	}
+}
+
+func TestLimiterOverfill(t *testing.T) {
+	p := &point.Point{
+		Size:   decimal.RequireFromString("5"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	v, err := New(uuid.NewString(), "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.orderMap.Store("first", newTestOrder("first", "3", "100", true))
+	v.orderMap.Store("second", newTestOrder("second", "2.5", "100", true))
+
+	if s := v.PendingSize(); !s.IsZero() {
+		t.Fatalf("want zero pending size, got %s", s)
+	}
+	if want, got := decimal.RequireFromString("0.5"), v.OverfilledSize(); !got.Equal(want) {
+		t.Fatalf("want overfilled size %s, got %s", want, got)
+	}
+
+	q := &point.Point{
+		Size:        decimal.RequireFromString("500"),
+		Price:       decimal.RequireFromString("100"),
+		Cancel:      decimal.RequireFromString("110"),
+		SizeInQuote: true,
+	}
+	w, err := New(uuid.NewString(), "coinbase", "BTC-USD", q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.orderMap.Store("only", newTestOrder("only", "6", "100", true))
+	if s := w.PendingSize(); !s.IsZero() {
+		t.Fatalf("want zero pending size in quote mode, got %s", s)
+	}
+	if want, got := decimal.RequireFromString("1"), w.OverfilledSize(); !got.Equal(want) {
+		t.Fatalf("want overfilled size %s in quote mode, got %s", want, got)
+	}
+
+	// Size limit is in base units, so it is checked against the base size of
+	// the quote funds, which is 5 here.
+	if err := w.SetOption("size-limit", "5"); err != nil {
+		t.Fatalf("want size-limit within the base size in quote mode, got %v", err)
+	}
+	if err := w.SetOption("size-limit", "6"); err == nil {
+		t.Fatalf("want error for size-limit above the base size in quote mode")
+	}
+}
+
+func TestLimiterSideMirror(t *testing.T) {
+	buy, err := New(uuid.NewString(), "coinbase", "BTC-USD", &point.Point{
+		Size:   decimal.NewFromInt(1),
+		Price:  decimal.NewFromInt(100),
+		Cancel: decimal.NewFromInt(110),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sell, err := New(uuid.NewString(), "coinbase", "BTC-USD", &point.Point{
+		Size:   decimal.NewFromInt(1),
+		Price:  decimal.NewFromInt(100),
+		Cancel: decimal.NewFromInt(90),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !buy.IsBuy() || !sell.IsSell() {
+		t.Fatalf("want buy and sell limiters")
+	}
+
+	// Sell limiter's decisions at price 200-p must match the buy limiter's
+	// decisions at price p.
+	mirror := decimal.NewFromInt(200)
+	for p := int64(80); p <= 120; p++ {
+		price := decimal.NewFromInt(p)
+		mprice := mirror.Sub(price)
+		if a, b := buy.shouldCancel(price), sell.shouldCancel(mprice); a != b {
+			t.Errorf("price %d: buy shouldCancel=%v, but sell shouldCancel=%v at mirror price %s", p, a, b, mprice)
+		}
+		if a, b := buy.shouldCreate(price), sell.shouldCreate(mprice); a != b {
+			t.Errorf("price %d: buy shouldCreate=%v, but sell shouldCreate=%v at mirror price %s", p, a, b, mprice)
+		}
+		if buy.shouldCancel(price) == buy.shouldCreate(price) {
+			t.Errorf("price %d: buy create and cancel decisions must be exclusive", p)
+		}
+	}
+	if !buy.shouldCancel(decimal.NewFromInt(110)) || !sell.shouldCancel(decimal.NewFromInt(90)) {
+		t.Errorf("orders must be canceled at the cancel price")
+	}
+}
+
+func TestLimiterMerge(t *testing.T) {
+	newLimiter := func(size, cancel string) *Limiter {
+		v, err := New(uuid.NewString(), "coinbase", "BTC-USD", &point.Point{
+			Size:   decimal.RequireFromString(size),
+			Price:  decimal.RequireFromString("100"),
+			Cancel: decimal.RequireFromString(cancel),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	a, b := newLimiter("5", "110"), newLimiter("3", "110")
+	a.orderMap.Store("a1", newTestOrder("a1", "5", "100", true))
+	b.orderMap.Store("b1", newTestOrder("b1", "1", "100", true))
+
+	v, err := Merge(uuid.NewString(), a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := decimal.NewFromInt(8); !v.point.Size.Equal(want) {
+		t.Fatalf("want merged size %s, got %s", want, v.point.Size)
+	}
+	if want := decimal.NewFromInt(6); !v.FilledSize().Equal(want) {
+		t.Fatalf("want merged filled size %s, got %s", want, v.FilledSize())
+	}
+
+	if _, err := Merge(uuid.NewString(), a, newLimiter("3", "120")); err == nil {
+		t.Fatalf("want error for limiters with different price points")
+	}
+
+	c := newLimiter("3", "110")
+	c.orderMap.Store("c1", newTestOrder("c1", "1", "100", false))
+	if _, err := Merge(uuid.NewString(), a, c); err == nil {
+		t.Fatalf("want error for limiters with live orders")
+	}
+
+	d := newLimiter("3", "110")
+	conflict := newTestOrder("a1", "4", "100", true)
+	d.orderMap.Store("a1", conflict)
+	if _, err := Merge(uuid.NewString(), a, d); err == nil {
+		t.Fatalf("want error for conflicting orders")
+	}
+}
+
+func TestLimiterSaveIfChanged(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("10"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.orderMap.Store("a", newTestOrder("a", "4", "100", true))
+	l.orderMap.Store("b", newTestOrder("b", "2", "99", false))
+
+	save := func() bool {
+		var written bool
+		if err := kv.WithReadWriter(ctx, db, func(ctx context.Context, rw kv.ReadWriter) (err error) {
+			written, err = l.SaveIfChanged(ctx, rw)
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return written
+	}
+
+	if !save() {
+		t.Fatalf("first save must write the state")
+	}
+	for i := 0; i < 5; i++ {
+		if save() {
+			t.Fatalf("unchanged state must not be written again")
+		}
+	}
+	if err := l.SetOption("size-limit", "5"); err != nil {
+		t.Fatal(err)
+	}
+	if !save() {
+		t.Fatalf("changed state must be written")
+	}
+
+	// Removing the value from the database must force a write.
+	if err := kv.WithReadWriter(ctx, db, func(ctx context.Context, rw kv.ReadWriter) error {
+		return rw.Delete(ctx, path.Join(DefaultKeyspace, l.uid))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !save() {
+		t.Fatalf("missing state must be written")
+	}
+}
+
+func TestLimiterSaveIfChangedRollback(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("10"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kv.WithReadWriter(ctx, db, l.Save); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetOption("size-limit", "5"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write of the changed state is rolled back with the transaction, so the
+	// next save must write it again even though the state is unchanged since.
+	errAbort := errors.New("abort")
+	abort := func(ctx context.Context, rw kv.ReadWriter) error {
+		if written, err := l.SaveIfChanged(ctx, rw); err != nil || !written {
+			t.Fatalf("want changed state written, got written=%t err=%v", written, err)
+		}
+		return errAbort
+	}
+	if err := kv.WithReadWriter(ctx, db, abort); !errors.Is(err, errAbort) {
+		t.Fatalf("want transaction aborted, got %v", err)
+	}
+
+	var written bool
+	save := func(ctx context.Context, rw kv.ReadWriter) (err error) {
+		written, err = l.SaveIfChanged(ctx, rw)
+		return err
+	}
+	if err := kv.WithReadWriter(ctx, db, save); err != nil {
+		t.Fatal(err)
+	}
+	if !written {
+		t.Fatalf("want state written after the rolled back save")
+	}
+
+	// Saved state must be loaded back as is.
+	var v *Limiter
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		v, err = Load(ctx, l.uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := v.sizeLimit(), decimal.NewFromInt(5); !got.Equal(want) {
+		t.Fatalf("want size limit %s after reload, got %s", want, got)
+	}
+}
+
+func TestLimiterFillLatency(t *testing.T) {
+	p := &point.Point{
+		Size:   decimal.RequireFromString("10"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	l.recordCreate("filled1", now.Add(-3*time.Second))
+	l.recordCreate("filled2", now.Add(-time.Second))
+	l.recordCreate("canceled", now.Add(-2*time.Second))
+
+	l.recordDone(newTestOrder("filled1", "4", "100", false), now)
+	l.recordDone(newTestOrder("filled1", "4", "100", true), now)
+	l.recordDone(newTestOrder("filled2", "2", "100", true), now)
+	l.recordDone(newTestOrder("canceled", "0", "100", true), now)
+
+	stats := l.FillLatency()
+	if stats.Count != 2 {
+		t.Fatalf("want 2 fills, got %d", stats.Count)
+	}
+	if stats.Min != time.Second || stats.Max != 3*time.Second || stats.Average != 2*time.Second || stats.Last != time.Second {
+		t.Fatalf("unexpected fill latency stats %+v", stats)
+	}
+}
+
+func TestLimiterFetchMissingOrder(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("10"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.orderMap.Store("missing", newTestOrder("missing", "0", "100", false))
+	product := paper.New("BTC-USD", nil)
+
+	if err := l.SetOption("fail-on-missing-orders", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.fetchOrderMap(ctx, product); !errors.Is(err, exchange.ErrNotFound) {
+		t.Fatalf("want ErrNotFound with fail-on-missing-orders option, got %v", err)
+	}
+
+	if err := l.SetOption("fail-on-missing-orders", "false"); err != nil {
+		t.Fatal(err)
+	}
+	nupdated, err := l.fetchOrderMap(ctx, product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nupdated != 1 {
+		t.Fatalf("want one updated order, got %d", nupdated)
+	}
+	if order, ok := l.orderMap.Load("missing"); !ok || !order.Done {
+		t.Fatalf("missing order must be marked as done")
+	}
+
+	if err := kv.WithReadWriter(ctx, db, l.Save); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.orderMap.Load("missing"); ok {
+		t.Fatalf("missing order without fills must be compacted away")
+	}
+}
+
+func TestLimiterSaveOffsetOnly(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("10"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l, err := New(uid, "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.orderMap.Store("a", newTestOrder("a", "4", "100", true))
+	if err := kv.WithReadWriter(ctx, db, l.Save); err != nil {
+		t.Fatal(err)
+	}
+
+	l.idgen.NextID()
+	l.idgen.NextID()
+	if err := kv.WithReadWriter(ctx, db, l.Save); err != nil {
+		t.Fatal(err)
+	}
+
+	key := path.Join(DefaultKeyspace, uid)
+	state, err := kvutil.GetDB[gobs.LimiterState](ctx, db, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.V2.ClientIDOffset != 0 {
+		t.Fatalf("offset only change must not rewrite the limiter state")
+	}
+	offset, err := kvutil.GetDB[gobs.LimiterOffset](ctx, db, path.Join(OffsetKeyspace, uid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset.ClientIDOffset != 2 {
+		t.Fatalf("want saved offset 2, got %d", offset.ClientIDOffset)
+	}
+
+	var l2 *Limiter
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		l2, err = Load(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := l.idgen.Offset(), l2.idgen.Offset(); a != b {
+		t.Fatalf("idgen offset: want %d, got %d", a, b)
+	}
+}
+
+func TestLimiterComplete(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("10"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l, err := New(uid, "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.orderMap.Store("live", newTestOrder("live", "4", "100", false))
+	if err := l.Complete(); err == nil {
+		t.Fatalf("limiter with a live order must not be completed")
+	}
+	l.orderMap.Store("live", newTestOrder("live", "4", "100", true))
+	if err := l.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	if !l.PendingSize().IsZero() {
+		t.Fatalf("force-completed limiter must have zero pending size")
+	}
+	if want := decimal.NewFromInt(6); !l.ResidualSize().Equal(want) {
+		t.Fatalf("want residual size %s, got %s", want, l.ResidualSize())
+	}
+	if err := l.Complete(); err == nil {
+		t.Fatalf("force-completed limiter must not be completed again")
+	}
+
+	if err := kv.WithReadWriter(ctx, db, l.Save); err != nil {
+		t.Fatal(err)
+	}
+	var l2 *Limiter
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		l2, err = Load(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+	if !l2.IsForceCompleted() || !l2.PendingSize().IsZero() || !l2.ResidualSize().Equal(l.ResidualSize()) {
+		t.Fatalf("force-completion must be persisted")
+	}
+}
+
+func TestLimiterAvgEntryPrice(t *testing.T) {
+	p := &point.Point{
+		Size:   decimal.RequireFromString("10"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := l.AvgEntryPrice(); !v.IsZero() {
+		t.Fatalf("want zero average entry price without fills, got %s", v)
+	}
+
+	for _, order := range []*exchange.Order{
+		newTestOrder("order1", "1", "100", true),
+		newTestOrder("order2", "3", "96", true),
+		newTestOrder("order3", "0", "90", true),
+	} {
+		l.orderMap.Store(order.OrderID, order)
+	}
+	if v, want := l.AvgEntryPrice(), decimal.NewFromInt(97); !v.Equal(want) {
+		t.Fatalf("want average entry price %s, got %s", want, v)
+	}
+}
+
+func TestLimiterAuditTrail(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l, err := New(uid, "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := &trader.Runtime{Database: db, Product: paper.New("BTC-USD", nil)}
+
+	// No entries are saved without the audit-trail option.
+	id, err := l.createTraced(ctx, rt, false /* market */, decimal.NewFromInt(105), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.cancelTraced(ctx, rt, id, decimal.NewFromInt(111), "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetOption("audit-trail", "true"); err != nil {
+		t.Fatal(err)
+	}
+	id, err = l.createTraced(ctx, rt, false /* market */, decimal.NewFromInt(105), "create")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.cancelTraced(ctx, rt, id, decimal.NewFromInt(111), "cancel"); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []*gobs.LimiterTrailEntry
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		entries, err = LoadTrail(ctx, r, uid)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("want 2 trail entries, got %d", len(entries))
+	}
+	order, _ := l.orderMap.Load(id)
+	for i, action := range []string{"create", "cancel"} {
+		e := entries[i]
+		if e.Action != action || e.Reason != action {
+			t.Fatalf("entry %d: want action %q, got %q (reason %q)", i, action, e.Action, e.Reason)
+		}
+		if e.ServerOrderID != string(id) || e.ClientOrderID != order.ClientOrderID {
+			t.Fatalf("entry %d: want order %s/%s, got %s/%s", i, id, order.ClientOrderID, e.ServerOrderID, e.ClientOrderID)
+		}
+	}
+	if !entries[0].TickerPrice.Equal(decimal.NewFromInt(105)) {
+		t.Fatalf("want ticker price 105 in the create entry, got %s", entries[0].TickerPrice)
+	}
+}
+
+func TestLimiterEstimatedCompletion(t *testing.T) {
+	p := &point.Point{
+		Size:   decimal.RequireFromString("10"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.EstimatedCompletion(); ok {
+		t.Fatalf("estimate must not be available without any orders")
+	}
+
+	// Order created an hour ago has filled 4 out of 10, so the remaining 6 must
+	// take one and half hours at the same rate.
+	order := newTestOrder("a", "4", "100", false)
+	l.orderMap.Store("a", order)
+	now := order.CreateTime.Time.Add(time.Hour)
+	if d, ok := l.estimatedCompletionAt(now); !ok || d != 90*time.Minute {
+		t.Fatalf("want estimate 90m, got %s (%t)", d, ok)
+	}
+
+	l.orderMap.Store("a", newTestOrder("a", "10", "100", true))
+	if d, ok := l.EstimatedCompletion(); !ok || d != 0 {
+		t.Fatalf("want zero estimate for a filled limiter, got %s (%t)", d, ok)
+	}
+}
+
+func TestLimiterProductMismatch(t *testing.T) {
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := &trader.Runtime{Database: kvmemdb.New(), Product: paper.New("ETH-USD", nil)}
+	err = l.Run(context.Background(), rt)
+	if !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("want os.ErrInvalid, got %v", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "BTC-USD") || !strings.Contains(msg, "ETH-USD") {
+		t.Fatalf("error must name both the product ids, got %q", msg)
+	}
+}
+
+func TestLimiterArchiveOrders(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("10"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l, err := New(uid, "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetOption("archive-orders", "true"); err != nil {
+		t.Fatal(err)
+	}
+	l.orderMap.Store("a", newTestOrder("a", "2", "100", true))
+	l.orderMap.Store("b", newTestOrder("b", "3", "100", true))
+	l.orderMap.Store("c", newTestOrder("c", "1", "100", false))
+	if err := kv.WithReadWriter(ctx, db, l.Save); err != nil {
+		t.Fatal(err)
+	}
+
+	key := path.Join(DefaultKeyspace, uid)
+	state, err := kvutil.GetDB[gobs.LimiterState](ctx, db, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(state.V2.ServerIDOrderMap); n != 1 {
+		t.Fatalf("want only the live order in the saved order map, got %d orders", n)
+	}
+	if state.V2.ArchivedOrders != 2 || !state.V2.ArchivedFilledSize.Equal(decimal.NewFromInt(5)) {
+		t.Fatalf("want 2 archived orders with size 5, got %d with size %s", state.V2.ArchivedOrders, state.V2.ArchivedFilledSize)
+	}
+
+	var l2 *Limiter
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		l2, err = Load(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := l.FilledSize(), l2.FilledSize(); !a.Equal(decimal.NewFromInt(6)) || !a.Equal(b) {
+		t.Fatalf("want filled size 6 before and after reload, got %s and %s", a, b)
+	}
+	if a, b := l.PendingSize(), l2.PendingSize(); !a.Equal(decimal.NewFromInt(4)) || !a.Equal(b) {
+		t.Fatalf("want pending size 4 before and after reload, got %s and %s", a, b)
+	}
+	if a, b := l.Fees(), l2.Fees(); !a.Equal(b) {
+		t.Fatalf("want same fees after reload, got %s and %s", a, b)
+	}
+	actions := l2.Actions()
+	if len(actions) != 1 || len(actions[0].Orders) != 2 {
+		t.Fatalf("want archived orders in the actions, got %v", actions)
+	}
+}
+
+func TestLimiterRejectedOrder(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	product := paper.New("BTC-USD", nil)
+	rt := &trader.Runtime{Database: kvmemdb.New(), Product: product}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- l.Run(ctx, rt) }()
+
+	liveOrder := func() exchange.OrderID {
+		for ctx.Err() == nil {
+			if live := l.LiveOrders(); len(live) == 1 {
+				return live[0].OrderID
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatal("timed out waiting for a live order")
+		return ""
+	}
+	feed := func(price string) {
+		for product.NumTickerSubscribers() == 0 && ctx.Err() == nil {
+			time.Sleep(time.Millisecond)
+		}
+		ticker := &exchange.Ticker{Timestamp: exchange.RemoteTime{Time: time.Now()}, Price: decimal.RequireFromString(price)}
+		if err := product.FeedTicker(ctx, ticker); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	feed("105")
+	first := liveOrder()
+	if err := product.Reject(ctx, first, "self-trade prevention"); err != nil {
+		t.Fatal(err)
+	}
+	for ctx.Err() == nil {
+		if order, ok := l.orderMap.Load(first); ok && order.Done {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Limiter must create a new order on the next ticker.
+	feed("105")
+	second := liveOrder()
+	if second == first {
+		t.Fatalf("rejected order must not remain active")
+	}
+
+	feed("99")
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if !l.PendingSize().IsZero() {
+		t.Fatalf("want limiter to complete, pending size is %s", l.PendingSize())
+	}
+}
+
+func TestLimiterVerifyCompletion(t *testing.T) {
+	ctx := context.Background()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetOption("completion-grace", "-1s"); err == nil {
+		t.Fatalf("negative completion-grace must be rejected")
+	}
+	if err := l.SetOption("completion-grace", "10ms"); err != nil {
+		t.Fatal(err)
+	}
+
+	product := paper.New("BTC-USD", &paper.Options{FeePct: 1})
+	id, err := product.LimitBuy(ctx, uuid.NewString(), p.Size, p.Price, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticker := &exchange.Ticker{Timestamp: exchange.RemoteTime{Time: time.Now()}, Price: decimal.RequireFromString("99")}
+	if err := product.FeedTicker(ctx, ticker); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fill update has reached the limiter without the fee.
+	order, err := product.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order.Fee = decimal.Zero
+	l.orderMap.Store(id, order)
+	if !l.PendingSize().IsZero() {
+		t.Fatalf("want zero pending size, got %s", l.PendingSize())
+	}
+
+	done, err := l.verifyCompletion(ctx, product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !done {
+		t.Fatalf("limiter must be verified as complete")
+	}
+	if fees := l.Fees(); !fees.Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("want fee 1 from the final order fetch, got %s", fees)
+	}
+}
+
+// slowGetProduct is a paper product whose order fetches block till released,
+// ignoring the context.
+type slowGetProduct struct {
+	*paper.Product
+
+	startCh   chan struct{}
+	releaseCh chan struct{}
+}
+
+func (p *slowGetProduct) Get(ctx context.Context, id exchange.OrderID) (*exchange.Order, error) {
+	close(p.startCh)
+	<-p.releaseCh
+	return p.Product.Get(ctx, id)
+}
+
+func TestLimiterCancelDuringStartup(t *testing.T) {
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.orderMap.Store("live", newTestOrder("live", "0", "100", false))
+
+	product := &slowGetProduct{
+		Product:   paper.New("BTC-USD", nil),
+		startCh:   make(chan struct{}),
+		releaseCh: make(chan struct{}),
+	}
+	defer close(product.releaseCh)
+	rt := &trader.Runtime{Database: kvmemdb.New(), Product: product}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- l.Run(ctx, rt) }()
+
+	<-product.startCh
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("want context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("limiter must return promptly when canceled during the startup fetch")
+	}
+	if n := product.NumTickerSubscribers(); n != 0 {
+		t.Fatalf("limiter must not enter the main loop, found %d ticker subscribers", n)
+	}
+}
+
+func TestLimiterSaveInconsistentOrderMap(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("10"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l, err := New(uid, "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two server orders mapped to the same client order id.
+	a := newTestOrder("a", "1", "100", true)
+	b := newTestOrder("b", "1", "100", true)
+	b.ClientOrderID = a.ClientOrderID
+	l.orderMap.Store(a.OrderID, a)
+	l.orderMap.Store(b.OrderID, b)
+	if err := kv.WithReadWriter(ctx, db, l.Save); err == nil {
+		t.Fatalf("save must fail when two orders share a client order id")
+	}
+	key := path.Join(DefaultKeyspace, uid)
+	if _, err := kvutil.GetDB[gobs.LimiterState](ctx, db, key); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("inconsistent limiter state must not be saved, got %v", err)
+	}
+
+	// Order stored under a different server order id.
+	b.ClientOrderID = uuid.NewString()
+	l.orderMap.Delete(b.OrderID)
+	l.orderMap.Store("c", b)
+	if err := checkOrderMap(l.dupOrderMap()); err == nil {
+		t.Fatalf("order stored under a different server order id must be detected")
+	}
+
+	l.orderMap.Delete("c")
+	l.orderMap.Store(b.OrderID, b)
+	if err := kv.WithReadWriter(ctx, db, l.Save); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLimiterHoldSaveLoad(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("10"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l1, err := New(uid, "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l1.Hold(true); err != nil {
+		t.Fatal(err)
+	}
+	if !l1.IsHeld() {
+		t.Fatalf("want limiter held after hold")
+	}
+	if err := kv.WithReadWriter(ctx, db, l1.Save); err != nil {
+		t.Fatal(err)
+	}
+
+	var l2 *Limiter
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		l2, err = Load(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+	if !l2.IsHeld() {
+		t.Fatalf("want hold option to persist across save and load")
+	}
+	if err := l2.Hold(false); err != nil {
+		t.Fatal(err)
+	}
+	if l2.IsHeld() {
+		t.Fatalf("want limiter released after clearing hold")
+	}
+}
+
+func TestLimiterReduceOnSizeChange(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("10"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetOption("reduce-on-size-change", "true"); err != nil {
+		t.Fatal(err)
+	}
+	product := paper.New("BTC-USD", nil)
+	rt := &trader.Runtime{Database: kvmemdb.New(), Product: product}
+
+	runCtx, runCancel := context.WithCancel(ctx)
+	errCh := make(chan error, 1)
+	go func() { errCh <- l.Run(runCtx, rt) }()
+
+	feed := func(price string) {
+		for product.NumTickerSubscribers() == 0 && ctx.Err() == nil {
+			time.Sleep(time.Millisecond)
+		}
+		ticker := &exchange.Ticker{Timestamp: exchange.RemoteTime{Time: time.Now()}, Price: decimal.RequireFromString(price)}
+		if err := product.FeedTicker(ctx, ticker); err != nil {
+			t.Fatal(err)
+		}
+	}
+	liveOrder := func() exchange.OrderID {
+		for ctx.Err() == nil {
+			if live := l.LiveOrders(); len(live) == 1 {
+				return live[0].OrderID
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatal("timed out waiting for a live order")
+		return ""
+	}
+
+	feed("105")
+	first := liveOrder()
+
+	if err := l.SetOption("size-limit", "4"); err != nil {
+		t.Fatal(err)
+	}
+	want := decimal.RequireFromString("4")
+	for ctx.Err() == nil {
+		feed("105")
+		if size, ok := l.orderSizes.Load(first); ok && size.Equal(want) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if live := liveOrder(); live != first {
+		t.Fatalf("want order %s to be reduced in place, got a new order %s", first, live)
+	}
+
+	// Reduced order must fill only the new size.
+	feed("99")
+	for ctx.Err() == nil && !l.FilledSize().IsPositive() {
+		time.Sleep(time.Millisecond)
+	}
+	if got := l.FilledSize(); !got.Equal(want) {
+		t.Fatalf("filled size: want %s, got %s", want, got)
+	}
+
+	runCancel()
+	<-errCh
+}
+
+func TestLimiterMaxPriceAge(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetOption("max-price-age", "1m"); err != nil {
+		t.Fatal(err)
+	}
+	product := paper.New("BTC-USD", nil)
+	rt := &trader.Runtime{Database: kvmemdb.New(), Product: product}
+
+	runCtx, runCancel := context.WithCancel(ctx)
+	errCh := make(chan error, 1)
+	go func() { errCh <- l.Run(runCtx, rt) }()
+
+	feed := func(price string, at time.Time) {
+		for product.NumTickerSubscribers() == 0 && ctx.Err() == nil {
+			time.Sleep(time.Millisecond)
+		}
+		ticker := &exchange.Ticker{Timestamp: exchange.RemoteTime{Time: at}, Price: decimal.RequireFromString(price)}
+		if err := product.FeedTicker(ctx, ticker); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	feed("105", time.Now().Add(-time.Hour))
+	time.Sleep(50 * time.Millisecond)
+	if live := l.LiveOrders(); len(live) != 0 {
+		t.Fatalf("want no orders for a stale ticker, got %d", len(live))
+	}
+
+	feed("105", time.Now())
+	for ctx.Err() == nil && len(l.LiveOrders()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if live := l.LiveOrders(); len(live) != 1 {
+		t.Fatalf("want an order for the fresh ticker, got %d", len(live))
+	}
+
+	runCancel()
+	<-errCh
+}
+
+func TestLimiterBudgetFootprint(t *testing.T) {
+	buy := &point.Point{
+		Size:   decimal.RequireFromString("10"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	b, err := New(uuid.NewString(), "coinbase", "BTC-USD", buy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10 * 100 plus 0.25% fee.
+	if v, want := b.BudgetFootprint(), decimal.RequireFromString("1002.5"); !v.Equal(want) {
+		t.Fatalf("buy footprint: want %s, got %s", want, v)
+	}
+	// Only the pending size 6 reserves the funds after a partial fill.
+	b.orderMap.Store("partial", newTestOrder("partial", "4", "100", false))
+	if v, want := b.BudgetFootprint(), decimal.RequireFromString("601.5"); !v.Equal(want) {
+		t.Fatalf("partially filled buy footprint: want %s, got %s", want, v)
+	}
+	b.orderMap.Store("partial", newTestOrder("partial", "10", "100", true))
+	if v := b.BudgetFootprint(); !v.IsZero() {
+		t.Fatalf("filled buy footprint: want zero, got %s", v)
+	}
+
+	sell := &point.Point{
+		Size:   decimal.RequireFromString("10"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("90"),
+	}
+	s, err := New(uuid.NewString(), "coinbase", "BTC-USD", sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := s.BudgetFootprint(); !v.IsZero() {
+		t.Fatalf("sell footprint: want zero, got %s", v)
+	}
+}
+
+func TestLimiterMaxFeePct(t *testing.T) {
+	p := &point.Point{
+		Size:   decimal.RequireFromString("0.001"),
+		Price:  decimal.RequireFromString("1"),
+		Cancel: decimal.RequireFromString("2"),
+	}
+	l, err := New(uuid.NewString(), "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.checkMaxFee(p.Size); err != nil {
+		t.Fatalf("max fee check must be disabled by default: %v", err)
+	}
+	if err := l.SetOption("max-fee-pct", "-1"); err == nil {
+		t.Fatalf("want error for a -ve max-fee-pct")
+	}
+	if err := l.SetOption("max-fee-pct", "1"); err != nil {
+		t.Fatal(err)
+	}
+	// Fee for a tiny order is rounded up to a cent, which is 1000% of the value.
+	if err := l.checkMaxFee(p.Size); !errors.Is(err, ErrFeeTooHigh) {
+		t.Fatalf("want ErrFeeTooHigh for a dust order, got %v", err)
+	}
+	if err := l.checkMaxFee(decimal.NewFromInt(1000)); err != nil {
+		t.Fatalf("want no error for a large order, got %v", err)
+	}
+	if v := l.Config()["max-fee-pct"]; v != "1" {
+		t.Fatalf("want max-fee-pct config 1, got %q", v)
+	}
+}
+
+// lowBalanceProduct reports a zero available balance for all currencies.
+type lowBalanceProduct struct {
+	*paper.Product
+}
+
+func (p *lowBalanceProduct) AvailableBalance(ctx context.Context, currency string) (decimal.Decimal, error) {
+	return decimal.Zero, nil
+}
+
+func TestLimiterInsufficientBalance(t *testing.T) {
+	ctx := context.Background()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := l.idgen.Offset()
+	product := &lowBalanceProduct{Product: paper.New("BTC-USD", nil)}
+	if _, err := l.create(ctx, product); !errors.Is(err, exchange.ErrInsufficientFunds) {
+		t.Fatalf("want ErrInsufficientFunds, got %v", err)
+	}
+	if got := l.idgen.Offset(); got != offset {
+		t.Fatalf("client order id must be reverted: want offset %d, got %d", offset, got)
+	}
+}
+
+// stopLimitProduct records the good-till and tag arguments of the stop-limit
+// orders.
+type stopLimitProduct struct {
+	*paper.Product
+
+	goodTill time.Duration
+	tag      string
+}
+
+func (p *stopLimitProduct) StopLimitBuy(ctx context.Context, clientOrderID string, size, limitPrice, triggerPrice decimal.Decimal, goodTill time.Duration, tag string) (exchange.OrderID, error) {
+	p.goodTill, p.tag = goodTill, tag
+	return p.Product.StopLimitBuy(ctx, clientOrderID, size, limitPrice, triggerPrice, goodTill, tag)
+}
+
+func TestLimiterStopLimitOptions(t *testing.T) {
+	ctx := context.Background()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l, err := New(path.Join(uid, "buy-000000"), "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetOption("trigger-price", "99"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetOption("good-till", "1h"); err != nil {
+		t.Fatal(err)
+	}
+
+	product := &stopLimitProduct{Product: paper.New("BTC-USD", nil)}
+	id, err := l.create(ctx, product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.goodTill != time.Hour {
+		t.Fatalf("want good-till 1h for the stop-limit order, got %s", product.goodTill)
+	}
+	if want := l.orderTag(); product.tag != want || len(want) == 0 {
+		t.Fatalf("want stop-limit order tag %q, got %q", want, product.tag)
+	}
+	order, err := product.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Tag != product.tag {
+		t.Fatalf("want order tag %q, got %q", product.tag, order.Tag)
+	}
+}
+
+func TestLimiterGoodTillOption(t *testing.T) {
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetOption("good-till", "90m"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Good-till durations in the past, too short for the exchange or not
+	// parseable are rejected and the previous value is kept.
+	for _, value := range []string{"-1h", "-1s", "30s", "59s", "1 hour", "tomorrow", ""} {
+		if err := l.SetOption("good-till", value); err == nil {
+			t.Errorf("want error for good-till value %q", value)
+		}
+	}
+	if v := l.goodTill(); v != 90*time.Minute {
+		t.Fatalf("want good-till 90m after rejected values, got %s", v)
+	}
+
+	// Zero value resets to good-till-cancel orders.
+	if err := l.SetOption("good-till", "0"); err != nil {
+		t.Fatal(err)
+	}
+	if v := l.goodTill(); v != 0 {
+		t.Fatalf("want zero good-till after reset, got %s", v)
+	}
+}
+
+// chanTickerProduct is a paper product that delivers the tickers from an
+// unbuffered channel, so that tests control when each ticker is processed.
+type chanTickerProduct struct {
+	*paper.Product
+	tickerCh chan *exchange.Ticker
+}
+
+func newChanTickerProduct(opts *paper.Options) *chanTickerProduct {
+	return &chanTickerProduct{
+		Product:  paper.New("BTC-USD", opts),
+		tickerCh: make(chan *exchange.Ticker),
+	}
+}
+
+func (p *chanTickerProduct) TickerCh() (<-chan *exchange.Ticker, func()) {
+	return p.tickerCh, func() {}
+}
+
+func newTestTicker(price string) *exchange.Ticker {
+	return &exchange.Ticker{Timestamp: exchange.RemoteTime{Time: time.Now()}, Price: decimal.RequireFromString(price)}
+}
+
+// runLimiter runs the limiter in the background and returns a channel that
+// receives the result of Run. Limiter is stopped and waited for when the test
+// completes.
+func runLimiter(ctx context.Context, t *testing.T, l *Limiter, rt *trader.Runtime) <-chan error {
+	ctx, cancel := context.WithCancel(ctx)
+	errCh := make(chan error, 1)
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		errCh <- l.Run(ctx, rt)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-doneCh
+	})
+	return errCh
+}
+
+// waitForLiveOrders waits till the limiter has n live orders or the context
+// is expired and returns the live orders.
+func waitForLiveOrders(ctx context.Context, l *Limiter, n int) []*LiveOrder {
+	for ctx.Err() == nil && len(l.LiveOrders()) != n {
+		time.Sleep(time.Millisecond)
+	}
+	return l.LiveOrders()
+}
+
+func TestLimiterPanicRecovery(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l, err := New(uid, "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := kvmemdb.New()
+	product := newChanTickerProduct(nil)
+	rt := &trader.Runtime{Database: db, Product: product}
+	errCh := runLimiter(ctx, t, l, rt)
+
+	product.tickerCh <- newTestTicker("105")
+	live := waitForLiveOrders(ctx, l, 1)
+	if len(live) != 1 {
+		t.Fatalf("want one live order, got %d", len(live))
+	}
+
+	product.tickerCh <- nil
+	if err := <-errCh; err == nil || !strings.Contains(err.Error(), "panicked") {
+		t.Fatalf("want a panic error, got %v", err)
+	}
+
+	order, err := product.Get(ctx, live[0].OrderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !order.Done {
+		t.Fatalf("live order must be canceled after the panic")
+	}
+
+	var l2 *Limiter
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		l2, err = Load(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatalf("limiter state must be saved after the panic: %v", err)
+	}
+	if live := l2.LiveOrders(); len(live) != 0 {
+		t.Fatalf("want no live orders in the saved state, got %d", len(live))
+	}
+}
+
+func TestLimiterPriceGapPct(t *testing.T) {
+	d := decimal.RequireFromString
+	sell, err := New(uuid.NewString(), "coinbase", "BTC-USD", &point.Point{Size: d("1"), Price: d("110"), Cancel: d("105")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := sell.PriceGapPct(d("100")); !v.Equal(d("10")) {
+		t.Fatalf("sell: want 10%% gap, got %s", v)
+	}
+	if v := sell.PriceGapPct(d("120")); !v.IsZero() {
+		t.Fatalf("sell: want no gap above the limit price, got %s", v)
+	}
+
+	buy, err := New(uuid.NewString(), "coinbase", "BTC-USD", &point.Point{Size: d("1"), Price: d("90"), Cancel: d("95")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := buy.PriceGapPct(d("100")); !v.Equal(d("10")) {
+		t.Fatalf("buy: want 10%% gap, got %s", v)
+	}
+}
+
+// filledCancelProduct simulates an order that is filled at the exchange just
+// before it's cancel request, which fails with ErrOrderDone.
+type filledCancelProduct struct {
+	*chanTickerProduct
+
+	mu     sync.Mutex
+	filled map[exchange.OrderID]bool
+}
+
+func (p *filledCancelProduct) Cancel(ctx context.Context, id exchange.OrderID) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.filled[id] = true
+	return fmt.Errorf("UNKNOWN_CANCEL_ORDER: %w", exchange.ErrOrderDone)
+}
+
+func (p *filledCancelProduct) Get(ctx context.Context, id exchange.OrderID) (*exchange.Order, error) {
+	order, err := p.Product.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.filled[id] {
+		filled := *order
+		filled.Status = "FILLED"
+		filled.Done = true
+		filled.FilledSize = decimal.NewFromInt(1)
+		filled.FilledPrice = decimal.NewFromInt(100)
+		return &filled, nil
+	}
+	return order, nil
+}
+
+func TestLimiterCancelAfterFill(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	product := &filledCancelProduct{
+		chanTickerProduct: newChanTickerProduct(nil),
+		filled:            make(map[exchange.OrderID]bool),
+	}
+	rt := &trader.Runtime{Database: kvmemdb.New(), Product: product}
+	errCh := runLimiter(ctx, t, l, rt)
+
+	product.tickerCh <- newTestTicker("105")
+	waitForLiveOrders(ctx, l, 1)
+
+	// Ticker above the cancel price cancels the order, which is filled already.
+	select {
+	case product.tickerCh <- newTestTicker("115"):
+	case err := <-errCh:
+		t.Fatalf("limiter has stopped before the cancel: %v", err)
+	}
+
+	for ctx.Err() == nil {
+		select {
+		case err := <-errCh:
+			if err != nil {
+				t.Fatalf("want limiter to complete after cancel-after-fill, got %v", err)
+			}
+			if !l.PendingSize().IsZero() {
+				t.Fatalf("want zero pending size, got %s", l.PendingSize())
+			}
+			return
+		case product.tickerCh <- newTestTicker("115"):
+		}
+	}
+	t.Fatalf("limiter did not complete: %v", context.Cause(ctx))
+}
+
+func TestLimiterDefaultSizeLimit(t *testing.T) {
+	d := decimal.RequireFromString
+	l, err := New(uuid.NewString(), "coinbase", "BCH-USD", &point.Point{Size: d("2"), Price: d("100"), Cancel: d("110")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := l.sizeLimit(); !v.Equal(d("2")) {
+		t.Fatalf("want point size as the size-limit, got %s", v)
+	}
+
+	defer SetDefaultSizeLimit("BCH-USD", decimal.Zero)
+	if err := SetDefaultSizeLimit("BCH-USD", d("0.5")); err != nil {
+		t.Fatal(err)
+	}
+	if v := l.sizeLimit(); !v.Equal(d("0.5")) {
+		t.Fatalf("want product default size-limit 0.5, got %s", v)
+	}
+	if err := SetDefaultSizeLimit("BCH-USD", d("5")); err != nil {
+		t.Fatal(err)
+	}
+	if v := l.sizeLimit(); !v.Equal(d("2")) {
+		t.Fatalf("want default size-limit capped to the point size, got %s", v)
+	}
+
+	// Explicit option overrides the default.
+	if err := l.SetOption("size-limit", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if v := l.sizeLimit(); !v.Equal(d("1")) {
+		t.Fatalf("want explicit size-limit 1, got %s", v)
+	}
+	if err := SetDefaultSizeLimit("BCH-USD", d("-1")); err == nil {
+		t.Fatalf("want error for negative default size-limit")
+	}
+}
+
+func TestLimiterClientIDs(t *testing.T) {
+	d := decimal.RequireFromString
+	l, err := New(uuid.NewString(), "coinbase", "BTC-USD", &point.Point{Size: d("1"), Price: d("100"), Cancel: d("110")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cids []string
+	for i := 0; i < 3; i++ {
+		cids = append(cids, l.idgen.NextID().String())
+	}
+	order := newTestOrder("order-1", "1", "100", true)
+	order.ClientOrderID = cids[1]
+	l.orderMap.Store(order.OrderID, order)
+
+	ids := l.ClientIDs()
+	if len(ids) != 3 {
+		t.Fatalf("want 3 client ids, got %d", len(ids))
+	}
+	for i, id := range ids {
+		if id.Offset != uint64(i) || id.ClientOrderID != cids[i] {
+			t.Fatalf("id %d: want %d/%s, got %d/%s", i, i, cids[i], id.Offset, id.ClientOrderID)
+		}
+	}
+	if ids[0].ServerOrderID != "" || ids[1].ServerOrderID != "order-1" || ids[2].ServerOrderID != "" {
+		t.Fatalf("want server order id only for the offset 1, got %q, %q, %q", ids[0].ServerOrderID, ids[1].ServerOrderID, ids[2].ServerOrderID)
+	}
+}
+
+// deadProduct delivers the tickers from a channel and counts the ticker
+// subscriptions.
+type deadProduct struct {
+	*chanTickerProduct
+
+	nsubscribes atomic.Int32
+}
+
+func (p *deadProduct) TickerCh() (<-chan *exchange.Ticker, func()) {
+	p.nsubscribes.Add(1)
+	return p.chanTickerProduct.TickerCh()
+}
+
+func TestLimiterNoTickerTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	defer func(d time.Duration) { NoTickerCheckInterval = d }(NoTickerCheckInterval)
+	NoTickerCheckInterval = 10 * time.Millisecond
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetOption("no-ticker-timeout", "-1s"); err == nil {
+		t.Fatalf("negative no-ticker-timeout must fail")
+	}
+	if err := l.SetOption("no-ticker-timeout", "100ms"); err != nil {
+		t.Fatal(err)
+	}
+	product := &deadProduct{chanTickerProduct: newChanTickerProduct(nil)}
+	rt := &trader.Runtime{Database: kvmemdb.New(), Product: product}
+	errCh := runLimiter(ctx, t, l, rt)
+
+	product.tickerCh <- newTestTicker("105")
+	live := waitForLiveOrders(ctx, l, 1)
+	if len(live) != 1 {
+		t.Fatalf("want one live order, got %d", len(live))
+	}
+
+	// Active order must be canceled and tickers resubscribed after the timeout.
+	waitForLiveOrders(ctx, l, 0)
+	if n := product.nsubscribes.Load(); n < 2 {
+		t.Fatalf("want ticker resubscription after the timeout, got %d subscriptions", n)
+	}
+	order, err := product.Get(ctx, live[0].OrderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !order.Done {
+		t.Fatalf("active order must be canceled after the no-ticker-timeout")
+	}
+
+	// A new order must be created when tickers resume.
+	select {
+	case product.tickerCh <- newTestTicker("105"):
+	case err := <-errCh:
+		t.Fatalf("limiter has stopped while dormant: %v", err)
+	}
+	if live := waitForLiveOrders(ctx, l, 1); len(live) != 1 {
+		t.Fatalf("want one live order after tickers resume, got %d", len(live))
+	}
+}
+
+func TestLimiterRequireFavorableBook(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetOption("require-favorable-book", "yes"); err == nil {
+		t.Fatalf("non-boolean require-favorable-book value must fail")
+	}
+	if err := l.SetOption("require-favorable-book", "true"); err != nil {
+		t.Fatal(err)
+	}
+	product := newChanTickerProduct(nil)
+	rt := &trader.Runtime{Database: kvmemdb.New(), Product: product}
+	runLimiter(ctx, t, l, rt)
+
+	ticker := func(bidSize, askSize string) *exchange.Ticker {
+		return &exchange.Ticker{
+			Timestamp: exchange.RemoteTime{Time: time.Now()},
+			Price:     decimal.RequireFromString("105"),
+			BidSize:   decimal.RequireFromString(bidSize),
+			AskSize:   decimal.RequireFromString(askSize),
+		}
+	}
+
+	// More asks than bids is unfavorable for a buy.
+	product.tickerCh <- ticker("1", "5")
+	product.tickerCh <- ticker("1", "5")
+	if live := l.LiveOrders(); len(live) != 0 {
+		t.Fatalf("want no live orders with unfavorable book, got %d", len(live))
+	}
+
+	product.tickerCh <- ticker("5", "1")
+	if live := waitForLiveOrders(ctx, l, 1); len(live) != 1 {
+		t.Fatalf("want one live order with favorable book, got %d", len(live))
+	}
+}
+
+func TestLogThrottle(t *testing.T) {
+	var lt logThrottle
+	now := time.Now()
+	if ok, n := lt.allow("create", time.Minute, now); !ok || n != 0 {
+		t.Fatalf("first message must be allowed, got %t (%d suppressed)", ok, n)
+	}
+	for i := 1; i <= 3; i++ {
+		if ok, _ := lt.allow("create", time.Minute, now.Add(time.Duration(i)*time.Second)); ok {
+			t.Fatalf("message %d within the interval must be suppressed", i)
+		}
+	}
+	if ok, _ := lt.allow("cancel", time.Minute, now.Add(time.Second)); !ok {
+		t.Fatalf("messages of a different kind must not be suppressed")
+	}
+	if ok, n := lt.allow("create", time.Minute, now.Add(time.Minute)); !ok || n != 3 {
+		t.Fatalf("want message allowed after the interval with 3 suppressed, got %t (%d suppressed)", ok, n)
+	}
+}
+
+func TestExchangeConcurrency(t *testing.T) {
+	defer SetExchangeConcurrency(0)
+
+	if err := SetExchangeConcurrency(-1); err == nil {
+		t.Fatalf("negative exchange concurrency must fail")
+	}
+	if err := SetExchangeConcurrency(2); err != nil {
+		t.Fatal(err)
+	}
+	if n := ExchangeConcurrency(); n != 2 {
+		t.Fatalf("want exchange concurrency 2, got %d", n)
+	}
+
+	ctx := context.Background()
+	r1, err := acquireExchange(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := acquireExchange(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := acquireExchange(tctx); err == nil {
+		t.Fatalf("third acquire must block till a release")
+	}
+
+	r1()
+	r3, err := acquireExchange(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2()
+	r3()
+
+	// Unlimited gate never blocks.
+	if err := SetExchangeConcurrency(0); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := acquireExchange(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLimiterSubMinSize(t *testing.T) {
+	d := decimal.RequireFromString
+	newLimiter := func(t *testing.T, policy string) (*Limiter, *chanTickerProduct, *trader.Runtime) {
+		p := &point.Point{Size: d("1"), Price: d("100"), Cancel: d("110")}
+		l, err := New(uuid.NewString(), "paper", "BTC-USD", p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.SetOption("sub-min-size", policy); err != nil {
+			t.Fatal(err)
+		}
+		l.orderMap.Store("filled", newTestOrder("filled", "0.99", "100", true))
+
+		opts := &paper.Options{BaseMinSize: d("0.1"), BaseIncrement: d("0.01")}
+		product := newChanTickerProduct(opts)
+		return l, product, &trader.Runtime{Database: kvmemdb.New(), Product: product}
+	}
+
+	l, _, _ := newLimiter(t, "bump")
+	if err := l.SetOption("sub-min-size", "unknown"); err == nil {
+		t.Fatalf("invalid sub-min-size value must fail")
+	}
+
+	t.Run("complete", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		l, _, rt := newLimiter(t, SubMinSizeComplete)
+		if err := l.Run(ctx, rt); err != nil {
+			t.Fatalf("want limiter to complete, got %v", err)
+		}
+		if !l.IsForceCompleted() {
+			t.Fatalf("limiter must be marked complete")
+		}
+		if v := l.ResidualSize(); !v.Equal(d("0.01")) {
+			t.Fatalf("want residual size 0.01, got %s", v)
+		}
+		if v := l.PendingSize(); !v.IsZero() {
+			t.Fatalf("want zero pending size, got %s", v)
+		}
+	})
+
+	t.Run("bump", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		l, product, rt := newLimiter(t, SubMinSizeBump)
+		runLimiter(ctx, t, l, rt)
+
+		product.tickerCh <- newTestTicker("105")
+		live := waitForLiveOrders(ctx, l, 1)
+		if len(live) != 1 {
+			t.Fatalf("want one live order, got %d", len(live))
+		}
+		if size, ok := l.orderSizes.Load(live[0].OrderID); !ok || !size.Equal(d("0.1")) {
+			t.Fatalf("want order size bumped to the min size 0.1, got %s", size)
+		}
+		if l.IsForceCompleted() {
+			t.Fatalf("limiter must not be marked complete")
+		}
+	})
+}
+
+func TestLimiterDebounce(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetOption("debounce", "-1s"); err == nil {
+		t.Fatalf("negative debounce value must fail")
+	}
+	const debounce = 200 * time.Millisecond
+	if err := l.SetOption("debounce", debounce.String()); err != nil {
+		t.Fatal(err)
+	}
+	product := newChanTickerProduct(nil)
+	rt := &trader.Runtime{Database: kvmemdb.New(), Product: product}
+	runLimiter(ctx, t, l, rt)
+
+	// Tickers are sent with explicit timestamps, which are used to measure the
+	// debounce duration. Sending a ticker also waits for the previous ticker to
+	// be processed, because the ticker channel is unbuffered.
+	base := time.Now()
+	tick := func(price string, at time.Duration) {
+		product.tickerCh <- &exchange.Ticker{
+			Timestamp: exchange.RemoteTime{Time: base.Add(at)},
+			Price:     decimal.RequireFromString(price),
+		}
+	}
+	checkLive := func(n int, msg string) {
+		t.Helper()
+		if live := l.LiveOrders(); len(live) != n {
+			t.Fatalf("%s: want %d live orders, got %d", msg, n, len(live))
+		}
+	}
+
+	// Spike into the create side that reverts within the debounce duration
+	// must not create an order.
+	tick("105", 0)
+	tick("115", debounce/2)
+	tick("105", time.Second)
+	tick("105", time.Second+debounce/2)
+	tick("115", time.Second+debounce)
+	tick("115", 2*time.Second)
+	checkLive(0, "reverted create spike")
+
+	// Hold option drops the pending create. Options are set between two
+	// tickers that are not actionable either way.
+	tick("105", 3*time.Second)
+	tick("105", 3*time.Second+debounce/2)
+	if err := l.SetOption("hold", "true"); err != nil {
+		t.Fatal(err)
+	}
+	tick("105", 3*time.Second+debounce)
+	tick("105", 3*time.Second+debounce+debounce/2)
+	if err := l.SetOption("hold", "false"); err != nil {
+		t.Fatal(err)
+	}
+	tick("105", 3*time.Second+2*debounce)
+	tick("105", 3*time.Second+2*debounce+debounce/2)
+	checkLive(0, "pending create under hold")
+
+	// Order is created on the first ticker after the price holds.
+	tick("105", 3*time.Second+3*debounce)
+	waitForLiveOrders(ctx, l, 1)
+	checkLive(1, "create after the debounce")
+
+	// Spike into the cancel side that reverts within the debounce duration
+	// must not cancel the active order.
+	tick("115", 4*time.Second)
+	tick("105", 4*time.Second+debounce)
+	tick("115", 5*time.Second)
+	checkLive(1, "reverted cancel spike")
+
+	tick("115", 5*time.Second+debounce/2)
+	tick("115", 5*time.Second+debounce)
+	waitForLiveOrders(ctx, l, 0)
+	checkLive(0, "cancel after the debounce")
+}
+
+// getOrderExchange is an exchange that only serves GetOrder requests, which
+// return the order without a finish time or liquidity.
+type getOrderExchange struct {
+	exchange.Exchange
+
+	ngets atomic.Int32
+}
+
+func (ex *getOrderExchange) ExchangeName() string { return "coinbase" }
+
+func (ex *getOrderExchange) GetOrder(ctx context.Context, id exchange.OrderID) (*exchange.Order, error) {
+	ex.ngets.Add(1)
+	return &exchange.Order{OrderID: id, Done: true}, nil
+}
+
+func TestFinishTimeAttempted(t *testing.T) {
+	ctx := context.Background()
+	ex := new(getOrderExchange)
+
+	p := &point.Point{Size: decimal.RequireFromString("1"), Price: decimal.RequireFromString("100"), Cancel: decimal.RequireFromString("110")}
+	l, err := New(uuid.NewString(), "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	active := newTestOrder(uuid.NewString(), "1", "100", true)
+	active.FinishTime = exchange.RemoteTime{}
+	l.orderMap.Store(active.OrderID, active)
+	for i := 0; i < 2; i++ {
+		if err := updateActiveLimiter(ctx, ex, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := ex.ngets.Load(); n != 1 {
+		t.Fatalf("want order fetched once for the missing finish time, got %d", n)
+	}
+
+	db := kvmemdb.New()
+	saved := newTestOrder(uuid.NewString(), "1", "100", true)
+	saved.FinishTime = exchange.RemoteTime{}
+	l.orderMap.Store(saved.OrderID, saved)
+	if err := kv.WithReadWriter(ctx, db, l.Save); err != nil {
+		t.Fatal(err)
+	}
+	key := path.Join(DefaultKeyspace, l.uid)
+	for i := 0; i < 2; i++ {
+		update := func(ctx context.Context, rw kv.ReadWriter) error {
+			return updateFinishTime(ctx, rw, ex, key, nil)
+		}
+		if err := kv.WithReadWriter(ctx, db, update); err != nil {
+			t.Fatalf("want no error for an order without finish time, got %v", err)
+		}
+	}
+	if n := ex.ngets.Load(); n != 2 {
+		t.Fatalf("want saved order fetched once for the missing finish time, got %d", n-1)
+	}
+}
+
+func TestLimiterSyncIDGen(t *testing.T) {
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := l.idgen.Offset()
+
+	// Ids of the orders in any status (eg: canceled orders without fills) on
+	// the exchange must advance the offset.
+	canceled := l.idgen.IDAt(old + 4).String()
+	if o, n := l.SyncIDGen([]string{uuid.NewString(), canceled}, 100); o != old || n != old+5 {
+		t.Fatalf("want offset advanced from %d to %d, got %d to %d", old, old+5, o, n)
+	}
+
+	// Offset must never move back.
+	if o, n := l.SyncIDGen([]string{l.idgen.IDAt(old).String()}, 100); o != n || n != old+5 {
+		t.Fatalf("want offset %d to be unchanged, got %d to %d", old+5, o, n)
+	}
+}
+
+// reconnectProduct is a paper product with reconnect notifications from a
+// channel and order fetches that can be made to fail.
+type reconnectProduct struct {
+	*chanTickerProduct
+
+	reconnectCh chan time.Time
+	failGets    atomic.Bool
+}
+
+func (p *reconnectProduct) ReconnectCh() (<-chan time.Time, func()) {
+	return p.reconnectCh, func() {}
+}
+
+func (p *reconnectProduct) Get(ctx context.Context, id exchange.OrderID) (*exchange.Order, error) {
+	if p.failGets.Load() {
+		return nil, fmt.Errorf("injected get failure for order %s", id)
+	}
+	return p.chanTickerProduct.Get(ctx, id)
+}
+
+func TestLimiterReconnectResyncFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := New(uuid.NewString(), "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	product := &reconnectProduct{
+		chanTickerProduct: newChanTickerProduct(nil),
+		reconnectCh:       make(chan time.Time),
+	}
+	rt := &trader.Runtime{Database: kvmemdb.New(), Product: product}
+	errCh := runLimiter(ctx, t, l, rt)
+
+	product.tickerCh <- newTestTicker("105")
+	if live := waitForLiveOrders(ctx, l, 1); len(live) != 1 {
+		t.Fatalf("want one live order, got %d", len(live))
+	}
+
+	// Resync failures after the reconnects must not stop the limiter. Channel is
+	// unbuffered, so every send is received by the limiter's run loop.
+	product.failGets.Store(true)
+	for i := 0; i < 2; i++ {
+		select {
+		case product.reconnectCh <- time.Now():
+		case err := <-errCh:
+			t.Fatalf("want limiter running after a failed resync, got %v", err)
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		}
+	}
+
+	product.failGets.Store(false)
+	product.reconnectCh <- time.Now()
+	product.tickerCh <- newTestTicker("105")
+	select {
+	case err := <-errCh:
+		t.Fatalf("want limiter running after the resync, got %v", err)
+	default:
+	}
+	if live := l.LiveOrders(); len(live) != 1 {
+		t.Fatalf("want the live order to be kept, got %d", len(live))
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bvk/tradebot/exchange"
+	"github.com/shopspring/decimal"
+)
+
+// LiveOrder describes an order of a limiter that is active on the exchange.
+type LiveOrder struct {
+	// UID is the uid of the limiter that owns the order.
+	UID string
+
+	OrderID    exchange.OrderID
+	Side       string
+	Price      decimal.Decimal
+	CreateTime time.Time
+
+	// Size is the unfilled size of the order, which is estimated from the
+	// limiter's pending size and the size-limit option.
+	Size decimal.Decimal
+}
+
+// LiveOrders returns the orders that are not complete as per the limiter's
+// latest order states, sorted by the create time.
+func (v *Limiter) LiveOrders() []*LiveOrder {
+	size := v.PendingSize()
+	if s := v.sizeLimit(); size.GreaterThan(s) {
+		size = s
+	}
+
+	var orders []*LiveOrder
+	for id, order := range v.dupOrderMap() {
+		if order.Done {
+			continue
+		}
+		orders = append(orders, &LiveOrder{
+			UID:        v.uid,
+			OrderID:    id,
+			Side:       v.point.Side(),
+			Price:      v.point.Price,
+			CreateTime: order.CreateTime.Time,
+			Size:       size,
+		})
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreateTime.Before(orders[j].CreateTime)
+	})
+	return orders
+}
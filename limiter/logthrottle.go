@@ -0,0 +1,78 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// logThrottle rate-limits the log messages of each kind to at most one per
+// interval and counts the suppressed messages, which are reported along with
+// the next message of the same kind.
+type logThrottle struct {
+	mu sync.Mutex
+
+	lastMap       map[string]time.Time
+	suppressedMap map[string]int
+}
+
+// allow returns true if a message of the kind can be logged at the current
+// time, along with the number of messages of the kind suppressed since the
+// last logged message.
+func (t *logThrottle) allow(kind string, interval time.Duration, now time.Time) (bool, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.lastMap == nil {
+		t.lastMap = make(map[string]time.Time)
+		t.suppressedMap = make(map[string]int)
+	}
+	if last, ok := t.lastMap[kind]; ok && now.Sub(last) < interval {
+		t.suppressedMap[kind]++
+		return false, 0
+	}
+	n := t.suppressedMap[kind]
+	t.lastMap[kind] = now
+	delete(t.suppressedMap, kind)
+	return true, n
+}
+
+func (v *Limiter) logThrottleInterval() time.Duration {
+	return time.Duration(v.logThrottleOpt.Load())
+}
+
+func (v *Limiter) setLogThrottleOption(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return fmt.Errorf("log-throttle value cannot be -ve")
+	}
+	v.logThrottleOpt.Store(int64(d))
+	return nil
+}
+
+// throttledLogf logs the high-frequency messages, like the order creates and
+// cancels, at most once per log-throttle option interval for each message
+// kind. Messages are never throttled when the option is zero. Important
+// one-time events, like the fills and errors, must use log.Printf directly.
+func (v *Limiter) throttledLogf(kind string, format string, args ...interface{}) {
+	d := v.logThrottleInterval()
+	if d <= 0 {
+		log.Printf(format, args...)
+		return
+	}
+	ok, suppressed := v.logThrottle.allow(kind, d, time.Now())
+	if !ok {
+		return
+	}
+	if suppressed > 0 {
+		log.Printf(format+" (%d similar %s messages were suppressed)", append(args, suppressed, kind)...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"fmt"
+
+	"github.com/bvk/tradebot/exchange"
+)
+
+// Merge creates a new limiter with the input uid that combines the orders of
+// two limiters with the same product and price point. Size of the new limiter
+// is the sum of the input limiter sizes. Limiters with live orders or with
+// conflicting orders are not merged.
+func Merge(uid string, a, b *Limiter) (*Limiter, error) {
+	if err := checkUID(uid); err != nil {
+		return nil, err
+	}
+	if a.uid == b.uid {
+		return nil, fmt.Errorf("cannot merge limiter %s with itself", a.uid)
+	}
+	if a.exchangeName != b.exchangeName || a.productID != b.productID {
+		return nil, fmt.Errorf("limiters are on different products (%s/%s vs %s/%s)", a.exchangeName, a.productID, b.exchangeName, b.productID)
+	}
+	if a.point.Side() != b.point.Side() {
+		return nil, fmt.Errorf("limiters are on different sides (%s vs %s)", a.point.Side(), b.point.Side())
+	}
+	if !a.point.Price.Equal(b.point.Price) || !a.point.Cancel.Equal(b.point.Cancel) {
+		return nil, fmt.Errorf("limiters have different price points (%s vs %s)", a.point, b.point)
+	}
+	if a.point.SizeInQuote != b.point.SizeInQuote {
+		return nil, fmt.Errorf("limiters have different size units")
+	}
+	for _, v := range []*Limiter{a, b} {
+		if v.IsForceCompleted() {
+			return nil, fmt.Errorf("limiter %s is force-completed", v.uid)
+		}
+		if n := v.archiveTotals().count; n > 0 {
+			return nil, fmt.Errorf("limiter %s has %d archived orders", v.uid, n)
+		}
+	}
+
+	orders := make(map[exchange.OrderID]*exchange.Order)
+	for _, v := range []*Limiter{a, b} {
+		for id, order := range v.dupOrderMap() {
+			if !order.Done {
+				return nil, fmt.Errorf("limiter %s has a live order %s", v.uid, id)
+			}
+			if old, ok := orders[id]; ok {
+				if !exchange.Equal(old, order) {
+					return nil, fmt.Errorf("order %s has conflicting states in the limiters", id)
+				}
+				continue
+			}
+			orders[id] = order
+		}
+	}
+	if err := checkOrderMap(orders); err != nil {
+		return nil, err
+	}
+
+	point := a.point
+	point.Size = a.point.Size.Add(b.point.Size)
+	v, err := New(uid, a.exchangeName, a.productID, &point)
+	if err != nil {
+		return nil, err
+	}
+	for id, order := range orders {
+		v.orderMap.Store(id, order)
+	}
+	return v, nil
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvkgo/kv"
+)
+
+// Move renames a saved limiter from one uid to another along with it's client
+// id offset, archived orders and audit trail entries. Client id seed is pinned
+// to the old value, so that the moved limiter keeps generating the same client
+// ids. Limiter must not be running while it is moved.
+func Move(ctx context.Context, rw kv.ReadWriter, from, to string) error {
+	if err := checkUID(from); err != nil {
+		return err
+	}
+	if err := checkUID(to); err != nil {
+		return err
+	}
+	fromKey, toKey := path.Join(DefaultKeyspace, from), path.Join(DefaultKeyspace, to)
+	if _, err := rw.Get(ctx, toKey); err == nil {
+		return fmt.Errorf("limiter %s already exists: %w", to, os.ErrExist)
+	}
+
+	gv, err := kvutil.Get[gobs.LimiterState](ctx, rw, fromKey)
+	if err != nil {
+		return fmt.Errorf("could not load limiter state: %w", err)
+	}
+	if len(gv.V2.ClientIDSeed) == 0 {
+		gv.V2.ClientIDSeed = from
+	}
+	offset, err := loadOffset(ctx, rw, from)
+	if err != nil {
+		return err
+	}
+	if offset > gv.V2.ClientIDOffset {
+		gv.V2.ClientIDOffset = offset
+	}
+	if err := kvutil.Set(ctx, rw, toKey, gv); err != nil {
+		return fmt.Errorf("could not save moved limiter state: %w", err)
+	}
+	if err := rw.Delete(ctx, fromKey); err != nil {
+		return fmt.Errorf("could not delete old limiter state: %w", err)
+	}
+	if err := rw.Delete(ctx, path.Join(OffsetKeyspace, from)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not delete old limiter client id offset: %w", err)
+	}
+
+	for _, keyspace := range []string{ArchiveKeyspace, TrailKeyspace} {
+		if err := moveKeys(ctx, rw, path.Join(keyspace, from), path.Join(keyspace, to)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveKeys renames the keys directly under the from directory to the to
+// directory. Keys of the child limiters, if any, are not moved.
+func moveKeys(ctx context.Context, rw kv.ReadWriter, from, to string) error {
+	values := make(map[string][]byte)
+	begin, end := kvutil.PathRange(from)
+	it, err := rw.Ascend(ctx, begin, end)
+	if err != nil {
+		return fmt.Errorf("could not scan keys under %s: %w", from, err)
+	}
+	for k, v, err := it.Fetch(ctx, false); err == nil; k, v, err = it.Fetch(ctx, true) {
+		if path.Dir(k) != from {
+			continue
+		}
+		data, err := io.ReadAll(v)
+		if err != nil {
+			kv.Close(it)
+			return fmt.Errorf("could not read value at key %s: %w", k, err)
+		}
+		values[k] = data
+	}
+	_, _, err = it.Fetch(ctx, false)
+	kv.Close(it)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("could not complete scan under %s: %w", from, err)
+	}
+
+	for key, data := range values {
+		nkey := path.Join(to, path.Base(key))
+		if err := rw.Set(ctx, nkey, bytes.NewReader(data)); err != nil {
+			return fmt.Errorf("could not save moved key %s: %w", nkey, err)
+		}
+		if err := rw.Delete(ctx, key); err != nil {
+			return fmt.Errorf("could not delete old key %s: %w", key, err)
+		}
+	}
+	return nil
+}
@@ -4,16 +4,46 @@ package limiter
 
 import (
 	"fmt"
+	"hash/fnv"
+	"io"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/syncmap"
 	"github.com/shopspring/decimal"
 )
 
 func (v *Limiter) SetOption(key, value string) error {
 	optMap := map[string]func(string) error{
-		"hold":                 v.setHoldOption,
-		"size-limit":           v.setSizeLimitOption,
-		"wait-for-ticker-side": v.setWaitForTickerSideOption,
+		"hold":                  v.setHoldOption,
+		"size-limit":            v.setSizeLimitOption,
+		"wait-for-ticker-side":  v.setWaitForTickerSideOption,
+		"readiness-band":        v.setReadinessBandOption,
+		"good-till":             v.setGoodTillOption,
+		"fetch-concurrency":     v.setFetchConcurrencyOption,
+		"min-action-interval":   v.setMinActionIntervalOption,
+		"market-fill-after":     v.setMarketFillAfterOption,
+		"flush-interval":        v.setFlushIntervalOption,
+		"stagger-flush":         v.setStaggerFlushOption,
+		"one-shot":              v.setOneShotOption,
+		"poll-interval":         v.setPollIntervalOption,
+		"audit-trail":           v.setAuditTrailOption,
+		"trigger-price":         v.setTriggerPriceOption,
+		"archive-orders":        v.setArchiveOrdersOption,
+		"completion-grace":      v.setCompletionGraceOption,
+		"reduce-on-size-change": v.setReduceOption,
+		"max-price-age":         v.setMaxPriceAgeOption,
+		"max-fee-pct":           v.setMaxFeePctOption,
+		"no-ticker-timeout":     v.setNoTickerTimeoutOption,
+		"log-throttle":          v.setLogThrottleOption,
+		"sub-min-size":          v.setSubMinSizeOption,
+		"debounce":              v.setDebounceOption,
+
+		"require-favorable-book": v.setFavorableBookOption,
+
+		"fail-on-missing-orders": v.setFailOnMissingOrdersOption,
 	}
 	handler, ok := optMap[key]
 	if !ok {
@@ -27,6 +57,50 @@ func (v *Limiter) SetOption(key, value string) error {
 	return nil
 }
 
+func (v *Limiter) Config() map[string]string {
+	return map[string]string{
+		"hold":                  strconv.FormatBool(v.holdOpt.Load()),
+		"size-limit":            v.sizeLimit().String(),
+		"wait-for-ticker-side":  strconv.FormatBool(v.waitForTickerSideOpt.Load()),
+		"readiness-band":        v.ReadinessBand().String(),
+		"good-till":             v.goodTill().String(),
+		"fetch-concurrency":     strconv.Itoa(v.fetchConcurrency()),
+		"min-action-interval":   time.Duration(v.minActionIntervalOpt.Load()).String(),
+		"market-fill-after":     v.MarketFillAfter().String(),
+		"flush-interval":        v.flushInterval().String(),
+		"stagger-flush":         strconv.FormatBool(v.staggerFlushOpt.Load()),
+		"one-shot":              strconv.FormatBool(v.oneShotOpt.Load()),
+		"poll-interval":         v.pollInterval().String(),
+		"audit-trail":           strconv.FormatBool(v.auditTrailOpt.Load()),
+		"trigger-price":         v.TriggerPrice().String(),
+		"archive-orders":        strconv.FormatBool(v.archiveOrdersOpt.Load()),
+		"completion-grace":      v.completionGrace().String(),
+		"reduce-on-size-change": strconv.FormatBool(v.reduceOpt.Load()),
+		"max-price-age":         v.maxPriceAge().String(),
+		"max-fee-pct":           v.MaxFeePct().String(),
+		"no-ticker-timeout":     v.noTickerTimeout().String(),
+		"log-throttle":          v.logThrottleInterval().String(),
+		"sub-min-size":          v.subMinSizePolicy(),
+		"debounce":              v.debounce().String(),
+
+		"require-favorable-book": strconv.FormatBool(v.favorableBookOpt.Load()),
+
+		"fail-on-missing-orders": strconv.FormatBool(v.failOnMissingOrdersOpt.Load()),
+	}
+}
+
+// Hold sets or clears the hold option. Held limiter cancels it's active order
+// and doesn't create a new order until it is released, without stopping the
+// job.
+func (v *Limiter) Hold(hold bool) error {
+	return v.SetOption("hold", strconv.FormatBool(hold))
+}
+
+// IsHeld returns true if the hold option is set.
+func (v *Limiter) IsHeld() bool {
+	return v.holdOpt.Load()
+}
+
 func (v *Limiter) setHoldOption(arg string) error {
 	arg = strings.ToLower(arg)
 	if arg == "true" {
@@ -40,11 +114,41 @@ func (v *Limiter) setHoldOption(arg string) error {
 	return fmt.Errorf(`%v: hold option only takes a "true" or "false" value`, v.uid)
 }
 
+// defaultSizeLimitMap holds the default size-limit for each product, which is
+// used by the limiters that don't have an explicit size-limit option.
+var defaultSizeLimitMap syncmap.Map[string, decimal.Decimal]
+
+// SetDefaultSizeLimit sets the default size-limit for the limiters on the
+// product. A zero size removes the default.
+func SetDefaultSizeLimit(productID string, size decimal.Decimal) error {
+	if size.IsNegative() {
+		return fmt.Errorf("default size limit for product %q cannot be -ve", productID)
+	}
+	if size.IsZero() {
+		defaultSizeLimitMap.Delete(productID)
+		return nil
+	}
+	defaultSizeLimitMap.Store(productID, size)
+	return nil
+}
+
+// DefaultSizeLimit returns the default size-limit for the product, if any.
+func DefaultSizeLimit(productID string) (decimal.Decimal, bool) {
+	return defaultSizeLimitMap.Load(productID)
+}
+
+// sizeLimit returns the size-limit option value when it is set. Otherwise,
+// returns the product's default size-limit (capped to the point size) or the
+// point size when there is no default.
 func (v *Limiter) sizeLimit() decimal.Decimal {
 	if p := v.sizeLimitOpt.Load(); p != nil {
 		return p.Copy()
 	}
-	return v.point.Size
+	size := v.point.BaseSize()
+	if d, ok := DefaultSizeLimit(v.productID); ok {
+		return decimal.Min(d, size)
+	}
+	return size
 }
 
 func (v *Limiter) setSizeLimitOption(value string) error {
@@ -55,7 +159,8 @@ func (v *Limiter) setSizeLimitOption(value string) error {
 	if size.IsNegative() {
 		return fmt.Errorf("size limit value cannot be -ve")
 	}
-	if size.GreaterThan(v.point.Size) {
+	// Size limit is in base units even when the point size is in quote units.
+	if size.GreaterThan(v.point.BaseSize()) {
 		return fmt.Errorf("size limit value cannot be more than total size")
 	}
 	v.sizeLimitOpt.Store(&size)
@@ -75,9 +180,462 @@ func (v *Limiter) setWaitForTickerSideOption(value string) error {
 	return fmt.Errorf(`%v: wait-for-ticker-side option only takes a "true" or "false" value`, v.uid)
 }
 
+// ReadinessBand returns the readiness-band option value, which is zero by
+// default.
+func (v *Limiter) ReadinessBand() decimal.Decimal {
+	if p := v.readinessBandOpt.Load(); p != nil {
+		return p.Copy()
+	}
+	return decimal.Zero
+}
+
+func (v *Limiter) setReadinessBandOption(value string) error {
+	band, err := decimal.NewFromString(value)
+	if err != nil {
+		return err
+	}
+	if band.IsNegative() {
+		return fmt.Errorf("readiness band value cannot be -ve")
+	}
+	v.readinessBandOpt.Store(&band)
+	return nil
+}
+
+func (v *Limiter) goodTill() time.Duration {
+	return time.Duration(v.goodTillOpt.Load())
+}
+
+func (v *Limiter) setGoodTillOption(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return fmt.Errorf("good-till value cannot be -ve")
+	}
+	if d != 0 && d < time.Minute {
+		return fmt.Errorf("good-till value must be at least a minute")
+	}
+	v.goodTillOpt.Store(int64(d))
+	return nil
+}
+
+// DefaultFetchConcurrency is the default number of parallel requests to fetch
+// the live orders from the exchange.
+const DefaultFetchConcurrency = 4
+
+func (v *Limiter) fetchConcurrency() int {
+	if n := v.fetchConcurrencyOpt.Load(); n > 0 {
+		return int(n)
+	}
+	return DefaultFetchConcurrency
+}
+
+func (v *Limiter) setFetchConcurrencyOption(value string) error {
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return err
+	}
+	if n < 1 {
+		return fmt.Errorf("fetch-concurrency value must be at least one")
+	}
+	v.fetchConcurrencyOpt.Store(int32(n))
+	return nil
+}
+
+func (v *Limiter) setMinActionIntervalOption(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return fmt.Errorf("min-action-interval value cannot be -ve")
+	}
+	v.minActionIntervalOpt.Store(int64(d))
+	return nil
+}
+
+// isActionAllowed returns true if min-action-interval duration has passed
+// since the last order create or cancel action.
+func (v *Limiter) isActionAllowed(now time.Time) bool {
+	interval := time.Duration(v.minActionIntervalOpt.Load())
+	if interval == 0 {
+		return true
+	}
+	last := v.lastActionTime.Load()
+	return last == 0 || now.Sub(time.Unix(0, last)) >= interval
+}
+
+// MarketFillAfter returns the market-fill-after option value, which is zero
+// (disabled) by default.
+func (v *Limiter) MarketFillAfter() time.Duration {
+	return time.Duration(v.marketFillAfterOpt.Load())
+}
+
+func (v *Limiter) setMarketFillAfterOption(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return fmt.Errorf("market-fill-after value cannot be -ve")
+	}
+	v.marketFillAfterOpt.Store(int64(d))
+	return nil
+}
+
+// isMarketFillDue returns true if market-fill-after duration has passed since
+// the first limit order was created.
+func (v *Limiter) isMarketFillDue(now time.Time) bool {
+	d := v.MarketFillAfter()
+	if d == 0 {
+		return false
+	}
+	anchor := v.marketFillAnchor.Load()
+	return anchor != 0 && now.Sub(time.Unix(0, anchor)) >= d
+}
+
+func (v *Limiter) setOneShotOption(value string) error {
+	arg := strings.ToLower(value)
+	if arg == "true" {
+		v.oneShotOpt.Store(true)
+		return nil
+	}
+	if arg == "false" {
+		v.oneShotOpt.Store(false)
+		return nil
+	}
+	return fmt.Errorf(`%v: one-shot option only takes a "true" or "false" value`, v.uid)
+}
+
+func (v *Limiter) setFailOnMissingOrdersOption(value string) error {
+	arg := strings.ToLower(value)
+	if arg == "true" {
+		v.failOnMissingOrdersOpt.Store(true)
+		return nil
+	}
+	if arg == "false" {
+		v.failOnMissingOrdersOpt.Store(false)
+		return nil
+	}
+	return fmt.Errorf(`%v: fail-on-missing-orders option only takes a "true" or "false" value`, v.uid)
+}
+
+// IsOneShotCanceled returns true if the limiter's order was canceled by the
+// cancel threshold with one-shot option, so no more orders will be created.
+func (v *Limiter) IsOneShotCanceled() bool {
+	return v.oneShotCanceled.Load()
+}
+
+// DefaultFlushInterval is the default interval between saves of the dirty
+// limiter state.
+const DefaultFlushInterval = time.Minute
+
+func (v *Limiter) flushInterval() time.Duration {
+	if d := v.flushIntervalOpt.Load(); d > 0 {
+		return time.Duration(d)
+	}
+	return DefaultFlushInterval
+}
+
+func (v *Limiter) setFlushIntervalOption(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return fmt.Errorf("flush-interval value cannot be -ve")
+	}
+	if d != 0 && d < time.Second {
+		return fmt.Errorf("flush-interval value must be at least a second")
+	}
+	v.flushIntervalOpt.Store(int64(d))
+	return nil
+}
+
+// DefaultPollInterval is the default interval between the order state fetches
+// for the active order. It is a safety net for the missed order updates.
+const DefaultPollInterval = 5 * time.Minute
+
+func (v *Limiter) pollInterval() time.Duration {
+	if d := v.pollIntervalOpt.Load(); d > 0 {
+		return time.Duration(d)
+	}
+	return DefaultPollInterval
+}
+
+func (v *Limiter) setPollIntervalOption(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return fmt.Errorf("poll-interval value cannot be -ve")
+	}
+	if d != 0 && d < time.Second {
+		return fmt.Errorf("poll-interval value must be at least a second")
+	}
+	v.pollIntervalOpt.Store(int64(d))
+	return nil
+}
+
+func (v *Limiter) completionGrace() time.Duration {
+	return time.Duration(v.completionGraceOpt.Load())
+}
+
+func (v *Limiter) setCompletionGraceOption(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return fmt.Errorf("completion-grace value cannot be -ve")
+	}
+	v.completionGraceOpt.Store(int64(d))
+	return nil
+}
+
+func (v *Limiter) maxPriceAge() time.Duration {
+	return time.Duration(v.maxPriceAgeOpt.Load())
+}
+
+func (v *Limiter) setMaxPriceAgeOption(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return fmt.Errorf("max-price-age value cannot be -ve")
+	}
+	v.maxPriceAgeOpt.Store(int64(d))
+	return nil
+}
+
+// isStaleTicker returns true if the ticker is older than the max-price-age
+// option at the current time.
+func (v *Limiter) isStaleTicker(ticker *exchange.Ticker, now time.Time) bool {
+	d := v.maxPriceAge()
+	return d > 0 && now.Sub(ticker.Timestamp.Time) > d
+}
+
+// Policies for the sub-min-size option, which selects the handling of a
+// pending size that is below the product's min size.
+const (
+	// SubMinSizeBump creates the order with the product's min size, which may
+	// overfill the limiter.
+	SubMinSizeBump = "bump"
+
+	// SubMinSizeComplete marks the limiter as complete with the pending size
+	// left unfilled as residual.
+	SubMinSizeComplete = "complete"
+)
+
+func (v *Limiter) subMinSizePolicy() string {
+	if v.completeSubMinOpt.Load() {
+		return SubMinSizeComplete
+	}
+	return SubMinSizeBump
+}
+
+func (v *Limiter) setSubMinSizeOption(value string) error {
+	switch strings.ToLower(value) {
+	case SubMinSizeBump:
+		v.completeSubMinOpt.Store(false)
+	case SubMinSizeComplete:
+		v.completeSubMinOpt.Store(true)
+	default:
+		return fmt.Errorf("%v: sub-min-size option only takes a %q or %q value", v.uid, SubMinSizeBump, SubMinSizeComplete)
+	}
+	return nil
+}
+
+// isSubMinSize returns true if the pending size is non-zero, but is below the
+// product's min size after rounding down to the base increment, so that an
+// order for the pending size cannot be created.
+func (v *Limiter) isSubMinSize(product exchange.Product) bool {
+	pending := v.PendingSize()
+	if pending.IsZero() {
+		return false
+	}
+	return roundDown(pending, product.BaseIncrement()).LessThan(product.BaseMinSize())
+}
+
+// NoTickerCheckInterval is the max interval between the checks for the
+// no-ticker-timeout option.
+var NoTickerCheckInterval = time.Minute
+
+func (v *Limiter) noTickerTimeout() time.Duration {
+	return time.Duration(v.noTickerTimeoutOpt.Load())
+}
+
+func (v *Limiter) setNoTickerTimeoutOption(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return fmt.Errorf("no-ticker-timeout value cannot be -ve")
+	}
+	v.noTickerTimeoutOpt.Store(int64(d))
+	return nil
+}
+
+// noTickerCheckInterval returns the time until the next no-ticker-timeout
+// check, which is frequent enough to honor the option value.
+func (v *Limiter) noTickerCheckInterval() time.Duration {
+	if d := v.noTickerTimeout(); d > 0 {
+		return min(d, NoTickerCheckInterval)
+	}
+	return NoTickerCheckInterval
+}
+
+// isTickerTimedOut returns true if no tickers are received since the last
+// time for longer than the no-ticker-timeout option at the current time.
+func (v *Limiter) isTickerTimedOut(last, now time.Time) bool {
+	d := v.noTickerTimeout()
+	return d > 0 && now.Sub(last) >= d
+}
+
+// MaxFeePct returns the max-fee-pct option value, which is zero (disabled) by
+// default.
+func (v *Limiter) MaxFeePct() decimal.Decimal {
+	if p := v.maxFeePctOpt.Load(); p != nil {
+		return p.Copy()
+	}
+	return decimal.Zero
+}
+
+func (v *Limiter) setMaxFeePctOption(value string) error {
+	pct, err := decimal.NewFromString(value)
+	if err != nil {
+		return err
+	}
+	if pct.IsNegative() {
+		return fmt.Errorf("max-fee-pct value cannot be -ve")
+	}
+	if pct.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+		return fmt.Errorf("max-fee-pct value must be less than 100")
+	}
+	v.maxFeePctOpt.Store(&pct)
+	return nil
+}
+
+// checkMaxFee returns an error wrapping ErrFeeTooHigh if the estimated fee for
+// an order of the input size at the limit price is more than the max-fee-pct
+// option of the order's notional value.
+func (v *Limiter) checkMaxFee(size decimal.Decimal) error {
+	maxPct := v.MaxFeePct()
+	if maxPct.IsZero() {
+		return nil
+	}
+	value := size.Mul(v.point.Price)
+	fee := estimatedFee(value)
+	if value.IsZero() || fee.Mul(decimal.NewFromInt(100)).GreaterThan(value.Mul(maxPct)) {
+		return fmt.Errorf("estimated fee %s for order value %s is more than %s%%: %w", fee, value.StringFixed(3), maxPct, ErrFeeTooHigh)
+	}
+	return nil
+}
+
+func (v *Limiter) setStaggerFlushOption(value string) error {
+	arg := strings.ToLower(value)
+	if arg == "true" {
+		v.staggerFlushOpt.Store(true)
+		return nil
+	}
+	if arg == "false" {
+		v.staggerFlushOpt.Store(false)
+		return nil
+	}
+	return fmt.Errorf(`%v: stagger-flush option only takes a "true" or "false" value`, v.uid)
+}
+
+// TriggerPrice returns the trigger-price option value, which is zero when the
+// orders are regular limit orders.
+func (v *Limiter) TriggerPrice() decimal.Decimal {
+	if p := v.triggerPriceOpt.Load(); p != nil {
+		return p.Copy()
+	}
+	return decimal.Zero
+}
+
+func (v *Limiter) setTriggerPriceOption(value string) error {
+	price, err := decimal.NewFromString(value)
+	if err != nil {
+		return err
+	}
+	if price.IsNegative() {
+		return fmt.Errorf("trigger-price value cannot be -ve")
+	}
+	// Order would be canceled before it is triggered if the trigger is beyond
+	// the cancel price.
+	if price.IsPositive() {
+		if v.IsBuy() && price.GreaterThanOrEqual(v.point.Cancel) {
+			return fmt.Errorf("trigger-price %s must be below the cancel price %s for buys", price, v.point.Cancel)
+		}
+		if v.IsSell() && price.LessThanOrEqual(v.point.Cancel) {
+			return fmt.Errorf("trigger-price %s must be above the cancel price %s for sells", price, v.point.Cancel)
+		}
+	}
+	v.triggerPriceOpt.Store(&price)
+	return nil
+}
+
+func (v *Limiter) setAuditTrailOption(value string) error {
+	arg := strings.ToLower(value)
+	if arg == "true" {
+		v.auditTrailOpt.Store(true)
+		return nil
+	}
+	if arg == "false" {
+		v.auditTrailOpt.Store(false)
+		return nil
+	}
+	return fmt.Errorf(`%v: audit-trail option only takes a "true" or "false" value`, v.uid)
+}
+
+// firstFlushDelay returns the delay for the first flush, which is offset by a
+// deterministic fraction of the flush interval derived from the uid when
+// stagger-flush option is set.
+func (v *Limiter) firstFlushDelay() time.Duration {
+	interval := v.flushInterval()
+	if !v.staggerFlushOpt.Load() {
+		return interval
+	}
+	h := fnv.New64a()
+	io.WriteString(h, v.uid)
+	return interval + time.Duration(h.Sum64()%uint64(interval))
+}
+
+// shouldCancel returns true if the ticker price has crossed the cancel
+// threshold, i.e., at or below the cancel price for sells and at or above the
+// cancel price for buys.
+func (v *Limiter) shouldCancel(price decimal.Decimal) bool {
+	if v.IsSell() {
+		return price.LessThanOrEqual(v.point.Cancel)
+	}
+	return price.GreaterThanOrEqual(v.point.Cancel)
+}
+
+// shouldCreate returns true if the ticker price is on the order side of the
+// cancel threshold, i.e., above the cancel price for sells and below the
+// cancel price for buys.
+func (v *Limiter) shouldCreate(price decimal.Decimal) bool {
+	return !v.shouldCancel(price)
+}
+
 // isTickerSideReady returns true if the input price unblocks the wait-for-ticker
-// side option.
+// side option and the input price is past the cancel threshold by at least the
+// readiness-band.
 func (v *Limiter) isTickerSideReady(price decimal.Decimal) bool {
+	if band := v.ReadinessBand(); !band.IsZero() {
+		if v.point.Side() == "BUY" && price.GreaterThan(v.point.Cancel.Sub(band)) {
+			return false
+		}
+		if v.point.Side() == "SELL" && price.LessThan(v.point.Cancel.Add(band)) {
+			return false
+		}
+	}
+
 	if wait := v.waitForTickerSideOpt.Load(); !wait {
 		return true
 	}
@@ -0,0 +1,88 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/trader"
+	"github.com/shopspring/decimal"
+)
+
+func (v *Limiter) setReduceOption(value string) error {
+	arg := strings.ToLower(value)
+	if arg == "true" {
+		v.reduceOpt.Store(true)
+		return nil
+	}
+	if arg == "false" {
+		v.reduceOpt.Store(false)
+		return nil
+	}
+	return fmt.Errorf(`%v: reduce-on-size-change option only takes a "true" or "false" value`, v.uid)
+}
+
+// reduce shrinks the active order to the size limit in place. It returns an
+// error when the order cannot be reduced, in which case caller must cancel and
+// recreate the order instead. Products without the native support return
+// errors.ErrUnsupported.
+func (v *Limiter) reduce(ctx context.Context, product exchange.Product, activeOrderID exchange.OrderID, sizeLimit decimal.Decimal) error {
+	size, ok := v.orderSizes.Load(activeOrderID)
+	if !ok {
+		return fmt.Errorf("size of order %s is unknown: %w", activeOrderID, os.ErrNotExist)
+	}
+	newSize := roundDown(sizeLimit, product.BaseIncrement())
+	if !newSize.LessThan(size) {
+		return fmt.Errorf("order %s size %s is already within the size-limit %s: %w", activeOrderID, size, newSize, os.ErrInvalid)
+	}
+	if newSize.LessThan(product.BaseMinSize()) {
+		return fmt.Errorf("new size %s is less than the product min size %s: %w", newSize, product.BaseMinSize(), os.ErrInvalid)
+	}
+	if order, ok := v.orderMap.Load(activeOrderID); ok && !newSize.GreaterThan(order.FilledSize) {
+		return fmt.Errorf("new size %s is not more than the filled size %s: %w", newSize, order.FilledSize, os.ErrInvalid)
+	}
+
+	release, err := acquireExchange(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := product.Reduce(ctx, activeOrderID, newSize); err != nil {
+		return err
+	}
+	v.orderSizes.Store(activeOrderID, newSize)
+	v.lastActionTime.Store(time.Now().UnixNano())
+	log.Printf("%s:%s: reduced the limit order %s from size %s to %s", v.uid, v.point, activeOrderID, size, newSize)
+	return nil
+}
+
+// reduceTraced reduces the order and records the decision in the audit trail
+// when audit-trail option is set.
+func (v *Limiter) reduceTraced(ctx context.Context, rt *trader.Runtime, id exchange.OrderID, sizeLimit, price decimal.Decimal, reason string) error {
+	start := time.Now()
+	err := v.reduce(ctx, rt.Product, id, sizeLimit)
+	entry := &gobs.LimiterTrailEntry{
+		Time:          start,
+		Action:        "reduce",
+		Reason:        reason,
+		TickerPrice:   price,
+		ServerOrderID: string(id),
+		Latency:       time.Since(start),
+	}
+	if order, ok := v.orderMap.Load(id); ok {
+		entry.ClientOrderID = order.ClientOrderID
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	v.saveTrail(ctx, rt.Database, entry)
+	return err
+}
@@ -4,23 +4,40 @@ package limiter
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/bvk/tradebot/ctxutil"
 	"github.com/bvk/tradebot/exchange"
 	"github.com/bvk/tradebot/trader"
 	"github.com/bvkgo/kv"
+	"github.com/shopspring/decimal"
 )
 
-func (v *Limiter) Run(ctx context.Context, rt *trader.Runtime) error {
+func (v *Limiter) Run(ctx context.Context, rt *trader.Runtime) (status error) {
+	defer func() {
+		if r := recover(); r != nil {
+			status = v.recoverPanic(rt, r)
+		}
+	}()
+
 	v.runtimeLock.Lock()
 	defer v.runtimeLock.Unlock()
 
 	log.Printf("%s:%s: started limiter job", v.uid, v.point)
-	if rt.Product.ProductID() != v.productID {
-		return os.ErrInvalid
+	if pid := rt.Product.ProductID(); pid != v.productID {
+		err := fmt.Errorf("limiter %s is for product %q, but the runtime product is %q: %w", v.uid, v.productID, pid, os.ErrInvalid)
+		log.Printf("%s:%s: ERROR: %v", v.uid, v.point, err)
+		return err
+	}
+	if inc := rt.Product.BaseIncrement(); inc.IsPositive() {
+		v.baseIncrement.Store(&inc)
 	}
 	// We also need to handle resume logic here.
 	nupdated, err := v.fetchOrderMap(ctx, rt.Product)
@@ -28,12 +45,27 @@ func (v *Limiter) Run(ctx context.Context, rt *trader.Runtime) error {
 		log.Printf("%s:%s: could not refresh/fetch order map: %v", v.uid, v.point, err)
 		return err
 	}
+	// Startup reconciliation may take long; do not enter the main loop if the
+	// job is stopped in the meantime.
+	if ctx.Err() != nil {
+		log.Printf("%s:%s: limiter is stopped during the startup reconciliation (%v)", v.uid, v.point, context.Cause(ctx))
+		return context.Cause(ctx)
+	}
+
+	if v.oneShotCanceled.Load() {
+		if nupdated != 0 {
+			_ = kv.WithReadWriter(ctx, rt.Database, v.Save)
+		}
+		log.Printf("%s:%s: one-shot limiter is complete cause it's order was canceled by the cancel threshold", v.uid, v.point)
+		return nil
+	}
 
 	if p := v.PendingSize(); p.IsZero() {
 		if nupdated != 0 {
 			_ = kv.WithReadWriter(ctx, rt.Database, v.Save)
 		}
 		asyncUpdateFinishTime(v)
+		v.logOverfill()
 		log.Printf("%s:%s: limiter is complete cause pending size is zero", v.uid, v.point)
 		return nil
 	}
@@ -60,24 +92,78 @@ func (v *Limiter) Run(ctx context.Context, rt *trader.Runtime) error {
 	}
 
 	dirty := 0
-	flushCh := time.After(time.Minute)
+	flushCh := time.After(v.firstFlushDelay())
+	pollCh := time.After(v.pollInterval())
 
 	localCtx := context.Background()
 
 	tickerCh, stopTickers := rt.Product.TickerCh()
-	defer stopTickers()
+	defer func() { stopTickers() }()
 
-	orderUpdatesCh, stopUpdates := rt.Product.OrderUpdatesCh()
+	orderUpdatesCh, stopUpdates := v.orderUpdatesCh(rt.Product)
 	defer stopUpdates()
 
+	reconnectCh, stopReconnects := rt.Product.ReconnectCh()
+	defer stopReconnects()
+
 	lastSizeLimit := v.sizeLimit()
 
-	for p := v.PendingSize(); !p.IsZero(); p = v.PendingSize() {
+	// feeTooHigh is true when order creates are skipped due to the max-fee-pct
+	// option, so that the skip is logged only once.
+	var feeTooHigh bool
+
+	// marketOrderID and marketCancelID track the market-fill-after conversion,
+	// which cancels the active limit order and waits for it's final update
+	// before the market order is created for the exact pending size.
+	var marketOrderID, marketCancelID exchange.OrderID
+
+	// lastPrice is the latest ticker price, which is recorded in the audit
+	// trail for the decisions not triggered by a ticker.
+	var lastPrice decimal.Decimal
+
+	// fresh is true after a ticker within the max-price-age is received, so
+	// that only the first actionable ticker after the startup is checked.
+	fresh := false
+
+	// lastTickerAt is the time of the latest ticker (or the ticker
+	// subscription) and dormant is true when the active order is canceled due
+	// to the no-ticker-timeout option, until a ticker is received.
+	lastTickerAt := time.Now()
+	dormant := false
+	noTickerCh := time.After(v.noTickerCheckInterval())
+
+	// debounce holds the create or cancel action waiting for the ticker price
+	// to hold for the debounce duration, which is measured with the ticker
+	// timestamps.
+	var debounce debounceState
+
+	for {
+		if activeOrderID == "" && v.completeSubMinOpt.Load() && v.isSubMinSize(rt.Product) {
+			residual := v.PendingSize()
+			// Complete fails when a canceled order is not yet done, in which case
+			// it is retried on the later events.
+			if err := v.Complete(); err == nil {
+				log.Printf("%s:%s: limiter is complete with residual size %s below the product min size %s (sub-min-size=%s)", v.uid, v.point, residual, rt.Product.BaseMinSize(), SubMinSizeComplete)
+				dirty++
+			}
+		}
+		if v.PendingSize().IsZero() {
+			done, err := v.verifyCompletion(ctx, rt.Product)
+			if err != nil {
+				return err
+			}
+			if done {
+				break
+			}
+			log.Printf("%s:%s: limiter is not complete after the final order fetch (pending size %s)", v.uid, v.point, v.PendingSize())
+			dirty++
+		}
+
 		select {
 		case <-ctx.Done():
 			if activeOrderID != "" {
 				log.Printf("%s:%s: canceling active limit order %v (%v)", v.uid, v.point, activeOrderID, context.Cause(ctx))
-				if err := v.cancel(localCtx, rt.Product, activeOrderID); err != nil {
+				if err := v.cancelTraced(localCtx, rt, activeOrderID, lastPrice, "job is stopped"); err != nil {
 					return err
 				}
 				dirty++
@@ -96,23 +182,104 @@ func (v *Limiter) Run(ctx context.Context, rt *trader.Runtime) error {
 					dirty = 0
 				}
 			}
-			flushCh = time.After(time.Minute)
+			flushCh = time.After(v.flushInterval())
+
+		case <-noTickerCh:
+			noTickerCh = time.After(v.noTickerCheckInterval())
+			if !v.isTickerTimedOut(lastTickerAt, time.Now()) {
+				continue
+			}
+			if !dormant {
+				log.Printf("%s:%s: no tickers are received for %s, which is over the no-ticker-timeout (limiter is dormant until tickers resume)", v.uid, v.point, time.Since(lastTickerAt).Round(time.Second))
+				dormant = true
+			} else {
+				log.Printf("%s:%s: no tickers are received for %s while dormant (retrying ticker subscription)", v.uid, v.point, time.Since(lastTickerAt).Round(time.Second))
+			}
+			if activeOrderID != "" && activeOrderID != marketOrderID {
+				if err := v.cancelTraced(localCtx, rt, activeOrderID, lastPrice, "no tickers within the no-ticker-timeout"); err != nil {
+					return err
+				}
+				dirty++
+				activeOrderID = ""
+			}
+			stopTickers()
+			tickerCh, stopTickers = rt.Product.TickerCh()
+			lastTickerAt = time.Now()
+
+		case <-pollCh:
+			// Order updates and tickers may not arrive at all during the feed gaps,
+			// so we periodically fetch the active order state to observe it's
+			// completion.
+			pollCh = time.After(v.pollInterval())
+			if activeOrderID == "" {
+				continue
+			}
+			nupdated, err := v.fetchOrderMap(ctx, rt.Product)
+			if err != nil {
+				log.Printf("%s:%s: could not poll the active order %s (will retry): %v", v.uid, v.point, activeOrderID, err)
+				continue
+			}
+			dirty += nupdated
+			if order, ok := v.orderMap.Load(activeOrderID); ok && order.Done {
+				log.Printf("%s:%s: active order %s is found completed with status %q by the periodic poll", v.uid, v.point, activeOrderID, order.Status)
+				v.recordDone(order, time.Now())
+				activeOrderID = ""
+			}
 
 		case order := <-orderUpdatesCh:
 			dirty++
 			v.updateOrderMap(order)
+			v.recordDone(order, time.Now())
+			if order.Done {
+				v.orderSizes.Delete(order.OrderID)
+			}
 			if order.Done && order.OrderID == activeOrderID {
 				log.Printf("%s:%s: limit order with server order-id %s is completed with status %q (DoneReason %q)", v.uid, v.point, activeOrderID, order.Status, order.DoneReason)
+				// Expired orders (with good-till option) and orders rejected by the
+				// exchange after they were accepted are not failures; a new order is
+				// created for the pending size on the next ticker update.
 				activeOrderID = ""
 			}
 
+		case <-reconnectCh:
+			// Order updates may be missed during the reconnect, so we resync the
+			// order states before acting on the next ticker.
+			if _, err := v.fetchOrderMap(ctx, rt.Product); err != nil {
+				log.Printf("%s:%s: could not resync order map after reconnect (will retry): %v", v.uid, v.point, err)
+				continue
+			}
+			dirty++
+			if activeOrderID != "" {
+				if order, ok := v.orderMap.Load(activeOrderID); ok && order.Done {
+					log.Printf("%s:%s: active order %s is found completed with status %q after the reconnect", v.uid, v.point, activeOrderID, order.Status)
+					activeOrderID = ""
+				}
+			}
+
 		case ticker := <-tickerCh:
+			decided := time.Now()
+			lastPrice = ticker.Price
+			lastTickerAt = decided
+			if dormant {
+				log.Printf("%s:%s: tickers have resumed with price %s (limiter is no longer dormant)", v.uid, v.point, ticker.Price)
+				dormant = false
+			}
+			if debounce.action != "" && !v.isDebounceActionable(debounce.action, activeOrderID, ticker.Price) {
+				log.Printf("%s:%s: ticker price %s has reverted within the debounce duration (dropping the pending %s)", v.uid, v.point, ticker.Price, debounce.action)
+				debounce.reset()
+			}
+			tickerAt := ticker.Timestamp.Time
+			if tickerAt.IsZero() {
+				tickerAt = decided
+			}
+
 			// We should pause this job when hold option is set, effectively pausing
 			// the job. We should cancel active order if any.
 			if v.holdOpt.Load() {
+				debounce.reset()
 				if activeOrderID != "" {
-					log.Printf("%v: canceling existing order %s cause option hold=true is set", v.uid, activeOrderID)
-					if err := v.cancel(localCtx, rt.Product, activeOrderID); err != nil {
+					v.throttledLogf("cancel", "%v: canceling existing order %s cause option hold=true is set", v.uid, activeOrderID)
+					if err := v.cancelTraced(localCtx, rt, activeOrderID, ticker.Price, "hold option is set"); err != nil {
 						return err
 					}
 					dirty++
@@ -122,11 +289,58 @@ func (v *Limiter) Run(ctx context.Context, rt *trader.Runtime) error {
 				continue
 			}
 
+			if !fresh {
+				if v.isStaleTicker(ticker, time.Now()) {
+					v.throttledLogf("stale-ticker", "%s:%s: ignoring stale ticker with price %s from %s (waiting for a fresh ticker)", v.uid, v.point, ticker.Price, ticker.Timestamp.Time.Format(time.RFC3339))
+					continue
+				}
+				fresh = true
+			}
+
+			if v.isMarketFillDue(time.Now()) {
+				if activeOrderID == "" {
+					id, err := v.createTraced(localCtx, rt, true /* market */, ticker.Price, "market-fill-after deadline has passed")
+					if err != nil {
+						if v.skipFeeTooHigh(err, &feeTooHigh) {
+							continue
+						}
+						return err
+					}
+					feeTooHigh = false
+					v.recordCreate(id, decided)
+					dirty++
+					activeOrderID, marketOrderID = id, id
+					continue
+				}
+				if activeOrderID != marketOrderID && activeOrderID != marketCancelID {
+					v.throttledLogf("cancel", "%s:%s: canceling limit order %s to fill the remaining size at market cause market-fill-after deadline has passed", v.uid, v.point, activeOrderID)
+					if err := v.cancelTraced(localCtx, rt, activeOrderID, ticker.Price, "market-fill-after deadline has passed"); err != nil {
+						return err
+					}
+					dirty++
+					marketCancelID = activeOrderID
+				}
+				continue
+			}
+
+			// Coalesce rapid ticker driven create/cancel decisions.
+			if !v.isActionAllowed(time.Now()) {
+				continue
+			}
+
 			// Cancel the active order if size-limit option value has changed; order
 			// will be recreated with correct size-limit.
+			if x := v.sizeLimit(); activeOrderID != "" && !lastSizeLimit.Equal(x) && v.reduceOpt.Load() && x.LessThan(lastSizeLimit) {
+				if err := v.reduceTraced(localCtx, rt, activeOrderID, x, ticker.Price, "size-limit option has been lowered"); err != nil {
+					log.Printf("%s:%s: could not reduce order %s to size-limit %s (falling back to cancel): %v", v.uid, v.point, activeOrderID, x, err)
+				} else {
+					dirty++
+					lastSizeLimit = x
+				}
+			}
 			if x := v.sizeLimit(); activeOrderID != "" && !lastSizeLimit.Equal(x) {
-				log.Printf("%v: canceling existing order %s cause size-limit has changed from %s to %s", v.uid, activeOrderID, lastSizeLimit, x)
-				if err := v.cancel(localCtx, rt.Product, activeOrderID); err != nil {
+				v.throttledLogf("cancel", "%v: canceling existing order %s cause size-limit has changed from %s to %s", v.uid, activeOrderID, lastSizeLimit, x)
+				if err := v.cancelTraced(localCtx, rt, activeOrderID, ticker.Price, "size-limit option has changed"); err != nil {
 					return err
 				}
 				dirty++
@@ -139,57 +353,96 @@ func (v *Limiter) Run(ctx context.Context, rt *trader.Runtime) error {
 				continue
 			}
 
-			if v.IsSell() {
-				if ticker.Price.LessThanOrEqual(v.point.Cancel) {
-					if activeOrderID != "" {
-						if err := v.cancel(localCtx, rt.Product, activeOrderID); err != nil {
-							return err
-						}
-						dirty++
-						activeOrderID = ""
-					}
+			if activeOrderID != "" && v.shouldCancel(ticker.Price) {
+				if !v.isDebounceDue(&debounce, debounceCancel, tickerAt) {
+					continue
 				}
-				if ticker.Price.GreaterThan(v.point.Cancel) {
-					if activeOrderID == "" {
-						id, err := v.create(localCtx, rt.Product)
-						if err != nil {
-							return err
-						}
-						dirty++
-						activeOrderID = id
-					}
+				if err := v.cancelTraced(localCtx, rt, activeOrderID, ticker.Price, "ticker has crossed the cancel price"); err != nil {
+					return err
+				}
+				dirty++
+				activeOrderID = ""
+				if v.oneShotOpt.Load() {
+					return v.finishOneShot(ctx, rt)
 				}
-				continue
 			}
-
-			if v.IsBuy() {
-				if ticker.Price.GreaterThanOrEqual(v.point.Cancel) {
-					if activeOrderID != "" {
-						if err := v.cancel(localCtx, rt.Product, activeOrderID); err != nil {
-							return err
-						}
-						dirty++
-						activeOrderID = ""
-					}
+			if activeOrderID == "" && v.shouldCreate(ticker.Price) {
+				// Book imbalance is rechecked on the next ticker.
+				if !v.isBookFavorable(ticker) {
+					continue
 				}
-				if ticker.Price.LessThan(v.point.Cancel) {
-					if activeOrderID == "" {
-						id, err := v.create(localCtx, rt.Product)
-						if err != nil {
-							return err
-						}
-						dirty++
-						activeOrderID = id
+				if !v.isDebounceDue(&debounce, debounceCreate, tickerAt) {
+					continue
+				}
+				id, err := v.createTraced(localCtx, rt, false /* market */, ticker.Price, "ticker is inside the cancel price")
+				if err != nil {
+					if v.skipFeeTooHigh(err, &feeTooHigh) {
+						continue
 					}
+					return err
 				}
-				continue
+				feeTooHigh = false
+				v.recordCreate(id, decided)
+				dirty++
+				activeOrderID = id
 			}
 		}
 	}
 
-	if _, err := v.fetchOrderMap(ctx, rt.Product); err != nil {
+	if err := kv.WithReadWriter(ctx, rt.Database, v.Save); err != nil {
 		return err
 	}
+	asyncUpdateFinishTime(v)
+	v.logOverfill()
+	return nil
+}
+
+// verifyCompletion confirms with the exchange that the limiter is complete
+// after it's pending size has reached zero. It waits for the completion-grace
+// period and refetches the live orders and the latest order, so that a last
+// fill update in flight is applied before the limiter is marked as done.
+func (v *Limiter) verifyCompletion(ctx context.Context, product exchange.Product) (bool, error) {
+	if d := v.completionGrace(); d > 0 {
+		ctxutil.Sleep(ctx, d)
+		if err := context.Cause(ctx); err != nil {
+			return false, err
+		}
+	}
+	if _, err := v.fetchOrderMap(ctx, product); err != nil {
+		return false, err
+	}
+
+	var last *exchange.Order
+	for _, order := range v.dupOrderMap() {
+		if last == nil || order.CreateTime.Time.After(last.CreateTime.Time) {
+			last = order
+		}
+	}
+	if last != nil {
+		release, err := acquireExchange(ctx)
+		if err != nil {
+			return false, err
+		}
+		order, err := product.Get(ctx, last.OrderID)
+		release()
+		if err != nil && !errors.Is(err, exchange.ErrNotFound) {
+			return false, fmt.Errorf("could not fetch the last order %s: %w", last.OrderID, err)
+		}
+		if err == nil {
+			v.updateOrderMap(order)
+		}
+	}
+	return v.PendingSize().IsZero(), nil
+}
+
+// finishOneShot marks the one-shot limiter as complete after it's order is
+// canceled by the cancel threshold and saves the final state.
+func (v *Limiter) finishOneShot(ctx context.Context, rt *trader.Runtime) error {
+	v.oneShotCanceled.Store(true)
+	log.Printf("%s:%s: one-shot limiter order is canceled by the cancel threshold (not recreating)", v.uid, v.point)
+	if _, err := v.fetchOrderMap(ctx, rt.Product); err != nil {
+		log.Printf("%s:%s: could not refresh order map for the one-shot limiter (ignored): %v", v.uid, v.point, err)
+	}
 	if err := kv.WithReadWriter(ctx, rt.Database, v.Save); err != nil {
 		return err
 	}
@@ -197,6 +450,14 @@ func (v *Limiter) Run(ctx context.Context, rt *trader.Runtime) error {
 	return nil
 }
 
+// logOverfill logs the overfilled size, if any, so that the excess size
+// accounted in the oversold/unsold summary fields can be traced.
+func (v *Limiter) logOverfill() {
+	if extra := v.OverfilledSize(); extra.IsPositive() {
+		log.Printf("%s:%s: limiter is overfilled by size %s (filled size %s, filled value %s)", v.uid, v.point, extra, v.FilledSize(), v.FilledValue())
+	}
+}
+
 // Fix is a temporary helper interface used to fix any past mistakes.
 func (v *Limiter) Fix(ctx context.Context, rt *trader.Runtime) error {
 	v.runtimeLock.Lock()
@@ -216,28 +477,102 @@ func (v *Limiter) Refresh(ctx context.Context, rt *trader.Runtime) error {
 	return nil
 }
 
+// recoverPanic is a safety net for the bugs in the Run method. It cancels the
+// live orders and saves the limiter state, so that a panic doesn't leave
+// orphaned orders on the exchange, and returns the panic as an error.
+func (v *Limiter) recoverPanic(rt *trader.Runtime, r any) error {
+	log.Printf("%s:%s: PANIC: limiter job has panicked: %v\n%s", v.uid, v.point, r, debug.Stack())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for id, order := range v.dupOrderMap() {
+		if order.Done {
+			continue
+		}
+		if err := v.cancel(ctx, rt.Product, id); err != nil {
+			log.Printf("%s:%s: could not cancel live order %s after the panic (may be orphaned): %v", v.uid, v.point, id, err)
+			continue
+		}
+		log.Printf("%s:%s: canceled live order %s after the panic", v.uid, v.point, id)
+	}
+	if _, err := v.fetchOrderMap(ctx, rt.Product); err != nil {
+		log.Printf("%s:%s: could not refresh order map after the panic (ignored): %v", v.uid, v.point, err)
+	}
+	if err := kv.WithReadWriter(ctx, rt.Database, v.Save); err != nil {
+		log.Printf("%s:%s: could not save limiter state after the panic: %v", v.uid, v.point, err)
+	}
+	return fmt.Errorf("limiter %s has panicked: %v", v.uid, r)
+}
+
+// orderUpdatesCh subscribes to the updates of the limiter's own orders when
+// the product supports filtering and to all order updates otherwise.
+func (v *Limiter) orderUpdatesCh(product exchange.Product) (<-chan *exchange.Order, func()) {
+	if f, ok := product.(exchange.OrderUpdatesFilterer); ok {
+		return f.OrderUpdatesChFiltered(v.isOwnOrder)
+	}
+	return product.OrderUpdatesCh()
+}
+
+// isOwnOrder returns true if the order is created by the limiter.
+func (v *Limiter) isOwnOrder(order *exchange.Order) bool {
+	if _, ok := v.orderMap.Load(order.OrderID); ok {
+		return true
+	}
+	_, ok := v.clientIDs.Load(order.ClientOrderID)
+	return ok
+}
+
 func (v *Limiter) create(ctx context.Context, product exchange.Product) (exchange.OrderID, error) {
 	offset := v.idgen.Offset()
 	clientOrderID := v.idgen.NextID()
+	v.clientIDs.Store(clientOrderID.String(), true)
 
 	size := v.PendingSize()
 	if s := v.sizeLimit(); size.GreaterThan(s) {
 		size = s
 	}
+	// Size can have more precision than the product allows (eg: base size
+	// translated from the quote funds), so we round it down to the product's
+	// base increment.
+	size = roundDown(size, product.BaseIncrement())
 	if size.LessThan(product.BaseMinSize()) {
 		size = product.BaseMinSize()
 	}
 
-	var err error
+	if err := v.checkMaxFee(size); err != nil {
+		v.idgen.RevertID()
+		return "", err
+	}
+	if err := v.checkBalance(ctx, product, size); err != nil {
+		v.idgen.RevertID()
+		return "", err
+	}
+
+	release, err := acquireExchange(ctx)
+	if err != nil {
+		v.idgen.RevertID()
+		return "", err
+	}
+	defer release()
+
 	var latency time.Duration
 	var orderID exchange.OrderID
-	if v.IsSell() {
+	if trigger := v.TriggerPrice(); trigger.IsPositive() {
+		s := time.Now()
+		if v.IsSell() {
+			orderID, err = product.StopLimitSell(ctx, clientOrderID.String(), size, v.point.Price, trigger, v.goodTill(), v.orderTag())
+		} else {
+			orderID, err = product.StopLimitBuy(ctx, clientOrderID.String(), size, v.point.Price, trigger, v.goodTill(), v.orderTag())
+		}
+		latency = time.Now().Sub(s)
+	} else if v.IsSell() {
 		s := time.Now()
-		orderID, err = product.LimitSell(ctx, clientOrderID.String(), size, v.point.Price)
+		orderID, err = product.LimitSell(ctx, clientOrderID.String(), size, v.point.Price, v.goodTill(), v.orderTag())
 		latency = time.Now().Sub(s)
 	} else {
 		s := time.Now()
-		orderID, err = product.LimitBuy(ctx, clientOrderID.String(), size, v.point.Price)
+		orderID, err = product.LimitBuy(ctx, clientOrderID.String(), size, v.point.Price, v.goodTill(), v.orderTag())
 		latency = time.Now().Sub(s)
 	}
 	if err != nil {
@@ -249,34 +584,284 @@ func (v *Limiter) create(ctx context.Context, product exchange.Product) (exchang
 	v.orderMap.Store(orderID, &exchange.Order{
 		OrderID:       orderID,
 		ClientOrderID: clientOrderID.String(),
+		Tag:           v.orderTag(),
 		Side:          v.point.Side(),
 	})
+	v.orderSizes.Store(orderID, size)
+	v.lastActionTime.Store(time.Now().UnixNano())
+	v.marketFillAnchor.CompareAndSwap(0, time.Now().UnixNano())
 
-	log.Printf("%s:%s: created a new limit order %s with client-order-id %s (%d) in %s", v.uid, v.point, orderID, clientOrderID, offset, latency)
+	v.throttledLogf("create", "%s:%s: created a new limit order %s with client-order-id %s (%d) in %s", v.uid, v.point, orderID, clientOrderID, offset, latency)
+	return orderID, nil
+}
+
+// skipFeeTooHigh returns true if the create error is due to the max-fee-pct
+// option, in which case the order is skipped till the next ticker; pending
+// size may change (eg: with a size-limit option update) or the option may be
+// relaxed in the meantime.
+func (v *Limiter) skipFeeTooHigh(err error, logged *bool) bool {
+	if !errors.Is(err, ErrFeeTooHigh) {
+		return false
+	}
+	if !*logged {
+		log.Printf("%s:%s: WARNING: skipping order create (will retry on ticker updates): %v", v.uid, v.point, err)
+		*logged = true
+	}
+	return true
+}
+
+// EstimatedFeePct is the fee percentage used to estimate the funds required
+// for a limit-buy order.
+const EstimatedFeePct = 0.25
+
+// ErrFeeTooHigh is returned when an order is not created because it's
+// estimated fee is more than the max-fee-pct option.
+var ErrFeeTooHigh = errors.New("estimated fee is too high")
+
+// estimatedFee returns the fee at EstimatedFeePct for an order value, rounded
+// up to a whole cent cause exchanges do not charge fractional cents, which
+// makes the fee dominate the value for tiny orders.
+func estimatedFee(value decimal.Decimal) decimal.Decimal {
+	return value.Mul(decimal.NewFromFloat(EstimatedFeePct)).Div(decimal.NewFromInt(100)).RoundUp(2)
+}
+
+// checkBalance verifies that the account has enough quote balance for a buy
+// (including the estimated fee) or enough base balance for a sell of the input
+// size. It returns an error wrapping exchange.ErrInsufficientFunds when the
+// balance is short, so that callers can pause till the funds are available.
+// Check is skipped when the product doesn't support balances.
+func (v *Limiter) checkBalance(ctx context.Context, product exchange.Product, size decimal.Decimal) error {
+	currency := exchange.QuoteCurrency(v.productID)
+	need := buyFunds(size, v.point.Price)
+	if v.IsSell() {
+		currency = exchange.BaseCurrency(v.productID)
+		need = size
+	}
+
+	release, err := acquireExchange(ctx)
+	if err != nil {
+		return err
+	}
+	avail, err := product.AvailableBalance(ctx, currency)
+	release()
+	if err != nil {
+		if errors.Is(err, errors.ErrUnsupported) {
+			return nil
+		}
+		return fmt.Errorf("could not check %s balance: %w", currency, err)
+	}
+	if avail.LessThan(need) {
+		return fmt.Errorf("%s balance %s is less than the required %s: %w", currency, avail, need, exchange.ErrInsufficientFunds)
+	}
+	return nil
+}
+
+func (v *Limiter) createMarket(ctx context.Context, product exchange.Product) (exchange.OrderID, error) {
+	offset := v.idgen.Offset()
+	clientOrderID := v.idgen.NextID()
+	v.clientIDs.Store(clientOrderID.String(), true)
+
+	size := roundDown(v.PendingSize(), product.BaseIncrement())
+	if size.LessThan(product.BaseMinSize()) {
+		size = product.BaseMinSize()
+	}
+	if err := v.checkMaxFee(size); err != nil {
+		v.idgen.RevertID()
+		return "", err
+	}
+
+	release, err := acquireExchange(ctx)
+	if err != nil {
+		v.idgen.RevertID()
+		return "", err
+	}
+	defer release()
+
+	var orderID exchange.OrderID
+	if v.IsSell() {
+		orderID, err = product.MarketSell(ctx, clientOrderID.String(), size)
+	} else {
+		orderID, err = product.MarketBuy(ctx, clientOrderID.String(), size)
+	}
+	if err != nil {
+		v.idgen.RevertID()
+		log.Printf("%s:%s: create market order with client-order-id %s (%d reverted) has failed: %v", v.uid, v.point, clientOrderID, offset, err)
+		return "", err
+	}
+
+	v.orderMap.Store(orderID, &exchange.Order{
+		OrderID:       orderID,
+		ClientOrderID: clientOrderID.String(),
+		Side:          v.point.Side(),
+	})
+	v.lastActionTime.Store(time.Now().UnixNano())
+
+	log.Printf("%s:%s: created a new market order %s for size %s with client-order-id %s (%d)", v.uid, v.point, orderID, size, clientOrderID, offset)
 	return orderID, nil
 }
 
 func (v *Limiter) cancel(ctx context.Context, product exchange.Product, activeOrderID exchange.OrderID) error {
-	if err := product.Cancel(ctx, activeOrderID); err != nil {
+	release, err := acquireExchange(ctx)
+	if err != nil {
+		return err
+	}
+	err = product.Cancel(ctx, activeOrderID)
+	release()
+	if err != nil {
+		if errors.Is(err, exchange.ErrOrderDone) || errors.Is(err, exchange.ErrNotFound) {
+			// Order is gone already, typically cause it's filled just before the
+			// cancel, so the cancel is treated as a success.
+			log.Printf("%s:%s: limit order %s is already done at the exchange (treating cancel as success): %v", v.uid, v.point, activeOrderID, err)
+			v.refreshDoneOrder(ctx, product, activeOrderID)
+			v.lastActionTime.Store(time.Now().UnixNano())
+			return nil
+		}
 		log.Printf("%s:%s: cancel limit order %s has failed: %v", v.uid, v.point, activeOrderID, err)
 		return err
 	}
+	v.lastActionTime.Store(time.Now().UnixNano())
 	// log.Printf("%s:%s: canceled the limit order %s", v.uid, v.point, activeOrderID)
 	return nil
 }
 
 func (v *Limiter) fetchOrderMap(ctx context.Context, product exchange.Product) (nupdated int, status error) {
+	var ids []exchange.OrderID
 	for id, order := range v.dupOrderMap() {
-		if order.Done {
-			continue
+		if !order.Done {
+			ids = append(ids, id)
 		}
-		norder, err := product.Get(ctx, id)
-		if err != nil {
-			log.Printf("%s:%s: could not fetch order with id %s: %v", v.uid, v.point, id, err)
-			return nupdated, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+
+	// Fetch the orders in parallel with a bounded number of workers.
+	sem := make(chan struct{}, v.fetchConcurrency())
+	for _, id := range ids {
+		select {
+		case <-ctx.Done():
+			mu.Lock()
+			errs = append(errs, context.Cause(ctx))
+			mu.Unlock()
+		case sem <- struct{}{}:
+			wg.Add(1)
+			go func(id exchange.OrderID) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				release, err := acquireExchange(ctx)
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+					return
+				}
+				norder, err := product.Get(ctx, id)
+				release()
+				mu.Lock()
+				defer mu.Unlock()
+
+				if err != nil && errors.Is(err, exchange.ErrNotFound) && !v.failOnMissingOrdersOpt.Load() {
+					log.Printf("%s:%s: order with id %s is not found at the exchange (marking it as done): %v", v.uid, v.point, id, err)
+					v.markMissing(id)
+					nupdated++
+					return
+				}
+				if err != nil {
+					log.Printf("%s:%s: could not fetch order with id %s: %v", v.uid, v.point, id, err)
+					errs = append(errs, fmt.Errorf("could not fetch order %s: %w", id, err))
+					return
+				}
+				v.orderMap.Store(id, norder)
+				nupdated++
+			}(id)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	// Slow fetches must not hold up the caller after the context is canceled;
+	// results from the pending fetches, if any, are still applied to the order
+	// map when they complete.
+	doneCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(doneCh)
+	}()
+	select {
+	case <-ctx.Done():
+		return 0, context.Cause(ctx)
+	case <-doneCh:
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return nupdated, errors.Join(errs...)
+}
+
+// refreshDoneOrder fetches the final state of an order that could not be
+// canceled cause it's already done. Failures are not fatal because the order is
+// fetched again by the fetchOrderMap before the limiter completes.
+func (v *Limiter) refreshDoneOrder(ctx context.Context, product exchange.Product, id exchange.OrderID) {
+	release, err := acquireExchange(ctx)
+	if err != nil {
+		log.Printf("%s:%s: could not fetch the final state of order %s (ignored): %v", v.uid, v.point, id, err)
+		return
+	}
+	norder, err := product.Get(ctx, id)
+	release()
+	if err != nil {
+		if errors.Is(err, exchange.ErrNotFound) && !v.failOnMissingOrdersOpt.Load() {
+			v.markMissing(id)
+			return
 		}
-		v.orderMap.Store(id, norder)
-		nupdated++
+		log.Printf("%s:%s: could not fetch the final state of order %s (ignored): %v", v.uid, v.point, id, err)
+		return
+	}
+	v.orderMap.Store(id, norder)
+}
+
+// markMissing marks an order that the exchange doesn't know about as done, so
+// that it doesn't block the limiter and is compacted away when it has no
+// fills.
+func (v *Limiter) markMissing(id exchange.OrderID) {
+	order, ok := v.orderMap.Load(id)
+	if !ok {
+		return
+	}
+	missing := *order
+	missing.Done = true
+	missing.Status = "CANCELLED"
+	missing.DoneReason = "order is not found at the exchange"
+	v.orderMap.Store(id, &missing)
+}
+
+// orderTag returns a short human-readable tag for the orders derived from the
+// job uid, i.e., the first eight characters of the uuid followed by the child
+// job path, if any (eg: 1a2b3c4d/loop-000001/buy-000002).
+func (v *Limiter) orderTag() string {
+	prefix, rest, _ := strings.Cut(v.uid, "/")
+	if len(prefix) > 8 {
+		prefix = prefix[:8]
+	}
+	if len(rest) == 0 {
+		return prefix
+	}
+	return prefix + "/" + rest
+}
+
+// roundDown rounds the size down to a multiple of the increment. Size is
+// returned as is when the increment is not positive.
+func roundDown(size, increment decimal.Decimal) decimal.Decimal {
+	if !increment.IsPositive() {
+		return size
 	}
-	return nupdated, nil
+	return size.Sub(size.Mod(increment))
 }
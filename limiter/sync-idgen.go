@@ -0,0 +1,67 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/idgen"
+	"github.com/google/uuid"
+)
+
+// SyncIDGen advances the client order id offset past the highest offset used
+// by any of the limiter's orders or the given client order ids observed on the
+// exchange. Offsets at or above the limit are not searched. Returns the old
+// and the new offsets, which are the same when no update is necessary.
+func (v *Limiter) SyncIDGen(clientOrderIDs []string, limit uint64) (uint64, uint64) {
+	usedMap := make(map[uuid.UUID]bool)
+	add := func(cid string) {
+		if id, err := uuid.Parse(cid); err == nil {
+			usedMap[id] = true
+		}
+	}
+	for _, cid := range clientOrderIDs {
+		add(cid)
+	}
+	for _, order := range v.dupOrderMap() {
+		add(order.ClientOrderID)
+	}
+	for _, order := range v.dupArchivedOrders() {
+		add(order.ClientOrderID)
+	}
+
+	old := v.idgen.Offset()
+	last, ok := v.idgen.LastUsed(limit, func(id uuid.UUID) bool { return usedMap[id] })
+	if !ok || last < old {
+		return old, old
+	}
+	v.idgen = idgen.New(v.idgen.Seed(), last+1)
+	return old, last + 1
+}
+
+// ClientID is a client order id issued by the limiter at an idgen offset.
+// ServerOrderID is empty when the limiter has no record of the order, e.g.,
+// when the order is compacted away or was never created successfully.
+type ClientID struct {
+	Offset        uint64
+	ClientOrderID string
+	ServerOrderID exchange.OrderID
+}
+
+// ClientIDs returns all client order ids generated from the limiter's seed at
+// the offsets below the current idgen offset, in the offset order.
+func (v *Limiter) ClientIDs() []*ClientID {
+	known := make(map[string]exchange.OrderID)
+	for id, order := range v.dupOrderMap() {
+		known[order.ClientOrderID] = id
+	}
+	for _, order := range v.dupArchivedOrders() {
+		known[order.ClientOrderID] = order.OrderID
+	}
+
+	var ids []*ClientID
+	for i, n := uint64(0), v.idgen.Offset(); i < n; i++ {
+		cid := v.idgen.IDAt(i).String()
+		ids = append(ids, &ClientID{Offset: i, ClientOrderID: cid, ServerOrderID: known[cid]})
+	}
+	return ids
+}
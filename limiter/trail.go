@@ -0,0 +1,101 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"time"
+
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/trader"
+	"github.com/bvkgo/kv"
+	"github.com/shopspring/decimal"
+)
+
+// TrailKeyspace holds the audit trail entries of the limiters, which are
+// keyed by the limiter uid and the decision time. Entries are never updated.
+const TrailKeyspace = "/limiter-trails/"
+
+// createTraced creates a new limit or market order and records the decision
+// in the audit trail when audit-trail option is set.
+func (v *Limiter) createTraced(ctx context.Context, rt *trader.Runtime, market bool, price decimal.Decimal, reason string) (exchange.OrderID, error) {
+	action, create := "create", v.create
+	if market {
+		action, create = "create-market", v.createMarket
+	}
+
+	start := time.Now()
+	id, err := create(ctx, rt.Product)
+	entry := &gobs.LimiterTrailEntry{
+		Time:          start,
+		Action:        action,
+		Reason:        reason,
+		TickerPrice:   price,
+		ServerOrderID: string(id),
+		Latency:       time.Since(start),
+	}
+	if order, ok := v.orderMap.Load(id); ok {
+		entry.ClientOrderID = order.ClientOrderID
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	v.saveTrail(ctx, rt.Database, entry)
+	return id, err
+}
+
+// cancelTraced cancels the order and records the decision in the audit trail
+// when audit-trail option is set.
+func (v *Limiter) cancelTraced(ctx context.Context, rt *trader.Runtime, id exchange.OrderID, price decimal.Decimal, reason string) error {
+	start := time.Now()
+	err := v.cancel(ctx, rt.Product, id)
+	entry := &gobs.LimiterTrailEntry{
+		Time:          start,
+		Action:        "cancel",
+		Reason:        reason,
+		TickerPrice:   price,
+		ServerOrderID: string(id),
+		Latency:       time.Since(start),
+	}
+	if order, ok := v.orderMap.Load(id); ok {
+		entry.ClientOrderID = order.ClientOrderID
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	v.saveTrail(ctx, rt.Database, entry)
+	return err
+}
+
+func (v *Limiter) saveTrail(ctx context.Context, db kv.Database, entry *gobs.LimiterTrailEntry) {
+	if !v.auditTrailOpt.Load() {
+		return
+	}
+	key := path.Join(TrailKeyspace, v.uid, fmt.Sprintf("%020d", entry.Time.UnixNano()))
+	if err := kvutil.SetDB(ctx, db, key, entry); err != nil {
+		log.Printf("%s:%s: could not save %s decision for order %s to the audit trail (ignored): %v", v.uid, v.point, entry.Action, entry.ServerOrderID, err)
+	}
+}
+
+// LoadTrail returns the audit trail entries of a limiter in the time order.
+func LoadTrail(ctx context.Context, r kv.Reader, uid string) ([]*gobs.LimiterTrailEntry, error) {
+	dir := path.Join(TrailKeyspace, uid)
+	var entries []*gobs.LimiterTrailEntry
+	collect := func(ctx context.Context, r kv.Reader, key string, entry *gobs.LimiterTrailEntry) error {
+		// Skip the entries of the child limiters, if any.
+		if path.Dir(key) == dir {
+			entries = append(entries, entry)
+		}
+		return nil
+	}
+	begin, end := kvutil.PathRange(dir)
+	if err := kvutil.Ascend(ctx, r, begin, end, collect); err != nil {
+		return nil, fmt.Errorf("could not scan audit trail for limiter %s: %w", uid, err)
+	}
+	return entries, nil
+}
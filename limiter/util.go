@@ -1,3 +1,46 @@
 // Copyright (c) 2023 BVK Chaitanya
 
 package limiter
+
+import (
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/gobs"
+)
+
+func gobOrder(v *exchange.Order) *gobs.Order {
+	return &gobs.Order{
+		ServerOrderID: string(v.OrderID),
+		ClientOrderID: v.ClientOrderID,
+		Tag:           v.Tag,
+		CreateTime:    gobs.RemoteTime{Time: v.CreateTime.Time},
+		FinishTime:    gobs.RemoteTime{Time: v.FinishTime.Time},
+		Side:          v.Side,
+		Status:        v.Status,
+		FilledFee:     v.Fee,
+		FeeCurrency:   v.FeeCurrency,
+		FilledSize:    v.FilledSize,
+		FilledPrice:   v.FilledPrice,
+		Liquidity:     v.Liquidity,
+		Done:          v.Done,
+		DoneReason:    v.DoneReason,
+	}
+}
+
+func exchangeOrder(v *gobs.Order) *exchange.Order {
+	return &exchange.Order{
+		OrderID:       exchange.OrderID(v.ServerOrderID),
+		ClientOrderID: v.ClientOrderID,
+		Tag:           v.Tag,
+		CreateTime:    exchange.RemoteTime{Time: v.CreateTime.Time},
+		FinishTime:    exchange.RemoteTime{Time: v.FinishTime.Time},
+		Side:          v.Side,
+		Status:        v.Status,
+		Fee:           v.FilledFee,
+		FeeCurrency:   v.FeeCurrency,
+		FilledSize:    v.FilledSize,
+		FilledPrice:   v.FilledPrice,
+		Liquidity:     v.Liquidity,
+		Done:          v.Done,
+		DoneReason:    v.DoneReason,
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package logdir
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// uuidRe matches the uuids in the log lines, which can be job uids (or child
+// uids under the job uid) as well as the order ids.
+var uuidRe = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// JobLogIdleTimeout is the duration after which the log file of a job without
+// any new log lines is closed. File is reopened when the job logs again.
+var JobLogIdleTimeout = 10 * time.Minute
+
+type jobLog struct {
+	backend   *Backend
+	lastWrite time.Time
+}
+
+// JobsBackend is a log backend that copies the log lines of every tracked job
+// into a separate log file named after the job uid. Lines without a tracked
+// job uid are dropped, so it is meant to be used along with another backend
+// for all logs.
+type JobsBackend struct {
+	dirname string
+	limitMB int64
+
+	mu        sync.Mutex
+	jobs      map[string]*jobLog
+	lastEvict time.Time
+}
+
+// NewJobs returns a backend that writes per-job log files in the directory,
+// each limited to limitMB size.
+func NewJobs(dirname string, limitMB int64) (*JobsBackend, error) {
+	if limitMB <= 0 {
+		return nil, fmt.Errorf("log file size limit must be positive")
+	}
+	if err := os.MkdirAll(dirname, 0700); err != nil {
+		return nil, fmt.Errorf("could not create job logs directory: %w", err)
+	}
+	b := &JobsBackend{
+		dirname: dirname,
+		limitMB: limitMB,
+		jobs:    make(map[string]*jobLog),
+	}
+	return b, nil
+}
+
+func (b *JobsBackend) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, v := range b.jobs {
+		if v.backend != nil {
+			v.backend.Close()
+		}
+	}
+	b.jobs = make(map[string]*jobLog)
+}
+
+// Track starts copying the log lines of a job into it's log file.
+func (b *JobsBackend) Track(uid string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.jobs[uid]; !ok {
+		b.jobs[uid] = new(jobLog)
+	}
+}
+
+// Untrack stops copying the log lines of a job and closes it's log file.
+func (b *JobsBackend) Untrack(uid string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if v, ok := b.jobs[uid]; ok {
+		if v.backend != nil {
+			v.backend.Close()
+		}
+		delete(b.jobs, uid)
+	}
+}
+
+// Write copies the log lines with a tracked job uid into the job's log file.
+// It never fails, so that per-job logging problems do not affect the other
+// backends.
+func (b *JobsBackend) Write(data []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		for _, uid := range uuidRe.FindAll(line, -1) {
+			v, ok := b.jobs[string(uid)]
+			if !ok {
+				continue
+			}
+			if v.backend == nil {
+				backend, err := New(b.dirname, string(uid), b.limitMB)
+				if err != nil {
+					break
+				}
+				v.backend = backend
+			}
+			v.backend.Write(line)
+			v.lastWrite = now
+			break
+		}
+	}
+	b.evictIdleLocked(now)
+	return len(data), nil
+}
+
+// evictIdleLocked closes the log files of the jobs that haven't logged for
+// the JobLogIdleTimeout.
+func (b *JobsBackend) evictIdleLocked(now time.Time) {
+	if now.Sub(b.lastEvict) < JobLogIdleTimeout/2 {
+		return
+	}
+	b.lastEvict = now
+	for _, v := range b.jobs {
+		if v.backend != nil && now.Sub(v.lastWrite) >= JobLogIdleTimeout {
+			v.backend.Close()
+			v.backend = nil
+		}
+	}
+}
@@ -37,17 +37,27 @@ type Backend struct {
 
 	size int64
 
+	// limit is the max size for the log files in bytes.
+	limit int64
+
 	dirname, logname string
 }
 
-func New(dirname, logname string) (*Backend, error) {
-	fp, size, err := openFile(dirname, logname, FileNameReuseInterval)
+// New returns a log backend that writes to log files in the directory, each
+// limited to limitMB size. Callers can pass FileSizeLimitMB for the default.
+func New(dirname, logname string, limitMB int64) (*Backend, error) {
+	if limitMB <= 0 {
+		return nil, fmt.Errorf("log file size limit must be positive")
+	}
+	limit := limitMB * 1024 * 1024
+	fp, size, err := openFile(dirname, logname, FileNameReuseInterval, limit)
 	if err != nil {
 		return nil, fmt.Errorf("could not open log file: %w", err)
 	}
 	b := &Backend{
 		fp:      fp,
 		size:    size,
+		limit:   limit,
 		dirname: dirname,
 		logname: logname,
 	}
@@ -68,7 +78,7 @@ func fileName(logname string, at time.Time, truncate time.Duration) string {
 	return fmt.Sprintf("%s-%s.log", logname, uniq)
 }
 
-func openFile(dirname, logname string, truncate time.Duration) (*os.File, int64, error) {
+func openFile(dirname, logname string, truncate time.Duration, limit int64) (*os.File, int64, error) {
 	filename := fileName(logname, time.Now(), truncate)
 	fp, err := os.OpenFile(filepath.Join(dirname, filename), os.O_CREATE|os.O_WRONLY|os.O_APPEND, FileMode)
 	if err != nil {
@@ -80,16 +90,16 @@ func openFile(dirname, logname string, truncate time.Duration) (*os.File, int64,
 		return nil, -1, fmt.Errorf("could not get file size: %w", err)
 	}
 	size := finfo.Size()
-	if size >= FileSizeLimitMB*1024*1024 {
+	if size >= limit {
 		fp.Close()
-		return openFile(dirname, logname, 0)
+		return openFile(dirname, logname, 0, limit)
 	}
 	return fp, size, nil
 }
 
 func (b *Backend) Write(data []byte) (int, error) {
-	if b.size+int64(len(data)) > FileSizeLimitMB*1024*1024 {
-		fp, size, err := openFile(b.dirname, b.logname, FileNameReuseInterval)
+	if b.size+int64(len(data)) > b.limit {
+		fp, size, err := openFile(b.dirname, b.logname, FileNameReuseInterval, b.limit)
 		if err != nil {
 			return 0, fmt.Errorf("could not open new log file: %w", err)
 		}
@@ -1,8 +1,13 @@
 package logdir
 
 import (
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLogDir(t *testing.T) {
@@ -15,3 +20,148 @@ func TestLogDir(t *testing.T) {
 		log.Printf("hello world")
 	}
 }
+
+func TestLogDirSizeLimit(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := New(dir, "testlogdir", 0); err == nil {
+		t.Fatalf("want error for zero size limit")
+	}
+
+	b, err := New(dir, "testlogdir", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	line := []byte(strings.Repeat("x", 1023) + "\n")
+	for i := 0; i < 3*1024; i++ {
+		if _, err := b.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "testlogdir-*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("want 3 log files of 1MB each, got %v", files)
+	}
+	for _, file := range files {
+		finfo, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if finfo.Size() > 1024*1024 {
+			t.Fatalf("log file %s is larger than the 1MB limit", file)
+		}
+	}
+}
+
+func TestJobsBackend(t *testing.T) {
+	dir := t.TempDir()
+	b, err := NewJobs(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	uid := "2b1e3d1c-6f1a-4f5e-9a57-3b1f0f5e1a2b"
+	orderID := "8c0f1e9a-1d2b-4c3d-9e4f-5a6b7c8d9e0f"
+	b.Track(uid)
+
+	log := log.New(b, "", log.Flags())
+	log.Printf("%s/buy-000001:100: created order %s", uid, orderID)
+	log.Printf("a line without any job uid")
+	log.Printf("order %s is not a job", orderID)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || !strings.HasPrefix(filepath.Base(files[0]), uid) {
+		t.Fatalf("want one log file for the job, got %v", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "created order") || strings.Contains(string(data), "without") {
+		t.Fatalf("job log file has unexpected content %q", data)
+	}
+}
+
+func TestJobsBackendUntrack(t *testing.T) {
+	dir := t.TempDir()
+	b, err := NewJobs(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	uid := "5d8e2f4a-7b3c-4d1e-8f2a-9b0c1d2e3f4a"
+	log := log.New(b, "", log.Flags())
+	log.Printf("%s: not tracked yet", uid)
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.log")); len(files) != 0 {
+		t.Fatalf("want no log files for untracked job, got %v", files)
+	}
+
+	b.Track(uid)
+	log.Printf("%s: tracked", uid)
+	if v := b.jobs[uid]; v == nil || v.backend == nil {
+		t.Fatalf("want an open log file for the tracked job")
+	}
+
+	b.Untrack(uid)
+	if _, ok := b.jobs[uid]; ok {
+		t.Fatalf("want untracked job to be removed")
+	}
+	log.Printf("%s: untracked", uid)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("want one log file for the job, got %v", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(data); !strings.Contains(s, ": tracked") || strings.Contains(s, "untracked") || strings.Contains(s, "not tracked") {
+		t.Fatalf("job log file has unexpected content %q", data)
+	}
+}
+
+func TestJobsBackendEvictIdle(t *testing.T) {
+	b, err := NewJobs(t.TempDir(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	uid := "6e9f3a5b-8c4d-4e2f-9a3b-0c1d2e3f4a5b"
+	b.Track(uid)
+	fmt.Fprintf(b, "%s: hello\n", uid)
+	if b.jobs[uid].backend == nil {
+		t.Fatalf("want an open log file for the tracked job")
+	}
+
+	b.mu.Lock()
+	b.evictIdleLocked(time.Now().Add(JobLogIdleTimeout))
+	b.mu.Unlock()
+
+	v, ok := b.jobs[uid]
+	if !ok {
+		t.Fatalf("want idle job to be tracked still")
+	}
+	if v.backend != nil {
+		t.Fatalf("want idle job log file to be closed")
+	}
+
+	fmt.Fprintf(b, "%s: hello again\n", uid)
+	if b.jobs[uid].backend == nil {
+		t.Fatalf("want log file to be reopened on a new log line")
+	}
+}
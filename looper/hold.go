@@ -0,0 +1,38 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package looper
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/bvk/tradebot/limiter"
+)
+
+// HoldChild sets or clears the hold option on a child limiter, which is
+// identified by it's name (eg: buy-000003) or the full uid. A held limiter
+// cancels it's active order and doesn't create new orders, so the looper
+// doesn't advance past it until it is released.
+func (v *Looper) HoldChild(child string, hold bool) error {
+	l, err := v.findChild(child)
+	if err != nil {
+		return err
+	}
+	if err := l.SetOption("hold", strconv.FormatBool(hold)); err != nil {
+		return fmt.Errorf("could not update hold option on child %s: %w", l.UID(), err)
+	}
+	return nil
+}
+
+func (v *Looper) findChild(child string) (*limiter.Limiter, error) {
+	for _, ls := range [][]*limiter.Limiter{v.buys, v.sells} {
+		for _, l := range ls {
+			if l.UID() == child || path.Base(l.UID()) == child {
+				return l, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("looper %s has no child limiter %q: %w", v.uid, child, os.ErrNotExist)
+}
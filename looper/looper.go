@@ -13,6 +13,8 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/bvk/tradebot/gobs"
 	"github.com/bvk/tradebot/kvutil"
@@ -39,6 +41,79 @@ type Looper struct {
 
 	buys  []*limiter.Limiter
 	sells []*limiter.Limiter
+
+	optionMap map[string]string
+
+	// state holds the current State of the buy-sell cycle. It is read by the
+	// status requests while the job is running, so it needs to be an atomic.
+	state atomic.Int32
+
+	// profitTargetOpt when set and non-zero, contains the realized profit
+	// target after which no new buys are started. This option can be updated
+	// while the job is running, so it needs to be an atomic.
+	profitTargetOpt atomic.Pointer[decimal.Decimal]
+
+	// windDownOpt when true, stops starting new buys while the existing
+	// holdings are sold normally. Looper completes when all holdings are sold.
+	windDownOpt atomic.Bool
+
+	// staggerFlushOpt when true, is propagated to the child limiters so that
+	// their state flushes are spread across the flush interval.
+	staggerFlushOpt atomic.Bool
+
+	// skipInitialWaitOpt when true, places the first buy without waiting for
+	// the ticker to go above the buy price.
+	skipInitialWaitOpt atomic.Bool
+
+	// maxConsecutiveErrorsOpt when non-zero, contains the number of consecutive
+	// failures after which Run gives up and returns the last error.
+	maxConsecutiveErrorsOpt atomic.Int64
+
+	// noAutoResumeOpt when true, disables the retries with backoff on the
+	// transient exchange errors, which are then limited by the
+	// max-consecutive-errors option like the other errors.
+	noAutoResumeOpt atomic.Bool
+
+	// maxPositionOpt when set and non-zero, contains the max inventory size
+	// (bought minus sold) allowed, beyond which no new buys are started.
+	maxPositionOpt atomic.Pointer[decimal.Decimal]
+
+	// stopOnBudgetOpt when true, completes the looper instead of waiting when a
+	// new buy cannot be placed due to the budget or the insufficient funds.
+	stopOnBudgetOpt atomic.Bool
+
+	// minBuyIntervalOpt when non-zero, contains the minimum time.Duration
+	// between successive buy additions.
+	minBuyIntervalOpt atomic.Int64
+
+	// lastBuyTime holds the time when the most recent buy was added.
+	lastBuyTime time.Time
+
+	// budget when set and non-zero, contains the max cost of the unsold buys
+	// beyond which no new buys are started. It can be updated while the job is
+	// running, so it needs to be an atomic.
+	budget atomic.Pointer[decimal.Decimal]
+
+	// waitingForFunds is true when a limit-buy has failed due to insufficient
+	// funds. It is used to log the funding gap only once.
+	waitingForFunds bool
+
+	// waitingForPosition is true when a new buy is held back by the
+	// max-position option. It is used to log the cap only once.
+	waitingForPosition bool
+
+	// waitingForBudget is true when a new buy is held back by the budget. It is
+	// used to log the budget gap only once.
+	waitingForBudget bool
+
+	// budgetStopped is true when the budget or the funds are exhausted with the
+	// stop policy, after which no new buys are started and the looper completes
+	// once all holdings are sold.
+	budgetStopped bool
+
+	// waitingForHoldings is true when the completion is held back by the unsold
+	// holdings. It is used to log the holdings only once.
+	waitingForHoldings bool
 }
 
 var _ trader.Trader = &Looper{}
@@ -50,13 +125,39 @@ func New(uid, exchangeName, productID string, buy, sell *point.Point) (*Looper,
 		uid:          uid,
 		buyPoint:     *buy,
 		sellPoint:    *sell,
+		optionMap:    make(map[string]string),
 	}
 	if err := v.check(); err != nil {
 		return nil, err
 	}
+	if err := v.checkProfitable(NewLooperFeePct); err != nil {
+		return nil, err
+	}
+	v.setState(NeedBuy)
 	return v, nil
 }
 
+// NewLooperFeePct is the fee percentage used to verify that the sell price of
+// a new looper is above the buy price by at least the fees for both sides.
+// Check is disabled when it is negative. Existing loopers are not checked when
+// they are loaded.
+var NewLooperFeePct = limiter.EstimatedFeePct
+
+// checkProfitable returns an error if the sell price doesn't cover the buy
+// price and the buy and sell fees at the given fee percentage per unit size,
+// which would make every loop a loss.
+func (v *Looper) checkProfitable(feePct float64) error {
+	if feePct < 0 {
+		return nil
+	}
+	pct := decimal.NewFromFloat(feePct).Div(decimal.NewFromInt(100))
+	fees := v.buyPoint.Price.Add(v.sellPoint.Price).Mul(pct)
+	if margin := v.sellPoint.Price.Sub(v.buyPoint.Price); !margin.GreaterThan(fees) {
+		return fmt.Errorf("sell price %s must be above buy price %s by more than the fees %s at %v%% (sell-buy margin is %s)", v.sellPoint.Price, v.buyPoint.Price, fees.StringFixed(3), feePct, margin)
+	}
+	return nil
+}
+
 func (v *Looper) check() error {
 	if len(v.uid) == 0 {
 		return fmt.Errorf("looper uid is empty")
@@ -99,6 +200,46 @@ func (v *Looper) BudgetAt(feePct float64) decimal.Decimal {
 	return v.buyPoint.Value().Add(v.buyPoint.FeeAt(feePct))
 }
 
+// Budget returns the max cost of unsold buys allowed for the looper. Zero
+// value means the looper has no budget limit.
+func (v *Looper) Budget() decimal.Decimal {
+	if p := v.budget.Load(); p != nil {
+		return p.Copy()
+	}
+	return decimal.Zero
+}
+
+// SetBudget updates the max cost of unsold buys allowed for the looper. Zero
+// value removes the budget limit. Budget must be saved explicitly.
+func (v *Looper) SetBudget(budget decimal.Decimal) error {
+	if budget.IsNegative() {
+		return fmt.Errorf("looper budget cannot be -ve")
+	}
+	v.budget.Store(&budget)
+	return nil
+}
+
+// BudgetFootprint returns the quote funds reserved by the looper's buys that
+// are not complete yet.
+func (v *Looper) BudgetFootprint() decimal.Decimal {
+	var sum decimal.Decimal
+	for _, b := range v.buys {
+		sum = sum.Add(b.BudgetFootprint())
+	}
+	return sum
+}
+
+// isOverBudget returns true if a new buy would take the cost of the unsold
+// buys, including the funds reserved by the incomplete buys, beyond the
+// budget.
+func (v *Looper) isOverBudget() bool {
+	budget := v.Budget()
+	if budget.IsZero() {
+		return false
+	}
+	return v.UnsoldValue().Add(v.BudgetFootprint()).Add(v.buyPoint.Value()).GreaterThan(budget)
+}
+
 func (v *Looper) Actions() []*gobs.Action {
 	var actions []*gobs.Action
 	for _, b := range v.buys {
@@ -153,13 +294,90 @@ func (v *Looper) SoldValue() decimal.Decimal {
 	return sum
 }
 
+// UnsoldValue returns the value of the held size at the average entry price
+// of the buys.
 func (v *Looper) UnsoldValue() decimal.Decimal {
-	bsize := v.BoughtValue().Div(v.buyPoint.Price)
-	ssize := v.SoldValue().Div(v.sellPoint.Price)
-	if d := bsize.Sub(ssize); d.GreaterThan(decimal.Zero) {
-		return d.Mul(v.buyPoint.Price)
+	_, cost := v.Inventory()
+	return cost
+}
+
+// AvgEntryPrice returns the size-weighted average fill price across all buys
+// of the looper. Returns zero when nothing is bought.
+func (v *Looper) AvgEntryPrice() decimal.Decimal {
+	var bsize, bvalue decimal.Decimal
+	for _, b := range v.buys {
+		bsize = bsize.Add(b.FilledSize())
+		bvalue = bvalue.Add(b.FilledValue())
 	}
-	return decimal.Zero
+	if bsize.IsZero() {
+		return decimal.Zero
+	}
+	return bvalue.Div(bsize)
+}
+
+// BreakEvenPrice returns the sell price at which the held size can be sold
+// without a loss after paying the buy and sell fees at the given percentage.
+// Returns zero when nothing is held.
+func (v *Looper) BreakEvenPrice(feePct float64) decimal.Decimal {
+	size, _ := v.Inventory()
+	if size.IsZero() {
+		return decimal.Zero
+	}
+	pct := decimal.NewFromFloat(feePct).Div(decimal.NewFromInt(100))
+	one := decimal.NewFromInt(1)
+	return v.AvgEntryPrice().Mul(one.Add(pct)).Div(one.Sub(pct))
+}
+
+// Inventory returns the base size held by the looper, i.e., filled buy size
+// that is not sold yet, and it's cost at the average entry price of the
+// looper's buys. Fees are not included in the cost.
+func (v *Looper) Inventory() (size, cost decimal.Decimal) {
+	size = v.holdings()
+	if !size.IsPositive() {
+		return decimal.Zero, decimal.Zero
+	}
+	return size, size.Mul(v.AvgEntryPrice())
+}
+
+// RealizedProfit returns the sum of profits from the completed buy-sell pairs
+// after subtracting the fees. It is computed from the child limiters, so it is
+// not saved separately and survives the restarts with the limiters.
+func (v *Looper) RealizedProfit() decimal.Decimal {
+	var sum decimal.Decimal
+	for i, s := range v.sells {
+		if i >= len(v.buys) || !s.PendingSize().IsZero() {
+			continue
+		}
+		b := v.buys[i]
+		fees := s.Fees().Add(b.Fees())
+		sum = sum.Add(s.SoldValue().Sub(b.BoughtValue()).Sub(fees))
+	}
+	return sum
+}
+
+// LiveOrders returns the active orders of all child limiters.
+func (v *Looper) LiveOrders() []*limiter.LiveOrder {
+	var orders []*limiter.LiveOrder
+	for _, b := range v.buys {
+		orders = append(orders, b.LiveOrders()...)
+	}
+	for _, s := range v.sells {
+		orders = append(orders, s.LiveOrders()...)
+	}
+	return orders
+}
+
+// FillLatency returns the combined fill latency statistics of all child
+// limiters.
+func (v *Looper) FillLatency() *limiter.FillLatency {
+	stats := new(limiter.FillLatency)
+	for _, b := range v.buys {
+		stats.Add(b.FillLatency())
+	}
+	for _, s := range v.sells {
+		stats.Add(s.FillLatency())
+	}
+	return stats
 }
 
 func (v *Looper) Save(ctx context.Context, rw kv.ReadWriter) error {
@@ -186,13 +404,19 @@ func (v *Looper) Save(ctx context.Context, rw kv.ReadWriter) error {
 					Size:   v.buyPoint.Size,
 					Price:  v.buyPoint.Price,
 					Cancel: v.buyPoint.Cancel,
+					Tag:    v.buyPoint.Tag,
 				},
 				Sell: gobs.Point{
 					Size:   v.sellPoint.Size,
 					Price:  v.sellPoint.Price,
 					Cancel: v.sellPoint.Cancel,
+					Tag:    v.sellPoint.Tag,
 				},
 			},
+			Options:     v.optionMap,
+			State:       v.State().String(),
+			Budget:      v.Budget(),
+			LastBuyTime: v.lastBuyTime,
 		},
 	}
 	if !slices.IsSorted(gv.V2.LimiterIDs) {
@@ -228,20 +452,38 @@ func cleanUID(uid string) string {
 }
 
 func Load(ctx context.Context, uid string, r kv.Reader) (*Looper, error) {
+	v, _, err := load(ctx, uid, r, false /* skipCorrupt */)
+	return v, err
+}
+
+// LoadSkipCorrupt is similar to Load, but skips the child limiters that cannot
+// be loaded and returns their uids, so that the rest of the looper can be
+// recovered. Returned looper is meant for inspection only; it must not be
+// saved or run because the skipped limiters would be lost.
+func LoadSkipCorrupt(ctx context.Context, uid string, r kv.Reader) (*Looper, []string, error) {
+	return load(ctx, uid, r, true /* skipCorrupt */)
+}
+
+func load(ctx context.Context, uid string, r kv.Reader, skipCorrupt bool) (_ *Looper, skipped []string, _ error) {
 	if err := checkUID(uid); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	key := path.Join(DefaultKeyspace, uid)
 	gv, err := kvutil.Get[gobs.LooperState](ctx, r, key)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	gv.Upgrade()
 	var buys, sells []*limiter.Limiter
 	for _, id := range gv.V2.LimiterIDs {
 		v, err := limiter.Load(ctx, cleanUID(id), r)
 		if err != nil {
-			return nil, err
+			if skipCorrupt && ctx.Err() == nil {
+				log.Printf("%s: skipping child limiter %s that could not be loaded: %v", uid, id, err)
+				skipped = append(skipped, cleanUID(id))
+				continue
+			}
+			return nil, nil, err
 		}
 		if v.IsBuy() {
 			buys = append(buys, v)
@@ -260,15 +502,36 @@ func Load(ctx context.Context, uid string, r kv.Reader) (*Looper, error) {
 			Size:   gv.V2.TradePair.Buy.Size,
 			Price:  gv.V2.TradePair.Buy.Price,
 			Cancel: gv.V2.TradePair.Buy.Cancel,
+			Tag:    gv.V2.TradePair.Buy.Tag,
 		},
 		sellPoint: point.Point{
 			Size:   gv.V2.TradePair.Sell.Size,
 			Price:  gv.V2.TradePair.Sell.Price,
 			Cancel: gv.V2.TradePair.Sell.Cancel,
+			Tag:    gv.V2.TradePair.Sell.Tag,
 		},
+		optionMap:   make(map[string]string),
+		lastBuyTime: gv.V2.LastBuyTime,
 	}
 	if err := v.check(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return v, nil
+	if err := v.SetBudget(gv.V2.Budget); err != nil {
+		return nil, nil, err
+	}
+	if len(gv.V2.State) == 0 {
+		v.setState(v.inferState())
+	} else {
+		state, err := parseState(gv.V2.State)
+		if err != nil {
+			return nil, nil, err
+		}
+		v.setState(state)
+	}
+	for opt, val := range gv.V2.Options {
+		if err := v.SetOption(opt, val); err != nil {
+			return nil, nil, fmt.Errorf("could not set options: %v", err)
+		}
+	}
+	return v, skipped, nil
 }
@@ -0,0 +1,1280 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package looper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/kvtest"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/paper"
+	"github.com/bvk/tradebot/point"
+	"github.com/bvk/tradebot/timerange"
+	"github.com/bvk/tradebot/trader"
+	"github.com/bvkgo/kv"
+	"github.com/bvkgo/kv/kvmemdb"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestLooperSaveLoad(t *testing.T) {
+	ctx := context.Background()
+	db := kvtest.New()
+
+	buy := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	sell := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("120"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l1, err := New(uid, "coinbase", "BTC-USD", buy, sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		b, err := limiter.New(path.Join(uid, fmt.Sprintf("buy-%06d", i)), "coinbase", "BTC-USD", buy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l1.buys = append(l1.buys, b)
+	}
+	s, err := limiter.New(path.Join(uid, fmt.Sprintf("sell-%06d", 0)), "coinbase", "BTC-USD", sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l1.sells = append(l1.sells, s)
+	if err := l1.SetOption("profit-target", "100"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := kv.WithReadWriter(ctx, db, l1.Save); err != nil {
+		t.Fatal(err)
+	}
+
+	var l2 *Looper
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		l2, err = Load(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+
+	if p1, p2 := l1.Pair(), l2.Pair(); !p1.Equal(p2) {
+		t.Fatalf("pair: want %v, got %v", p1, p2)
+	}
+	if len(l2.buys) != 2 || len(l2.sells) != 1 {
+		t.Fatalf("want 2 buys and 1 sell, got %d buys and %d sells", len(l2.buys), len(l2.sells))
+	}
+	for i := range l1.buys {
+		if a, b := l1.buys[i].UID(), l2.buys[i].UID(); a != b {
+			t.Fatalf("buy %d: want %s, got %s", i, a, b)
+		}
+	}
+	if a, b := l1.sells[0].UID(), l2.sells[0].UID(); a != b {
+		t.Fatalf("sell: want %s, got %s", a, b)
+	}
+	if a, _ := l2.profitTarget(); !a.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("profit target: want 100, got %s", a)
+	}
+	if s1, s2 := l1.Status(nil), l2.Status(nil); !s1.Budget.Equal(s2.Budget) {
+		t.Fatalf("budget: want %s, got %s", s1.Budget, s2.Budget)
+	}
+
+	// Loaded looper must save the same keys and values, including the child
+	// limiters.
+	snap1, err := kvtest.Take(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db2 := kvtest.New()
+	if err := kv.WithReadWriter(ctx, db2, l2.Save); err != nil {
+		t.Fatal(err)
+	}
+	snap2, err := kvtest.Take(ctx, db2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap1) != 4 {
+		t.Fatalf("want looper and 3 child limiter keys, got %v", snap1.Keys())
+	}
+	if diffs := kvtest.Diff(snap1, snap2); len(diffs) != 0 {
+		t.Fatalf("want no differences after a round-trip, got %v", diffs)
+	}
+}
+
+func TestLooperNextIndex(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	buy := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	sell := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("120"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l1, err := New(uid, "coinbase", "BTC-USD", buy, sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Create buys with a gap in the indices.
+	for _, i := range []int{0, 2} {
+		b, err := limiter.New(path.Join(uid, fmt.Sprintf("buy-%06d", i)), "coinbase", "BTC-USD", buy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l1.buys = append(l1.buys, b)
+	}
+	if err := kv.WithReadWriter(ctx, db, l1.Save); err != nil {
+		t.Fatal(err)
+	}
+
+	var l2 *Looper
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		l2, err = Load(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := nextIndex(l2.buys, "buy-"); n != 3 {
+		t.Fatalf("next buy index: want 3, got %d", n)
+	}
+	if n := nextIndex(l2.sells, "sell-"); n != 0 {
+		t.Fatalf("next sell index: want 0, got %d", n)
+	}
+}
+
+func TestLooperLoadSkipCorrupt(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	buy := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	sell := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("120"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l1, err := New(uid, "coinbase", "BTC-USD", buy, sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		b, err := limiter.New(path.Join(uid, fmt.Sprintf("buy-%06d", i)), "coinbase", "BTC-USD", buy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l1.buys = append(l1.buys, b)
+	}
+	if err := kv.WithReadWriter(ctx, db, l1.Save); err != nil {
+		t.Fatal(err)
+	}
+
+	corruptUID := l1.buys[1].UID()
+	corrupt := func(ctx context.Context, rw kv.ReadWriter) error {
+		return rw.Set(ctx, path.Join(limiter.DefaultKeyspace, corruptUID), strings.NewReader("corrupt"))
+	}
+	if err := kv.WithReadWriter(ctx, db, corrupt); err != nil {
+		t.Fatal(err)
+	}
+
+	load := func(ctx context.Context, r kv.Reader) error {
+		_, err := Load(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err == nil {
+		t.Fatalf("want load to fail with a corrupt child limiter")
+	}
+
+	var l2 *Looper
+	var skipped []string
+	loadSkip := func(ctx context.Context, r kv.Reader) (err error) {
+		l2, skipped, err = LoadSkipCorrupt(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, loadSkip); err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 1 || skipped[0] != corruptUID {
+		t.Fatalf("want skipped uid %s, got %v", corruptUID, skipped)
+	}
+	if len(l2.buys) != 1 || l2.buys[0].UID() != l1.buys[0].UID() {
+		t.Fatalf("want one recovered buy limiter, got %d", len(l2.buys))
+	}
+}
+
+func TestLooperResumeState(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	buy := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	sell := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("120"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l1, err := New(uid, "coinbase", "BTC-USD", buy, sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reload := func() *Looper {
+		if err := kv.WithReadWriter(ctx, db, l1.Save); err != nil {
+			t.Fatal(err)
+		}
+		var l2 *Looper
+		load := func(ctx context.Context, r kv.Reader) (err error) {
+			l2, err = Load(ctx, uid, r)
+			return err
+		}
+		if err := kv.WithReader(ctx, db, load); err != nil {
+			t.Fatal(err)
+		}
+		return l2
+	}
+
+	if s := reload().resumeState(); s != NeedBuy {
+		t.Fatalf("new looper: want %s, got %s", NeedBuy, s)
+	}
+
+	b, err := limiter.New(path.Join(uid, "buy-000000"), "coinbase", "BTC-USD", buy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l1.buys = append(l1.buys, b)
+	l1.setState(RunningBuy)
+	if s := reload().resumeState(); s != RunningBuy {
+		t.Fatalf("running buy: want %s, got %s", RunningBuy, s)
+	}
+
+	l1.setState(NeedSell)
+	if s := reload().resumeState(); s != NeedSell {
+		t.Fatalf("need sell: want %s, got %s", NeedSell, s)
+	}
+
+	sl, err := limiter.New(path.Join(uid, "sell-000000"), "coinbase", "BTC-USD", sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l1.sells = append(l1.sells, sl)
+	l1.setState(RunningSell)
+	if s := reload().resumeState(); s != RunningSell {
+		t.Fatalf("running sell: want %s, got %s", RunningSell, s)
+	}
+
+	// Loopers saved without a state infer it from the last limiters.
+	if s := l1.inferState(); s != RunningSell {
+		t.Fatalf("inferred with pending sell: want %s, got %s", RunningSell, s)
+	}
+	if err := sl.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	if s := l1.inferState(); s != RunningBuy {
+		t.Fatalf("inferred with pending buy: want %s, got %s", RunningBuy, s)
+	}
+	if err := b.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	if s := l1.inferState(); s != NeedBuy {
+		t.Fatalf("inferred with no pending limiters: want %s, got %s", NeedBuy, s)
+	}
+
+	// Running states without a limiter are not resumed.
+	l1.sells = nil
+	l1.setState(RunningSell)
+	if s := l1.resumeState(); s != NeedBuy {
+		t.Fatalf("running sell without sells: want %s, got %s", NeedBuy, s)
+	}
+}
+
+func TestLooperSettledState(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	buy := &point.Point{
+		Size:   decimal.RequireFromString("2"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	sell := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("120"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l, err := New(uid, "paper", "BTC-USD", buy, sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := l.settledState(); s != NeedBuy {
+		t.Fatalf("no holdings: want %s, got %s", NeedBuy, s)
+	}
+
+	// Holding size 1 is enough for the sell size 1, but buys are decided by
+	// the buy size, so a partially filled buy is topped up first.
+	l.buys = append(l.buys, newFilledLimiter(ctx, t, db, path.Join(uid, "buy-000000"), buy, "1"))
+	if s := l.settledState(); s != NeedBuy {
+		t.Fatalf("holding less than the buy size: want %s, got %s", NeedBuy, s)
+	}
+
+	l.buys = append(l.buys, newFilledLimiter(ctx, t, db, path.Join(uid, "buy-000001"), buy, "1"))
+	if s := l.settledState(); s != NeedSell {
+		t.Fatalf("holding the buy size: want %s, got %s", NeedSell, s)
+	}
+
+	// Holdings enough for a sell are sold when no new buys are started.
+	l.buys = l.buys[:1]
+	l.setState(NeedBuy)
+	if err := l.SetOption("wind-down", "true"); err != nil {
+		t.Fatal(err)
+	}
+	rt := &trader.Runtime{Database: db, Product: paper.New("BTC-USD", nil)}
+	tctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := l.Run(tctx, rt); err == nil {
+		t.Fatalf("winding down looper with holdings must only stop with the context")
+	}
+	if n := len(l.sells); n != 1 {
+		t.Fatalf("want a sell for the holdings when winding down, got %d sells", n)
+	}
+}
+
+func TestLooperRepair(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	buy := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	sell := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("120"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l, err := New(uid, "coinbase", "BTC-USD", buy, sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		b, err := limiter.New(path.Join(uid, fmt.Sprintf("buy-%06d", i)), "coinbase", "BTC-USD", buy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.buys = append(l.buys, b)
+	}
+	if err := kv.WithReadWriter(ctx, db, l.Save); err != nil {
+		t.Fatal(err)
+	}
+
+	// Drop the first buy from the limiters keyspace and add a sell limiter
+	// that is not referenced by the looper.
+	s, err := limiter.New(path.Join(uid, "sell-000000"), "coinbase", "BTC-USD", sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	surgery := func(ctx context.Context, rw kv.ReadWriter) error {
+		if err := rw.Delete(ctx, path.Join(limiter.DefaultKeyspace, uid, "buy-000000")); err != nil {
+			return err
+		}
+		return s.Save(ctx, rw)
+	}
+	if err := kv.WithReadWriter(ctx, db, surgery); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, write := range []bool{false, true} {
+		var added, dropped []string
+		repair := func(ctx context.Context, rw kv.ReadWriter) (err error) {
+			added, dropped, err = Repair(ctx, uid, rw, write)
+			return err
+		}
+		if err := kv.WithReadWriter(ctx, db, repair); err != nil {
+			t.Fatal(err)
+		}
+		if len(added) != 1 || added[0] != s.UID() {
+			t.Fatalf("write=%t: want added %s, got %v", write, s.UID(), added)
+		}
+		if len(dropped) != 1 || dropped[0] != path.Join(uid, "buy-000000") {
+			t.Fatalf("write=%t: want dropped buy-000000, got %v", write, dropped)
+		}
+	}
+
+	var l2 *Looper
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		l2, err = Load(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+	if len(l2.buys) != 1 || len(l2.sells) != 1 {
+		t.Fatalf("want 1 buy and 1 sell, got %d buys and %d sells", len(l2.buys), len(l2.sells))
+	}
+}
+
+// newFilledLimiter returns a limiter with a single completed order that is
+// filled for the input size.
+func newFilledLimiter(ctx context.Context, t *testing.T, db kv.Database, uid string, p *point.Point, filled string) *limiter.Limiter {
+	return newFilledLimiterAt(ctx, t, db, uid, p, filled, time.Time{})
+}
+
+// newFilledLimiterAt is similar to newFilledLimiter, but the order is created
+// and finished at the input time.
+func newFilledLimiterAt(ctx context.Context, t *testing.T, db kv.Database, uid string, p *point.Point, filled string, at time.Time) *limiter.Limiter {
+	l, err := limiter.New(uid, "paper", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kv.WithReadWriter(ctx, db, l.Save); err != nil {
+		t.Fatal(err)
+	}
+	key := path.Join(limiter.DefaultKeyspace, uid)
+	gv, err := kvutil.GetDB[gobs.LimiterState](ctx, db, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gv.V2.ServerIDOrderMap = map[string]*gobs.Order{
+		uid: {
+			ServerOrderID: uid,
+			ClientOrderID: uuid.NewString(),
+			Side:          p.Side(),
+			Status:        "FILLED",
+			FilledSize:    decimal.RequireFromString(filled),
+			FilledPrice:   p.Price,
+			CreateTime:    gobs.RemoteTime{Time: at},
+			FinishTime:    gobs.RemoteTime{Time: at},
+			Done:          true,
+		},
+	}
+	if err := kvutil.SetDB(ctx, db, key, gv); err != nil {
+		t.Fatal(err)
+	}
+	var v *limiter.Limiter
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		v, err = limiter.Load(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestLooperMaxPosition(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	buy := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	sell := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("120"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l, err := New(uid, "paper", "BTC-USD", buy, sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two buys are filled, but the second sell is completed with only half of
+	// it's size filled, so half of the bought size is still held.
+	l.buys = append(l.buys,
+		newFilledLimiter(ctx, t, db, path.Join(uid, "buy-000000"), buy, "1"),
+		newFilledLimiter(ctx, t, db, path.Join(uid, "buy-000001"), buy, "1"))
+	s1 := newFilledLimiter(ctx, t, db, path.Join(uid, "sell-000001"), sell, "0.5")
+	if err := s1.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	l.sells = append(l.sells,
+		newFilledLimiter(ctx, t, db, path.Join(uid, "sell-000000"), sell, "1"), s1)
+	l.setState(l.settledState())
+	if s := l.State(); s != NeedBuy {
+		t.Fatalf("want %s, got %s", NeedBuy, s)
+	}
+
+	if l.isPositionCapped() {
+		t.Fatalf("buys must not be capped without the max-position option")
+	}
+	if err := l.SetOption("max-position", "1.2"); err != nil {
+		t.Fatal(err)
+	}
+	if !l.isPositionCapped() {
+		t.Fatalf("holding 0.5 with a buy of 1 must be capped by max-position 1.2")
+	}
+
+	// Option must be persisted.
+	if err := kv.WithReadWriter(ctx, db, l.Save); err != nil {
+		t.Fatal(err)
+	}
+	var l2 *Looper
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		l2, err = Load(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+	if v := l2.Config()["max-position"]; v != "1.2" {
+		t.Fatalf("want max-position 1.2 after reload, got %q", v)
+	}
+
+	// Run must not start any new buys while capped.
+	defer func(d time.Duration) { MaxPositionBackoff = d }(MaxPositionBackoff)
+	MaxPositionBackoff = 10 * time.Millisecond
+
+	rt := &trader.Runtime{Database: db, Product: paper.New("BTC-USD", nil)}
+	tctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := l2.Run(tctx, rt); err == nil {
+		t.Fatalf("capped looper run must only stop with the context")
+	}
+	if n := len(l2.buys); n != 2 {
+		t.Fatalf("want no new buys when capped, got %d buys", n)
+	}
+
+	if err := l2.SetOption("max-position", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if l2.isPositionCapped() {
+		t.Fatalf("holding 0.5 with a buy of 1 must not be capped by max-position 2")
+	}
+}
+
+func TestLooperBudget(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	buy := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	sell := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("120"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l, err := New(uid, "paper", "BTC-USD", buy, sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetBudget(decimal.NewFromInt(-1)); err == nil {
+		t.Fatalf("negative budget must be rejected")
+	}
+
+	// One buy is filled and not sold yet, so unsold cost is 100.
+	l.buys = append(l.buys, newFilledLimiter(ctx, t, db, path.Join(uid, "buy-000000"), buy, "1"))
+	l.setState(NeedBuy)
+
+	if l.isOverBudget() {
+		t.Fatalf("buys must not be limited without a budget")
+	}
+	if err := l.SetBudget(decimal.NewFromInt(150)); err != nil {
+		t.Fatal(err)
+	}
+	if !l.isOverBudget() {
+		t.Fatalf("unsold cost 100 with a buy of 100 must be over budget 150")
+	}
+
+	// Budget must be persisted.
+	if err := kv.WithReadWriter(ctx, db, l.Save); err != nil {
+		t.Fatal(err)
+	}
+	var l2 *Looper
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		l2, err = Load(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+	if b := l2.Budget(); !b.Equal(decimal.NewFromInt(150)) {
+		t.Fatalf("want budget 150 after reload, got %s", b)
+	}
+
+	// Run must not start any new buys while over budget.
+	defer func(d time.Duration) { BudgetBackoff = d }(BudgetBackoff)
+	BudgetBackoff = 10 * time.Millisecond
+
+	rt := &trader.Runtime{Database: db, Product: paper.New("BTC-USD", nil)}
+	tctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := l2.Run(tctx, rt); err == nil {
+		t.Fatalf("over budget looper run must only stop with the context")
+	}
+	if n := len(l2.buys); n != 1 {
+		t.Fatalf("want no new buys when over budget, got %d buys", n)
+	}
+
+	// Run must complete when over budget with the stop policy.
+	if err := l2.SetOption("budget-exhausted-policy", "pause"); err == nil {
+		t.Fatalf("invalid budget-exhausted-policy must be rejected")
+	}
+	if err := l2.SetOption("budget-exhausted-policy", BudgetExhaustedStop); err != nil {
+		t.Fatal(err)
+	}
+
+	// Run must not complete while the bought size is unsold, but must start a
+	// sell for it.
+	uctx, ucancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer ucancel()
+	if err := l2.Run(uctx, rt); err == nil {
+		t.Fatalf("over budget looper with unsold holdings must only stop with the context")
+	}
+	if n := len(l2.buys); n != 1 {
+		t.Fatalf("want no new buys with the stop policy, got %d buys", n)
+	}
+	if n := len(l2.sells); n != 1 || l2.State() != RunningSell {
+		t.Fatalf("want a running sell for the holdings, got %d sells in state %s", n, l2.State())
+	}
+
+	// Run must complete once the holdings are sold and the budget is still
+	// exhausted.
+	l2.sells = []*limiter.Limiter{newFilledLimiter(ctx, t, db, path.Join(uid, "sell-000000"), sell, "1")}
+	l2.setState(NeedBuy)
+	if err := l2.SetBudget(decimal.NewFromInt(50)); err != nil {
+		t.Fatal(err)
+	}
+	sctx, scancel := context.WithTimeout(ctx, time.Second)
+	defer scancel()
+	if err := l2.Run(sctx, rt); err != nil {
+		t.Fatalf("over budget looper with stop policy must complete, got %v", err)
+	}
+	if sctx.Err() != nil {
+		t.Fatalf("over budget looper with stop policy must not wait after the holdings are sold")
+	}
+
+	if err := l2.SetBudget(decimal.NewFromInt(200)); err != nil {
+		t.Fatal(err)
+	}
+	if l2.isOverBudget() {
+		t.Fatalf("no unsold cost with a buy of 100 must be within budget 200")
+	}
+}
+
+// noFundsProduct reports a zero available balance for all currencies and
+// sends a few tickers at the input price to every subscriber.
+type noFundsProduct struct {
+	*paper.Product
+
+	price decimal.Decimal
+}
+
+func (p *noFundsProduct) AvailableBalance(ctx context.Context, currency string) (decimal.Decimal, error) {
+	return decimal.Zero, nil
+}
+
+func (p *noFundsProduct) TickerCh() (<-chan *exchange.Ticker, func()) {
+	ch := make(chan *exchange.Ticker, 10)
+	for i := 0; i < cap(ch); i++ {
+		ch <- &exchange.Ticker{Timestamp: exchange.RemoteTime{Time: time.Now()}, Price: p.price}
+	}
+	return ch, func() {}
+}
+
+func TestLooperInsufficientFunds(t *testing.T) {
+	defer func(d time.Duration) { InsufficientFundsBackoff = d }(InsufficientFundsBackoff)
+	InsufficientFundsBackoff = 10 * time.Millisecond
+	defer func(d time.Duration) { BudgetBackoff = d }(BudgetBackoff)
+	BudgetBackoff = 10 * time.Millisecond
+
+	buy := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	sell := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("120"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+
+	// Looper must complete without holdings when the buy cannot be placed with
+	// the stop policy, and the failed buy must be force-completed.
+	{
+		ctx := context.Background()
+		db := kvmemdb.New()
+		l, err := New(uuid.NewString(), "paper", "BTC-USD", buy, sell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.SetOption("budget-exhausted-policy", BudgetExhaustedStop); err != nil {
+			t.Fatal(err)
+		}
+		rt := &trader.Runtime{Database: db, Product: &noFundsProduct{Product: paper.New("BTC-USD", nil), price: decimal.RequireFromString("105")}}
+		tctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		if err := l.Run(tctx, rt); err != nil {
+			t.Fatalf("looper without funds and holdings must complete, got %v", err)
+		}
+		if n := len(l.buys); n != 1 {
+			t.Fatalf("want one failed buy, got %d buys", n)
+		}
+		if p := l.buys[0].PendingSize(); !p.IsZero() {
+			t.Fatalf("failed buy must be force-completed, got pending size %s", p)
+		}
+	}
+
+	// Looper must not complete while the bought size is unsold.
+	{
+		ctx := context.Background()
+		db := kvmemdb.New()
+		uid := uuid.NewString()
+		l, err := New(uid, "paper", "BTC-USD", buy, sell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.SetOption("budget-exhausted-policy", BudgetExhaustedStop); err != nil {
+			t.Fatal(err)
+		}
+		l.buys = append(l.buys, newFilledLimiter(ctx, t, db, path.Join(uid, "buy-000000"), buy, "1"))
+		l.setState(NeedBuy)
+
+		rt := &trader.Runtime{Database: db, Product: &noFundsProduct{Product: paper.New("BTC-USD", nil), price: decimal.RequireFromString("105")}}
+		tctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+		if err := l.Run(tctx, rt); err == nil {
+			t.Fatalf("looper with unsold holdings must only stop with the context")
+		}
+		if n := len(l.buys); n != 2 {
+			t.Fatalf("want one more failed buy, got %d buys", n)
+		}
+		if p := l.buys[1].PendingSize(); !p.IsZero() {
+			t.Fatalf("failed buy must be force-completed, got pending size %s", p)
+		}
+		if h := l.holdings(); !h.Equal(decimal.NewFromInt(1)) {
+			t.Fatalf("want holding size 1, got %s", h)
+		}
+	}
+}
+
+func TestLooperSplit(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	pair := func(buyPrice, sellPrice string) (*point.Point, *point.Point) {
+		buy := &point.Point{
+			Size:   decimal.RequireFromString("1"),
+			Price:  decimal.RequireFromString(buyPrice),
+			Cancel: decimal.RequireFromString(sellPrice),
+		}
+		sell := &point.Point{
+			Size:   decimal.RequireFromString("1"),
+			Price:  decimal.RequireFromString(sellPrice),
+			Cancel: decimal.RequireFromString(buyPrice),
+		}
+		return buy, sell
+	}
+	lowBuy, lowSell := pair("100", "110")
+	highBuy, highSell := pair("200", "210")
+
+	uid := uuid.NewString()
+	l, err := New(uid, "paper", "BTC-USD", highBuy, highSell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.buys = append(l.buys,
+		newFilledLimiter(ctx, t, db, path.Join(uid, "buy-000000"), lowBuy, "1"),
+		newFilledLimiter(ctx, t, db, path.Join(uid, "buy-000001"), highBuy, "1"))
+	l.sells = append(l.sells,
+		newFilledLimiter(ctx, t, db, path.Join(uid, "sell-000000"), lowSell, "1"),
+		newFilledLimiter(ctx, t, db, path.Join(uid, "sell-000001"), highSell, "1"))
+	l.setState(NeedBuy)
+	if err := kv.WithReadWriter(ctx, db, l.Save); err != nil {
+		t.Fatal(err)
+	}
+	trailKey := path.Join(limiter.TrailKeyspace, uid, "buy-000001", fmt.Sprintf("%020d", 1))
+	if err := kv.WithReadWriter(ctx, db, func(ctx context.Context, rw kv.ReadWriter) error {
+		return kvutil.Set(ctx, rw, trailKey, &gobs.LimiterTrailEntry{Action: "create"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	newUID := uuid.NewString()
+	split := func(pivot string) func(context.Context, kv.ReadWriter) error {
+		return func(ctx context.Context, rw kv.ReadWriter) error {
+			_, err := Split(ctx, rw, uid, decimal.RequireFromString(pivot), newUID)
+			return err
+		}
+	}
+	if err := kv.WithReadWriter(ctx, db, split("300")); err == nil {
+		t.Fatalf("split with nothing to move must fail")
+	}
+	if err := kv.WithReadWriter(ctx, db, split("150")); err != nil {
+		t.Fatal(err)
+	}
+
+	var src, dst *Looper
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		if src, err = Load(ctx, uid, r); err != nil {
+			return err
+		}
+		dst, err = Load(ctx, newUID, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+	if len(src.buys) != 1 || len(src.sells) != 1 || !src.buys[0].Point().Price.Equal(lowBuy.Price) {
+		t.Fatalf("source looper must keep only the low cycle")
+	}
+	if len(dst.buys) != 1 || len(dst.sells) != 1 {
+		t.Fatalf("new looper must have the high cycle")
+	}
+	if want := path.Join(newUID, "buy-000001"); dst.buys[0].UID() != want {
+		t.Fatalf("want moved buy uid %s, got %s", want, dst.buys[0].UID())
+	}
+	if !dst.buys[0].FilledSize().Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("moved buy must keep it's fills")
+	}
+	if p := dst.Pair(); !p.Buy.Price.Equal(highBuy.Price) || !p.Sell.Price.Equal(highSell.Price) {
+		t.Fatalf("new looper must trade at the high cycle points, got %v", p)
+	}
+
+	// Source looper must not trade at the moved points, so that the price
+	// ranges of the loopers do not overlap.
+	if p := src.Pair(); !p.Buy.Price.Equal(lowBuy.Price) || !p.Sell.Price.Equal(lowSell.Price) {
+		t.Fatalf("source looper must trade at the low cycle points, got %v", p)
+	}
+	if sp, dp := src.Pair(), dst.Pair(); !sp.Sell.Price.LessThan(dp.Buy.Price) {
+		t.Fatalf("source range %v overlaps with the new looper range %v", sp, dp)
+	}
+
+	// Audit trail entries are moved along with the child limiters.
+	var trail []*gobs.LimiterTrailEntry
+	if err := kv.WithReader(ctx, db, func(ctx context.Context, r kv.Reader) (err error) {
+		if _, err := r.Get(ctx, trailKey); !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("old trail key must be removed: %v", err)
+		}
+		trail, err = limiter.LoadTrail(ctx, r, dst.buys[0].UID())
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(trail) != 1 || trail[0].Action != "create" {
+		t.Fatalf("want the trail entry under the moved limiter, got %v", trail)
+	}
+}
+
+// errorProduct fails all order fetches with the injected error.
+type errorProduct struct {
+	*paper.Product
+
+	err   error
+	ngets atomic.Int32
+}
+
+func (p *errorProduct) Get(ctx context.Context, id exchange.OrderID) (*exchange.Order, error) {
+	p.ngets.Add(1)
+	return nil, p.err
+}
+
+func TestLooperErrorClasses(t *testing.T) {
+	defer func(d time.Duration) { TransientBackoff = d }(TransientBackoff)
+	TransientBackoff = 5 * time.Millisecond
+
+	buy := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	sell := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("120"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+
+	// newRunningLooper returns a looper with a running buy, whose live order
+	// is fetched from the product when the buy is resumed.
+	newRunningLooper := func(ctx context.Context, t *testing.T, db kv.Database) *Looper {
+		uid := uuid.NewString()
+		l, err := New(uid, "paper", "BTC-USD", buy, sell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := newFilledLimiter(ctx, t, db, path.Join(uid, "buy-000000"), buy, "0")
+		key := path.Join(limiter.DefaultKeyspace, b.UID())
+		gv, err := kvutil.GetDB[gobs.LimiterState](ctx, db, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, order := range gv.V2.ServerIDOrderMap {
+			order.Status, order.Done = "OPEN", false
+		}
+		if err := kvutil.SetDB(ctx, db, key, gv); err != nil {
+			t.Fatal(err)
+		}
+		load := func(ctx context.Context, r kv.Reader) (err error) {
+			b, err = limiter.Load(ctx, b.UID(), r)
+			return err
+		}
+		if err := kv.WithReader(ctx, db, load); err != nil {
+			t.Fatal(err)
+		}
+		l.buys = append(l.buys, b)
+		l.setState(RunningBuy)
+		return l
+	}
+
+	// Fatal errors must be returned without any retries.
+	{
+		ctx := context.Background()
+		db := kvmemdb.New()
+		l := newRunningLooper(ctx, t, db)
+		product := &errorProduct{Product: paper.New("BTC-USD", nil), err: fmt.Errorf("injected: %w", exchange.ErrProductDelisted)}
+		rt := &trader.Runtime{Database: db, Product: product}
+
+		tctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		if err := l.Run(tctx, rt); !errors.Is(err, exchange.ErrFatal) {
+			t.Fatalf("want a fatal error from the run, got %v", err)
+		}
+		if n := product.ngets.Load(); n != 1 {
+			t.Fatalf("want no retries on fatal errors, got %d fetches", n)
+		}
+	}
+
+	// Transient errors must be retried beyond the max-consecutive-errors till
+	// the context is canceled.
+	{
+		ctx := context.Background()
+		db := kvmemdb.New()
+		l := newRunningLooper(ctx, t, db)
+		if err := l.SetOption("max-consecutive-errors", "1"); err != nil {
+			t.Fatal(err)
+		}
+		product := &errorProduct{Product: paper.New("BTC-USD", nil), err: fmt.Errorf("injected: %w", exchange.ErrTransient)}
+		rt := &trader.Runtime{Database: db, Product: product}
+
+		tctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+		if err := l.Run(tctx, rt); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("transient errors must be retried till the timeout, got %v", err)
+		}
+		if n := product.ngets.Load(); n < 3 {
+			t.Fatalf("want multiple retries on transient errors, got %d fetches", n)
+		}
+	}
+
+	// Transient errors must be limited by max-consecutive-errors when
+	// auto-resume is disabled.
+	{
+		ctx := context.Background()
+		db := kvmemdb.New()
+		l := newRunningLooper(ctx, t, db)
+		if err := l.SetOption("max-consecutive-errors", "1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.SetOption("auto-resume", "false"); err != nil {
+			t.Fatal(err)
+		}
+		product := &errorProduct{Product: paper.New("BTC-USD", nil), err: fmt.Errorf("injected: %w", exchange.ErrTransient)}
+		rt := &trader.Runtime{Database: db, Product: product}
+
+		tctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if err := l.Run(tctx, rt); !errors.Is(err, exchange.ErrTransient) {
+			t.Fatalf("want the transient error from the run, got %v", err)
+		}
+		if n := product.ngets.Load(); n != 2 {
+			t.Fatalf("want one retry with max-consecutive-errors 1, got %d fetches", n)
+		}
+	}
+}
+
+func TestLooperInvertedPair(t *testing.T) {
+	newPoint := func(size, price, cancel string) *point.Point {
+		return &point.Point{
+			Size:   decimal.RequireFromString(size),
+			Price:  decimal.RequireFromString(price),
+			Cancel: decimal.RequireFromString(cancel),
+		}
+	}
+
+	// Sell price is below the buy price.
+	if _, err := New(uuid.NewString(), "coinbase", "BTC-USD", newPoint("1", "120", "130"), newPoint("1", "100", "90")); err == nil {
+		t.Fatalf("want error for inverted buy/sell pair")
+	}
+	// Sell price is above the buy price, but doesn't cover the fees.
+	if _, err := New(uuid.NewString(), "coinbase", "BTC-USD", newPoint("1", "100", "110"), newPoint("1", "100.3", "90")); err == nil {
+		t.Fatalf("want error for a pair that doesn't cover the fees")
+	}
+	if _, err := New(uuid.NewString(), "coinbase", "BTC-USD", newPoint("1", "100", "110"), newPoint("1", "101", "90")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Check can be disabled.
+	defer func(pct float64) { NewLooperFeePct = pct }(NewLooperFeePct)
+	NewLooperFeePct = -1
+	if _, err := New(uuid.NewString(), "coinbase", "BTC-USD", newPoint("1", "100", "110"), newPoint("1", "100.3", "90")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLooperMinBuyInterval(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	buy := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	sell := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("120"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l1, err := New(uid, "coinbase", "BTC-USD", buy, sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l1.SetOption("min-buy-interval", "-1s"); err == nil {
+		t.Fatalf("want error for negative min-buy-interval")
+	}
+	if err := l1.SetOption("min-buy-interval", "1h"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if d := l1.buyIntervalWait(now); d != 0 {
+		t.Fatalf("want no wait before the first buy, got %s", d)
+	}
+	l1.lastBuyTime = now.Add(-time.Minute)
+	if err := kv.WithReadWriter(ctx, db, l1.Save); err != nil {
+		t.Fatal(err)
+	}
+
+	var l2 *Looper
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		l2, err = Load(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+	if !l2.lastBuyTime.Equal(l1.lastBuyTime) {
+		t.Fatalf("last buy time: want %s, got %s", l1.lastBuyTime, l2.lastBuyTime)
+	}
+	if d := l2.buyIntervalWait(now); d != 59*time.Minute {
+		t.Fatalf("want 59m wait after the last buy, got %s", d)
+	}
+	if d := l2.buyIntervalWait(now.Add(2 * time.Hour)); d != 0 {
+		t.Fatalf("want no wait after the min-buy-interval, got %s", d)
+	}
+}
+
+func TestLooperStatusNumLoops(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	buy := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	sell := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("120"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	uid := uuid.NewString()
+	l, err := New(uid, "paper", "BTC-USD", buy, sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// First sell has no buy before it (oversold), second buy-sell pair is a
+	// complete loop and the third pair is sold only after the period.
+	base := time.Now().Add(-24 * time.Hour).Truncate(time.Hour)
+	at := func(hours int) time.Time { return base.Add(time.Duration(hours) * time.Hour) }
+	l.buys = append(l.buys,
+		newFilledLimiterAt(ctx, t, db, path.Join(uid, "buy-000000"), buy, "1", at(2)),
+		newFilledLimiterAt(ctx, t, db, path.Join(uid, "buy-000001"), buy, "1", at(4)))
+	l.sells = append(l.sells,
+		newFilledLimiterAt(ctx, t, db, path.Join(uid, "sell-000000"), sell, "1", at(1)),
+		newFilledLimiterAt(ctx, t, db, path.Join(uid, "sell-000001"), sell, "1", at(3)),
+		newFilledLimiterAt(ctx, t, db, path.Join(uid, "sell-000002"), sell, "1", at(8)))
+
+	period := &timerange.Range{Begin: base, End: at(6)}
+	s := l.Status(period)
+	if s.NumBuys != 2 || s.NumSells != 2 {
+		t.Fatalf("want 2 buys and 2 sells in the period, got %d buys and %d sells", s.NumBuys, s.NumSells)
+	}
+	if s.NumLoops != 1 {
+		t.Fatalf("want only the complete buy-sell pair as a loop, got %d loops", s.NumLoops)
+	}
+	if !s.OversoldSize.Equal(decimal.RequireFromString("1")) {
+		t.Fatalf("want oversold size 1 for the sell without a buy, got %s", s.OversoldSize)
+	}
+
+	// Third pair is a loop when the period includes it's sell.
+	if s := l.Status(nil); s.NumLoops != 2 || s.NumSells != 3 {
+		t.Fatalf("want 2 loops and 3 sells without a period, got %d loops and %d sells", s.NumLoops, s.NumSells)
+	}
+}
+
+func TestLooperAvgEntryPrice(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	d := decimal.RequireFromString
+	buy1 := &point.Point{Size: d("1"), Price: d("100"), Cancel: d("110")}
+	buy2 := &point.Point{Size: d("2"), Price: d("94"), Cancel: d("110")}
+	sell := &point.Point{Size: d("1"), Price: d("120"), Cancel: d("110")}
+
+	uid := uuid.NewString()
+	l, err := New(uid, "paper", "BTC-USD", buy1, sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := l.AvgEntryPrice(); !v.IsZero() {
+		t.Fatalf("want zero average entry price without buys, got %s", v)
+	}
+	if v := l.BreakEvenPrice(0.25); !v.IsZero() {
+		t.Fatalf("want zero break-even price without holdings, got %s", v)
+	}
+
+	l.buys = append(l.buys,
+		newFilledLimiter(ctx, t, db, path.Join(uid, "buy-000000"), buy1, "1"),
+		newFilledLimiter(ctx, t, db, path.Join(uid, "buy-000001"), buy2, "2"))
+	l.sells = append(l.sells,
+		newFilledLimiter(ctx, t, db, path.Join(uid, "sell-000000"), sell, "1"))
+
+	// Buys are weighted by their filled sizes.
+	if v, want := l.AvgEntryPrice(), d("96"); !v.Equal(want) {
+		t.Fatalf("want average entry price %s, got %s", want, v)
+	}
+	size, cost := l.Inventory()
+	if !size.Equal(d("2")) || !cost.Equal(d("192")) {
+		t.Fatalf("want inventory size 2 at cost 192, got size %s at cost %s", size, cost)
+	}
+	if v := l.UnsoldValue(); !v.Equal(cost) {
+		t.Fatalf("want unsold value %s to match the inventory cost, got %s", cost, v)
+	}
+	if v, want := l.BreakEvenPrice(0.25).Round(6), d("96.481203"); !v.Equal(want) {
+		t.Fatalf("want break-even price %s, got %s", want, v)
+	}
+	if v := l.BreakEvenPrice(0); !v.Equal(d("96")) {
+		t.Fatalf("want break-even price at the average entry price without fees, got %s", v)
+	}
+}
+
+func TestLooperHoldChild(t *testing.T) {
+	d := decimal.RequireFromString
+	buy := &point.Point{Size: d("1"), Price: d("100"), Cancel: d("110")}
+	sell := &point.Point{Size: d("1"), Price: d("120"), Cancel: d("110")}
+
+	uid := uuid.NewString()
+	l, err := New(uid, "paper", "BTC-USD", buy, sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := limiter.New(path.Join(uid, "buy-000000"), "paper", "BTC-USD", buy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.buys = append(l.buys, child)
+
+	// Child can be named by it's name or the full uid.
+	if err := l.HoldChild("buy-000000", true); err != nil {
+		t.Fatal(err)
+	}
+	if !child.IsHeld() {
+		t.Fatalf("want child limiter held")
+	}
+	if err := l.HoldChild(child.UID(), false); err != nil {
+		t.Fatal(err)
+	}
+	if child.IsHeld() {
+		t.Fatalf("want child limiter released")
+	}
+
+	if err := l.HoldChild("sell-000000", true); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("want os.ErrNotExist for unknown child, got %v", err)
+	}
+}
+
+func TestLooperRealizedProfitReload(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	d := decimal.RequireFromString
+	buy := &point.Point{Size: d("1"), Price: d("100"), Cancel: d("110")}
+	sell := &point.Point{Size: d("1"), Price: d("120"), Cancel: d("110")}
+
+	uid := uuid.NewString()
+	l1, err := New(uid, "paper", "BTC-USD", buy, sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l1.buys = append(l1.buys,
+		newFilledLimiter(ctx, t, db, path.Join(uid, "buy-000000"), buy, "1"),
+		newFilledLimiter(ctx, t, db, path.Join(uid, "buy-000001"), buy, "1"))
+	l1.sells = append(l1.sells,
+		newFilledLimiter(ctx, t, db, path.Join(uid, "sell-000000"), sell, "1"))
+	if err := l1.SetOption("profit-target", "20"); err != nil {
+		t.Fatal(err)
+	}
+	if err := kv.WithReadWriter(ctx, db, l1.Save); err != nil {
+		t.Fatal(err)
+	}
+
+	// Realized profit is recomputed from the saved child limiters, so the
+	// profit target stays reached after a restart.
+	var l2 *Looper
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		l2, err = Load(ctx, uid, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		t.Fatal(err)
+	}
+	if p1, p2 := l1.RealizedProfit(), l2.RealizedProfit(); !p1.Equal(d("20")) || !p2.Equal(p1) {
+		t.Fatalf("want realized profit 20 after reload, got %s and %s", p1, p2)
+	}
+	if !l2.isProfitTargetReached() {
+		t.Fatalf("want profit target to be reached after reload")
+	}
+}
@@ -4,8 +4,249 @@ package looper
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func (v *Looper) SetOption(key, value string) error {
+	optMap := map[string]func(string) error{
+		"profit-target":          v.setProfitTargetOption,
+		"wind-down":              v.setWindDownOption,
+		"stagger-flush":          v.setStaggerFlushOption,
+		"max-consecutive-errors": v.setMaxConsecutiveErrorsOption,
+		"skip-initial-wait":      v.setSkipInitialWaitOption,
+		"max-position":           v.setMaxPositionOption,
+		"auto-resume":            v.setAutoResumeOption,
+		"min-buy-interval":       v.setMinBuyIntervalOption,
+
+		"budget-exhausted-policy": v.setBudgetExhaustedPolicyOption,
+	}
+	handler, ok := optMap[key]
+	if !ok {
+		return fmt.Errorf("invalid option key %q", key)
+	}
+
+	if err := handler(value); err != nil {
+		return err
+	}
+	v.optionMap[key] = value
+	return nil
+}
+
+func (v *Looper) Config() map[string]string {
+	target, _ := v.profitTarget()
+	return map[string]string{
+		"profit-target":          target.String(),
+		"wind-down":              strconv.FormatBool(v.windDownOpt.Load()),
+		"stagger-flush":          strconv.FormatBool(v.staggerFlushOpt.Load()),
+		"max-consecutive-errors": strconv.FormatInt(v.maxConsecutiveErrorsOpt.Load(), 10),
+		"skip-initial-wait":      strconv.FormatBool(v.skipInitialWaitOpt.Load()),
+		"max-position":           v.maxPosition().String(),
+		"auto-resume":            strconv.FormatBool(!v.noAutoResumeOpt.Load()),
+		"min-buy-interval":       v.minBuyInterval().String(),
+
+		"budget-exhausted-policy": v.budgetExhaustedPolicy(),
+	}
+}
+
+func (v *Looper) setProfitTargetOption(value string) error {
+	target, err := decimal.NewFromString(value)
+	if err != nil {
+		return err
+	}
+	if target.IsNegative() {
+		return fmt.Errorf("profit target value cannot be -ve")
+	}
+	v.profitTargetOpt.Store(&target)
+	return nil
+}
+
+func (v *Looper) setMaxPositionOption(value string) error {
+	size, err := decimal.NewFromString(value)
+	if err != nil {
+		return err
+	}
+	if size.IsNegative() {
+		return fmt.Errorf("max-position value cannot be -ve")
+	}
+	v.maxPositionOpt.Store(&size)
+	return nil
+}
+
+// maxPosition returns the max-position option value, which is zero when it is
+// not set.
+func (v *Looper) maxPosition() decimal.Decimal {
+	if p := v.maxPositionOpt.Load(); p != nil {
+		return p.Copy()
+	}
+	return decimal.Zero
+}
+
+// isPositionCapped returns true if a new buy would take the held inventory
+// beyond the max-position option value. Zero option value means unlimited.
+func (v *Looper) isPositionCapped() bool {
+	max := v.maxPosition()
+	if max.IsZero() {
+		return false
+	}
+	return v.holdings().Add(v.buyPoint.BaseSize()).GreaterThan(max)
+}
+
+func (v *Looper) setMinBuyIntervalOption(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return fmt.Errorf("min-buy-interval value cannot be -ve")
+	}
+	v.minBuyIntervalOpt.Store(int64(d))
+	return nil
+}
+
+func (v *Looper) minBuyInterval() time.Duration {
+	return time.Duration(v.minBuyIntervalOpt.Load())
+}
+
+// buyIntervalWait returns the time remaining at now before a new buy can be
+// added as per the min-buy-interval option.
+func (v *Looper) buyIntervalWait(now time.Time) time.Duration {
+	interval := v.minBuyInterval()
+	if interval == 0 || v.lastBuyTime.IsZero() {
+		return 0
+	}
+	return max(v.lastBuyTime.Add(interval).Sub(now), 0)
+}
+
+func (v *Looper) setWindDownOption(value string) error {
+	arg := strings.ToLower(value)
+	if arg == "true" {
+		v.windDownOpt.Store(true)
+		return nil
+	}
+	if arg == "false" {
+		v.windDownOpt.Store(false)
+		return nil
+	}
+	return fmt.Errorf(`%v: wind-down option only takes a "true" or "false" value`, v.uid)
+}
+
+func (v *Looper) setAutoResumeOption(value string) error {
+	arg := strings.ToLower(value)
+	if arg == "true" {
+		v.noAutoResumeOpt.Store(false)
+		return nil
+	}
+	if arg == "false" {
+		v.noAutoResumeOpt.Store(true)
+		return nil
+	}
+	return fmt.Errorf(`%v: auto-resume option only takes a "true" or "false" value`, v.uid)
+}
+
+// Policies for the budget-exhausted-policy option, which decides what happens
+// when a new buy cannot be placed due to the looper budget or the insufficient
+// funds. Looper waits for a sell (or a budget increase) to free the funds with
+// the BudgetExhaustedWait policy and completes with the BudgetExhaustedStop
+// policy.
+const (
+	BudgetExhaustedWait = "wait"
+	BudgetExhaustedStop = "stop"
 )
 
-func (v *Looper) SetOption(opt, val string) error {
-	return fmt.Errorf("TODO")
+func (v *Looper) budgetExhaustedPolicy() string {
+	if v.stopOnBudgetOpt.Load() {
+		return BudgetExhaustedStop
+	}
+	return BudgetExhaustedWait
+}
+
+func (v *Looper) setBudgetExhaustedPolicyOption(value string) error {
+	switch strings.ToLower(value) {
+	case BudgetExhaustedWait:
+		v.stopOnBudgetOpt.Store(false)
+		return nil
+	case BudgetExhaustedStop:
+		v.stopOnBudgetOpt.Store(true)
+		return nil
+	}
+	return fmt.Errorf(`%v: budget-exhausted-policy option only takes a %q or %q value`, v.uid, BudgetExhaustedWait, BudgetExhaustedStop)
+}
+
+func (v *Looper) setSkipInitialWaitOption(value string) error {
+	arg := strings.ToLower(value)
+	if arg == "true" {
+		v.skipInitialWaitOpt.Store(true)
+		return nil
+	}
+	if arg == "false" {
+		v.skipInitialWaitOpt.Store(false)
+		return nil
+	}
+	return fmt.Errorf(`%v: skip-initial-wait option only takes a "true" or "false" value`, v.uid)
+}
+
+func (v *Looper) setStaggerFlushOption(value string) error {
+	arg := strings.ToLower(value)
+	if arg != "true" && arg != "false" {
+		return fmt.Errorf(`%v: stagger-flush option only takes a "true" or "false" value`, v.uid)
+	}
+	for _, l := range v.buys {
+		if err := l.SetOption("stagger-flush", arg); err != nil {
+			return err
+		}
+	}
+	for _, l := range v.sells {
+		if err := l.SetOption("stagger-flush", arg); err != nil {
+			return err
+		}
+	}
+	v.staggerFlushOpt.Store(arg == "true")
+	return nil
+}
+
+func (v *Looper) setMaxConsecutiveErrorsOption(value string) error {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		return fmt.Errorf("max-consecutive-errors value cannot be -ve")
+	}
+	v.maxConsecutiveErrorsOpt.Store(n)
+	return nil
+}
+
+// isTooManyErrors returns true if the number of consecutive errors exceeds the
+// max-consecutive-errors option value. Zero option value means unlimited.
+func (v *Looper) isTooManyErrors(nerrors int) bool {
+	max := v.maxConsecutiveErrorsOpt.Load()
+	return max > 0 && int64(nerrors) > max
+}
+
+// isWindingDown returns true if no new buys should be started, either because
+// wind-down option is set or the profit target is reached.
+func (v *Looper) isWindingDown() bool {
+	return v.windDownOpt.Load() || v.isProfitTargetReached()
+}
+
+// profitTarget returns the profit target if it is set and non-zero.
+func (v *Looper) profitTarget() (decimal.Decimal, bool) {
+	if p := v.profitTargetOpt.Load(); p != nil && !p.IsZero() {
+		return p.Copy(), true
+	}
+	return decimal.Zero, false
+}
+
+// isProfitTargetReached returns true if the realized profit has reached the
+// profit target option value.
+func (v *Looper) isProfitTargetReached() bool {
+	target, ok := v.profitTarget()
+	if !ok {
+		return false
+	}
+	return v.RealizedProfit().GreaterThanOrEqual(target)
 }
@@ -0,0 +1,90 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package looper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvkgo/kv"
+)
+
+// Repair rebuilds the child limiter list of a looper from the limiters found
+// under the looper's uid in the limiters keyspace. References to missing
+// limiters are dropped and unreferenced child limiters are added back, in the
+// same sorted order used by Save. Returns the uids added and dropped. Looper
+// state is updated only when write is true.
+func Repair(ctx context.Context, uid string, rw kv.ReadWriter, write bool) (added, dropped []string, _ error) {
+	if err := checkUID(uid); err != nil {
+		return nil, nil, err
+	}
+	key := path.Join(DefaultKeyspace, uid)
+	gv, err := kvutil.Get[gobs.LooperState](ctx, rw, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not load looper state: %w", err)
+	}
+	gv.Upgrade()
+
+	children, err := listChildLimiters(ctx, rw, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var current []string
+	for _, id := range gv.V2.LimiterIDs {
+		id = cleanUID(id)
+		if !slices.Contains(children, id) {
+			dropped = append(dropped, id)
+			continue
+		}
+		current = append(current, id)
+	}
+	for _, id := range children {
+		if !slices.Contains(current, id) {
+			added = append(added, id)
+		}
+	}
+
+	if !write {
+		return added, dropped, nil
+	}
+
+	gv.V2.LimiterIDs = children
+	if err := kvutil.Set(ctx, rw, key, gv); err != nil {
+		return nil, nil, fmt.Errorf("could not save repaired looper state: %w", err)
+	}
+	return added, dropped, nil
+}
+
+// listChildLimiters returns the sorted uids of the limiters that are direct
+// children of the looper uid.
+func listChildLimiters(ctx context.Context, r kv.Reader, uid string) ([]string, error) {
+	begin, end := kvutil.PathRange(path.Join(limiter.DefaultKeyspace, uid))
+	it, err := r.Ascend(ctx, begin, end)
+	if err != nil {
+		return nil, fmt.Errorf("could not scan limiters keyspace: %w", err)
+	}
+	defer kv.Close(it)
+
+	var children []string
+	for k, _, err := it.Fetch(ctx, false); err == nil; k, _, err = it.Fetch(ctx, true) {
+		id := strings.TrimPrefix(k, limiter.DefaultKeyspace)
+		if path.Dir(id) != uid {
+			continue
+		}
+		children = append(children, id)
+	}
+	if _, _, err := it.Fetch(ctx, false); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("could not complete limiters scan: %w", err)
+	}
+	slices.Sort(children)
+	return children, nil
+}
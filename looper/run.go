@@ -4,17 +4,47 @@ package looper
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"path"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/bvk/tradebot/ctxutil"
+	"github.com/bvk/tradebot/exchange"
 	"github.com/bvk/tradebot/limiter"
 	"github.com/bvk/tradebot/trader"
 	"github.com/bvkgo/kv"
 	"github.com/shopspring/decimal"
 )
 
+// InsufficientFundsBackoff is the time to wait before retrying a limit-buy
+// that has failed due to insufficient funds.
+var InsufficientFundsBackoff = time.Minute
+
+// MaxPositionBackoff is the time to wait before checking again when a new buy
+// is held back by the max-position option.
+var MaxPositionBackoff = time.Minute
+
+// BudgetBackoff is the time to wait before checking again when a new buy is
+// held back by the looper budget.
+var BudgetBackoff = time.Minute
+
+// TransientBackoff is the time to wait before retrying after a transient
+// exchange error, which is doubled for every consecutive transient error up to
+// MaxTransientBackoff.
+var TransientBackoff = time.Second
+
+// MaxTransientBackoff is the max time to wait before retrying after a
+// transient exchange error.
+var MaxTransientBackoff = time.Minute
+
+// errOverBudget is returned by addNewBuy when the new buy would exceed the
+// looper budget.
+var errOverBudget = errors.New("looper budget is exhausted")
+
 func (v *Looper) Fix(ctx context.Context, rt *trader.Runtime) error {
 	v.runtimeLock.Lock()
 	defer v.runtimeLock.Unlock()
@@ -53,107 +83,261 @@ func (v *Looper) Run(ctx context.Context, rt *trader.Runtime) error {
 	v.runtimeLock.Lock()
 	defer v.runtimeLock.Unlock()
 
+	// nerrors is the number of consecutive failures, which is reset on any
+	// successful operation.
+	nerrors := 0
+
+	// ntransient is the number of consecutive transient exchange errors, which
+	// determines the backoff before the next retry.
+	ntransient := 0
+
+	if s, r := v.State(), v.resumeState(); s != r {
+		log.Printf("%s: resuming from state %s instead of the saved state %s", v.uid, r, s)
+		v.setState(r)
+	}
+
 	for ctx.Err() == nil {
 		nbuys, nsells := len(v.buys), len(v.sells)
 
-		var bought decimal.Decimal
-		for _, b := range v.buys {
-			bought = bought.Add(b.FilledSize())
-		}
-		var sold decimal.Decimal
-		for _, s := range v.sells {
-			sold = sold.Add(s.FilledSize())
-		}
-
-		holdings := bought.Sub(sold)
-		if holdings.IsNegative() {
-			log.Printf("%s: WARNING: current holding size %s-%s = %s is negative (out of %d buys and %d sells)", v.uid, bought, sold, holdings, nbuys, nsells)
-			var bought decimal.Decimal
+		if holdings := v.holdings(); holdings.IsNegative() {
+			log.Printf("%s: WARNING: current holding size %s is negative (out of %d buys and %d sells)", v.uid, holdings, nbuys, nsells)
 			for i, b := range v.buys {
-				size := b.FilledSize()
-				log.Printf("%s: WARNING: buyer %d (%s) has filled size %s", v.uid, i, b.UID(), size)
-				bought = bought.Add(size)
+				log.Printf("%s: WARNING: buyer %d (%s) has filled size %s", v.uid, i, b.UID(), b.FilledSize())
 			}
-			var sold decimal.Decimal
 			for i, s := range v.sells {
-				size := s.FilledSize()
-				log.Printf("%s: WARNING: seller %d (%s) has filled size %s", v.uid, i, s.UID(), size)
-				sold = sold.Add(size)
+				log.Printf("%s: WARNING: seller %d (%s) has filled size %s", v.uid, i, s.UID(), s.FilledSize())
 			}
 			<-ctx.Done()
 			return context.Cause(ctx)
 		}
 
-		// Start a buy if holding amount is less than buy size.
-		if holdings.LessThan(v.buyPoint.Size) {
-			log.Printf("%s: current holding size %s-%s=%s is less than buy size %s (starting a buy)", v.uid, bought, sold, holdings, v.buyPoint.Size)
+		switch state := v.State(); state {
+		case NeedBuy:
+			// Holdings that are enough for a sell are sold when no new buys can be
+			// started, which happens when winding down or with the stop policy.
+			noNewBuys := v.isWindingDown() || (v.budgetStopped && v.stopOnBudgetOpt.Load())
+			if noNewBuys && v.holdings().GreaterThanOrEqual(v.sellPoint.Size) {
+				v.transition(ctx, rt, NeedSell)
+				continue
+			}
 
-			if nbuys == 0 || v.buys[nbuys-1].PendingSize().IsZero() {
-				if err := v.addNewBuy(ctx, rt); err != nil {
-					if ctx.Err() == nil {
-						log.Printf("could not add limit-buy %d (retrying): %v", nbuys, err)
-						time.Sleep(time.Second)
+			// No new buys are started after the profit target is reached or when
+			// winding down, but in-flight buys and sells are completed before
+			// stopping the loop.
+			if v.isWindingDown() {
+				log.Printf("%s: looper is complete with realized profit %s (wind-down %t)", v.uid, v.RealizedProfit().StringFixed(3), v.windDownOpt.Load())
+				return nil
+			}
+
+			// Looper cannot complete with the stop policy while any bought size is
+			// unsold, so it waits for the holdings to be sold (or resolved)
+			// manually.
+			if v.budgetStopped && v.stopOnBudgetOpt.Load() {
+				if holdings := v.holdings(); !holdings.IsZero() {
+					if !v.waitingForHoldings {
+						log.Printf("%s: WARNING: looper cannot complete with the unsold holding size %s (budget-exhausted-policy is %s; checking every %s)", v.uid, holdings, BudgetExhaustedStop, BudgetBackoff)
+						v.waitingForHoldings = true
+					}
+					ctxutil.Sleep(ctx, BudgetBackoff)
+					continue
+				}
+				log.Printf("%s: looper is complete cause the budget or the funds are exhausted (budget-exhausted-policy is %s)", v.uid, BudgetExhaustedStop)
+				return nil
+			}
+
+			// Buys are held back (but not the sells) while the inventory is at
+			// the max-position, which can change when the option is updated.
+			if v.isPositionCapped() {
+				if !v.waitingForPosition {
+					log.Printf("%s: WARNING: new limit-buy is held back cause holding size %s is at the max-position %s (checking every %s)", v.uid, v.holdings(), v.maxPosition(), MaxPositionBackoff)
+					v.waitingForPosition = true
+				}
+				ctxutil.Sleep(ctx, MaxPositionBackoff)
+				continue
+			}
+			if v.waitingForPosition {
+				log.Printf("%s: holding size %s is below the max-position %s and new limit-buys are resumed", v.uid, v.holdings(), v.maxPosition())
+				v.waitingForPosition = false
+			}
+
+			log.Printf("%s: current holding size %s is less than buy size %s (starting a buy)", v.uid, v.holdings(), v.buyPoint.Size)
+			if err := v.addNewBuy(ctx, rt); err != nil {
+				if ctx.Err() == nil && errors.Is(err, errOverBudget) {
+					if v.stopOnBudgetOpt.Load() {
+						log.Printf("%s: no new buys are started cause unsold cost %s plus buy value %s is over the budget %s (budget-exhausted-policy is %s; holding size %s)", v.uid, v.UnsoldValue().StringFixed(3), v.buyPoint.Value().StringFixed(3), v.Budget().StringFixed(3), BudgetExhaustedStop, v.holdings())
+						v.budgetStopped = true
 						continue
 					}
-					log.Printf("%v: could not create new limit-buy op (will retry): %v", v.uid, err)
+					if !v.waitingForBudget {
+						log.Printf("%s: WARNING: new limit-buy is held back cause unsold cost %s plus buy value %s is over the budget %s (checking every %s)", v.uid, v.UnsoldValue().StringFixed(3), v.buyPoint.Value().StringFixed(3), v.Budget().StringFixed(3), BudgetBackoff)
+						v.waitingForBudget = true
+					}
+					ctxutil.Sleep(ctx, BudgetBackoff)
 					continue
 				}
-				nbuys = len(v.buys)
+				if ctx.Err() == nil {
+					log.Printf("could not add limit-buy %d: %v", nbuys, err)
+					if !v.retryError(ctx, err, &nerrors, &ntransient) {
+						return fmt.Errorf("could not add limit-buy %d after %d consecutive errors: %w", nbuys, nerrors, err)
+					}
+					continue
+				}
+				log.Printf("%v: could not create new limit-buy op (will retry): %v", v.uid, err)
+				continue
 			}
+			if v.waitingForBudget {
+				log.Printf("%s: unsold cost is within the budget %s and new limit-buys are resumed", v.uid, v.Budget().StringFixed(3))
+				v.waitingForBudget = false
+			}
+			nerrors, ntransient = 0, 0
 
+		case RunningBuy:
 			if err := v.buys[nbuys-1].Run(ctx, rt); err != nil {
+				if ctx.Err() == nil && errors.Is(err, exchange.ErrInsufficientFunds) {
+					// Pending buy is completed with it's filled size, so that the
+					// bought size, if any, is sold before the looper completes.
+					if v.stopOnBudgetOpt.Load() {
+						cerr := v.buys[nbuys-1].Complete()
+						if cerr == nil {
+							log.Printf("%s: no new buys are started cause limit-buy %d cannot be placed due to insufficient funds (budget-exhausted-policy is %s; holding size %s): %v", v.uid, nbuys, BudgetExhaustedStop, v.holdings(), err)
+							v.budgetStopped = true
+							v.transition(ctx, rt, v.settledState())
+							continue
+						}
+						log.Printf("%s: could not complete limit-buy %d for the budget-exhausted-policy (waiting for funds): %v", v.uid, nbuys, cerr)
+					}
+					if !v.waitingForFunds {
+						log.Printf("%s: WARNING: limit-buy %d cannot be placed due to insufficient funds (retrying every %s until funds are available)", v.uid, nbuys, InsufficientFundsBackoff)
+						v.waitingForFunds = true
+					}
+					ctxutil.Sleep(ctx, InsufficientFundsBackoff)
+					continue
+				}
 				if ctx.Err() == nil {
-					log.Printf("limit-buy %d has failed (retrying): %v", nbuys, err)
-					time.Sleep(time.Second)
+					log.Printf("limit-buy %d has failed: %v", nbuys, err)
+					if !v.retryError(ctx, err, &nerrors, &ntransient) {
+						return fmt.Errorf("limit-buy %d has failed after %d consecutive errors: %w", nbuys, nerrors, err)
+					}
 					continue
 				}
 				log.Printf("%v: could not complete limit-buy op (will retry): %v", v.uid, err)
 				continue
 			}
-		}
+			if v.waitingForFunds {
+				log.Printf("%s: funds are available and limit-buy %d is resumed", v.uid, nbuys)
+				v.waitingForFunds = false
+			}
+			nerrors, ntransient = 0, 0
+			v.transition(ctx, rt, v.settledState())
 
-		// Start a sell if holding amount is greater than sell size.
-		if holdings.GreaterThanOrEqual(v.sellPoint.Size) {
-			log.Printf("%s: current holding size %s-%s=%s is greater-than or equal to sell size %s (starting a sell)", v.uid, bought, sold, holdings, v.sellPoint.Size)
-			if nsells == 0 || v.sells[nsells-1].PendingSize().IsZero() {
-				if err := v.addNewSell(ctx, rt); err != nil {
-					if ctx.Err() == nil {
-						log.Printf("could not add limit-sell %d (retrying); %v", nsells, err)
-						time.Sleep(time.Second)
-						continue
+		case NeedSell:
+			log.Printf("%s: current holding size %s is greater-than or equal to sell size %s (starting a sell)", v.uid, v.holdings(), v.sellPoint.Size)
+			if err := v.addNewSell(ctx, rt); err != nil {
+				if ctx.Err() == nil {
+					log.Printf("could not add limit-sell %d: %v", nsells, err)
+					if !v.retryError(ctx, err, &nerrors, &ntransient) {
+						return fmt.Errorf("could not add limit-sell %d after %d consecutive errors: %w", nsells, nerrors, err)
 					}
-					log.Printf("%v: could not create new limit-sell op (will retry): %v", v.uid, err)
 					continue
 				}
-				nsells = len(v.sells)
+				log.Printf("%v: could not create new limit-sell op (will retry): %v", v.uid, err)
+				continue
 			}
+			nerrors, ntransient = 0, 0
 
+		case RunningSell:
 			if err := v.sells[nsells-1].Run(ctx, rt); err != nil {
 				if ctx.Err() == nil {
-					log.Printf("limit-sell %d has failed (retrying): %v", nsells, err)
-					time.Sleep(time.Second)
+					log.Printf("limit-sell %d has failed: %v", nsells, err)
+					if !v.retryError(ctx, err, &nerrors, &ntransient) {
+						return fmt.Errorf("limit-sell %d has failed after %d consecutive errors: %w", nsells, nerrors, err)
+					}
 					continue
 				}
 				log.Printf("%v: could not complete limit-sell op (will retry): %v", v.uid, err)
 				continue
 			}
 
-			sell, buy := v.sells[nsells-1], v.buys[nbuys-1]
-			fees := sell.Fees().Add(buy.Fees())
-			profit := sell.SoldValue().Sub(buy.BoughtValue()).Sub(fees)
-			rt.Messenger.SendMessage(ctx, time.Now(), "A sell is completed successfully at price %s in product %s (%s) with %s of profit.", v.sellPoint.Price.StringFixed(3), v.productID, v.exchangeName, profit.StringFixed(3))
+			nerrors, ntransient = 0, 0
+			v.transition(ctx, rt, v.settledState())
+			if nbuys > 0 {
+				sell, buy := v.sells[nsells-1], v.buys[nbuys-1]
+				fees := sell.Fees().Add(buy.Fees())
+				profit := sell.SoldValue().Sub(buy.BoughtValue()).Sub(fees)
+				rt.Messenger.SendMessage(ctx, time.Now(), "A sell is completed successfully at price %s in product %s (%s) with %s of profit.", v.sellPoint.Price.StringFixed(3), v.productID, v.exchangeName, profit.StringFixed(3))
+			}
+
+		default:
+			return fmt.Errorf("looper %s has invalid state %s", v.uid, state)
 		}
 	}
 	return context.Cause(ctx)
 }
 
+// retryError waits before the retry of a failed operation and returns true, or
+// returns false if Run must give up and return the error. Fatal exchange
+// errors are never retried. Transient exchange errors are retried with an
+// exponential backoff when the auto-resume option is enabled, without counting
+// towards the max-consecutive-errors option.
+func (v *Looper) retryError(ctx context.Context, err error, nerrors, ntransient *int) bool {
+	if exchange.IsFatal(err) {
+		log.Printf("%s: giving up on a fatal exchange error: %v", v.uid, err)
+		*nerrors++
+		return false
+	}
+	if !v.noAutoResumeOpt.Load() && exchange.IsTransient(err) {
+		backoff := TransientBackoff << min(*ntransient, 16)
+		backoff = min(backoff, MaxTransientBackoff)
+		*ntransient++
+		log.Printf("%s: retrying after %s on a transient exchange error (%d consecutive)", v.uid, backoff, *ntransient)
+		ctxutil.Sleep(ctx, backoff)
+		return true
+	}
+	if *nerrors++; v.isTooManyErrors(*nerrors) {
+		return false
+	}
+	time.Sleep(time.Second)
+	return true
+}
+
+// transition moves the looper into the next state and saves it. Failure to
+// save is not fatal because the same next state is reached on resume when the
+// completed limiter is run again.
+func (v *Looper) transition(ctx context.Context, rt *trader.Runtime, next State) {
+	v.setState(next)
+	if err := kv.WithReadWriter(ctx, rt.Database, v.Save); err != nil {
+		log.Printf("%s: could not save looper state %s (ignored): %v", v.uid, next, err)
+	}
+}
+
 func (v *Looper) addNewBuy(ctx context.Context, rt *trader.Runtime) error {
+	// Cost of the outstanding unsold buys, including the new buy, must be
+	// within the budget.
+	if v.isOverBudget() {
+		return errOverBudget
+	}
+
+	// Successive buys are spaced out by the min-buy-interval option, so that a
+	// choppy ticker around the buy price doesn't stack up many buys.
+	if wait := v.buyIntervalWait(time.Now()); wait > 0 {
+		log.Printf("%s: waiting %s before adding a new limit-buy as per the min-buy-interval %s", v.uid, wait.Round(time.Second), v.minBuyInterval())
+		ctxutil.Sleep(ctx, wait)
+		if err := context.Cause(ctx); err != nil {
+			return err
+		}
+	}
+
 	// Wait for the ticker to go above the buy point price.
 	tickerCh, stopTickers := rt.Product.TickerCh()
 	defer stopTickers()
 
+	// The first buy can be placed at any ticker price when skip-initial-wait
+	// option is set, because a ticker at or below the buy price is already on
+	// the favorable side for the buy.
+	skipWait := len(v.buys) == 0 && v.skipInitialWaitOpt.Load()
+
 	var curPrice decimal.Decimal
-	for curPrice.IsZero() || curPrice.LessThanOrEqual(v.buyPoint.Price) {
+	for curPrice.IsZero() || (!skipWait && curPrice.LessThanOrEqual(v.buyPoint.Price)) {
 		select {
 		case <-ctx.Done():
 			return context.Cause(ctx)
@@ -161,34 +345,72 @@ func (v *Looper) addNewBuy(ctx context.Context, rt *trader.Runtime) error {
 			curPrice = ticker.Price
 		}
 	}
-	log.Printf("%s: adding new limit-buy buy-%06d at buy-price %s when current price is %s", v.uid, len(v.buys), v.buyPoint.Price.StringFixed(3), curPrice.StringFixed(3))
+	index := nextIndex(v.buys, "buy-")
+	log.Printf("%s: adding new limit-buy buy-%06d at buy-price %s when current price is %s", v.uid, index, v.buyPoint.Price.StringFixed(3), curPrice.StringFixed(3))
 
-	uid := path.Join(v.uid, fmt.Sprintf("buy-%06d", len(v.buys)))
+	uid := path.Join(v.uid, fmt.Sprintf("buy-%06d", index))
 	b, err := limiter.New(uid, v.exchangeName, v.productID, &v.buyPoint)
 	if err != nil {
 		return err
 	}
+	if v.staggerFlushOpt.Load() {
+		if err := b.SetOption("stagger-flush", "true"); err != nil {
+			return err
+		}
+	}
 
+	lastBuyTime := v.lastBuyTime
 	v.buys = append(v.buys, b)
+	v.lastBuyTime = time.Now()
+	v.setState(RunningBuy)
 	if err := kv.WithReadWriter(ctx, rt.Database, v.Save); err != nil {
 		v.buys = v.buys[:len(v.buys)-1]
+		v.lastBuyTime = lastBuyTime
+		v.setState(NeedBuy)
 		return err
 	}
 	return nil
 }
 
 func (v *Looper) addNewSell(ctx context.Context, rt *trader.Runtime) error {
-	log.Printf("%s: adding new limit-sell sell-%06d", v.uid, len(v.sells))
+	index := nextIndex(v.sells, "sell-")
+	log.Printf("%s: adding new limit-sell sell-%06d", v.uid, index)
 
-	uid := path.Join(v.uid, fmt.Sprintf("sell-%06d", len(v.sells)))
+	uid := path.Join(v.uid, fmt.Sprintf("sell-%06d", index))
 	s, err := limiter.New(uid, v.exchangeName, v.productID, &v.sellPoint)
 	if err != nil {
 		return err
 	}
+	if v.staggerFlushOpt.Load() {
+		if err := s.SetOption("stagger-flush", "true"); err != nil {
+			return err
+		}
+	}
 	v.sells = append(v.sells, s)
+	v.setState(RunningSell)
 	if err := kv.WithReadWriter(ctx, rt.Database, v.Save); err != nil {
 		v.sells = v.sells[:len(v.sells)-1]
+		v.setState(NeedSell)
 		return err
 	}
 	return nil
 }
+
+// nextIndex returns one more than the largest index used by the limiters with
+// the given name prefix, so that new limiters never reuse an index even when
+// the existing indices have gaps.
+func nextIndex(limiters []*limiter.Limiter, prefix string) int {
+	next := 0
+	for _, l := range limiters {
+		name := strings.TrimPrefix(path.Base(l.UID()), prefix)
+		index, err := strconv.Atoi(name)
+		if err != nil {
+			log.Printf("could not parse index from limiter uid %q (ignored): %v", l.UID(), err)
+			continue
+		}
+		if index >= next {
+			next = index + 1
+		}
+	}
+	return next
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package looper
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/point"
+	"github.com/bvkgo/kv"
+	"github.com/shopspring/decimal"
+)
+
+// Split moves the buy-sell cycles of a saved looper whose buy price is at or
+// above the pivot price into a new looper with the newUID. Each cycle is a buy
+// limiter and the sell limiter at the same position, so that a cycle is never
+// separated. Child limiters are renamed under the new looper with their
+// history intact, and the states of both loopers are re-derived from their
+// children. Both loopers trade at the points of their latest cycles, so that
+// their price ranges do not overlap. Returns the uids of the moved child
+// limiters.
+//
+// Split fails if a moved child limiter has a live order, because the order
+// updates would be lost. Looper must not be running while it is split.
+func Split(ctx context.Context, rw kv.ReadWriter, uid string, pivot decimal.Decimal, newUID string) (moved []string, _ error) {
+	if err := checkUID(newUID); err != nil {
+		return nil, err
+	}
+	if _, err := rw.Get(ctx, path.Join(DefaultKeyspace, newUID)); err == nil {
+		return nil, fmt.Errorf("looper %s already exists: %w", newUID, os.ErrExist)
+	}
+	v, err := Load(ctx, uid, rw)
+	if err != nil {
+		return nil, err
+	}
+
+	var keepBuys, keepSells, moveBuys, moveSells []*limiter.Limiter
+	for i, b := range v.buys {
+		var s *limiter.Limiter
+		if i < len(v.sells) {
+			s = v.sells[i]
+		}
+		if b.Point().Price.LessThan(pivot) {
+			keepBuys = append(keepBuys, b)
+			if s != nil {
+				keepSells = append(keepSells, s)
+			}
+			continue
+		}
+		moveBuys = append(moveBuys, b)
+		if s != nil {
+			moveSells = append(moveSells, s)
+		}
+	}
+	if len(moveBuys) == 0 {
+		return nil, fmt.Errorf("looper %s has no buys at or above the pivot price %s: %w", uid, pivot, os.ErrInvalid)
+	}
+	if len(keepBuys) == 0 {
+		return nil, fmt.Errorf("looper %s has no buys below the pivot price %s: %w", uid, pivot, os.ErrInvalid)
+	}
+	for _, l := range append(moveBuys, moveSells...) {
+		if live := l.LiveOrders(); len(live) > 0 {
+			return nil, fmt.Errorf("child limiter %s has %d live order(s): %w", l.UID(), len(live), os.ErrInvalid)
+		}
+	}
+
+	// New looper trades at the points of it's latest cycle.
+	buyPoint, sellPoint := latestPoints(moveBuys, moveSells, v.buyPoint, v.sellPoint)
+	nv, err := New(newUID, v.exchangeName, v.productID, &buyPoint, &sellPoint)
+	if err != nil {
+		return nil, fmt.Errorf("could not create new looper: %w", err)
+	}
+
+	for _, l := range append(moveBuys, moveSells...) {
+		to := path.Join(newUID, path.Base(l.UID()))
+		if err := limiter.Move(ctx, rw, l.UID(), to); err != nil {
+			return nil, fmt.Errorf("could not move child limiter %s: %w", l.UID(), err)
+		}
+		ml, err := limiter.Load(ctx, to, rw)
+		if err != nil {
+			return nil, fmt.Errorf("could not load moved child limiter %s: %w", to, err)
+		}
+		moved = append(moved, to)
+		if ml.IsBuy() {
+			nv.buys = append(nv.buys, ml)
+		} else {
+			nv.sells = append(nv.sells, ml)
+		}
+	}
+	nv.setState(nv.inferState())
+
+	// Source looper must not trade at the moved points anymore.
+	if !v.buyPoint.Price.LessThan(pivot) {
+		v.buyPoint, v.sellPoint = latestPoints(keepBuys, keepSells, v.buyPoint, v.sellPoint)
+	}
+	v.buys, v.sells = keepBuys, keepSells
+	v.setState(v.inferState())
+
+	if err := v.Save(ctx, rw); err != nil {
+		return nil, fmt.Errorf("could not save looper %s: %w", uid, err)
+	}
+	if err := nv.Save(ctx, rw); err != nil {
+		return nil, fmt.Errorf("could not save new looper %s: %w", newUID, err)
+	}
+	return moved, nil
+}
+
+// latestPoints returns the buy and sell points of the latest cycle. When the
+// latest cycle has no sell yet, sell point is derived from the input points
+// with the same margin.
+func latestPoints(buys, sells []*limiter.Limiter, buyPoint, sellPoint point.Point) (point.Point, point.Point) {
+	buy := *buys[len(buys)-1].Point()
+	if n := len(sells); n == len(buys) {
+		return buy, *sells[n-1].Point()
+	}
+	delta := buy.Price.Sub(buyPoint.Price)
+	sell := sellPoint
+	sell.Price = sell.Price.Add(delta)
+	sell.Cancel = sell.Cancel.Add(delta)
+	return buy, sell
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package looper
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// State is the position of the looper in it's buy-sell cycle. State is saved
+// to the database, so that a resumed looper continues from the exact step.
+type State int32
+
+const (
+	// NeedBuy indicates that a new limit-buy must be added.
+	NeedBuy State = iota + 1
+
+	// RunningBuy indicates that the last limit-buy is in progress.
+	RunningBuy
+
+	// NeedSell indicates that a new limit-sell must be added.
+	NeedSell
+
+	// RunningSell indicates that the last limit-sell is in progress.
+	RunningSell
+)
+
+func (s State) String() string {
+	switch s {
+	case NeedBuy:
+		return "NeedBuy"
+	case RunningBuy:
+		return "RunningBuy"
+	case NeedSell:
+		return "NeedSell"
+	case RunningSell:
+		return "RunningSell"
+	}
+	return fmt.Sprintf("State(%d)", int32(s))
+}
+
+func parseState(s string) (State, error) {
+	for _, v := range []State{NeedBuy, RunningBuy, NeedSell, RunningSell} {
+		if s == v.String() {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid looper state %q", s)
+}
+
+// State returns the current state of the looper.
+func (v *Looper) State() State {
+	return State(v.state.Load())
+}
+
+func (v *Looper) setState(s State) {
+	v.state.Store(int32(s))
+}
+
+// holdings returns the filled buy size that is not sold yet.
+func (v *Looper) holdings() decimal.Decimal {
+	var bought decimal.Decimal
+	for _, b := range v.buys {
+		bought = bought.Add(b.FilledSize())
+	}
+	var sold decimal.Decimal
+	for _, s := range v.sells {
+		sold = sold.Add(s.FilledSize())
+	}
+	return bought.Sub(sold)
+}
+
+// settledState returns the state to move into after a limit-buy or a
+// limit-sell is completed. A buy is preferred while the holding size is less
+// than the buy size, so holdings that are enough for a smaller sell size are
+// topped up to a full buy first.
+func (v *Looper) settledState() State {
+	if v.holdings().LessThan(v.buyPoint.Size) {
+		return NeedBuy
+	}
+	return NeedSell
+}
+
+// inferState returns the state for loopers saved without one, which is derived
+// from the pending sizes of the last limiters.
+func (v *Looper) inferState() State {
+	if n := len(v.sells); n > 0 && !v.sells[n-1].PendingSize().IsZero() {
+		return RunningSell
+	}
+	if n := len(v.buys); n > 0 && !v.buys[n-1].PendingSize().IsZero() {
+		return RunningBuy
+	}
+	return v.settledState()
+}
+
+// resumeState returns the state to resume from, which is the saved state
+// unless it refers to a limiter that doesn't exist.
+func (v *Looper) resumeState() State {
+	switch s := v.State(); s {
+	case NeedBuy, NeedSell:
+		return s
+	case RunningBuy:
+		if len(v.buys) > 0 {
+			return s
+		}
+	case RunningSell:
+		if len(v.sells) > 0 {
+			return s
+		}
+	}
+	return v.inferState()
+}
@@ -62,8 +62,12 @@ func (v *Looper) Status(period *timerange.Range) *trader.Status {
 			UID:          v.uid,
 			ProductID:    v.productID,
 			ExchangeName: v.exchangeName,
+			Tag:          v.buyPoint.Tag,
 			Summary: &trader.Summary{
 				Budget: v.BudgetAt(0.25),
+
+				BoughtResidualSize: v.boughtResidualSize(),
+				SoldResidualSize:   v.soldResidualSize(),
 			},
 		}
 	}
@@ -93,11 +97,15 @@ func (v *Looper) Status(period *timerange.Range) *trader.Status {
 		pairs = append(pairs, pair)
 	}
 
-	nbuys, nsells := 0, 0
+	quote := exchange.QuoteCurrency(v.productID)
+	otherFees := make(map[string]decimal.Decimal)
+
+	nbuys, nsells, nloops := 0, 0, 0
 	var sellFeesTotal, sellSizeTotal, sellValueTotal decimal.Decimal
 	var buyFeesTotal, buySizeTotal, buyValueTotal decimal.Decimal
 	var unsoldFeesTotal, unsoldSizeTotal, unsoldValueTotal decimal.Decimal
 	var oversoldFeesTotal, oversoldSizeTotal, oversoldValueTotal decimal.Decimal
+	var trades []*trader.TradeProfit
 
 	for _, bs := range pairs {
 		btime := actionTime(bs[0])
@@ -117,7 +125,8 @@ func (v *Looper) Status(period *timerange.Range) *trader.Status {
 		if sellInRange {
 			nsells++
 			for _, s := range bs[1] {
-				sfees := exchange.FilledFee(s.Orders)
+				sfees := exchange.FilledQuoteFee(s.Orders, quote)
+				exchange.AddOtherFees(otherFees, s.Orders, quote)
 				ssize := exchange.FilledSize(s.Orders)
 				svalue := exchange.FilledValue(s.Orders)
 
@@ -138,9 +147,14 @@ func (v *Looper) Status(period *timerange.Range) *trader.Status {
 			nbuys++
 		}
 
+		if sellInRange && len(bs[0]) > 0 {
+			nloops++
+		}
+
 		if buyInRange || sellInRange {
 			for _, b := range bs[0] {
-				bfees := exchange.FilledFee(b.Orders)
+				bfees := exchange.FilledQuoteFee(b.Orders, quote)
+				exchange.AddOtherFees(otherFees, b.Orders, quote)
 				bsize := exchange.FilledSize(b.Orders)
 				bvalue := exchange.FilledValue(b.Orders)
 
@@ -155,7 +169,7 @@ func (v *Looper) Status(period *timerange.Range) *trader.Status {
 		}
 
 		for _, u := range unsoldActions(bs[0], bs[1]) {
-			ufees := exchange.FilledFee(u.Orders)
+			ufees := exchange.FilledQuoteFee(u.Orders, quote)
 			usize := exchange.FilledSize(u.Orders)
 			uvalue := exchange.FilledValue(u.Orders)
 
@@ -176,6 +190,15 @@ func (v *Looper) Status(period *timerange.Range) *trader.Status {
 		unsoldSizeTotal = unsoldSizeTotal.Add(pusize)
 		unsoldValueTotal = unsoldValueTotal.Add(puvalue)
 
+		// Realized profit of the trade is computed for the size that is both
+		// bought and sold.
+		if sellInRange && pssize.IsPositive() && pbsize.IsPositive() {
+			size := decimal.Min(pssize, pbsize)
+			proceeds := psvalue.Sub(psfees).Mul(size).Div(pssize)
+			cost := pbvalue.Add(pbfees).Mul(size).Div(pbsize)
+			trades = append(trades, &trader.TradeProfit{Time: stime, Profit: proceeds.Sub(cost)})
+		}
+
 		sizediff := pbsize.Sub(pssize)
 		if sizediff.IsNegative() {
 			// log.Printf("OVERSELL: %s sold size %s, but only bought size %s", bs[0][0].PairingKey, pssize.StringFixed(3), pbsize.StringFixed(3))
@@ -206,10 +229,12 @@ func (v *Looper) Status(period *timerange.Range) *trader.Status {
 		UID:          v.uid,
 		ProductID:    v.productID,
 		ExchangeName: v.exchangeName,
+		Tag:          v.buyPoint.Tag,
 
 		Summary: &trader.Summary{
 			NumBuys:  nbuys,
 			NumSells: nsells,
+			NumLoops: nloops,
 
 			SoldFees:  sellFeesTotal,
 			SoldSize:  sellSizeTotal,
@@ -227,10 +252,53 @@ func (v *Looper) Status(period *timerange.Range) *trader.Status {
 			OversoldSize:  oversoldSizeTotal,
 			OversoldValue: oversoldValueTotal,
 
+			BoughtResidualSize: v.boughtResidualSize(),
+			SoldResidualSize:   v.soldResidualSize(),
+
 			TimePeriod: *period,
+
+			Trades: trades,
 		},
 	}
+	if len(otherFees) > 0 {
+		s.OtherFees = otherFees
+	}
 	feePct, _ := s.FeePct().Float64()
 	s.Budget = v.BudgetAt(feePct)
 	return s
 }
+
+// LoopState returns the current state of the buy-sell loop, which is one of
+// "buying", "holding", "waiting" or "completed".
+func (v *Looper) LoopState() string {
+	switch v.State() {
+	case RunningBuy:
+		return "buying"
+	case NeedSell, RunningSell:
+		return "holding"
+	}
+	if v.isWindingDown() {
+		return "completed"
+	}
+	return "waiting"
+}
+
+// boughtResidualSize returns the total size left unfilled by the buy limiters
+// that are marked as complete manually.
+func (v *Looper) boughtResidualSize() decimal.Decimal {
+	var sum decimal.Decimal
+	for _, b := range v.buys {
+		sum = sum.Add(b.ResidualSize())
+	}
+	return sum
+}
+
+// soldResidualSize returns the total size left unfilled by the sell limiters
+// that are marked as complete manually.
+func (v *Looper) soldResidualSize() decimal.Decimal {
+	var sum decimal.Decimal
+	for _, s := range v.sells {
+		sum = sum.Add(s.ResidualSize())
+	}
+	return sum
+}
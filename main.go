@@ -16,6 +16,7 @@ import (
 	"github.com/bvk/tradebot/subcmds/job"
 	"github.com/bvk/tradebot/subcmds/limiter"
 	"github.com/bvk/tradebot/subcmds/looper"
+	"github.com/bvk/tradebot/subcmds/report"
 	"github.com/bvk/tradebot/subcmds/waller"
 )
 
@@ -28,6 +29,11 @@ func main() {
 		new(db.List),
 		new(db.Backup),
 		new(db.Restore),
+		new(db.Export),
+		new(db.Import),
+		new(db.Compact),
+		new(db.Orphans),
+		new(db.BackfillFinishTime),
 	}
 
 	fixCmds := []cli.Command{
@@ -40,24 +46,43 @@ func main() {
 		new(job.List),
 		new(job.Pause),
 		new(job.Resume),
+		new(job.StartPaused),
 		new(job.Cancel),
 		new(job.Actions),
 		new(job.Export),
 		new(job.Import),
 		new(job.SetName),
+		new(job.Note),
 		new(job.SetOption),
+		new(job.Config),
+		new(job.Verify),
+		new(job.Watch),
+		new(job.Dump),
+		new(job.Restore),
+		new(job.Clone),
 	}
 
 	limiterCmds := []cli.Command{
 		new(limiter.Add),
 		new(limiter.List),
 		new(limiter.Get),
+		new(limiter.Pending),
+		new(limiter.Hold),
+		new(limiter.Merge),
+		new(limiter.Complete),
+		new(limiter.SyncIDGen),
+		new(limiter.ClientIDs),
+		new(limiter.Gap),
+		new(limiter.Trail),
 	}
 
 	looperCmds := []cli.Command{
 		new(looper.Add),
 		new(looper.List),
 		new(looper.Get),
+		new(looper.Repair),
+		new(looper.Hold),
+		new(looper.Split),
 	}
 
 	wallerCmds := []cli.Command{
@@ -65,23 +90,47 @@ func main() {
 		new(waller.List),
 		new(waller.Get),
 		new(waller.Query),
+		new(waller.Performance),
+		new(waller.Analyze),
+		new(waller.Suggest),
+		new(waller.Lint),
+		new(waller.Chart),
+		new(waller.Status),
+		new(waller.Sim),
+		new(waller.WhatIf),
 		new(waller.Upgrade),
 	}
 
 	exchangeCmds := []cli.Command{
+		new(exchange.FeeTier),
+		new(exchange.CheckAuth),
 		new(exchange.GetOrder),
+		new(exchange.GetOrders),
 		new(exchange.GetProduct),
+		new(exchange.Repeg),
+		new(exchange.CancelStale),
+		new(exchange.Snapshot),
+	}
+
+	reportCmds := []cli.Command{
+		new(report.Gains),
+		new(report.Orders),
+		new(report.Fees),
+		new(report.Diff),
+		new(report.TimeInForce),
 	}
 
 	coinbaseCmds := []cli.Command{
 		new(coinbase.Sync),
 		new(coinbase.List),
+		new(coinbase.RecordTicks),
 	}
 
 	cmds := []cli.Command{
 		new(subcmds.Run),
 		new(subcmds.Status),
 		new(subcmds.IDGen),
+		new(subcmds.KillSwitch),
 		cli.CommandGroup("fix", "Fix misc. metadata issues", fixCmds...),
 		cli.CommandGroup("job", "Control trader jobs", jobCmds...),
 		cli.CommandGroup("db", "View/update database directly", dbCmds...),
@@ -90,6 +139,7 @@ func main() {
 		cli.CommandGroup("waller", "Manage trades in a price range", wallerCmds...),
 		cli.CommandGroup("exchange", "View/query exchange directly", exchangeCmds...),
 		cli.CommandGroup("coinbase", "Handles coinbase specific operations", coinbaseCmds...),
+		cli.CommandGroup("report", "Prints reports from the trade history", reportCmds...),
 	}
 	if err := cli.Run(context.Background(), cmds, os.Args[1:]); err != nil {
 		log.Fatal(err)
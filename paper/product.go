@@ -0,0 +1,508 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+// Package paper implements a simulated exchange product that fills limit
+// orders against the tickers fed by the caller.
+package paper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bvk/tradebot/exchange"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const ExchangeName = "paper"
+
+type Options struct {
+	// FeePct is the fee percentage charged for every filled order.
+	FeePct float64
+
+	BaseMinSize   decimal.Decimal
+	BaseIncrement decimal.Decimal
+}
+
+type subscription[T any] struct {
+	ch   chan T
+	done chan struct{}
+
+	// filter, when non-nil, returns false for the values that must not be
+	// delivered to the subscriber.
+	filter func(T) bool
+}
+
+type paperOrder struct {
+	order *exchange.Order
+	size  decimal.Decimal
+	price decimal.Decimal
+
+	// trigger is non-zero for the stop-limit orders, which are not filled till
+	// a ticker reaches the trigger price.
+	trigger decimal.Decimal
+}
+
+// Product is a simulated exchange.Product. Limit orders are filled completely
+// at the limit price when a ticker crosses the order price.
+type Product struct {
+	productID string
+
+	opts Options
+
+	mu sync.Mutex
+
+	lastTicker *exchange.Ticker
+
+	orderMap       map[exchange.OrderID]*paperOrder
+	clientOrderMap map[string]exchange.OrderID
+
+	nextSubID  int
+	tickerSubs map[int]*subscription[*exchange.Ticker]
+	orderSubs  map[int]*subscription[*exchange.Order]
+
+	// subsChangedCh is closed and replaced when a subscription is added or
+	// removed.
+	subsChangedCh chan struct{}
+
+	// filledSubs are the filtered order update subscriptions that were sent a
+	// filled order by the last FeedTicker.
+	filledSubs []*subscription[*exchange.Order]
+}
+
+var _ exchange.Product = &Product{}
+
+func New(productID string, opts *Options) *Product {
+	if opts == nil {
+		opts = new(Options)
+	}
+	return &Product{
+		productID:      productID,
+		opts:           *opts,
+		orderMap:       make(map[exchange.OrderID]*paperOrder),
+		clientOrderMap: make(map[string]exchange.OrderID),
+		tickerSubs:     make(map[int]*subscription[*exchange.Ticker]),
+		orderSubs:      make(map[int]*subscription[*exchange.Order]),
+		subsChangedCh:  make(chan struct{}),
+	}
+}
+
+func (p *Product) Close() error {
+	return nil
+}
+
+func (p *Product) ProductID() string {
+	return p.productID
+}
+
+func (p *Product) ExchangeName() string {
+	return ExchangeName
+}
+
+func (p *Product) BaseMinSize() decimal.Decimal {
+	return p.opts.BaseMinSize
+}
+
+func (p *Product) BaseIncrement() decimal.Decimal {
+	return p.opts.BaseIncrement
+}
+
+func (p *Product) LastPrice(ctx context.Context) (decimal.Decimal, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.lastTicker == nil {
+		return decimal.Zero, fmt.Errorf("no tickers are fed yet: %w", os.ErrNotExist)
+	}
+	return p.lastTicker.Price, nil
+}
+
+func subscribe[T any](p *Product, subs map[int]*subscription[T], size int, filter func(T) bool) (<-chan T, func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := p.nextSubID
+	p.nextSubID++
+
+	sub := &subscription[T]{
+		ch:     make(chan T, size),
+		done:   make(chan struct{}),
+		filter: filter,
+	}
+	subs[id] = sub
+	p.notifySubsChanged()
+
+	var once sync.Once
+	stopf := func() {
+		once.Do(func() {
+			p.mu.Lock()
+			delete(subs, id)
+			close(sub.done)
+			p.notifySubsChanged()
+			p.mu.Unlock()
+		})
+	}
+	return sub.ch, stopf
+}
+
+func (p *Product) TickerCh() (<-chan *exchange.Ticker, func()) {
+	return subscribe(p, p.tickerSubs, 0, nil)
+}
+
+func (p *Product) OrderUpdatesCh() (<-chan *exchange.Order, func()) {
+	return subscribe(p, p.orderSubs, 1024, nil)
+}
+
+func (p *Product) OrderUpdatesChFiltered(match func(*exchange.Order) bool) (<-chan *exchange.Order, func()) {
+	return subscribe(p, p.orderSubs, 1024, match)
+}
+
+// ReconnectCh returns a channel that never receives any value cause paper
+// product has no order updates feed to reconnect.
+func (p *Product) ReconnectCh() (<-chan time.Time, func()) {
+	return make(chan time.Time), func() {}
+}
+
+// AvailableBalance returns errors.ErrUnsupported cause paper product doesn't
+// track any account balances.
+func (p *Product) AvailableBalance(ctx context.Context, currency string) (decimal.Decimal, error) {
+	return decimal.Zero, errors.ErrUnsupported
+}
+
+// Ping always succeeds cause paper product has no remote feeds.
+func (p *Product) Ping(ctx context.Context) error {
+	return nil
+}
+
+// NumTickerSubscribers returns the number of active ticker subscriptions.
+func (p *Product) NumTickerSubscribers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.tickerSubs)
+}
+
+// notifySubsChanged wakes up the WaitTickerSubscribers callers. Caller must
+// hold the lock.
+func (p *Product) notifySubsChanged() {
+	close(p.subsChangedCh)
+	p.subsChangedCh = make(chan struct{})
+}
+
+// WaitTickerSubscribers blocks till the filtered order update subscriptions
+// that were sent a filled order by the last FeedTicker are stopped and there
+// are at least n ticker subscriptions. Simulations use it to acknowledge that
+// the last ticker is processed, including by the jobs that replace the
+// completed limiters, before the next ticker is fed.
+func (p *Product) WaitTickerSubscribers(ctx context.Context, n int) error {
+	for {
+		p.mu.Lock()
+		ready := len(p.tickerSubs) >= n
+		for _, sub := range p.filledSubs {
+			select {
+			case <-sub.done:
+			default:
+				ready = false
+			}
+		}
+		changedCh := p.subsChangedCh
+		p.mu.Unlock()
+
+		if ready {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-changedCh:
+		}
+	}
+}
+
+func (p *Product) create(side, clientOrderID string, size, price decimal.Decimal, tag string) (exchange.OrderID, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if id, ok := p.clientOrderMap[clientOrderID]; ok {
+		return id, nil
+	}
+	if size.LessThan(p.opts.BaseMinSize) {
+		return "", fmt.Errorf("min size is %s: %w", p.opts.BaseMinSize, os.ErrInvalid)
+	}
+
+	var now time.Time
+	if p.lastTicker != nil {
+		now = p.lastTicker.Timestamp.Time
+	}
+	id := exchange.OrderID(uuid.NewString())
+	p.orderMap[id] = &paperOrder{
+		size:  size,
+		price: price,
+		order: &exchange.Order{
+			OrderID:       id,
+			ClientOrderID: clientOrderID,
+			Tag:           tag,
+			Side:          side,
+			CreateTime:    exchange.RemoteTime{Time: now},
+			Status:        "OPEN",
+		},
+	}
+	p.clientOrderMap[clientOrderID] = id
+	return id, nil
+}
+
+func (p *Product) LimitBuy(ctx context.Context, clientOrderID string, size, price decimal.Decimal, _ time.Duration, tag string) (exchange.OrderID, error) {
+	return p.create("BUY", clientOrderID, size, price, tag)
+}
+
+func (p *Product) LimitSell(ctx context.Context, clientOrderID string, size, price decimal.Decimal, _ time.Duration, tag string) (exchange.OrderID, error) {
+	return p.create("SELL", clientOrderID, size, price, tag)
+}
+
+// StopLimitBuy and StopLimitSell create limit orders that are filled only
+// after a ticker reaches the trigger price.
+func (p *Product) StopLimitBuy(ctx context.Context, clientOrderID string, size, limitPrice, triggerPrice decimal.Decimal, _ time.Duration, tag string) (exchange.OrderID, error) {
+	return p.createStopLimit("BUY", clientOrderID, size, limitPrice, triggerPrice, tag)
+}
+
+func (p *Product) StopLimitSell(ctx context.Context, clientOrderID string, size, limitPrice, triggerPrice decimal.Decimal, _ time.Duration, tag string) (exchange.OrderID, error) {
+	return p.createStopLimit("SELL", clientOrderID, size, limitPrice, triggerPrice, tag)
+}
+
+func (p *Product) createStopLimit(side, clientOrderID string, size, limitPrice, triggerPrice decimal.Decimal, tag string) (exchange.OrderID, error) {
+	if !triggerPrice.IsPositive() {
+		return "", fmt.Errorf("trigger price must be positive: %w", os.ErrInvalid)
+	}
+	id, err := p.create(side, clientOrderID, size, limitPrice, tag)
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if v := p.orderMap[id]; v.order.FilledSize.IsZero() && !v.order.Done {
+		v.trigger = triggerPrice
+	}
+	return id, nil
+}
+
+// MarketBuy and MarketSell create orders that are filled completely at the
+// last ticker price.
+func (p *Product) MarketBuy(ctx context.Context, clientOrderID string, size decimal.Decimal) (exchange.OrderID, error) {
+	return p.createMarket(ctx, "BUY", clientOrderID, size)
+}
+
+func (p *Product) MarketSell(ctx context.Context, clientOrderID string, size decimal.Decimal) (exchange.OrderID, error) {
+	return p.createMarket(ctx, "SELL", clientOrderID, size)
+}
+
+func (p *Product) createMarket(ctx context.Context, side, clientOrderID string, size decimal.Decimal) (exchange.OrderID, error) {
+	p.mu.Lock()
+	if p.lastTicker == nil {
+		p.mu.Unlock()
+		return "", fmt.Errorf("no tickers are fed yet: %w", os.ErrNotExist)
+	}
+	price := p.lastTicker.Price
+	p.mu.Unlock()
+
+	id, err := p.create(side, clientOrderID, size, price, "")
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	v := p.orderMap[id]
+	if v.order.Done {
+		p.mu.Unlock()
+		return id, nil
+	}
+	p.fill(v, p.lastTicker)
+	v.order.Liquidity = exchange.LiquidityTaker
+	order := *v.order
+	subs := p.orderSubscribers()
+	p.mu.Unlock()
+
+	sendAll(ctx, subs, &order)
+	return id, nil
+}
+
+// fill marks the order as completely filled at the order price. Caller must
+// hold the lock.
+func (p *Product) fill(v *paperOrder, ticker *exchange.Ticker) {
+	fee := v.size.Mul(v.price).Mul(decimal.NewFromFloat(p.opts.FeePct)).Div(decimal.NewFromInt(100))
+	v.order.FilledSize = v.size
+	v.order.FilledPrice = v.price
+	v.order.Fee = fee
+	v.order.LastFillFee = fee
+	v.order.Liquidity = exchange.LiquidityMaker
+	v.order.Status = "FILLED"
+	v.order.Done = true
+	v.order.FinishTime = ticker.Timestamp
+}
+
+func (p *Product) Get(ctx context.Context, id exchange.OrderID) (*exchange.Order, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	v, ok := p.orderMap[id]
+	if !ok {
+		return nil, fmt.Errorf("order %s: %w", id, exchange.ErrNotFound)
+	}
+	order := *v.order
+	return &order, nil
+}
+
+func (p *Product) Cancel(ctx context.Context, id exchange.OrderID) error {
+	p.mu.Lock()
+	v, ok := p.orderMap[id]
+	if !ok {
+		p.mu.Unlock()
+		return fmt.Errorf("order %s: %w", id, exchange.ErrNotFound)
+	}
+	if v.order.Done {
+		p.mu.Unlock()
+		return nil
+	}
+	v.order.Status = "CANCELLED"
+	v.order.Done = true
+	v.order.DoneReason = "CANCELLED"
+	order := *v.order
+	subs := p.orderSubscribers()
+	p.mu.Unlock()
+
+	sendAll(ctx, subs, &order)
+	return nil
+}
+
+// Reduce shrinks an open order to the new size.
+func (p *Product) Reduce(ctx context.Context, id exchange.OrderID, newSize decimal.Decimal) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	v, ok := p.orderMap[id]
+	if !ok {
+		return fmt.Errorf("order %s: %w", id, exchange.ErrNotFound)
+	}
+	if v.order.Done {
+		return fmt.Errorf("order %s is already complete: %w", id, os.ErrInvalid)
+	}
+	if !newSize.LessThan(v.size) {
+		return fmt.Errorf("new size %s is not less than the order size %s: %w", newSize, v.size, os.ErrInvalid)
+	}
+	if !newSize.GreaterThan(v.order.FilledSize) {
+		return fmt.Errorf("new size %s is not more than the filled size %s: %w", newSize, v.order.FilledSize, os.ErrInvalid)
+	}
+	v.size = newSize
+	return nil
+}
+
+// Reject simulates an exchange rejection of an open order after it was
+// accepted (ex: self-trade prevention). A terminal update with a non-empty
+// DoneReason is sent to the order update subscribers.
+func (p *Product) Reject(ctx context.Context, id exchange.OrderID, reason string) error {
+	p.mu.Lock()
+	v, ok := p.orderMap[id]
+	if !ok {
+		p.mu.Unlock()
+		return fmt.Errorf("order %s: %w", id, exchange.ErrNotFound)
+	}
+	if v.order.Done {
+		p.mu.Unlock()
+		return fmt.Errorf("order %s is already complete: %w", id, os.ErrInvalid)
+	}
+	v.order.Status = "FAILED"
+	v.order.Done = true
+	v.order.DoneReason = "REJECTED: " + reason
+	order := *v.order
+	subs := p.orderSubscribers()
+	p.mu.Unlock()
+
+	sendAll(ctx, subs, &order)
+	return nil
+}
+
+func (p *Product) orderSubscribers() []*subscription[*exchange.Order] {
+	var subs []*subscription[*exchange.Order]
+	for _, sub := range p.orderSubs {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+func sendAll[T any](ctx context.Context, subs []*subscription[T], v T) {
+	for _, sub := range subs {
+		if sub.filter != nil && !sub.filter(v) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.done:
+		case sub.ch <- v:
+		}
+	}
+}
+
+// FeedTicker updates the current price and fills the open orders that are
+// crossed by the ticker price. Order updates for the filled orders are sent
+// before the ticker is delivered to all ticker subscribers. Ticker channels
+// are unbuffered, so a ticker is delivered only after the subscribers have
+// processed the previous ticker.
+func (p *Product) FeedTicker(ctx context.Context, ticker *exchange.Ticker) error {
+	p.mu.Lock()
+	p.lastTicker = ticker
+
+	var filled []*exchange.Order
+	for _, v := range p.orderMap {
+		if v.order.Done {
+			continue
+		}
+		if v.trigger.IsPositive() {
+			if (v.order.Side == "BUY" && ticker.Price.LessThan(v.trigger)) ||
+				(v.order.Side == "SELL" && ticker.Price.GreaterThan(v.trigger)) {
+				continue
+			}
+			// Order is placed on the book at the limit price once triggered.
+			v.trigger = decimal.Zero
+		}
+		if (v.order.Side == "BUY" && ticker.Price.LessThanOrEqual(v.price)) ||
+			(v.order.Side == "SELL" && ticker.Price.GreaterThanOrEqual(v.price)) {
+			p.fill(v, ticker)
+			order := *v.order
+			filled = append(filled, &order)
+		}
+	}
+
+	orderSubs := p.orderSubscribers()
+	var tickerSubs []*subscription[*exchange.Ticker]
+	for _, sub := range p.tickerSubs {
+		tickerSubs = append(tickerSubs, sub)
+	}
+	p.mu.Unlock()
+
+	var filledSubs []*subscription[*exchange.Order]
+	for _, sub := range orderSubs {
+		if sub.filter == nil {
+			continue
+		}
+		for _, order := range filled {
+			if sub.filter(order) {
+				filledSubs = append(filledSubs, sub)
+				break
+			}
+		}
+	}
+	p.mu.Lock()
+	p.filledSubs = filledSubs
+	p.mu.Unlock()
+
+	for _, order := range filled {
+		log.Printf("paper: %s order %s is filled at price %s", order.Side, order.OrderID, order.FilledPrice)
+		sendAll(ctx, orderSubs, order)
+	}
+	sendAll(ctx, tickerSubs, ticker)
+	return ctx.Err()
+}
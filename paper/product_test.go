@@ -0,0 +1,137 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package paper
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bvk/tradebot/exchange"
+	"github.com/shopspring/decimal"
+)
+
+func TestFeedTicker(t *testing.T) {
+	ctx := context.Background()
+
+	tickers, err := ReadTickers(strings.NewReader(`
+# comment
+2024-01-01T00:00:00Z 105
+2024-01-01T00:00:01Z 99.5
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tickers) != 2 {
+		t.Fatalf("want 2 tickers, got %d", len(tickers))
+	}
+
+	p := New("BTC-USD", &Options{FeePct: 1})
+	updatesCh, stopUpdates := p.OrderUpdatesCh()
+	defer stopUpdates()
+
+	id, err := p.LimitBuy(ctx, "client-1", decimal.NewFromInt(1), decimal.NewFromInt(100), 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.FeedTicker(ctx, tickers[0]); err != nil {
+		t.Fatal(err)
+	}
+	if order, _ := p.Get(ctx, id); order.Done {
+		t.Fatalf("buy order must not be filled above the limit price")
+	}
+
+	if err := p.FeedTicker(ctx, tickers[1]); err != nil {
+		t.Fatal(err)
+	}
+	order := <-updatesCh
+	if order.OrderID != id || !order.Done || order.Status != "FILLED" {
+		t.Fatalf("want filled order update for %s, got %v", id, order)
+	}
+	if !order.Fee.Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("want fee 1, got %s", order.Fee)
+	}
+}
+
+func TestOrderUpdatesChFiltered(t *testing.T) {
+	ctx := context.Background()
+	p := New("BTC-USD", nil)
+
+	match := func(clientOrderID string) func(*exchange.Order) bool {
+		return func(order *exchange.Order) bool { return order.ClientOrderID == clientOrderID }
+	}
+	ch1, stop1 := p.OrderUpdatesChFiltered(match("client-1"))
+	defer stop1()
+	ch2, stop2 := p.OrderUpdatesChFiltered(match("client-2"))
+	defer stop2()
+
+	id1, err := p.LimitBuy(ctx, "client-1", decimal.NewFromInt(1), decimal.NewFromInt(100), 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id2, err := p.LimitBuy(ctx, "client-2", decimal.NewFromInt(1), decimal.NewFromInt(90), 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Cancel(ctx, id2); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.FeedTicker(ctx, &exchange.Ticker{Price: decimal.NewFromInt(99)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if order := <-ch1; order.OrderID != id1 {
+		t.Fatalf("want update for %s, got %s", id1, order.OrderID)
+	}
+	if order := <-ch2; order.OrderID != id2 {
+		t.Fatalf("want update for %s, got %s", id2, order.OrderID)
+	}
+	select {
+	case order := <-ch1:
+		t.Fatalf("unexpected order update %s on the filtered channel", order.OrderID)
+	case order := <-ch2:
+		t.Fatalf("unexpected order update %s on the filtered channel", order.OrderID)
+	default:
+	}
+}
+
+func TestWaitTickerSubscribers(t *testing.T) {
+	ctx := context.Background()
+	p := New("BTC-USD", nil)
+
+	updatesCh, stopUpdates := p.OrderUpdatesChFiltered(func(order *exchange.Order) bool { return order.ClientOrderID == "client-1" })
+	if _, err := p.LimitBuy(ctx, "client-1", decimal.NewFromInt(1), decimal.NewFromInt(100), 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.FeedTicker(ctx, &exchange.Ticker{Price: decimal.NewFromInt(99)}); err != nil {
+		t.Fatal(err)
+	}
+	<-updatesCh
+
+	// Wait is not complete till the subscriber of the filled order stops and
+	// a replacement ticker subscription is added.
+	waitCh := make(chan error, 1)
+	go func() {
+		waitCh <- p.WaitTickerSubscribers(ctx, 1)
+	}()
+	stopUpdates()
+	select {
+	case err := <-waitCh:
+		t.Fatalf("want wait to block without ticker subscribers, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	_, stopTickers := p.TickerCh()
+	defer stopTickers()
+	if err := <-waitCh; err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait is canceled with the context.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := p.WaitTickerSubscribers(cctx, 2); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context canceled error, got %v", err)
+	}
+}
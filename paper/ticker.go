@@ -0,0 +1,63 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package paper
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/bvk/tradebot/exchange"
+	"github.com/shopspring/decimal"
+)
+
+// FormatTicker returns the ticker as a single line of text with the timestamp
+// in RFC3339Nano format and the price separated by a space.
+func FormatTicker(t *exchange.Ticker) string {
+	return fmt.Sprintf("%s %s", t.Timestamp.Time.UTC().Format(time.RFC3339Nano), t.Price)
+}
+
+// ReadTickers parses tickers from the reader, one per line, either in the
+// FormatTicker format or as JSON lines (as recorded by the coinbase client).
+// Empty lines and lines starting with '#' are ignored.
+func ReadTickers(r io.Reader) ([]*exchange.Ticker, error) {
+	var tickers []*exchange.Ticker
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "{") {
+			ticker := new(exchange.Ticker)
+			if err := json.Unmarshal([]byte(line), ticker); err != nil {
+				return nil, fmt.Errorf("line %d: could not parse json ticker: %w", lineno, err)
+			}
+			tickers = append(tickers, ticker)
+			continue
+		}
+		ts, ps, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: want timestamp and price fields", lineno)
+		}
+		timestamp, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: could not parse timestamp: %w", lineno, err)
+		}
+		price, err := decimal.NewFromString(strings.TrimSpace(ps))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: could not parse price: %w", lineno, err)
+		}
+		tickers = append(tickers, &exchange.Ticker{
+			Timestamp: exchange.RemoteTime{Time: timestamp},
+			Price:     price,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return tickers, nil
+}
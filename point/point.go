@@ -14,6 +14,9 @@ import (
 type Point gobs.Point
 
 func (p Point) String() string {
+	if p.SizeInQuote {
+		return fmt.Sprintf("%s:$%s@%s", p.Side(), p.Size, p.Price.StringFixed(2))
+	}
 	return fmt.Sprintf("%s:%s@%s", p.Side(), p.Size, p.Price.StringFixed(2))
 }
 
@@ -34,7 +37,13 @@ func (p *Point) Check() error {
 	if p.Price.IsNegative() {
 		return fmt.Errorf("price cannot be negative")
 	}
+	if p.CancelPct.IsNegative() || p.CancelPct.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+		return fmt.Errorf("cancel-pct must be within 0-100 range")
+	}
 	if p.Cancel.IsZero() {
+		if !p.CancelPct.IsZero() {
+			return fmt.Errorf("cancel-pct must be resolved to a cancel-price")
+		}
 		return fmt.Errorf("cancel-price cannot be zero")
 	}
 	if p.Cancel.IsNegative() {
@@ -47,7 +56,7 @@ func (p *Point) Check() error {
 }
 
 func Equal(a, b gobs.Point) bool {
-	return a.Size.Equal(b.Size) && a.Price.Equal(b.Price) && a.Cancel.Equal(b.Cancel)
+	return a.Size.Equal(b.Size) && a.Price.Equal(b.Price) && a.Cancel.Equal(b.Cancel) && a.SizeInQuote == b.SizeInQuote
 }
 
 func (p Point) Equal(v *Point) bool {
@@ -65,6 +74,44 @@ func (p *Point) Side() string {
 	return "BUY"
 }
 
+// ResolveCancel sets the absolute cancel price from the CancelPct percentage
+// offset for the given side, i.e., above the price for BUY and below the price
+// for SELL. Absolute cancel price takes precedence when both are set, so it is
+// not modified when it is already non-zero.
+func (p *Point) ResolveCancel(side string) error {
+	if !p.Cancel.IsZero() || p.CancelPct.IsZero() {
+		return nil
+	}
+	offset := p.Price.Mul(p.CancelPct).Div(decimal.NewFromInt(100))
+	switch side {
+	case "BUY":
+		p.Cancel = p.Price.Add(offset)
+	case "SELL":
+		p.Cancel = p.Price.Sub(offset)
+	default:
+		return fmt.Errorf("side must be one of BUY or SELL: %w", os.ErrInvalid)
+	}
+	return nil
+}
+
+// SnapCancel rounds the cancel price to a multiple of the product's price
+// increment, so that the cancel threshold compares exactly with the prices
+// where the orders can rest. Cancel price is rounded away from the point
+// price, i.e., up for BUY points and down for SELL points, so that the order
+// is never canceled before the ticker reaches the requested cancel price and
+// the cancel price never collapses onto the point price.
+func (p *Point) SnapCancel(increment decimal.Decimal) {
+	if !increment.IsPositive() || p.Cancel.Mod(increment).IsZero() {
+		return
+	}
+	n := p.Cancel.Div(increment)
+	if p.Side() == "BUY" {
+		p.Cancel = n.Ceil().Mul(increment)
+	} else {
+		p.Cancel = n.Floor().Mul(increment)
+	}
+}
+
 // FeeAt returns the fee incurred for the buy or sell at the given fee
 // percentage.
 func (p *Point) FeeAt(pct float64) decimal.Decimal {
@@ -74,9 +121,21 @@ func (p *Point) FeeAt(pct float64) decimal.Decimal {
 // Value returns the dollar amount for point (i.e, size*price) without
 // including any fee.
 func (p *Point) Value() decimal.Decimal {
+	if p.SizeInQuote {
+		return p.Size
+	}
 	return p.Size.Mul(p.Price)
 }
 
+// BaseSize returns the point size in base currency units. When the point size
+// is in quote currency units, it is translated using the point price.
+func (p *Point) BaseSize() decimal.Decimal {
+	if p.SizeInQuote {
+		return p.Size.Div(p.Price)
+	}
+	return p.Size
+}
+
 // SellPoint returns a sell point for the input buy point with the given profit
 // margin. Returned sell point uses the full size of the buy point as the sell
 // size and uses the same cancel price offset as the buy point, but on the
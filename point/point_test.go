@@ -0,0 +1,77 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package point
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestResolveCancel(t *testing.T) {
+	tests := []struct {
+		side, cancel, pct, want string
+	}{
+		{"BUY", "0", "0.5", "100.5"},
+		{"SELL", "0", "0.5", "99.5"},
+		{"BUY", "110", "0.5", "110"},
+		{"SELL", "90", "0.5", "90"},
+	}
+	for _, test := range tests {
+		p := &Point{
+			Size:      decimal.NewFromInt(1),
+			Price:     decimal.NewFromInt(100),
+			Cancel:    decimal.RequireFromString(test.cancel),
+			CancelPct: decimal.RequireFromString(test.pct),
+		}
+		if err := p.ResolveCancel(test.side); err != nil {
+			t.Fatal(err)
+		}
+		if !p.Cancel.Equal(decimal.RequireFromString(test.want)) {
+			t.Errorf("%s with cancel %s and cancel-pct %s: want %s, got %s", test.side, test.cancel, test.pct, test.want, p.Cancel)
+		}
+		if err := p.Check(); err != nil {
+			t.Error(err)
+		}
+		if p.Side() != test.side {
+			t.Errorf("want side %s, got %s", test.side, p.Side())
+		}
+	}
+
+	p := &Point{
+		Size:      decimal.NewFromInt(1),
+		Price:     decimal.NewFromInt(100),
+		CancelPct: decimal.NewFromInt(1),
+	}
+	if err := p.Check(); err == nil {
+		t.Errorf("unresolved cancel-pct point must fail the check")
+	}
+}
+
+func TestSnapCancel(t *testing.T) {
+	tests := []struct {
+		cancel, inc, want string
+	}{
+		{"100.503", "0.01", "100.51"},
+		{"99.497", "0.01", "99.49"},
+		{"100.5", "0.01", "100.5"},
+		{"100.5", "1", "101"},
+		{"99.5", "1", "99"},
+		{"100.503", "0", "100.503"},
+	}
+	for _, test := range tests {
+		p := &Point{
+			Size:   decimal.NewFromInt(1),
+			Price:  decimal.NewFromInt(100),
+			Cancel: decimal.RequireFromString(test.cancel),
+		}
+		side := p.Side()
+		p.SnapCancel(decimal.RequireFromString(test.inc))
+		if !p.Cancel.Equal(decimal.RequireFromString(test.want)) {
+			t.Errorf("cancel %s with increment %s: want %s, got %s", test.cancel, test.inc, test.want, p.Cancel)
+		}
+		if p.Side() != side {
+			t.Errorf("snap must not change the side %s", side)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/bvk/tradebot/api"
+)
+
+type authScope int
+
+const (
+	scopeNone authScope = iota
+	scopeReadOnly
+	scopeAdmin
+)
+
+// readOnlyPaths holds the api endpoints that do not modify the jobs or the
+// exchanges, which are allowed for the read-only tokens. All other endpoints
+// require an admin token.
+var readOnlyPaths = map[string]bool{
+	api.JobListPath:            true,
+	api.JobConfigPath:          true,
+	api.JobVerifyPath:          true,
+	api.JobWatchPath:           true,
+	api.WallerStatusPath:       true,
+	api.ExchangeGetOrderPath:   true,
+	api.ExchangeGetOrdersPath:  true,
+	api.ExchangeGetProductPath: true,
+	api.ExchangeFeeTierPath:    true,
+	DebugJobsPath:              true,
+	DebugHealthPath:            true,
+}
+
+// tokenScope returns the scope of the bearer token in the request.
+func (s *Server) tokenScope(r *http.Request) authScope {
+	token, ok := strings.CutPrefix(r.Header.Get("authorization"), "Bearer ")
+	if !ok || len(token) == 0 {
+		return scopeNone
+	}
+	match := func(tokens []string) bool {
+		found := false
+		for _, t := range tokens {
+			if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+				found = true
+			}
+		}
+		return found
+	}
+	if match(s.tokens.Admin) {
+		return scopeAdmin
+	}
+	if match(s.tokens.ReadOnly) {
+		return scopeReadOnly
+	}
+	return scopeNone
+}
+
+// authHandler wraps the handler to require a token with the scope. Requests
+// are not checked when the api tokens are not configured.
+func (s *Server) authHandler(scope authScope, h http.Handler) http.Handler {
+	if s.tokens == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := s.tokenScope(r)
+		if got == scopeNone {
+			w.Header().Set("www-authenticate", "Bearer")
+			http.Error(w, "missing or invalid api token", http.StatusUnauthorized)
+			return
+		}
+		if got < scope {
+			http.Error(w, "api token is not allowed to use this endpoint", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// readOnlyDBPaths holds the database api endpoints that only read from the
+// snapshots, which are allowed for the read-only tokens. Transactions can
+// modify the database, so they require an admin token.
+var readOnlyDBPaths = map[string]bool{
+	"/new-snap":     true,
+	"/new-snapshot": true,
+	"/snap/get":     true,
+	"/snap/ascend":  true,
+	"/snap/descend": true,
+	"/snap/scan":    true,
+	"/snap/discard": true,
+	"/it/fetch":     true,
+}
+
+// DatabaseHandler wraps the database handler to allow the read-only tokens
+// to read the database through the snapshots, which is used by the status and
+// summary commands. All other database requests require an admin token. Input
+// handler must see the paths without the database handler prefix.
+func (s *Server) DatabaseHandler(h http.Handler) http.Handler {
+	if s.tokens == nil {
+		return h
+	}
+	readOnly := s.authHandler(scopeReadOnly, h)
+	admin := s.authHandler(scopeAdmin, h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if readOnlyDBPaths[r.URL.Path] {
+			readOnly.ServeHTTP(w, r)
+			return
+		}
+		admin.ServeHTTP(w, r)
+	})
+}
+
+// ReadOnlyHandler wraps a handler that is not part of the server api to
+// require a read-only or admin token when the api tokens are configured.
+func (s *Server) ReadOnlyHandler(h http.Handler) http.Handler {
+	return s.authHandler(scopeReadOnly, h)
+}
+
+// AdminHandler wraps a handler that is not part of the server api, like the
+// database handler, to require an admin token when the api tokens are
+// configured.
+func (s *Server) AdminHandler(h http.Handler) http.Handler {
+	return s.authHandler(scopeAdmin, h)
+}
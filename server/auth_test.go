@@ -0,0 +1,88 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthHandler(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	s := &Server{tokens: &APITokens{ReadOnly: []string{"reader"}, Admin: []string{"admin"}}}
+	readOnly := s.authHandler(scopeReadOnly, ok)
+	admin := s.authHandler(scopeAdmin, ok)
+
+	status := func(h http.Handler, token string) int {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if len(token) != 0 {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		token   string
+		want    int
+	}{
+		{"no-token", readOnly, "", http.StatusUnauthorized},
+		{"bad-token", readOnly, "unknown", http.StatusUnauthorized},
+		{"reader-read", readOnly, "reader", http.StatusOK},
+		{"admin-read", readOnly, "admin", http.StatusOK},
+		{"reader-admin", admin, "reader", http.StatusForbidden},
+		{"admin-admin", admin, "admin", http.StatusOK},
+	}
+	for _, test := range tests {
+		if got := status(test.handler, test.token); got != test.want {
+			t.Errorf("%s: want status %d, got %d", test.name, test.want, got)
+		}
+	}
+
+	// Authentication is disabled without the tokens.
+	open := &Server{}
+	if got := status(open.authHandler(scopeAdmin, ok), ""); got != http.StatusOK {
+		t.Errorf("want status %d without tokens, got %d", http.StatusOK, got)
+	}
+}
+
+func TestDatabaseHandler(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	s := &Server{tokens: &APITokens{ReadOnly: []string{"reader"}, Admin: []string{"admin"}}}
+	h := http.StripPrefix("/db", s.DatabaseHandler(ok))
+
+	status := func(path, token string) int {
+		r := httptest.NewRequest(http.MethodPost, path, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	tests := []struct {
+		path  string
+		token string
+		want  int
+	}{
+		{"/db/new-snapshot", "reader", http.StatusOK},
+		{"/db/snap/get", "reader", http.StatusOK},
+		{"/db/snap/ascend", "reader", http.StatusOK},
+		{"/db/it/fetch", "reader", http.StatusOK},
+		{"/db/new-tx", "reader", http.StatusForbidden},
+		{"/db/tx/set", "reader", http.StatusForbidden},
+		{"/db/tx/commit", "reader", http.StatusForbidden},
+		{"/db/tx/set", "admin", http.StatusOK},
+		{"/db/snap/get", "unknown", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		if got := status(test.path, test.token); got != test.want {
+			t.Errorf("%s with %q token: want status %d, got %d", test.path, test.token, test.want, got)
+		}
+	}
+}
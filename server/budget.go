@@ -0,0 +1,78 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bvk/tradebot/syncmap"
+	"github.com/bvk/tradebot/trader"
+	"github.com/shopspring/decimal"
+)
+
+// ErrBudgetExceeded is returned when a new job would take the total budget of
+// the running jobs above the max-budget limit.
+var ErrBudgetExceeded = errors.New("max budget is exceeded")
+
+// budgetGuard limits the total budget footprint of the running jobs, i.e., the
+// quote funds reserved by their incomplete buys. New jobs reserve
+// their budget before they are started, so that the concurrent job creations
+// are serialized against the limit, even before the jobs are running.
+type budgetGuard struct {
+	max decimal.Decimal
+
+	mu sync.Mutex
+
+	// reserved holds the budgets of the jobs created by this process, which
+	// are released when the jobs stop.
+	reserved map[string]decimal.Decimal
+}
+
+func newBudgetGuard(max decimal.Decimal) *budgetGuard {
+	return &budgetGuard{
+		max:      max,
+		reserved: make(map[string]decimal.Decimal),
+	}
+}
+
+// reserve verifies that the budget for a new job is within the limit along
+// with the running jobs and the existing reservations and reserves it. Limit
+// is not checked when the max budget is zero.
+func (g *budgetGuard) reserve(uid string, budget decimal.Decimal, jobMap *syncmap.Map[string, trader.Trader]) error {
+	if !g.max.IsPositive() {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Existing reservation for the same job is replaced, so that a job is
+	// never counted twice.
+	var total decimal.Decimal
+	for id, v := range g.reserved {
+		if id != uid {
+			total = total.Add(v)
+		}
+	}
+	jobMap.Range(func(id string, v trader.Trader) bool {
+		if _, ok := g.reserved[id]; !ok && id != uid {
+			total = total.Add(v.BudgetFootprint())
+		}
+		return true
+	})
+	if x := total.Add(budget); x.GreaterThan(g.max) {
+		return fmt.Errorf("job budget %s with the committed budget %s is over the limit %s: %w", budget.StringFixed(2), total.StringFixed(2), g.max.StringFixed(2), ErrBudgetExceeded)
+	}
+	g.reserved[uid] = budget
+	return nil
+}
+
+// release removes the budget reservation for a job, if any.
+func (g *budgetGuard) release(uid string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.reserved, uid)
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/point"
+	"github.com/bvk/tradebot/syncmap"
+	"github.com/bvk/tradebot/trader"
+	"github.com/bvkgo/kv"
+	"github.com/bvkgo/kv/kvmemdb"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestBudgetGuardConcurrent(t *testing.T) {
+	d := decimal.RequireFromString
+
+	// Running jobs that are not reserved by the guard.
+	var jobMap syncmap.Map[string, trader.Trader]
+	running, err := limiter.New(uuid.NewString(), "paper", "BTC-USD", &point.Point{Size: d("1"), Price: d("100"), Cancel: d("110")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobMap.Store(running.UID(), running)
+	runningBudget := running.BudgetFootprint()
+
+	// Running sells reserve no quote funds, so they must not be counted.
+	sell, err := limiter.New(uuid.NewString(), "paper", "BTC-USD", &point.Point{Size: d("100"), Price: d("100"), Cancel: d("90")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobMap.Store(sell.UID(), sell)
+
+	max := d("1000")
+	g := newBudgetGuard(max)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var reserved []string
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			uid := uuid.NewString()
+			if err := g.reserve(uid, d("100"), &jobMap); err != nil {
+				if !errors.Is(err, ErrBudgetExceeded) {
+					t.Errorf("want ErrBudgetExceeded, got %v", err)
+				}
+				return
+			}
+			mu.Lock()
+			reserved = append(reserved, uid)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(reserved) != 8 {
+		t.Fatalf("want 8 reservations, got %d", len(reserved))
+	}
+	total := runningBudget.Add(d("100").Mul(decimal.NewFromInt(int64(len(reserved)))))
+	if total.GreaterThan(max) {
+		t.Fatalf("total budget %s is over the limit %s", total, max)
+	}
+
+	// Released budget can be reserved again.
+	if err := g.reserve(uuid.NewString(), d("100"), &jobMap); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("want ErrBudgetExceeded, got %v", err)
+	}
+	g.release(reserved[0])
+	if err := g.reserve(uuid.NewString(), d("100"), &jobMap); err != nil {
+		t.Fatalf("want reservation after a release, got %v", err)
+	}
+
+	// Zero max budget disables the guard.
+	if err := newBudgetGuard(decimal.Zero).reserve(uuid.NewString(), d("1000000"), &jobMap); err != nil {
+		t.Fatalf("want no limit with zero max budget, got %v", err)
+	}
+}
+
+func TestStartJobReservesBudget(t *testing.T) {
+	ctx := context.Background()
+	d := decimal.RequireFromString
+
+	s := &Server{
+		db:     kvmemdb.New(),
+		runner: job.NewRunner(),
+		budget: newBudgetGuard(d("150")),
+	}
+
+	// Jobs on an unknown exchange fail before they run, which must release
+	// their reservation.
+	newJob := func(size, cancel string) *limiter.Limiter {
+		l, err := limiter.New(uuid.NewString(), "unknown", "BTC-USD", &point.Point{Size: d(size), Price: d("100"), Cancel: d(cancel)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		add := func(ctx context.Context, rw kv.ReadWriter) error {
+			if err := l.Save(ctx, rw); err != nil {
+				return err
+			}
+			return s.runner.Add(ctx, rw, l.UID(), "Limiter")
+		}
+		if err := kv.WithReadWriter(ctx, s.db, add); err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	start := func(l *limiter.Limiter) error {
+		return kv.WithReadWriter(ctx, s.db, func(ctx context.Context, rw kv.ReadWriter) error {
+			_, err := s.startJob(ctx, rw, l.UID(), l)
+			return err
+		})
+	}
+	wait := func(l *limiter.Limiter) {
+		if err := kv.WithReadWriter(ctx, s.db, func(ctx context.Context, rw kv.ReadWriter) error {
+			_, err := s.runner.Pause(ctx, rw, l.UID())
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	big := newJob("2", "110")
+	if err := start(big); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("want ErrBudgetExceeded, got %v", err)
+	}
+
+	// Sells do not reserve any quote funds.
+	sell := newJob("2", "90")
+	if err := start(sell); err != nil {
+		t.Fatalf("want sell limiter within the budget, got %v", err)
+	}
+	wait(sell)
+
+	small := newJob("1", "110")
+	if err := start(small); err != nil {
+		t.Fatal(err)
+	}
+	wait(small)
+	if len(s.budget.reserved) != 0 {
+		t.Fatalf("want reservation to be released after the job exits, got %v", s.budget.reserved)
+	}
+
+	// Resuming a job checks the budget again.
+	if err := start(small); err != nil {
+		t.Fatal(err)
+	}
+	wait(small)
+	if len(s.budget.reserved) != 0 {
+		t.Fatalf("want reservation to be released after the resumed job exits, got %v", s.budget.reserved)
+	}
+}
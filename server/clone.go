@@ -0,0 +1,128 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/looper"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/point"
+	"github.com/bvk/tradebot/trader"
+	"github.com/bvk/tradebot/waller"
+	"github.com/bvkgo/kv"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// shiftPoint returns a copy of the input point with the price and cancel
+// price adjusted by the offset.
+func shiftPoint(p *point.Point, offset decimal.Decimal) *point.Point {
+	np := *p
+	np.Price = np.Price.Add(offset)
+	np.Cancel = np.Cancel.Add(offset)
+	return &np
+}
+
+// cloneTrader creates a new trader with the given uid using the trade points
+// of the source trader. Cloned trader doesn't share any orders or client-ids
+// with the source trader.
+func cloneTrader(src trader.Trader, uid, productID string, offset decimal.Decimal) (trader.Trader, string, error) {
+	switch v := src.(type) {
+	case *limiter.Limiter:
+		nv, err := limiter.New(uid, v.ExchangeName(), productID, shiftPoint(v.Point(), offset))
+		if err != nil {
+			return nil, "", err
+		}
+		return nv, "Limiter", nil
+	case *looper.Looper:
+		pair := v.Pair()
+		nv, err := looper.New(uid, v.ExchangeName(), productID, shiftPoint(&pair.Buy, offset), shiftPoint(&pair.Sell, offset))
+		if err != nil {
+			return nil, "", err
+		}
+		if err := nv.SetBudget(v.Budget()); err != nil {
+			return nil, "", err
+		}
+		return nv, "Looper", nil
+	case *waller.Waller:
+		var pairs []*point.Pair
+		for _, p := range v.Pairs() {
+			pairs = append(pairs, &point.Pair{
+				Buy:  *shiftPoint(&p.Buy, offset),
+				Sell: *shiftPoint(&p.Sell, offset),
+			})
+		}
+		nv, err := waller.New(uid, v.ExchangeName(), productID, pairs)
+		if err != nil {
+			return nil, "", err
+		}
+		return nv, "Waller", nil
+	}
+	return nil, "", fmt.Errorf("unsupported trader type %T", src)
+}
+
+// doJobClone creates a new job with the same trade points as an existing job.
+func (s *Server) doJobClone(ctx context.Context, req *api.JobCloneRequest) (*api.JobCloneResponse, error) {
+	if err := req.Check(); err != nil {
+		return nil, fmt.Errorf("invalid clone request: %w", err)
+	}
+
+	if _, err := uuid.Parse(req.UID); err != nil {
+		return nil, fmt.Errorf("job uid must be an uuid: %w", err)
+	}
+
+	uid := uuid.New().String()
+	clone := func(ctx context.Context, rw kv.ReadWriter) error {
+		jd, err := s.runner.Get(ctx, rw, req.UID)
+		if err != nil {
+			return fmt.Errorf("could not load job %q: %w", req.UID, err)
+		}
+		src, err := Load(ctx, rw, req.UID, jd.Typename)
+		if err != nil {
+			return fmt.Errorf("could not load trader job %q: %w", req.UID, err)
+		}
+
+		productID := src.ProductID()
+		if len(req.ProductID) != 0 {
+			productID = req.ProductID
+		}
+		dst, typename, err := cloneTrader(src, uid, productID, req.PriceOffset)
+		if err != nil {
+			return fmt.Errorf("could not clone trader job %q: %w", req.UID, err)
+		}
+
+		if err := dst.Save(ctx, rw); err != nil {
+			return fmt.Errorf("could not save cloned job: %w", err)
+		}
+		if err := s.runner.Add(ctx, rw, uid, typename); err != nil {
+			return fmt.Errorf("could not add cloned job: %w", err)
+		}
+		if err := namer.SetName(ctx, rw, req.JobName, uid, typename); err != nil {
+			return fmt.Errorf("could not assign name: %w", err)
+		}
+		if req.Start {
+			if _, err := s.getProduct(ctx, dst.ExchangeName(), dst.ProductID()); err != nil {
+				return err
+			}
+			if err := checkKillSwitch(ctx, rw); err != nil {
+				return err
+			}
+			if _, err := s.startJob(ctx, rw, uid, dst); err != nil {
+				return fmt.Errorf("could not resume cloned job: %w", err)
+			}
+		}
+		return nil
+	}
+	if err := kv.WithReadWriter(ctx, s.db, clone); err != nil {
+		return nil, err
+	}
+
+	resp := &api.JobCloneResponse{
+		UID: uid,
+	}
+	return resp, nil
+}
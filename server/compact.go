@@ -0,0 +1,145 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/ctxutil"
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvkgo/kv"
+)
+
+func (s *Server) doDBCompact(ctx context.Context, req *api.DBCompactRequest) (*api.DBCompactResponse, error) {
+	if err := req.Check(); err != nil {
+		return nil, fmt.Errorf("invalid db compact request: %w", err)
+	}
+	return s.compact(ctx, req.Retention, req.NoExport, req.DryRun)
+}
+
+// compactionCandidates returns the completed limiter jobs whose last order
+// has finished before the cutoff time.
+func (s *Server) compactionCandidates(ctx context.Context, cutoff time.Time) ([]string, error) {
+	var uids []string
+	collect := func(ctx context.Context, r kv.Reader, jd *job.JobData) error {
+		if jd.State != job.COMPLETED || !strings.EqualFold(jd.Typename, "limiter") {
+			return nil
+		}
+		l, err := limiter.Load(ctx, jd.UID, r)
+		if err != nil {
+			log.Printf("could not load limiter %s for compaction (skipped): %v", jd.UID, err)
+			return nil
+		}
+		var last time.Time
+		for _, action := range l.Actions() {
+			for _, order := range action.Orders {
+				t := order.FinishTime.Time
+				if t.IsZero() {
+					t = order.CreateTime.Time
+				}
+				if t.After(last) {
+					last = t
+				}
+			}
+		}
+		// Limiters without any timestamps are left alone.
+		if last.IsZero() || !last.Before(cutoff) {
+			return nil
+		}
+		uids = append(uids, jd.UID)
+		return nil
+	}
+	if err := job.ScanDB(ctx, s.runner, s.db, collect); err != nil {
+		return nil, fmt.Errorf("could not scan jobs: %w", err)
+	}
+	return uids, nil
+}
+
+// compact removes the completed limiter jobs older than the retention
+// window. Jobs are exported into the export directory before they are removed
+// unless noExport is true.
+func (s *Server) compact(ctx context.Context, retention time.Duration, noExport, dryRun bool) (*api.DBCompactResponse, error) {
+	if !noExport && len(s.opts.ExportDir) == 0 {
+		return nil, fmt.Errorf("export directory is not configured: %w", os.ErrInvalid)
+	}
+
+	uids, err := s.compactionCandidates(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(api.DBCompactResponse)
+	if dryRun {
+		resp.Removed = uids
+		return resp, nil
+	}
+
+	if !noExport {
+		if err := os.MkdirAll(s.opts.ExportDir, 0700); err != nil {
+			return nil, fmt.Errorf("could not create export directory: %w", err)
+		}
+	}
+
+	for _, uid := range uids {
+		if !noExport {
+			export, err := ExportJob(ctx, s.db, uid)
+			if err != nil {
+				log.Printf("could not export job %s (not removed): %v", uid, err)
+				continue
+			}
+			fpath := filepath.Join(s.opts.ExportDir, uid+".gob")
+			if err := WriteExportFile(export, fpath); err != nil {
+				log.Printf("could not write export file for job %s (not removed): %v", uid, err)
+				continue
+			}
+			resp.Exported = append(resp.Exported, fpath)
+		}
+
+		remove := func(ctx context.Context, rw kv.ReadWriter) error {
+			if err := limiter.Delete(ctx, rw, uid); err != nil {
+				return err
+			}
+			if err := s.runner.Remove(ctx, rw, uid); err != nil {
+				return err
+			}
+			if err := namer.DeleteID(ctx, rw, uid); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			if err := rw.Delete(ctx, path.Join(JobNotesKeyspace, uid)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("could not delete job notes: %w", err)
+			}
+			return nil
+		}
+		if err := kv.WithReadWriter(ctx, s.db, remove); err != nil {
+			log.Printf("could not remove job %s: %v", uid, err)
+			continue
+		}
+		log.Printf("removed completed limiter job %s during compaction", uid)
+		resp.Removed = append(resp.Removed, uid)
+	}
+	return resp, nil
+}
+
+// goCompact runs the compaction periodically in the background.
+func (s *Server) goCompact(ctx context.Context) {
+	for ctx.Err() == nil {
+		resp, err := s.compact(ctx, s.opts.CompactRetention, false /* noExport */, false /* dryRun */)
+		if err != nil {
+			log.Printf("could not compact the database (will retry): %v", err)
+		} else if len(resp.Removed) > 0 {
+			log.Printf("compaction has removed %d completed jobs", len(resp.Removed))
+		}
+		ctxutil.Sleep(ctx, s.opts.CompactInterval)
+	}
+}
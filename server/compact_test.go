@@ -0,0 +1,126 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/point"
+	"github.com/bvkgo/kv"
+	"github.com/bvkgo/kv/kvmemdb"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestCompactExportsArchivedOrders(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+	d := decimal.RequireFromString
+
+	uid := uuid.NewString()
+	l, err := limiter.New(uid, "coinbase", "BTC-USD", &point.Point{Size: d("1"), Price: d("100"), Cancel: d("110")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	stateKey := path.Join(limiter.DefaultKeyspace, uid)
+	archiveKey := path.Join(limiter.ArchiveKeyspace, uid, "order-1")
+	trailKey := path.Join(limiter.TrailKeyspace, uid, "00000000000000000001")
+	setup := func(ctx context.Context, rw kv.ReadWriter) error {
+		if err := l.Save(ctx, rw); err != nil {
+			return err
+		}
+		gv, err := kvutil.Get[gobs.LimiterState](ctx, rw, stateKey)
+		if err != nil {
+			return err
+		}
+		gv.V2.ServerIDOrderMap["order-2"] = &gobs.Order{
+			ServerOrderID: "order-2",
+			ClientOrderID: uuid.NewString(),
+			Side:          "BUY",
+			CreateTime:    gobs.RemoteTime{Time: old},
+			FinishTime:    gobs.RemoteTime{Time: old},
+			FilledSize:    d("0.5"),
+			FilledPrice:   d("100"),
+			Status:        "FILLED",
+			Done:          true,
+		}
+		if err := kvutil.Set(ctx, rw, stateKey, gv); err != nil {
+			return err
+		}
+		archived := &gobs.Order{
+			ServerOrderID: "order-1",
+			ClientOrderID: uuid.NewString(),
+			Side:          "BUY",
+			CreateTime:    gobs.RemoteTime{Time: old},
+			FinishTime:    gobs.RemoteTime{Time: old},
+			FilledSize:    d("0.5"),
+			FilledPrice:   d("100"),
+			Status:        "FILLED",
+			Done:          true,
+		}
+		if err := kvutil.Set(ctx, rw, archiveKey, archived); err != nil {
+			return err
+		}
+		if err := kvutil.Set(ctx, rw, trailKey, &gobs.LimiterTrailEntry{Time: old, Action: "create"}); err != nil {
+			return err
+		}
+		export := &gobs.JobExportData{UID: uid, Typename: "limiter", JobState: string(job.COMPLETED)}
+		return job.NewRunner().Import(ctx, rw, export)
+	}
+	if err := kv.WithReadWriter(ctx, db, setup); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{db: db, runner: job.NewRunner(), opts: Options{ExportDir: t.TempDir()}}
+	resp, err := s.compact(ctx, 24*time.Hour, false /* noExport */, false /* dryRun */)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Removed) != 1 || resp.Removed[0] != uid {
+		t.Fatalf("want job %s removed, got %v", uid, resp.Removed)
+	}
+
+	fp, err := os.Open(filepath.Join(s.opts.ExportDir, uid+".gob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fp.Close()
+	export := new(gobs.JobExportData)
+	if err := gob.NewDecoder(fp).Decode(export); err != nil {
+		t.Fatal(err)
+	}
+	keys := make(map[string]bool)
+	for _, item := range export.KeyValues {
+		keys[item.Key] = true
+	}
+	for _, key := range []string{stateKey, archiveKey, trailKey} {
+		if !keys[key] {
+			t.Fatalf("want key %s in the export, got %v", key, keys)
+		}
+	}
+
+	removed := func(ctx context.Context, r kv.Reader) error {
+		for _, key := range []string{stateKey, archiveKey, trailKey} {
+			if _, err := r.Get(ctx, key); !errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("want key %s removed by the compaction, got %v", key, err)
+			}
+		}
+		return nil
+	}
+	if err := kv.WithReader(ctx, db, removed); err != nil {
+		t.Fatal(err)
+	}
+}
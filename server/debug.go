@@ -0,0 +1,270 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/shopspring/decimal"
+)
+
+const DebugJobsPath = "/debug/jobs"
+
+const DebugHealthPath = "/debug/health"
+
+// ExchangeHealth holds runtime information about an exchange client.
+type ExchangeHealth struct {
+	Name string
+
+	// TimeSkew is the measured difference between the local time and the
+	// exchange server time, which is positive when local time is ahead.
+	TimeSkew time.Duration
+
+	// Products holds the liveness of the products opened on the exchange.
+	Products []*ProductHealth
+}
+
+// ProductHealth holds the liveness of a product as reported by it's Ping.
+type ProductHealth struct {
+	ProductID string
+
+	// Error is empty when the product is alive.
+	Error string
+}
+
+// JobStats holds runtime information about a job to detect leaked or
+// orphaned job goroutines.
+type JobStats struct {
+	UID string
+
+	// Alive is true when the job's Run method is executing.
+	Alive bool
+
+	// Supervised is true when the job runner considers the job as running.
+	// Alive and Supervised values differ when a job's Run has returned, but
+	// it's supervisor didn't notice it or vice versa.
+	Supervised bool
+
+	StartTime time.Time     `json:",omitempty"`
+	Uptime    time.Duration `json:",omitempty"`
+
+	// LastActivity is the time of the job's last exchange operation.
+	LastActivity time.Time `json:",omitempty"`
+
+	// FillLatency holds the fill latency statistics for the jobs that track
+	// them.
+	FillLatency *limiter.FillLatency `json:",omitempty"`
+
+	// EstimatedCompletion is the estimated time to fill the pending size at
+	// the observed fill rate for the jobs that can estimate it.
+	EstimatedCompletion time.Duration `json:",omitempty"`
+}
+
+type jobStat struct {
+	startTime time.Time
+
+	// lastActivity holds the time of the last exchange operation as unix
+	// nanoseconds.
+	lastActivity atomic.Int64
+}
+
+func (v *jobStat) touch() {
+	v.lastActivity.Store(time.Now().UnixNano())
+}
+
+// JobStats returns the runtime information for all jobs that are either
+// running or considered as running by the job runner.
+func (s *Server) JobStats() []*JobStats {
+	now := time.Now()
+	statMap := make(map[string]*JobStats)
+	s.jobStatMap.Range(func(uid string, v *jobStat) bool {
+		js := &JobStats{
+			UID:       uid,
+			Alive:     true,
+			StartTime: v.startTime,
+			Uptime:    now.Sub(v.startTime),
+		}
+		if t := v.lastActivity.Load(); t != 0 {
+			js.LastActivity = time.Unix(0, t)
+		}
+		if job, ok := s.jobMap.Load(uid); ok {
+			if v, ok := job.(interface{ FillLatency() *limiter.FillLatency }); ok {
+				js.FillLatency = v.FillLatency()
+			}
+			if v, ok := job.(interface{ EstimatedCompletion() (time.Duration, bool) }); ok {
+				if d, ok := v.EstimatedCompletion(); ok {
+					js.EstimatedCompletion = d
+				}
+			}
+		}
+		statMap[uid] = js
+		return true
+	})
+	for _, uid := range s.runner.Running() {
+		if js, ok := statMap[uid]; ok {
+			js.Supervised = true
+			continue
+		}
+		statMap[uid] = &JobStats{UID: uid, Supervised: true}
+	}
+
+	stats := make([]*JobStats, 0, len(statMap))
+	for _, js := range statMap {
+		stats = append(stats, js)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].UID < stats[j].UID
+	})
+	return stats
+}
+
+func (s *Server) serveDebugJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "invalid http method type", http.StatusMethodNotAllowed)
+		return
+	}
+	jsbytes, err := json.Marshal(s.JobStats())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	w.Write(jsbytes)
+}
+
+// ExchangeHealth returns the runtime information for all exchange clients
+// and pings all products opened on the exchanges.
+func (s *Server) ExchangeHealth(ctx context.Context) []*ExchangeHealth {
+	type TimeSkewer interface {
+		TimeSkew() time.Duration
+	}
+
+	var hs []*ExchangeHealth
+	for name, ex := range s.exchangeMap {
+		h := &ExchangeHealth{Name: name}
+		if v, ok := ex.(TimeSkewer); ok {
+			h.TimeSkew = v.TimeSkew()
+		}
+		h.Products = s.pingProducts(ctx, name)
+		hs = append(hs, h)
+	}
+	sort.Slice(hs, func(i, j int) bool {
+		return hs[i].Name < hs[j].Name
+	})
+	return hs
+}
+
+// pingProducts pings all opened products of an exchange in parallel.
+func (s *Server) pingProducts(ctx context.Context, exchangeName string) []*ProductHealth {
+	s.mu.Lock()
+	var products []exchange.Product
+	for _, p := range s.exProductsMap[exchangeName] {
+		products = append(products, p)
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	phs := make([]*ProductHealth, len(products))
+	for i, p := range products {
+		wg.Add(1)
+		go func(i int, p exchange.Product) {
+			defer wg.Done()
+
+			ph := &ProductHealth{ProductID: p.ProductID()}
+			if err := p.Ping(ctx); err != nil {
+				ph.Error = err.Error()
+			}
+			phs[i] = ph
+		}(i, p)
+	}
+	wg.Wait()
+
+	sort.Slice(phs, func(i, j int) bool {
+		return phs[i].ProductID < phs[j].ProductID
+	})
+	return phs
+}
+
+func (s *Server) serveDebugHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "invalid http method type", http.StatusMethodNotAllowed)
+		return
+	}
+	jsbytes, err := json.Marshal(s.ExchangeHealth(r.Context()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	w.Write(jsbytes)
+}
+
+// activityProduct wraps a product to record the time of order operations
+// issued by a job.
+type activityProduct struct {
+	exchange.Product
+
+	stat *jobStat
+}
+
+func (p *activityProduct) LimitBuy(ctx context.Context, clientOrderID string, size, price decimal.Decimal, goodTill time.Duration, tag string) (exchange.OrderID, error) {
+	p.stat.touch()
+	return p.Product.LimitBuy(ctx, clientOrderID, size, price, goodTill, tag)
+}
+
+func (p *activityProduct) LimitSell(ctx context.Context, clientOrderID string, size, price decimal.Decimal, goodTill time.Duration, tag string) (exchange.OrderID, error) {
+	p.stat.touch()
+	return p.Product.LimitSell(ctx, clientOrderID, size, price, goodTill, tag)
+}
+
+func (p *activityProduct) MarketBuy(ctx context.Context, clientOrderID string, size decimal.Decimal) (exchange.OrderID, error) {
+	p.stat.touch()
+	return p.Product.MarketBuy(ctx, clientOrderID, size)
+}
+
+func (p *activityProduct) MarketSell(ctx context.Context, clientOrderID string, size decimal.Decimal) (exchange.OrderID, error) {
+	p.stat.touch()
+	return p.Product.MarketSell(ctx, clientOrderID, size)
+}
+
+func (p *activityProduct) StopLimitBuy(ctx context.Context, clientOrderID string, size, limitPrice, triggerPrice decimal.Decimal, goodTill time.Duration, tag string) (exchange.OrderID, error) {
+	p.stat.touch()
+	return p.Product.StopLimitBuy(ctx, clientOrderID, size, limitPrice, triggerPrice, goodTill, tag)
+}
+
+func (p *activityProduct) StopLimitSell(ctx context.Context, clientOrderID string, size, limitPrice, triggerPrice decimal.Decimal, goodTill time.Duration, tag string) (exchange.OrderID, error) {
+	p.stat.touch()
+	return p.Product.StopLimitSell(ctx, clientOrderID, size, limitPrice, triggerPrice, goodTill, tag)
+}
+
+func (p *activityProduct) Get(ctx context.Context, id exchange.OrderID) (*exchange.Order, error) {
+	p.stat.touch()
+	return p.Product.Get(ctx, id)
+}
+
+func (p *activityProduct) Cancel(ctx context.Context, id exchange.OrderID) error {
+	p.stat.touch()
+	return p.Product.Cancel(ctx, id)
+}
+
+// OrderUpdatesChFiltered forwards to the underlying product when it supports
+// the filtered order updates and falls back to all order updates otherwise.
+func (p *activityProduct) OrderUpdatesChFiltered(match func(*exchange.Order) bool) (<-chan *exchange.Order, func()) {
+	if f, ok := p.Product.(exchange.OrderUpdatesFilterer); ok {
+		return f.OrderUpdatesChFiltered(match)
+	}
+	return p.Product.OrderUpdatesCh()
+}
+
+// Close is a no-op cause the underlying product is shared by the server.
+func (p *activityProduct) Close() error {
+	return nil
+}
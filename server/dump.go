@@ -0,0 +1,132 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/looper"
+	"github.com/bvk/tradebot/waller"
+	"github.com/bvkgo/kv"
+)
+
+// JobDump is the portable JSON form of a job's export data, where the saved
+// values are converted from their gob encoding to JSON.
+type JobDump struct {
+	UID      string
+	Name     string `json:",omitempty"`
+	Typename string
+
+	JobFlags uint64
+	JobState string
+
+	Values []*JobDumpValue
+}
+
+// JobDumpValue holds a saved key-value of the job, where Type is the gobs type
+// name of the value.
+type JobDumpValue struct {
+	Key   string
+	Type  string
+	Value json.RawMessage
+}
+
+// dumpTypes maps the keyspaces of the trader states to their gobs types.
+var dumpTypes = []struct {
+	keyspace string
+	typename string
+	newValue func() any
+}{
+	{limiter.DefaultKeyspace, "LimiterState", func() any { return new(gobs.LimiterState) }},
+	{limiter.OffsetKeyspace, "LimiterOffset", func() any { return new(gobs.LimiterOffset) }},
+	{limiter.ArchiveKeyspace, "Order", func() any { return new(gobs.Order) }},
+	{limiter.TrailKeyspace, "LimiterTrailEntry", func() any { return new(gobs.LimiterTrailEntry) }},
+	{looper.DefaultKeyspace, "LooperState", func() any { return new(gobs.LooperState) }},
+	{waller.DefaultKeyspace, "WallerState", func() any { return new(gobs.WallerState) }},
+}
+
+func newDumpValue(key, typename string) (any, error) {
+	for _, v := range dumpTypes {
+		if strings.HasPrefix(key, v.keyspace) && (typename == "" || typename == v.typename) {
+			return v.newValue(), nil
+		}
+	}
+	return nil, fmt.Errorf("key %q with type %q is not supported for job dumps", key, typename)
+}
+
+func dumpTypename(key string) string {
+	for _, v := range dumpTypes {
+		if strings.HasPrefix(key, v.keyspace) {
+			return v.typename
+		}
+	}
+	return ""
+}
+
+// DumpJob returns the job's export data in the portable JSON form.
+func DumpJob(ctx context.Context, db kv.Database, uid string) (*JobDump, error) {
+	export, err := ExportJob(ctx, db, uid)
+	if err != nil {
+		return nil, err
+	}
+	dump := &JobDump{
+		UID:      export.UID,
+		Name:     export.Name,
+		Typename: export.Typename,
+		JobFlags: export.JobFlags,
+		JobState: export.JobState,
+	}
+	for _, kv := range export.KeyValues {
+		value, err := newDumpValue(kv.Key, "")
+		if err != nil {
+			return nil, err
+		}
+		if err := gob.NewDecoder(bytes.NewReader(kv.Value)).Decode(value); err != nil {
+			return nil, fmt.Errorf("could not gob-decode value at key %q: %w", kv.Key, err)
+		}
+		js, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("could not json-encode value at key %q: %w", kv.Key, err)
+		}
+		dump.Values = append(dump.Values, &JobDumpValue{
+			Key:   kv.Key,
+			Type:  dumpTypename(kv.Key),
+			Value: js,
+		})
+	}
+	return dump, nil
+}
+
+// Export converts the job dump back to the job export data with the gob
+// encoded values.
+func (d *JobDump) Export() (*gobs.JobExportData, error) {
+	export := &gobs.JobExportData{
+		UID:      d.UID,
+		Name:     d.Name,
+		Typename: d.Typename,
+		JobFlags: d.JobFlags,
+		JobState: d.JobState,
+	}
+	for _, v := range d.Values {
+		value, err := newDumpValue(v.Key, v.Type)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(v.Value, value); err != nil {
+			return nil, fmt.Errorf("could not json-decode value at key %q: %w", v.Key, err)
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+			return nil, fmt.Errorf("could not gob-encode value at key %q: %w", v.Key, err)
+		}
+		export.KeyValues = append(export.KeyValues, &gobs.KeyValue{Key: v.Key, Value: buf.Bytes()})
+	}
+	return export, nil
+}
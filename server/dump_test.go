@@ -0,0 +1,175 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/point"
+	"github.com/bvkgo/kv"
+	"github.com/bvkgo/kv/kvmemdb"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestJobDumpRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	uid := uuid.NewString()
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := limiter.New(uid, "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := path.Join(limiter.DefaultKeyspace, uid)
+	setup := func(ctx context.Context, rw kv.ReadWriter) error {
+		if err := l.Save(ctx, rw); err != nil {
+			return err
+		}
+		gv, err := kvutil.Get[gobs.LimiterState](ctx, rw, key)
+		if err != nil {
+			return err
+		}
+		gv.V2.ClientIDOffset = 7
+		gv.V2.ServerIDOrderMap["order-1"] = &gobs.Order{
+			ServerOrderID: "order-1",
+			ClientOrderID: uuid.NewString(),
+			Side:          "BUY",
+			CreateTime:    gobs.RemoteTime{Time: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)},
+			FinishTime:    gobs.RemoteTime{Time: time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC)},
+			FilledSize:    decimal.RequireFromString("0.5"),
+			FilledPrice:   decimal.RequireFromString("100"),
+			FilledFee:     decimal.RequireFromString("0.125"),
+			Status:        "FILLED",
+			Done:          true,
+		}
+		if err := kvutil.Set(ctx, rw, key, gv); err != nil {
+			return err
+		}
+		return job.NewRunner().Add(ctx, rw, uid, "Limiter")
+	}
+	if err := kv.WithReadWriter(ctx, db, setup); err != nil {
+		t.Fatal(err)
+	}
+
+	dump, err := DumpJob(ctx, db, uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := new(JobDump)
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	export, err := restored.Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if export.UID != uid || export.Typename != "Limiter" {
+		t.Fatalf("want limiter job %s, got %s job %s", uid, export.Typename, export.UID)
+	}
+
+	target := kvmemdb.New()
+	restore := func(ctx context.Context, rw kv.ReadWriter) error {
+		for _, pair := range export.KeyValues {
+			if err := rw.Set(ctx, pair.Key, bytes.NewReader(pair.Value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := kv.WithReadWriter(ctx, target, restore); err != nil {
+		t.Fatal(err)
+	}
+
+	state := func(db kv.Database) string {
+		gv, err := kvutil.GetDB[gobs.LimiterState](ctx, db, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		js, _ := json.Marshal(gv)
+		return string(js)
+	}
+	if want, got := state(db), state(target); got != want {
+		t.Fatalf("restored limiter state differs:\nwant %s\ngot  %s", want, got)
+	}
+}
+
+func TestJobDumpArchiveAndTrail(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	uid := uuid.NewString()
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := limiter.New(uid, "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archiveKey := path.Join(limiter.ArchiveKeyspace, uid, "order-1")
+	trailKey := path.Join(limiter.TrailKeyspace, uid, "00000000000000000001")
+	setup := func(ctx context.Context, rw kv.ReadWriter) error {
+		if err := l.Save(ctx, rw); err != nil {
+			return err
+		}
+		if err := kvutil.Set(ctx, rw, archiveKey, &gobs.Order{ServerOrderID: "order-1", Side: "BUY", Done: true}); err != nil {
+			return err
+		}
+		if err := kvutil.Set(ctx, rw, trailKey, &gobs.LimiterTrailEntry{Action: "create", Reason: "test"}); err != nil {
+			return err
+		}
+		return job.NewRunner().Add(ctx, rw, uid, "Limiter")
+	}
+	if err := kv.WithReadWriter(ctx, db, setup); err != nil {
+		t.Fatal(err)
+	}
+
+	dump, err := DumpJob(ctx, db, uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := make(map[string]string)
+	for _, v := range dump.Values {
+		types[v.Key] = v.Type
+	}
+	if types[archiveKey] != "Order" || types[trailKey] != "LimiterTrailEntry" {
+		t.Fatalf("want archived order and trail entry in the dump, got %v", types)
+	}
+
+	if err := kv.WithReadWriter(ctx, db, func(ctx context.Context, rw kv.ReadWriter) error {
+		return DeleteJobKeys(ctx, rw, uid)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var kvs []*gobs.KeyValue
+	if err := kv.WithReader(ctx, db, func(ctx context.Context, r kv.Reader) (err error) {
+		kvs, err = jobKeyValues(ctx, r, uid)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 0 {
+		t.Fatalf("want no job keys after the delete, got %d", len(kvs))
+	}
+}
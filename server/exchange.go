@@ -4,46 +4,125 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/bvk/tradebot/api"
 	"github.com/bvk/tradebot/exchange"
 	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/trader"
+	"github.com/bvkgo/kv"
+	"github.com/shopspring/decimal"
 )
 
+// lookupExchange returns the exchange with the given name. Error includes the
+// names of all registered exchanges when the name is not found, so that users
+// can correct the typos.
+func (s *Server) lookupExchange(name string) (exchange.Exchange, error) {
+	if ex, ok := s.exchangeMap[strings.ToLower(name)]; ok {
+		return ex, nil
+	}
+	var names []string
+	for k := range s.exchangeMap {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("no exchange with name %q (available exchanges: %s): %w", name, strings.Join(names, ", "), os.ErrNotExist)
+}
+
 func (s *Server) doExchangeGetOrder(ctx context.Context, req *api.ExchangeGetOrderRequest) (*api.ExchangeGetOrderResponse, error) {
-	ex, ok := s.exchangeMap[strings.ToLower(req.Name)]
-	if !ok {
-		return nil, fmt.Errorf("no exchange with name %q: %w", req.Name, os.ErrNotExist)
+	ex, err := s.lookupExchange(req.Name)
+	if err != nil {
+		return nil, err
 	}
 	order, err := ex.GetOrder(ctx, exchange.OrderID(req.OrderID))
 	if err != nil {
 		return &api.ExchangeGetOrderResponse{Error: err.Error()}, nil
 	}
-	resp := &api.ExchangeGetOrderResponse{
-		Order: &gobs.Order{
-			ServerOrderID: string(order.OrderID),
-			ClientOrderID: order.ClientOrderID,
-			Side:          order.Side,
-			Status:        order.Status,
-			CreateTime:    gobs.RemoteTime{Time: order.CreateTime.Time},
-			FinishTime:    gobs.RemoteTime{Time: order.FinishTime.Time},
-			FilledFee:     order.Fee,
-			FilledSize:    order.FilledSize,
-			FilledPrice:   order.FilledPrice,
-			Done:          order.Done,
-			DoneReason:    order.DoneReason,
-		},
+	return &api.ExchangeGetOrderResponse{Order: toGobsOrder(order)}, nil
+}
+
+// DefaultGetOrdersConcurrency is the number of orders fetched in parallel by
+// the get-orders requests that do not specify the concurrency.
+const DefaultGetOrdersConcurrency = 8
+
+// doExchangeGetOrders fetches multiple orders from the exchange in parallel
+// with a bounded number of workers. Failures to fetch an order are reported
+// per order.
+func (s *Server) doExchangeGetOrders(ctx context.Context, req *api.ExchangeGetOrdersRequest) (*api.ExchangeGetOrdersResponse, error) {
+	if err := req.Check(); err != nil {
+		return nil, fmt.Errorf("invalid get-orders request: %w", err)
+	}
+	ex, err := s.lookupExchange(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := req.Concurrency
+	if concurrency == 0 {
+		concurrency = DefaultGetOrdersConcurrency
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	items := make([]*api.ExchangeGetOrdersItem, len(req.OrderIDs))
+	for i, id := range req.OrderIDs {
+		items[i] = &api.ExchangeGetOrdersItem{OrderID: id}
+		select {
+		case <-ctx.Done():
+			items[i].Error = context.Cause(ctx).Error()
+			continue
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(item *api.ExchangeGetOrdersItem) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			order, err := ex.GetOrder(ctx, exchange.OrderID(item.OrderID))
+			if err != nil {
+				item.Error = err.Error()
+				return
+			}
+			item.Order = toGobsOrder(order)
+		}(items[i])
+	}
+	wg.Wait()
+
+	return &api.ExchangeGetOrdersResponse{Orders: items}, nil
+}
+
+func toGobsOrder(order *exchange.Order) *gobs.Order {
+	return &gobs.Order{
+		ServerOrderID: string(order.OrderID),
+		ClientOrderID: order.ClientOrderID,
+		Side:          order.Side,
+		Status:        order.Status,
+		CreateTime:    gobs.RemoteTime{Time: order.CreateTime.Time},
+		FinishTime:    gobs.RemoteTime{Time: order.FinishTime.Time},
+		FilledFee:     order.Fee,
+		FilledSize:    order.FilledSize,
+		FilledPrice:   order.FilledPrice,
+		Done:          order.Done,
+		DoneReason:    order.DoneReason,
 	}
-	return resp, nil
 }
 
 func (s *Server) doGetProduct(ctx context.Context, req *api.ExchangeGetProductRequest) (*api.ExchangeGetProductResponse, error) {
-	ex, ok := s.exchangeMap[strings.ToLower(req.ExchangeName)]
-	if !ok {
-		return nil, fmt.Errorf("no exchange with name %q: %w", req.ExchangeName, os.ErrNotExist)
+	ex, err := s.lookupExchange(req.ExchangeName)
+	if err != nil {
+		return nil, err
 	}
 	product, err := ex.GetProduct(ctx, req.ProductID)
 	if err != nil {
@@ -51,3 +130,245 @@ func (s *Server) doGetProduct(ctx context.Context, req *api.ExchangeGetProductRe
 	}
 	return &api.ExchangeGetProductResponse{Product: product}, nil
 }
+
+func (s *Server) doExchangeFeeTier(ctx context.Context, req *api.ExchangeFeeTierRequest) (*api.ExchangeFeeTierResponse, error) {
+	type FeeTierer interface {
+		FeeTier(ctx context.Context) (maker, taker decimal.Decimal, err error)
+	}
+
+	ex, err := s.lookupExchange(req.ExchangeName)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := ex.(FeeTierer)
+	if !ok {
+		return nil, fmt.Errorf("exchange %q doesn't report fee tiers: %w", req.ExchangeName, os.ErrInvalid)
+	}
+	maker, taker, err := v.FeeTier(ctx)
+	if err != nil {
+		return &api.ExchangeFeeTierResponse{Error: err.Error()}, nil
+	}
+	return &api.ExchangeFeeTierResponse{MakerFeePct: maker, TakerFeePct: taker}, nil
+}
+
+func (s *Server) doExchangeCheckAuth(ctx context.Context, req *api.ExchangeCheckAuthRequest) (*api.ExchangeCheckAuthResponse, error) {
+	type AuthChecker interface {
+		CheckAuth(ctx context.Context) (*exchange.AuthInfo, error)
+	}
+
+	ex, err := s.lookupExchange(req.ExchangeName)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := ex.(AuthChecker)
+	if !ok {
+		return nil, fmt.Errorf("exchange %q doesn't use credentials: %w", req.ExchangeName, os.ErrInvalid)
+	}
+	info, err := v.CheckAuth(ctx)
+	if err != nil {
+		return &api.ExchangeCheckAuthResponse{Error: err.Error()}, nil
+	}
+	resp := &api.ExchangeCheckAuthResponse{
+		AccountID:   info.AccountID,
+		AccountType: info.AccountType,
+		Permissions: info.Permissions,
+		PricingTier: info.PricingTier,
+		MakerFeePct: info.MakerFeePct,
+		TakerFeePct: info.TakerFeePct,
+	}
+	return resp, nil
+}
+
+// doExchangeRepeg pauses all running jobs on a product, which cancels their
+// live orders, and resumes them back, so that every job recreates it's orders
+// at the current point. Jobs are paused in parallel to yank the orders
+// quickly, but they are resumed only after all of them are stopped.
+func (s *Server) doExchangeRepeg(ctx context.Context, req *api.ExchangeRepegRequest) (*api.ExchangeRepegResponse, error) {
+	if err := req.Check(); err != nil {
+		return nil, fmt.Errorf("invalid repeg request: %w", err)
+	}
+
+	s.repegMu.Lock()
+	defer s.repegMu.Unlock()
+
+	var uids []string
+	s.jobMap.Range(func(uid string, v trader.Trader) bool {
+		if strings.EqualFold(v.ExchangeName(), req.ExchangeName) && v.ProductID() == req.ProductID {
+			uids = append(uids, uid)
+		}
+		return true
+	})
+	sort.Strings(uids)
+
+	resp := &api.ExchangeRepegResponse{Orders: s.liveOrders(uids)}
+	if req.DryRun {
+		resp.UIDs = uids
+		return resp, nil
+	}
+
+	var mu sync.Mutex
+	failed := make(map[string]string)
+	setFailed := func(uid string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failed[uid] = err.Error()
+	}
+
+	// Only the jobs that are running at the time of pause are resumed, so that
+	// concurrent manual pauses are not undone.
+	paused := make([]bool, len(uids))
+	var wg sync.WaitGroup
+	for i, uid := range uids {
+		wg.Add(1)
+		go func(i int, uid string) {
+			defer wg.Done()
+
+			pause := func(ctx context.Context, rw kv.ReadWriter) error {
+				jd, err := s.runner.Get(ctx, rw, uid)
+				if err != nil {
+					return err
+				}
+				if jd.State != job.RUNNING {
+					return nil
+				}
+				if _, err := s.runner.Pause(ctx, rw, uid); err != nil {
+					return err
+				}
+				paused[i] = true
+				return nil
+			}
+			if err := kv.WithReadWriter(ctx, s.db, pause); err != nil {
+				log.Printf("could not pause job %q for repeg: %v", uid, err)
+				setFailed(uid, err)
+			}
+		}(i, uid)
+	}
+	wg.Wait()
+
+	resp.Failed = failed
+	for i, uid := range uids {
+		if !paused[i] {
+			continue
+		}
+		resume := func(ctx context.Context, rw kv.ReadWriter) error {
+			jd, err := s.runner.Get(ctx, rw, uid)
+			if err != nil {
+				return err
+			}
+			if _, err := s.resume(ctx, rw, jd); err != nil {
+				return err
+			}
+			return nil
+		}
+		// Resume must not be skipped when the request context is canceled after
+		// the pause, so it uses the server context.
+		if err := kv.WithReadWriter(s.cg.Context(), s.db, resume); err != nil {
+			log.Printf("could not resume job %q after repeg: %v", uid, err)
+			setFailed(uid, err)
+			continue
+		}
+		resp.UIDs = append(resp.UIDs, uid)
+	}
+	log.Printf("repegged %d jobs on product %q in exchange %q (%d failed)", len(resp.UIDs), req.ProductID, req.ExchangeName, len(failed))
+	return resp, nil
+}
+
+// liveOrders returns the live orders of the running jobs.
+func (s *Server) liveOrders(uids []string) []*api.ExchangeRepegOrder {
+	type LiveOrderser interface {
+		LiveOrders() []*limiter.LiveOrder
+	}
+
+	var orders []*api.ExchangeRepegOrder
+	for _, uid := range uids {
+		v, ok := s.jobMap.Load(uid)
+		if !ok {
+			continue
+		}
+		lv, ok := v.(LiveOrderser)
+		if !ok {
+			continue
+		}
+		for _, order := range lv.LiveOrders() {
+			orders = append(orders, &api.ExchangeRepegOrder{
+				JobUID:     uid,
+				LimiterUID: order.UID,
+				OrderID:    string(order.OrderID),
+				Side:       order.Side,
+				Size:       order.Size,
+				Price:      order.Price,
+				CreateTime: order.CreateTime,
+			})
+		}
+	}
+	return orders
+}
+
+// openOrdersLister is implemented by the exchanges that can list open orders.
+type openOrdersLister interface {
+	ListOpenOrders(ctx context.Context, productID string) ([]*exchange.Order, error)
+}
+
+// doExchangeCancelStale cancels the open orders of a product that are older
+// than the requested age, which is useful to clean up the orders left behind
+// by dead jobs. Live orders of the running jobs are never canceled.
+func (s *Server) doExchangeCancelStale(ctx context.Context, req *api.ExchangeCancelStaleRequest) (*api.ExchangeCancelStaleResponse, error) {
+	if err := req.Check(); err != nil {
+		return nil, fmt.Errorf("invalid cancel-stale request: %w", err)
+	}
+	ex, err := s.lookupExchange(req.ExchangeName)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := ex.(openOrdersLister)
+	if !ok {
+		return nil, fmt.Errorf("exchange %q cannot list open orders: %w", req.ExchangeName, errors.ErrUnsupported)
+	}
+	orders, err := lister.ListOpenOrders(ctx, req.ProductID)
+	if err != nil {
+		return nil, fmt.Errorf("could not list open orders: %w", err)
+	}
+
+	var uids []string
+	s.jobMap.Range(func(uid string, _ trader.Trader) bool {
+		uids = append(uids, uid)
+		return true
+	})
+	liveMap := make(map[string]bool)
+	for _, order := range s.liveOrders(uids) {
+		liveMap[order.OrderID] = true
+	}
+
+	resp := &api.ExchangeCancelStaleResponse{NumOpen: len(orders)}
+	cutoff := time.Now().Add(-req.OlderThan)
+	for _, order := range orders {
+		if order.CreateTime.IsZero() || !order.CreateTime.Before(cutoff) {
+			continue
+		}
+		if liveMap[string(order.OrderID)] {
+			resp.NumLive++
+			continue
+		}
+		resp.Orders = append(resp.Orders, &api.ExchangeCancelStaleOrder{
+			OrderID:    string(order.OrderID),
+			Side:       order.Side,
+			CreateTime: order.CreateTime.Time,
+		})
+	}
+	if req.DryRun || len(resp.Orders) == 0 {
+		return resp, nil
+	}
+
+	product, err := s.getProduct(ctx, req.ExchangeName, req.ProductID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load product %q: %w", req.ProductID, err)
+	}
+	for _, order := range resp.Orders {
+		if err := product.Cancel(ctx, exchange.OrderID(order.OrderID)); err != nil {
+			order.Error = err.Error()
+			continue
+		}
+		log.Printf("canceled stale order %s of product %q created at %s", order.OrderID, req.ProductID, order.CreateTime.Format(time.RFC3339))
+	}
+	return resp, nil
+}
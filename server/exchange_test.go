@@ -0,0 +1,79 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/point"
+	"github.com/bvk/tradebot/trader"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// openOrdersExchange is an exchange that only lists the open orders.
+type openOrdersExchange struct {
+	exchange.Exchange
+
+	orders []*exchange.Order
+}
+
+func (ex *openOrdersExchange) ListOpenOrders(ctx context.Context, productID string) ([]*exchange.Order, error) {
+	return ex.orders, nil
+}
+
+// liveOrdersJob is a job with fixed live orders.
+type liveOrdersJob struct {
+	*limiter.Limiter
+
+	orders []*limiter.LiveOrder
+}
+
+func (v *liveOrdersJob) LiveOrders() []*limiter.LiveOrder {
+	return v.orders
+}
+
+func TestCancelStaleSkipsLiveOrders(t *testing.T) {
+	ctx := context.Background()
+	d := decimal.RequireFromString
+
+	old := exchange.RemoteTime{Time: time.Now().Add(-48 * time.Hour)}
+	ex := &openOrdersExchange{
+		orders: []*exchange.Order{
+			{OrderID: "live", Side: "BUY", CreateTime: old},
+			{OrderID: "stale", Side: "BUY", CreateTime: old},
+			{OrderID: "fresh", Side: "BUY", CreateTime: exchange.RemoteTime{Time: time.Now()}},
+		},
+	}
+	s := &Server{exchangeMap: map[string]exchange.Exchange{"coinbase": ex}}
+
+	uid := uuid.NewString()
+	l, err := limiter.New(uid, "coinbase", "BTC-USD", &point.Point{Size: d("1"), Price: d("100"), Cancel: d("110")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job trader.Trader = &liveOrdersJob{Limiter: l, orders: []*limiter.LiveOrder{{UID: uid, OrderID: "live"}}}
+	s.jobMap.Store(uid, job)
+
+	req := &api.ExchangeCancelStaleRequest{
+		ExchangeName: "coinbase",
+		ProductID:    "BTC-USD",
+		OlderThan:    24 * time.Hour,
+		DryRun:       true,
+	}
+	resp, err := s.doExchangeCancelStale(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.NumOpen != 3 || resp.NumLive != 1 {
+		t.Fatalf("want 3 open and 1 live orders, got %d and %d", resp.NumOpen, resp.NumLive)
+	}
+	if len(resp.Orders) != 1 || resp.Orders[0].OrderID != "stale" {
+		t.Fatalf("want only the stale order of a dead job, got %v", resp.Orders)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/looper"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/waller"
+	"github.com/bvkgo/kv"
+)
+
+// JobKeyspaces are the keyspaces that hold the key-values of the traders and
+// their child traders, which are keyed by the job uid.
+var JobKeyspaces = []string{
+	limiter.DefaultKeyspace,
+	limiter.OffsetKeyspace,
+	limiter.ArchiveKeyspace,
+	limiter.TrailKeyspace,
+	looper.DefaultKeyspace,
+	waller.DefaultKeyspace,
+}
+
+// jobKeyValues returns all key-values of the job and it's child jobs as they
+// are in the database. Archived orders and the audit trail entries are
+// included, which are not written by a trader's Save after it is loaded.
+func jobKeyValues(ctx context.Context, r kv.Reader, uid string) ([]*gobs.KeyValue, error) {
+	var kvs []*gobs.KeyValue
+	for _, keyspace := range JobKeyspaces {
+		key := path.Join(keyspace, uid)
+		if v, err := r.Get(ctx, key); err == nil {
+			value, err := io.ReadAll(v)
+			if err != nil {
+				return nil, fmt.Errorf("could not read value at key %q: %w", key, err)
+			}
+			kvs = append(kvs, &gobs.KeyValue{Key: key, Value: value})
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("could not read key %q: %w", key, err)
+		}
+
+		begin, end := kvutil.PathRange(key)
+		it, err := r.Ascend(ctx, begin, end)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan keys under %s: %w", key, err)
+		}
+		for k, v, err := it.Fetch(ctx, false); err == nil; k, v, err = it.Fetch(ctx, true) {
+			value, err := io.ReadAll(v)
+			if err != nil {
+				kv.Close(it)
+				return nil, fmt.Errorf("could not read value at key %q: %w", k, err)
+			}
+			kvs = append(kvs, &gobs.KeyValue{Key: k, Value: value})
+		}
+		_, _, err = it.Fetch(ctx, false)
+		kv.Close(it)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("could not complete scan under %s: %w", key, err)
+		}
+	}
+	return kvs, nil
+}
+
+// DeleteJobKeys removes all key-values of the job and it's child jobs from the
+// job keyspaces. Job metadata and the name are not removed. Job must not be
+// running while it's keys are removed.
+func DeleteJobKeys(ctx context.Context, rw kv.ReadWriter, uid string) error {
+	kvs, err := jobKeyValues(ctx, rw, uid)
+	if err != nil {
+		return err
+	}
+	for _, item := range kvs {
+		if err := rw.Delete(ctx, item.Key); err != nil {
+			return fmt.Errorf("could not delete key %q: %w", item.Key, err)
+		}
+	}
+	return nil
+}
+
+// ExportJob returns the export data for a job, which includes the job's
+// metadata and all key-values of the job and it's child jobs.
+func ExportJob(ctx context.Context, db kv.Database, uid string) (*gobs.JobExportData, error) {
+	export := &gobs.JobExportData{
+		UID: uid,
+	}
+
+	loader := func(ctx context.Context, r kv.Reader) error {
+		runner := job.NewRunner()
+		if err := runner.Export(ctx, r, export); err != nil {
+			return fmt.Errorf("could not export job data: %w", err)
+		}
+
+		name, _, typename, err := namer.Resolve(ctx, r, export.UID)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not resolve job id to name: %w", err)
+		}
+		export.Name = name
+
+		if len(typename) == 0 {
+			typename = export.Typename
+		}
+		// Job is loaded only to verify that the exported state is usable.
+		if _, err := Load(ctx, r, export.UID, typename); err != nil {
+			return fmt.Errorf("could not load trader: %w", err)
+		}
+
+		kvs, err := jobKeyValues(ctx, r, export.UID)
+		if err != nil {
+			return err
+		}
+		export.KeyValues = kvs
+		return nil
+	}
+	if err := kv.WithReader(ctx, db, loader); err != nil {
+		return nil, fmt.Errorf("could not export trader job: %w", err)
+	}
+	return export, nil
+}
+
+// WriteExportFile saves the job export data into a file in the gob format.
+func WriteExportFile(export *gobs.JobExportData, filename string) error {
+	fp, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY, os.FileMode(0600))
+	if err != nil {
+		return fmt.Errorf("could not open %q: %w", filename, err)
+	}
+	defer fp.Close()
+
+	bw := bufio.NewWriter(fp)
+	if err := gob.NewEncoder(bw).Encode(export); err != nil {
+		return fmt.Errorf("could not gob-encode export data: %w", err)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("could not flush the bufio writer: %w", err)
+	}
+	if err := fp.Sync(); err != nil {
+		return fmt.Errorf("could not sync the file: %w", err)
+	}
+	return nil
+}
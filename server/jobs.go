@@ -8,9 +8,12 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/bvk/tradebot/api"
 	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/looper"
 	"github.com/bvk/tradebot/namer"
 	"github.com/bvk/tradebot/trader"
 	"github.com/bvkgo/kv"
@@ -19,11 +22,16 @@ import (
 
 const (
 	ManualFlag uint64 = 0x1 << 0
+
+	// StartPausedFlag when set, keeps the job paused when the server is
+	// restarted, even after it is resumed explicitly.
+	StartPausedFlag uint64 = 0x1 << 1
 )
 
 func (s *Server) makeJobFunc(v trader.Trader) job.Func {
 	return func(ctx context.Context) error {
 		uid := v.UID()
+		defer s.budget.release(uid)
 
 		ename, pid := v.ExchangeName(), v.ProductID()
 		product, err := s.getProduct(ctx, ename, pid)
@@ -31,10 +39,20 @@ func (s *Server) makeJobFunc(v trader.Trader) job.Func {
 			return fmt.Errorf("%s: could not load product %q in exchange %q: %w", uid, pid, ename, err)
 		}
 
+		if t := s.opts.JobLogs; t != nil {
+			t.Track(uid)
+			defer t.Untrack(uid)
+		}
+
+		stat := &jobStat{startTime: time.Now()}
+		s.jobStatMap.Store(uid, stat)
+		defer s.jobStatMap.Delete(uid)
+
 		s.jobMap.Store(uid, v)
 		defer s.jobMap.Delete(uid)
 
-		return v.Run(ctx, s.Runtime(product))
+		rt := s.Runtime(&activityProduct{Product: product, stat: stat})
+		return v.Run(ctx, rt)
 	}
 }
 
@@ -103,6 +121,31 @@ func (s *Server) doResume(ctx context.Context, req *api.JobResumeRequest) (*api.
 	return resp, nil
 }
 
+// doJobStartPaused sets or clears the start-paused flag on a job.
+func (s *Server) doJobStartPaused(ctx context.Context, req *api.JobStartPausedRequest) (*api.JobStartPausedResponse, error) {
+	if err := req.Check(); err != nil {
+		return nil, fmt.Errorf("invalid start-paused request: %w", err)
+	}
+	update := func(ctx context.Context, rw kv.ReadWriter) error {
+		jd, err := s.runner.Get(ctx, rw, req.UID)
+		if err != nil {
+			return err
+		}
+		flags := jd.Flags &^ StartPausedFlag
+		if req.StartPaused {
+			flags |= StartPausedFlag
+		}
+		if err := s.runner.UpdateFlags(ctx, rw, req.UID, flags); err != nil {
+			return fmt.Errorf("could not update job flags: %w", err)
+		}
+		return nil
+	}
+	if err := kv.WithReadWriter(ctx, s.db, update); err != nil {
+		return nil, err
+	}
+	return &api.JobStartPausedResponse{}, nil
+}
+
 // doCancel cancels a non-final job. If job is running, it will be stopped.
 func (s *Server) doCancel(ctx context.Context, req *api.JobCancelRequest) (*api.JobCancelResponse, error) {
 	state, err := job.CancelDB(ctx, s.runner, s.db, req.UID)
@@ -130,12 +173,22 @@ func (s *Server) doList(ctx context.Context, req *api.JobListRequest) (*api.JobL
 				return fmt.Errorf("could not resolve job id %q: %w", jd.UID, err)
 			}
 		}
+		note, err := jobNote(ctx, snap, jd.UID)
+		if err != nil {
+			return fmt.Errorf("could not load note for job %q: %w", jd.UID, err)
+		}
 		item := &api.JobListResponseItem{
 			UID:        jd.UID,
 			Type:       jd.Typename,
 			State:      string(jd.State),
 			Name:       name,
+			Note:       note,
 			ManualFlag: (jd.Flags & ManualFlag) != 0,
+
+			StartPaused: (jd.Flags & StartPausedFlag) != 0,
+		}
+		if !job.IsDone(jd.State) {
+			item.Held = s.isHeld(jd.UID)
 		}
 		resp.Jobs = append(resp.Jobs, item)
 		return nil
@@ -210,6 +263,9 @@ func (s *Server) doJobSetOption(ctx context.Context, req *api.JobSetOptionReques
 		if err := job.SetOption(req.OptionKey, req.OptionValue); err != nil {
 			return fmt.Errorf("could not set job option: %w", err)
 		}
+		if err := job.Save(ctx, rw); err != nil {
+			return fmt.Errorf("could not save job option change: %w", err)
+		}
 		return nil
 	}
 	if err := kv.WithReadWriter(ctx, s.db, update); err != nil {
@@ -217,3 +273,165 @@ func (s *Server) doJobSetOption(ctx context.Context, req *api.JobSetOptionReques
 	}
 	return &api.JobSetOptionResponse{}, nil
 }
+
+// doJobHoldChild holds or releases a single child limiter of a job without
+// pausing the job. Job can be running or in paused state.
+func (s *Server) doJobHoldChild(ctx context.Context, req *api.JobHoldChildRequest) (*api.JobHoldChildResponse, error) {
+	type ChildHolder interface {
+		HoldChild(child string, hold bool) error
+	}
+
+	if err := req.Check(); err != nil {
+		return nil, fmt.Errorf("invalid hold-child request: %w", err)
+	}
+	hold := func(v trader.Trader) error {
+		h, ok := v.(ChildHolder)
+		if !ok {
+			return fmt.Errorf("job %q has no child limiters to hold: %w", req.UID, os.ErrInvalid)
+		}
+		return h.HoldChild(req.Child, req.Hold)
+	}
+	if err := s.updateTrader(ctx, req.UID, hold); err != nil {
+		return nil, err
+	}
+	return &api.JobHoldChildResponse{}, nil
+}
+
+// isHeld returns true if the job is a running limiter with the hold option
+// set. Paused limiters are not loaded from the database, so they are never
+// reported as held.
+func (s *Server) isHeld(uid string) bool {
+	if v, ok := s.jobMap.Load(uid); ok {
+		if l, ok := v.(*limiter.Limiter); ok {
+			return l.IsHeld()
+		}
+	}
+	return false
+}
+
+// doJobHold holds or releases a limiter job without pausing it. Job can be
+// running or in paused state.
+func (s *Server) doJobHold(ctx context.Context, req *api.JobHoldRequest) (*api.JobHoldResponse, error) {
+	if err := req.Check(); err != nil {
+		return nil, fmt.Errorf("invalid hold request: %w", err)
+	}
+	hold := func(v trader.Trader) error {
+		l, ok := v.(*limiter.Limiter)
+		if !ok {
+			return fmt.Errorf("job %q is not a limiter: %w", req.UID, os.ErrInvalid)
+		}
+		return l.Hold(req.Hold)
+	}
+	if err := s.updateTrader(ctx, req.UID, hold); err != nil {
+		return nil, err
+	}
+	return &api.JobHoldResponse{Held: req.Hold}, nil
+}
+
+// updateTrader applies the update function to a running or paused trader job
+// and saves the job. Running job instance is updated in-place, so that the
+// change takes effect without a restart.
+func (s *Server) updateTrader(ctx context.Context, uid string, update func(trader.Trader) error) error {
+	if _, err := uuid.Parse(uid); err != nil {
+		return fmt.Errorf("job uid must be an uuid: %w", err)
+	}
+
+	save := func(ctx context.Context, rw kv.ReadWriter) error {
+		v, ok := s.jobMap.Load(uid)
+		if !ok {
+			jd, err := s.runner.Get(ctx, rw, uid)
+			if err != nil {
+				return err
+			}
+			if job.IsDone(jd.State) {
+				return fmt.Errorf("job %q is already completed (%q)", uid, jd.State)
+			}
+			if v, err = Load(ctx, rw, uid, jd.Typename); err != nil {
+				return fmt.Errorf("could not load trader job %q: %w", uid, err)
+			}
+		}
+		if err := update(v); err != nil {
+			return err
+		}
+		if err := v.Save(ctx, rw); err != nil {
+			return fmt.Errorf("could not save job %q: %w", uid, err)
+		}
+		return nil
+	}
+	return kv.WithReadWriter(ctx, s.db, save)
+}
+
+// doJobSplit splits a paused looper job into two jobs by the pivot price.
+func (s *Server) doJobSplit(ctx context.Context, req *api.JobSplitRequest) (*api.JobSplitResponse, error) {
+	if err := req.Check(); err != nil {
+		return nil, fmt.Errorf("invalid split request: %w", err)
+	}
+	if _, err := uuid.Parse(req.UID); err != nil {
+		return nil, fmt.Errorf("job uid must be an uuid: %w", err)
+	}
+	if _, err := uuid.Parse(req.NewUID); err != nil {
+		return nil, fmt.Errorf("new job uid must be an uuid: %w", err)
+	}
+
+	var moved []string
+	split := func(ctx context.Context, rw kv.ReadWriter) error {
+		jd, err := s.runner.Get(ctx, rw, req.UID)
+		if err != nil {
+			return err
+		}
+		if jd.Typename != "Looper" {
+			return fmt.Errorf("job %q is not a looper: %w", req.UID, os.ErrInvalid)
+		}
+		if jd.State != job.PAUSED {
+			return fmt.Errorf("job %q must be paused to split (%q): %w", req.UID, jd.State, os.ErrInvalid)
+		}
+		uids, err := looper.Split(ctx, rw, req.UID, req.PivotPrice, req.NewUID)
+		if err != nil {
+			return err
+		}
+		moved = uids
+		if err := s.runner.Add(ctx, rw, req.NewUID, "Looper"); err != nil {
+			return fmt.Errorf("could not add new job: %w", err)
+		}
+		return nil
+	}
+	if err := kv.WithReadWriter(ctx, s.db, split); err != nil {
+		return nil, err
+	}
+	return &api.JobSplitResponse{MovedLimiters: moved}, nil
+}
+
+// doJobConfig returns the effective options of a job. Options are taken from
+// the running instance when the job is active and from the database otherwise.
+func (s *Server) doJobConfig(ctx context.Context, req *api.JobConfigRequest) (*api.JobConfigResponse, error) {
+	if err := req.Check(); err != nil {
+		return nil, fmt.Errorf("invalid job config request: %w", err)
+	}
+
+	if v, ok := s.jobMap.Load(req.UID); ok {
+		resp := &api.JobConfigResponse{
+			UID:     req.UID,
+			Running: true,
+			Options: v.Config(),
+		}
+		return resp, nil
+	}
+
+	var config map[string]string
+	load := func(ctx context.Context, r kv.Reader) error {
+		jd, err := s.runner.Get(ctx, r, req.UID)
+		if err != nil {
+			return err
+		}
+		job, err := Load(ctx, r, req.UID, jd.Typename)
+		if err != nil {
+			return fmt.Errorf("could not load trader job %q: %w", req.UID, err)
+		}
+		config = job.Config()
+		return nil
+	}
+	if err := kv.WithReader(ctx, s.db, load); err != nil {
+		return nil, err
+	}
+	return &api.JobConfigResponse{UID: req.UID, Options: config}, nil
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/looper"
+	"github.com/bvk/tradebot/point"
+	"github.com/bvk/tradebot/trader"
+	"github.com/bvkgo/kv"
+	"github.com/bvkgo/kv/kvmemdb"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestJobHold(t *testing.T) {
+	ctx := context.Background()
+	d := decimal.RequireFromString
+
+	s := &Server{
+		db:     kvmemdb.New(),
+		runner: job.NewRunner(),
+	}
+
+	uid := uuid.NewString()
+	l, err := limiter.New(uid, "coinbase", "BTC-USD", &point.Point{Size: d("1"), Price: d("100"), Cancel: d("110")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.jobMap.Store(uid, l)
+
+	if s.isHeld(uid) {
+		t.Fatalf("want limiter not held initially")
+	}
+	if _, err := s.doJobHold(ctx, &api.JobHoldRequest{UID: uid, Hold: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !l.IsHeld() || !s.isHeld(uid) {
+		t.Fatalf("want running limiter held in-place")
+	}
+	if _, err := s.doJobHold(ctx, &api.JobHoldRequest{UID: uid, Hold: false}); err != nil {
+		t.Fatal(err)
+	}
+	if s.isHeld(uid) {
+		t.Fatalf("want limiter released")
+	}
+
+	// Jobs that are not running are never reported as held.
+	s.jobMap.Delete(uid)
+	if s.isHeld(uid) {
+		t.Fatalf("want stopped limiter not reported as held")
+	}
+
+	// Hold is only supported for limiters and hold-child only for jobs with
+	// child limiters.
+	other := uuid.NewString()
+	var v trader.Trader = &liveOrdersJob{Limiter: l}
+	s.jobMap.Store(other, v)
+	if _, err := s.doJobHold(ctx, &api.JobHoldRequest{UID: other, Hold: true}); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("want os.ErrInvalid for non-limiter jobs, got %v", err)
+	}
+	if _, err := s.doJobHoldChild(ctx, &api.JobHoldChildRequest{UID: other, Child: uid, Hold: true}); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("want os.ErrInvalid for jobs without child limiters, got %v", err)
+	}
+}
+
+func TestJobHoldChild(t *testing.T) {
+	ctx := context.Background()
+	d := decimal.RequireFromString
+
+	s := &Server{
+		db:     kvmemdb.New(),
+		runner: job.NewRunner(),
+	}
+
+	buy := &point.Point{Size: d("1"), Price: d("100"), Cancel: d("110")}
+	sell := &point.Point{Size: d("1"), Price: d("120"), Cancel: d("110")}
+	uid := uuid.NewString()
+	l, err := looper.New(uid, "coinbase", "BTC-USD", buy, sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	childUID := path.Join(uid, "buy-000000")
+	child, err := limiter.New(childUID, "coinbase", "BTC-USD", buy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Looper is paused, so it is loaded from the database with it's child.
+	save := func(ctx context.Context, rw kv.ReadWriter) error {
+		if err := l.Save(ctx, rw); err != nil {
+			return err
+		}
+		if err := child.Save(ctx, rw); err != nil {
+			return err
+		}
+		key := path.Join(looper.DefaultKeyspace, uid)
+		gv, err := kvutil.Get[gobs.LooperState](ctx, rw, key)
+		if err != nil {
+			return err
+		}
+		gv.V2.LimiterIDs = append(gv.V2.LimiterIDs, childUID)
+		if err := kvutil.Set(ctx, rw, key, gv); err != nil {
+			return err
+		}
+		return s.runner.Add(ctx, rw, uid, "looper")
+	}
+	if err := kv.WithReadWriter(ctx, s.db, save); err != nil {
+		t.Fatal(err)
+	}
+
+	isHeld := func() bool {
+		var v *limiter.Limiter
+		load := func(ctx context.Context, r kv.Reader) (err error) {
+			v, err = limiter.Load(ctx, childUID, r)
+			return err
+		}
+		if err := kv.WithReader(ctx, s.db, load); err != nil {
+			t.Fatal(err)
+		}
+		return v.IsHeld()
+	}
+
+	if _, err := s.doJobHoldChild(ctx, &api.JobHoldChildRequest{UID: uid, Child: "buy-000000", Hold: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !isHeld() {
+		t.Fatalf("want child limiter hold saved")
+	}
+	if _, err := s.doJobHoldChild(ctx, &api.JobHoldChildRequest{UID: uid, Child: childUID, Hold: false}); err != nil {
+		t.Fatal(err)
+	}
+	if isHeld() {
+		t.Fatalf("want child limiter release saved")
+	}
+
+	if _, err := s.doJobHoldChild(ctx, &api.JobHoldChildRequest{UID: uid, Child: "sell-000000", Hold: true}); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("want os.ErrNotExist for unknown child, got %v", err)
+	}
+	if _, err := s.doJobHoldChild(ctx, &api.JobHoldChildRequest{UID: uid, Hold: true}); err == nil {
+		t.Fatalf("want error for empty child name")
+	}
+}
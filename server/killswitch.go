@@ -0,0 +1,299 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/trader"
+	"github.com/bvkgo/kv"
+)
+
+// loadKillSwitch returns the kill-switch state if it is engaged or nil.
+func loadKillSwitch(ctx context.Context, db kv.Database) (*gobs.KillSwitch, error) {
+	var ks *gobs.KillSwitch
+	load := func(ctx context.Context, r kv.Reader) (err error) {
+		ks, err = getKillSwitch(ctx, r)
+		return err
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+func getKillSwitch(ctx context.Context, g kv.Getter) (*gobs.KillSwitch, error) {
+	ks, err := kvutil.Get[gobs.KillSwitch](ctx, g, killSwitchKey)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return ks, nil
+}
+
+// checkKillSwitch returns an error when the kill-switch is engaged, so that no
+// jobs are started or resumed.
+func checkKillSwitch(ctx context.Context, g kv.Getter) error {
+	ks, err := getKillSwitch(ctx, g)
+	if err != nil {
+		return fmt.Errorf("could not check the kill-switch: %w", err)
+	}
+	if ks != nil {
+		return fmt.Errorf("kill-switch is engaged since %s: %w", ks.EngageTime.Format(time.RFC3339), os.ErrPermission)
+	}
+	return nil
+}
+
+func (s *Server) doKillSwitch(ctx context.Context, req *api.KillSwitchRequest) (*api.KillSwitchResponse, error) {
+	if err := req.Check(); err != nil {
+		return nil, fmt.Errorf("invalid kill-switch request: %w", err)
+	}
+
+	// Repeg resumes the jobs it has paused, so it must not run concurrently.
+	s.repegMu.Lock()
+	defer s.repegMu.Unlock()
+
+	if req.Clear {
+		return s.clearKillSwitch(ctx, req)
+	}
+	return s.engageKillSwitch(ctx, req)
+}
+
+// engageKillSwitch persists the kill-switch and halts all running jobs, which
+// cancels their active orders. Open orders on the exchanges that are still
+// live after the jobs are halted, whether owned by a job or not, are canceled
+// explicitly.
+func (s *Server) engageKillSwitch(ctx context.Context, req *api.KillSwitchRequest) (*api.KillSwitchResponse, error) {
+	// Halting must not be interrupted when the request is canceled, so server
+	// context is used for the rest of the operation.
+	ctx = s.cg.Context()
+
+	traders := make(map[string]trader.Trader)
+	var uids []string
+	s.jobMap.Range(func(uid string, v trader.Trader) bool {
+		traders[uid] = v
+		uids = append(uids, uid)
+		return true
+	})
+	sort.Strings(uids)
+	orders := s.liveOrders(uids)
+
+	engage := func(ctx context.Context, rw kv.ReadWriter) error {
+		ks, err := getKillSwitch(ctx, rw)
+		if err != nil {
+			return err
+		}
+		if ks == nil {
+			ks = &gobs.KillSwitch{EngageTime: time.Now(), Reason: req.Reason}
+		}
+		for _, uid := range uids {
+			if !slices.Contains(ks.JobIDs, uid) {
+				ks.JobIDs = append(ks.JobIDs, uid)
+			}
+		}
+		return kvutil.Set(ctx, rw, killSwitchKey, ks)
+	}
+	if err := kv.WithReadWriter(ctx, s.db, engage); err != nil {
+		return nil, fmt.Errorf("could not save the kill-switch: %w", err)
+	}
+	log.Printf("kill-switch is engaged (%s); halting %d jobs", req.Reason, len(uids))
+
+	var mu sync.Mutex
+	failed := make(map[string]string)
+	var wg sync.WaitGroup
+	for _, uid := range uids {
+		wg.Add(1)
+		go func(uid string) {
+			defer wg.Done()
+
+			pause := func(ctx context.Context, rw kv.ReadWriter) error {
+				jd, err := s.runner.Get(ctx, rw, uid)
+				if err != nil {
+					return err
+				}
+				if jd.State != job.RUNNING {
+					return nil
+				}
+				_, err = s.runner.Pause(ctx, rw, uid)
+				return err
+			}
+			if err := kv.WithReadWriter(ctx, s.db, pause); err != nil {
+				log.Printf("could not halt job %q for the kill-switch: %v", uid, err)
+				mu.Lock()
+				failed[uid] = err.Error()
+				mu.Unlock()
+			}
+		}(uid)
+	}
+	wg.Wait()
+
+	resp := &api.KillSwitchResponse{UIDs: uids, Failed: failed}
+
+	// All open orders of the exchanges are canceled, including the orders that
+	// are not owned by any job.
+	listed := make(map[string]bool)
+	for exchangeName, productIDs := range s.killSwitchProducts(traders) {
+		lister, ok := s.exchangeMap[exchangeName].(openOrdersLister)
+		if !ok {
+			continue
+		}
+		listed[exchangeName] = true
+		for _, productID := range productIDs {
+			open, err := lister.ListOpenOrders(ctx, productID)
+			if err != nil {
+				log.Printf("could not list open orders of product %q on exchange %q: %v", productID, exchangeName, err)
+				resp.FailedProducts = append(resp.FailedProducts, path.Join(exchangeName, productID))
+				continue
+			}
+			if len(open) == 0 {
+				continue
+			}
+			product, err := s.getProduct(ctx, exchangeName, productID)
+			if err != nil {
+				log.Printf("could not open product %q on exchange %q to cancel orders: %v", productID, exchangeName, err)
+				resp.FailedProducts = append(resp.FailedProducts, path.Join(exchangeName, productID))
+				continue
+			}
+			for _, order := range open {
+				if err := product.Cancel(ctx, order.OrderID); err != nil {
+					if errors.Is(err, exchange.ErrOrderDone) {
+						continue
+					}
+					log.Printf("could not cancel open order %s of product %q: %v", order.OrderID, productID, err)
+					failed[string(order.OrderID)] = err.Error()
+					continue
+				}
+				resp.CanceledOrders = append(resp.CanceledOrders, string(order.OrderID))
+			}
+		}
+	}
+
+	// Live orders of the jobs are verified and canceled one by one on the
+	// exchanges that cannot list the open orders.
+	for _, order := range orders {
+		t := traders[order.JobUID]
+		if listed[t.ExchangeName()] {
+			continue
+		}
+		product, err := s.getProduct(ctx, t.ExchangeName(), t.ProductID())
+		if err != nil {
+			log.Printf("could not open product %q to verify order %s (ignored): %v", t.ProductID(), order.OrderID, err)
+			continue
+		}
+		v, err := product.Get(ctx, exchange.OrderID(order.OrderID))
+		if err != nil {
+			if errors.Is(err, exchange.ErrNotFound) {
+				continue
+			}
+			log.Printf("could not verify order %s of job %q (ignored): %v", order.OrderID, order.JobUID, err)
+			continue
+		}
+		if v.Done {
+			continue
+		}
+		if err := product.Cancel(ctx, v.OrderID); err != nil {
+			log.Printf("could not cancel order %s of job %q: %v", order.OrderID, order.JobUID, err)
+			failed[order.JobUID] = err.Error()
+			continue
+		}
+		resp.CanceledOrders = append(resp.CanceledOrders, order.OrderID)
+	}
+	s.SendMessage(ctx, time.Now(), "Kill-switch is engaged (%s); %d jobs are halted and %d orders are canceled explicitly.", req.Reason, len(uids)-len(failed), len(resp.CanceledOrders))
+	return resp, nil
+}
+
+// killSwitchProducts returns the products of every exchange whose open orders
+// are canceled by the kill-switch, which are the enabled products and the
+// products of the halted jobs.
+func (s *Server) killSwitchProducts(traders map[string]trader.Trader) map[string][]string {
+	productsMap := make(map[string][]string)
+	add := func(exchangeName, productID string) {
+		if !slices.Contains(productsMap[exchangeName], productID) {
+			productsMap[exchangeName] = append(productsMap[exchangeName], productID)
+		}
+	}
+
+	s.mu.Lock()
+	if s.state != nil {
+		for exchangeName := range s.exchangeMap {
+			// Profiles of an exchange share the enabled products of the exchange.
+			baseName, _, _ := strings.Cut(exchangeName, ":")
+			if estate, ok := s.state.ExchangeMap[baseName]; ok {
+				for _, productID := range estate.EnabledProductIDs {
+					add(exchangeName, productID)
+				}
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	for _, t := range traders {
+		add(t.ExchangeName(), t.ProductID())
+	}
+	for _, productIDs := range productsMap {
+		sort.Strings(productIDs)
+	}
+	return productsMap
+}
+
+// clearKillSwitch removes the kill-switch and optionally resumes the jobs that
+// were halted by it.
+func (s *Server) clearKillSwitch(ctx context.Context, req *api.KillSwitchRequest) (*api.KillSwitchResponse, error) {
+	var ks *gobs.KillSwitch
+	clear := func(ctx context.Context, rw kv.ReadWriter) (err error) {
+		if ks, err = getKillSwitch(ctx, rw); err != nil || ks == nil {
+			return err
+		}
+		return rw.Delete(ctx, killSwitchKey)
+	}
+	if err := kv.WithReadWriter(ctx, s.db, clear); err != nil {
+		return nil, fmt.Errorf("could not clear the kill-switch: %w", err)
+	}
+	if ks == nil {
+		return nil, fmt.Errorf("kill-switch is not engaged: %w", os.ErrNotExist)
+	}
+	log.Printf("kill-switch is cleared")
+
+	resp := &api.KillSwitchResponse{Failed: make(map[string]string)}
+	if !req.Resume {
+		return resp, nil
+	}
+	for _, uid := range ks.JobIDs {
+		resume := func(ctx context.Context, rw kv.ReadWriter) error {
+			jd, err := s.runner.Get(ctx, rw, uid)
+			if err != nil {
+				return err
+			}
+			if jd.State == job.RUNNING || job.IsDone(jd.State) {
+				return nil
+			}
+			if _, err := s.resume(ctx, rw, jd); err != nil {
+				return err
+			}
+			resp.UIDs = append(resp.UIDs, uid)
+			return nil
+		}
+		if err := kv.WithReadWriter(ctx, s.db, resume); err != nil {
+			log.Printf("could not resume job %q after the kill-switch: %v", uid, err)
+			resp.Failed[uid] = err.Error()
+		}
+	}
+	return resp, nil
+}
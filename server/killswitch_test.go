@@ -0,0 +1,76 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/paper"
+	"github.com/bvkgo/kv/kvmemdb"
+	"github.com/shopspring/decimal"
+)
+
+func TestKillSwitchCancelsOpenOrders(t *testing.T) {
+	ctx := context.Background()
+	d := decimal.RequireFromString
+
+	// Open orders are not owned by any job.
+	product := paper.New("BTC-USD", nil)
+	var open []*exchange.Order
+	for _, cid := range []string{"manual-1", "manual-2"} {
+		id, err := product.LimitBuy(ctx, cid, d("1"), d("100"), 0, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		order, err := product.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		open = append(open, order)
+	}
+
+	s := &Server{
+		db:     kvmemdb.New(),
+		runner: job.NewRunner(),
+		state: &gobs.ServerState{
+			ExchangeMap: map[string]*gobs.ServerExchangeState{
+				"coinbase": {EnabledProductIDs: []string{"BTC-USD"}},
+			},
+		},
+		exchangeMap:   map[string]exchange.Exchange{"coinbase": &openOrdersExchange{orders: open}},
+		exProductsMap: map[string]map[string]exchange.Product{"coinbase": {"BTC-USD": product}},
+	}
+
+	resp, err := s.doKillSwitch(ctx, &api.KillSwitchRequest{Reason: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.CanceledOrders) != len(open) {
+		t.Fatalf("want all %d open orders canceled, got %v", len(open), resp.CanceledOrders)
+	}
+	for _, order := range open {
+		if !slices.Contains(resp.CanceledOrders, string(order.OrderID)) {
+			t.Fatalf("open order %s is not canceled", order.OrderID)
+		}
+		v, err := product.Get(ctx, order.OrderID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !v.Done {
+			t.Fatalf("open order %s must be canceled on the exchange", order.OrderID)
+		}
+	}
+	if len(resp.Failed) != 0 || len(resp.FailedProducts) != 0 {
+		t.Fatalf("want no failures, got %v and %v", resp.Failed, resp.FailedProducts)
+	}
+
+	if ks, err := loadKillSwitch(ctx, s.db); err != nil || ks == nil {
+		t.Fatalf("kill-switch must be engaged, got %v", err)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"io"
 	"path"
 	"strings"
+	"sync"
 
+	"github.com/bvk/tradebot/job"
 	"github.com/bvk/tradebot/limiter"
 	"github.com/bvk/tradebot/looper"
 	"github.com/bvk/tradebot/namer"
@@ -114,3 +116,48 @@ func loadFromDB(ctx context.Context, db kv.Database, uid, typename string) (job
 	})
 	return
 }
+
+// DefaultLoadConcurrency is the default number of jobs loaded in parallel at
+// the startup.
+const DefaultLoadConcurrency = 8
+
+// loadJobs loads the traders for the input jobs in parallel using at most
+// concurrency workers, each with it's own read-only transaction. Jobs that
+// could not be loaded are left out of the result map and their errors are
+// joined into the returned error, so that a single bad job doesn't prevent
+// the rest from loading.
+func loadJobs(ctx context.Context, db kv.Database, jds []*job.JobData, concurrency int) (map[string]trader.Trader, error) {
+	if concurrency < 1 {
+		concurrency = DefaultLoadConcurrency
+	}
+
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	jobMap := make(map[string]trader.Trader)
+
+	sem := make(chan struct{}, concurrency)
+	for _, jd := range jds {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(jd *job.JobData) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			v, err := loadFromDB(ctx, db, jd.UID, jd.Typename)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("could not load job %q: %w", jd.UID, err))
+				return
+			}
+			jobMap[jd.UID] = v
+		}(jd)
+	}
+	wg.Wait()
+
+	return jobMap, errors.Join(errs...)
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/point"
+	"github.com/bvkgo/kv"
+	"github.com/bvkgo/kv/kvmemdb"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestLoadJobs(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+
+	var jds []*job.JobData
+	for i := 0; i < 20; i++ {
+		uid := uuid.NewString()
+		l, err := limiter.New(uid, "coinbase", "BTC-USD", p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := kv.WithReadWriter(ctx, db, l.Save); err != nil {
+			t.Fatal(err)
+		}
+		jds = append(jds, &job.JobData{UID: uid, Typename: "limiter"})
+	}
+
+	corrupt := uuid.NewString()
+	put := func(ctx context.Context, rw kv.ReadWriter) error {
+		return rw.Set(ctx, path.Join(limiter.DefaultKeyspace, corrupt), strings.NewReader("corrupt"))
+	}
+	if err := kv.WithReadWriter(ctx, db, put); err != nil {
+		t.Fatal(err)
+	}
+	missing, unknown := uuid.NewString(), uuid.NewString()
+	jds = append(jds,
+		&job.JobData{UID: corrupt, Typename: "limiter"},
+		&job.JobData{UID: missing, Typename: "limiter"},
+		&job.JobData{UID: unknown, Typename: "unknown"})
+
+	traders, err := loadJobs(ctx, db, jds, 4)
+	if err == nil {
+		t.Fatalf("want an error for the invalid jobs")
+	}
+	if len(traders) != 20 {
+		t.Fatalf("want 20 loaded jobs, got %d (%v)", len(traders), err)
+	}
+	for _, uid := range []string{corrupt, missing, unknown} {
+		if _, ok := traders[uid]; ok {
+			t.Fatalf("invalid job %q must not be loaded", uid)
+		}
+	}
+	for _, jd := range jds[:20] {
+		if v, ok := traders[jd.UID]; !ok || v.UID() != jd.UID {
+			t.Fatalf("job %q is not loaded", jd.UID)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvkgo/kv"
+	"github.com/google/uuid"
+)
+
+// JobNotesKeyspace holds the operator notes for the jobs keyed by the job uid.
+// Notes are metadata only and do not affect trading.
+const JobNotesKeyspace = "/notes/"
+
+func (s *Server) doJobNote(ctx context.Context, req *api.JobNoteRequest) (*api.JobNoteResponse, error) {
+	if err := req.Check(); err != nil {
+		return nil, fmt.Errorf("invalid job note request: %w", err)
+	}
+
+	if _, err := uuid.Parse(req.UID); err != nil {
+		return nil, fmt.Errorf("job uid must be an uuid: %w", err)
+	}
+
+	note := func(ctx context.Context, rw kv.ReadWriter) error {
+		if _, err := s.runner.Get(ctx, rw, req.UID); err != nil {
+			return fmt.Errorf("could not load job %q: %w", req.UID, err)
+		}
+		key := path.Join(JobNotesKeyspace, req.UID)
+		text := strings.TrimSpace(req.Note)
+		if len(text) == 0 {
+			if err := rw.Delete(ctx, key); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("could not delete job note: %w", err)
+			}
+			return nil
+		}
+		v := &gobs.JobNote{
+			Note:       text,
+			UpdateTime: time.Now(),
+		}
+		if err := kvutil.Set(ctx, rw, key, v); err != nil {
+			return fmt.Errorf("could not save job note: %w", err)
+		}
+		return nil
+	}
+	if err := kv.WithReadWriter(ctx, s.db, note); err != nil {
+		return nil, err
+	}
+	return &api.JobNoteResponse{}, nil
+}
+
+// jobNote returns the note attached to a job or an empty string if the job
+// has no note.
+func jobNote(ctx context.Context, r kv.Reader, uid string) (string, error) {
+	v, err := kvutil.Get[gobs.JobNote](ctx, r, path.Join(JobNotesKeyspace, uid))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	return v.Note, nil
+}
@@ -2,7 +2,11 @@
 
 package server
 
-import "time"
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
 
 type Options struct {
 	// RunFixes when true, trader.Start method will call Fix method on all trade
@@ -12,6 +16,11 @@ type Options struct {
 	// NoResume when true, will NOT resume the trade jobs automatically.
 	NoResume bool
 
+	// StartPaused when true, marks all jobs that would be resumed as paused
+	// manually, so that they must be resumed explicitly, even across the later
+	// restarts. It is meant for verifying the environment after a migration.
+	StartPaused bool
+
 	// NoFetchCandles when true, will disable periodic fetch of product candles data.
 	NoFetchCandles bool
 
@@ -20,6 +29,76 @@ type Options struct {
 
 	// Max timeout for http requests.
 	MaxHttpClientTimeout time.Duration
+
+	// AllowedProducts when non-empty, restricts the products that can be traded
+	// on the exchanges.
+	AllowedProducts []string
+
+	// PriceSource selects the price used in the product tickers, which is one
+	// of "last-trade" (default), "mid" or "index" when supported by the
+	// exchange.
+	PriceSource string
+
+	// TickerMode selects how the tickers are received from the exchanges,
+	// which is one of "websocket" (default), "rest-poll" or "auto" when
+	// supported by the exchange.
+	TickerMode string
+
+	// TickerPollInterval is the interval between the ticker polls when the
+	// tickers are polled.
+	TickerPollInterval time.Duration
+
+	// UseKeyring when true, allows loading the coinbase credentials from the
+	// OS keyring when they are not in the environment. Keyring credentials
+	// take precedence over the secrets file.
+	UseKeyring bool
+
+	// ExportDir is the directory where jobs are exported before they are
+	// removed by the compaction.
+	ExportDir string
+
+	// CompactRetention when non-zero, enables the background compaction, which
+	// removes the completed limiter jobs older than the retention window.
+	CompactRetention time.Duration
+
+	// CompactInterval is the interval between the background compactions.
+	CompactInterval time.Duration
+
+	// StopTimeout when non-zero, is the max time given to the jobs to cancel
+	// their active orders when the server is stopped. Orders of the jobs that
+	// are not stopped within the timeout are logged as possibly orphaned.
+	StopTimeout time.Duration
+
+	// SnapCancel when true, rounds the cancel prices of the new jobs to the
+	// product's price increment. See point.SnapCancel for the rounding
+	// direction.
+	SnapCancel bool
+
+	// LoadConcurrency is the max number of jobs loaded in parallel when the
+	// jobs are resumed at the startup.
+	LoadConcurrency int
+
+	// DefaultSizeLimits holds the default size-limit for the limiters on each
+	// product, which is used when a limiter has no explicit size-limit option.
+	DefaultSizeLimits map[string]decimal.Decimal
+
+	// MaxBudget when positive, limits the total budget of the running jobs, so
+	// that new jobs that would exceed the limit are rejected.
+	MaxBudget decimal.Decimal
+
+	// ExchangeConcurrency when positive, limits the number of in-flight
+	// exchange REST calls by all limiters.
+	ExchangeConcurrency int
+
+	// JobLogs when non-nil, is notified when the jobs are started and stopped,
+	// so that per-job log files are written only for the running jobs.
+	JobLogs JobLogTracker
+}
+
+// JobLogTracker tracks the running jobs for the per-job logs.
+type JobLogTracker interface {
+	Track(uid string)
+	Untrack(uid string)
 }
 
 func (v *Options) setDefaults() {
@@ -29,4 +108,10 @@ func (v *Options) setDefaults() {
 	if v.MaxHttpClientTimeout == 0 {
 		v.MaxHttpClientTimeout = 10 * time.Second
 	}
+	if v.CompactInterval == 0 {
+		v.CompactInterval = 24 * time.Hour
+	}
+	if v.LoadConcurrency == 0 {
+		v.LoadConcurrency = DefaultLoadConcurrency
+	}
 }
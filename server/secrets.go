@@ -4,6 +4,7 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 
 	"github.com/bvk/tradebot/coinbase"
@@ -13,11 +14,34 @@ import (
 type Secrets struct {
 	Coinbase *coinbase.Credentials
 	Pushover *pushover.Keys
+
+	// CoinbaseProfiles holds the credentials for the coinbase sub-accounts
+	// keyed by the profile name. Jobs target a sub-account with the
+	// "coinbase:<profile>" exchange name. Credentials can be left empty to use
+	// the profile specific environment variables instead.
+	CoinbaseProfiles map[string]*coinbase.Credentials
+
+	// APITokens when non-nil, enables the token authentication for the server
+	// api handlers.
+	APITokens *APITokens
+}
+
+// APITokens holds the bearer tokens for the server api. Read-only tokens can
+// only use the endpoints that do not modify the jobs and admin tokens can use
+// all endpoints.
+type APITokens struct {
+	ReadOnly []string
+	Admin    []string
 }
 
+// SecretsFromFile loads the secrets from a json file. Missing secrets file is
+// not an error when coinbase credentials are given through the environment.
 func SecretsFromFile(fpath string) (*Secrets, error) {
 	data, err := os.ReadFile(fpath)
 	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && coinbase.CredentialsFromEnv() != nil {
+			return new(Secrets), nil
+		}
 		return nil, err
 	}
 	s := new(Secrets)
@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"os"
 	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -26,6 +27,7 @@ import (
 	"github.com/bvk/tradebot/kvutil"
 	"github.com/bvk/tradebot/limiter"
 	"github.com/bvk/tradebot/looper"
+	"github.com/bvk/tradebot/point"
 	"github.com/bvk/tradebot/pushover"
 	"github.com/bvk/tradebot/syncmap"
 	"github.com/bvk/tradebot/trader"
@@ -41,7 +43,12 @@ const (
 
 	NamesKeyspace = "/names/"
 
+	// SnapshotsKeyspace holds the price snapshots keyed by the snapshot time.
+	SnapshotsKeyspace = "/price-snapshots/"
+
 	serverStateKey = "/server/state"
+
+	killSwitchKey = "/server/kill-switch"
 )
 
 type Server struct {
@@ -59,13 +66,28 @@ type Server struct {
 
 	jobMap syncmap.Map[string, trader.Trader]
 
+	// jobStatMap holds the runtime statistics for the jobs whose Run method is
+	// executing.
+	jobStatMap syncmap.Map[string, *jobStat]
+
+	// budget limits the total budget of the running jobs.
+	budget *budgetGuard
+
 	mu sync.Mutex
 
+	// repegMu serializes the repeg operations, so that concurrent repegs do not
+	// pause and resume the same jobs out of order.
+	repegMu sync.Mutex
+
 	state *gobs.ServerState
 
 	exProductsMap map[string]map[string]exchange.Product
 
 	pushoverClient *pushover.Client
+
+	// tokens holds the api tokens, which is nil when the authentication is
+	// disabled.
+	tokens *APITokens
 }
 
 func New(newctx context.Context, secrets *Secrets, db kv.Database, opts *Options) (_ *Server, status error) {
@@ -74,6 +96,15 @@ func New(newctx context.Context, secrets *Secrets, db kv.Database, opts *Options
 	}
 	opts.setDefaults()
 
+	for pid, size := range opts.DefaultSizeLimits {
+		if err := limiter.SetDefaultSizeLimit(pid, size); err != nil {
+			return nil, err
+		}
+	}
+	if err := limiter.SetExchangeConcurrency(opts.ExchangeConcurrency); err != nil {
+		return nil, err
+	}
+
 	exchangeMap := make(map[string]exchange.Exchange)
 	defer func() {
 		if status != nil {
@@ -84,10 +115,19 @@ func New(newctx context.Context, secrets *Secrets, db kv.Database, opts *Options
 	}()
 
 	var coinbaseClient *coinbase.Exchange
+	if secrets.Coinbase == nil && (opts.UseKeyring || coinbase.CredentialsFromEnv() != nil) {
+		secrets.Coinbase = new(coinbase.Credentials)
+	}
 	if secrets.Coinbase != nil {
 		cbopts := &coinbase.Options{
 			MaxFetchTimeLatency: opts.MaxFetchTimeLatency,
 			HttpClientTimeout:   opts.MaxHttpClientTimeout,
+			AuthScheme:          secrets.Coinbase.AuthScheme,
+			AllowedProducts:     opts.AllowedProducts,
+			PriceSource:         opts.PriceSource,
+			TickerMode:          opts.TickerMode,
+			TickerPollInterval:  opts.TickerPollInterval,
+			UseKeyring:          opts.UseKeyring,
 		}
 		if opts.NoFetchCandles {
 			cbopts.FetchCandlesInterval = -1
@@ -97,8 +137,37 @@ func New(newctx context.Context, secrets *Secrets, db kv.Database, opts *Options
 			return nil, fmt.Errorf("could not create coinbase client: %w", err)
 		}
 		coinbaseClient = client
+		exchangeMap["coinbase"] = coinbaseClient
+	}
+
+	var profiles []string
+	for profile := range secrets.CoinbaseProfiles {
+		profiles = append(profiles, profile)
+	}
+	sort.Strings(profiles)
+	for _, profile := range profiles {
+		creds := secrets.CoinbaseProfiles[profile]
+		if creds == nil {
+			creds = new(coinbase.Credentials)
+		}
+		cbopts := &coinbase.Options{
+			MaxFetchTimeLatency: opts.MaxFetchTimeLatency,
+			HttpClientTimeout:   opts.MaxHttpClientTimeout,
+			AuthScheme:          creds.AuthScheme,
+			AllowedProducts:     opts.AllowedProducts,
+			PriceSource:         opts.PriceSource,
+			TickerMode:          opts.TickerMode,
+			TickerPollInterval:  opts.TickerPollInterval,
+			Profile:             profile,
+			// Candles are shared by all accounts, so they are fetched only once.
+			FetchCandlesInterval: -1,
+		}
+		client, err := coinbase.New(newctx, db, creds.Key, creds.Secret, cbopts)
+		if err != nil {
+			return nil, fmt.Errorf("could not create coinbase client for profile %q: %w", profile, err)
+		}
+		exchangeMap[client.ExchangeName()] = client
 	}
-	exchangeMap["coinbase"] = coinbaseClient
 
 	var pushoverClient *pushover.Client
 	if secrets.Pushover != nil {
@@ -123,7 +192,9 @@ func New(newctx context.Context, secrets *Secrets, db kv.Database, opts *Options
 		exchangeMap:    exchangeMap,
 		handlerMap:     make(map[string]http.Handler),
 		runner:         job.NewRunner(),
+		budget:         newBudgetGuard(opts.MaxBudget),
 		pushoverClient: pushoverClient,
+		tokens:         secrets.APITokens,
 	}
 
 	if t.state == nil {
@@ -152,14 +223,43 @@ func New(newctx context.Context, secrets *Secrets, db kv.Database, opts *Options
 	t.handlerMap[api.JobResumePath] = httpPostJSONHandler(t.doResume)
 	t.handlerMap[api.JobPausePath] = httpPostJSONHandler(t.doPause)
 	t.handlerMap[api.JobSetOptionPath] = httpPostJSONHandler(t.doJobSetOption)
+	t.handlerMap[api.JobHoldChildPath] = httpPostJSONHandler(t.doJobHoldChild)
+	t.handlerMap[api.JobHoldPath] = httpPostJSONHandler(t.doJobHold)
+	t.handlerMap[api.JobStartPausedPath] = httpPostJSONHandler(t.doJobStartPaused)
 	t.handlerMap[api.SetJobNamePath] = httpPostJSONHandler(t.doSetJobName)
+	t.handlerMap[api.JobNotePath] = httpPostJSONHandler(t.doJobNote)
+	t.handlerMap[api.KillSwitchPath] = httpPostJSONHandler(t.doKillSwitch)
+	t.handlerMap[api.JobClonePath] = httpPostJSONHandler(t.doJobClone)
+	t.handlerMap[api.JobSplitPath] = httpPostJSONHandler(t.doJobSplit)
+	t.handlerMap[api.JobConfigPath] = httpPostJSONHandler(t.doJobConfig)
+	t.handlerMap[api.DBCompactPath] = httpPostJSONHandler(t.doDBCompact)
+	t.handlerMap[api.JobVerifyPath] = httpPostJSONHandler(t.doJobVerify)
 
 	t.handlerMap[api.LimitPath] = httpPostJSONHandler(t.doLimit)
 	t.handlerMap[api.LoopPath] = httpPostJSONHandler(t.doLoop)
 	t.handlerMap[api.WallPath] = httpPostJSONHandler(t.doWall)
+	t.handlerMap[api.WallerStatusPath] = httpPostJSONHandler(t.doWallerStatus)
 
 	t.handlerMap[api.ExchangeGetOrderPath] = httpPostJSONHandler(t.doExchangeGetOrder)
+	t.handlerMap[api.ExchangeGetOrdersPath] = httpPostJSONHandler(t.doExchangeGetOrders)
 	t.handlerMap[api.ExchangeGetProductPath] = httpPostJSONHandler(t.doGetProduct)
+	t.handlerMap[api.ExchangeRepegPath] = httpPostJSONHandler(t.doExchangeRepeg)
+	t.handlerMap[api.ExchangeCancelStalePath] = httpPostJSONHandler(t.doExchangeCancelStale)
+	t.handlerMap[api.ExchangeFeeTierPath] = httpPostJSONHandler(t.doExchangeFeeTier)
+	t.handlerMap[api.ExchangeCheckAuthPath] = httpPostJSONHandler(t.doExchangeCheckAuth)
+	t.handlerMap[api.ExchangeSnapshotPath] = httpPostJSONHandler(t.doExchangeSnapshot)
+
+	t.handlerMap[DebugJobsPath] = http.HandlerFunc(t.serveDebugJobs)
+	t.handlerMap[DebugHealthPath] = http.HandlerFunc(t.serveDebugHealth)
+	t.handlerMap[api.JobWatchPath] = http.HandlerFunc(t.serveJobWatch)
+
+	for path, h := range t.handlerMap {
+		scope := scopeAdmin
+		if readOnlyPaths[path] {
+			scope = scopeReadOnly
+		}
+		t.handlerMap[path] = t.authHandler(scope, h)
+	}
 
 	for _, ex := range t.exchangeMap {
 		limiter.RunBackgroundTasks(&t.cg, t.db, ex)
@@ -202,7 +302,31 @@ func (s *Server) SendMessage(ctx context.Context, at time.Time, msgfmt string, a
 }
 
 func (s *Server) Stop(ctx context.Context) error {
-	if err := job.StopAllDB(ctx, s.runner, s.db); err != nil {
+	var uids []string
+	s.jobMap.Range(func(uid string, _ trader.Trader) bool {
+		uids = append(uids, uid)
+		return true
+	})
+	sort.Strings(uids)
+	// Jobs are removed from the jobMap when they are stopped, so live orders
+	// are collected before the jobs are stopped.
+	orders := s.liveOrders(uids)
+
+	waitCtx := ctx
+	if s.opts.StopTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, s.opts.StopTimeout)
+		defer cancel()
+	}
+	if running := s.runner.PauseAll(waitCtx); len(running) > 0 {
+		log.Printf("%d jobs could not be stopped within the stop timeout %s", len(running), s.opts.StopTimeout)
+		for _, order := range orders {
+			if slices.Contains(running, order.JobUID) {
+				log.Printf("warning: %s order %s of job %s for size %s at price %s may be left active on the exchange", order.Side, order.OrderID, order.JobUID, order.Size.StringFixed(3), order.Price.StringFixed(3))
+			}
+		}
+	}
+	if err := kv.WithReadWriter(ctx, s.db, s.runner.Sync); err != nil {
 		return fmt.Errorf("could not stop all jobs: %w", err)
 	}
 	hostname, _ := os.Hostname()
@@ -226,11 +350,22 @@ func (s *Server) Start(ctx context.Context) (status error) {
 		}
 	}
 
+	if s.opts.CompactRetention > 0 {
+		s.cg.Go(s.goCompact)
+	}
+
 	if s.opts.NoResume {
 		return nil
 	}
 
-	var uids []string
+	if ks, err := loadKillSwitch(ctx, s.db); err != nil {
+		return fmt.Errorf("could not check the kill-switch: %w", err)
+	} else if ks != nil {
+		log.Printf("kill-switch is engaged since %s (%s); no jobs are resumed", ks.EngageTime.Format(time.RFC3339), ks.Reason)
+		return nil
+	}
+
+	var jds []*job.JobData
 	collect := func(ctx context.Context, r kv.Reader, jd *job.JobData) error {
 		uid := jd.UID
 		if job.IsDone(jd.State) {
@@ -243,7 +378,7 @@ func (s *Server) Start(ctx context.Context) (status error) {
 			return nil
 		}
 
-		uids = append(uids, uid)
+		jds = append(jds, jd)
 		return nil
 	}
 
@@ -251,13 +386,33 @@ func (s *Server) Start(ctx context.Context) (status error) {
 		return fmt.Errorf("could not resume all jobs: %w", err)
 	}
 
+	// Jobs are loaded in parallel cause loading hundreds of limiters serially
+	// makes the startup slow.
+	start := time.Now()
+	traderMap, err := loadJobs(ctx, s.db, jds, s.opts.LoadConcurrency)
+	if err != nil {
+		log.Printf("could not load %d of %d jobs (skipped): %v", len(jds)-len(traderMap), len(jds), err)
+	}
+	log.Printf("loaded %d jobs in %s", len(traderMap), time.Since(start))
+
 	resume := func(ctx context.Context, rw kv.ReadWriter) error {
-		for _, uid := range uids {
+		for _, v := range jds {
+			uid := v.UID
 			jd, err := s.runner.Get(ctx, rw, uid)
 			if err != nil {
 				return fmt.Errorf("could not get job data for %q: %w", uid, err)
 			}
-			if _, err := s.resume(ctx, rw, jd); err != nil {
+			if s.opts.StartPaused || jd.Flags&StartPausedFlag != 0 {
+				if err := s.startPaused(ctx, rw, jd); err != nil {
+					log.Printf("could not mark job %q as paused (skipped): %v", uid, err)
+				}
+				continue
+			}
+			trader, ok := traderMap[uid]
+			if !ok {
+				continue
+			}
+			if _, err := s.resumeTrader(ctx, rw, jd, trader); err != nil {
 				log.Printf("could not resume job %q (skipped): %v", uid, err)
 			}
 		}
@@ -267,6 +422,22 @@ func (s *Server) Start(ctx context.Context) (status error) {
 	return nil
 }
 
+// startPaused marks a job that would be resumed at startup as paused. Job is
+// also marked as manual when all jobs are started paused, so that it needs an
+// explicit resume.
+func (s *Server) startPaused(ctx context.Context, rw kv.ReadWriter, jd *job.JobData) error {
+	if _, err := s.runner.Pause(ctx, rw, jd.UID); err != nil {
+		return err
+	}
+	if s.opts.StartPaused {
+		if err := s.runner.UpdateFlags(ctx, rw, jd.UID, jd.Flags|ManualFlag); err != nil {
+			return err
+		}
+	}
+	log.Printf("job %q is left paused at startup (flags 0x%x)", jd.UID, jd.Flags)
+	return nil
+}
+
 func (s *Server) resume(ctx context.Context, rw kv.ReadWriter, jdata *job.JobData) (job.State, error) {
 	uid := jdata.UID
 	if job.IsDone(jdata.State) {
@@ -281,8 +452,16 @@ func (s *Server) resume(ctx context.Context, rw kv.ReadWriter, jdata *job.JobDat
 	if err != nil {
 		return "", fmt.Errorf("could not load trader job %q: %w", uid, err)
 	}
+	return s.resumeTrader(ctx, rw, jdata, trader)
+}
 
-	state, err := s.runner.Resume(ctx, rw, uid, s.makeJobFunc(trader), s.cg.Context())
+// resumeTrader resumes a job with an already loaded trader.
+func (s *Server) resumeTrader(ctx context.Context, rw kv.ReadWriter, jdata *job.JobData, trader trader.Trader) (job.State, error) {
+	uid := jdata.UID
+	if err := checkKillSwitch(ctx, rw); err != nil {
+		return "", err
+	}
+	state, err := s.startJob(ctx, rw, uid, trader)
 	if err != nil {
 		return "", fmt.Errorf("could not resume job %q: %w", uid, err)
 	}
@@ -290,6 +469,25 @@ func (s *Server) resume(ctx context.Context, rw kv.ReadWriter, jdata *job.JobDat
 	return state, nil
 }
 
+// startJob reserves the budget for a job and resumes it with the runner. All
+// jobs must be started through this function, so that the max-budget limit is
+// checked for every job. Reservation is released when the job function returns
+// or when the job could not be started.
+func (s *Server) startJob(ctx context.Context, rw kv.ReadWriter, uid string, v trader.Trader) (job.State, error) {
+	if err := s.budget.reserve(uid, v.BudgetFootprint(), &s.jobMap); err != nil {
+		return "", err
+	}
+	state, err := s.runner.Resume(ctx, rw, uid, s.makeJobFunc(v), s.cg.Context())
+	if err != nil {
+		// Reservation belongs to the running job when it is already resumed.
+		if !errors.Is(err, os.ErrExist) {
+			s.budget.release(uid)
+		}
+		return "", err
+	}
+	return state, nil
+}
+
 func (s *Server) runFixes(ctx context.Context) (status error) {
 	type Fixer interface {
 		Fix(context.Context, *trader.Runtime) error
@@ -339,8 +537,10 @@ func (s *Server) getProductLocked(ctx context.Context, exchangeName, productID s
 		}
 	}
 
-	// check if product is enabled.
-	estate, ok := s.state.ExchangeMap[exchangeName]
+	// check if product is enabled. Profiles of an exchange share the enabled
+	// products of the exchange.
+	baseName, _, _ := strings.Cut(exchangeName, ":")
+	estate, ok := s.state.ExchangeMap[baseName]
 	if !ok {
 		return nil, fmt.Errorf("exchange %q is not supported", exchangeName)
 	}
@@ -435,6 +635,9 @@ func (s *Server) doLimit(ctx context.Context, req *api.LimitRequest) (_ *api.Lim
 	if _, err := s.getProduct(ctx, req.ExchangeName, req.ProductID); err != nil {
 		return nil, err
 	}
+	if err := s.snapCancels(ctx, req.ExchangeName, req.ProductID, req.Point); err != nil {
+		return nil, err
+	}
 
 	uid := uuid.New().String()
 	limit, err := limiter.New(uid, req.ExchangeName, req.ProductID, req.Point)
@@ -449,7 +652,10 @@ func (s *Server) doLimit(ctx context.Context, req *api.LimitRequest) (_ *api.Lim
 		if err := s.runner.Add(ctx, rw, uid, "Limiter"); err != nil {
 			return fmt.Errorf("could not add new limiter as a job: %w", err)
 		}
-		if _, err := s.runner.Resume(ctx, rw, uid, s.makeJobFunc(limit), s.cg.Context()); err != nil {
+		if err := checkKillSwitch(ctx, rw); err != nil {
+			return err
+		}
+		if _, err := s.startJob(ctx, rw, uid, limit); err != nil {
 			return fmt.Errorf("could not resume new limiter job: %w", err)
 		}
 		return nil
@@ -478,12 +684,18 @@ func (s *Server) doLoop(ctx context.Context, req *api.LoopRequest) (_ *api.LoopR
 	if _, err := s.getProduct(ctx, req.ExchangeName, req.ProductID); err != nil {
 		return nil, err
 	}
+	if err := s.snapCancels(ctx, req.ExchangeName, req.ProductID, req.Buy, req.Sell); err != nil {
+		return nil, err
+	}
 
 	uid := uuid.New().String()
 	loop, err := looper.New(uid, req.ExchangeName, req.ProductID, req.Buy, req.Sell)
 	if err != nil {
 		return nil, err
 	}
+	if err := loop.SetBudget(req.Budget); err != nil {
+		return nil, err
+	}
 
 	start := func(ctx context.Context, rw kv.ReadWriter) error {
 		if err := loop.Save(ctx, rw); err != nil {
@@ -492,7 +704,10 @@ func (s *Server) doLoop(ctx context.Context, req *api.LoopRequest) (_ *api.LoopR
 		if err := s.runner.Add(ctx, rw, uid, "Looper"); err != nil {
 			return fmt.Errorf("could not add new looper as a job: %w", err)
 		}
-		if _, err := s.runner.Resume(ctx, rw, uid, s.makeJobFunc(loop), s.cg.Context()); err != nil {
+		if err := checkKillSwitch(ctx, rw); err != nil {
+			return err
+		}
+		if _, err := s.startJob(ctx, rw, uid, loop); err != nil {
 			return fmt.Errorf("could not resume new looper job: %w", err)
 		}
 		return nil
@@ -518,6 +733,18 @@ func (s *Server) doWall(ctx context.Context, req *api.WallRequest) (_ *api.WallR
 		return nil, fmt.Errorf("invalid wall request: %w", err)
 	}
 
+	var points []*point.Point
+	for _, p := range req.Pairs {
+		points = append(points, &p.Buy, &p.Sell)
+	}
+	if err := s.snapCancels(ctx, req.ExchangeName, req.ProductID, points...); err != nil {
+		return nil, err
+	}
+
+	if req.Validate {
+		return s.validateWall(ctx, req)
+	}
+
 	if _, err := s.getProduct(ctx, req.ExchangeName, req.ProductID); err != nil {
 		return nil, err
 	}
@@ -535,7 +762,10 @@ func (s *Server) doWall(ctx context.Context, req *api.WallRequest) (_ *api.WallR
 		if err := s.runner.Add(ctx, rw, uid, "Waller"); err != nil {
 			return fmt.Errorf("could not add new waller as a job: %w", err)
 		}
-		if _, err := s.runner.Resume(ctx, rw, uid, s.makeJobFunc(wall), s.cg.Context()); err != nil {
+		if err := checkKillSwitch(ctx, rw); err != nil {
+			return err
+		}
+		if _, err := s.startJob(ctx, rw, uid, wall); err != nil {
 			return fmt.Errorf("could not resume new waller job: %w", err)
 		}
 		return nil
@@ -0,0 +1,35 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/bvk/tradebot/point"
+)
+
+// snapCancels rounds the cancel prices of the input points to the product's
+// price increment when the snap-cancel option is set.
+func (s *Server) snapCancels(ctx context.Context, exchangeName, productID string, points ...*point.Point) error {
+	if !s.opts.SnapCancel {
+		return nil
+	}
+	exch, ok := s.exchangeMap[exchangeName]
+	if !ok {
+		return fmt.Errorf("exchange with name %q not found: %w", exchangeName, os.ErrNotExist)
+	}
+	product, err := exch.GetProduct(ctx, productID)
+	if err != nil {
+		return fmt.Errorf("could not get product %q on exchange %q: %w", productID, exchangeName, err)
+	}
+	for _, p := range points {
+		old := p.Cancel
+		p.SnapCancel(product.QuoteIncrement)
+		if err := p.Check(); err != nil {
+			return fmt.Errorf("point %s is invalid after snapping cancel-price %s to increment %s: %w", p, old, product.QuoteIncrement, err)
+		}
+	}
+	return nil
+}
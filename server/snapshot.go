@@ -0,0 +1,76 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/trader"
+)
+
+// doExchangeSnapshot fetches the current prices for all products with active
+// jobs in parallel, so that all jobs can be valued against the same instant.
+// Products with errors are reported, but they are not saved in the snapshot.
+func (s *Server) doExchangeSnapshot(ctx context.Context, req *api.ExchangeSnapshotRequest) (*api.ExchangeSnapshotResponse, error) {
+	type exProduct struct{ exchangeName, productID string }
+	pset := make(map[exProduct]struct{})
+	s.jobMap.Range(func(uid string, v trader.Trader) bool {
+		pset[exProduct{v.ExchangeName(), v.ProductID()}] = struct{}{}
+		return true
+	})
+
+	resp := &api.ExchangeSnapshotResponse{Timestamp: time.Now()}
+	for p := range pset {
+		resp.Prices = append(resp.Prices, &api.ExchangeSnapshotPrice{ExchangeName: p.exchangeName, ProductID: p.productID})
+	}
+	sort.Slice(resp.Prices, func(i, j int) bool {
+		a, b := resp.Prices[i], resp.Prices[j]
+		if a.ExchangeName != b.ExchangeName {
+			return a.ExchangeName < b.ExchangeName
+		}
+		return a.ProductID < b.ProductID
+	})
+
+	var wg sync.WaitGroup
+	for _, v := range resp.Prices {
+		wg.Add(1)
+		go func(v *api.ExchangeSnapshotPrice) {
+			defer wg.Done()
+
+			product, err := s.getProduct(ctx, v.ExchangeName, v.ProductID)
+			if err != nil {
+				v.Error = err.Error()
+				return
+			}
+			price, err := product.LastPrice(ctx)
+			if err != nil {
+				v.Error = err.Error()
+				return
+			}
+			v.Price = price
+		}(v)
+	}
+	wg.Wait()
+
+	if req.Save {
+		snapshot := &gobs.PriceSnapshot{Timestamp: resp.Timestamp}
+		for _, v := range resp.Prices {
+			if len(v.Error) == 0 {
+				snapshot.Prices = append(snapshot.Prices, &gobs.ProductPrice{ExchangeName: v.ExchangeName, ProductID: v.ProductID, Price: v.Price})
+			}
+		}
+		key := path.Join(SnapshotsKeyspace, fmt.Sprintf("%020d", resp.Timestamp.UnixNano()))
+		if err := kvutil.SetDB(ctx, s.db, key, snapshot); err != nil {
+			return nil, fmt.Errorf("could not save price snapshot: %w", err)
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/waller"
+	"github.com/google/uuid"
+)
+
+// validateWall runs all checks for a new waller job without persisting or
+// starting it and reports the required budget.
+func (s *Server) validateWall(ctx context.Context, req *api.WallRequest) (*api.WallResponse, error) {
+	exch, ok := s.exchangeMap[req.ExchangeName]
+	if !ok {
+		return nil, fmt.Errorf("exchange with name %q not found: %w", req.ExchangeName, os.ErrNotExist)
+	}
+	product, err := exch.GetProduct(ctx, req.ProductID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get product %q on exchange %q: %w", req.ProductID, req.ExchangeName, err)
+	}
+
+	for i, p := range req.Pairs {
+		if err := waller.CheckPoint(product, &p.Buy); err != nil {
+			return nil, fmt.Errorf("invalid buy point in pair %d: %w", i, err)
+		}
+		if err := waller.CheckPoint(product, &p.Sell); err != nil {
+			return nil, fmt.Errorf("invalid sell point in pair %d: %w", i, err)
+		}
+	}
+
+	wall, err := waller.New(uuid.New().String(), req.ExchangeName, req.ProductID, req.Pairs)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &api.WallResponse{
+		Budget:   wall.BudgetAt(req.FeePct),
+		NumPairs: len(req.Pairs),
+	}
+	return resp, nil
+}
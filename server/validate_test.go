@@ -0,0 +1,86 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/point"
+	"github.com/shopspring/decimal"
+)
+
+// productExchange is an exchange that only reports the product properties.
+type productExchange struct {
+	exchange.Exchange
+
+	product *gobs.Product
+}
+
+func (ex *productExchange) GetProduct(ctx context.Context, id string) (*gobs.Product, error) {
+	return ex.product, nil
+}
+
+func TestValidateWall(t *testing.T) {
+	ctx := context.Background()
+	d := decimal.RequireFromString
+
+	product := &gobs.Product{
+		ProductID:      "BTC-USD",
+		BaseMinSize:    d("0.01"),
+		BaseMaxSize:    d("10"),
+		BaseIncrement:  d("0.01"),
+		QuoteIncrement: d("0.01"),
+	}
+	s := &Server{exchangeMap: map[string]exchange.Exchange{"coinbase": &productExchange{product: product}}}
+
+	pair := func(size, bprice, bcancel, sprice, scancel string) *point.Pair {
+		return &point.Pair{
+			Buy:  point.Point{Size: d(size), Price: d(bprice), Cancel: d(bcancel)},
+			Sell: point.Point{Size: d(size), Price: d(sprice), Cancel: d(scancel)},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		exchange string
+		pair     *point.Pair
+		err      string
+	}{
+		{"valid", "coinbase", pair("1", "100", "105", "110", "105"), ""},
+		{"unknown exchange", "other", pair("1", "100", "105", "110", "105"), "not found"},
+		{"below min size", "coinbase", pair("0.001", "100", "105", "110", "105"), "below the product min size"},
+		{"above max size", "coinbase", pair("11", "100", "105", "110", "105"), "above the product max size"},
+		{"size increment", "coinbase", pair("1.005", "100", "105", "110", "105"), "base increment"},
+		{"buy price increment", "coinbase", pair("1", "100.005", "105", "110", "105"), "invalid buy point"},
+		{"buy cancel increment", "coinbase", pair("1", "100", "105.005", "110", "105"), "cancel price 105.005"},
+		{"sell price increment", "coinbase", pair("1", "100", "105", "110.005", "105"), "invalid sell point"},
+		{"sell cancel increment", "coinbase", pair("1", "100", "105", "110", "104.995"), "cancel price 104.995"},
+	}
+	for _, test := range tests {
+		req := &api.WallRequest{
+			ExchangeName: test.exchange,
+			ProductID:    "BTC-USD",
+			Pairs:        []*point.Pair{test.pair},
+			Validate:     true,
+		}
+		resp, err := s.doWall(ctx, req)
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("%s: want no error, got %v", test.name, err)
+				continue
+			}
+			if resp.NumPairs != 1 || !resp.Budget.Equal(d("100")) || resp.UID != "" {
+				t.Errorf("%s: want budget 100 for one pair without a job, got %+v", test.name, resp)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: want error with %q, got %v", test.name, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/timerange"
+	"github.com/bvk/tradebot/trader"
+	"github.com/bvkgo/kv"
+)
+
+// doJobVerify compares the in-memory state of the running jobs with a fresh
+// copy loaded from the database. Divergences indicate a missed save, which
+// would lose state if the server crashes. Jobs that are saving concurrently
+// may report transient divergences.
+func (s *Server) doJobVerify(ctx context.Context, req *api.JobVerifyRequest) (*api.JobVerifyResponse, error) {
+	running := make(map[string]trader.Trader)
+	s.jobMap.Range(func(uid string, v trader.Trader) bool {
+		if len(req.UIDs) == 0 || slices.Contains(req.UIDs, uid) {
+			running[uid] = v
+		}
+		return true
+	})
+	for _, uid := range req.UIDs {
+		if _, ok := running[uid]; !ok {
+			return nil, fmt.Errorf("job %q is not running", uid)
+		}
+	}
+
+	var uids []string
+	for uid := range running {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+
+	resp := new(api.JobVerifyResponse)
+	verify := func(ctx context.Context, r kv.Reader) error {
+		for _, uid := range uids {
+			item := &api.JobVerifyResponseItem{UID: uid}
+			resp.Jobs = append(resp.Jobs, item)
+
+			jd, err := s.runner.Get(ctx, r, uid)
+			if err != nil {
+				return fmt.Errorf("could not get job %q data: %w", uid, err)
+			}
+			saved, err := Load(ctx, r, uid, jd.Typename)
+			if err != nil {
+				item.Divergences = append(item.Divergences, fmt.Sprintf("could not load saved state: %v", err))
+				continue
+			}
+			item.Divergences = traderDivergences(running[uid], saved)
+		}
+		return nil
+	}
+	if err := kv.WithReader(ctx, s.db, verify); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// traderDivergences returns the differences between the orders and status
+// summaries of the in-memory and saved copies of a trader.
+func traderDivergences(mem, saved trader.Trader) []string {
+	var diffs []string
+
+	orderMap := func(t trader.Trader) map[string]*gobs.Order {
+		m := make(map[string]*gobs.Order)
+		for _, a := range t.Actions() {
+			for _, order := range a.Orders {
+				id := order.ServerOrderID
+				if len(id) == 0 {
+					id = order.ClientOrderID
+				}
+				m[id] = order
+			}
+		}
+		return m
+	}
+	memOrders, savedOrders := orderMap(mem), orderMap(saved)
+
+	var ids []string
+	for id := range memOrders {
+		ids = append(ids, id)
+	}
+	for id := range savedOrders {
+		if _, ok := memOrders[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		m, mok := memOrders[id]
+		s, sok := savedOrders[id]
+		switch {
+		case !sok:
+			diffs = append(diffs, fmt.Sprintf("order %s is not saved", id))
+		case !mok:
+			diffs = append(diffs, fmt.Sprintf("order %s is saved, but not in memory", id))
+		case m.Done != s.Done || m.Status != s.Status:
+			diffs = append(diffs, fmt.Sprintf("order %s has status %s (done=%t) in memory, but %s (done=%t) in saved state", id, m.Status, m.Done, s.Status, s.Done))
+		case !m.FilledSize.Equal(s.FilledSize) || !m.FilledFee.Equal(s.FilledFee):
+			diffs = append(diffs, fmt.Sprintf("order %s has filled size %s (fee %s) in memory, but %s (fee %s) in saved state", id, m.FilledSize, m.FilledFee, s.FilledSize, s.FilledFee))
+		}
+	}
+
+	type Statuser interface {
+		Status(*timerange.Range) *trader.Status
+	}
+	if ms, ok := mem.(Statuser); ok {
+		if ss, ok := saved.(Statuser); ok {
+			mstatus, sstatus := ms.Status(nil), ss.Status(nil)
+			if (mstatus == nil) != (sstatus == nil) {
+				diffs = append(diffs, "status is available in only one of the in-memory and saved states")
+			} else if mstatus != nil && mstatus.Summary.String() != sstatus.Summary.String() {
+				diffs = append(diffs, fmt.Sprintf("status summary is {%s} in memory, but {%s} in saved state", mstatus.Summary, sstatus.Summary))
+			}
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/point"
+	"github.com/bvkgo/kv"
+	"github.com/bvkgo/kv/kvmemdb"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// actionsJob is a job with fixed in-memory actions.
+type actionsJob struct {
+	*limiter.Limiter
+
+	actions []*gobs.Action
+}
+
+func (v *actionsJob) Actions() []*gobs.Action {
+	return v.actions
+}
+
+func TestJobVerify(t *testing.T) {
+	ctx := context.Background()
+	d := decimal.RequireFromString
+
+	s := &Server{
+		db:     kvmemdb.New(),
+		runner: job.NewRunner(),
+	}
+
+	uid := uuid.NewString()
+	l, err := limiter.New(uid, "coinbase", "BTC-USD", &point.Point{Size: d("1"), Price: d("100"), Cancel: d("110")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	save := func(ctx context.Context, rw kv.ReadWriter) error {
+		if err := l.Save(ctx, rw); err != nil {
+			return err
+		}
+		return s.runner.Add(ctx, rw, uid, "limiter")
+	}
+	if err := kv.WithReadWriter(ctx, s.db, save); err != nil {
+		t.Fatal(err)
+	}
+	s.jobMap.Store(uid, l)
+
+	resp, err := s.doJobVerify(ctx, &api.JobVerifyRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Jobs) != 1 || resp.Jobs[0].UID != uid {
+		t.Fatalf("want verify result for the running job, got %v", resp.Jobs)
+	}
+	if diffs := resp.Jobs[0].Divergences; len(diffs) != 0 {
+		t.Fatalf("want no divergences for a saved job, got %v", diffs)
+	}
+
+	// Orders that are only in memory indicate a missed save.
+	mem := &actionsJob{
+		Limiter: l,
+		actions: []*gobs.Action{{
+			UID:    uid,
+			Orders: []*gobs.Order{{ServerOrderID: "unsaved", Side: "BUY", Status: "OPEN"}},
+		}},
+	}
+	s.jobMap.Store(uid, mem)
+
+	resp, err = s.doJobVerify(ctx, &api.JobVerifyRequest{UIDs: []string{uid}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diffs := resp.Jobs[0].Divergences; len(diffs) != 1 || !strings.Contains(diffs[0], "unsaved is not saved") {
+		t.Fatalf("want a divergence for the unsaved order, got %v", diffs)
+	}
+
+	// Only running jobs can be verified.
+	if _, err := s.doJobVerify(ctx, &api.JobVerifyRequest{UIDs: []string{uuid.NewString()}}); err == nil {
+		t.Fatalf("want error for jobs that are not running")
+	}
+}
+
+func TestTraderDivergences(t *testing.T) {
+	d := decimal.RequireFromString
+
+	l, err := limiter.New(uuid.NewString(), "coinbase", "BTC-USD", &point.Point{Size: d("1"), Price: d("100"), Cancel: d("110")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newJob := func(orders ...*gobs.Order) *actionsJob {
+		return &actionsJob{Limiter: l, actions: []*gobs.Action{{UID: l.UID(), Orders: orders}}}
+	}
+
+	open := &gobs.Order{ServerOrderID: "a", Status: "OPEN"}
+	filled := &gobs.Order{ServerOrderID: "a", Status: "FILLED", Done: true, FilledSize: d("1")}
+	if diffs := traderDivergences(newJob(open), newJob(open)); len(diffs) != 0 {
+		t.Fatalf("want no divergences for identical orders, got %v", diffs)
+	}
+	if diffs := traderDivergences(newJob(filled), newJob(open)); len(diffs) != 1 || !strings.Contains(diffs[0], "has status FILLED") {
+		t.Fatalf("want a status divergence, got %v", diffs)
+	}
+	if diffs := traderDivergences(newJob(), newJob(open)); len(diffs) != 1 || !strings.Contains(diffs[0], "not in memory") {
+		t.Fatalf("want a divergence for the saved-only order, got %v", diffs)
+	}
+
+	partial := &gobs.Order{ServerOrderID: "a", Status: "OPEN", FilledSize: d("0.5")}
+	if diffs := traderDivergences(newJob(partial), newJob(open)); len(diffs) != 1 || !strings.Contains(diffs[0], "filled size 0.5") {
+		t.Fatalf("want a filled size divergence, got %v", diffs)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/waller"
+	"github.com/bvkgo/kv"
+)
+
+// doWallerStatus returns per-loop status of a waller. Status is taken from the
+// running instance when the job is active and from the database otherwise.
+func (s *Server) doWallerStatus(ctx context.Context, req *api.WallerStatusRequest) (*api.WallerStatusResponse, error) {
+	if err := req.Check(); err != nil {
+		return nil, fmt.Errorf("invalid waller status request: %w", err)
+	}
+
+	var wall *waller.Waller
+	if v, ok := s.jobMap.Load(req.UID); ok {
+		w, ok := v.(*waller.Waller)
+		if !ok {
+			return nil, fmt.Errorf("job %q is not a waller", req.UID)
+		}
+		wall = w
+	} else {
+		load := func(ctx context.Context, r kv.Reader) error {
+			w, err := waller.Load(ctx, req.UID, r)
+			if err != nil {
+				return fmt.Errorf("could not load waller %q: %w", req.UID, err)
+			}
+			wall = w
+			return nil
+		}
+		if err := kv.WithReader(ctx, s.db, load); err != nil {
+			return nil, err
+		}
+	}
+
+	resp := &api.WallerStatusResponse{
+		UID:       wall.UID(),
+		ProductID: wall.ProductID(),
+	}
+	resp.InventorySize, resp.InventoryAvgCost = wall.Inventory()
+	if product, err := s.getProduct(ctx, wall.ExchangeName(), wall.ProductID()); err == nil {
+		if price, err := product.LastPrice(ctx); err == nil {
+			e := wall.CapitalEfficiency(price, limiter.EstimatedFeePct)
+			resp.MarketPrice = e.Price
+			resp.NumActive = e.NumActive
+			resp.ActiveBudget = e.ActiveBudget
+			resp.IdleBudget = e.IdleBudget
+		}
+	}
+	for _, v := range wall.LoopStatuses() {
+		resp.Loops = append(resp.Loops, &api.WallerLoopStatus{
+			UID:      v.UID,
+			Pair:     v.Pair,
+			State:    v.State,
+			NumBuys:  v.NumBuys,
+			NumSells: v.NumSells,
+			Profit:   v.Profit,
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/timerange"
+	"github.com/bvk/tradebot/trader"
+)
+
+// WatchHeartbeatInterval is the interval for the heartbeat comments on the
+// job watch streams, which keep the idle connections alive through proxies.
+var WatchHeartbeatInterval = 15 * time.Second
+
+// watchCheckInterval is the interval to re-check the job status, cause the
+// job may process an event after the watcher has received it.
+const watchCheckInterval = time.Second
+
+// serveJobWatch streams the status of a running job as Server-Sent Events.
+// New status is sent when it changes after a ticker or order update on the
+// job's product. Stream ends with the final job state when the job stops.
+func (s *Server) serveJobWatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "invalid http method type", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ctx := r.Context()
+	uid := r.URL.Query().Get("uid")
+	if len(uid) == 0 {
+		http.Error(w, "uid parameter is required", http.StatusBadRequest)
+		return
+	}
+	v, ok := s.jobMap.Load(uid)
+	if !ok {
+		http.Error(w, fmt.Sprintf("job %q is not running", uid), http.StatusNotFound)
+		return
+	}
+
+	product, err := s.getProduct(ctx, v.ExchangeName(), v.ProductID())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tickerCh, stopTickers := product.TickerCh()
+	defer stopTickers()
+	orderCh, stopOrders := product.OrderUpdatesCh()
+	defer stopOrders()
+
+	w.Header().Set("content-type", "text/event-stream")
+	w.Header().Set("cache-control", "no-cache")
+	w.Header().Set("connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var last []byte
+	var lastTicker *exchange.Ticker
+
+	// Status summary only changes with the order updates, so it is cached
+	// instead of recomputing it for every ticker. Job may process an order
+	// update after the watcher, so it is recomputed again on the next check.
+	summary := watchSummary(v)
+	orderUpdated := false
+	send := func(status *api.JobWatchStatus) bool {
+		data, err := json.Marshal(status)
+		if err != nil {
+			log.Printf("could not marshal job watch status for %q: %v", uid, err)
+			return false
+		}
+		if bytes.Equal(data, last) {
+			return true
+		}
+		last = data
+		if _, err := fmt.Fprintf(w, "event: status\ndata: %s\n\n", data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	heartbeat := time.NewTicker(WatchHeartbeatInterval)
+	defer heartbeat.Stop()
+	check := time.NewTicker(watchCheckInterval)
+	defer check.Stop()
+
+	for {
+		// Job has stopped when it is removed from the job map; send the final
+		// state and end the stream.
+		if _, ok := s.jobMap.Load(uid); !ok {
+			status := watchStatus(v, lastTicker, watchSummary(v))
+			if state, err := job.StatusDB(ctx, s.db, uid); err == nil {
+				status.State = string(state)
+			} else {
+				status.State = ""
+			}
+			send(status)
+			return
+		}
+		if !send(watchStatus(v, lastTicker, summary)) {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprintf(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case t := <-tickerCh:
+			lastTicker = t
+		case <-orderCh:
+			summary = watchSummary(v)
+			orderUpdated = true
+		case <-check.C:
+			if orderUpdated {
+				summary = watchSummary(v)
+				orderUpdated = false
+			}
+		}
+	}
+}
+
+// watchSummary returns the status summary of a job or nil if the job doesn't
+// report it's status.
+func watchSummary(v trader.Trader) *trader.Summary {
+	type Statuser interface {
+		Status(*timerange.Range) *trader.Status
+	}
+	if sv, ok := v.(Statuser); ok {
+		if st := sv.Status(nil); st != nil {
+			return st.Summary
+		}
+	}
+	return nil
+}
+
+// watchStatus returns the current status of a running job. Unrealized profit
+// is computed from the summary at the ticker price.
+func watchStatus(v trader.Trader, ticker *exchange.Ticker, summary *trader.Summary) *api.JobWatchStatus {
+	type LiveOrderser interface {
+		LiveOrders() []*limiter.LiveOrder
+	}
+
+	status := &api.JobWatchStatus{
+		UID:          v.UID(),
+		State:        string(job.RUNNING),
+		ProductID:    v.ProductID(),
+		ExchangeName: v.ExchangeName(),
+	}
+	if lv, ok := v.(LiveOrderser); ok {
+		for _, order := range lv.LiveOrders() {
+			status.ActiveOrders = append(status.ActiveOrders, string(order.OrderID))
+			status.PendingSize = status.PendingSize.Add(order.Size)
+		}
+	}
+	if ticker != nil {
+		status.LastPrice = ticker.Price
+		status.LastTickerTime = ticker.Timestamp.Time
+	}
+	if summary != nil && ticker != nil {
+		status.UnrealizedProfit = summary.UnrealizedProfit(ticker.Price)
+	}
+	return status
+}
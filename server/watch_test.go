@@ -0,0 +1,95 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/paper"
+	"github.com/bvk/tradebot/point"
+	"github.com/bvk/tradebot/timerange"
+	"github.com/bvk/tradebot/trader"
+	"github.com/bvkgo/kv/kvmemdb"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// statusCountJob is a job that counts the status computations.
+type statusCountJob struct {
+	*limiter.Limiter
+
+	nstatus atomic.Int32
+}
+
+func (v *statusCountJob) Status(*timerange.Range) *trader.Status {
+	v.nstatus.Add(1)
+	return &trader.Status{
+		Summary: &trader.Summary{
+			UnsoldSize:  decimal.RequireFromString("1"),
+			UnsoldValue: decimal.RequireFromString("100"),
+		},
+	}
+}
+
+func TestJobWatchCachesStatus(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	d := decimal.RequireFromString
+
+	uid := uuid.NewString()
+	l, err := limiter.New(uid, "paper", "BTC-USD", &point.Point{Size: d("1"), Price: d("100"), Cancel: d("110")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	product := paper.New("BTC-USD", nil)
+	s := &Server{
+		db:            kvmemdb.New(),
+		exchangeMap:   map[string]exchange.Exchange{"paper": nil},
+		exProductsMap: map[string]map[string]exchange.Product{"paper": {"BTC-USD": product}},
+	}
+	job := &statusCountJob{Limiter: l}
+	var v trader.Trader = job
+	s.jobMap.Store(uid, v)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/trader/job/watch?uid="+uid, nil).WithContext(ctx)
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		s.serveJobWatch(w, r)
+	}()
+
+	for ctx.Err() == nil && product.NumTickerSubscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		ticker := &exchange.Ticker{Timestamp: exchange.RemoteTime{Time: time.Now()}, Price: d("105")}
+		if err := product.FeedTicker(ctx, ticker); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Next ticker ends the stream after the job is stopped.
+	s.jobMap.Delete(uid)
+	ticker := &exchange.Ticker{Timestamp: exchange.RemoteTime{Time: time.Now()}, Price: d("105")}
+	if err := product.FeedTicker(ctx, ticker); err != nil {
+		t.Fatal(err)
+	}
+	<-doneCh
+
+	// Status is computed once when the watch starts and once for the final
+	// state, but not for the tickers.
+	if n := job.nstatus.Load(); n != 2 {
+		t.Fatalf("want 2 status computations, got %d", n)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `"UnrealizedProfit":"5"`) {
+		t.Fatalf("want unrealized profit 5 at the ticker price, got %q", body)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
 	"time"
 )
@@ -21,6 +22,9 @@ type ClientFlags struct {
 	Host        string
 	APIPath     string
 	HTTPTimeout time.Duration
+
+	// APIToken is the bearer token for the server api, if required.
+	APIToken string
 }
 
 func (cf *ClientFlags) SetFlags(fset *flag.FlagSet) {
@@ -28,6 +32,7 @@ func (cf *ClientFlags) SetFlags(fset *flag.FlagSet) {
 	fset.StringVar(&cf.Host, "connect-host", "127.0.0.1", "Hostname or IP address for the api endpoint")
 	fset.StringVar(&cf.APIPath, "api-path", "/", "base path to the api handler")
 	fset.DurationVar(&cf.HTTPTimeout, "http-timeout", 30*time.Second, "http client timeout")
+	fset.StringVar(&cf.APIToken, "api-token", os.Getenv("TRADEBOT_API_TOKEN"), "bearer token for the api endpoint (defaults to TRADEBOT_API_TOKEN environment variable)")
 }
 
 func (cf *ClientFlags) AddressURL() *url.URL {
@@ -39,9 +44,25 @@ func (cf *ClientFlags) AddressURL() *url.URL {
 }
 
 func (cf *ClientFlags) HttpClient() *http.Client {
-	return &http.Client{
+	client := &http.Client{
 		Timeout: cf.HTTPTimeout,
 	}
+	if len(cf.APIToken) != 0 {
+		client.Transport = &tokenTransport{token: cf.APIToken, base: http.DefaultTransport}
+	}
+	return client
+}
+
+// tokenTransport adds the bearer token to all requests.
+type tokenTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(r)
 }
 
 func Post[RESP, REQ any](ctx context.Context, cf *ClientFlags, subpath string, req *REQ) (*RESP, error) {
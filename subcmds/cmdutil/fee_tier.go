@@ -0,0 +1,28 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package cmdutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/shopspring/decimal"
+)
+
+// LiveMakerFeePct fetches the maker fee percentage for the current fee tier
+// from the exchange through the server.
+func LiveMakerFeePct(ctx context.Context, cf *ClientFlags, exchangeName string) (decimal.Decimal, error) {
+	req := &api.ExchangeFeeTierRequest{
+		ExchangeName: exchangeName,
+	}
+	resp, err := Post[api.ExchangeFeeTierResponse](ctx, cf, api.ExchangeFeeTierPath, req)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("POST request to fee-tier failed: %w", err)
+	}
+	if len(resp.Error) != 0 {
+		return decimal.Zero, fmt.Errorf("could not fetch fee tier: %w", errors.New(resp.Error))
+	}
+	return resp.MakerFeePct, nil
+}
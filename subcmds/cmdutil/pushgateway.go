@@ -0,0 +1,120 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package cmdutil
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/bvk/tradebot/trader"
+	"github.com/shopspring/decimal"
+)
+
+// SummaryMetrics returns the summary as metrics in the Prometheus text
+// exposition format. Metric names are prefixed with "tradebot_" and every
+// sample carries the given labels.
+func SummaryMetrics(sum *trader.Summary, labels map[string]string) string {
+	var keys []string
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+	lstr := ""
+	if len(pairs) > 0 {
+		lstr = "{" + strings.Join(pairs, ",") + "}"
+	}
+
+	metrics := []struct {
+		name, help string
+		value      decimal.Decimal
+	}{
+		{"num_days", "Number of days in the summary time period.", sum.NumDays()},
+		{"num_buys", "Number of buy orders.", decimal.NewFromInt(int64(sum.NumBuys))},
+		{"num_sells", "Number of sell orders.", decimal.NewFromInt(int64(sum.NumSells))},
+		{"num_loops", "Number of completed buy-sell loops.", decimal.NewFromInt(int64(sum.NumLoops))},
+		{"budget", "Budget of the jobs.", sum.Budget},
+		{"bought_value", "Total value of the buys.", sum.Bought()},
+		{"sold_value", "Total value of the sells.", sum.Sold()},
+		{"unsold_value", "Value of the bought size that is not sold yet.", sum.UnsoldValue},
+		{"fees", "Total fees for the buys and sells.", sum.Fees()},
+		{"profit", "Realized profit after the fees.", sum.Profit()},
+		{"return_rate_pct", "Profit as a percentage of the budget.", sum.ReturnRate()},
+		{"max_drawdown", "Largest decline in the cumulative realized profit.", sum.MaxDrawdown()},
+	}
+
+	var b strings.Builder
+	for _, m := range metrics {
+		name := "tradebot_" + m.name
+		fmt.Fprintf(&b, "# HELP %s %s\n", name, m.help)
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", name)
+		fmt.Fprintf(&b, "%s%s %s\n", name, lstr, m.value.String())
+	}
+	return b.String()
+}
+
+// PushSummary pushes the summary metrics to a Prometheus pushgateway at the
+// given address under the job name, replacing all metrics previously pushed
+// for the same job.
+func PushSummary(ctx context.Context, addr, job string, sum *trader.Summary, labels map[string]string) error {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	u := strings.TrimSuffix(addr, "/") + "/metrics/job/" + url.PathEscape(job)
+
+	body := bytes.NewBufferString(SummaryMetrics(sum, labels))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, body)
+	if err != nil {
+		return fmt.Errorf("could not create pushgateway request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not push metrics to pushgateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("pushgateway returned status %q: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	return nil
+}
+
+// PushgatewayFlags holds the flags for the commands that push their summary
+// to a Prometheus pushgateway on completion.
+type PushgatewayFlags struct {
+	pushgateway    string
+	pushgatewayJob string
+}
+
+func (f *PushgatewayFlags) SetFlags(fset *flag.FlagSet) {
+	fset.StringVar(&f.pushgateway, "pushgateway", "", "When non-empty, pushes the summary as metrics to the Prometheus pushgateway at this address on completion")
+	fset.StringVar(&f.pushgatewayJob, "pushgateway-job", "tradebot", "Job name for the metrics pushed to the pushgateway")
+}
+
+// Push pushes the summary metrics to the pushgateway if the pushgateway flag
+// is set. Summary's quote currency is added as the currency label.
+func (f *PushgatewayFlags) Push(ctx context.Context, sum *trader.Summary, labels map[string]string) error {
+	if len(f.pushgateway) == 0 {
+		return nil
+	}
+	if len(sum.QuoteCurrency) != 0 {
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels["currency"] = sum.QuoteCurrency
+	}
+	return PushSummary(ctx, f.pushgateway, f.pushgatewayJob, sum, labels)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package cmdutil
+
+import (
+	"context"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bvk/tradebot/trader"
+	"github.com/shopspring/decimal"
+)
+
+func TestPushSummary(t *testing.T) {
+	var method, path, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(data)
+	}))
+	defer srv.Close()
+
+	sum := &trader.Summary{
+		NumBuys:     2,
+		NumSells:    1,
+		Budget:      decimal.NewFromInt(1000),
+		SoldValue:   decimal.NewFromInt(110),
+		BoughtValue: decimal.NewFromInt(100),
+		SoldFees:    decimal.NewFromInt(1),
+		BoughtFees:  decimal.NewFromInt(1),
+	}
+	labels := map[string]string{"currency": "USD"}
+	if err := PushSummary(context.Background(), srv.URL, "backtest", sum, labels); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPut || path != "/metrics/job/backtest" {
+		t.Fatalf("want PUT to /metrics/job/backtest, got %s to %s", method, path)
+	}
+	for _, want := range []string{
+		"# TYPE tradebot_profit gauge\n",
+		"tradebot_profit{currency=\"USD\"} 8\n",
+		"tradebot_num_sells{currency=\"USD\"} 1\n",
+		"tradebot_fees{currency=\"USD\"} 2\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("want %q in pushed metrics, got:\n%s", want, body)
+		}
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad metrics", http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	if err := PushSummary(context.Background(), failing.URL, "backtest", sum, nil); err == nil {
+		t.Fatalf("want error from pushgateway failure")
+	}
+}
+
+func TestPushgatewayFlags(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer srv.Close()
+
+	var f PushgatewayFlags
+	fset := flag.NewFlagSet("test", flag.ContinueOnError)
+	f.SetFlags(fset)
+
+	// Nothing is pushed without the pushgateway flag.
+	sum := &trader.Summary{QuoteCurrency: "USD", NumSells: 1}
+	if err := f.Push(context.Background(), sum, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != 0 {
+		t.Fatalf("want no push without the pushgateway flag")
+	}
+
+	if err := fset.Parse([]string{"-pushgateway", srv.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Push(context.Background(), sum, map[string]string{"waller": "w1"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "tradebot_num_sells{currency=\"USD\",waller=\"w1\"} 1\n"; !strings.Contains(body, want) {
+		t.Fatalf("want %q in pushed metrics, got:\n%s", want, body)
+	}
+}
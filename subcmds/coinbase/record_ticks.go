@@ -0,0 +1,111 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package coinbase
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/coinbase"
+	"github.com/bvk/tradebot/server"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type RecordTicks struct {
+	cmdutil.DBFlags
+
+	secretsPath string
+
+	profile string
+
+	output string
+}
+
+func (c *RecordTicks) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("record-ticks", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.StringVar(&c.secretsPath, "secrets-file", "", "path to credentials file")
+	fset.StringVar(&c.profile, "profile", "", "name of the coinbase profile for the credentials")
+	fset.StringVar(&c.output, "output", "", "path to the output file for the JSON lines")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *RecordTicks) Synopsis() string {
+	return "Records live tickers for a product as JSON lines till interrupted"
+}
+
+func (c *RecordTicks) run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one product-id argument")
+	}
+	productID := args[0]
+
+	if len(c.output) == 0 {
+		return fmt.Errorf("output file is required")
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if len(c.secretsPath) == 0 {
+		return fmt.Errorf("secrets file is required")
+	}
+	secrets, err := server.SecretsFromFile(c.secretsPath)
+	if err != nil {
+		return fmt.Errorf("could not load secrets: %w", err)
+	}
+	creds := secrets.Coinbase
+	if len(c.profile) != 0 {
+		creds = secrets.CoinbaseProfiles[c.profile]
+	}
+	if creds == nil {
+		creds = new(coinbase.Credentials)
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	opts := coinbase.SubcommandOptions()
+	opts.AuthScheme = creds.AuthScheme
+	opts.Profile = c.profile
+	exchange, err := coinbase.New(ctx, db, creds.Key, creds.Secret, opts)
+	if err != nil {
+		return fmt.Errorf("could not create coinbase client: %w", err)
+	}
+	defer exchange.Close()
+
+	fp, err := os.Create(c.output)
+	if err != nil {
+		return fmt.Errorf("could not create output file: %w", err)
+	}
+	defer fp.Close()
+
+	product, err := exchange.OpenProduct(ctx, productID)
+	if err != nil {
+		return fmt.Errorf("could not open product %q: %w", productID, err)
+	}
+	defer product.Close()
+
+	stopRecording := product.(*coinbase.Product).RecordTickers(fp)
+	<-ctx.Done()
+
+	if err := stopRecording(); err != nil {
+		return fmt.Errorf("could not record tickers: %w", err)
+	}
+	if err := fp.Sync(); err != nil {
+		return err
+	}
+	if err := context.Cause(ctx); !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
@@ -24,6 +24,8 @@ type Sync struct {
 
 	secretsPath string
 
+	profile string
+
 	fromDate string
 
 	dataType string
@@ -37,6 +39,7 @@ func (c *Sync) Command() (*flag.FlagSet, cli.CmdFunc) {
 	fset.StringVar(&c.productID, "product-id", "", "product id")
 	fset.StringVar(&c.fromDate, "from-date", "", "date of the day in YYYY-MM-DD format")
 	fset.StringVar(&c.secretsPath, "secrets-file", "", "path to credentials file")
+	fset.StringVar(&c.profile, "profile", "", "name of the coinbase profile for the credentials")
 	fset.StringVar(&c.dataType, "data-type", "", "one of filled|canceled|candles")
 	return fset, cli.CmdFunc(c.run)
 }
@@ -59,7 +62,11 @@ func (c *Sync) run(ctx context.Context, args []string) error {
 	if err != nil {
 		return fmt.Errorf("could not load secrets: %w", err)
 	}
-	if secrets.Coinbase == nil {
+	creds := secrets.Coinbase
+	if len(c.profile) != 0 {
+		creds = secrets.CoinbaseProfiles[c.profile]
+	}
+	if creds == nil {
 		return fmt.Errorf("coinbase credentials are missing")
 	}
 
@@ -78,7 +85,9 @@ func (c *Sync) run(ctx context.Context, args []string) error {
 	defer closer()
 
 	opts := coinbase.SubcommandOptions()
-	exchange, err := coinbase.New(ctx, db, secrets.Coinbase.Key, secrets.Coinbase.Secret, opts)
+	opts.AuthScheme = creds.AuthScheme
+	opts.Profile = c.profile
+	exchange, err := coinbase.New(ctx, db, creds.Key, creds.Secret, opts)
 	if err != nil {
 		return fmt.Errorf("could not create coinbase client: %w", err)
 	}
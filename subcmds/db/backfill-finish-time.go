@@ -0,0 +1,190 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package db
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvkgo/kv"
+)
+
+type BackfillFinishTime struct {
+	cmdutil.DBFlags
+
+	apply bool
+
+	noFetch bool
+}
+
+func (c *BackfillFinishTime) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("backfill-finish-time", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.BoolVar(&c.apply, "apply", false, "when true, saves the finish times to the database; otherwise, only prints them")
+	fset.BoolVar(&c.noFetch, "no-fetch", false, "when true, finish times are not fetched from the exchange and are always inferred")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *BackfillFinishTime) Synopsis() string {
+	return "Fills the missing finish times for the done orders in limiters"
+}
+
+func (c *BackfillFinishTime) CommandHelp() string {
+	return `
+
+Command "backfill-finish-time" scans all limiters for the done orders without
+a finish time and fetches the finish times from the exchange. When the
+exchange doesn't report it, finish time is inferred as the create time of the
+limiter's next order, or the limiter's last action time, or the order's own
+create time, whichever is available first.
+
+Command only prints the finish times by default (dry-run). Use the -apply flag
+to save them to the database. Running jobs update their own orders, so it is
+best used on the completed jobs.
+
+`
+}
+
+// backfill holds the finish time found for an order of a limiter.
+type backfill struct {
+	key     string
+	orderID string
+
+	finishTime time.Time
+	source     string
+}
+
+func (c *BackfillFinishTime) run(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("this command takes no arguments")
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	var backfills []*backfill
+	collect := func(ctx context.Context, r kv.Reader, key string, value *gobs.LimiterState) error {
+		value.Upgrade()
+		ename := value.V2.ExchangeName
+		if ename == "" {
+			ename = "coinbase"
+		}
+		for id, order := range value.V2.ServerIDOrderMap {
+			if !order.Done || !order.FinishTime.Time.IsZero() {
+				continue
+			}
+			b := &backfill{key: key, orderID: id}
+			if !c.noFetch {
+				b.finishTime = c.fetchFinishTime(ctx, ename, id)
+				b.source = "exchange"
+			}
+			if b.finishTime.IsZero() {
+				b.finishTime = inferFinishTime(value.V2, order)
+				b.source = "inferred"
+			}
+			backfills = append(backfills, b)
+		}
+		return nil
+	}
+	begin, end := kvutil.PathRange(limiter.DefaultKeyspace)
+	if err := kvutil.AscendDB(ctx, db, begin, end, collect); err != nil {
+		return fmt.Errorf("could not scan limiter states: %w", err)
+	}
+	sort.SliceStable(backfills, func(i, j int) bool {
+		return backfills[i].key < backfills[j].key
+	})
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "Limiter\tOrderID\tFinishTime\tSource\t\n")
+	for _, b := range backfills {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", strings.TrimPrefix(b.key, limiter.DefaultKeyspace), b.orderID, b.finishTime.Format(time.RFC3339), b.source)
+	}
+	tw.Flush()
+
+	if !c.apply {
+		fmt.Printf("%d orders need a finish time (use -apply to save them)\n", len(backfills))
+		return nil
+	}
+
+	keyMap := make(map[string][]*backfill)
+	for _, b := range backfills {
+		keyMap[b.key] = append(keyMap[b.key], b)
+	}
+	nsaved := 0
+	for key, bs := range keyMap {
+		save := func(ctx context.Context, rw kv.ReadWriter) error {
+			value, err := kvutil.Get[gobs.LimiterState](ctx, rw, key)
+			if err != nil {
+				return err
+			}
+			value.Upgrade()
+			modified := false
+			for _, b := range bs {
+				// Order may be updated by a running job in the meantime.
+				if order, ok := value.V2.ServerIDOrderMap[b.orderID]; ok && order.FinishTime.Time.IsZero() {
+					order.FinishTime = gobs.RemoteTime{Time: b.finishTime}
+					modified = true
+				}
+			}
+			if !modified {
+				return nil
+			}
+			return kvutil.Set(ctx, rw, key, value)
+		}
+		if err := kv.WithReadWriter(ctx, db, save); err != nil {
+			return fmt.Errorf("could not save finish times for limiter %s: %w", key, err)
+		}
+		nsaved++
+	}
+	fmt.Printf("saved finish times for %d orders in %d limiters\n", len(backfills), nsaved)
+	return nil
+}
+
+// fetchFinishTime returns the finish time of an order from the exchange or
+// zero time when it is not available.
+func (c *BackfillFinishTime) fetchFinishTime(ctx context.Context, ename, orderID string) time.Time {
+	req := &api.ExchangeGetOrderRequest{
+		Name:    ename,
+		OrderID: orderID,
+	}
+	resp, err := cmdutil.Post[api.ExchangeGetOrderResponse](ctx, &c.ClientFlags, api.ExchangeGetOrderPath, req)
+	if err != nil || resp.Order == nil {
+		return time.Time{}
+	}
+	return resp.Order.FinishTime.Time
+}
+
+// inferFinishTime returns an estimate for the finish time of a done order of
+// the limiter. Limiters keep at most one active order, so an order is done
+// before the next order is created.
+func inferFinishTime(state *gobs.LimiterStateV2, order *gobs.Order) time.Time {
+	created := order.CreateTime.Time
+	var next time.Time
+	for _, v := range state.ServerIDOrderMap {
+		if t := v.CreateTime.Time; t.After(created) && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	if !next.IsZero() {
+		return next
+	}
+	if !state.LastActionTime.IsZero() && !state.LastActionTime.Before(created) {
+		return state.LastActionTime
+	}
+	return created
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bvk/tradebot/gobs"
+)
+
+func TestInferFinishTime(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	order := func(id string, created time.Time) *gobs.Order {
+		return &gobs.Order{ServerOrderID: id, CreateTime: gobs.RemoteTime{Time: created}, Done: true}
+	}
+
+	first := order("first", t0)
+	second := order("second", t0.Add(2*time.Hour))
+	third := order("third", t0.Add(time.Hour))
+	state := &gobs.LimiterStateV2{
+		ServerIDOrderMap: map[string]*gobs.Order{
+			"first":  first,
+			"second": second,
+			"third":  third,
+		},
+		LastActionTime: t0.Add(3 * time.Hour),
+	}
+
+	// Order is done before the limiter's next order is created.
+	if v := inferFinishTime(state, first); !v.Equal(third.CreateTime.Time) {
+		t.Fatalf("want finish time %s from the next order, got %s", third.CreateTime.Time, v)
+	}
+	if v := inferFinishTime(state, third); !v.Equal(second.CreateTime.Time) {
+		t.Fatalf("want finish time %s from the next order, got %s", second.CreateTime.Time, v)
+	}
+
+	// Last order uses the last action time.
+	if v := inferFinishTime(state, second); !v.Equal(state.LastActionTime) {
+		t.Fatalf("want finish time %s from the last action time, got %s", state.LastActionTime, v)
+	}
+
+	// Create time is used when the last action time is not usable.
+	state.LastActionTime = t0
+	if v := inferFinishTime(state, second); !v.Equal(second.CreateTime.Time) {
+		t.Fatalf("want finish time %s from the create time, got %s", second.CreateTime.Time, v)
+	}
+}
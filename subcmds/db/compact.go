@@ -0,0 +1,79 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package db
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type Compact struct {
+	cmdutil.ClientFlags
+
+	retention time.Duration
+	noExport  bool
+	dryRun    bool
+}
+
+func (c *Compact) run(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("this command takes no arguments")
+	}
+
+	req := &api.DBCompactRequest{
+		Retention: c.retention,
+		NoExport:  c.noExport,
+		DryRun:    c.dryRun,
+	}
+	if err := req.Check(); err != nil {
+		return err
+	}
+	resp, err := cmdutil.Post[api.DBCompactResponse](ctx, &c.ClientFlags, api.DBCompactPath, req)
+	if err != nil {
+		return err
+	}
+	for _, uid := range resp.Removed {
+		if c.dryRun {
+			fmt.Printf("would remove %s\n", uid)
+		} else {
+			fmt.Printf("removed %s\n", uid)
+		}
+	}
+	for _, fpath := range resp.Exported {
+		fmt.Printf("exported %s\n", fpath)
+	}
+	return nil
+}
+
+func (c *Compact) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("compact", flag.ContinueOnError)
+	c.ClientFlags.SetFlags(fset)
+	fset.DurationVar(&c.retention, "retention", 90*24*time.Hour, "completed limiter jobs older than this are removed")
+	fset.BoolVar(&c.noExport, "no-export", false, "when true, jobs are removed without exporting them first")
+	fset.BoolVar(&c.dryRun, "dry-run", false, "when true, only prints the jobs that would be removed")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Compact) Synopsis() string {
+	return "Removes old completed limiter jobs from the database"
+}
+
+func (c *Compact) CommandHelp() string {
+	return `
+
+Command "compact" removes the completed limiter jobs whose last order is older
+than the retention window, along with their archived orders, audit trail,
+names and notes. Jobs are exported into the "exports" directory under the
+server's data directory before they are removed unless -no-export is given.
+
+Server can also run the compaction in the background with the
+-compact-retention flag.
+
+`
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package db
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"slices"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/server"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+// ExportPaths holds the database paths with the persistent state for all
+// trading jobs, their job runner states and names. Trader keyspaces are the
+// same as the keyspaces used to export a single job.
+var ExportPaths = append(slices.Clone(server.JobKeyspaces), job.Keyspace, namer.Keyspace)
+
+type Export struct {
+	cmdutil.DBFlags
+
+	outfile string
+}
+
+func (c *Export) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("export", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.StringVar(&c.outfile, "output", "", "Output file name for the exported data.")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Export) Synopsis() string {
+	return "Exports all trading jobs state into a file"
+}
+
+func (c *Export) run(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("this command takes no arguments")
+	}
+	if len(c.outfile) == 0 {
+		return fmt.Errorf("output file name must be specified")
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get database instance: %w", err)
+	}
+	defer closer()
+
+	return kvutil.ExportPathsDB(ctx, db, c.outfile, ExportPaths...)
+}
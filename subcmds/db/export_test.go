@@ -0,0 +1,146 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/point"
+	"github.com/bvkgo/kv"
+	"github.com/bvkgo/kv/kvmemdb"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// exportImport exports the src database with the ExportPaths and imports it
+// into a fresh database.
+func exportImport(ctx context.Context, t *testing.T, src kv.Database) kv.Database {
+	var buf bytes.Buffer
+	export := func(ctx context.Context, r kv.Reader) error {
+		return kvutil.ExportPaths(ctx, r, &buf, ExportPaths...)
+	}
+	if err := kv.WithReader(ctx, src, export); err != nil {
+		t.Fatal(err)
+	}
+	dst := kvmemdb.New()
+	if err := doRestore(ctx, &buf, dst, 10); err != nil {
+		t.Fatal(err)
+	}
+	return dst
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	d := decimal.RequireFromString
+
+	src := kvmemdb.New()
+	runner := job.NewRunner()
+	uid := uuid.NewString()
+	l, err := limiter.New(uid, "coinbase", "BTC-USD", &point.Point{Size: d("1"), Price: d("100"), Cancel: d("110")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trailKey := path.Join(limiter.TrailKeyspace, uid, "00000000000000000001")
+	offsetKey := path.Join(limiter.OffsetKeyspace, uid)
+	archiveKey := path.Join(limiter.ArchiveKeyspace, uid, "archived")
+	otherKey := path.Join("/coinbase", uid)
+	populate := func(ctx context.Context, rw kv.ReadWriter) error {
+		if err := l.Save(ctx, rw); err != nil {
+			return err
+		}
+		if err := runner.Add(ctx, rw, uid, "limiter"); err != nil {
+			return err
+		}
+		if err := namer.SetName(ctx, rw, "test-limiter", uid, "limiter"); err != nil {
+			return err
+		}
+		if err := kvutil.Set(ctx, rw, offsetKey, &gobs.LimiterOffset{ClientIDOffset: 42}); err != nil {
+			return err
+		}
+		archived := &gobs.Order{
+			ServerOrderID: "archived",
+			Side:          "BUY",
+			Status:        "FILLED",
+			FilledSize:    d("1"),
+			FilledPrice:   d("100"),
+			Done:          true,
+		}
+		if err := kvutil.Set(ctx, rw, archiveKey, archived); err != nil {
+			return err
+		}
+		key := path.Join(limiter.DefaultKeyspace, uid)
+		gv, err := kvutil.Get[gobs.LimiterState](ctx, rw, key)
+		if err != nil {
+			return err
+		}
+		gv.V2.ArchivedOrders = 1
+		gv.V2.ArchivedFilledSize = archived.FilledSize
+		gv.V2.ArchivedFilledValue = archived.FilledSize.Mul(archived.FilledPrice)
+		if err := kvutil.Set(ctx, rw, key, gv); err != nil {
+			return err
+		}
+		if err := kvutil.Set(ctx, rw, trailKey, &gobs.LimiterTrailEntry{Time: time.Now(), Action: "create"}); err != nil {
+			return err
+		}
+		return kvutil.Set(ctx, rw, otherKey, &gobs.LimiterTrailEntry{})
+	}
+	if err := kv.WithReadWriter(ctx, src, populate); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := exportImport(ctx, t, src)
+	check := func(ctx context.Context, r kv.Reader) error {
+		// Jobs must be known to the runner, so that they are resumed.
+		jd, err := job.NewRunner().Get(ctx, r, uid)
+		if err != nil {
+			t.Fatalf("want job data to be imported: %v", err)
+		}
+		if jd.Typename != "limiter" {
+			t.Fatalf("want limiter job type, got %q", jd.Typename)
+		}
+		v, err := limiter.Load(ctx, uid, r)
+		if err != nil {
+			t.Fatalf("want limiter state to be imported: %v", err)
+		}
+		// Missing archives are only logged when a limiter is loaded, so archived
+		// actions would be lost silently.
+		var found bool
+		for _, a := range v.Actions() {
+			for _, order := range a.Orders {
+				found = found || order.ServerOrderID == "archived"
+			}
+		}
+		if !found {
+			t.Fatalf("want archived order to be imported")
+		}
+		if _, id, _, err := namer.Resolve(ctx, r, "test-limiter"); err != nil || id != uid {
+			t.Fatalf("want job name to be imported, got id %q: %v", id, err)
+		}
+		// Client id offsets saved after the last full save must not go back,
+		// or else client order ids would be reused.
+		if v, err := kvutil.Get[gobs.LimiterOffset](ctx, r, offsetKey); err != nil || v.ClientIDOffset != 42 {
+			t.Fatalf("want client id offset to be imported, got %v: %v", v, err)
+		}
+		if entries, err := limiter.LoadTrail(ctx, r, uid); err != nil || len(entries) != 1 {
+			t.Fatalf("want audit trail to be imported, got %d entries: %v", len(entries), err)
+		}
+		if _, err := r.Get(ctx, otherKey); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("want non-job keys not to be exported, got %v", err)
+		}
+		return nil
+	}
+	if err := kv.WithReader(ctx, dst, check); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package db
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvkgo/kv"
+)
+
+type Import struct {
+	cmdutil.DBFlags
+
+	numOpsPerTx int
+}
+
+func (c *Import) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("import", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.IntVar(&c.numOpsPerTx, "num-ops-per-tx", 100, "max number of ops per import transaction")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Import) Synopsis() string {
+	return "Imports trading jobs state from an exported file into a fresh database"
+}
+
+func (c *Import) run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("command takes one (input export file) argument")
+	}
+
+	fp, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("could not open file %q: %w", args[0], err)
+	}
+	defer fp.Close()
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get database instance: %w", err)
+	}
+	defer closer()
+
+	empty := func(ctx context.Context, r kv.Reader) error {
+		it, err := r.Scan(ctx)
+		if err != nil {
+			return fmt.Errorf("could not create scanning iterator: %w", err)
+		}
+		defer kv.Close(it)
+
+		if k, _, err := it.Fetch(ctx, false); err == nil {
+			return fmt.Errorf("database is not empty (found key %q): %w", k, os.ErrExist)
+		} else if !errors.Is(err, io.EOF) {
+			return fmt.Errorf("iterator fetch has failed: %w", err)
+		}
+		return nil
+	}
+	if err := kv.WithReader(ctx, db, empty); err != nil {
+		return err
+	}
+
+	if err := doRestore(ctx, bufio.NewReader(fp), db, c.numOpsPerTx); err != nil {
+		return fmt.Errorf("could not import from the export file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package db
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/looper"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvkgo/kv"
+)
+
+type Orphans struct {
+	cmdutil.DBFlags
+}
+
+func (c *Orphans) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("orphans", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Orphans) Synopsis() string {
+	return "Prints the limiters that are not referenced by any looper"
+}
+
+func (c *Orphans) CommandHelp() string {
+	return `
+
+Command "orphans" scans all loopers (including the loopers of wallers) to
+collect the limiters they refer to and prints the limiters in the database
+that are not referenced by any of them, along with their job status.
+
+Standalone limiters created with the "limiter add" command are also listed
+because they are not part of any looper; their job status and name can be
+used to tell them apart from the stray limiters.
+
+`
+}
+
+func (c *Orphans) run(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("this command takes no arguments")
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	type Orphan struct {
+		uid, name, status string
+	}
+	var orphans []*Orphan
+	scan := func(ctx context.Context, r kv.Reader) error {
+		uids, err := orphanLimiters(ctx, r)
+		if err != nil {
+			return err
+		}
+		for _, uid := range uids {
+			v := &Orphan{uid: uid, status: "-"}
+			if name, _, _, err := namer.Resolve(ctx, r, uid); err == nil {
+				v.name = name
+			}
+			if state, err := job.Status(ctx, r, uid); err == nil {
+				v.status = string(state)
+			}
+			orphans = append(orphans, v)
+		}
+		return nil
+	}
+	if err := kv.WithReader(ctx, db, scan); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "UID\tStatus\tName\t\n")
+	for _, v := range orphans {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t\n", v.uid, v.status, v.name)
+	}
+	tw.Flush()
+	return nil
+}
+
+// orphanLimiters returns the sorted uids of the saved limiters that are not
+// referenced by any of the saved loopers.
+func orphanLimiters(ctx context.Context, r kv.Reader) ([]string, error) {
+	referenced := make(map[string]bool)
+	collect := func(ctx context.Context, r kv.Reader, key string, gv *gobs.LooperState) error {
+		gv.Upgrade()
+		for _, id := range gv.V2.LimiterIDs {
+			referenced[strings.TrimPrefix(id, limiter.DefaultKeyspace)] = true
+		}
+		return nil
+	}
+	begin, end := kvutil.PathRange(looper.DefaultKeyspace)
+	if err := kvutil.Ascend(ctx, r, begin, end, collect); err != nil {
+		return nil, fmt.Errorf("could not scan the loopers: %w", err)
+	}
+
+	begin, end = kvutil.PathRange(limiter.DefaultKeyspace)
+	it, err := r.Ascend(ctx, begin, end)
+	if err != nil {
+		return nil, fmt.Errorf("could not scan the limiters: %w", err)
+	}
+	defer kv.Close(it)
+
+	var orphans []string
+	for k, _, err := it.Fetch(ctx, false); err == nil; k, _, err = it.Fetch(ctx, true) {
+		if uid := strings.TrimPrefix(k, limiter.DefaultKeyspace); !referenced[uid] {
+			orphans = append(orphans, uid)
+		}
+	}
+	if _, _, err := it.Fetch(ctx, false); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("could not complete the limiters scan: %w", err)
+	}
+	sort.Strings(orphans)
+	return orphans, nil
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package exchange
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type CancelStale struct {
+	cmdutil.ClientFlags
+
+	name string
+
+	olderThan time.Duration
+
+	dryRun bool
+}
+
+func (c *CancelStale) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("cancel-stale", flag.ContinueOnError)
+	c.ClientFlags.SetFlags(fset)
+	fset.StringVar(&c.name, "name", "coinbase", "name of the exchange")
+	fset.DurationVar(&c.olderThan, "older-than", 0, "cancels only the open orders created more than this duration ago")
+	fset.BoolVar(&c.dryRun, "dry-run", false, "when true, only prints the orders that would be canceled")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *CancelStale) run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one (product-id) argument")
+	}
+	if c.olderThan <= 0 {
+		return fmt.Errorf("older-than flag must be a positive duration")
+	}
+
+	req := &api.ExchangeCancelStaleRequest{
+		ExchangeName: c.name,
+		ProductID:    args[0],
+		OlderThan:    c.olderThan,
+		DryRun:       c.dryRun,
+	}
+	resp, err := cmdutil.Post[api.ExchangeCancelStaleResponse](ctx, &c.ClientFlags, api.ExchangeCancelStalePath, req)
+	if err != nil {
+		return fmt.Errorf("POST request to cancel-stale failed: %w", err)
+	}
+
+	now := time.Now()
+	nfailed := 0
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "OrderID\tSide\tAge\tError\t\n")
+	for _, order := range resp.Orders {
+		if len(order.Error) != 0 {
+			nfailed++
+		}
+		age := now.Sub(order.CreateTime).Truncate(time.Second)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", order.OrderID, order.Side, age, order.Error)
+	}
+	tw.Flush()
+	fmt.Println()
+
+	if resp.NumLive > 0 {
+		fmt.Printf("%d stale orders are skipped because they belong to the running jobs\n", resp.NumLive)
+	}
+	if c.dryRun {
+		fmt.Printf("%d of %d open orders would be canceled\n", len(resp.Orders), resp.NumOpen)
+		return nil
+	}
+	fmt.Printf("%d of %d open orders are canceled\n", len(resp.Orders)-nfailed, resp.NumOpen)
+	if nfailed > 0 {
+		return fmt.Errorf("could not cancel %d stale orders", nfailed)
+	}
+	return nil
+}
+
+func (c *CancelStale) Synopsis() string {
+	return "Cancels the open orders of a product that are older than a given age"
+}
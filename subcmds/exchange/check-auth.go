@@ -0,0 +1,58 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package exchange
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type CheckAuth struct {
+	cmdutil.ClientFlags
+
+	name string
+}
+
+func (c *CheckAuth) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("check-auth", flag.ContinueOnError)
+	c.ClientFlags.SetFlags(fset)
+	fset.StringVar(&c.name, "name", "coinbase", "name of the exchange")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *CheckAuth) run(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("this command takes no arguments")
+	}
+
+	req := &api.ExchangeCheckAuthRequest{
+		ExchangeName: c.name,
+	}
+	resp, err := cmdutil.Post[api.ExchangeCheckAuthResponse](ctx, &c.ClientFlags, api.ExchangeCheckAuthPath, req)
+	if err != nil {
+		return fmt.Errorf("POST request to check-auth failed: %w", err)
+	}
+	if len(resp.Error) != 0 {
+		return fmt.Errorf("credentials check failed: %w", errors.New(resp.Error))
+	}
+
+	fmt.Printf("Account: %s (%s)\n", resp.AccountID, resp.AccountType)
+	fmt.Printf("Permissions: %s\n", strings.Join(resp.Permissions, ","))
+	fmt.Printf("Fee tier: %s (maker %s%%, taker %s%%)\n", resp.PricingTier, resp.MakerFeePct, resp.TakerFeePct)
+	if !slices.Contains(resp.Permissions, "trade") {
+		return fmt.Errorf("credentials do not have the trade permission")
+	}
+	return nil
+}
+
+func (c *CheckAuth) Synopsis() string {
+	return "Verifies the exchange credentials and prints their account and permissions"
+}
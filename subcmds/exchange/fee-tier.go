@@ -0,0 +1,52 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package exchange
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type FeeTier struct {
+	cmdutil.ClientFlags
+
+	name string
+}
+
+func (c *FeeTier) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("fee-tier", flag.ContinueOnError)
+	c.ClientFlags.SetFlags(fset)
+	fset.StringVar(&c.name, "name", "coinbase", "name of the exchange")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *FeeTier) run(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("this command takes no arguments")
+	}
+
+	req := &api.ExchangeFeeTierRequest{
+		ExchangeName: c.name,
+	}
+	resp, err := cmdutil.Post[api.ExchangeFeeTierResponse](ctx, &c.ClientFlags, api.ExchangeFeeTierPath, req)
+	if err != nil {
+		return fmt.Errorf("POST request to fee-tier failed: %w", err)
+	}
+	if len(resp.Error) != 0 {
+		return errors.New(resp.Error)
+	}
+
+	fmt.Printf("Maker fee: %s%%\n", resp.MakerFeePct)
+	fmt.Printf("Taker fee: %s%%\n", resp.TakerFeePct)
+	return nil
+}
+
+func (c *FeeTier) Synopsis() string {
+	return "Prints the maker and taker fee percentages of the current fee tier"
+}
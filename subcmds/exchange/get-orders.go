@@ -0,0 +1,77 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package exchange
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type GetOrders struct {
+	cmdutil.ClientFlags
+
+	name string
+
+	idsFile string
+
+	concurrency int
+}
+
+func (c *GetOrders) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("get-orders", flag.ContinueOnError)
+	c.ClientFlags.SetFlags(fset)
+	fset.StringVar(&c.name, "name", "coinbase", "name of the exchange")
+	fset.StringVar(&c.idsFile, "ids-file", "", "file with one order id per line (in addition to the arguments)")
+	fset.IntVar(&c.concurrency, "concurrency", 0, "number of orders to fetch in parallel (0 for the server default)")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *GetOrders) run(ctx context.Context, args []string) error {
+	ids := args
+	if len(c.idsFile) != 0 {
+		fp, err := os.Open(c.idsFile)
+		if err != nil {
+			return fmt.Errorf("could not open ids file: %w", err)
+		}
+		defer fp.Close()
+
+		scanner := bufio.NewScanner(fp)
+		for scanner.Scan() {
+			if id := strings.TrimSpace(scanner.Text()); len(id) != 0 {
+				ids = append(ids, id)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("could not read ids file: %w", err)
+		}
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("this command needs one or more order-id arguments or an ids file")
+	}
+
+	req := &api.ExchangeGetOrdersRequest{
+		Name:        c.name,
+		OrderIDs:    ids,
+		Concurrency: c.concurrency,
+	}
+	resp, err := cmdutil.Post[api.ExchangeGetOrdersResponse](ctx, &c.ClientFlags, api.ExchangeGetOrdersPath, req)
+	if err != nil {
+		return fmt.Errorf("POST request to get-orders failed: %w", err)
+	}
+	jsdata, _ := json.MarshalIndent(resp, "", "  ")
+	fmt.Printf("%s\n", jsdata)
+	return nil
+}
+
+func (c *GetOrders) Synopsis() string {
+	return "Prints the states of multiple orders from an exchange"
+}
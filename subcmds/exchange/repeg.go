@@ -0,0 +1,84 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package exchange
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type Repeg struct {
+	cmdutil.ClientFlags
+
+	name string
+
+	dryRun bool
+}
+
+func (c *Repeg) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("repeg", flag.ContinueOnError)
+	c.ClientFlags.SetFlags(fset)
+	fset.StringVar(&c.name, "name", "coinbase", "name of the exchange")
+	fset.BoolVar(&c.dryRun, "dry-run", false, "when true, only prints the orders that would be canceled")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Repeg) run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one (product-id) argument")
+	}
+
+	req := &api.ExchangeRepegRequest{
+		ExchangeName: c.name,
+		ProductID:    args[0],
+		DryRun:       c.dryRun,
+	}
+	resp, err := cmdutil.Post[api.ExchangeRepegResponse](ctx, &c.ClientFlags, api.ExchangeRepegPath, req)
+	if err != nil {
+		return fmt.Errorf("POST request to repeg failed: %w", err)
+	}
+
+	now := time.Now()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Job\tOrderID\tSide\tSize\tPrice\tAge\t\n")
+	for _, order := range resp.Orders {
+		age := now.Sub(order.CreateTime).Truncate(time.Second)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t\n", order.LimiterUID, order.OrderID, order.Side, order.Size, order.Price.StringFixed(2), age)
+	}
+	tw.Flush()
+	fmt.Println()
+
+	if c.dryRun {
+		fmt.Printf("%d orders from %d jobs would be canceled\n", len(resp.Orders), len(resp.UIDs))
+		return nil
+	}
+
+	for _, uid := range resp.UIDs {
+		fmt.Printf("%s repegged\n", uid)
+	}
+	var failed []string
+	for uid := range resp.Failed {
+		failed = append(failed, uid)
+	}
+	sort.Strings(failed)
+	for _, uid := range failed {
+		fmt.Printf("%s failed: %s\n", uid, resp.Failed[uid])
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not repeg %d jobs", len(failed))
+	}
+	return nil
+}
+
+func (c *Repeg) Synopsis() string {
+	return "Cancels and recreates the orders of all running jobs on a product"
+}
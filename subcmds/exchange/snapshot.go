@@ -0,0 +1,57 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package exchange
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type Snapshot struct {
+	cmdutil.ClientFlags
+
+	save bool
+}
+
+func (c *Snapshot) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	c.ClientFlags.SetFlags(fset)
+	fset.BoolVar(&c.save, "save", false, "when true, saves the snapshot in the database")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Snapshot) run(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("this command takes no arguments")
+	}
+
+	req := &api.ExchangeSnapshotRequest{
+		Save: c.save,
+	}
+	resp, err := cmdutil.Post[api.ExchangeSnapshotResponse](ctx, &c.ClientFlags, api.ExchangeSnapshotPath, req)
+	if err != nil {
+		return fmt.Errorf("POST request to snapshot failed: %w", err)
+	}
+
+	fmt.Printf("Timestamp: %s\n", resp.Timestamp.Format(time.RFC3339))
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Exchange\tProduct\tPrice\tError\t\n")
+	for _, v := range resp.Prices {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", v.ExchangeName, v.ProductID, v.Price, v.Error)
+	}
+	tw.Flush()
+	return nil
+}
+
+func (c *Snapshot) Synopsis() string {
+	return "Prints the current prices for all products with active jobs"
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/shopspring/decimal"
+)
+
+type Clone struct {
+	cmdutil.DBFlags
+
+	product     string
+	priceOffset float64
+	start       bool
+}
+
+func (c *Clone) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("clone", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.StringVar(&c.product, "product", "", "when non-empty, overrides the product id for the cloned job")
+	fset.Float64Var(&c.priceOffset, "price-offset", 0, "offset to add to all prices in the cloned job")
+	fset.BoolVar(&c.start, "start", false, "when true, starts the cloned job immediately")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Clone) run(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("this command takes two (src-job-id and new-name) arguments")
+	}
+	jobArg, newName := args[0], args[1]
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	_, uid, _, err := namer.ResolveDB(ctx, db, jobArg)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not resolve job argument %q: %w", jobArg, err)
+		}
+		uid = jobArg
+	}
+
+	req := &api.JobCloneRequest{
+		UID:         uid,
+		JobName:     newName,
+		ProductID:   c.product,
+		PriceOffset: decimal.NewFromFloat(c.priceOffset),
+		Start:       c.start,
+	}
+	resp, err := cmdutil.Post[api.JobCloneResponse](ctx, &c.ClientFlags, api.JobClonePath, req)
+	if err != nil {
+		return err
+	}
+	jsdata, _ := json.MarshalIndent(resp, "", "  ")
+	fmt.Printf("%s\n", jsdata)
+	return nil
+}
+
+func (c *Clone) Synopsis() string {
+	return "Creates a new job with the same trade points as an existing job"
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package job
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type Config struct {
+	cmdutil.DBFlags
+}
+
+func (c *Config) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("config", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Config) Synopsis() string {
+	return "Prints the effective options of a trading job"
+}
+
+func (c *Config) run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one (job-id) argument")
+	}
+	jobArg := args[0]
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	_, uid, _, err := namer.ResolveDB(ctx, db, jobArg)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not resolve job argument %q: %w", jobArg, err)
+		}
+		uid = jobArg
+	}
+
+	req := &api.JobConfigRequest{UID: uid}
+	resp, err := cmdutil.Post[api.JobConfigResponse](ctx, &c.ClientFlags, api.JobConfigPath, req)
+	if err != nil {
+		return err
+	}
+
+	if resp.Running {
+		fmt.Printf("# options of the running job %s\n", resp.UID)
+	} else {
+		fmt.Printf("# options of the saved job %s (not running)\n", resp.UID)
+	}
+	var keys []string
+	for k := range resp.Options {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%s\t%s\t\n", k, resp.Options[k])
+	}
+	tw.Flush()
+	return nil
+}
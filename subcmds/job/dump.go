@@ -0,0 +1,74 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/server"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type Dump struct {
+	cmdutil.DBFlags
+}
+
+func (c *Dump) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("dump", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Dump) Synopsis() string {
+	return "Prints a trader job's saved state as JSON"
+}
+
+func (c *Dump) CommandHelp() string {
+	return `
+
+Command "dump" prints the saved state of a job, including the states of it's
+child limiters with their order maps and client id offsets, in a portable
+JSON format to the standard output. Output can be saved to the same or a
+different database with the "restore" command.
+
+`
+}
+
+func (c *Dump) run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one (job-id) argument")
+	}
+	jobArg := args[0]
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get db access: %w", err)
+	}
+	defer closer()
+
+	_, uid, _, err := namer.ResolveDB(ctx, db, jobArg)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not resolve job argument %q: %w", jobArg, err)
+		}
+		uid = jobArg
+	}
+
+	dump, err := server.DumpJob(ctx, db, uid)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal job dump: %w", err)
+	}
+	fmt.Printf("%s\n", data)
+	return nil
+}
@@ -3,24 +3,16 @@
 package job
 
 import (
-	"bufio"
 	"context"
-	"encoding/gob"
 	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"os"
 
 	"github.com/bvk/tradebot/cli"
-	"github.com/bvk/tradebot/gobs"
-	"github.com/bvk/tradebot/job"
 	"github.com/bvk/tradebot/namer"
 	"github.com/bvk/tradebot/server"
 	"github.com/bvk/tradebot/subcmds/cmdutil"
-	"github.com/bvk/tradebot/trader"
-	"github.com/bvkgo/kv"
-	"github.com/bvkgo/kv/kvmemdb"
 )
 
 type Export struct {
@@ -60,83 +52,13 @@ func (c *Export) run(ctx context.Context, args []string) error {
 		uid = jobArg
 	}
 
-	export := &gobs.JobExportData{
-		UID: uid,
-	}
-
-	var trader trader.Trader
-	loader := func(ctx context.Context, r kv.Reader) error {
-		runner := job.NewRunner()
-		if err := runner.Export(ctx, r, export); err != nil {
-			return fmt.Errorf("could not export job data: %w", err)
-		}
-
-		name, _, typename, err := namer.Resolve(ctx, r, export.UID)
-		if err != nil && !errors.Is(err, os.ErrNotExist) {
-			return fmt.Errorf("could not resolve job id to name: %w", err)
-		}
-		export.Name = name
-
-		trader, err = server.Load(ctx, r, export.UID, typename)
-		if err != nil {
-			return fmt.Errorf("could not load trader: %w", err)
-		}
-		return nil
-	}
-	if err := kv.WithReader(ctx, db, loader); err != nil {
-		return fmt.Errorf("could not load trader job: %w", err)
-	}
-
-	memdb := kvmemdb.New()
-	if err := kv.WithReadWriter(ctx, memdb, trader.Save); err != nil {
-		return fmt.Errorf("could not save job to a temporary memdb: %w", err)
-	}
-
-	iterate := func(ctx context.Context, r kv.Reader) error {
-		it, err := r.Scan(ctx)
-		if err != nil {
-			return err
-		}
-		defer kv.Close(it)
-
-		for k, v, err := it.Fetch(ctx, false); err == nil; k, v, err = it.Fetch(ctx, true) {
-			value, err := io.ReadAll(v)
-			if err != nil {
-				return fmt.Errorf("could not read value at key %q: %w", k, err)
-			}
-			item := &gobs.KeyValue{
-				Key:   k,
-				Value: value,
-			}
-			export.KeyValues = append(export.KeyValues, item)
-		}
-		if _, _, err := it.Fetch(ctx, false); err != nil && !errors.Is(err, io.EOF) {
-			return fmt.Errorf("iterator fetch has failed: %w", err)
-		}
-		return nil
-	}
-	if err := kv.WithReader(ctx, memdb, iterate); err != nil {
-		return fmt.Errorf("could not iterate over temporary memdb: %w", err)
-	}
-
-	// Save the export data into a file.
-	fp, err := os.OpenFile(c.outfile, os.O_CREATE|os.O_WRONLY, os.FileMode(0600))
+	export, err := server.ExportJob(ctx, db, uid)
 	if err != nil {
-		return fmt.Errorf("could not open %q: %w", c.outfile, err)
-	}
-	defer fp.Close()
-
-	bw := bufio.NewWriter(fp)
-	if err := gob.NewEncoder(bw).Encode(export); err != nil {
-		return fmt.Errorf("could not gob-encode export data: %w", err)
+		return err
 	}
-	if err := bw.Flush(); err != nil {
-		return fmt.Errorf("could not flush the bufio writer: %w", err)
+	if err := server.WriteExportFile(export, c.outfile); err != nil {
+		return err
 	}
-	if err := fp.Sync(); err != nil {
-		return fmt.Errorf("could not sync the file: %w", err)
-	}
-
 	return nil
 }
 
@@ -16,6 +16,7 @@ import (
 	"github.com/bvk/tradebot/gobs"
 	"github.com/bvk/tradebot/job"
 	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/server"
 	"github.com/bvk/tradebot/subcmds/cmdutil"
 	"github.com/bvkgo/kv"
 )
@@ -56,21 +57,37 @@ func (c *Import) run(ctx context.Context, args []string) error {
 	}
 	defer closer()
 
+	return importJob(ctx, db, export, c.dryRun, false /* force */)
+}
+
+// importJob saves the job export data to the database. When force is true,
+// job and it's key-values replace the existing ones with the same uid, in
+// which case existing job must not be running and all of it's old key-values
+// are removed before the import.
+func importJob(ctx context.Context, db kv.Database, export *gobs.JobExportData, dryRun, force bool) error {
 	runner := job.NewRunner()
 
 	// Verify that name, job id and all other keys doesn't exist in the target db.
 	verifier := func(ctx context.Context, r kv.Reader) error {
 		if len(export.Name) > 0 {
-			if _, _, _, err := namer.Resolve(ctx, r, export.Name); err == nil {
+			if _, uid, _, err := namer.Resolve(ctx, r, export.Name); err == nil && (!force || uid != export.UID) {
 				return fmt.Errorf("target already has job named %q: %w", export.Name, os.ErrExist)
 			}
 		}
 
-		if _, err := runner.Get(ctx, r, export.UID); err == nil {
-			return fmt.Errorf("job with uid %q already exists in the target: %w", export.UID, os.ErrExist)
+		if jd, err := runner.Get(ctx, r, export.UID); err == nil {
+			if !force {
+				return fmt.Errorf("job with uid %q already exists in the target: %w", export.UID, os.ErrExist)
+			}
+			if jd.State == job.RUNNING {
+				return fmt.Errorf("job with uid %q is running in the target (pause it first): %w", export.UID, os.ErrInvalid)
+			}
 		} else if !errors.Is(err, os.ErrNotExist) {
 			return fmt.Errorf("could not check for job %q: %w", export.UID, err)
 		}
+		if force {
+			return nil
+		}
 
 		for _, pair := range export.KeyValues {
 			if _, err := r.Get(ctx, pair.Key); err == nil {
@@ -85,13 +102,21 @@ func (c *Import) run(ctx context.Context, args []string) error {
 		return fmt.Errorf("cannot import the job to the target db: %w", err)
 	}
 
-	if c.dryRun {
+	if dryRun {
 		data, _ := json.MarshalIndent(export, "", "  ")
 		fmt.Printf("%s\n", data)
 		return nil
 	}
 
 	importer := func(ctx context.Context, rw kv.ReadWriter) error {
+		if force {
+			if err := server.DeleteJobKeys(ctx, rw, export.UID); err != nil {
+				return fmt.Errorf("could not remove existing job state: %w", err)
+			}
+			if err := namer.DeleteID(ctx, rw, export.UID); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("could not remove existing job name: %w", err)
+			}
+		}
 		if err := runner.Import(ctx, rw, export); err != nil {
 			return fmt.Errorf("could not import job state: %w", err)
 		}
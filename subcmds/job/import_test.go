@@ -0,0 +1,87 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package job
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/point"
+	"github.com/bvk/tradebot/server"
+	"github.com/bvkgo/kv"
+	"github.com/bvkgo/kv/kvmemdb"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestImportJobForce(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+
+	uid := uuid.NewString()
+	p := &point.Point{
+		Size:   decimal.RequireFromString("1"),
+		Price:  decimal.RequireFromString("100"),
+		Cancel: decimal.RequireFromString("110"),
+	}
+	l, err := limiter.New(uid, "coinbase", "BTC-USD", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setup := func(ctx context.Context, rw kv.ReadWriter) error {
+		if err := l.Save(ctx, rw); err != nil {
+			return err
+		}
+		return job.NewRunner().Add(ctx, rw, uid, "Limiter")
+	}
+	if err := kv.WithReadWriter(ctx, db, setup); err != nil {
+		t.Fatal(err)
+	}
+	export, err := server.ExportJob(ctx, db, uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Archived order written after the export is stale for the import.
+	staleKey := path.Join(limiter.ArchiveKeyspace, uid, "order-1")
+	if err := kvutil.SetDB(ctx, db, staleKey, &gobs.Order{ServerOrderID: "order-1", Done: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := importJob(ctx, db, export, false /* dryRun */, false /* force */); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("want import to fail for an existing job without force, got %v", err)
+	}
+
+	running := &gobs.JobExportData{UID: uid, Typename: "Limiter", JobState: string(job.RUNNING)}
+	if err := kv.WithReadWriter(ctx, db, func(ctx context.Context, rw kv.ReadWriter) error {
+		return job.NewRunner().Import(ctx, rw, running)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := importJob(ctx, db, export, false /* dryRun */, true /* force */); err == nil {
+		t.Fatalf("want forced import to fail for a running job")
+	}
+
+	paused := &gobs.JobExportData{UID: uid, Typename: "Limiter", JobState: string(job.PAUSED)}
+	if err := kv.WithReadWriter(ctx, db, func(ctx context.Context, rw kv.ReadWriter) error {
+		return job.NewRunner().Import(ctx, rw, paused)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := importJob(ctx, db, export, false /* dryRun */, true /* force */); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kvutil.GetDB[gobs.Order](ctx, db, staleKey); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("want stale key removed by the forced import, got %v", err)
+	}
+	if _, err := kvutil.GetDB[gobs.LimiterState](ctx, db, path.Join(limiter.DefaultKeyspace, uid)); err != nil {
+		t.Fatalf("want limiter state restored by the forced import, got %v", err)
+	}
+}
@@ -36,9 +36,13 @@ func (c *List) run(ctx context.Context, args []string) error {
 	}
 
 	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
-	fmt.Fprintf(tw, "Name\tUID\tType\tStatus\t\n")
+	fmt.Fprintf(tw, "Name\tUID\tType\tStatus\tHeld\tNote\t\n")
 	for _, job := range resp.Jobs {
-		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", job.Name, job.UID, job.Type, job.State)
+		held := ""
+		if job.Held {
+			held = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t\n", job.Name, job.UID, job.Type, job.State, held, job.Note)
 	}
 	tw.Flush()
 	return nil
@@ -0,0 +1,70 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package job
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type Note struct {
+	cmdutil.DBFlags
+
+	clear bool
+}
+
+func (c *Note) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("note", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.BoolVar(&c.clear, "clear", false, "when true, removes the existing note")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Note) run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("this command takes job-id and note text arguments")
+	}
+	jobArg, text := args[0], strings.Join(args[1:], " ")
+	if c.clear && len(text) != 0 {
+		return fmt.Errorf("note text cannot be given with the -clear flag")
+	}
+	if !c.clear && len(strings.TrimSpace(text)) == 0 {
+		return fmt.Errorf("note text cannot be empty (use -clear to remove a note)")
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	_, uid, _, err := namer.ResolveDB(ctx, db, jobArg)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not resolve job argument %q: %w", jobArg, err)
+		}
+		uid = jobArg
+	}
+
+	req := &api.JobNoteRequest{
+		UID:  uid,
+		Note: text,
+	}
+	if _, err := cmdutil.Post[api.JobNoteResponse](ctx, &c.ClientFlags, api.JobNotePath, req); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Note) Synopsis() string {
+	return "Attaches a free-form note to a trading job"
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/server"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type Restore struct {
+	cmdutil.DBFlags
+
+	force bool
+
+	dryRun bool
+}
+
+func (c *Restore) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("restore", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.BoolVar(&c.force, "force", false, "when true, overwrites the job with the same uid, if any")
+	fset.BoolVar(&c.dryRun, "dry-run", false, "when true only prints the restored data")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Restore) Synopsis() string {
+	return "Restores a trader job's state from a JSON dump file"
+}
+
+func (c *Restore) CommandHelp() string {
+	return `
+
+Command "restore" saves the job state from a file created by the "dump"
+command to the database. It refuses to overwrite an existing job with the
+same uid unless the -force flag is given, in which case the job must not be
+running cause it's in-memory state would overwrite the restored state. All
+key-values of the existing job are removed before the restore, so that no
+stale state is left behind.
+
+`
+}
+
+func (c *Restore) run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one file argument")
+	}
+	filename := args[0]
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("could not read file %q: %w", filename, err)
+	}
+	dump := new(server.JobDump)
+	if err := json.Unmarshal(data, dump); err != nil {
+		return fmt.Errorf("could not json-decode dump file: %w", err)
+	}
+	if len(dump.UID) == 0 || len(dump.Typename) == 0 {
+		return fmt.Errorf("dump file must have job uid and typename")
+	}
+	export, err := dump.Export()
+	if err != nil {
+		return err
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get db access: %w", err)
+	}
+	defer closer()
+
+	return importJob(ctx, db, export, c.dryRun, c.force)
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package job
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type StartPaused struct {
+	cmdutil.DBFlags
+
+	clear bool
+}
+
+func (c *StartPaused) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("start-paused", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.BoolVar(&c.clear, "clear", false, "when true, clears the start-paused flag")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *StartPaused) Synopsis() string {
+	return "Keeps a job paused across the server restarts"
+}
+
+func (c *StartPaused) CommandHelp() string {
+	return `
+
+Command "start-paused" sets a flag on the job, so that the job is left paused
+when the server is restarted and must be resumed explicitly. Unlike the manual
+pause, the flag is kept after the job is resumed, till it is cleared with the
+-clear flag. Server's -start-paused flag applies the same to all jobs for a
+single restart.
+
+`
+}
+
+func (c *StartPaused) run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one (job-id) argument")
+	}
+	jobArg := args[0]
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	_, uid, _, err := namer.ResolveDB(ctx, db, jobArg)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not resolve job argument %q: %w", jobArg, err)
+		}
+		uid = jobArg
+	}
+
+	req := &api.JobStartPausedRequest{
+		UID:         uid,
+		StartPaused: !c.clear,
+	}
+	if _, err := cmdutil.Post[api.JobStartPausedResponse](ctx, &c.ClientFlags, api.JobStartPausedPath, req); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package job
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type Verify struct {
+	cmdutil.DBFlags
+}
+
+func (c *Verify) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("verify", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Verify) Synopsis() string {
+	return "Verifies that the saved state of running jobs matches their in-memory state"
+}
+
+func (c *Verify) CommandHelp() string {
+	return `
+
+Command "verify" compares the in-memory state of the running jobs (or the
+selected jobs given as arguments) in the server with their state saved in
+the database and prints the differences, which indicate a missed save. Jobs
+that are updating their state at the same time may report differences that
+go away when the command is repeated.
+
+Command fails when differences are found for any of the jobs.
+
+`
+}
+
+func (c *Verify) run(ctx context.Context, args []string) error {
+	req := new(api.JobVerifyRequest)
+	if len(args) > 0 {
+		db, closer, err := c.DBFlags.GetDatabase(ctx)
+		if err != nil {
+			return fmt.Errorf("could not create database client: %w", err)
+		}
+		defer closer()
+
+		for _, arg := range args {
+			_, uid, _, err := namer.ResolveDB(ctx, db, arg)
+			if err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf("could not resolve job argument %q: %w", arg, err)
+				}
+				uid = arg
+			}
+			req.UIDs = append(req.UIDs, uid)
+		}
+	}
+
+	resp, err := cmdutil.Post[api.JobVerifyResponse](ctx, &c.ClientFlags, api.JobVerifyPath, req)
+	if err != nil {
+		return err
+	}
+
+	ndiverged := 0
+	for _, job := range resp.Jobs {
+		if len(job.Divergences) == 0 {
+			continue
+		}
+		ndiverged++
+		fmt.Printf("%s:\n", job.UID)
+		for _, d := range job.Divergences {
+			fmt.Printf("  %s\n", d)
+		}
+	}
+	if ndiverged > 0 {
+		return fmt.Errorf("%d of %d running jobs have diverged from their saved state", ndiverged, len(resp.Jobs))
+	}
+	fmt.Printf("saved state of %d running jobs matches their in-memory state\n", len(resp.Jobs))
+	return nil
+}
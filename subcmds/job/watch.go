@@ -0,0 +1,178 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package job
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type Watch struct {
+	cmdutil.DBFlags
+
+	interval time.Duration
+}
+
+func (c *Watch) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("watch", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.DurationVar(&c.interval, "interval", time.Second, "interval to redraw the status line")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Watch) Synopsis() string {
+	return "Continuously prints the status of a running job"
+}
+
+func (c *Watch) CommandHelp() string {
+	return `
+
+Command "watch" follows the status stream of a running job from the server
+and redraws a compact status line with the pending size, active orders, last
+price and the unrealized profit. Command exits when the job stops or on
+Ctrl-C.
+
+`
+}
+
+func (c *Watch) run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one (job-id) argument")
+	}
+	if c.interval <= 0 {
+		return fmt.Errorf("interval flag must be positive")
+	}
+	jobArg := args[0]
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	_, uid, _, err := namer.ResolveDB(ctx, db, jobArg)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not resolve job argument %q: %w", jobArg, err)
+		}
+		uid = jobArg
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	addrURL := c.ClientFlags.AddressURL()
+	addrURL.Path = path.Join(addrURL.Path, api.JobWatchPath)
+	addrURL.RawQuery = url.Values{"uid": []string{uid}}.Encode()
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, addrURL.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	// Stream stays open as long as the job runs, so http-timeout flag is not
+	// applicable.
+	client := c.ClientFlags.HttpClient()
+	client.Timeout = 0
+	resp, err := client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("http status code %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	statusCh := make(chan *api.JobWatchStatus)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- readWatchEvents(ctx, resp.Body, statusCh)
+	}()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	var last *api.JobWatchStatus
+	redraw := func() {
+		if last != nil {
+			fmt.Printf("\r\033[K%s", formatWatchStatus(last, time.Now()))
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println()
+			return nil
+		case err := <-errCh:
+			redraw()
+			fmt.Println()
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case status := <-statusCh:
+			last = status
+			redraw()
+		case <-ticker.C:
+			redraw()
+		}
+	}
+}
+
+// readWatchEvents parses the Server-Sent Events stream and sends the job
+// status events to the channel. Returns io.EOF when the stream ends.
+func readWatchEvents(ctx context.Context, r io.Reader, statusCh chan<- *api.JobWatchStatus) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		status := new(api.JobWatchStatus)
+		if err := json.Unmarshal([]byte(data), status); err != nil {
+			return fmt.Errorf("could not parse job status event: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case statusCh <- status:
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// formatWatchStatus returns a single line summary of the job status.
+func formatWatchStatus(s *api.JobWatchStatus, now time.Time) string {
+	active := "-"
+	if len(s.ActiveOrders) > 0 {
+		active = strings.Join(s.ActiveOrders, ",")
+	}
+	age := "-"
+	if !s.LastTickerTime.IsZero() {
+		age = now.Sub(s.LastTickerTime).Round(time.Second).String()
+	}
+	return fmt.Sprintf("%s %s %s pending=%s active=%s price=%s (%s ago) upnl=%s",
+		now.Format(time.TimeOnly), s.ProductID, s.State, s.PendingSize, active, s.LastPrice, age, s.UnrealizedProfit.StringFixed(2))
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package job
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/shopspring/decimal"
+)
+
+func TestReadWatchEvents(t *testing.T) {
+	ctx := context.Background()
+
+	stream := strings.Join([]string{
+		": heartbeat",
+		"",
+		"event: status",
+		`data: {"UID":"a","PendingSize":"1"}`,
+		"",
+		"event: status",
+		`data: {"UID":"a","PendingSize":"0.5"}`,
+		"",
+	}, "\n")
+	statusCh := make(chan *api.JobWatchStatus, 2)
+	if err := readWatchEvents(ctx, strings.NewReader(stream), statusCh); !errors.Is(err, io.EOF) {
+		t.Fatalf("want io.EOF at the end of stream, got %v", err)
+	}
+	if len(statusCh) != 2 {
+		t.Fatalf("want 2 status events, got %d", len(statusCh))
+	}
+	<-statusCh
+	if s := <-statusCh; !s.PendingSize.Equal(decimal.RequireFromString("0.5")) {
+		t.Fatalf("want pending size 0.5 from the last event, got %s", s.PendingSize)
+	}
+
+	if err := readWatchEvents(ctx, strings.NewReader("data: {"), statusCh); err == nil || errors.Is(err, io.EOF) {
+		t.Fatalf("want parse error for a malformed event, got %v", err)
+	}
+}
+
+func TestFormatWatchStatus(t *testing.T) {
+	now := time.Now()
+	s := &api.JobWatchStatus{
+		ProductID:        "BTC-USD",
+		State:            "RUNNING",
+		PendingSize:      decimal.RequireFromString("1"),
+		ActiveOrders:     []string{"x", "y"},
+		LastPrice:        decimal.RequireFromString("105"),
+		LastTickerTime:   now.Add(-2 * time.Second),
+		UnrealizedProfit: decimal.RequireFromString("5"),
+	}
+	want := "BTC-USD RUNNING pending=1 active=x,y price=105 (2s ago) upnl=5.00"
+	if v := formatWatchStatus(s, now); !strings.HasSuffix(v, want) {
+		t.Fatalf("want status line ending with %q, got %q", want, v)
+	}
+
+	if v := formatWatchStatus(&api.JobWatchStatus{}, now); !strings.Contains(v, "active=- ") || !strings.Contains(v, "(- ago)") {
+		t.Fatalf("want placeholders for missing orders and ticker, got %q", v)
+	}
+}
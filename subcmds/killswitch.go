@@ -0,0 +1,70 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package subcmds
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type KillSwitch struct {
+	cmdutil.ClientFlags
+
+	reason string
+	clear  bool
+	resume bool
+}
+
+func (c *KillSwitch) run(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("this command takes no arguments")
+	}
+	if c.resume && !c.clear {
+		return fmt.Errorf("resume flag can only be used with the clear flag")
+	}
+
+	req := &api.KillSwitchRequest{
+		Reason: c.reason,
+		Clear:  c.clear,
+		Resume: c.resume,
+	}
+	resp, err := cmdutil.Post[api.KillSwitchResponse](ctx, &c.ClientFlags, api.KillSwitchPath, req)
+	if err != nil {
+		return err
+	}
+	jsdata, _ := json.MarshalIndent(resp, "", "  ")
+	fmt.Printf("%s\n", jsdata)
+	return nil
+}
+
+func (c *KillSwitch) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("kill-switch", flag.ContinueOnError)
+	c.ClientFlags.SetFlags(fset)
+	fset.StringVar(&c.reason, "reason", "", "reason for engaging the kill-switch")
+	fset.BoolVar(&c.clear, "clear", false, "when true, clears the kill-switch instead of engaging it")
+	fset.BoolVar(&c.resume, "resume", false, "when true, resumes the halted jobs after clearing the kill-switch")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *KillSwitch) Synopsis() string {
+	return "Halts all jobs and cancels all open orders until cleared"
+}
+
+func (c *KillSwitch) CommandHelp() string {
+	return `
+
+Command "kill-switch" halts all running jobs, cancels all open orders of the
+enabled products on the exchanges (including the orders not placed by any
+job) and keeps any job from starting or resuming, even across the server
+restarts, until the kill-switch is cleared with the -clear flag. Halted jobs are not
+resumed automatically when the kill-switch is cleared unless -resume flag is
+also given.
+
+`
+}
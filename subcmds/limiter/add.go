@@ -25,6 +25,11 @@ type Add struct {
 	size         float64
 	price        float64
 	cancelOffset float64
+	cancelPct    float64
+
+	sizeInQuote bool
+
+	tag string
 }
 
 func (c *Add) check() error {
@@ -41,8 +46,11 @@ func (c *Add) check() error {
 	if c.price <= 0 {
 		return fmt.Errorf("price cannot be zero or negative")
 	}
-	if c.cancelOffset <= 0 {
-		return fmt.Errorf("cancel-offset cannot be zero or negative")
+	if c.cancelOffset < 0 || c.cancelPct < 0 {
+		return fmt.Errorf("cancel-offset or cancel-pct cannot be negative")
+	}
+	if c.cancelOffset == 0 && c.cancelPct == 0 {
+		return fmt.Errorf("one of cancel-offset or cancel-pct must be given")
 	}
 	if c.side != "BUY" && c.side != "SELL" {
 		return fmt.Errorf("side must be one of BUY or SELL")
@@ -54,7 +62,7 @@ func (c *Add) check() error {
 	} else {
 		cancelPrice = c.price - c.cancelOffset
 	}
-	if cancelPrice <= 0 {
+	if c.cancelOffset != 0 && cancelPrice <= 0 {
 		return fmt.Errorf("cancel-price cannot be zero or negative")
 	}
 	return nil
@@ -68,21 +76,34 @@ func (c *Add) Run(ctx context.Context, args []string) error {
 		return err
 	}
 
+	// Absolute cancel-offset takes precedence over the cancel-pct.
 	var cancelPrice float64
-	if c.side == "BUY" {
-		cancelPrice = c.price + c.cancelOffset
-	} else {
-		cancelPrice = c.price - c.cancelOffset
+	if c.cancelOffset != 0 {
+		if c.side == "BUY" {
+			cancelPrice = c.price + c.cancelOffset
+		} else {
+			cancelPrice = c.price - c.cancelOffset
+		}
+	}
+
+	p := &point.Point{
+		Size:      decimal.NewFromFloat(c.size),
+		Price:     decimal.NewFromFloat(c.price),
+		Cancel:    decimal.NewFromFloat(cancelPrice),
+		CancelPct: decimal.NewFromFloat(c.cancelPct),
+
+		SizeInQuote: c.sizeInQuote,
+
+		Tag: c.tag,
+	}
+	if err := p.ResolveCancel(c.side); err != nil {
+		return err
 	}
 
 	req := &api.LimitRequest{
 		ProductID:    c.product,
 		ExchangeName: c.exchange,
-		Point: &point.Point{
-			Size:   decimal.NewFromFloat(c.size),
-			Price:  decimal.NewFromFloat(c.price),
-			Cancel: decimal.NewFromFloat(cancelPrice),
-		},
+		Point:        p,
 	}
 	resp, err := cmdutil.Post[api.LimitResponse](ctx, &c.ClientFlags, api.LimitPath, req)
 	if err != nil {
@@ -97,10 +118,13 @@ func (c *Add) Command() (*flag.FlagSet, cli.CmdFunc) {
 	fset := flag.NewFlagSet("add", flag.ContinueOnError)
 	c.ClientFlags.SetFlags(fset)
 	fset.Float64Var(&c.size, "size", 0, "asset size for the trade")
+	fset.BoolVar(&c.sizeInQuote, "size-in-quote", false, "when true, size is the amount of quote funds (ex: dollars) for the trade")
 	fset.Float64Var(&c.price, "price", 0, "limit price for the trade")
 	fset.StringVar(&c.side, "side", "", "must be one of BUY or SELL")
 	fset.Float64Var(&c.cancelOffset, "cancel-offset", 0, "cancel-price offset for the trade")
+	fset.Float64Var(&c.cancelPct, "cancel-pct", 0, "cancel-price offset as a percentage of the price (cancel-offset takes precedence)")
 	fset.StringVar(&c.product, "product", "", "product id for the trade")
+	fset.StringVar(&c.tag, "tag", "", "optional strategy tag for the trade")
 	fset.StringVar(&c.exchange, "exchange", "coinbase", "exchange name for the product")
 	return fset, cli.CmdFunc(c.Run)
 }
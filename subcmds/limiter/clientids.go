@@ -0,0 +1,89 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvkgo/kv"
+)
+
+type ClientIDs struct {
+	cmdutil.DBFlags
+
+	unknownOnly bool
+}
+
+func (c *ClientIDs) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("clientids", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.BoolVar(&c.unknownOnly, "unknown-only", false, "when true, prints only the client ids without a known order")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *ClientIDs) Synopsis() string {
+	return "Prints all client order ids issued by a limiter"
+}
+
+func (c *ClientIDs) CommandHelp() string {
+	return `
+
+Command "clientids" takes a limiter argument and prints every client order id
+generated from the limiter's seed, from offset zero up to the current idgen
+offset, along with the server order id when the limiter has a record of the
+order. Client ids without a known order can be searched in the exchange's
+records to find the orders missing from the limiter.
+
+`
+}
+
+func (c *ClientIDs) run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one limiter argument")
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	var v *limiter.Limiter
+	load := func(ctx context.Context, r kv.Reader) error {
+		_, uid, _, err := namer.Resolve(ctx, r, args[0])
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("could not resolve limiter argument %q: %w", args[0], err)
+			}
+			uid = args[0]
+		}
+		v, err = limiter.Load(ctx, uid, r)
+		if err != nil {
+			return fmt.Errorf("could not load limiter %q: %w", args[0], err)
+		}
+		return nil
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Offset\tClientOrderID\tServerOrderID\t\n")
+	for _, id := range v.ClientIDs() {
+		if c.unknownOnly && id.ServerOrderID != "" {
+			continue
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t\n", id.Offset, id.ClientOrderID, id.ServerOrderID)
+	}
+	tw.Flush()
+	return nil
+}
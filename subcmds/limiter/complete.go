@@ -0,0 +1,92 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvkgo/kv"
+)
+
+type Complete struct {
+	cmdutil.DBFlags
+}
+
+func (c *Complete) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("complete", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Complete) Synopsis() string {
+	return "Marks a stuck limiter as complete leaving it's pending size unfilled"
+}
+
+func (c *Complete) CommandHelp() string {
+	return `
+
+Command "complete" takes a limiter argument and marks it as complete, so that
+a limiter pending on a dust remainder that cannot be filled (eg: below the min
+size) doesn't block it's parent looper forever. Pending size is recorded as
+the unfilled residual size, which is reported in the job summaries.
+
+Limiter must not have any live orders and it's job must not be running.
+
+`
+}
+
+func (c *Complete) run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one limiter argument")
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	complete := func(ctx context.Context, rw kv.ReadWriter) error {
+		_, uid, _, err := namer.Resolve(ctx, rw, args[0])
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("could not resolve limiter argument %q: %w", args[0], err)
+			}
+			uid = args[0]
+		}
+
+		// Running job would overwrite the limiter state, so top-level job (the
+		// first component of a child limiter uid) must not be running.
+		jobID, _, _ := strings.Cut(uid, "/")
+		if state, err := job.Status(ctx, rw, jobID); err == nil && state == job.RUNNING {
+			return fmt.Errorf("job %q is running; it must be paused first", jobID)
+		}
+
+		v, err := limiter.Load(ctx, uid, rw)
+		if err != nil {
+			return fmt.Errorf("could not load limiter %q: %w", args[0], err)
+		}
+		if err := v.Complete(); err != nil {
+			return err
+		}
+		if err := v.Save(ctx, rw); err != nil {
+			return fmt.Errorf("could not save limiter %q: %w", uid, err)
+		}
+		fmt.Printf("limiter %s is marked complete with residual size %s\n", uid, v.ResidualSize())
+		return nil
+	}
+	if err := kv.WithReadWriter(ctx, db, complete); err != nil {
+		return err
+	}
+	return nil
+}
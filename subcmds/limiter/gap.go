@@ -0,0 +1,256 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/coinbase"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/server"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvk/tradebot/trader"
+	"github.com/bvkgo/kv"
+	"github.com/shopspring/decimal"
+)
+
+type Gap struct {
+	cmdutil.DBFlags
+
+	price string
+
+	secretsPath string
+}
+
+func (c *Gap) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("gap", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.StringVar(&c.price, "price", "", "market price to use instead of the current product price")
+	fset.StringVar(&c.secretsPath, "secrets-file", "", "path to credentials file")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Gap) Synopsis() string {
+	return "Prints the price move needed to fill the pending sells"
+}
+
+func (c *Gap) CommandHelp() string {
+	return `
+
+Command "gap" takes one or more limiter, looper or waller arguments and prints
+the market price, the limit price and the percentage price rise needed to
+fill each sell limiter with a pending size, including the sell limiters of
+the loopers and the wallers. Largest move is the move needed for all of the
+held inventory to clear.
+
+It is a read-only analysis of the saved limiter states. Market price is the
+current product price from the limiter's exchange, which requires the
+-secrets-file flag, and can be overridden with the -price flag.
+
+`
+}
+
+type gapRow struct {
+	uid     string
+	product string
+	pending decimal.Decimal
+	price   decimal.Decimal
+	mark    decimal.Decimal
+	gapPct  decimal.Decimal
+}
+
+func (c *Gap) run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("this command takes one or more job arguments")
+	}
+
+	var override decimal.Decimal
+	if len(c.price) != 0 {
+		v, err := decimal.NewFromString(c.price)
+		if err != nil {
+			return fmt.Errorf("could not parse -price value: %w", err)
+		}
+		if !v.IsPositive() {
+			return fmt.Errorf("-price value must be positive")
+		}
+		override = v
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	var sells []*limiter.Limiter
+	collect := func(ctx context.Context, r kv.Reader) error {
+		for _, arg := range args {
+			_, uid, _, err := namer.Resolve(ctx, r, arg)
+			if err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf("could not resolve job argument %q: %w", arg, err)
+				}
+				uid = arg
+			}
+
+			vs, err := pendingSells(ctx, r, uid)
+			if err != nil {
+				return err
+			}
+			sells = append(sells, vs...)
+		}
+		return nil
+	}
+	if err := kv.WithReader(ctx, db, collect); err != nil {
+		return err
+	}
+
+	var rows []*gapRow
+	if len(sells) != 0 {
+		runtimes := make(map[string]*trader.Runtime)
+		var closers []func()
+		defer func() {
+			for _, closer := range closers {
+				closer()
+			}
+		}()
+
+		for _, v := range sells {
+			mark := override
+			if !mark.IsPositive() {
+				key := v.ExchangeName() + "/" + v.ProductID()
+				rt, ok := runtimes[key]
+				if !ok {
+					var closer func()
+					rt, closer, err = c.openRuntime(ctx, db, v.ExchangeName(), v.ProductID())
+					if err != nil {
+						return err
+					}
+					runtimes[key] = rt
+					closers = append(closers, closer)
+				}
+				mark, err = rt.MarkPrice(ctx)
+				if err != nil {
+					return fmt.Errorf("could not get market price for product %q: %w", v.ProductID(), err)
+				}
+			}
+			rows = append(rows, &gapRow{
+				uid:     v.UID(),
+				product: v.ProductID(),
+				pending: v.PendingSize(),
+				price:   v.Point().Price,
+				mark:    mark,
+				gapPct:  v.PriceGapPct(mark),
+			})
+		}
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("no sell limiters with a pending size")
+		return nil
+	}
+
+	var maxRow *gapRow
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "UID\tProduct\tPending\tPrice\tMarket\tMove%%\t\n")
+	for _, v := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t\n", v.uid, v.product, v.pending, v.price.StringFixed(3), v.mark.StringFixed(3), v.gapPct.StringFixed(2))
+		if maxRow == nil || v.gapPct.GreaterThan(maxRow.gapPct) {
+			maxRow = v
+		}
+	}
+	tw.Flush()
+
+	fmt.Println()
+	fmt.Printf("Largest move %s%% is needed to fill %s\n", maxRow.gapPct.StringFixed(2), maxRow.uid)
+	return nil
+}
+
+// pendingSells returns the sell limiters with a pending size among the
+// limiter with the input uid and the child limiters of the job with the input
+// uid.
+func pendingSells(ctx context.Context, r kv.Reader, uid string) ([]*limiter.Limiter, error) {
+	// Child limiter uids are in the "<parent-uid>/..." form, so the range
+	// covers the limiter itself and all of the descendant limiters.
+	begin := path.Join(limiter.DefaultKeyspace, uid)
+	end := begin + "0"
+
+	var uids []string
+	collect := func(ctx context.Context, r kv.Reader, key string, value *gobs.LimiterState) error {
+		if key == begin || strings.HasPrefix(key, begin+"/") {
+			uids = append(uids, strings.TrimPrefix(key, limiter.DefaultKeyspace))
+		}
+		return nil
+	}
+	if err := kvutil.Ascend(ctx, r, begin, end, collect); err != nil {
+		return nil, fmt.Errorf("could not scan limiters of %q: %w", uid, err)
+	}
+
+	var sells []*limiter.Limiter
+	for _, id := range uids {
+		v, err := limiter.Load(ctx, id, r)
+		if err != nil {
+			return nil, fmt.Errorf("could not load limiter %q: %w", id, err)
+		}
+		if v.IsSell() && v.PendingSize().IsPositive() {
+			sells = append(sells, v)
+		}
+	}
+	return sells, nil
+}
+
+// openRuntime returns a runtime with the product opened on the exchange with
+// the input name using the credentials from the secrets file. Returned closer
+// must be called to close the product and the exchange.
+func (c *Gap) openRuntime(ctx context.Context, db kv.Database, exchangeName, productID string) (*trader.Runtime, func(), error) {
+	if len(c.secretsPath) == 0 {
+		return nil, nil, fmt.Errorf("secrets file is required to get the market price; use -price flag otherwise")
+	}
+
+	name, profile, _ := strings.Cut(exchangeName, ":")
+	if !strings.EqualFold(name, "coinbase") {
+		return nil, nil, fmt.Errorf("limiter exchange %q is not supported", exchangeName)
+	}
+
+	secrets, err := server.SecretsFromFile(c.secretsPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not load secrets: %w", err)
+	}
+	creds := secrets.Coinbase
+	if len(profile) != 0 {
+		creds = secrets.CoinbaseProfiles[profile]
+	}
+	if creds == nil {
+		return nil, nil, fmt.Errorf("coinbase credentials are missing for the limiter exchange")
+	}
+
+	opts := coinbase.SubcommandOptions()
+	opts.AuthScheme = creds.AuthScheme
+	opts.Profile = profile
+	ex, err := coinbase.New(ctx, db, creds.Key, creds.Secret, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create coinbase client: %w", err)
+	}
+
+	product, err := ex.OpenProduct(ctx, productID)
+	if err != nil {
+		ex.Close()
+		return nil, nil, fmt.Errorf("could not open product %q: %w", productID, err)
+	}
+	closer := func() {
+		product.Close()
+		ex.Close()
+	}
+	return &trader.Runtime{Database: db, Product: product}, closer, nil
+}
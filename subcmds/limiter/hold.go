@@ -0,0 +1,74 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type Hold struct {
+	cmdutil.DBFlags
+
+	release bool
+}
+
+func (c *Hold) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("hold", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.BoolVar(&c.release, "release", false, "when true, releases a held limiter")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Hold) Synopsis() string {
+	return "Cancels and holds (or releases) the order of a limiter job"
+}
+
+func (c *Hold) CommandHelp() string {
+	return `
+
+Command "hold" sets the hold option on a limiter job without pausing the job.
+Held limiter cancels it's active order and doesn't create any new orders till
+it is released with the -release flag, but the job keeps running and
+processing the tickers. Held limiters are marked in the "job list" output.
+
+`
+}
+
+func (c *Hold) run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one (job-id) argument")
+	}
+	jobArg := args[0]
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	_, uid, _, err := namer.ResolveDB(ctx, db, jobArg)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not resolve job argument %q: %w", jobArg, err)
+		}
+		uid = jobArg
+	}
+
+	req := &api.JobHoldRequest{
+		UID:  uid,
+		Hold: !c.release,
+	}
+	if _, err := cmdutil.Post[api.JobHoldResponse](ctx, &c.ClientFlags, api.JobHoldPath, req); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvkgo/kv"
+)
+
+type Merge struct {
+	cmdutil.DBFlags
+
+	dryRun bool
+}
+
+func (c *Merge) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("merge", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.BoolVar(&c.dryRun, "dry-run", false, "when true only verifies the limiters can be merged")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Merge) Synopsis() string {
+	return "Merges two limiters on the same product and price point into a new limiter"
+}
+
+func (c *Merge) CommandHelp() string {
+	return `
+
+Command "merge" takes two limiter arguments and a new uid. It creates a new
+limiter with the combined size and order maps of the two limiters. Limiters
+must be on the same product and price point and must not have any live
+orders. Input limiters are not modified.
+
+`
+}
+
+func (c *Merge) run(ctx context.Context, args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("this command takes three (uid1, uid2, new-uid) arguments")
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	merge := func(ctx context.Context, rw kv.ReadWriter) error {
+		var limiters []*limiter.Limiter
+		for _, arg := range args[:2] {
+			_, uid, _, err := namer.Resolve(ctx, rw, arg)
+			if err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf("could not resolve limiter argument %q: %w", arg, err)
+				}
+				uid = arg
+			}
+			v, err := limiter.Load(ctx, uid, rw)
+			if err != nil {
+				return fmt.Errorf("could not load limiter %q: %w", arg, err)
+			}
+			limiters = append(limiters, v)
+		}
+
+		if _, err := limiter.Load(ctx, args[2], rw); err == nil {
+			return fmt.Errorf("limiter with uid %q already exists: %w", args[2], os.ErrExist)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not check for limiter %q: %w", args[2], err)
+		}
+
+		merged, err := limiter.Merge(args[2], limiters[0], limiters[1])
+		if err != nil {
+			return fmt.Errorf("could not merge limiters: %w", err)
+		}
+		fmt.Printf("merged limiter %s has size %s with filled size %s\n", merged.UID(), merged.Point().Size, merged.FilledSize())
+
+		if c.dryRun {
+			return nil
+		}
+		return merged.Save(ctx, rw)
+	}
+	if err := kv.WithReadWriter(ctx, db, merge); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvkgo/kv"
+)
+
+type Pending struct {
+	cmdutil.DBFlags
+}
+
+func (c *Pending) Run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("this command takes one or more limiter arguments")
+	}
+
+	getter := func(ctx context.Context, r kv.Reader) error {
+		for _, arg := range args {
+			_, uid, _, err := namer.Resolve(ctx, r, arg)
+			if err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf("could not resolve limiter argument %q: %w", arg, err)
+				}
+				uid = strings.TrimPrefix(arg, limiter.DefaultKeyspace)
+			}
+
+			v, err := limiter.Load(ctx, uid, r)
+			if err != nil {
+				return fmt.Errorf("could not load limiter %q: %w", uid, err)
+			}
+			active := len(v.LiveOrders()) > 0
+			fmt.Printf("%s pending=%s filled=%s active-order=%t\n", uid, v.PendingSize(), v.FilledSize(), active)
+		}
+		return nil
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	if err := kv.WithReader(ctx, db, getter); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Pending) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("pending", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	return fset, cli.CmdFunc(c.Run)
+}
+
+func (c *Pending) Synopsis() string {
+	return "Prints pending and filled sizes of limiters from the saved state"
+}
+
+func (c *Pending) CommandHelp() string {
+	return `
+
+Command "pending" loads the saved limiter states read-only and prints the
+pending size, filled size and if an active order exists for each limiter. No
+exchange calls are made, so values are as of the last saved state of the
+limiters, which is cheap enough to poll many jobs.
+
+`
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/coinbase"
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/server"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvkgo/kv"
+)
+
+type SyncIDGen struct {
+	cmdutil.DBFlags
+
+	write bool
+
+	maxOffset uint64
+
+	secretsPath string
+
+	fromDate string
+}
+
+func (c *SyncIDGen) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("sync-idgen", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.BoolVar(&c.write, "write", false, "when true, saves the updated offset to the database")
+	fset.Uint64Var(&c.maxOffset, "max-offset", 100000, "max client id offset to search for used ids")
+	fset.StringVar(&c.secretsPath, "secrets-file", "", "path to credentials file")
+	fset.StringVar(&c.fromDate, "from-date", "", "when non-empty, only orders created from this date (YYYY-MM-DD) are listed from the exchange")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *SyncIDGen) Synopsis() string {
+	return "Advances limiter's client id offset past the ids used by observed orders"
+}
+
+func (c *SyncIDGen) CommandHelp() string {
+	return `
+
+Command "sync-idgen" takes a limiter argument and scans all of the product's
+orders on the exchange (open, filled, canceled, expired and failed orders) and
+the filled orders in the datastore for client order ids generated from the
+limiter's seed. If the highest used offset is at or beyond the persisted idgen
+offset (eg: after a restore from an old backup), offset is advanced past it so
+that new orders do not reuse client order ids.
+
+Exchange credentials are loaded from the -secrets-file flag; credentials of
+the limiter's coinbase profile are used for the "coinbase:<profile>" limiters.
+
+Command only prints the old and new offsets by default; -write flag must be
+given to save the updated offset. Limiter's job must not be running.
+
+`
+}
+
+func (c *SyncIDGen) run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one limiter argument")
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	var uid string
+	var v *limiter.Limiter
+	load := func(ctx context.Context, r kv.Reader) error {
+		_, id, _, err := namer.Resolve(ctx, r, args[0])
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("could not resolve limiter argument %q: %w", args[0], err)
+			}
+			id = args[0]
+		}
+		uid = id
+
+		v, err = limiter.Load(ctx, uid, r)
+		if err != nil {
+			return fmt.Errorf("could not load limiter %q: %w", args[0], err)
+		}
+		return nil
+	}
+	if err := kv.WithReader(ctx, db, load); err != nil {
+		return err
+	}
+
+	// Orders of all coinbase profiles are saved in the same datastore.
+	name, profile, _ := strings.Cut(v.ExchangeName(), ":")
+	if !strings.EqualFold(name, "coinbase") {
+		return fmt.Errorf("limiter exchange %q is not supported", v.ExchangeName())
+	}
+
+	var cids []string
+	collect := func(order *gobs.Order) error {
+		if len(order.ClientOrderID) > 0 {
+			cids = append(cids, order.ClientOrderID)
+		}
+		return nil
+	}
+	var zero time.Time
+	if err := coinbase.NewDatastore(db).ScanFilled(ctx, v.ProductID(), zero, zero, collect); err != nil {
+		return fmt.Errorf("could not scan orders for product %q: %w", v.ProductID(), err)
+	}
+
+	// Datastore only has the filled orders, so orders in all other statuses are
+	// listed from the exchange.
+	orders, err := c.listOrders(ctx, db, profile, v.ProductID())
+	if err != nil {
+		return err
+	}
+	for _, order := range orders {
+		if len(order.ClientOrderID) > 0 {
+			cids = append(cids, order.ClientOrderID)
+		}
+	}
+
+	sync := func(ctx context.Context, rw kv.ReadWriter) error {
+		// Running job would overwrite the limiter state, so top-level job (the
+		// first component of a child limiter uid) must not be running.
+		jobID, _, _ := strings.Cut(uid, "/")
+		if state, err := job.Status(ctx, rw, jobID); err == nil && state == job.RUNNING {
+			return fmt.Errorf("job %q is running; it must be paused first", jobID)
+		}
+
+		// Reload the limiter to pick up any changes since the scan.
+		v, err := limiter.Load(ctx, uid, rw)
+		if err != nil {
+			return fmt.Errorf("could not load limiter %q: %w", args[0], err)
+		}
+		old, offset := v.SyncIDGen(cids, c.maxOffset)
+		if old == offset {
+			fmt.Printf("limiter %s client id offset %d is up to date\n", uid, old)
+			return nil
+		}
+		if !c.write {
+			fmt.Printf("limiter %s client id offset would be advanced from %d to %d (use -write to save)\n", uid, old, offset)
+			return nil
+		}
+		if err := v.Save(ctx, rw); err != nil {
+			return fmt.Errorf("could not save limiter %q: %w", uid, err)
+		}
+		fmt.Printf("limiter %s client id offset is advanced from %d to %d\n", uid, old, offset)
+		return nil
+	}
+	if err := kv.WithReadWriter(ctx, db, sync); err != nil {
+		return err
+	}
+	return nil
+}
+
+// listOrders returns all orders of the product from the coinbase exchange
+// using the credentials for the input profile.
+func (c *SyncIDGen) listOrders(ctx context.Context, db kv.Database, profile, productID string) ([]*exchange.Order, error) {
+	if len(c.secretsPath) == 0 {
+		return nil, fmt.Errorf("secrets file is required")
+	}
+	var from time.Time
+	if len(c.fromDate) != 0 {
+		v, err := time.Parse("2006-01-02", c.fromDate)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse from-date flag: %w", err)
+		}
+		from = v
+	}
+
+	secrets, err := server.SecretsFromFile(c.secretsPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not load secrets: %w", err)
+	}
+	creds := secrets.Coinbase
+	if len(profile) != 0 {
+		creds = secrets.CoinbaseProfiles[profile]
+	}
+	if creds == nil {
+		return nil, fmt.Errorf("coinbase credentials are missing for the limiter exchange")
+	}
+
+	opts := coinbase.SubcommandOptions()
+	opts.AuthScheme = creds.AuthScheme
+	opts.Profile = profile
+	ex, err := coinbase.New(ctx, db, creds.Key, creds.Secret, opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not create coinbase client: %w", err)
+	}
+	defer ex.Close()
+
+	orders, err := ex.ListProductOrders(ctx, productID, from)
+	if err != nil {
+		return nil, fmt.Errorf("could not list orders for product %q: %w", productID, err)
+	}
+	return orders, nil
+}
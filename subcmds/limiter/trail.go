@@ -0,0 +1,81 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package limiter
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvkgo/kv"
+)
+
+type Trail struct {
+	cmdutil.DBFlags
+}
+
+func (c *Trail) Run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one limiter argument")
+	}
+	arg := args[0]
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Time\tAction\tTickerPrice\tServerOrderID\tClientOrderID\tLatency\tReason\tError\t\n")
+
+	printer := func(ctx context.Context, r kv.Reader) error {
+		_, uid, _, err := namer.Resolve(ctx, r, arg)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("could not resolve limiter argument %q: %w", arg, err)
+			}
+			uid = arg
+		}
+
+		entries, err := limiter.LoadTrail(ctx, r, uid)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+				e.Time.Format(time.RFC3339Nano),
+				e.Action,
+				e.TickerPrice,
+				e.ServerOrderID,
+				e.ClientOrderID,
+				e.Latency,
+				e.Reason,
+				e.Error)
+		}
+		return nil
+	}
+	if err := kv.WithReader(ctx, db, printer); err != nil {
+		return err
+	}
+	tw.Flush()
+	return nil
+}
+
+func (c *Trail) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("trail", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	return fset, cli.CmdFunc(c.Run)
+}
+
+func (c *Trail) Synopsis() string {
+	return "Prints the order create and cancel decisions of a limiter"
+}
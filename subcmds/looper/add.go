@@ -28,6 +28,8 @@ type Add struct {
 	sellSize         float64
 	sellPrice        float64
 	sellCancelOffset float64
+
+	budget float64
 }
 
 func (c *Add) check() error {
@@ -55,6 +57,9 @@ func (c *Add) check() error {
 	if c.sellPrice <= c.buyPrice {
 		return fmt.Errorf("sell price point must be above the buy price point")
 	}
+	if c.budget < 0 {
+		return fmt.Errorf("budget cannot be negative")
+	}
 	return nil
 }
 
@@ -79,6 +84,7 @@ func (c *Add) Run(ctx context.Context, args []string) error {
 			Price:  decimal.NewFromFloat(c.sellPrice),
 			Cancel: decimal.NewFromFloat(c.sellPrice - c.sellCancelOffset),
 		},
+		Budget: decimal.NewFromFloat(c.budget),
 	}
 	resp, err := cmdutil.Post[api.LoopResponse](ctx, &c.ClientFlags, api.LoopPath, req)
 	if err != nil {
@@ -100,6 +106,7 @@ func (c *Add) Command() (*flag.FlagSet, cli.CmdFunc) {
 	fset.Float64Var(&c.sellSize, "sell-size", 0, "sell-size for the trade")
 	fset.Float64Var(&c.sellPrice, "sell-price", 0, "limit sell-price for the trade")
 	fset.Float64Var(&c.sellCancelOffset, "sell-cancel-offset", 0, "sell-cancel price offset for the trade")
+	fset.Float64Var(&c.budget, "budget", 0, "when non-zero, max cost of the unsold buys")
 	return fset, cli.CmdFunc(c.Run)
 }
 
@@ -0,0 +1,75 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package looper
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+)
+
+type Hold struct {
+	cmdutil.DBFlags
+
+	release bool
+}
+
+func (c *Hold) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("hold", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.BoolVar(&c.release, "release", false, "when true, releases a held child limiter")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Hold) Synopsis() string {
+	return "Cancels and holds (or releases) a single child limiter of a looper"
+}
+
+func (c *Hold) CommandHelp() string {
+	return `
+
+Command "hold" takes a looper job argument and a child limiter name (eg:
+buy-000003) and sets the hold option on the child limiter. Held limiter cancels
+it's active order and doesn't create any new orders, so the looper waits on it
+till it is released with the -release flag.
+
+`
+}
+
+func (c *Hold) run(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("this command takes two (job-id, child-name) arguments")
+	}
+	jobArg, child := args[0], args[1]
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	_, uid, _, err := namer.ResolveDB(ctx, db, jobArg)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not resolve job argument %q: %w", jobArg, err)
+		}
+		uid = jobArg
+	}
+
+	req := &api.JobHoldChildRequest{
+		UID:   uid,
+		Child: child,
+		Hold:  !c.release,
+	}
+	if _, err := cmdutil.Post[api.JobHoldChildResponse](ctx, &c.ClientFlags, api.JobHoldChildPath, req); err != nil {
+		return err
+	}
+	return nil
+}
@@ -9,9 +9,11 @@ import (
 	"fmt"
 	"html/template"
 	"log"
+	"os"
 	"regexp"
 	"slices"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/bvk/tradebot/cli"
 	"github.com/bvk/tradebot/gobs"
@@ -29,6 +31,8 @@ type List struct {
 	dataType string
 
 	printTemplate string
+
+	skipCorrupt bool
 }
 
 func (c *List) Run(ctx context.Context, args []string) error {
@@ -45,7 +49,7 @@ func (c *List) Run(ctx context.Context, args []string) error {
 		keyRe = re
 	}
 
-	dataTypes := []string{"state", "status"}
+	dataTypes := []string{"state", "status", "table"}
 	if !slices.Contains(dataTypes, c.dataType) {
 		return fmt.Errorf("invalid data-type %q", c.dataType)
 	}
@@ -65,18 +69,42 @@ func (c *List) Run(ctx context.Context, args []string) error {
 	}
 	defer closer()
 
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	if c.dataType == "table" {
+		fmt.Fprintf(tw, "UID\tProduct\tBuyPrice\tSellPrice\tBuys\tSells\tRealizedProfit\t\n")
+	}
+
 	lister := func(ctx context.Context, r kv.Reader, k string, v *gobs.LooperState) error {
 		if keyRe != nil && !keyRe.MatchString(k) {
 			return nil
 		}
 
+		if c.dataType == "table" {
+			uid := strings.TrimPrefix(k, looper.DefaultKeyspace)
+			t, err := c.load(ctx, uid, r)
+			if err != nil {
+				return fmt.Errorf("could not load looper instance at key %q: %w", k, err)
+			}
+			s := t.Status(nil)
+			pair := t.Pair()
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%s\t\n",
+				uid,
+				t.ProductID(),
+				pair.Buy.Price.StringFixed(3),
+				pair.Sell.Price.StringFixed(3),
+				s.NumBuys,
+				s.NumSells,
+				t.RealizedProfit().StringFixed(3))
+			return nil
+		}
+
 		var value any
 		switch c.dataType {
 		case "state":
 			value = v
 		case "status":
 			uid := strings.TrimPrefix(k, looper.DefaultKeyspace)
-			t, err := looper.Load(ctx, uid, r)
+			t, err := c.load(ctx, uid, r)
 			if err != nil {
 				return fmt.Errorf("could not load looper instance at key %q: %w", k, err)
 			}
@@ -105,15 +133,31 @@ func (c *List) Run(ctx context.Context, args []string) error {
 	if err := kvutil.AscendDB(ctx, db, beg, end, lister); err != nil {
 		return err
 	}
+	tw.Flush()
 	return nil
 }
 
+func (c *List) load(ctx context.Context, uid string, r kv.Reader) (*looper.Looper, error) {
+	if !c.skipCorrupt {
+		return looper.Load(ctx, uid, r)
+	}
+	t, skipped, err := looper.LoadSkipCorrupt(ctx, uid, r)
+	if err != nil {
+		return nil, err
+	}
+	if len(skipped) > 0 {
+		log.Printf("looper %s is loaded partially with %d unreadable limiters skipped: %s", uid, len(skipped), strings.Join(skipped, " "))
+	}
+	return t, nil
+}
+
 func (c *List) Command() (*flag.FlagSet, cli.CmdFunc) {
 	fset := flag.NewFlagSet("list", flag.ContinueOnError)
 	c.DBFlags.SetFlags(fset)
 	fset.StringVar(&c.keyRe, "key-regexp", "", "regular expression to pick keys")
-	fset.StringVar(&c.dataType, "data-type", "state", "one of state|status")
+	fset.StringVar(&c.dataType, "data-type", "state", "one of state|status|table")
 	fset.StringVar(&c.printTemplate, "print-template", "", "text/template to print the value")
+	fset.BoolVar(&c.skipCorrupt, "skip-corrupt", false, "when true, skips and reports the child limiters that cannot be loaded")
 	return fset, cli.CmdFunc(c.Run)
 }
 
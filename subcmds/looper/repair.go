@@ -0,0 +1,69 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package looper
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/looper"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvkgo/kv"
+)
+
+type Repair struct {
+	cmdutil.DBFlags
+
+	write bool
+}
+
+func (c *Repair) Run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one (looper-uid) argument")
+	}
+	uid := strings.TrimPrefix(args[0], looper.DefaultKeyspace)
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	var added, dropped []string
+	repair := func(ctx context.Context, rw kv.ReadWriter) (err error) {
+		added, dropped, err = looper.Repair(ctx, uid, rw, c.write)
+		return err
+	}
+	if err := kv.WithReadWriter(ctx, db, repair); err != nil {
+		return fmt.Errorf("could not repair looper %q: %w", uid, err)
+	}
+
+	for _, id := range added {
+		fmt.Printf("added: %s\n", id)
+	}
+	for _, id := range dropped {
+		fmt.Printf("dropped: %s\n", id)
+	}
+	if len(added) == 0 && len(dropped) == 0 {
+		fmt.Printf("looper %s has no missing or dangling limiter references\n", uid)
+		return nil
+	}
+	if !c.write {
+		fmt.Printf("dry-run: use -write flag to save the changes\n")
+	}
+	return nil
+}
+
+func (c *Repair) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("repair", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.BoolVar(&c.write, "write", false, "when true, saves the repaired looper state")
+	return fset, cli.CmdFunc(c.Run)
+}
+
+func (c *Repair) Synopsis() string {
+	return "Rebuilds a looper's child limiter list from the limiters keyspace"
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package looper
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/shopspring/decimal"
+)
+
+type Split struct {
+	cmdutil.DBFlags
+}
+
+func (c *Split) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("split", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Split) Synopsis() string {
+	return "Splits a looper into two loopers by a pivot price"
+}
+
+func (c *Split) CommandHelp() string {
+	return `
+
+Command "split" takes a paused looper job, a pivot price and an uid for the new
+looper. Buy-sell cycles of the looper with the buy price at or above the pivot
+price are moved, with their order history, into a new looper job with the
+given uid. New job is created in the paused state.
+
+Split is refused if any of the moved child limiters has a live order.
+
+`
+}
+
+func (c *Split) run(ctx context.Context, args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("this command takes three (job-id, pivot-price, new-uid) arguments")
+	}
+	jobArg, newUID := args[0], args[2]
+
+	pivot, err := decimal.NewFromString(args[1])
+	if err != nil {
+		return fmt.Errorf("could not parse pivot price %q: %w", args[1], err)
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	_, uid, _, err := namer.ResolveDB(ctx, db, jobArg)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not resolve job argument %q: %w", jobArg, err)
+		}
+		uid = jobArg
+	}
+
+	req := &api.JobSplitRequest{
+		UID:        uid,
+		PivotPrice: pivot,
+		NewUID:     newUID,
+	}
+	resp, err := cmdutil.Post[api.JobSplitResponse](ctx, &c.ClientFlags, api.JobSplitPath, req)
+	if err != nil {
+		return err
+	}
+	for _, id := range resp.MovedLimiters {
+		fmt.Printf("moved: %s\n", id)
+	}
+	return nil
+}
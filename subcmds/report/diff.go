@@ -0,0 +1,123 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/trader"
+	"github.com/shopspring/decimal"
+)
+
+type Diff struct {
+}
+
+func (c *Diff) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("diff", flag.ContinueOnError)
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Diff) Synopsis() string {
+	return "Prints the changes between two saved summaries"
+}
+
+func (c *Diff) CommandHelp() string {
+	return `
+
+Command "diff" takes two summary files saved by the "status -summary-file"
+command (eg: for two different time periods) and prints the metrics from both
+summaries side by side along with the change from the first to the second.
+
+`
+}
+
+// DiffRow holds a summary metric from two summaries.
+type DiffRow struct {
+	Name string
+	A, B decimal.Decimal
+
+	// Precision is the number of decimal places used to print the values.
+	Precision int32
+}
+
+// Delta returns the change from the first value to the second.
+func (v *DiffRow) Delta() decimal.Decimal {
+	return v.B.Sub(v.A)
+}
+
+// ChangePct returns the delta as a percentage of the first value, which is
+// zero when the first value is zero.
+func (v *DiffRow) ChangePct() decimal.Decimal {
+	if v.A.IsZero() {
+		return decimal.Zero
+	}
+	return v.Delta().Mul(decimal.NewFromInt(100)).Div(v.A.Abs())
+}
+
+// DiffSummaries returns the metrics from two summaries.
+func DiffSummaries(a, b *trader.Summary) []*DiffRow {
+	row := func(name string, f func(*trader.Summary) decimal.Decimal, precision int32) *DiffRow {
+		return &DiffRow{Name: name, A: f(a), B: f(b), Precision: precision}
+	}
+	return []*DiffRow{
+		row("Num Days", (*trader.Summary).NumDays, 2),
+		row("Num Buys", func(s *trader.Summary) decimal.Decimal { return decimal.NewFromInt(int64(s.NumBuys)) }, 0),
+		row("Num Sells", func(s *trader.Summary) decimal.Decimal { return decimal.NewFromInt(int64(s.NumSells)) }, 0),
+		row("Num Loops", func(s *trader.Summary) decimal.Decimal { return decimal.NewFromInt(int64(s.NumLoops)) }, 0),
+		row("Budget", func(s *trader.Summary) decimal.Decimal { return s.Budget }, 3),
+		row("Fees", (*trader.Summary).Fees, 3),
+		row("Effective Fee Pct", (*trader.Summary).FeePct, 3),
+		row("Profit", (*trader.Summary).Profit, 3),
+		row("Profit Per Day", (*trader.Summary).ProfitPerDay, 3),
+		row("Return Rate", (*trader.Summary).ReturnRate, 3),
+		row("Annual Return Rate", (*trader.Summary).AnnualReturnRate, 3),
+		row("Max Drawdown", (*trader.Summary).MaxDrawdown, 3),
+	}
+}
+
+func loadSummary(fpath string) (*trader.Summary, error) {
+	data, err := os.ReadFile(fpath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read summary file: %w", err)
+	}
+	sum := new(trader.Summary)
+	if err := json.Unmarshal(data, sum); err != nil {
+		return nil, fmt.Errorf("could not decode summary file %q: %w", fpath, err)
+	}
+	return sum, nil
+}
+
+func (c *Diff) run(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("this command takes two (summary-file) arguments")
+	}
+	a, err := loadSummary(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := loadSummary(args[1])
+	if err != nil {
+		return err
+	}
+	if a.QuoteCurrency != b.QuoteCurrency {
+		return fmt.Errorf("summaries are in different quote currencies %q and %q", a.QuoteCurrency, b.QuoteCurrency)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Metric\tA\tB\tDelta\tChange%%\t\n")
+	for _, v := range DiffSummaries(a, b) {
+		change := ""
+		if !v.A.IsZero() {
+			change = v.ChangePct().StringFixed(2)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n", v.Name, v.A.StringFixed(v.Precision), v.B.StringFixed(v.Precision), v.Delta().StringFixed(v.Precision), change)
+	}
+	tw.Flush()
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package report
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bvk/tradebot/trader"
+	"github.com/shopspring/decimal"
+)
+
+func TestDiffSummaries(t *testing.T) {
+	d := decimal.RequireFromString
+	a := &trader.Summary{NumBuys: 2, NumSells: 1, Budget: d("1000"), SoldValue: d("110"), BoughtValue: d("100"), SoldFees: d("1"), BoughtFees: d("1")}
+	b := &trader.Summary{NumBuys: 4, NumSells: 3, Budget: d("1000"), SoldValue: d("330"), BoughtValue: d("300"), SoldFees: d("2"), BoughtFees: d("2")}
+
+	// Summaries must round trip through the json summary files.
+	jsdata, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = new(trader.Summary)
+	if err := json.Unmarshal(jsdata, b); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := make(map[string]*DiffRow)
+	for _, v := range DiffSummaries(a, b) {
+		rows[v.Name] = v
+	}
+	if v := rows["Num Sells"]; !v.Delta().Equal(d("2")) {
+		t.Fatalf("num sells delta: want 2, got %s", v.Delta())
+	}
+	if v := rows["Profit"]; !v.A.Equal(d("8")) || !v.B.Equal(d("26")) || !v.Delta().Equal(d("18")) {
+		t.Fatalf("profit: want 8 -> 26, got %s -> %s", v.A, v.B)
+	}
+	if v := rows["Profit"]; !v.ChangePct().Equal(d("225")) {
+		t.Fatalf("profit change: want 225%%, got %s", v.ChangePct())
+	}
+	if v := rows["Fees"]; !v.Delta().Equal(d("2")) {
+		t.Fatalf("fees delta: want 2, got %s", v.Delta())
+	}
+}
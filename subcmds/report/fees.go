@@ -0,0 +1,153 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package report
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/shopspring/decimal"
+)
+
+type Fees struct {
+	cmdutil.DBFlags
+
+	product     string
+	takerFeePct float64
+}
+
+func (c *Fees) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("fees", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.StringVar(&c.product, "product", "", "when non-empty, only reports the orders of this product")
+	fset.Float64Var(&c.takerFeePct, "taker-fee-pct", 0.6, "taker fee percentage used for the hypothetical taker fees")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Fees) Synopsis() string {
+	return "Prints the fees paid versus the hypothetical taker fees"
+}
+
+func (c *Fees) CommandHelp() string {
+	return `
+
+Command "fees" compares the fees paid for the filled orders recorded by the
+limiters against the fees that would be paid if all orders were filled as a
+taker at the -taker-fee-pct rate, which quantifies the savings from resting
+maker orders. Orders are also counted by their liquidity (maker, taker, mixed
+or unknown for the orders recorded before the liquidity was tracked). Fees
+charged in currencies other than the quote currency are not included.
+
+`
+}
+
+// FeeSummary holds the actual and the hypothetical taker fees for the filled
+// orders of a product.
+type FeeSummary struct {
+	ProductID string
+
+	NumMaker   int
+	NumTaker   int
+	NumMixed   int
+	NumUnknown int
+
+	FilledValue decimal.Decimal
+	PaidFees    decimal.Decimal
+	TakerFees   decimal.Decimal
+}
+
+// Savings returns the difference between the hypothetical taker fees and the
+// fees paid.
+func (v *FeeSummary) Savings() decimal.Decimal {
+	return v.TakerFees.Sub(v.PaidFees)
+}
+
+// SummarizeFees computes the fee summary for the filled orders of a product.
+func SummarizeFees(productID string, orders []*gobs.Order, takerFeePct decimal.Decimal) *FeeSummary {
+	quote := exchange.QuoteCurrency(productID)
+	v := &FeeSummary{ProductID: productID}
+	for _, order := range orders {
+		if !order.FilledSize.IsPositive() {
+			continue
+		}
+		if order.FeeCurrency != "" && order.FeeCurrency != quote {
+			continue
+		}
+		switch order.Liquidity {
+		case exchange.LiquidityMaker:
+			v.NumMaker++
+		case exchange.LiquidityTaker:
+			v.NumTaker++
+		case exchange.LiquidityMixed:
+			v.NumMixed++
+		default:
+			v.NumUnknown++
+		}
+		value := order.FilledSize.Mul(order.FilledPrice)
+		v.FilledValue = v.FilledValue.Add(value)
+		v.PaidFees = v.PaidFees.Add(order.FilledFee)
+		v.TakerFees = v.TakerFees.Add(value.Mul(takerFeePct).Div(decimal.NewFromInt(100)))
+	}
+	return v
+}
+
+func (c *Fees) run(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("this command takes no arguments")
+	}
+	if c.takerFeePct < 0 {
+		return fmt.Errorf("taker fee percentage cannot be negative")
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	productOrdersMap, err := loadLimiterOrders(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	var products []string
+	for p := range productOrdersMap {
+		if c.product == "" || c.product == p {
+			products = append(products, p)
+		}
+	}
+	sort.Strings(products)
+
+	pct := decimal.NewFromFloat(c.takerFeePct)
+	total := &FeeSummary{ProductID: "Total"}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Product\tMaker\tTaker\tMixed\tUnknown\tFilledValue\tPaidFees\tTakerFees\tSavings\t\n")
+	print := func(v *FeeSummary) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\t%s\t%s\t%s\t\n",
+			v.ProductID, v.NumMaker, v.NumTaker, v.NumMixed, v.NumUnknown,
+			v.FilledValue.StringFixed(2), v.PaidFees.StringFixed(2), v.TakerFees.StringFixed(2), v.Savings().StringFixed(2))
+	}
+	for _, p := range products {
+		v := SummarizeFees(p, productOrdersMap[p], pct)
+		print(v)
+
+		total.NumMaker += v.NumMaker
+		total.NumTaker += v.NumTaker
+		total.NumMixed += v.NumMixed
+		total.NumUnknown += v.NumUnknown
+		total.FilledValue = total.FilledValue.Add(v.FilledValue)
+		total.PaidFees = total.PaidFees.Add(v.PaidFees)
+		total.TakerFees = total.TakerFees.Add(v.TakerFees)
+	}
+	print(total)
+	tw.Flush()
+	return nil
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package report
+
+import (
+	"testing"
+
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/shopspring/decimal"
+)
+
+func TestSummarizeFees(t *testing.T) {
+	d := decimal.RequireFromString
+	orders := []*gobs.Order{
+		{Side: "BUY", FilledSize: d("1"), FilledPrice: d("100"), FilledFee: d("0.4"), Liquidity: exchange.LiquidityMaker},
+		{Side: "SELL", FilledSize: d("1"), FilledPrice: d("100"), FilledFee: d("0.6"), Liquidity: exchange.LiquidityTaker},
+		{Side: "SELL", FilledSize: d("1"), FilledPrice: d("100"), FilledFee: d("0.4")},
+		{Side: "SELL", FilledSize: d("1"), FilledPrice: d("100"), FilledFee: d("0.001"), FeeCurrency: "BTC"},
+		{Side: "BUY", FilledSize: d("0"), FilledPrice: d("100")},
+	}
+	v := SummarizeFees("BTC-USD", orders, d("0.6"))
+	if v.NumMaker != 1 || v.NumTaker != 1 || v.NumMixed != 0 || v.NumUnknown != 1 {
+		t.Fatalf("counts: want 1/1/0/1, got %d/%d/%d/%d", v.NumMaker, v.NumTaker, v.NumMixed, v.NumUnknown)
+	}
+	if !v.FilledValue.Equal(d("300")) {
+		t.Fatalf("filled value: want 300, got %s", v.FilledValue)
+	}
+	if !v.PaidFees.Equal(d("1.4")) {
+		t.Fatalf("paid fees: want 1.4, got %s", v.PaidFees)
+	}
+	if !v.TakerFees.Equal(d("1.8")) {
+		t.Fatalf("taker fees: want 1.8, got %s", v.TakerFees)
+	}
+	if !v.Savings().Equal(d("0.4")) {
+		t.Fatalf("savings: want 0.4, got %s", v.Savings())
+	}
+}
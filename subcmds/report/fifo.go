@@ -0,0 +1,201 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package report
+
+import (
+	"log"
+	"slices"
+	"time"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/shopspring/decimal"
+)
+
+// Lot is a realized tax-lot created by matching a sell against the earliest
+// unsold buys.
+type Lot struct {
+	ProductID string
+
+	Size decimal.Decimal
+
+	AcquireTime time.Time
+	DisposeTime time.Time
+
+	Proceeds  decimal.Decimal
+	CostBasis decimal.Decimal
+
+	// AcquireOrderID is the server order id of the buy that acquired the lot.
+	AcquireOrderID string
+
+	// WashSize is the size of a loss lot that is offset by the replacement
+	// buys within the wash-sale window. It is set only by MarkWashSales.
+	WashSize decimal.Decimal
+
+	// WashBuys are the replacement buys offsetting the WashSize, in the
+	// order of their fill times.
+	WashBuys []*WashBuy
+}
+
+// WashBuy identifies a replacement buy and the size of a loss lot that is
+// offset by it.
+type WashBuy struct {
+	OrderID string
+	Time    time.Time
+	Size    decimal.Decimal
+}
+
+func (v *Lot) Gain() decimal.Decimal {
+	return v.Proceeds.Sub(v.CostBasis)
+}
+
+// IsWashSale returns true if the lot is a loss that is (partially) offset by
+// a replacement buy.
+func (v *Lot) IsWashSale() bool {
+	return v.WashSize.IsPositive()
+}
+
+// DisallowedLoss returns the portion of the loss that is disallowed by the
+// wash-sale rule as a positive value.
+func (v *Lot) DisallowedLoss() decimal.Decimal {
+	if !v.IsWashSale() || !v.Gain().IsNegative() {
+		return decimal.Zero
+	}
+	return v.Gain().Neg().Mul(v.WashSize).Div(v.Size)
+}
+
+// TaxableGain returns the gain after adding back the disallowed wash-sale
+// loss, if any.
+func (v *Lot) TaxableGain() decimal.Decimal {
+	return v.Gain().Add(v.DisallowedLoss())
+}
+
+// orderTime returns the finish time of the order or the create time when the
+// finish time is unknown.
+func orderTime(order *gobs.Order) time.Time {
+	if !order.FinishTime.Time.IsZero() {
+		return order.FinishTime.Time
+	}
+	return order.CreateTime.Time
+}
+
+type holding struct {
+	id   string
+	time time.Time
+	size decimal.Decimal
+	// price and fee are per unit size.
+	price decimal.Decimal
+	fee   decimal.Decimal
+}
+
+// MatchFIFO matches the sell orders against the buy orders of a single
+// product in first-in-first-out order and returns the realized lots. Orders
+// without any filled size are ignored. Fees are included in the cost basis for
+// buys and are subtracted from the proceeds for sells.
+func MatchFIFO(productID string, orders []*gobs.Order) []*Lot {
+	var filled []*gobs.Order
+	for _, order := range orders {
+		if order.FilledSize.IsPositive() {
+			filled = append(filled, order)
+		}
+	}
+	slices.SortStableFunc(filled, func(a, b *gobs.Order) int {
+		return orderTime(a).Compare(orderTime(b))
+	})
+
+	var lots []*Lot
+	var queue []*holding
+	for _, order := range filled {
+		if order.Side == "BUY" {
+			queue = append(queue, &holding{
+				id:    order.ServerOrderID,
+				time:  orderTime(order),
+				size:  order.FilledSize,
+				price: order.FilledPrice,
+				fee:   order.FilledFee.Div(order.FilledSize),
+			})
+			continue
+		}
+
+		sellFee := order.FilledFee.Div(order.FilledSize)
+		for remaining := order.FilledSize; remaining.IsPositive(); {
+			if len(queue) == 0 {
+				log.Printf("%s: sell order %s has %s size without any matching buys (ignored)", productID, order.ServerOrderID, remaining)
+				break
+			}
+			h := queue[0]
+			size := decimal.Min(h.size, remaining)
+			lots = append(lots, &Lot{
+				ProductID:   productID,
+				Size:        size,
+				AcquireTime: h.time,
+				DisposeTime: orderTime(order),
+				Proceeds:    size.Mul(order.FilledPrice.Sub(sellFee)),
+				CostBasis:   size.Mul(h.price.Add(h.fee)),
+
+				AcquireOrderID: h.id,
+			})
+			remaining = remaining.Sub(size)
+			if h.size = h.size.Sub(size); !h.size.IsPositive() {
+				queue = queue[1:]
+			}
+		}
+	}
+	return lots
+}
+
+// WashSaleWindow is the duration before and after a loss sale in which a
+// replacement buy triggers the wash-sale rule.
+const WashSaleWindow = 30 * 24 * time.Hour
+
+// MarkWashSales annotates the loss lots of a single product with the
+// replacement buys from the orders that were filled within the wash-sale
+// window around the disposal. A loss lot can be offset by multiple
+// replacement buys till it's full size is covered. Every replacement buy
+// offsets the losses only up to it's filled size and buys that acquired the
+// lot itself are not considered as replacements.
+func MarkWashSales(lots []*Lot, orders []*gobs.Order) {
+	var buys []*gobs.Order
+	for _, order := range orders {
+		if order.Side == "BUY" && order.FilledSize.IsPositive() {
+			buys = append(buys, order)
+		}
+	}
+	slices.SortStableFunc(buys, func(a, b *gobs.Order) int {
+		return orderTime(a).Compare(orderTime(b))
+	})
+
+	sorted := slices.Clone(lots)
+	slices.SortStableFunc(sorted, func(a, b *Lot) int {
+		return a.DisposeTime.Compare(b.DisposeTime)
+	})
+
+	used := make(map[string]decimal.Decimal)
+	for _, lot := range sorted {
+		if !lot.Gain().IsNegative() {
+			continue
+		}
+		for _, buy := range buys {
+			if lot.WashSize.GreaterThanOrEqual(lot.Size) {
+				break
+			}
+			if buy.ServerOrderID == lot.AcquireOrderID {
+				continue
+			}
+			if d := orderTime(buy).Sub(lot.DisposeTime); d < -WashSaleWindow || d > WashSaleWindow {
+				continue
+			}
+			available := buy.FilledSize.Sub(used[buy.ServerOrderID])
+			if !available.IsPositive() {
+				continue
+			}
+			size := decimal.Min(available, lot.Size.Sub(lot.WashSize))
+			used[buy.ServerOrderID] = used[buy.ServerOrderID].Add(size)
+			lot.WashSize = lot.WashSize.Add(size)
+			lot.WashBuys = append(lot.WashBuys, &WashBuy{
+				OrderID: buy.ServerOrderID,
+				Time:    orderTime(buy),
+				Size:    size,
+			})
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/shopspring/decimal"
+)
+
+func TestMatchFIFO(t *testing.T) {
+	day := func(d int) gobs.RemoteTime {
+		return gobs.RemoteTime{Time: time.Date(2023, time.January, d, 0, 0, 0, 0, time.UTC)}
+	}
+	order := func(side, size, price string, d int) *gobs.Order {
+		return &gobs.Order{
+			Side:        side,
+			FilledSize:  decimal.RequireFromString(size),
+			FilledPrice: decimal.RequireFromString(price),
+			FinishTime:  day(d),
+		}
+	}
+	orders := []*gobs.Order{
+		order("SELL", "1.5", "30", 4),
+		order("BUY", "1", "10", 1),
+		order("BUY", "1", "20", 2),
+		order("SELL", "0.5", "40", 5),
+	}
+	lots := MatchFIFO("BTC-USD", orders)
+	if len(lots) != 3 {
+		t.Fatalf("want 3 lots, got %d", len(lots))
+	}
+
+	wants := []struct {
+		size, proceeds, cost string
+		acquired             int
+	}{
+		{"1", "30", "10", 1},
+		{"0.5", "15", "10", 2},
+		{"0.5", "20", "10", 2},
+	}
+	for i, want := range wants {
+		lot := lots[i]
+		if !lot.Size.Equal(decimal.RequireFromString(want.size)) {
+			t.Errorf("lot %d: want size %s, got %s", i, want.size, lot.Size)
+		}
+		if !lot.Proceeds.Equal(decimal.RequireFromString(want.proceeds)) {
+			t.Errorf("lot %d: want proceeds %s, got %s", i, want.proceeds, lot.Proceeds)
+		}
+		if !lot.CostBasis.Equal(decimal.RequireFromString(want.cost)) {
+			t.Errorf("lot %d: want cost basis %s, got %s", i, want.cost, lot.CostBasis)
+		}
+		if !lot.AcquireTime.Equal(day(want.acquired).Time) {
+			t.Errorf("lot %d: want acquire time %s, got %s", i, day(want.acquired).Time, lot.AcquireTime)
+		}
+	}
+}
+
+func TestMarkWashSales(t *testing.T) {
+	day := func(d int) gobs.RemoteTime {
+		return gobs.RemoteTime{Time: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, d)}
+	}
+	order := func(id, side, size, price string, d int) *gobs.Order {
+		return &gobs.Order{
+			ServerOrderID: id,
+			Side:          side,
+			FilledSize:    decimal.RequireFromString(size),
+			FilledPrice:   decimal.RequireFromString(price),
+			FinishTime:    day(d),
+		}
+	}
+	orders := []*gobs.Order{
+		order("b1", "BUY", "2", "100", 0),
+		order("s1", "SELL", "1", "80", 10),    // loss, washed by b2
+		order("b2", "BUY", "0.5", "90", 20),   // offsets only half of s1
+		order("s2", "SELL", "1", "120", 50),   // gain, never a wash-sale
+		order("b3", "BUY", "1", "70", 100),    // too late for s1
+		order("s3", "SELL", "0.5", "60", 140), // loss, no buys within 30 days
+	}
+	lots := MatchFIFO("BTC-USD", orders)
+	MarkWashSales(lots, orders)
+	if len(lots) != 3 {
+		t.Fatalf("want 3 lots, got %d", len(lots))
+	}
+
+	d := decimal.RequireFromString
+	if lot := lots[0]; !lot.WashSize.Equal(d("0.5")) || len(lot.WashBuys) != 1 || lot.WashBuys[0].OrderID != "b2" {
+		t.Fatalf("want s1 lot washed by 0.5 size from b2, got %s from %v", lot.WashSize, lot.WashBuys)
+	}
+	if v := lots[0].DisallowedLoss(); !v.Equal(d("10")) {
+		t.Fatalf("want disallowed loss 10, got %s", v)
+	}
+	if v := lots[0].TaxableGain(); !v.Equal(d("-10")) {
+		t.Fatalf("want taxable gain -10, got %s", v)
+	}
+	for _, lot := range lots[1:] {
+		if lot.IsWashSale() {
+			t.Fatalf("lot disposed at %s must not be a wash-sale", lot.DisposeTime)
+		}
+		if !lot.TaxableGain().Equal(lot.Gain()) {
+			t.Fatalf("want taxable gain %s, got %s", lot.Gain(), lot.TaxableGain())
+		}
+	}
+}
+
+func TestMarkWashSalesMultipleBuys(t *testing.T) {
+	day := func(d int) gobs.RemoteTime {
+		return gobs.RemoteTime{Time: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, d)}
+	}
+	order := func(id, side, size, price string, d int) *gobs.Order {
+		return &gobs.Order{
+			ServerOrderID: id,
+			Side:          side,
+			FilledSize:    decimal.RequireFromString(size),
+			FilledPrice:   decimal.RequireFromString(price),
+			FinishTime:    day(d),
+		}
+	}
+	orders := []*gobs.Order{
+		order("b1", "BUY", "3", "100", 0),
+		order("s1", "SELL", "3", "80", 10), // loss, washed by b2, b3 and b4
+		order("b2", "BUY", "1", "90", 12),
+		order("b3", "BUY", "1", "90", 14),
+		order("b4", "BUY", "2", "90", 16), // offsets only the remaining size 1
+	}
+	lots := MatchFIFO("BTC-USD", orders)
+	MarkWashSales(lots, orders)
+	if len(lots) != 1 {
+		t.Fatalf("want 1 lot, got %d", len(lots))
+	}
+
+	d := decimal.RequireFromString
+	lot := lots[0]
+	if !lot.WashSize.Equal(lot.Size) {
+		t.Fatalf("want full lot size %s to be washed, got %s", lot.Size, lot.WashSize)
+	}
+	if len(lot.WashBuys) != 3 {
+		t.Fatalf("want 3 replacement buys, got %d", len(lot.WashBuys))
+	}
+	for i, want := range []string{"b2", "b3", "b4"} {
+		if b := lot.WashBuys[i]; b.OrderID != want || !b.Size.Equal(d("1")) {
+			t.Fatalf("want replacement buy %s of size 1, got %s of size %s", want, b.OrderID, b.Size)
+		}
+	}
+	if v := lot.TaxableGain(); !v.IsZero() {
+		t.Fatalf("want the whole loss to be disallowed, got taxable gain %s", v)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package report
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvkgo/kv"
+	"github.com/shopspring/decimal"
+)
+
+type Gains struct {
+	cmdutil.DBFlags
+
+	year int
+
+	washSales bool
+}
+
+func (c *Gains) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("gains", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.IntVar(&c.year, "year", 0, "when non-zero, only reports the lots disposed in the year")
+	fset.BoolVar(&c.washSales, "wash-sales", false, "when true, flags the losses with replacement buys within 30 days as wash-sales")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Gains) Synopsis() string {
+	return "Prints FIFO matched realized gains for all sells"
+}
+
+func (c *Gains) run(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("this command takes no arguments")
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	productOrdersMap, err := loadLimiterOrders(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	var products []string
+	for p := range productOrdersMap {
+		products = append(products, p)
+	}
+	sort.Strings(products)
+
+	var lots []*Lot
+	for _, p := range products {
+		plots := MatchFIFO(p, productOrdersMap[p])
+		if c.washSales {
+			MarkWashSales(plots, productOrdersMap[p])
+		}
+		for _, lot := range plots {
+			if c.year == 0 || lot.DisposeTime.Year() == c.year {
+				lots = append(lots, lot)
+			}
+		}
+	}
+
+	var proceeds, costBasis, taxableGain decimal.Decimal
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	if c.washSales {
+		fmt.Fprintf(tw, "Product\tSize\tAcquired\tDisposed\tProceeds\tCostBasis\tGain\tWashSale\tWashBuy\tTaxableGain\t\n")
+	} else {
+		fmt.Fprintf(tw, "Product\tSize\tAcquired\tDisposed\tProceeds\tCostBasis\tGain\t\n")
+	}
+	for _, lot := range lots {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t",
+			lot.ProductID,
+			lot.Size.StringFixed(8),
+			lot.AcquireTime.Format(time.DateOnly),
+			lot.DisposeTime.Format(time.DateOnly),
+			lot.Proceeds.StringFixed(2),
+			lot.CostBasis.StringFixed(2),
+			lot.Gain().StringFixed(2))
+		if c.washSales {
+			washSize, washBuy := "", ""
+			if lot.IsWashSale() {
+				washSize = lot.WashSize.StringFixed(8)
+				var washBuys []string
+				for _, b := range lot.WashBuys {
+					washBuys = append(washBuys, fmt.Sprintf("%s@%s", b.OrderID, b.Time.Format(time.DateOnly)))
+				}
+				washBuy = strings.Join(washBuys, ",")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t", washSize, washBuy, lot.TaxableGain().StringFixed(2))
+		}
+		fmt.Fprintf(tw, "\n")
+		proceeds = proceeds.Add(lot.Proceeds)
+		costBasis = costBasis.Add(lot.CostBasis)
+		taxableGain = taxableGain.Add(lot.TaxableGain())
+	}
+	fmt.Fprintf(tw, "Total\t\t\t\t%s\t%s\t%s\t", proceeds.StringFixed(2), costBasis.StringFixed(2), proceeds.Sub(costBasis).StringFixed(2))
+	if c.washSales {
+		fmt.Fprintf(tw, "\t\t%s\t", taxableGain.StringFixed(2))
+	}
+	fmt.Fprintf(tw, "\n")
+	tw.Flush()
+	return nil
+}
+
+// scanLimiterOrders calls the function with every order of every limiter
+// along with the limiter uid and product id. Orders moved out of the limiter
+// states into the ArchiveKeyspace are included.
+func scanLimiterOrders(ctx context.Context, db kv.Database, fn func(uid, productID string, order *gobs.Order)) error {
+	uidProductMap := make(map[string]string)
+	collect := func(ctx context.Context, r kv.Reader, k string, v *gobs.LimiterState) error {
+		v.Upgrade()
+		uid := strings.TrimPrefix(k, limiter.DefaultKeyspace)
+		uidProductMap[uid] = v.V2.ProductID
+		for _, order := range v.V2.ServerIDOrderMap {
+			fn(uid, v.V2.ProductID, order)
+		}
+		return nil
+	}
+	begin, end := kvutil.PathRange(limiter.DefaultKeyspace)
+	if err := kvutil.AscendDB(ctx, db, begin, end, collect); err != nil {
+		return fmt.Errorf("could not scan limiter states: %w", err)
+	}
+
+	// Archived orders are keyed by the limiter uid and the order id.
+	archived := func(ctx context.Context, r kv.Reader, k string, order *gobs.Order) error {
+		uid := strings.TrimPrefix(path.Dir(k), limiter.ArchiveKeyspace)
+		if pid, ok := uidProductMap[uid]; ok {
+			fn(uid, pid, order)
+		}
+		return nil
+	}
+	begin, end = kvutil.PathRange(limiter.ArchiveKeyspace)
+	if err := kvutil.AscendDB(ctx, db, begin, end, archived); err != nil {
+		return fmt.Errorf("could not scan archived limiter orders: %w", err)
+	}
+	return nil
+}
+
+// loadLimiterOrders returns all filled orders from all limiters grouped by
+// the product id.
+func loadLimiterOrders(ctx context.Context, db kv.Database) (map[string][]*gobs.Order, error) {
+	seen := make(map[string]bool)
+	productOrdersMap := make(map[string][]*gobs.Order)
+	collect := func(uid, productID string, order *gobs.Order) {
+		if seen[order.ServerOrderID] || !order.FilledSize.IsPositive() {
+			return
+		}
+		seen[order.ServerOrderID] = true
+		productOrdersMap[productID] = append(productOrdersMap[productID], order)
+	}
+	if err := scanLimiterOrders(ctx, db, collect); err != nil {
+		return nil, err
+	}
+	for _, orders := range productOrdersMap {
+		slices.SortFunc(orders, func(a, b *gobs.Order) int {
+			return orderTime(a).Compare(orderTime(b))
+		})
+	}
+	return productOrdersMap, nil
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package report
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/shopspring/decimal"
+)
+
+type Orders struct {
+	cmdutil.DBFlags
+
+	product string
+	format  string
+}
+
+func (c *Orders) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("orders", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.StringVar(&c.product, "product", "", "when non-empty, only reports the orders of this product")
+	fset.StringVar(&c.format, "format", "table", "one of table|csv")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Orders) Synopsis() string {
+	return "Prints all filled orders from the limiter records"
+}
+
+func (c *Orders) CommandHelp() string {
+	return `
+
+Command "orders" prints the filled orders recorded by the limiters. With the
+csv format, columns are aligned with the exchange's downloadable fills export
+(trade id, product, side, created at, size, size unit, price, fee, total,
+price/fee/total unit), so that the bot's records can be diffed against the
+exchange's records. Note that each row is an order, which may be multiple fills
+on the exchange; the trade id column holds the server order id and the price
+is the average fill price for the order. Total is negative for the buys.
+
+`
+}
+
+func (c *Orders) run(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("this command takes no arguments")
+	}
+	if !slices.Contains([]string{"table", "csv"}, c.format) {
+		return fmt.Errorf("invalid format %q", c.format)
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	productOrdersMap, err := loadLimiterOrders(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	var products []string
+	for p := range productOrdersMap {
+		if c.product == "" || c.product == p {
+			products = append(products, p)
+		}
+	}
+	sort.Strings(products)
+
+	header := []string{"trade id", "product", "side", "created at", "size", "size unit", "price", "fee", "total", "price/fee/total unit"}
+	var rows [][]string
+	for _, p := range products {
+		base, quote := exchange.BaseCurrency(p), exchange.QuoteCurrency(p)
+		for _, order := range productOrdersMap[p] {
+			rows = append(rows, []string{
+				order.ServerOrderID,
+				p,
+				order.Side,
+				orderTime(order).UTC().Format(time.RFC3339Nano),
+				order.FilledSize.String(),
+				base,
+				order.FilledPrice.String(),
+				order.FilledFee.String(),
+				orderTotal(order, quote).String(),
+				quote,
+			})
+		}
+	}
+
+	if c.format == "csv" {
+		w := csv.NewWriter(os.Stdout)
+		w.Write(header)
+		w.WriteAll(rows)
+		if err := w.Error(); err != nil {
+			return fmt.Errorf("could not write csv output: %w", err)
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	for _, row := range slices.Insert(rows, 0, header) {
+		for _, col := range row {
+			fmt.Fprintf(tw, "%s\t", col)
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+	return nil
+}
+
+// orderTotal returns the net quote amount for the order, which is negative for
+// the buys. Fees charged in other currencies are not included.
+func orderTotal(order *gobs.Order, quote string) decimal.Decimal {
+	value := order.FilledSize.Mul(order.FilledPrice)
+	var fee decimal.Decimal
+	if order.FeeCurrency == "" || order.FeeCurrency == quote {
+		fee = order.FilledFee
+	}
+	if order.Side == "BUY" {
+		return value.Add(fee).Neg()
+	}
+	return value.Sub(fee)
+}
@@ -0,0 +1,26 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package report
+
+import (
+	"testing"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/shopspring/decimal"
+)
+
+func TestOrderTotal(t *testing.T) {
+	d := decimal.RequireFromString
+	buy := &gobs.Order{Side: "BUY", FilledSize: d("2"), FilledPrice: d("100"), FilledFee: d("0.5")}
+	if v := orderTotal(buy, "USD"); !v.Equal(d("-200.5")) {
+		t.Fatalf("buy total: want -200.5, got %s", v)
+	}
+	sell := &gobs.Order{Side: "SELL", FilledSize: d("2"), FilledPrice: d("110"), FilledFee: d("0.5")}
+	if v := orderTotal(sell, "USD"); !v.Equal(d("219.5")) {
+		t.Fatalf("sell total: want 219.5, got %s", v)
+	}
+	sell.FeeCurrency = "BTC"
+	if v := orderTotal(sell, "USD"); !v.Equal(d("220")) {
+		t.Fatalf("sell total with non-quote fee: want 220, got %s", v)
+	}
+}
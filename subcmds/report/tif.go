@@ -0,0 +1,163 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package report
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvkgo/kv"
+)
+
+type TimeInForce struct {
+	cmdutil.DBFlags
+
+	product string
+	by      string
+}
+
+func (c *TimeInForce) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("time-in-force", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.StringVar(&c.product, "product", "", "when non-empty, only reports the orders of this product")
+	fset.StringVar(&c.by, "by", "product", "one of product|limiter")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *TimeInForce) Synopsis() string {
+	return "Prints the time orders rest before they are filled or canceled"
+}
+
+func (c *TimeInForce) CommandHelp() string {
+	return `
+
+Command "time-in-force" prints the min, median and max durations between the
+create and finish times of the completed orders recorded by the limiters,
+separately for the filled and the canceled orders, per product or per limiter.
+Short times to cancel indicate that the cancel thresholds may be too close to
+the limit prices.
+
+Limiters do not keep the canceled orders without any fills, so the canceled
+orders are the partially filled ones. Orders archived by the archive-orders
+option are included. Orders without a finish time are skipped; they are
+backfilled by the server in the background.
+
+`
+}
+
+// Durations holds the distribution of the order resting times.
+type Durations struct {
+	N int
+
+	Min, Median, Max time.Duration
+}
+
+func newDurations(ds []time.Duration) *Durations {
+	if len(ds) == 0 {
+		return &Durations{}
+	}
+	slices.Sort(ds)
+	median := ds[len(ds)/2]
+	if len(ds)%2 == 0 {
+		median = (ds[len(ds)/2-1] + ds[len(ds)/2]) / 2
+	}
+	return &Durations{N: len(ds), Min: ds[0], Median: median, Max: ds[len(ds)-1]}
+}
+
+func (d *Durations) String() string {
+	if d.N == 0 {
+		return "-"
+	}
+	round := func(v time.Duration) time.Duration { return v.Round(time.Second) }
+	return fmt.Sprintf("%s/%s/%s", round(d.Min), round(d.Median), round(d.Max))
+}
+
+// OrderTimesInForce returns the distributions of time-to-fill and
+// time-to-cancel for the completed orders. Orders without create or finish
+// times are ignored.
+func OrderTimesInForce(orders []*gobs.Order) (fills, cancels *Durations) {
+	var fds, cds []time.Duration
+	for _, order := range orders {
+		if !order.Done || order.CreateTime.Time.IsZero() || order.FinishTime.Time.IsZero() {
+			continue
+		}
+		d := order.FinishTime.Time.Sub(order.CreateTime.Time)
+		if d < 0 {
+			continue
+		}
+		if strings.EqualFold(order.Status, "FILLED") {
+			fds = append(fds, d)
+		} else {
+			cds = append(cds, d)
+		}
+	}
+	return newDurations(fds), newDurations(cds)
+}
+
+// loadGroupOrders returns the orders of all limiters, including the archived
+// orders, grouped by the product id or the limiter uid.
+func loadGroupOrders(ctx context.Context, db kv.Database, product, by string) (map[string][]*gobs.Order, error) {
+	groupOrdersMap := make(map[string][]*gobs.Order)
+	collect := func(uid, productID string, order *gobs.Order) {
+		if product != "" && product != productID {
+			return
+		}
+		group := productID
+		if by == "limiter" {
+			group = uid
+		}
+		groupOrdersMap[group] = append(groupOrdersMap[group], order)
+	}
+	if err := scanLimiterOrders(ctx, db, collect); err != nil {
+		return nil, err
+	}
+	return groupOrdersMap, nil
+}
+
+func (c *TimeInForce) run(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("this command takes no arguments")
+	}
+	if c.by != "product" && c.by != "limiter" {
+		return fmt.Errorf("invalid -by value %q", c.by)
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	groupOrdersMap, err := loadGroupOrders(ctx, db, c.product, c.by)
+	if err != nil {
+		return err
+	}
+
+	var groups []string
+	for g := range groupOrdersMap {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\tFills\tTimeToFill(min/median/max)\tCancels\tTimeToCancel(min/median/max)\t\n", strings.ToUpper(c.by[:1])+c.by[1:])
+	for _, g := range groups {
+		fills, cancels := OrderTimesInForce(groupOrdersMap[g])
+		if fills.N == 0 && cancels.N == 0 {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%s\t\n", g, fills.N, fills, cancels.N, cancels)
+	}
+	tw.Flush()
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package report
+
+import (
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/limiter"
+	"github.com/bvk/tradebot/point"
+	"github.com/bvkgo/kv"
+	"github.com/bvkgo/kv/kvmemdb"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestOrderTimesInForce(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	order := func(status string, d time.Duration) *gobs.Order {
+		return &gobs.Order{
+			Status:     status,
+			Done:       true,
+			CreateTime: gobs.RemoteTime{Time: start},
+			FinishTime: gobs.RemoteTime{Time: start.Add(d)},
+		}
+	}
+
+	orders := []*gobs.Order{
+		order("FILLED", 3*time.Minute),
+		order("FILLED", time.Minute),
+		order("FILLED", 10*time.Minute),
+		order("FILLED", 2*time.Minute),
+		order("CANCELLED", 30*time.Second),
+		{Status: "FILLED", Done: true, CreateTime: gobs.RemoteTime{Time: start}},
+		{Status: "OPEN", CreateTime: gobs.RemoteTime{Time: start}},
+	}
+	fills, cancels := OrderTimesInForce(orders)
+	if fills.N != 4 || fills.Min != time.Minute || fills.Max != 10*time.Minute {
+		t.Fatalf("want 4 fills within 1m-10m, got %d within %s-%s", fills.N, fills.Min, fills.Max)
+	}
+	if fills.Median != 150*time.Second {
+		t.Fatalf("want median time-to-fill 2m30s, got %s", fills.Median)
+	}
+	if cancels.N != 1 || cancels.Median != 30*time.Second {
+		t.Fatalf("want one cancel in 30s, got %d with median %s", cancels.N, cancels.Median)
+	}
+	if s := cancels.String(); s != "30s/30s/30s" {
+		t.Fatalf("want 30s/30s/30s, got %s", s)
+	}
+}
+
+func TestLoadGroupOrdersArchived(t *testing.T) {
+	ctx := context.Background()
+	db := kvmemdb.New()
+	d := decimal.RequireFromString
+
+	uid := path.Join(uuid.NewString(), "buy-000000")
+	l, err := limiter.New(uid, "coinbase", "BTC-USD", &point.Point{Size: d("1"), Price: d("100"), Cancel: d("110")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kv.WithReadWriter(ctx, db, l.Save); err != nil {
+		t.Fatal(err)
+	}
+	archived := &gobs.Order{ServerOrderID: "archived", Side: "BUY", Status: "FILLED", Done: true, FilledSize: d("1"), FilledPrice: d("100")}
+	if err := kvutil.SetDB(ctx, db, path.Join(limiter.ArchiveKeyspace, uid, "archived"), archived); err != nil {
+		t.Fatal(err)
+	}
+
+	byLimiter, err := loadGroupOrders(ctx, db, "", "limiter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orders := byLimiter[uid]; len(orders) != 1 || orders[0].ServerOrderID != "archived" {
+		t.Fatalf("want the archived order for the limiter, got %v", byLimiter)
+	}
+
+	byProduct, err := loadGroupOrders(ctx, db, "ETH-USD", "product")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byProduct) != 0 {
+		t.Fatalf("want no orders for other products, got %v", byProduct)
+	}
+
+	productOrdersMap, err := loadLimiterOrders(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orders := productOrdersMap["BTC-USD"]; len(orders) != 1 {
+		t.Fatalf("want the archived order in the product orders, got %v", productOrdersMap)
+	}
+}
@@ -4,6 +4,7 @@ package subcmds
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -16,6 +17,7 @@ import (
 	"os/signal"
 	"path"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -30,6 +32,7 @@ import (
 	"github.com/bvkgo/kvbadger"
 	"github.com/dgraph-io/badger/v4"
 	"github.com/nightlyone/lockfile"
+	"github.com/shopspring/decimal"
 )
 
 type Run struct {
@@ -42,9 +45,31 @@ type Run struct {
 
 	noPprof              bool
 	noResume             bool
+	startPaused          bool
 	noFetchCandles       bool
 	maxFetchTimeLatency  time.Duration
 	maxHttpClientTimeout time.Duration
+	allowedProducts      string
+	priceSource          string
+	tickerMode           string
+	tickerPollInterval   time.Duration
+	useKeyring           bool
+	jobLogDir            string
+	jobLogSizeMB         int64
+	compactRetention     time.Duration
+	compactInterval      time.Duration
+
+	stopTimeout time.Duration
+
+	loadConcurrency int
+
+	exchangeConcurrency int
+
+	snapCancel bool
+
+	defaultSizeLimits string
+
+	maxBudget string
 
 	secretsPath string
 	dataDir     string
@@ -58,9 +83,25 @@ func (c *Run) Command() (*flag.FlagSet, cli.CmdFunc) {
 	fset.DurationVar(&c.shutdownTimeout, "shutdown-timeout", 30*time.Second, "max timeout for shutdown when restarting")
 	fset.BoolVar(&c.noPprof, "no-pprof", false, "when true net/http/pprof handler is not registered")
 	fset.BoolVar(&c.noResume, "no-resume", false, "when true old jobs aren't resumed automatically")
+	fset.BoolVar(&c.startPaused, "start-paused", false, "when true old jobs are marked paused and must be resumed explicitly")
 	fset.BoolVar(&c.noFetchCandles, "no-fetch-candles", false, "when true, candle data is not saved in the datastore")
 	fset.DurationVar(&c.maxFetchTimeLatency, "max-fetch-time-latency", 0, "max latency for fetch-time operation in finding time difference")
 	fset.DurationVar(&c.maxHttpClientTimeout, "max-http-client-timeout", 10*time.Second, "default max timeout for http requests")
+	fset.StringVar(&c.allowedProducts, "allowed-products", "", "comma separated list of product ids allowed for trading (empty allows all)")
+	fset.StringVar(&c.priceSource, "price-source", "last-trade", "price used in the tickers; one of last-trade|mid|index")
+	fset.StringVar(&c.tickerMode, "ticker-mode", "websocket", "how tickers are received; one of websocket|rest-poll|auto")
+	fset.DurationVar(&c.tickerPollInterval, "ticker-poll-interval", 5*time.Second, "interval between ticker polls in rest-poll and auto modes")
+	fset.BoolVar(&c.useKeyring, "use-keyring", false, "when true, coinbase credentials can be loaded from the OS keyring")
+	fset.StringVar(&c.jobLogDir, "job-log-dir", "", "when non-empty, job log lines are also written to per-job files in this directory (relative to data-dir)")
+	fset.DurationVar(&c.compactRetention, "compact-retention", 0, "when non-zero, completed limiter jobs older than this are exported and removed in the background")
+	fset.DurationVar(&c.stopTimeout, "stop-timeout", 30*time.Second, "max time for the jobs to cancel their active orders on shutdown; zero waits forever")
+	fset.BoolVar(&c.snapCancel, "snap-cancel", false, "when true, rounds cancel prices of new jobs to the product price increment")
+	fset.StringVar(&c.defaultSizeLimits, "default-size-limits", "", "comma separated list of product=size pairs for the default size-limit of the limiters without an explicit size-limit option")
+	fset.StringVar(&c.maxBudget, "max-budget", "", "when non-empty, max total budget for all running jobs; new jobs over the limit are rejected")
+	fset.IntVar(&c.loadConcurrency, "load-concurrency", server.DefaultLoadConcurrency, "max number of jobs loaded in parallel at the startup")
+	fset.IntVar(&c.exchangeConcurrency, "exchange-concurrency", 0, "when non-zero, max number of in-flight exchange calls by all limiters")
+	fset.DurationVar(&c.compactInterval, "compact-interval", 24*time.Hour, "interval between the background compactions")
+	fset.Int64Var(&c.jobLogSizeMB, "job-log-size-mb", 10, "size limit in MB before a per-job log file is rotated")
 	fset.StringVar(&c.secretsPath, "secrets-file", "", "path to credentials file")
 	fset.StringVar(&c.dataDir, "data-dir", "", "path to the data directory")
 	return fset, cli.CmdFunc(c.run)
@@ -92,6 +133,25 @@ JSON format. A example secrets file format is given below:
 Users should consult the exchange specific documentation to learn how to create
 the API keys.
 
+Coinbase credentials can also be passed through the COINBASE_KEY and
+COINBASE_SECRET environment variables or the OS keyring (with the -use-keyring
+flag). Environment variables take precedence over the keyring, which takes
+precedence over the secrets file, so the secrets file is only a fallback.
+
+API TOKENS
+
+When the secrets file has an "APITokens" entry, all api requests must carry a
+bearer token in the Authorization header. Read-only tokens can only list and
+inspect the jobs and read the database, while admin tokens can use all
+endpoints, including the database updates and the debug handlers. Example:
+
+    {
+        "APITokens":{
+            "ReadOnly":["dashboard-token"],
+            "Admin":["admin-token"]
+        }
+    }
+
 `
 }
 
@@ -120,7 +180,10 @@ func (c *Run) run(ctx context.Context, args []string) error {
 	}
 	secrets, err := server.SecretsFromFile(c.secretsPath)
 	if err != nil {
-		return err
+		if !c.useKeyring || !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		secrets = new(server.Secrets)
 	}
 
 	if ip := net.ParseIP(c.IP); ip == nil {
@@ -139,7 +202,14 @@ func (c *Run) run(ctx context.Context, args []string) error {
 	// instance.
 	check := func(ctx context.Context, child *os.Process) (bool, error) {
 		client := http.Client{Timeout: time.Second}
-		resp, err := client.Get(fmt.Sprintf("http://%s/pid", addr.String()))
+		r, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s/pid", addr.String()), nil)
+		if err != nil {
+			return false, err
+		}
+		if token := healthCheckToken(secrets.APITokens); len(token) != 0 {
+			r.Header.Set("authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(r)
 		if err != nil {
 			return true, err
 		}
@@ -160,7 +230,7 @@ func (c *Run) run(ctx context.Context, args []string) error {
 		return false, nil
 	}
 
-	logger, err := logdir.New(dataDir, "tradebot")
+	logger, err := logdir.New(dataDir, "tradebot", logdir.FileSizeLimitMB)
 	if err != nil {
 		return fmt.Errorf("could not create logger: %w", err)
 	}
@@ -178,6 +248,22 @@ func (c *Run) run(ctx context.Context, args []string) error {
 		outputs = []io.Writer{logger, syslogger}
 	}
 
+	var jobLogger *logdir.JobsBackend
+	if len(c.jobLogDir) != 0 {
+		// Relative paths are resolved under the data directory, because the
+		// background process runs in the root directory.
+		jobLogDir := c.jobLogDir
+		if !filepath.IsAbs(jobLogDir) {
+			jobLogDir = filepath.Join(dataDir, jobLogDir)
+		}
+		jobLogger, err = logdir.NewJobs(jobLogDir, c.jobLogSizeMB)
+		if err != nil {
+			return fmt.Errorf("could not create per-job logger: %w", err)
+		}
+		defer jobLogger.Close()
+		outputs = append(outputs, jobLogger)
+	}
+
 	log.SetOutput(io.MultiWriter(outputs...))
 	log.SetFlags(log.Flags() | log.Lmicroseconds)
 	log.Printf("using data directory %s and secrets file %s", dataDir, c.secretsPath)
@@ -223,14 +309,6 @@ func (c *Run) run(ctx context.Context, args []string) error {
 	}
 	defer s.Stop(tcpServer)
 
-	if !c.noPprof {
-		s.AddHandler("/debug/pprof/heap", pprof.Handler("heap"))
-		s.AddHandler("/debug/pprof/goroutine", pprof.Handler("goroutine"))
-		s.AddHandler("/debug/pprof/allocs", pprof.Handler("allocs"))
-		s.AddHandler("/debug/pprof/block", pprof.Handler("block"))
-		s.AddHandler("/debug/pprof/mutex", pprof.Handler("mutex"))
-	}
-
 	// Open the database.
 	bopts := badger.DefaultOptions(dataDir)
 	bdb, err := badger.Open(bopts)
@@ -240,14 +318,61 @@ func (c *Run) run(ctx context.Context, args []string) error {
 	defer bdb.Close()
 	db := kvbadger.New(bdb, isGoodKey)
 
-	s.AddHandler("/db/", http.StripPrefix("/db", kvhttp.Handler(db)))
-
 	// Start other services.
 	topts := &server.Options{
 		NoResume:             c.noResume,
+		StartPaused:          c.startPaused,
 		NoFetchCandles:       c.noFetchCandles,
 		MaxFetchTimeLatency:  c.maxFetchTimeLatency,
 		MaxHttpClientTimeout: c.maxHttpClientTimeout,
+		PriceSource:          c.priceSource,
+		TickerMode:           c.tickerMode,
+		TickerPollInterval:   c.tickerPollInterval,
+		UseKeyring:           c.useKeyring,
+		ExportDir:            filepath.Join(dataDir, "exports"),
+		CompactRetention:     c.compactRetention,
+		CompactInterval:      c.compactInterval,
+		StopTimeout:          c.stopTimeout,
+		LoadConcurrency:      c.loadConcurrency,
+		ExchangeConcurrency:  c.exchangeConcurrency,
+		SnapCancel:           c.snapCancel,
+	}
+	if len(c.allowedProducts) > 0 {
+		for _, pid := range strings.Split(c.allowedProducts, ",") {
+			if pid = strings.TrimSpace(pid); len(pid) > 0 {
+				topts.AllowedProducts = append(topts.AllowedProducts, pid)
+			}
+		}
+	}
+	if len(c.defaultSizeLimits) > 0 {
+		topts.DefaultSizeLimits = make(map[string]decimal.Decimal)
+		for _, item := range strings.Split(c.defaultSizeLimits, ",") {
+			if item = strings.TrimSpace(item); len(item) == 0 {
+				continue
+			}
+			pid, value, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("default-size-limits item %q must be in product=size format", item)
+			}
+			size, err := decimal.NewFromString(strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("could not parse default size limit for product %q: %w", pid, err)
+			}
+			topts.DefaultSizeLimits[strings.TrimSpace(pid)] = size
+		}
+	}
+	if len(c.maxBudget) > 0 {
+		max, err := decimal.NewFromString(c.maxBudget)
+		if err != nil {
+			return fmt.Errorf("could not parse max-budget flag value: %w", err)
+		}
+		if max.IsNegative() {
+			return fmt.Errorf("max-budget flag value cannot be negative")
+		}
+		topts.MaxBudget = max
+	}
+	if jobLogger != nil {
+		topts.JobLogs = jobLogger
 	}
 	trader, err := server.New(ctx, secrets, db, topts)
 	if err != nil {
@@ -255,6 +380,16 @@ func (c *Run) run(ctx context.Context, args []string) error {
 	}
 	defer trader.Close()
 
+	s.AddHandler("/db/", http.StripPrefix("/db", trader.DatabaseHandler(kvhttp.Handler(db))))
+
+	if !c.noPprof {
+		s.AddHandler("/debug/pprof/heap", trader.AdminHandler(pprof.Handler("heap")))
+		s.AddHandler("/debug/pprof/goroutine", trader.AdminHandler(pprof.Handler("goroutine")))
+		s.AddHandler("/debug/pprof/allocs", trader.AdminHandler(pprof.Handler("allocs")))
+		s.AddHandler("/debug/pprof/block", trader.AdminHandler(pprof.Handler("block")))
+		s.AddHandler("/debug/pprof/mutex", trader.AdminHandler(pprof.Handler("mutex")))
+	}
+
 	// Add trader api handlers
 	traderAPIs := trader.HandlerMap()
 	for k, v := range traderAPIs {
@@ -278,15 +413,30 @@ func (c *Run) run(ctx context.Context, args []string) error {
 	// Wait for the signals
 
 	log.Printf("started tradebot server at %s", addr)
-	s.AddHandler("/pid", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	s.AddHandler("/pid", trader.ReadOnlyHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		io.WriteString(w, fmt.Sprintf("%d", os.Getpid()))
-	}))
+	})))
 
 	<-ctx.Done()
 	log.Printf("tradebot server is shutting down")
 	return nil
 }
 
+// healthCheckToken returns an api token that can be used to check the pid of
+// the background process.
+func healthCheckToken(tokens *server.APITokens) string {
+	if tokens == nil {
+		return ""
+	}
+	if len(tokens.ReadOnly) != 0 {
+		return tokens.ReadOnly[0]
+	}
+	if len(tokens.Admin) != 0 {
+		return tokens.Admin[0]
+	}
+	return ""
+}
+
 func isGoodKey(k string) bool {
 	return path.IsAbs(k) && k == path.Clean(k)
 }
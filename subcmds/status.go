@@ -4,6 +4,7 @@ package subcmds
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -17,7 +18,10 @@ import (
 
 	"github.com/bvk/tradebot/cli"
 	"github.com/bvk/tradebot/coinbase"
+	"github.com/bvk/tradebot/exchange"
+	"github.com/bvk/tradebot/gobs"
 	"github.com/bvk/tradebot/job"
+	"github.com/bvk/tradebot/kvutil"
 	"github.com/bvk/tradebot/limiter"
 	"github.com/bvk/tradebot/looper"
 	"github.com/bvk/tradebot/namer"
@@ -36,6 +40,16 @@ type Status struct {
 	budget float64
 
 	beginTime, endTime string
+
+	feePct string
+
+	currency string
+
+	useSnapshot bool
+
+	summaryFile string
+
+	push cmdutil.PushgatewayFlags
 }
 
 func (c *Status) Synopsis() string {
@@ -48,6 +62,11 @@ func (c *Status) Command() (*flag.FlagSet, cli.CmdFunc) {
 	fset.Float64Var(&c.budget, "budget", 0, "Includes this budget in the return rate table")
 	fset.StringVar(&c.beginTime, "begin-time", "", "Begin time for status time period")
 	fset.StringVar(&c.endTime, "end-time", "", "End time for status time period")
+	fset.StringVar(&c.feePct, "fee-pct", "", "When non-empty, recomputes the fees at this percentage (or the live maker fee when \"live\") instead of the recorded fees")
+	fset.StringVar(&c.currency, "currency", "", "When non-empty, includes only the jobs in this quote currency")
+	fset.BoolVar(&c.useSnapshot, "use-snapshot", false, "When true, values the unsold sizes at the prices from the latest saved price snapshot")
+	fset.StringVar(&c.summaryFile, "summary-file", "", "When non-empty, also saves the summary in json format to this file (see report diff)")
+	c.push.SetFlags(fset)
 	return fset, cli.CmdFunc(c.run)
 }
 
@@ -72,6 +91,24 @@ func (c *Status) run(ctx context.Context, args []string) error {
 		}
 		priceMap = make(map[string]decimal.Decimal)
 	}
+	if c.useSnapshot {
+		begin, end := kvutil.PathRange(server.SnapshotsKeyspace)
+		_, snapshot, err := kvutil.LastDB[gobs.PriceSnapshot](ctx, db, begin, end)
+		if err != nil {
+			return fmt.Errorf("could not load the latest price snapshot: %w", err)
+		}
+		log.Printf("using price snapshot taken at %s", snapshot.Timestamp.Format(time.RFC3339))
+		for _, p := range snapshot.Prices {
+			priceMap[p.ProductID] = p.Price
+		}
+	}
+	incrementMap, err := datastore.ProductsBaseIncrementMap(ctx)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("could not load base increment information (ignored): %v", err)
+		}
+		incrementMap = make(map[string]decimal.Decimal)
+	}
 
 	var assets []string
 	holdMap := make(map[string]decimal.Decimal)
@@ -161,6 +198,24 @@ func (c *Status) run(ctx context.Context, args []string) error {
 		period.End = v
 	}
 
+	var feePct *decimal.Decimal
+	if c.feePct == "live" {
+		v, err := cmdutil.LiveMakerFeePct(ctx, &c.DBFlags.ClientFlags, "coinbase")
+		if err != nil {
+			return err
+		}
+		feePct = &v
+	} else if len(c.feePct) > 0 {
+		v, err := decimal.NewFromString(c.feePct)
+		if err != nil {
+			return fmt.Errorf("could not parse fee-pct value: %w", err)
+		}
+		if v.IsNegative() {
+			return fmt.Errorf("fee-pct value cannot be negative")
+		}
+		feePct = &v
+	}
+
 	// Remove jobs that don't implement Status interface.
 	type Statuser interface {
 		Status(*timerange.Range) *trader.Status
@@ -179,16 +234,35 @@ func (c *Status) run(ctx context.Context, args []string) error {
 	for _, j := range jobs {
 		if v, ok := j.(Statuser); ok {
 			if s := v.Status(&period); s != nil {
+				s.BaseIncrement = incrementMap[s.ProductID]
+				if feePct != nil {
+					s.Summary = s.Summary.WithFeePct(*feePct)
+				}
 				statuses = append(statuses, s)
 			}
 		}
 	}
 
+	if len(c.currency) != 0 {
+		statuses = slices.DeleteFunc(statuses, func(s *trader.Status) bool {
+			return !strings.EqualFold(exchange.QuoteCurrency(s.ProductID), c.currency)
+		})
+	}
+	if sums := trader.SummarizeByCurrency(statuses); len(sums) > 1 {
+		var currencies []string
+		for currency := range sums {
+			currencies = append(currencies, currency)
+		}
+		sort.Strings(currencies)
+		return fmt.Errorf("jobs use multiple quote currencies (%s); pick one with the -currency flag", strings.Join(currencies, ", "))
+	}
+
 	sum := trader.Summarize(statuses)
-	var curUnsoldValue decimal.Decimal
+	var curUnsoldValue, unrealizedProfit decimal.Decimal
 	for _, s := range statuses {
 		if p, ok := priceMap[s.ProductID]; ok {
 			curUnsoldValue = curUnsoldValue.Add(s.UnsoldSize.Mul(p))
+			unrealizedProfit = unrealizedProfit.Add(s.UnrealizedProfit(p))
 		}
 	}
 
@@ -200,6 +274,20 @@ func (c *Status) run(ctx context.Context, args []string) error {
 	}
 	runningSum := trader.Summarize(runningStatuses)
 
+	if len(c.summaryFile) != 0 {
+		jsdata, err := json.MarshalIndent(sum, "", "  ")
+		if err != nil {
+			return fmt.Errorf("could not marshal summary to json: %w", err)
+		}
+		if err := os.WriteFile(c.summaryFile, jsdata, 0644); err != nil {
+			return fmt.Errorf("could not write summary file: %w", err)
+		}
+	}
+
+	if err := c.push.Push(ctx, sum, nil); err != nil {
+		return err
+	}
+
 	var (
 		d30  = decimal.NewFromInt(30)
 		d100 = decimal.NewFromInt(100)
@@ -210,9 +298,13 @@ func (c *Status) run(ctx context.Context, args []string) error {
 		fmt.Printf("Num Days: %s\n", sum.NumDays().StringFixed(2))
 		fmt.Printf("Num Buys: %d\n", sum.NumBuys)
 		fmt.Printf("Num Sells: %d\n", sum.NumSells)
+		fmt.Printf("Num Loops: %d\n", sum.NumLoops)
 
 		fmt.Println()
 		fmt.Printf("Fees: %s\n", sum.Fees().StringFixed(3))
+		if len(sum.OtherFees) != 0 {
+			fmt.Printf("Other Fees: %s\n", sum.OtherFeesString())
+		}
 		fmt.Printf("Sold: %s\n", sum.Sold().StringFixed(3))
 		fmt.Printf("Bought: %s\n", sum.Bought().StringFixed(3))
 		fmt.Printf("Effective Fee Pct: %s%%\n", sum.FeePct().StringFixed(3))
@@ -221,17 +313,20 @@ func (c *Status) run(ctx context.Context, args []string) error {
 		fmt.Printf("Lockin Position: %s\n", curUnsoldValue.Sub(sum.UnsoldValue).StringFixed(3))
 		fmt.Printf("Lockin at Buy Price: %s\n", sum.UnsoldValue.StringFixed(3))
 		fmt.Printf("Lockin at Current Price: %s\n", curUnsoldValue.StringFixed(3))
+		fmt.Printf("Unrealized Profit: %s\n", unrealizedProfit.StringFixed(3))
 
 		fmt.Println()
 		fmt.Printf("Profit: %s\n", sum.Profit().StringFixed(3))
 		fmt.Printf("Per day (average): %s\n", sum.ProfitPerDay().StringFixed(3))
 		fmt.Printf("Per month (projected): %s\n", sum.ProfitPerDay().Mul(d30).StringFixed(3))
 		fmt.Printf("Per year (projected): %s\n", sum.ProfitPerDay().Mul(d365).StringFixed(3))
+		fmt.Printf("Max Drawdown: %s\n", sum.MaxDrawdown().StringFixed(3))
 
 		fmt.Println()
 		fmt.Printf("Budget: %s\n", runningSum.Budget.StringFixed(3))
 		fmt.Printf("Return Rate: %s%%\n", runningSum.ReturnRate().StringFixed(3))
 		fmt.Printf("Annual Return Rate: %s%%\n", runningSum.AnnualReturnRate().StringFixed(3))
+		fmt.Printf("Profit per Loop: %s\n", sum.ProfitPerLoop().StringFixed(3))
 	}
 
 	emptystrings := func(n int) (vs []any) {
@@ -316,7 +411,41 @@ func (c *Status) run(ctx context.Context, args []string) error {
 		for _, s := range statuses {
 			name := uid2nameMap[s.UID]
 			status := uid2statusMap[s.UID]
-			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s%%\t%s%%\t%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", name, status, s.ProductID, s.Budget.StringFixed(3), s.ReturnRate().StringFixed(3), s.AnnualReturnRate().StringFixed(3), s.NumDays().StringFixed(2), s.NumBuys, s.NumSells, s.Profit().StringFixed(3), s.Fees().StringFixed(3), s.Bought().StringFixed(3), s.Sold().StringFixed(3), s.UnsoldValue.StringFixed(3), s.SoldSize.Sub(s.OversoldSize).StringFixed(3), s.UnsoldSize.StringFixed(3))
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s%%\t%s%%\t%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", name, status, s.ProductID, s.Budget.StringFixed(3), s.ReturnRate().StringFixed(3), s.AnnualReturnRate().StringFixed(3), s.NumDays().StringFixed(2), s.NumBuys, s.NumSells, s.Profit().StringFixed(3), s.Fees().StringFixed(3), s.Bought().StringFixed(3), s.Sold().StringFixed(3), s.UnsoldValue.StringFixed(3), s.SizeString(s.SoldSize.Sub(s.OversoldSize)), s.SizeString(s.UnsoldSize))
+		}
+		tw.Flush()
+	}
+
+	if sums := trader.SummarizeByProduct(statuses); len(sums) > 1 {
+		var pids []string
+		for pid := range sums {
+			pids = append(pids, pid)
+		}
+		sort.Strings(pids)
+
+		fmt.Println()
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "Product\tBuys\tSells\tBoughtValue\tSoldValue\tFees\tFeePct\tProfit\t\n")
+		for _, pid := range pids {
+			s := sums[pid]
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s%%\t%s\t\n", pid, s.NumBuys, s.NumSells, s.Bought().StringFixed(3), s.Sold().StringFixed(3), s.Fees().StringFixed(3), s.FeePct().StringFixed(3), s.Profit().StringFixed(3))
+		}
+		tw.Flush()
+	}
+
+	if sums := trader.SummarizeByTag(statuses); len(sums) > 1 {
+		var tags []string
+		for tag := range sums {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+
+		fmt.Println()
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "Tag\tBudget\tReturn\tBuys\tSells\tProfit\tFees\t\n")
+		for _, tag := range tags {
+			s := sums[tag]
+			fmt.Fprintf(tw, "%s\t%s\t%s%%\t%d\t%d\t%s\t%s\t\n", tag, s.Budget.StringFixed(3), s.ReturnRate().StringFixed(3), s.NumBuys, s.NumSells, s.Profit().StringFixed(3), s.Fees().StringFixed(3))
 		}
 		tw.Flush()
 	}
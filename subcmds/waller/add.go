@@ -18,13 +18,18 @@ import (
 type Add struct {
 	cmdutil.ClientFlags
 
-	dryRun bool
+	dryRun   bool
+	validate bool
 
 	product  string
 	exchange string
 	name     string
 
+	liveFees bool
+
 	spec Spec
+
+	fset *flag.FlagSet
 }
 
 func (c *Add) check() error {
@@ -47,13 +52,28 @@ func (c *Add) check() error {
 }
 
 func (c *Add) buySellPairs() []*point.Pair {
-	return fixedProfitPairs(&c.spec)
+	return c.spec.BuySellPairs()
 }
 
 func (c *Add) Run(ctx context.Context, args []string) error {
 	if len(args) != 0 {
 		return fmt.Errorf("this command takes no arguments")
 	}
+	if c.liveFees {
+		// An explicit fee-pct flag always overrides the live fees.
+		explicit := false
+		c.fset.Visit(func(f *flag.Flag) {
+			explicit = explicit || f.Name == "fee-pct"
+		})
+		if !explicit {
+			pct, err := cmdutil.LiveMakerFeePct(ctx, &c.ClientFlags, c.exchange)
+			if err != nil {
+				return err
+			}
+			log.Printf("using live maker fee percentage %s from the exchange", pct)
+			c.spec.feePercentage = pct.InexactFloat64()
+		}
+	}
 	if err := c.check(); err != nil {
 		return err
 	}
@@ -77,12 +97,20 @@ func (c *Add) Run(ctx context.Context, args []string) error {
 		ProductID:    c.product,
 		ExchangeName: c.exchange,
 		Pairs:        pairs,
+		Validate:     c.validate,
+		FeePct:       c.spec.feePercentage,
 	}
 	resp1, err := cmdutil.Post[api.WallResponse](ctx, &c.ClientFlags, api.WallPath, req1)
 	if err != nil {
 		return err
 	}
 
+	if c.validate {
+		fmt.Printf("Budget: %s\n", resp1.Budget.StringFixed(3))
+		fmt.Printf("NumPairs: %d\n", resp1.NumPairs)
+		return nil
+	}
+
 	req2 := &api.SetJobNameRequest{
 		UID:     resp1.UID,
 		JobName: c.name,
@@ -101,9 +129,12 @@ func (c *Add) Command() (*flag.FlagSet, cli.CmdFunc) {
 	c.ClientFlags.SetFlags(fset)
 	c.spec.SetFlags(fset)
 	fset.BoolVar(&c.dryRun, "dry-run", false, "when true only prints the trade points")
+	fset.BoolVar(&c.validate, "validate", false, "when true only validates the job with the server")
 	fset.StringVar(&c.name, "name", "", "a name for the trader job")
 	fset.StringVar(&c.product, "product", "", "product id for the trader")
 	fset.StringVar(&c.exchange, "exchange", "coinbase", "exchange name for the product")
+	fset.BoolVar(&c.liveFees, "live-fees", false, "when true, uses the maker fee from the exchange unless fee-pct is given")
+	c.fset = fset
 	return fset, cli.CmdFunc(c.Run)
 }
 
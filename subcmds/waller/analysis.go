@@ -6,10 +6,14 @@ import (
 	"fmt"
 
 	"github.com/bvk/tradebot/waller"
+	"github.com/shopspring/decimal"
 )
 
-func PrintAnalysis(a *waller.Analysis) {
+// PrintAnalysis prints the analysis along with the maximum capital at risk,
+// which is the amount lost when all buys fill and the price goes to zero.
+func PrintAnalysis(a *waller.Analysis, maxAtRisk decimal.Decimal) {
 	fmt.Printf("Budget required: %s\n", a.Budget().StringFixed(2))
+	fmt.Printf("Max capital at risk: %s (lost entirely if all buys fill and the price goes to zero)\n", maxAtRisk.StringFixed(2))
 	fmt.Printf("Fee percentage: %.2f%%\n", a.FeePct())
 
 	fmt.Println()
@@ -0,0 +1,125 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/coinbase"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvk/tradebot/waller"
+)
+
+type Analyze struct {
+	cmdutil.DBFlags
+
+	spec Spec
+
+	days int
+}
+
+func (c *Analyze) run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one (product-id) argument")
+	}
+	productID := args[0]
+
+	if err := c.spec.Check(); err != nil {
+		return err
+	}
+	if c.days <= 0 {
+		return fmt.Errorf("days flag must be positive")
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	end := time.Now()
+	begin := end.Add(-time.Duration(c.days) * 24 * time.Hour)
+
+	var candles []*gobs.Candle
+	collect := func(c *gobs.Candle) error {
+		candles = append(candles, c)
+		return nil
+	}
+	datastore := coinbase.NewDatastore(db)
+	if err := datastore.ScanCandles(ctx, productID, begin, end, collect); err != nil {
+		return fmt.Errorf("could not scan candles for product %q: %w", productID, err)
+	}
+	if len(candles) == 0 {
+		return fmt.Errorf("no candles data is found for product %q in the last %d days", productID, c.days)
+	}
+
+	first, last := candles[0], candles[len(candles)-1]
+	ndays := last.StartTime.Time.Add(last.Duration).Sub(first.StartTime.Time).Hours() / 24
+	if ndays <= 0 {
+		return fmt.Errorf("candles data for product %q doesn't cover a time period", productID)
+	}
+
+	pairs := c.spec.BuySellPairs()
+	a := waller.Analyze(pairs, c.spec.feePercentage)
+
+	dailyVol := waller.DailyVolatility(candles)
+	nloops := waller.CountLoops(pairs, candles)
+	perYear := float64(nloops) * 365 / ndays
+
+	fmt.Printf("Num candles: %d (from %s to %s)\n", len(candles), first.StartTime.Time.Format(time.RFC3339), last.StartTime.Time.Format(time.RFC3339))
+	fmt.Printf("Num days: %.2f\n", ndays)
+	fmt.Println()
+	fmt.Printf("Daily volatility: %.2f%%\n", dailyVol*100)
+	fmt.Printf("Annual volatility: %.2f%%\n", dailyVol*math.Sqrt(365)*100)
+	fmt.Println()
+	fmt.Printf("Budget required: %s\n", a.Budget().StringFixed(2))
+	fmt.Printf("Num Buy/Sell pairs: %d\n", a.NumPairs())
+	fmt.Printf("Num loop completions: %d\n", nloops)
+	fmt.Printf("Num sells per month: %.2f\n", perYear/12)
+	fmt.Printf("Num sells per year: %.2f\n", perYear)
+	fmt.Printf("Estimated annual return rate: %s%%\n", a.ReturnRateForNumSells(int(perYear)).StringFixed(3))
+
+	fmt.Println()
+	for _, rate := range aprs {
+		need := a.NumSellsForReturnRate(rate)
+		verdict := "unrealistic"
+		if perYear >= float64(need) {
+			verdict = "realistic"
+		}
+		fmt.Printf("For %.1f%% return: needs %d sells per year, estimated %.2f (%s)\n", rate, need, perYear, verdict)
+	}
+	return nil
+}
+
+func (c *Analyze) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	c.spec.SetFlags(fset)
+	fset.IntVar(&c.days, "days", 90, "number of days of historical candles to analyze")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Analyze) Synopsis() string {
+	return "Estimates waller spec returns from historical candles"
+}
+
+func (c *Analyze) CommandHelp() string {
+	return `
+
+Command "analyze" replays the historical candles data saved in the datastore
+for a product against a hypothetical waller spec. It reports the realized
+volatility of the product and the number of buy-sell loops the spec would have
+completed, which is cross-referenced with the return rates from the "query"
+command to judge if the spec's target returns are realistic.
+
+Limit orders are assumed to be filled when a candle's price range reaches the
+limit price, so estimates are optimistic for thinly traded products.
+
+`
+}
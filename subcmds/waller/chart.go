@@ -0,0 +1,181 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/point"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/shopspring/decimal"
+)
+
+type Chart struct {
+	cmdutil.ClientFlags
+
+	product  string
+	exchange string
+
+	price string
+	width int
+
+	spec Spec
+}
+
+func (c *Chart) run(ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("this command takes at most one (spec-file) argument")
+	}
+	if c.width < 10 {
+		return fmt.Errorf("width must be at least 10 columns")
+	}
+
+	var pairs []*point.Pair
+	if len(args) == 1 {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("could not read spec file: %w", err)
+		}
+		req := new(api.WallRequest)
+		if err := json.Unmarshal(data, req); err != nil {
+			return fmt.Errorf("could not parse spec file: %w", err)
+		}
+		pairs = req.Pairs
+		if len(c.product) == 0 {
+			c.product = req.ProductID
+		}
+		if len(req.ExchangeName) != 0 {
+			c.exchange = req.ExchangeName
+		}
+	} else {
+		if err := c.spec.Check(); err != nil {
+			return err
+		}
+		pairs = c.spec.BuySellPairs()
+	}
+	if len(pairs) == 0 {
+		return fmt.Errorf("no buy/sell pairs to chart")
+	}
+
+	var price decimal.Decimal
+	if len(c.price) != 0 {
+		v, err := decimal.NewFromString(c.price)
+		if err != nil {
+			return fmt.Errorf("could not parse price flag value: %w", err)
+		}
+		price = v
+	} else if len(c.product) != 0 {
+		req := &api.ExchangeGetProductRequest{
+			ExchangeName: c.exchange,
+			ProductID:    c.product,
+		}
+		resp, err := cmdutil.Post[api.ExchangeGetProductResponse](ctx, &c.ClientFlags, api.ExchangeGetProductPath, req)
+		if err != nil {
+			return fmt.Errorf("could not fetch current price (use -price flag to supply it): %w", err)
+		}
+		if len(resp.Error) != 0 {
+			return fmt.Errorf("could not fetch current price: %w", errors.New(resp.Error))
+		}
+		price = resp.Product.Price
+	}
+
+	printChart(os.Stdout, pairs, price, c.width)
+	return nil
+}
+
+// printChart prints one row per buy/sell pair, ordered by the buy price from
+// the highest to lowest, with the span from the buy price (B) to the sell
+// price (S) drawn on a common price axis. Current price, when non-zero, is
+// drawn as a vertical line (|) across all rows.
+func printChart(w io.Writer, pairs []*point.Pair, price decimal.Decimal, width int) {
+	pairs = slices.Clone(pairs)
+	slices.SortFunc(pairs, func(a, b *point.Pair) int {
+		return b.Buy.Price.Cmp(a.Buy.Price)
+	})
+
+	lo, hi := pairs[0].Buy.Price, pairs[0].Sell.Price
+	for _, p := range pairs {
+		lo = decimal.Min(lo, p.Buy.Price)
+		hi = decimal.Max(hi, p.Sell.Price)
+	}
+	if price.IsPositive() {
+		lo = decimal.Min(lo, price)
+		hi = decimal.Max(hi, price)
+	}
+	column := func(v decimal.Decimal) int {
+		if hi.Equal(lo) {
+			return 0
+		}
+		return int(v.Sub(lo).Mul(decimal.NewFromInt(int64(width - 1))).Div(hi.Sub(lo)).Round(0).IntPart())
+	}
+
+	// Header labels the lowest and highest prices at the axis ends.
+	loStr, hiStr := lo.StringFixed(2), hi.StringFixed(2)
+	gap := strings.Repeat(" ", max(1, width-len(loStr)-len(hiStr)))
+	fmt.Fprintf(w, "%12s %12s  %s%s%s\n", "Buy", "Sell", loStr, gap, hiStr)
+
+	above, below := 0, 0
+	for _, p := range pairs {
+		row := []byte(strings.Repeat(" ", width))
+		b, s := column(p.Buy.Price), column(p.Sell.Price)
+		for i := b; i <= s; i++ {
+			row[i] = '-'
+		}
+		row[b], row[s] = 'B', 'S'
+		if price.IsPositive() {
+			if i := column(price); row[i] == ' ' {
+				row[i] = '|'
+			} else {
+				row[i] = '*'
+			}
+			if p.Sell.Price.LessThan(price) {
+				below++
+			} else if p.Buy.Price.GreaterThan(price) {
+				above++
+			}
+		}
+		fmt.Fprintf(w, "%12s %12s  %s\n", p.Buy.Price.StringFixed(2), p.Sell.Price.StringFixed(2), row)
+	}
+
+	if price.IsPositive() {
+		fmt.Fprintf(w, "\nCurrent price %s: %d pairs are above, %d pairs are below and %d pairs bracket the price\n", price.StringFixed(2), above, below, len(pairs)-above-below)
+	}
+}
+
+func (c *Chart) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("chart", flag.ContinueOnError)
+	c.ClientFlags.SetFlags(fset)
+	c.spec.SetFlags(fset)
+	fset.StringVar(&c.product, "product", "", "product id to fetch the current price")
+	fset.StringVar(&c.exchange, "exchange", "coinbase", "exchange name for the product")
+	fset.StringVar(&c.price, "price", "", "current price to mark instead of fetching it from the exchange")
+	fset.IntVar(&c.width, "width", 60, "width of the price axis in columns")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Chart) Synopsis() string {
+	return "Prints the buy/sell pairs of a waller as a text chart"
+}
+
+func (c *Chart) CommandHelp() string {
+	return `
+
+Command "chart" draws the buy/sell pairs on a common price axis, one row per
+pair, with the current market price marked across all rows. Pairs are taken
+from the spec file argument (a JSON encoded waller request, same as the lint
+command) or computed from the spec flags when no argument is given. Current
+price is fetched from the server for the product unless the -price flag is
+given.
+
+`
+}
@@ -11,17 +11,21 @@ import (
 	"text/tabwriter"
 
 	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/coinbase"
 	"github.com/bvk/tradebot/namer"
 	"github.com/bvk/tradebot/server"
 	"github.com/bvk/tradebot/subcmds/cmdutil"
 	"github.com/bvk/tradebot/waller"
 	"github.com/bvkgo/kv"
+	"github.com/shopspring/decimal"
 )
 
 type Get struct {
 	cmdutil.DBFlags
 
 	skipZeroBuys bool
+
+	push cmdutil.PushgatewayFlags
 }
 
 func (c *Get) Run(ctx context.Context, args []string) error {
@@ -62,8 +66,15 @@ func (c *Get) Run(ctx context.Context, args []string) error {
 		return fmt.Errorf("could not load waller (unexpected)")
 	}
 
+	// Base increment is used to print the sizes at product's precision.
+	var baseIncrement decimal.Decimal
+	if imap, err := coinbase.NewDatastore(db).ProductsBaseIncrementMap(ctx); err == nil {
+		baseIncrement = imap[wall.ProductID()]
+	}
+
 	// Print the waller state in a human readable format.
 	s := wall.Status(nil)
+	s.BaseIncrement = baseIncrement
 	fmt.Println("UID", s.UID)
 	fmt.Println("ProductID", s.ProductID)
 	fmt.Println("ExchangeName", s.ExchangeName)
@@ -72,32 +83,43 @@ func (c *Get) Run(ctx context.Context, args []string) error {
 	fmt.Println("ReturnRate", s.ReturnRate().StringFixed(3))
 	fmt.Println("AnnualReturnRate", s.AnnualReturnRate().StringFixed(3))
 	fmt.Println("ProfitPerDay", s.ProfitPerDay().StringFixed(3))
+	fmt.Println("MaxDrawdown", s.MaxDrawdown().StringFixed(3))
 	fmt.Println()
 	fmt.Println("NumDays", s.NumDays())
 	fmt.Println("NumBuys", s.NumBuys)
 	fmt.Println("NumSells", s.NumSells)
 	fmt.Println()
 	fmt.Println("BoughtFees", s.BoughtFees.StringFixed(3))
-	fmt.Println("BoughtSize", s.BoughtSize.StringFixed(3))
+	fmt.Println("BoughtSize", s.SizeString(s.BoughtSize))
 	fmt.Println("BoughtValue", s.BoughtValue.StringFixed(3))
 	fmt.Println()
 	fmt.Println("SoldFees", s.SoldFees.StringFixed(3))
-	fmt.Println("SoldSize", s.SoldSize.StringFixed(3))
+	fmt.Println("SoldSize", s.SizeString(s.SoldSize))
 	fmt.Println("SoldValue", s.SoldValue.StringFixed(3))
 	fmt.Println()
 	fmt.Println("UnsoldFees", s.UnsoldFees.StringFixed(3))
-	fmt.Println("UnsoldSize", s.UnsoldSize.StringFixed(3))
+	fmt.Println("UnsoldSize", s.SizeString(s.UnsoldSize))
 	fmt.Println("UnsoldValue", s.UnsoldValue.StringFixed(3))
 	fmt.Println()
 	fmt.Println("OversoldFees", s.OversoldFees.StringFixed(3))
-	fmt.Println("OversoldSize", s.OversoldSize.StringFixed(3))
+	fmt.Println("OversoldSize", s.SizeString(s.OversoldSize))
 	fmt.Println("OversoldValue", s.OversoldValue.StringFixed(3))
+	if !s.BoughtResidualSize.IsZero() || !s.SoldResidualSize.IsZero() {
+		fmt.Println()
+		fmt.Println("BoughtResidualSize", s.SizeString(s.BoughtResidualSize))
+		fmt.Println("SoldResidualSize", s.SizeString(s.SoldResidualSize))
+	}
+	if len(s.OtherFees) != 0 {
+		fmt.Println()
+		fmt.Println("OtherFees", s.OtherFeesString())
+	}
 
 	fmt.Println()
 	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
 	fmt.Fprintf(tw, "Pair\tBudget\tReturn\tAnnualReturn\tDays\tBuys\tSells\tProfit\tFees\tBoughtValue\tSoldValue\tUnsoldValue\tSoldSize\tUnsoldSize\t\n")
 	for _, p := range wall.Pairs() {
 		s := wall.PairStatus(p, nil)
+		s.BaseIncrement = baseIncrement
 		if c.skipZeroBuys && s.NumBuys == 0 {
 			continue
 		}
@@ -115,10 +137,15 @@ func (c *Get) Run(ctx context.Context, args []string) error {
 			s.Bought().StringFixed(3),
 			s.Sold().StringFixed(3),
 			s.UnsoldValue.StringFixed(3),
-			s.SoldSize.Sub(s.OversoldSize).StringFixed(3),
-			s.UnsoldSize.StringFixed(3))
+			s.SizeString(s.SoldSize.Sub(s.OversoldSize)),
+			s.SizeString(s.UnsoldSize))
 	}
 	tw.Flush()
+
+	labels := map[string]string{"waller": wall.UID(), "product": wall.ProductID()}
+	if err := c.push.Push(ctx, s.Summary, labels); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -126,6 +153,7 @@ func (c *Get) Command() (*flag.FlagSet, cli.CmdFunc) {
 	fset := flag.NewFlagSet("get", flag.ContinueOnError)
 	c.DBFlags.SetFlags(fset)
 	fset.BoolVar(&c.skipZeroBuys, "skip-zero-buys", true, "when true, doesn't print inactive pairs")
+	c.push.SetFlags(fset)
 	return fset, cli.CmdFunc(c.Run)
 }
 
@@ -0,0 +1,112 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/waller"
+	"github.com/shopspring/decimal"
+)
+
+type Lint struct {
+	baseMinSize    string
+	baseMaxSize    string
+	baseIncrement  string
+	priceIncrement string
+
+	maxBudget string
+}
+
+func (c *Lint) run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one (spec-file) argument")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("could not read spec file: %w", err)
+	}
+	spec := new(api.WallRequest)
+	if err := json.Unmarshal(data, spec); err != nil {
+		return fmt.Errorf("could not parse spec file: %w", err)
+	}
+
+	parse := func(name, value string) (decimal.Decimal, error) {
+		if len(value) == 0 {
+			return decimal.Zero, nil
+		}
+		v, err := decimal.NewFromString(value)
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("could not parse %s flag value: %w", name, err)
+		}
+		if v.IsNegative() {
+			return decimal.Zero, fmt.Errorf("%s flag value cannot be negative", name)
+		}
+		return v, nil
+	}
+
+	product := &gobs.Product{ProductID: spec.ProductID}
+	if product.BaseMinSize, err = parse("base-min-size", c.baseMinSize); err != nil {
+		return err
+	}
+	if product.BaseMaxSize, err = parse("base-max-size", c.baseMaxSize); err != nil {
+		return err
+	}
+	if product.BaseIncrement, err = parse("base-increment", c.baseIncrement); err != nil {
+		return err
+	}
+	if product.QuoteIncrement, err = parse("price-increment", c.priceIncrement); err != nil {
+		return err
+	}
+	opts := &waller.LintOptions{
+		Product: product,
+		FeePct:  spec.FeePct,
+	}
+	if opts.MaxBudget, err = parse("max-budget", c.maxBudget); err != nil {
+		return err
+	}
+
+	issues := waller.Lint(spec.Pairs, opts)
+	for _, v := range issues {
+		fmt.Println(v)
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("found %d issues in the spec file", len(issues))
+	}
+	fmt.Printf("%d buy/sell pairs are valid\n", len(spec.Pairs))
+	return nil
+}
+
+func (c *Lint) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fset.StringVar(&c.baseMinSize, "base-min-size", "", "min order size for the product")
+	fset.StringVar(&c.baseMaxSize, "base-max-size", "", "max order size for the product")
+	fset.StringVar(&c.baseIncrement, "base-increment", "", "order size increment for the product")
+	fset.StringVar(&c.priceIncrement, "price-increment", "", "price increment for the product")
+	fset.StringVar(&c.maxBudget, "max-budget", "", "max budget allowed for the waller")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Lint) Synopsis() string {
+	return "Validates buy/sell pairs in a waller spec file"
+}
+
+func (c *Lint) CommandHelp() string {
+	return `
+
+Command "lint" runs all validations on the buy/sell pairs of a waller spec
+file and prints every issue with the offending pair index. Spec file must be
+a JSON encoded waller request with the ProductID, FeePct and Pairs fields.
+Product checks are performed only for the properties given as flags. Command
+fails when any issue is found, so it can be used to gate the deployments.
+
+`
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/point"
+	"github.com/shopspring/decimal"
+)
+
+func TestLintCommand(t *testing.T) {
+	ctx := context.Background()
+	d := decimal.RequireFromString
+
+	writeSpec := func(pairs ...*point.Pair) string {
+		spec := &api.WallRequest{ProductID: "BTC-USD", FeePct: 0.25, Pairs: pairs}
+		data, err := json.Marshal(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := filepath.Join(t.TempDir(), "spec.json")
+		if err := os.WriteFile(file, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	good := &point.Pair{
+		Buy:  point.Point{Size: d("1"), Price: d("100"), Cancel: d("105")},
+		Sell: point.Point{Size: d("1"), Price: d("110"), Cancel: d("105")},
+	}
+	small := &point.Pair{
+		Buy:  point.Point{Size: d("0.0001"), Price: d("120"), Cancel: d("125")},
+		Sell: point.Point{Size: d("0.0001"), Price: d("130"), Cancel: d("125")},
+	}
+
+	c := new(Lint)
+	fset, run := c.Command()
+	if err := fset.Parse([]string{"-base-min-size=0.001"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(ctx, []string{writeSpec(good)}); err != nil {
+		t.Fatalf("want no issues for a valid spec, got %v", err)
+	}
+	if err := run(ctx, []string{writeSpec(good, small)}); err == nil {
+		t.Fatalf("want failure for the pair below the product min size")
+	}
+
+	c = new(Lint)
+	fset, run = c.Command()
+	if err := fset.Parse([]string{"-max-budget=-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(ctx, []string{writeSpec(good)}); err == nil {
+		t.Fatalf("want failure for a negative flag value")
+	}
+}
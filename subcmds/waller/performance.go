@@ -0,0 +1,133 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/server"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvk/tradebot/waller"
+	"github.com/bvkgo/kv"
+	"github.com/shopspring/decimal"
+)
+
+type Performance struct {
+	cmdutil.DBFlags
+
+	push cmdutil.PushgatewayFlags
+}
+
+func (c *Performance) Run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one waller argument")
+	}
+	arg := args[0]
+
+	var wall *waller.Waller
+	getter := func(ctx context.Context, r kv.Reader) error {
+		_, uid, _, err := namer.Resolve(ctx, r, arg)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("could not resolve waller argument %q: %w", arg, err)
+			}
+			uid = arg
+		}
+
+		job, err := server.Load(ctx, r, uid, "waller")
+		if err != nil {
+			return fmt.Errorf("could not load waller from db: %w", err)
+		}
+		wall = job.(*waller.Waller)
+		return nil
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	if err := kv.WithReader(ctx, db, getter); err != nil {
+		return err
+	}
+	if wall == nil {
+		return fmt.Errorf("could not load waller (unexpected)")
+	}
+
+	p := wall.Performance()
+	s, a := p.Actual, p.Analysis
+
+	fmt.Println("UID", wall.UID())
+	fmt.Println("ProductID", wall.ProductID())
+	fmt.Println()
+	fmt.Println("NumDays", s.NumDays().StringFixed(2))
+	fmt.Println("NumLoops", s.NumLoops)
+	fmt.Println("LoopsPerYear", p.LoopsPerYear().StringFixed(2))
+	fmt.Println("FeePct", s.FeePct().StringFixed(3))
+	fmt.Println()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Metric\tActual\tExpected\tVariance\tVariance%%\t\n")
+	row := func(name string, actual, expected decimal.Decimal) {
+		diff := actual.Sub(expected)
+		pct := "-"
+		if !expected.IsZero() {
+			pct = diff.Mul(decimal.NewFromInt(100)).Div(expected).StringFixed(3) + "%"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n", name, actual.StringFixed(3), expected.StringFixed(3), diff.StringFixed(3), pct)
+	}
+	row("Budget", s.Budget, a.Budget())
+	row("Profit", s.Profit(), p.ExpectedProfit())
+	row("ProfitPerLoop", s.ProfitPerLoop(), a.AvgProfitMargin())
+	row("AnnualReturnRate", s.AnnualReturnRate(), p.ExpectedAnnualReturnRate())
+	tw.Flush()
+
+	fmt.Println()
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "TargetReturn\tLoopsPerYear\t\n")
+	for _, rate := range aprs {
+		fmt.Fprintf(tw, "%.1f%%\t%d\t\n", rate, a.NumSellsForReturnRate(rate))
+	}
+	tw.Flush()
+
+	labels := map[string]string{"waller": wall.UID(), "product": wall.ProductID()}
+	if err := c.push.Push(ctx, s, labels); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Performance) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("performance", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	c.push.SetFlags(fset)
+	return fset, cli.CmdFunc(c.Run)
+}
+
+func (c *Performance) Synopsis() string {
+	return "Compares actual and expected returns of a waller"
+}
+
+func (c *Performance) CommandHelp() string {
+	return `
+
+Command "performance" prints the actual realized returns of a waller job along
+with the returns expected by the "query" command for it's buy-sell pairs.
+
+Expected profit and the annual return rate are computed at the average profit
+margin of the pairs for the actual number of completed loops, so the variance
+shows whether the loops are completing at the modeled margins. Fees for the
+expected returns are computed at the effective fee percentage of the actual
+fills. Number of loops per year required for the target return rates is
+printed after the actual loop rate.
+
+`
+}
@@ -5,6 +5,7 @@ package waller
 import (
 	"context"
 	"flag"
+	"fmt"
 
 	"github.com/bvk/tradebot/cli"
 	"github.com/bvk/tradebot/waller"
@@ -23,7 +24,13 @@ func (c *Query) run(ctx context.Context, args []string) error {
 	pairs := c.spec.BuySellPairs()
 	feePct := c.spec.feePercentage
 	a := waller.Analyze(pairs, feePct)
-	PrintAnalysis(a)
+	PrintAnalysis(a, c.spec.MaxCapitalAtRisk())
+	if n := c.spec.NumTooClose(); n > 0 {
+		fmt.Printf("\nDropped %d pairs with buy price closer than the min price gap %v\n", n, c.spec.minPriceGap)
+	}
+	if n := c.spec.NumExcluded(); n > 0 {
+		fmt.Printf("\nExcluded %d pairs below the min profit margin\n", n)
+	}
 	return nil
 }
 
@@ -47,6 +54,8 @@ a wall job without actually spending the funds in an exchange.
 Users can get the following information for a waller job:
 
   - Total budget required for the job
+  - Maximum capital at risk, i.e., the downside exposure when all buys fill
+    and the price goes to zero
   - Average fee for each buy-sell loop
 
   - Number of sells required per month for returns at 5%, 10%, etc.
@@ -0,0 +1,142 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/paper"
+	"github.com/bvk/tradebot/trader"
+	"github.com/bvk/tradebot/waller"
+	"github.com/bvkgo/kv/kvmemdb"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var errSimDone = errors.New("simulation is complete")
+
+type nopMessenger struct{}
+
+func (nopMessenger) SendMessage(context.Context, time.Time, string, ...interface{}) {}
+
+type Sim struct {
+	spec Spec
+
+	product     string
+	tickerFile  string
+	baseMinSize float64
+}
+
+func (c *Sim) run(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("this command takes no arguments")
+	}
+	if err := c.spec.Check(); err != nil {
+		return err
+	}
+	if len(c.tickerFile) == 0 {
+		return fmt.Errorf("ticker-file flag is required")
+	}
+
+	fp, err := os.Open(c.tickerFile)
+	if err != nil {
+		return fmt.Errorf("could not open ticker file: %w", err)
+	}
+	defer fp.Close()
+
+	tickers, err := paper.ReadTickers(fp)
+	if err != nil {
+		return fmt.Errorf("could not read tickers from %q: %w", c.tickerFile, err)
+	}
+
+	pairs := c.spec.BuySellPairs()
+	wall, err := waller.New(uuid.NewString(), paper.ExchangeName, c.product, pairs)
+	if err != nil {
+		return fmt.Errorf("could not create waller: %w", err)
+	}
+
+	product := paper.New(c.product, &paper.Options{
+		FeePct:      c.spec.feePercentage,
+		BaseMinSize: decimal.NewFromFloat(c.baseMinSize),
+	})
+	rt := &trader.Runtime{
+		Database:  kvmemdb.New(),
+		Product:   product,
+		Messenger: nopMessenger{},
+	}
+
+	simCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(errSimDone)
+
+	doneCh := make(chan error, 1)
+	go func() {
+		err := wall.Run(simCtx, rt)
+		cancel(err)
+		doneCh <- err
+	}()
+
+	for _, ticker := range tickers {
+		// Every looper has one ticker subscription at a time, so the next ticker
+		// is fed only after the loopers, including the limiters that replace the
+		// filled ones, have processed the last ticker and subscribed again. This
+		// keeps the simulation deterministic.
+		if err := product.WaitTickerSubscribers(simCtx, len(pairs)); err != nil {
+			break
+		}
+		if err := product.FeedTicker(simCtx, ticker); err != nil {
+			break
+		}
+	}
+	cancel(errSimDone)
+	if err := <-doneCh; err != nil && !errors.Is(err, errSimDone) {
+		return err
+	}
+
+	s := wall.Status(nil)
+	fmt.Println("NumTickers", len(tickers))
+	fmt.Println("NumPairs", len(pairs))
+	fmt.Println()
+	fmt.Println("Budget", s.Budget.StringFixed(3))
+	fmt.Println("NumBuys", s.NumBuys)
+	fmt.Println("NumSells", s.NumSells)
+	fmt.Println("Profit", s.Profit().StringFixed(3))
+	fmt.Println("Fees", s.Fees().StringFixed(3))
+	fmt.Println()
+	fmt.Println("BoughtValue", s.BoughtValue.StringFixed(3))
+	fmt.Println("SoldValue", s.SoldValue.StringFixed(3))
+	fmt.Println("UnsoldSize", s.UnsoldSize.StringFixed(3))
+	fmt.Println("UnsoldValue", s.UnsoldValue.StringFixed(3))
+	return nil
+}
+
+func (c *Sim) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("sim", flag.ContinueOnError)
+	c.spec.SetFlags(fset)
+	fset.StringVar(&c.product, "product", "BTC-USD", "product id for the simulation")
+	fset.StringVar(&c.tickerFile, "ticker-file", "", "file with recorded tickers")
+	fset.Float64Var(&c.baseMinSize, "base-min-size", 0, "min order size for the simulated product")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Sim) Synopsis() string {
+	return "Simulates a waller spec against a recorded ticker stream"
+}
+
+func (c *Sim) CommandHelp() string {
+	return `
+
+Command "sim" replays a recorded tick-by-tick ticker stream through the actual
+limiter and looper jobs of a waller spec using a paper exchange, which fills
+limit orders completely at their limit price when the ticker price crosses
+them. Ticker file must have one ticker per line, either with a RFC3339
+timestamp and the price separated by a space or as a JSON line recorded by the
+"coinbase record-ticks" command.
+
+`
+}
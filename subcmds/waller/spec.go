@@ -15,6 +15,8 @@ import (
 type Spec struct {
 	feePercentage float64
 
+	tag string
+
 	beginPriceRange float64
 	endPriceRange   float64
 
@@ -27,7 +29,19 @@ type Spec struct {
 
 	cancelOffset float64
 
+	minProfitMargin float64
+
+	minPriceGap float64
+
 	pairs []*point.Pair
+
+	// numExcluded holds the number of pairs dropped cause their profit margin
+	// after fees is below the minProfitMargin.
+	numExcluded int
+
+	// numTooClose holds the number of pairs dropped cause their buy price is
+	// closer than the minPriceGap to the previous buy price.
+	numTooClose int
 }
 
 func (s *Spec) SetFlags(fset *flag.FlagSet) {
@@ -40,12 +54,38 @@ func (s *Spec) SetFlags(fset *flag.FlagSet) {
 	fset.Float64Var(&s.sellSize, "sell-size", 0, "asset sell-size for the trade")
 	fset.Float64Var(&s.cancelOffset, "cancel-offset", 50, "cancel-at price offset for the buy/sell points")
 	fset.Float64Var(&s.feePercentage, "fee-pct", 0.25, "exchange fee percentage to adjust sell margin")
+	fset.Float64Var(&s.minProfitMargin, "min-profit-margin", 0, "minimum profit after fees for a buy/sell pair to be included")
+	fset.StringVar(&s.tag, "tag", "", "optional strategy tag for the buy/sell pairs, to attribute profits in a mixed waller")
+	fset.Float64Var(&s.minPriceGap, "min-price-gap", 0.01, "minimum gap between successive buy prices (typically the product's price increment)")
 }
 
 func (s *Spec) BuySellPairs() []*point.Pair {
 	return s.pairs
 }
 
+// NumExcluded returns the number of buy/sell pairs dropped for not meeting the
+// minimum profit margin.
+func (s *Spec) NumExcluded() int {
+	return s.numExcluded
+}
+
+// NumTooClose returns the number of buy/sell pairs dropped for being closer
+// than the minimum price gap to their previous pair.
+func (s *Spec) NumTooClose() int {
+	return s.numTooClose
+}
+
+// MaxCapitalAtRisk returns the worst-case loss for the spec, which happens
+// when all buys are filled and the price goes to zero. It is the total value
+// of all buys plus the buy fees already paid for them.
+func (s *Spec) MaxCapitalAtRisk() decimal.Decimal {
+	var sum decimal.Decimal
+	for _, p := range s.pairs {
+		sum = sum.Add(p.Buy.Value()).Add(p.Buy.FeeAt(s.feePercentage))
+	}
+	return sum
+}
+
 func (s *Spec) setDefaults() {
 }
 
@@ -84,6 +124,12 @@ func (s *Spec) Check() error {
 	if s.feePercentage < 0 || s.feePercentage >= 100 {
 		return fmt.Errorf("fee percentage should be in between 0-100")
 	}
+	if s.minProfitMargin < 0 {
+		return fmt.Errorf("min profit margin cannot be negative")
+	}
+	if s.minPriceGap < 0 {
+		return fmt.Errorf("min price gap cannot be negative")
+	}
 
 	if s.profitMargin > 0 {
 		pairs := fixedProfitPairs(s)
@@ -100,9 +146,51 @@ func (s *Spec) Check() error {
 		s.pairs = pairs
 	}
 
+	for _, p := range s.pairs {
+		p.Buy.Tag, p.Sell.Tag = s.tag, s.tag
+	}
+
+	if s.minPriceGap > 0 {
+		s.pairs, s.numTooClose = filterClosePairs(s.pairs, decimal.NewFromFloat(s.minPriceGap))
+	}
+
+	if s.minProfitMargin > 0 {
+		s.pairs, s.numExcluded = filterProfitPairs(s.pairs, s.feePercentage, decimal.NewFromFloat(s.minProfitMargin))
+		if len(s.pairs) == 0 {
+			return fmt.Errorf("no buy/sell pairs meet the min profit margin")
+		}
+	}
 	return nil
 }
 
+// filterProfitPairs returns the pairs with a profit margin after the fees
+// that is at least the given minimum and the number of pairs dropped.
+func filterProfitPairs(pairs []*point.Pair, feePct float64, min decimal.Decimal) ([]*point.Pair, int) {
+	var keep []*point.Pair
+	for _, p := range pairs {
+		if p.ValueMargin().Sub(p.FeesAt(feePct)).LessThan(min) {
+			continue
+		}
+		keep = append(keep, p)
+	}
+	return keep, len(pairs) - len(keep)
+}
+
+// filterClosePairs returns the pairs sorted by buy price with the pairs whose
+// buy price is closer than the gap to the previous kept pair removed, and the
+// number of pairs dropped. Such pairs would result in effectively duplicate
+// orders at the exchange's price increment.
+func filterClosePairs(pairs []*point.Pair, gap decimal.Decimal) ([]*point.Pair, int) {
+	var keep []*point.Pair
+	for _, p := range pairs {
+		if n := len(keep); n > 0 && p.Buy.Price.Sub(keep[n-1].Buy.Price).LessThan(gap) {
+			continue
+		}
+		keep = append(keep, p)
+	}
+	return keep, len(pairs) - len(keep)
+}
+
 func fixedProfitPairs(s *Spec) []*point.Pair {
 	var pairs []*point.Pair
 	for price := s.beginPriceRange; price < s.endPriceRange; price += s.buyInterval {
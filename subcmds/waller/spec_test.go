@@ -0,0 +1,40 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestSpecMaxCapitalAtRisk(t *testing.T) {
+	spec := new(Spec)
+	fset := flag.NewFlagSet("test", flag.ContinueOnError)
+	spec.SetFlags(fset)
+	args := []string{
+		"-begin-price=100",
+		"-end-price=120",
+		"-buy-interval=10",
+		"-buy-size=1",
+		"-sell-size=1",
+		"-profit-margin=5",
+		"-fee-pct=0.25",
+	}
+	if err := fset.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	if err := spec.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Buys at 100 and 110 for one unit each, plus 0.25% buy fees.
+	if n := len(spec.BuySellPairs()); n != 2 {
+		t.Fatalf("want 2 buy/sell pairs, got %d", n)
+	}
+	want := decimal.RequireFromString("210.525")
+	if v := spec.MaxCapitalAtRisk(); !v.Equal(want) {
+		t.Fatalf("want max capital at risk %s, got %s", want, v)
+	}
+}
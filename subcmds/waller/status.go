@@ -0,0 +1,112 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvk/tradebot/waller"
+	"github.com/shopspring/decimal"
+)
+
+type Status struct {
+	cmdutil.DBFlags
+}
+
+func (c *Status) Run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one waller argument")
+	}
+	arg := args[0]
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create database client: %w", err)
+	}
+	defer closer()
+
+	_, uid, _, err := namer.ResolveDB(ctx, db, arg)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not resolve waller argument %q: %w", arg, err)
+		}
+		uid = arg
+	}
+
+	req := &api.WallerStatusRequest{UID: uid}
+	resp, err := cmdutil.Post[api.WallerStatusResponse](ctx, &c.ClientFlags, api.WallerStatusPath, req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("UID", resp.UID)
+	fmt.Println("ProductID", resp.ProductID)
+	if resp.InventorySize.IsPositive() {
+		fmt.Println("Inventory", resp.InventorySize, "at average cost", resp.InventoryAvgCost.StringFixed(3))
+	}
+	if resp.MarketPrice.IsPositive() {
+		e := &waller.Efficiency{ActiveBudget: resp.ActiveBudget, IdleBudget: resp.IdleBudget}
+		fmt.Println("MarketPrice", resp.MarketPrice.StringFixed(3))
+		fmt.Printf("Active %d loops with budget %s (%s%%) and idle budget %s\n", resp.NumActive, resp.ActiveBudget.StringFixed(3), e.ActivePct().StringFixed(1), resp.IdleBudget.StringFixed(3))
+	}
+	fmt.Println()
+
+	var tags []string
+	tagProfits := make(map[string]decimal.Decimal)
+	for _, v := range resp.Loops {
+		tag := v.Pair.Buy.Tag
+		if _, ok := tagProfits[tag]; !ok {
+			tags = append(tags, tag)
+		}
+		tagProfits[tag] = tagProfits[tag].Add(v.Profit)
+	}
+	sort.Strings(tags)
+	hasTags := len(tags) > 1 || (len(tags) == 1 && tags[0] != "")
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	if hasTags {
+		fmt.Fprintf(tw, "Pair\tTag\tState\tBuys\tSells\tProfit\t\n")
+	} else {
+		fmt.Fprintf(tw, "Pair\tState\tBuys\tSells\tProfit\t\n")
+	}
+	for _, v := range resp.Loops {
+		id := fmt.Sprintf("%s-%s", v.Pair.Buy.Price.StringFixed(2), v.Pair.Sell.Price.StringFixed(2))
+		if hasTags {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\t\n", id, v.Pair.Buy.Tag, v.State, v.NumBuys, v.NumSells, v.Profit.StringFixed(3))
+		} else {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t\n", id, v.State, v.NumBuys, v.NumSells, v.Profit.StringFixed(3))
+		}
+	}
+	tw.Flush()
+
+	if hasTags {
+		fmt.Println()
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "Tag\tProfit\t\n")
+		for _, tag := range tags {
+			fmt.Fprintf(tw, "%s\t%s\t\n", tag, tagProfits[tag].StringFixed(3))
+		}
+		tw.Flush()
+	}
+	return nil
+}
+
+func (c *Status) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("status", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	return fset, cli.CmdFunc(c.Run)
+}
+
+func (c *Status) Synopsis() string {
+	return "Prints the per-loop status of a waller"
+}
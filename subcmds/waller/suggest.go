@@ -0,0 +1,228 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/coinbase"
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvk/tradebot/waller"
+	"github.com/shopspring/decimal"
+)
+
+type Suggest struct {
+	cmdutil.DBFlags
+
+	loopsPerDay float64
+	budget      float64
+
+	days         int
+	feePct       float64
+	minPriceGap  float64
+	cancelOffset float64
+
+	maxMarginPct  float64
+	marginPctStep float64
+
+	specFile string
+}
+
+// suggestion holds the spec and the loop rate estimated for a profit margin
+// percentage.
+type suggestion struct {
+	marginPct   float64
+	spec        *Spec
+	loopsPerDay float64
+}
+
+func (c *Suggest) run(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("this command takes one (product-id) argument")
+	}
+	productID := args[0]
+
+	if c.loopsPerDay <= 0 {
+		return fmt.Errorf("loops-per-day flag must be positive")
+	}
+	if c.budget <= 0 {
+		return fmt.Errorf("budget flag must be positive")
+	}
+	if c.days <= 0 {
+		return fmt.Errorf("days flag must be positive")
+	}
+	if c.marginPctStep <= 0 || c.maxMarginPct < c.marginPctStep {
+		return fmt.Errorf("margin-pct-step must be positive and not more than max-margin-pct")
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	end := time.Now()
+	begin := end.Add(-time.Duration(c.days) * 24 * time.Hour)
+
+	var candles []*gobs.Candle
+	collect := func(c *gobs.Candle) error {
+		candles = append(candles, c)
+		return nil
+	}
+	datastore := coinbase.NewDatastore(db)
+	if err := datastore.ScanCandles(ctx, productID, begin, end, collect); err != nil {
+		return fmt.Errorf("could not scan candles for product %q: %w", productID, err)
+	}
+	if len(candles) == 0 {
+		return fmt.Errorf("no candles data is found for product %q in the last %d days", productID, c.days)
+	}
+
+	first, last := candles[0], candles[len(candles)-1]
+	ndays := last.StartTime.Time.Add(last.Duration).Sub(first.StartTime.Time).Hours() / 24
+	if ndays <= 0 {
+		return fmt.Errorf("candles data for product %q doesn't cover a time period", productID)
+	}
+
+	low, high := first.Low, first.High
+	for _, c := range candles {
+		low, high = decimal.Min(low, c.Low), decimal.Max(high, c.High)
+	}
+	lowPrice, _ := low.Float64()
+	highPrice, _ := high.Float64()
+
+	// Loop counts do not depend on the buy size, so the candidate specs use an
+	// unit size and the chosen spec is scaled to the budget at the end.
+	var best, fallback *suggestion
+	for pct := c.marginPctStep; pct <= c.maxMarginPct+1e-9; pct += c.marginPctStep {
+		spec := c.newSpec(lowPrice, highPrice, pct, 1)
+		if err := spec.Check(); err != nil {
+			continue
+		}
+		nloops := waller.CountLoops(spec.BuySellPairs(), candles)
+		s := &suggestion{marginPct: pct, spec: spec, loopsPerDay: float64(nloops) / ndays}
+		if fallback == nil || s.loopsPerDay > fallback.loopsPerDay {
+			fallback = s
+		}
+		// Larger margins make more profit per loop, so the largest margin that
+		// meets the target loop rate is preferred.
+		if s.loopsPerDay >= c.loopsPerDay {
+			best = s
+		}
+	}
+	if fallback == nil {
+		return fmt.Errorf("could not create any buy/sell pairs for the price range %s-%s", low, high)
+	}
+	if best == nil {
+		fmt.Printf("WARNING: no spacing reaches %.2f loops per day; using the spacing with the most loops\n\n", c.loopsPerDay)
+		best = fallback
+	}
+
+	unit := waller.Analyze(best.spec.BuySellPairs(), c.feePct).Budget()
+	size, _ := decimal.NewFromFloat(c.budget).Div(unit).RoundDown(8).Float64()
+	if size <= 0 {
+		return fmt.Errorf("budget %.2f is too small for %d buy/sell pairs", c.budget, len(best.spec.BuySellPairs()))
+	}
+	spec := c.newSpec(lowPrice, highPrice, best.marginPct, size)
+	if err := spec.Check(); err != nil {
+		return fmt.Errorf("could not create buy/sell pairs for the suggested spacing: %w", err)
+	}
+	pairs := spec.BuySellPairs()
+	a := waller.Analyze(pairs, c.feePct)
+
+	fmt.Printf("Num days: %.2f\n", ndays)
+	fmt.Printf("Price range: %s - %s\n", low.StringFixed(2), high.StringFixed(2))
+	fmt.Printf("Daily volatility: %.2f%%\n", waller.DailyVolatility(candles)*100)
+	fmt.Println()
+	fmt.Printf("Profit margin: %.2f%%\n", best.marginPct)
+	fmt.Printf("Buy interval: %.8f\n", spec.buyInterval)
+	fmt.Printf("Buy size: %.8f\n", size)
+	fmt.Printf("Num Buy/Sell pairs: %d\n", a.NumPairs())
+	fmt.Printf("Budget required: %s\n", a.Budget().StringFixed(2))
+	fmt.Printf("Estimated loops per day: %.2f\n", best.loopsPerDay)
+
+	req := &api.WallRequest{
+		ExchangeName: "coinbase",
+		ProductID:    productID,
+		Pairs:        pairs,
+		FeePct:       c.feePct,
+	}
+	data, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal spec: %w", err)
+	}
+	if len(c.specFile) == 0 {
+		fmt.Println()
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := os.WriteFile(c.specFile, data, 0644); err != nil {
+		return fmt.Errorf("could not write spec file: %w", err)
+	}
+	fmt.Printf("\nSpec is saved to %s\n", c.specFile)
+	return nil
+}
+
+// newSpec returns a spec covering the price range with buy points spaced by
+// the profit margin percentage of the mid price.
+func (c *Suggest) newSpec(low, high, marginPct, size float64) *Spec {
+	interval := (low + high) / 2 * marginPct / 100
+	cancelOffset := c.cancelOffset
+	if cancelOffset <= 0 {
+		cancelOffset = interval
+	}
+	return &Spec{
+		feePercentage:   c.feePct,
+		beginPriceRange: low,
+		endPriceRange:   high,
+		buyInterval:     interval,
+		profitMarginPct: marginPct,
+		buySize:         size,
+		sellSize:        size,
+		cancelOffset:    cancelOffset,
+		minPriceGap:     c.minPriceGap,
+	}
+}
+
+func (c *Suggest) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("suggest", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.Float64Var(&c.loopsPerDay, "loops-per-day", 1, "target number of buy-sell loops per day")
+	fset.Float64Var(&c.budget, "budget", 0, "max budget for the waller")
+	fset.IntVar(&c.days, "days", 90, "number of days of historical candles to use")
+	fset.Float64Var(&c.feePct, "fee-pct", 0.25, "exchange fee percentage to adjust sell margin")
+	fset.Float64Var(&c.minPriceGap, "min-price-gap", 0.01, "minimum gap between successive buy prices (typically the product's price increment)")
+	fset.Float64Var(&c.cancelOffset, "cancel-offset", 0, "cancel-at price offset for the buy/sell points (defaults to the buy interval)")
+	fset.Float64Var(&c.maxMarginPct, "max-margin-pct", 10, "largest profit margin percentage to consider")
+	fset.Float64Var(&c.marginPctStep, "margin-pct-step", 0.25, "step between the profit margin percentages considered")
+	fset.StringVar(&c.specFile, "spec-file", "", "path to save the suggested spec (printed when empty)")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *Suggest) Synopsis() string {
+	return "Suggests a waller spec for a target number of loops per day"
+}
+
+func (c *Suggest) CommandHelp() string {
+	return `
+
+Command "suggest" generates a waller spec for a product from the historical
+candles data saved in the datastore. Buy points cover the price range seen in
+the candles and are spaced by the profit margin. Profit margins are tried in
+steps and the largest margin that would have completed the target number of
+loops per day in the historical candles is chosen. Buy size is scaled so that
+the spec fits in the budget.
+
+Spec is written in the same format as checked by the "lint" command. Estimates
+are optimistic as limit orders are assumed to fill when a candle reaches the
+limit price.
+
+`
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/bvk/tradebot/api"
+	"github.com/bvk/tradebot/cli"
+	"github.com/bvk/tradebot/namer"
+	"github.com/bvk/tradebot/server"
+	"github.com/bvk/tradebot/subcmds/cmdutil"
+	"github.com/bvk/tradebot/waller"
+	"github.com/bvkgo/kv"
+)
+
+type WhatIf struct {
+	cmdutil.DBFlags
+
+	feePct float64
+}
+
+func (c *WhatIf) Command() (*flag.FlagSet, cli.CmdFunc) {
+	fset := flag.NewFlagSet("what-if", flag.ContinueOnError)
+	c.DBFlags.SetFlags(fset)
+	fset.Float64Var(&c.feePct, "fee-pct", 0.25, "exchange fee percentage to compute the budgets")
+	return fset, cli.CmdFunc(c.run)
+}
+
+func (c *WhatIf) Synopsis() string {
+	return "Prints the impact of replacing a waller's pairs with a new spec"
+}
+
+func (c *WhatIf) CommandHelp() string {
+	return `
+
+Command "what-if" takes a waller and a spec file (in the same format as the
+"waller lint" command) and prints how the new spec would treat the current
+state of the waller: number of pairs kept, added and removed, the old and new
+budgets and the removed pairs that still hold unsold inventory, which would be
+orphaned by the new spec. Waller is not modified.
+
+`
+}
+
+func (c *WhatIf) run(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("this command takes two (waller and spec-file) arguments")
+	}
+	arg := args[0]
+
+	data, err := os.ReadFile(args[1])
+	if err != nil {
+		return fmt.Errorf("could not read spec file: %w", err)
+	}
+	spec := new(api.WallRequest)
+	if err := json.Unmarshal(data, spec); err != nil {
+		return fmt.Errorf("could not parse spec file: %w", err)
+	}
+	if len(spec.Pairs) == 0 {
+		return fmt.Errorf("spec file has no buy/sell pairs")
+	}
+
+	var wall *waller.Waller
+	getter := func(ctx context.Context, r kv.Reader) error {
+		_, uid, _, err := namer.Resolve(ctx, r, arg)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("could not resolve waller argument %q: %w", arg, err)
+			}
+			uid = arg
+		}
+		job, err := server.Load(ctx, r, uid, "waller")
+		if err != nil {
+			return fmt.Errorf("could not load waller from db: %w", err)
+		}
+		wall = job.(*waller.Waller)
+		return nil
+	}
+
+	db, closer, err := c.DBFlags.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	if err := kv.WithReader(ctx, db, getter); err != nil {
+		return err
+	}
+	if len(spec.ProductID) != 0 && spec.ProductID != wall.ProductID() {
+		return fmt.Errorf("spec product %q doesn't match the waller product %q", spec.ProductID, wall.ProductID())
+	}
+
+	v := wall.WhatIf(spec.Pairs, c.feePct)
+	fmt.Printf("Num pairs: %d -> %d\n", v.NumPairs, v.NumNewPairs)
+	fmt.Printf("Kept pairs: %d\n", v.NumKept)
+	fmt.Printf("Added pairs: %d\n", v.NumAdded)
+	fmt.Printf("Removed pairs: %d\n", v.NumRemoved)
+	fmt.Println()
+	fmt.Printf("Budget required: %s -> %s (change %s)\n", v.Budget.StringFixed(2), v.NewBudget.StringFixed(2), v.NewBudget.Sub(v.Budget).StringFixed(2))
+
+	if len(v.Orphans) == 0 {
+		fmt.Println()
+		fmt.Println("No unsold inventory is orphaned by the new spec")
+		return nil
+	}
+
+	size, cost := v.OrphanedSize()
+	fmt.Println()
+	fmt.Printf("Orphaned inventory: %s (cost %s) in %d removed pairs\n", size, cost.StringFixed(2), len(v.Orphans))
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "BuyPrice\tSellPrice\tUnsoldSize\tCost\t\n")
+	for _, o := range v.Orphans {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", o.Pair.Buy.Price.StringFixed(2), o.Pair.Sell.Price.StringFixed(2), o.Size, o.Cost.StringFixed(2))
+	}
+	tw.Flush()
+	return nil
+}
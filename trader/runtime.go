@@ -4,10 +4,13 @@ package trader
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/bvk/tradebot/exchange"
 	"github.com/bvkgo/kv"
+	"github.com/shopspring/decimal"
 )
 
 type Messenger interface {
@@ -19,3 +22,11 @@ type Runtime struct {
 	Product   exchange.Product
 	Messenger Messenger
 }
+
+// MarkPrice returns the current price for the runtime's product.
+func (rt *Runtime) MarkPrice(ctx context.Context) (decimal.Decimal, error) {
+	if rt.Product == nil {
+		return decimal.Zero, fmt.Errorf("runtime has no product: %w", os.ErrInvalid)
+	}
+	return rt.Product.LastPrice(ctx)
+}
@@ -4,6 +4,8 @@ package trader
 
 import (
 	"fmt"
+
+	"github.com/bvk/tradebot/exchange"
 )
 
 type Status struct {
@@ -12,8 +14,20 @@ type Status struct {
 	UID          string
 	ProductID    string
 	ExchangeName string
+
+	// Tag is the strategy tag of the trader's points, if any.
+	Tag string
 }
 
 func (s *Status) String() string {
 	return fmt.Sprintf("uid %s product %s bvalue %s s %s usize %s", s.UID, s.ProductID, s.BoughtSize, s.SoldSize, s.UnsoldSize)
 }
+
+// quoteCurrency returns the currency of the status values, which defaults to
+// the quote currency of the product when it is not set explicitly.
+func (s *Status) quoteCurrency() string {
+	if s.Summary != nil && len(s.QuoteCurrency) != 0 {
+		return s.QuoteCurrency
+	}
+	return exchange.QuoteCurrency(s.ProductID)
+}
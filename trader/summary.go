@@ -4,17 +4,31 @@ package trader
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/bvk/tradebot/timerange"
 	"github.com/shopspring/decimal"
 )
 
+// TradeProfit holds the realized profit of a completed buy-sell trade at the
+// time of it's sell.
+type TradeProfit struct {
+	Time   time.Time
+	Profit decimal.Decimal
+}
+
 type Summary struct {
 	TimePeriod timerange.Range
 
 	NumSells int
 	NumBuys  int
 
+	// NumLoops is the number of completed loops, i.e., the matched buy and sell
+	// pairs with the sell in the time period.
+	NumLoops int
+
 	Budget decimal.Decimal
 
 	SoldFees  decimal.Decimal
@@ -32,12 +46,136 @@ type Summary struct {
 	OversoldFees  decimal.Decimal
 	OversoldSize  decimal.Decimal
 	OversoldValue decimal.Decimal
+
+	// OtherFees holds the fees that are not charged in the quote currency,
+	// keyed by the fee currency. These fees are not included in the fee fields
+	// above.
+	OtherFees map[string]decimal.Decimal
+
+	// BoughtResidualSize and SoldResidualSize hold the sizes left unfilled by
+	// the buy and sell limiters that are marked as complete manually.
+	BoughtResidualSize decimal.Decimal
+	SoldResidualSize   decimal.Decimal
+
+	// BaseIncrement is the smallest size unit of the product, which determines
+	// the precision for size fields when printed. Zero value indicates an
+	// unknown increment, in which case DefaultSizePrecision is used.
+	BaseIncrement decimal.Decimal
+
+	// QuoteCurrency is the currency of all value and fee fields. It is empty
+	// when the currency is unknown or when summaries in different currencies
+	// are combined, in which case the values are meaningless.
+	QuoteCurrency string
+
+	// Trades holds the realized profits of the trades sold in the time period,
+	// which are used to track the equity curve for the drawdown.
+	Trades []*TradeProfit `json:",omitempty"`
 }
 
+// DefaultSizePrecision is the number of decimal places used for size fields
+// when base increment of the product is unknown.
+const DefaultSizePrecision = 3
+
+// QuotePrecision is the number of decimal places used for the quote currency
+// fields.
+const QuotePrecision = 2
+
 func (s *Summary) String() string {
-	return fmt.Sprintf("nsells=%d nbuys=%d sfees=%s ssize=%s svalue=%s bfees=%s bsize=%s bvalue=%s",
-		s.NumSells, s.NumBuys, s.SoldFees.StringFixed(3), s.SoldSize.StringFixed(3), s.SoldValue.StringFixed(3),
-		s.BoughtFees.StringFixed(3), s.BoughtSize.StringFixed(3), s.BoughtValue.StringFixed(3))
+	str := fmt.Sprintf("nsells=%d nbuys=%d nloops=%d sfees=%s ssize=%s svalue=%s bfees=%s bsize=%s bvalue=%s",
+		s.NumSells, s.NumBuys, s.NumLoops, s.QuoteString(s.SoldFees), s.SizeString(s.SoldSize), s.QuoteString(s.SoldValue),
+		s.QuoteString(s.BoughtFees), s.SizeString(s.BoughtSize), s.QuoteString(s.BoughtValue))
+	if len(s.OtherFees) != 0 {
+		str += " ofees=" + s.OtherFeesString()
+	}
+	return str
+}
+
+// OtherFeesString formats the fees in the non-quote currencies as a comma
+// separated list of "amount currency" values in the currency order. Returns
+// an empty string when there are no such fees.
+func (s *Summary) OtherFeesString() string {
+	currencies := make([]string, 0, len(s.OtherFees))
+	for currency := range s.OtherFees {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	var fees []string
+	for _, currency := range currencies {
+		fees = append(fees, s.OtherFees[currency].String()+" "+currency)
+	}
+	return strings.Join(fees, ",")
+}
+
+// SizePrecision returns the number of decimal places for the size fields,
+// which is derived from the base increment when it is known.
+func (s *Summary) SizePrecision() int32 {
+	if !s.BaseIncrement.IsPositive() {
+		return DefaultSizePrecision
+	}
+	return incrementPrecision(s.BaseIncrement)
+}
+
+// SizeString formats a size value at the product's native precision.
+func (s *Summary) SizeString(size decimal.Decimal) string {
+	return size.StringFixed(s.SizePrecision())
+}
+
+// QuoteString formats a quote currency value with QuotePrecision decimals.
+func (s *Summary) QuoteString(v decimal.Decimal) string {
+	return v.StringFixed(QuotePrecision)
+}
+
+// incrementPrecision returns the number of significant decimal places in a
+// positive increment value, ignoring the trailing zeros, so that "0.00100000"
+// and "0.001" both have three decimal places and "1" or "1000" have none.
+func incrementPrecision(inc decimal.Decimal) int32 {
+	d10 := decimal.NewFromInt(10)
+	var n int32
+	for v := inc; !v.IsInteger(); v = v.Mul(d10) {
+		n++
+	}
+	return n
+}
+
+// WithFeePct returns a copy of the summary with all fees recomputed as the
+// input percentage of the corresponding values, instead of the recorded
+// fees. It is useful to evaluate the profits under a different fee tier.
+// Trade profits are not recomputed, so the drawdown uses the recorded fees.
+func (s *Summary) WithFeePct(pct decimal.Decimal) *Summary {
+	d100 := decimal.NewFromInt(100)
+	feeOf := func(v decimal.Decimal) decimal.Decimal {
+		return v.Mul(pct).Div(d100)
+	}
+	v := *s
+	v.SoldFees = feeOf(s.SoldValue)
+	v.BoughtFees = feeOf(s.BoughtValue)
+	v.UnsoldFees = feeOf(s.UnsoldValue)
+	v.OversoldFees = feeOf(s.OversoldValue)
+	v.OtherFees = nil
+	return &v
+}
+
+// Convert returns a copy of the summary with all value and fee fields in the
+// quote currency converted into the target currency at the input rate. Sizes
+// and the fees in other currencies are left unchanged.
+func (s *Summary) Convert(currency string, rate decimal.Decimal) *Summary {
+	v := *s
+	v.Budget = s.Budget.Mul(rate)
+	v.SoldFees = s.SoldFees.Mul(rate)
+	v.SoldValue = s.SoldValue.Mul(rate)
+	v.BoughtFees = s.BoughtFees.Mul(rate)
+	v.BoughtValue = s.BoughtValue.Mul(rate)
+	v.UnsoldFees = s.UnsoldFees.Mul(rate)
+	v.UnsoldValue = s.UnsoldValue.Mul(rate)
+	v.OversoldFees = s.OversoldFees.Mul(rate)
+	v.OversoldValue = s.OversoldValue.Mul(rate)
+	v.QuoteCurrency = currency
+	v.Trades = nil
+	for _, t := range s.Trades {
+		v.Trades = append(v.Trades, &TradeProfit{Time: t.Time, Profit: t.Profit.Mul(rate)})
+	}
+	return &v
 }
 
 func (s *Summary) FeePct() decimal.Decimal {
@@ -73,6 +211,13 @@ func (s *Summary) Profit() decimal.Decimal {
 	return profit
 }
 
+// UnrealizedProfit returns the profit if the unsold size is sold at the mark
+// price, after the fees already paid for the unsold buys. Sell fees are not
+// included. Mark price must be for the product of the summary.
+func (s *Summary) UnrealizedProfit(mark decimal.Decimal) decimal.Decimal {
+	return s.UnsoldSize.Mul(mark).Sub(s.UnsoldValue).Sub(s.UnsoldFees)
+}
+
 func (s *Summary) NumDays() decimal.Decimal {
 	if s.TimePeriod.IsZero() {
 		return decimal.Zero
@@ -95,6 +240,15 @@ func (s *Summary) ReturnRate() decimal.Decimal {
 	return s.Profit().Mul(decimal.NewFromInt(100)).Div(s.Budget)
 }
 
+// ProfitPerLoop returns the average profit of a completed loop. Returns zero
+// when no loops are completed.
+func (s *Summary) ProfitPerLoop() decimal.Decimal {
+	if s.NumLoops == 0 {
+		return decimal.Zero
+	}
+	return s.Profit().Div(decimal.NewFromInt(int64(s.NumLoops)))
+}
+
 func (s *Summary) AnnualReturnRate() decimal.Decimal {
 	if s.Budget.IsZero() {
 		return decimal.Zero
@@ -103,19 +257,44 @@ func (s *Summary) AnnualReturnRate() decimal.Decimal {
 	return perYear.Mul(decimal.NewFromInt(100)).Div(s.Budget)
 }
 
+// MaxDrawdown returns the largest decline from a peak to a trough in the
+// cumulative realized profit of the trades in the time order. Returns zero
+// when the cumulative profit never declines.
+func (s *Summary) MaxDrawdown() decimal.Decimal {
+	trades := make([]*TradeProfit, len(s.Trades))
+	copy(trades, s.Trades)
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].Time.Before(trades[j].Time)
+	})
+
+	var equity, peak, maxDrawdown decimal.Decimal
+	for _, t := range trades {
+		equity = equity.Add(t.Profit)
+		peak = decimal.Max(peak, equity)
+		maxDrawdown = decimal.Max(maxDrawdown, peak.Sub(equity))
+	}
+	return maxDrawdown
+}
+
 func Summarize(statuses []*Status) *Summary {
 	sum := new(Summary)
 
 	var tr *timerange.Range
+	sameIncrement, sameCurrency := true, true
 	for i, s := range statuses {
 		if i == 0 {
 			tr = &s.TimePeriod
+			sum.BaseIncrement = s.BaseIncrement
+			sum.QuoteCurrency = s.quoteCurrency()
 		} else {
 			tr = timerange.Union(tr, &s.TimePeriod)
+			sameIncrement = sameIncrement && sum.BaseIncrement.Equal(s.BaseIncrement)
+			sameCurrency = sameCurrency && sum.QuoteCurrency == s.quoteCurrency()
 		}
 
 		sum.NumBuys += s.NumBuys
 		sum.NumSells += s.NumSells
+		sum.NumLoops += s.NumLoops
 		sum.Budget = sum.Budget.Add(s.Budget)
 
 		sum.SoldFees = sum.SoldFees.Add(s.SoldFees)
@@ -133,10 +312,89 @@ func Summarize(statuses []*Status) *Summary {
 		sum.OversoldFees = sum.OversoldFees.Add(s.OversoldFees)
 		sum.OversoldSize = sum.OversoldSize.Add(s.OversoldSize)
 		sum.OversoldValue = sum.OversoldValue.Add(s.OversoldValue)
+
+		sum.BoughtResidualSize = sum.BoughtResidualSize.Add(s.BoughtResidualSize)
+		sum.SoldResidualSize = sum.SoldResidualSize.Add(s.SoldResidualSize)
+
+		sum.Trades = append(sum.Trades, s.Trades...)
+
+		for currency, fee := range s.OtherFees {
+			if sum.OtherFees == nil {
+				sum.OtherFees = make(map[string]decimal.Decimal)
+			}
+			sum.OtherFees[currency] = sum.OtherFees[currency].Add(fee)
+		}
 	}
 
 	if tr != nil {
 		sum.TimePeriod = *tr
 	}
+	// Sizes from products with different increments cannot share a precision.
+	if !sameIncrement {
+		sum.BaseIncrement = decimal.Zero
+	}
+	if !sameCurrency {
+		sum.QuoteCurrency = ""
+	}
 	return sum
 }
+
+// SummarizeByCurrency summarizes the statuses separately for each quote
+// currency, so that values in different currencies are never added together.
+func SummarizeByCurrency(statuses []*Status) map[string]*Summary {
+	return summarizeBy(statuses, (*Status).quoteCurrency)
+}
+
+// SummarizeByProduct summarizes the statuses separately for each product.
+func SummarizeByProduct(statuses []*Status) map[string]*Summary {
+	return summarizeBy(statuses, func(s *Status) string { return s.ProductID })
+}
+
+// SummarizeByTag summarizes the statuses separately for each strategy tag.
+func SummarizeByTag(statuses []*Status) map[string]*Summary {
+	return summarizeBy(statuses, func(s *Status) string { return s.Tag })
+}
+
+func summarizeBy(statuses []*Status, keyFunc func(*Status) string) map[string]*Summary {
+	groups := make(map[string][]*Status)
+	for _, s := range statuses {
+		key := keyFunc(s)
+		groups[key] = append(groups[key], s)
+	}
+	sums := make(map[string]*Summary)
+	for key, ss := range groups {
+		sums[key] = Summarize(ss)
+	}
+	return sums
+}
+
+// FXConverter returns the rate to convert one unit of the input currency into
+// the reporting currency.
+type FXConverter func(currency string) (decimal.Decimal, error)
+
+// SummarizeIn summarizes the statuses in the reporting currency. Statuses in
+// other quote currencies are converted using the converter, so it is an error
+// to pass a nil converter when statuses use different currencies.
+func SummarizeIn(statuses []*Status, currency string, convert FXConverter) (*Summary, error) {
+	var converted []*Status
+	for _, s := range statuses {
+		from := s.quoteCurrency()
+		if from == currency {
+			converted = append(converted, s)
+			continue
+		}
+		if convert == nil {
+			return nil, fmt.Errorf("job %s is in currency %q instead of %q and no converter is given", s.UID, from, currency)
+		}
+		rate, err := convert(from)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert currency %q to %q: %w", from, currency, err)
+		}
+		v := *s
+		v.Summary = s.Summary.Convert(currency, rate)
+		converted = append(converted, &v)
+	}
+	sum := Summarize(converted)
+	sum.QuoteCurrency = currency
+	return sum, nil
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package trader
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestSummaryMaxDrawdown(t *testing.T) {
+	now := time.Now()
+	trade := func(hours int, profit int64) *TradeProfit {
+		return &TradeProfit{Time: now.Add(time.Duration(hours) * time.Hour), Profit: decimal.NewFromInt(profit)}
+	}
+
+	// Equity curve in the time order is 10, 15, 7, 3, 12, 1, 20 with the
+	// largest decline from 15 to 1.
+	a := &Status{Summary: &Summary{Trades: []*TradeProfit{trade(0, 10), trade(2, -8), trade(4, 9), trade(6, 19)}}}
+	b := &Status{Summary: &Summary{Trades: []*TradeProfit{trade(1, 5), trade(3, -4), trade(5, -11)}}}
+	sum := Summarize([]*Status{a, b})
+	if d := sum.MaxDrawdown(); !d.Equal(decimal.NewFromInt(14)) {
+		t.Fatalf("want max drawdown 14, got %s", d)
+	}
+
+	if d := (&Summary{Trades: []*TradeProfit{trade(0, 1), trade(1, 2)}}).MaxDrawdown(); !d.IsZero() {
+		t.Fatalf("want zero drawdown for rising profits, got %s", d)
+	}
+}
+
+func TestSummarizeByProductFeePct(t *testing.T) {
+	status := func(pid string, bvalue, bfees, svalue, sfees int64) *Status {
+		return &Status{
+			ProductID: pid,
+			Summary: &Summary{
+				BoughtValue: decimal.NewFromInt(bvalue),
+				BoughtFees:  decimal.NewFromInt(bfees),
+				SoldValue:   decimal.NewFromInt(svalue),
+				SoldFees:    decimal.NewFromInt(sfees),
+			},
+		}
+	}
+	statuses := []*Status{
+		status("BTC-USD", 1000, 2, 1000, 2),
+		status("BTC-USD", 500, 1, 500, 1),
+		status("ETH-USD", 100, 1, 100, 1),
+	}
+	sums := SummarizeByProduct(statuses)
+	if len(sums) != 2 {
+		t.Fatalf("want summaries for 2 products, got %v", sums)
+	}
+	if v := sums["BTC-USD"].FeePct(); !v.Equal(decimal.RequireFromString("0.2")) {
+		t.Fatalf("BTC-USD: want 0.2%% fees, got %s", v)
+	}
+	if v := sums["ETH-USD"].FeePct(); !v.Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("ETH-USD: want 1%% fees, got %s", v)
+	}
+}
+
+func TestSummarizeNumLoops(t *testing.T) {
+	a := &Status{Summary: &Summary{NumBuys: 3, NumSells: 2, NumLoops: 2, SoldValue: decimal.NewFromInt(30)}}
+	b := &Status{Summary: &Summary{NumBuys: 1, NumSells: 1, NumLoops: 1, SoldValue: decimal.NewFromInt(15)}}
+	sum := Summarize([]*Status{a, b})
+	if sum.NumLoops != 3 {
+		t.Fatalf("want 3 loops, got %d", sum.NumLoops)
+	}
+	if v := sum.ProfitPerLoop(); !v.Equal(decimal.NewFromInt(15)) {
+		t.Fatalf("want profit per loop 15, got %s", v)
+	}
+	if v := (&Summary{}).ProfitPerLoop(); !v.IsZero() {
+		t.Fatalf("want zero profit per loop without loops, got %s", v)
+	}
+}
+
+func TestSummarizeOtherFees(t *testing.T) {
+	d := decimal.RequireFromString
+	a := &Status{Summary: &Summary{NumBuys: 1, OtherFees: map[string]decimal.Decimal{"BNB": d("0.1")}}}
+	b := &Status{Summary: &Summary{NumBuys: 1, OtherFees: map[string]decimal.Decimal{"BNB": d("0.2"), "ETH": d("0.01")}}}
+	c := &Status{Summary: &Summary{NumBuys: 1}}
+	sum := Summarize([]*Status{a, b, c})
+	if v := sum.OtherFees["BNB"]; !v.Equal(d("0.3")) {
+		t.Fatalf("want BNB fees 0.3, got %s", v)
+	}
+	if v := sum.OtherFeesString(); v != "0.3 BNB,0.01 ETH" {
+		t.Fatalf("want other fees in the currency order, got %q", v)
+	}
+	if v := sum.String(); !strings.HasSuffix(v, " ofees=0.3 BNB,0.01 ETH") {
+		t.Fatalf("want other fees in the summary string, got %q", v)
+	}
+
+	// Other fees are not in the quote currency, so they must not be added to
+	// the fees.
+	if v := sum.Fees(); !v.IsZero() {
+		t.Fatalf("want zero quote fees, got %s", v)
+	}
+	if v := (&Summary{}).String(); strings.Contains(v, "ofees") {
+		t.Fatalf("want no other fees in the summary string, got %q", v)
+	}
+}
+
+func TestSummarizeCurrencies(t *testing.T) {
+	status := func(uid, pid string, bvalue int64) *Status {
+		return &Status{
+			UID:       uid,
+			ProductID: pid,
+			Summary:   &Summary{BoughtValue: decimal.NewFromInt(bvalue)},
+		}
+	}
+	statuses := []*Status{
+		status("a", "BTC-USD", 100),
+		status("b", "ETH-USD", 50),
+		status("c", "ETH-EUR", 10),
+	}
+
+	if sum := Summarize(statuses[:2]); sum.QuoteCurrency != "USD" {
+		t.Fatalf("want USD quote currency for USD jobs, got %q", sum.QuoteCurrency)
+	}
+	if sum := Summarize(statuses); sum.QuoteCurrency != "" {
+		t.Fatalf("want empty quote currency for mixed currencies, got %q", sum.QuoteCurrency)
+	}
+
+	sums := SummarizeByCurrency(statuses)
+	if len(sums) != 2 {
+		t.Fatalf("want summaries for 2 currencies, got %v", sums)
+	}
+	if v := sums["USD"].BoughtValue; !v.Equal(decimal.NewFromInt(150)) {
+		t.Fatalf("USD: want bought value 150, got %s", v)
+	}
+	if v := sums["EUR"].BoughtValue; !v.Equal(decimal.NewFromInt(10)) {
+		t.Fatalf("EUR: want bought value 10, got %s", v)
+	}
+
+	if _, err := SummarizeIn(statuses, "USD", nil); err == nil {
+		t.Fatalf("want error for mixed currencies without a converter")
+	}
+	convert := func(currency string) (decimal.Decimal, error) {
+		if currency != "EUR" {
+			return decimal.Zero, fmt.Errorf("unexpected currency %q", currency)
+		}
+		return decimal.RequireFromString("1.1"), nil
+	}
+	sum, err := SummarizeIn(statuses, "USD", convert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.QuoteCurrency != "USD" || !sum.BoughtValue.Equal(decimal.NewFromInt(161)) {
+		t.Fatalf("want bought value 161 USD, got %s %q", sum.BoughtValue, sum.QuoteCurrency)
+	}
+	// Input statuses must not be modified by the conversion.
+	if v := statuses[2].BoughtValue; !v.Equal(decimal.NewFromInt(10)) {
+		t.Fatalf("want input status unchanged, got bought value %s", v)
+	}
+}
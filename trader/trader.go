@@ -29,6 +29,15 @@ type Trader interface {
 	// given fee percentage.
 	BudgetAt(feePct float64) decimal.Decimal
 
+	// BudgetFootprint returns the quote funds currently reserved by the
+	// trader's incomplete buys. It is the amount used to enforce the budget
+	// limits.
+	BudgetFootprint() decimal.Decimal
+
 	// SetOption updates trader job's customize-able parameters.
 	SetOption(opt, val string) error
+
+	// Config returns the effective values for all options of the trader job,
+	// including the default values for the options that are not set.
+	Config() map[string]string
 }
@@ -32,11 +32,15 @@ func filledFee(vs []*gobs.Order) decimal.Decimal {
 }
 
 func avgPrice(vs []*gobs.Order) decimal.Decimal {
-	var sum decimal.Decimal
+	var size, value decimal.Decimal
 	for _, v := range vs {
-		sum = sum.Add(v.FilledPrice)
+		size = size.Add(v.FilledSize)
+		value = value.Add(v.FilledSize.Mul(v.FilledPrice))
+	}
+	if size.IsZero() {
+		return decimal.Zero
 	}
-	return sum.Div(decimal.NewFromInt(int64(len(vs))))
+	return value.Div(size)
 }
 
 func maxPrice(max decimal.Decimal, vs []*gobs.Order) decimal.Decimal {
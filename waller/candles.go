@@ -0,0 +1,74 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"math"
+	"time"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/point"
+)
+
+// DailyVolatility returns the realized volatility of the candle close prices
+// as the standard deviation of log returns scaled to a day. Candles must be in
+// the ascending order of their start times.
+func DailyVolatility(candles []*gobs.Candle) float64 {
+	if len(candles) < 3 {
+		return 0
+	}
+
+	var returns []float64
+	for i := 1; i < len(candles); i++ {
+		prev, _ := candles[i-1].Close.Float64()
+		cur, _ := candles[i].Close.Float64()
+		if prev <= 0 || cur <= 0 {
+			continue
+		}
+		returns = append(returns, math.Log(cur/prev))
+	}
+	if len(returns) < 2 {
+		return 0
+	}
+
+	var sum float64
+	for _, r := range returns {
+		sum += r
+	}
+	mean := sum / float64(len(returns))
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+
+	interval := candles[0].Duration
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	perDay := float64(24*time.Hour) / float64(interval)
+	return math.Sqrt(variance * perDay)
+}
+
+// CountLoops returns the number of buy-sell loops the pairs would have
+// completed over the candles, assuming that a limit order is filled when the
+// candle's price range reaches the limit price. A sell is only considered
+// from the candle after it's buy is filled, cause the order of prices within
+// a candle is unknown.
+func CountLoops(pairs []*point.Pair, candles []*gobs.Candle) int {
+	nloops := 0
+	for _, p := range pairs {
+		holding := false
+		for _, c := range candles {
+			if !holding {
+				holding = c.Low.LessThanOrEqual(p.Buy.Price)
+				continue
+			}
+			if c.High.GreaterThanOrEqual(p.Sell.Price) {
+				holding = false
+				nloops++
+			}
+		}
+	}
+	return nloops
+}
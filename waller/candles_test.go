@@ -0,0 +1,45 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/point"
+	"github.com/shopspring/decimal"
+)
+
+func TestCountLoops(t *testing.T) {
+	newCandle := func(low, high int64) *gobs.Candle {
+		return &gobs.Candle{
+			Duration: time.Minute,
+			Low:      decimal.NewFromInt(low),
+			High:     decimal.NewFromInt(high),
+			Close:    decimal.NewFromInt((low + high) / 2),
+		}
+	}
+	pair := &point.Pair{
+		Buy:  point.Point{Size: decimal.NewFromInt(1), Price: decimal.NewFromInt(100), Cancel: decimal.NewFromInt(110)},
+		Sell: point.Point{Size: decimal.NewFromInt(1), Price: decimal.NewFromInt(110), Cancel: decimal.NewFromInt(100)},
+	}
+
+	candles := []*gobs.Candle{
+		newCandle(101, 105), // no buy
+		newCandle(95, 112),  // buy; sell is not considered in the same candle
+		newCandle(104, 108), // holding
+		newCandle(105, 111), // sell
+		newCandle(108, 115), // no buy
+		newCandle(99, 104),  // buy
+		newCandle(100, 109), // holding
+	}
+	if n := CountLoops([]*point.Pair{pair}, candles); n != 1 {
+		t.Fatalf("want 1 loop, got %d", n)
+	}
+
+	flat := []*gobs.Candle{newCandle(100, 100), newCandle(100, 100), newCandle(100, 100)}
+	if v := DailyVolatility(flat); v != 0 {
+		t.Fatalf("want zero volatility for flat prices, got %v", v)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Efficiency describes how much of the waller budget is deployed in the pairs
+// with an order resting near the market price.
+type Efficiency struct {
+	Price decimal.Decimal
+
+	NumPairs  int
+	NumActive int
+
+	ActiveBudget decimal.Decimal
+	IdleBudget   decimal.Decimal
+}
+
+// ActivePct returns the percentage of the budget in the active pairs.
+func (e *Efficiency) ActivePct() decimal.Decimal {
+	total := e.ActiveBudget.Add(e.IdleBudget)
+	if total.IsZero() {
+		return decimal.Zero
+	}
+	return e.ActiveBudget.Mul(decimal.NewFromInt(100)).Div(total)
+}
+
+// CapitalEfficiency returns the split of the waller budget (at the given fee
+// percentage) between the active and the idle pairs at the market price. A
+// pair is active when it's current side would have a live order at the
+// price, i.e., the price is inside the cancel threshold of the sell point for
+// the loops holding the inventory and of the buy point for the others.
+// Completed loops are not counted.
+func (w *Waller) CapitalEfficiency(price decimal.Decimal, feePct float64) *Efficiency {
+	e := &Efficiency{Price: price}
+	for _, l := range w.loopers {
+		state := l.LoopState()
+		if state == "completed" {
+			continue
+		}
+		e.NumPairs++
+
+		p := l.Pair()
+		budget := p.Buy.Value().Add(p.Buy.FeeAt(feePct))
+		active := price.LessThan(p.Buy.Cancel)
+		if state == "holding" {
+			active = price.GreaterThan(p.Sell.Cancel)
+		}
+		if active {
+			e.NumActive++
+			e.ActiveBudget = e.ActiveBudget.Add(budget)
+		} else {
+			e.IdleBudget = e.IdleBudget.Add(budget)
+		}
+	}
+	return e
+}
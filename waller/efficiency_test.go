@@ -0,0 +1,49 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"testing"
+
+	"github.com/bvk/tradebot/point"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestCapitalEfficiency(t *testing.T) {
+	d := decimal.RequireFromString
+	pair := func(bprice, sprice string) *point.Pair {
+		return &point.Pair{
+			Buy:  point.Point{Size: d("1"), Price: d(bprice), Cancel: d(bprice).Add(d("5"))},
+			Sell: point.Point{Size: d("1"), Price: d(sprice), Cancel: d(sprice).Sub(d("5"))},
+		}
+	}
+
+	pairs := []*point.Pair{
+		pair("90", "100"),
+		pair("98", "108"),
+		pair("120", "130"),
+		pair("200", "210"),
+	}
+	w, err := New(uuid.NewString(), "coinbase", "BTC-USD", pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Buys with cancel prices above the market price would have live orders.
+	e := w.CapitalEfficiency(d("100"), 0)
+	if e.NumPairs != 4 || e.NumActive != 3 {
+		t.Fatalf("want 3 of 4 active pairs, got %d of %d", e.NumActive, e.NumPairs)
+	}
+	if !e.ActiveBudget.Equal(d("418")) || !e.IdleBudget.Equal(d("90")) {
+		t.Fatalf("want active budget 418 and idle budget 90, got %s and %s", e.ActiveBudget, e.IdleBudget)
+	}
+	if pct := e.ActivePct().StringFixed(1); pct != "82.3" {
+		t.Fatalf("want active pct 82.3, got %s", pct)
+	}
+
+	e = w.CapitalEfficiency(d("300"), 0)
+	if e.NumActive != 0 || !e.IdleBudget.Equal(d("508")) {
+		t.Fatalf("want all pairs idle far above the range, got %d active", e.NumActive)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"fmt"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/point"
+	"github.com/shopspring/decimal"
+)
+
+// CheckPoint verifies that the trade point is acceptable for the product.
+func CheckPoint(product *gobs.Product, p *point.Point) error {
+	if inc := product.QuoteIncrement; !inc.IsZero() {
+		if !p.Price.Mod(inc).IsZero() {
+			return fmt.Errorf("price %s is not a multiple of the product quote increment %s", p.Price, inc)
+		}
+		if !p.Cancel.Mod(inc).IsZero() {
+			return fmt.Errorf("cancel price %s is not a multiple of the product quote increment %s", p.Cancel, inc)
+		}
+	}
+	if p.SizeInQuote {
+		return nil
+	}
+	if !product.BaseMinSize.IsZero() && p.Size.LessThan(product.BaseMinSize) {
+		return fmt.Errorf("size %s is below the product min size %s", p.Size, product.BaseMinSize)
+	}
+	if !product.BaseMaxSize.IsZero() && p.Size.GreaterThan(product.BaseMaxSize) {
+		return fmt.Errorf("size %s is above the product max size %s", p.Size, product.BaseMaxSize)
+	}
+	if !product.BaseIncrement.IsZero() && !p.Size.Mod(product.BaseIncrement).IsZero() {
+		return fmt.Errorf("size %s is not a multiple of the product base increment %s", p.Size, product.BaseIncrement)
+	}
+	return nil
+}
+
+// Issue is a problem found by Lint. Index is the offending buy/sell pair
+// index or -1 for the problems that are not specific to a pair.
+type Issue struct {
+	Index int
+	Err   error
+}
+
+func (v *Issue) String() string {
+	if v.Index < 0 {
+		return v.Err.Error()
+	}
+	return fmt.Sprintf("pair %d: %v", v.Index, v.Err)
+}
+
+// LintOptions holds the optional inputs for Lint. Product checks are skipped
+// when Product is nil and budget limit is not checked when MaxBudget is zero.
+type LintOptions struct {
+	Product *gobs.Product
+
+	FeePct float64
+
+	MaxBudget decimal.Decimal
+}
+
+// Lint runs all validations on the buy/sell pairs and returns every issue
+// found instead of stopping at the first one.
+func Lint(pairs []*point.Pair, opts *LintOptions) []*Issue {
+	var issues []*Issue
+	add := func(i int, format string, args ...any) {
+		issues = append(issues, &Issue{Index: i, Err: fmt.Errorf(format, args...)})
+	}
+
+	if len(pairs) == 0 {
+		add(-1, "buy/sell pairs cannot be empty")
+		return issues
+	}
+	if opts.FeePct < 0 || opts.FeePct >= 100 {
+		add(-1, "fee percentage should be in between 0-100")
+	}
+
+	var budget decimal.Decimal
+	seen := make(map[string]int)
+	for i, p := range pairs {
+		bvalid, svalid := true, true
+		if err := p.Buy.Check(); err != nil {
+			add(i, "buy point %s is invalid: %w", p.Buy, err)
+			bvalid = false
+		} else if side := p.Buy.Side(); side != "BUY" {
+			add(i, "buy point %s has cancel-price on the wrong side", p.Buy)
+		}
+		if err := p.Sell.Check(); err != nil {
+			add(i, "sell point %s is invalid: %w", p.Sell, err)
+			svalid = false
+		} else if side := p.Sell.Side(); side != "SELL" {
+			add(i, "sell point %s has cancel-price on the wrong side", p.Sell)
+		}
+		if p.Sell.Size.GreaterThan(p.Buy.Size) {
+			add(i, "sell size %s is more than buy size %s", p.Sell.Size, p.Buy.Size)
+		}
+
+		if opts.Product != nil {
+			if err := CheckPoint(opts.Product, &p.Buy); err != nil {
+				add(i, "buy point: %w", err)
+			}
+			if err := CheckPoint(opts.Product, &p.Sell); err != nil {
+				add(i, "sell point: %w", err)
+			}
+		}
+
+		if bvalid && svalid {
+			fees := p.FeesAt(opts.FeePct)
+			if profit := p.ValueMargin().Sub(fees); !profit.IsPositive() {
+				add(i, "profit %s after fees %s is not positive", profit.StringFixed(3), fees.StringFixed(3))
+			}
+		}
+
+		key := p.String()
+		if j, ok := seen[key]; ok {
+			add(i, "pair is a duplicate of pair %d", j)
+		} else {
+			seen[key] = i
+		}
+		budget = budget.Add(p.Buy.Value()).Add(p.Buy.FeeAt(opts.FeePct))
+	}
+
+	if !budget.IsPositive() {
+		add(-1, "total budget %s is not positive", budget.StringFixed(3))
+	}
+	if max := opts.MaxBudget; max.IsPositive() && budget.GreaterThan(max) {
+		add(-1, "total budget %s is more than the max budget %s", budget.StringFixed(3), max.StringFixed(3))
+	}
+	return issues
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"testing"
+
+	"github.com/bvk/tradebot/gobs"
+	"github.com/bvk/tradebot/point"
+	"github.com/shopspring/decimal"
+)
+
+func TestLint(t *testing.T) {
+	d := decimal.RequireFromString
+	pair := func(bprice, sprice, size string) *point.Pair {
+		return &point.Pair{
+			Buy:  point.Point{Size: d(size), Price: d(bprice), Cancel: d(bprice).Add(d("5"))},
+			Sell: point.Point{Size: d(size), Price: d(sprice), Cancel: d(sprice).Sub(d("5"))},
+		}
+	}
+
+	pairs := []*point.Pair{
+		pair("100", "110", "1"),
+		pair("100", "110", "1"),
+		pair("120", "120.1", "1"),
+		pair("130", "140", "0.0001"),
+	}
+	pairs[1].Sell.Cancel = d("115")
+
+	opts := &LintOptions{
+		Product:   &gobs.Product{BaseMinSize: d("0.001")},
+		FeePct:    0.25,
+		MaxBudget: d("200"),
+	}
+	issues := Lint(pairs, opts)
+
+	want := map[int]int{1: 1, 2: 1, 3: 2, -1: 1}
+	got := make(map[int]int)
+	for _, v := range issues {
+		got[v.Index]++
+	}
+	for i, n := range want {
+		if got[i] != n {
+			t.Errorf("pair %d: want %d issues, got %d (%v)", i, n, got[i], issues)
+		}
+	}
+	if len(issues) != 5 {
+		t.Fatalf("want 5 issues, got %d: %v", len(issues), issues)
+	}
+}
+
+func TestLintPairChecks(t *testing.T) {
+	d := decimal.RequireFromString
+
+	if issues := Lint(nil, &LintOptions{}); len(issues) != 1 || issues[0].Index != -1 {
+		t.Fatalf("want one spec issue for empty pairs, got %v", issues)
+	}
+
+	p := &point.Pair{
+		Buy:  point.Point{Size: d("2"), Price: d("100.005"), Cancel: d("105")},
+		Sell: point.Point{Size: d("3"), Price: d("110"), Cancel: d("105")},
+	}
+	opts := &LintOptions{
+		Product: &gobs.Product{QuoteIncrement: d("0.01")},
+		FeePct:  100,
+	}
+	issues := Lint([]*point.Pair{p}, opts)
+	var nspec, npair int
+	for _, v := range issues {
+		if v.Index < 0 {
+			nspec++
+		} else {
+			npair++
+		}
+	}
+	// Bad fee percentage, sell size over buy size, buy price increment and
+	// non-positive profit after fees.
+	if nspec != 1 || npair != 3 {
+		t.Fatalf("want 1 spec issue and 3 pair issues, got %v", issues)
+	}
+}
+
+func TestCheckPoint(t *testing.T) {
+	d := decimal.RequireFromString
+	product := &gobs.Product{
+		BaseMinSize:    d("0.01"),
+		BaseMaxSize:    d("10"),
+		BaseIncrement:  d("0.01"),
+		QuoteIncrement: d("0.01"),
+	}
+
+	tests := []struct {
+		point *point.Point
+		valid bool
+	}{
+		{&point.Point{Size: d("1"), Price: d("100")}, true},
+		{&point.Point{Size: d("0.001"), Price: d("100")}, false},
+		{&point.Point{Size: d("11"), Price: d("100")}, false},
+		{&point.Point{Size: d("1.005"), Price: d("100")}, false},
+		{&point.Point{Size: d("0.001"), Price: d("100"), SizeInQuote: true}, true},
+		{&point.Point{Size: d("1"), Price: d("100.005")}, false},
+		{&point.Point{Size: d("1"), Price: d("100"), Cancel: d("105.005")}, false},
+		{&point.Point{Size: d("100"), Price: d("100.005"), SizeInQuote: true}, false},
+	}
+	for i, test := range tests {
+		if err := CheckPoint(product, test.point); (err == nil) != test.valid {
+			t.Errorf("%d: point %s: want valid=%t, got error %v", i, test.point, test.valid, err)
+		}
+	}
+}
@@ -4,8 +4,54 @@ package waller
 
 import (
 	"fmt"
+
+	"github.com/bvk/tradebot/looper"
 )
 
+// Config returns the waller options, which are applied to all loopers. An
+// option's value is "mixed" when it differs across the loopers.
+func (w *Waller) Config() map[string]string {
+	// Defaults are taken from a new looper, which are used when the waller has
+	// no loopers.
+	defaults := new(looper.Looper).Config()
+	config := map[string]string{
+		"wind-down":               defaults["wind-down"],
+		"budget-exhausted-policy": defaults["budget-exhausted-policy"],
+	}
+	for i, l := range w.loopers {
+		lconfig := l.Config()
+		for key := range config {
+			if i == 0 {
+				config[key] = lconfig[key]
+			} else if config[key] != lconfig[key] {
+				config[key] = "mixed"
+			}
+		}
+	}
+	return config
+}
+
 func (w *Waller) SetOption(opt, val string) error {
-	return fmt.Errorf("TODO")
+	switch opt {
+	case "wind-down":
+		// Wind-down option is applied to all loopers, so that no new buys are
+		// started and the waller completes when all holdings are sold.
+		for _, l := range w.loopers {
+			if err := l.SetOption(opt, val); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "budget-exhausted-policy":
+		// Loopers of a waller have no budget, so the policy applies when the
+		// exchange account runs out of funds for the next buy.
+		for _, l := range w.loopers {
+			if err := l.SetOption(opt, val); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid option key %q", opt)
 }
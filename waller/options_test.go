@@ -0,0 +1,52 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"testing"
+
+	"github.com/bvk/tradebot/looper"
+	"github.com/bvk/tradebot/point"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestWallerOptions(t *testing.T) {
+	d := decimal.RequireFromString
+	pair := func(bprice, sprice string) *point.Pair {
+		return &point.Pair{
+			Buy:  point.Point{Size: d("1"), Price: d(bprice), Cancel: d(bprice).Add(d("5"))},
+			Sell: point.Point{Size: d("1"), Price: d(sprice), Cancel: d(sprice).Sub(d("5"))},
+		}
+	}
+
+	empty := &Waller{}
+	if v := empty.Config()["budget-exhausted-policy"]; v != looper.BudgetExhaustedWait {
+		t.Fatalf("want default budget-exhausted-policy %q, got %q", looper.BudgetExhaustedWait, v)
+	}
+
+	w, err := New(uuid.NewString(), "paper", "BTC-USD", []*point.Pair{pair("100", "110"), pair("110", "120")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := w.Config()["budget-exhausted-policy"]; v != looper.BudgetExhaustedWait {
+		t.Fatalf("want budget-exhausted-policy %q, got %q", looper.BudgetExhaustedWait, v)
+	}
+	if err := w.SetOption("budget-exhausted-policy", "pause"); err == nil {
+		t.Fatalf("invalid budget-exhausted-policy must be rejected")
+	}
+	if err := w.SetOption("budget-exhausted-policy", looper.BudgetExhaustedStop); err != nil {
+		t.Fatal(err)
+	}
+	if v := w.Config()["budget-exhausted-policy"]; v != looper.BudgetExhaustedStop {
+		t.Fatalf("want budget-exhausted-policy %q, got %q", looper.BudgetExhaustedStop, v)
+	}
+
+	// Option value is mixed when the loopers differ.
+	if err := w.loopers[0].SetOption("budget-exhausted-policy", looper.BudgetExhaustedWait); err != nil {
+		t.Fatal(err)
+	}
+	if v := w.Config()["budget-exhausted-policy"]; v != "mixed" {
+		t.Fatalf("want mixed budget-exhausted-policy, got %q", v)
+	}
+}
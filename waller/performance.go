@@ -0,0 +1,59 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"github.com/bvk/tradebot/point"
+	"github.com/bvk/tradebot/trader"
+	"github.com/shopspring/decimal"
+)
+
+// Performance compares the actual realized returns of a waller with the
+// returns expected from the analysis of it's buy-sell pairs at the same rate
+// of completed loops.
+type Performance struct {
+	Actual   *trader.Summary
+	Analysis *Analysis
+}
+
+// Performance returns the actual summary of the waller along with the
+// analysis of it's pairs at the effective fee percentage of the actual fills.
+func (w *Waller) Performance() *Performance {
+	return newPerformance(w.Status(nil).Summary, w.Pairs())
+}
+
+func newPerformance(actual *trader.Summary, pairs []*point.Pair) *Performance {
+	feePct, _ := actual.FeePct().Float64()
+	return &Performance{
+		Actual:   actual,
+		Analysis: Analyze(pairs, feePct),
+	}
+}
+
+// LoopsPerYear returns the number of completed loops projected for a year at
+// the actual loop rate. Returns zero when the time period is unknown.
+func (p *Performance) LoopsPerYear() decimal.Decimal {
+	ndays := p.Actual.NumDays()
+	if ndays.IsZero() {
+		return decimal.Zero
+	}
+	nloops := decimal.NewFromInt(int64(p.Actual.NumLoops))
+	return nloops.Mul(decimal.NewFromInt(365)).Div(ndays)
+}
+
+// ExpectedProfit returns the profit expected for the actual number of
+// completed loops at the average profit margin of the pairs.
+func (p *Performance) ExpectedProfit() decimal.Decimal {
+	return p.Analysis.AvgProfitMargin().Mul(decimal.NewFromInt(int64(p.Actual.NumLoops)))
+}
+
+// ExpectedAnnualReturnRate returns the annual return rate expected for the
+// actual loop rate at the average profit margin of the pairs.
+func (p *Performance) ExpectedAnnualReturnRate() decimal.Decimal {
+	budget := p.Analysis.Budget()
+	if budget.IsZero() {
+		return decimal.Zero
+	}
+	perYear := p.Analysis.AvgProfitMargin().Mul(p.LoopsPerYear())
+	return perYear.Mul(decimal.NewFromInt(100)).Div(budget)
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bvk/tradebot/point"
+	"github.com/bvk/tradebot/timerange"
+	"github.com/bvk/tradebot/trader"
+	"github.com/shopspring/decimal"
+)
+
+func TestPerformance(t *testing.T) {
+	d := decimal.RequireFromString
+	pair := func(bprice, sprice string) *point.Pair {
+		return &point.Pair{
+			Buy:  point.Point{Size: d("1"), Price: d(bprice), Cancel: d(bprice).Add(d("5"))},
+			Sell: point.Point{Size: d("1"), Price: d(sprice), Cancel: d(sprice).Sub(d("5"))},
+		}
+	}
+
+	// Average profit margin is 10 on a budget of 200 without the fees.
+	end := time.Now()
+	p := &Performance{
+		Actual: &trader.Summary{
+			TimePeriod: timerange.Range{Begin: end.Add(-73 * 24 * time.Hour), End: end},
+			NumLoops:   4,
+		},
+		Analysis: Analyze([]*point.Pair{pair("90", "100"), pair("110", "120")}, 0),
+	}
+	if v := p.LoopsPerYear(); !v.Equal(d("20")) {
+		t.Fatalf("want 20 loops per year, got %s", v)
+	}
+	if v := p.ExpectedProfit(); !v.Equal(d("40")) {
+		t.Fatalf("want expected profit 40, got %s", v)
+	}
+	if v := p.ExpectedAnnualReturnRate(); !v.Equal(d("100")) {
+		t.Fatalf("want expected annual return rate 100%%, got %s", v)
+	}
+
+	p.Actual.TimePeriod = timerange.Range{}
+	if v := p.LoopsPerYear(); !v.IsZero() {
+		t.Fatalf("want zero loops per year without a time period, got %s", v)
+	}
+}
+
+func TestPerformanceFeePct(t *testing.T) {
+	d := decimal.RequireFromString
+	pairs := []*point.Pair{{
+		Buy:  point.Point{Size: d("1"), Price: d("100"), Cancel: d("105")},
+		Sell: point.Point{Size: d("1"), Price: d("110"), Cancel: d("105")},
+	}}
+
+	// Fees of 0.42 on the fills of 210 is an effective fee of 0.2%.
+	actual := &trader.Summary{
+		BoughtValue: d("100"),
+		BoughtFees:  d("0.2"),
+		SoldValue:   d("110"),
+		SoldFees:    d("0.22"),
+	}
+	p := newPerformance(actual, pairs)
+	if v := p.Analysis.FeePct(); v != 0.2 {
+		t.Fatalf("want fee percentage 0.2 from the actual fills, got %v", v)
+	}
+	if v := p.Analysis.Budget(); !v.Equal(d("100.42")) {
+		t.Fatalf("want budget 100.42 with the fees at the actual fee percentage, got %s", v)
+	}
+
+	if v := newPerformance(&trader.Summary{}, pairs).Analysis.FeePct(); v != 0 {
+		t.Fatalf("want zero fee percentage without any fills, got %v", v)
+	}
+}
@@ -41,16 +41,23 @@ func (w *Waller) Run(ctx context.Context, rt *trader.Runtime) error {
 			defer wg.Done()
 
 			for ctx.Err() == nil {
-				if err := loop.Run(ctx, rt); err != nil {
-					if ctx.Err() == nil {
-						log.Printf("wall-looper %v has failed (retry): %v", loop, err)
-						time.Sleep(time.Second)
-					}
+				err := loop.Run(ctx, rt)
+				if err == nil {
+					log.Printf("wall-looper %v is complete", loop)
+					return
+				}
+				if ctx.Err() == nil {
+					log.Printf("wall-looper %v has failed (retry): %v", loop, err)
+					time.Sleep(time.Second)
 				}
 			}
 		}()
 	}
 
 	wg.Wait()
-	return context.Cause(ctx)
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	log.Printf("waller %s is complete cause all loopers are complete", w.uid)
+	return nil
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/bvk/tradebot/point"
 	"github.com/bvk/tradebot/timerange"
 	"github.com/bvk/tradebot/trader"
+	"github.com/shopspring/decimal"
 )
 
 // PairStatus returns trade status for a buy-sell pair. Returns nil if trading
@@ -32,5 +33,44 @@ func (w *Waller) Status(period *timerange.Range) *trader.Status {
 		ExchangeName: w.exchangeName,
 		Summary:      summary,
 	}
+	// Waller is tagged only when all of it's loops have the same tag.
+	for i, v := range ss {
+		if i == 0 {
+			s.Tag = v.Tag
+		} else if s.Tag != v.Tag {
+			s.Tag = ""
+			break
+		}
+	}
 	return s
 }
+
+// LoopStatus holds the operational state of a single buy-sell loop.
+type LoopStatus struct {
+	UID   string
+	Pair  *point.Pair
+	State string
+
+	NumBuys  int
+	NumSells int
+
+	Profit decimal.Decimal
+}
+
+// LoopStatuses returns the operational state and the cumulative profit for
+// each buy-sell loop of the waller.
+func (w *Waller) LoopStatuses() []*LoopStatus {
+	var vs []*LoopStatus
+	for _, l := range w.loopers {
+		s := l.Status(nil)
+		vs = append(vs, &LoopStatus{
+			UID:      l.UID(),
+			Pair:     l.Pair(),
+			State:    l.LoopState(),
+			NumBuys:  s.NumBuys,
+			NumSells: s.NumSells,
+			Profit:   l.RealizedProfit(),
+		})
+	}
+	return vs
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/bvk/tradebot/gobs"
 	"github.com/bvk/tradebot/kvutil"
+	"github.com/bvk/tradebot/limiter"
 	"github.com/bvk/tradebot/looper"
 	"github.com/bvk/tradebot/point"
 	"github.com/bvk/tradebot/trader"
@@ -94,6 +95,16 @@ func (w *Waller) BudgetAt(feePct float64) decimal.Decimal {
 	return sum
 }
 
+// BudgetFootprint returns the quote funds reserved by the incomplete buys of
+// all loopers.
+func (w *Waller) BudgetFootprint() decimal.Decimal {
+	var sum decimal.Decimal
+	for _, l := range w.loopers {
+		sum = sum.Add(l.BudgetFootprint())
+	}
+	return sum
+}
+
 func (w *Waller) Pairs() []*point.Pair {
 	var ps []*point.Pair
 	for _, l := range w.loopers {
@@ -112,6 +123,24 @@ func (w *Waller) Actions() []*gobs.Action {
 	return actions
 }
 
+// LiveOrders returns the active orders of all loopers.
+func (w *Waller) LiveOrders() []*limiter.LiveOrder {
+	var orders []*limiter.LiveOrder
+	for _, l := range w.loopers {
+		orders = append(orders, l.LiveOrders()...)
+	}
+	return orders
+}
+
+// FillLatency returns the combined fill latency statistics of all loopers.
+func (w *Waller) FillLatency() *limiter.FillLatency {
+	stats := new(limiter.FillLatency)
+	for _, l := range w.loopers {
+		stats.Add(l.FillLatency())
+	}
+	return stats
+}
+
 func (w *Waller) Fees() decimal.Decimal {
 	var sum decimal.Decimal
 	for _, l := range w.loopers {
@@ -144,6 +173,22 @@ func (w *Waller) UnsoldValue() decimal.Decimal {
 	return sum
 }
 
+// Inventory returns the net base size held across all loopers and it's
+// volume-weighted average cost per unit. Average cost is zero when nothing is
+// held.
+func (w *Waller) Inventory() (size, avgCost decimal.Decimal) {
+	var cost decimal.Decimal
+	for _, l := range w.loopers {
+		lsize, lcost := l.Inventory()
+		size = size.Add(lsize)
+		cost = cost.Add(lcost)
+	}
+	if !size.IsPositive() {
+		return decimal.Zero, decimal.Zero
+	}
+	return size, cost.Div(size)
+}
+
 func (w *Waller) Save(ctx context.Context, rw kv.ReadWriter) error {
 	var loopers []string
 	for _, l := range w.loopers {
@@ -0,0 +1,88 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"github.com/bvk/tradebot/point"
+	"github.com/shopspring/decimal"
+)
+
+// Orphan is an existing buy-sell pair that holds unsold inventory, but is not
+// part of a proposed spec, so it's inventory would not be sold by the waller.
+type Orphan struct {
+	Pair *point.Pair
+
+	Size decimal.Decimal
+	Cost decimal.Decimal
+}
+
+// WhatIf describes how a proposed set of buy-sell pairs would treat the
+// current state of a waller.
+type WhatIf struct {
+	NumPairs    int
+	NumNewPairs int
+
+	// NumKept is the number of existing pairs that are also in the proposed
+	// pairs, NumAdded is the number of proposed pairs that are new and
+	// NumRemoved is the number of existing pairs that are dropped.
+	NumKept    int
+	NumAdded   int
+	NumRemoved int
+
+	Budget    decimal.Decimal
+	NewBudget decimal.Decimal
+
+	// Orphans holds the removed pairs with unsold inventory.
+	Orphans []*Orphan
+}
+
+// OrphanedSize returns the total unsold size and it's cost in the orphaned
+// pairs.
+func (v *WhatIf) OrphanedSize() (size, cost decimal.Decimal) {
+	for _, o := range v.Orphans {
+		size = size.Add(o.Size)
+		cost = cost.Add(o.Cost)
+	}
+	return size, cost
+}
+
+// WhatIf returns the impact of replacing the waller's buy-sell pairs with the
+// proposed pairs, with the budgets computed at the given fee percentage. The
+// waller itself is not modified.
+func (w *Waller) WhatIf(pairs []*point.Pair, feePct float64) *WhatIf {
+	v := &WhatIf{
+		NumPairs:    len(w.loopers),
+		NumNewPairs: len(pairs),
+		Budget:      w.BudgetAt(feePct),
+		NewBudget:   Analyze(pairs, feePct).Budget(),
+	}
+
+	contains := func(ps []*point.Pair, p *point.Pair) bool {
+		for _, x := range ps {
+			if x.Equal(p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	existing := w.Pairs()
+	for _, p := range pairs {
+		if contains(existing, p) {
+			v.NumKept++
+		} else {
+			v.NumAdded++
+		}
+	}
+	for _, l := range w.loopers {
+		p := l.Pair()
+		if contains(pairs, p) {
+			continue
+		}
+		v.NumRemoved++
+		if size, cost := l.Inventory(); size.IsPositive() {
+			v.Orphans = append(v.Orphans, &Orphan{Pair: p, Size: size, Cost: cost})
+		}
+	}
+	return v
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2024 BVK Chaitanya
+
+package waller
+
+import (
+	"testing"
+
+	"github.com/bvk/tradebot/point"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestWhatIf(t *testing.T) {
+	d := decimal.RequireFromString
+	pair := func(bprice, sprice string) *point.Pair {
+		return &point.Pair{
+			Buy:  point.Point{Size: d("1"), Price: d(bprice), Cancel: d(bprice).Add(d("5"))},
+			Sell: point.Point{Size: d("1"), Price: d(sprice), Cancel: d(sprice).Sub(d("5"))},
+		}
+	}
+
+	w, err := New(uuid.NewString(), "coinbase", "BTC-USD", []*point.Pair{
+		pair("90", "100"),
+		pair("100", "110"),
+		pair("110", "120"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := w.WhatIf([]*point.Pair{pair("100", "110"), pair("110", "120"), pair("120", "130"), pair("130", "140")}, 0)
+	if v.NumPairs != 3 || v.NumNewPairs != 4 {
+		t.Fatalf("want 3 existing and 4 new pairs, got %d and %d", v.NumPairs, v.NumNewPairs)
+	}
+	if v.NumKept != 2 || v.NumAdded != 2 || v.NumRemoved != 1 {
+		t.Fatalf("want 2 kept, 2 added and 1 removed pairs, got %d, %d and %d", v.NumKept, v.NumAdded, v.NumRemoved)
+	}
+	if !v.Budget.Equal(d("300")) || !v.NewBudget.Equal(d("460")) {
+		t.Fatalf("want budget 300 and new budget 460, got %s and %s", v.Budget, v.NewBudget)
+	}
+	// Removed pair has no inventory, so it is not an orphan.
+	if len(v.Orphans) != 0 {
+		t.Fatalf("want no orphans, got %d", len(v.Orphans))
+	}
+}